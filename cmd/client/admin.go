@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "microchat.ai/proto"
+)
+
+// adminCommand is the argument that runs an admin subcommand instead of the chat, e.g. "admin sessions"
+const adminCommand = "admin"
+
+// Admin subcommands
+const (
+	adminMetrics  = "metrics"  // Server's Prometheus metrics, from the metrics endpoint
+	adminSessions = "sessions" // Active sessions, from ListSessions
+	adminKeys     = "keys"     // Traffic per API key, from ListKeyUsage
+)
+
+const (
+	adminTimeout       = 30 * time.Second // Deadline for an admin request when -timeout isn't set
+	defaultMetricsPort = "9090"           // Server's METRICS_PORT default
+)
+
+// adminUsage describes the admin subcommands for usage errors
+const adminUsage = "usage: microchat-client [flags] admin metrics|sessions|keys"
+
+// runAdminMode runs an admin subcommand with the admin key and returns the process exit code, as in one-shot mode
+func runAdminMode(cfg config, logger *slog.Logger, args []string, metricsURL string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, adminUsage)
+		return exitUsage
+	}
+
+	app := &application{config: cfg, logger: logger}
+	var err error
+	switch args[0] {
+	case adminMetrics:
+		if metricsURL == "" {
+			metricsURL = defaultMetricsURL(cfg.serverAddr)
+		}
+		err = app.adminMetrics(os.Stdout, metricsURL)
+	case adminSessions, adminKeys:
+		if err := app.connect(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitError
+		}
+		defer app.conn.Close()
+		if args[0] == adminSessions {
+			err = app.adminSessions(os.Stdout)
+		} else {
+			err = app.adminKeys(os.Stdout)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown admin command %q\n%s\n", args[0], adminUsage)
+		return exitUsage
+	}
+
+	if err != nil {
+		message := status.Convert(err).Message()
+		if code := status.Code(err); code == codes.PermissionDenied || code == codes.Unauthenticated {
+			message += " (admin commands need an admin key, set with MICROCHAT_ADMIN_KEY)"
+		}
+		fmt.Fprintf(os.Stderr, "Error: %s\n", message)
+		return oneShotExitCode(err)
+	}
+	return 0
+}
+
+// defaultMetricsURL is the metrics endpoint on the server's host at the default port
+// Unix socket servers are assumed to be local
+func defaultMetricsURL(serverAddr string) string {
+	host := "localhost"
+	if h, _, err := net.SplitHostPort(serverAddr); err == nil && !strings.HasPrefix(serverAddr, "unix:") && h != "" {
+		host = h
+	}
+	return "http://" + net.JoinHostPort(host, defaultMetricsPort) + "/metrics"
+}
+
+// adminContext returns a context with the admin key and the request deadline
+func (app *application) adminContext() (context.Context, context.CancelFunc) {
+	timeout := app.config.timeout
+	if timeout <= 0 {
+		timeout = adminTimeout
+	}
+	return context.WithTimeout(app.addAuthContext(context.Background()), timeout)
+}
+
+// adminMetrics fetches the server's Prometheus metrics and prints its own, skipping the Go runtime's
+// and histogram buckets
+func (app *application) adminMetrics(out io.Writer, metricsURL string) error {
+	ctx, cancel := app.adminContext()
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metricsURL, nil)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid metrics URL: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+app.config.apiKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return status.Errorf(codes.Unavailable, "failed to fetch %s: %v", metricsURL, err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return status.Errorf(codes.PermissionDenied, "%s: %s", metricsURL, resp.Status)
+	default:
+		return status.Errorf(codes.Unavailable, "%s: %s", metricsURL, resp.Status)
+	}
+	return printMetrics(out, resp.Body)
+}
+
+// printMetrics prints microchat's samples from Prometheus text exposition
+// Unlabeled samples are listed with their values aligned; labeled ones are grouped under their metric's name
+func printMetrics(out io.Writer, exposition io.Reader) error {
+	type sample struct{ labels, value string }
+	type family struct {
+		name    string
+		samples []sample
+	}
+	var families []*family
+	byName := make(map[string]*family)
+	width := 0
+
+	scanner := bufio.NewScanner(exposition)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "microchat_") {
+			continue // Comments and other collectors' metrics
+		}
+		i := strings.LastIndexByte(line, ' ')
+		if i < 0 {
+			continue
+		}
+		name, labels, _ := strings.Cut(line[:i], "{")
+		if strings.HasSuffix(name, "_bucket") {
+			continue
+		}
+		f := byName[name]
+		if f == nil {
+			f = &family{name: name}
+			byName[name] = f
+			families = append(families, f)
+		}
+		f.samples = append(f.samples, sample{strings.TrimSuffix(labels, "}"), line[i+1:]})
+		width = max(width, len(name))
+	}
+	if err := scanner.Err(); err != nil {
+		return status.Errorf(codes.Unavailable, "failed to read metrics: %v", err)
+	}
+
+	if len(families) == 0 {
+		fmt.Fprintln(out, "No microchat metrics reported")
+		return nil
+	}
+	for _, f := range families {
+		if len(f.samples) == 1 && f.samples[0].labels == "" {
+			fmt.Fprintf(out, "%-*s  %s\n", width, f.name, f.samples[0].value)
+			continue
+		}
+		fmt.Fprintln(out, f.name)
+		labelWidth := 0
+		for _, s := range f.samples {
+			labelWidth = max(labelWidth, len(s.labels))
+		}
+		for _, s := range f.samples {
+			fmt.Fprintf(out, "  %-*s  %s\n", labelWidth, s.labels, s.value)
+		}
+	}
+	return nil
+}
+
+// adminSessions lists the server's active sessions, most recently active first
+func (app *application) adminSessions(out io.Writer) error {
+	ctx, cancel := app.adminContext()
+	defer cancel()
+	resp, err := app.grpc.ListSessions(ctx, &pb.ListSessionsRequest{})
+	if err != nil {
+		return err
+	}
+
+	if len(resp.Sessions) == 0 {
+		fmt.Fprintln(out, "No active sessions")
+		return nil
+	}
+	fmt.Fprintf(out, "%-36s  %8s  %10s  %-16s  %s\n", "SESSION", "MESSAGES", "SIZE", "LAST ACTIVE", "TITLE")
+	for _, s := range resp.Sessions {
+		lastActive := time.Unix(s.LastActiveUnix, 0).Local().Format("2006-01-02 15:04")
+		fmt.Fprintf(out, "%-36s  %8d  %10s  %-16s  %s\n", s.SessionId, s.MessageCount, formatBytes(int64(s.SizeBytes)), lastActive, s.Title)
+	}
+	fmt.Fprintf(out, "%d sessions\n", len(resp.Sessions))
+	return nil
+}
+
+// adminKeys lists traffic per API key, identified by hash
+func (app *application) adminKeys(out io.Writer) error {
+	ctx, cancel := app.adminContext()
+	defer cancel()
+	resp, err := app.grpc.ListKeyUsage(ctx, &pb.ListKeyUsageRequest{})
+	if err != nil {
+		return err
+	}
+
+	if len(resp.Keys) == 0 {
+		fmt.Fprintln(out, "No API key traffic since the server started")
+		return nil
+	}
+	fmt.Fprintf(out, "%-16s  %11s  %12s  %21s  %21s\n", "KEY", "CALLS TODAY", "TOKENS TODAY", "PAYLOAD IN/OUT", "WIRE IN/OUT")
+	for _, k := range resp.Keys {
+		payload := formatBytes(k.PayloadBytesIn) + " / " + formatBytes(k.PayloadBytesOut)
+		wire := formatBytes(k.WireBytesIn) + " / " + formatBytes(k.WireBytesOut)
+		fmt.Fprintf(out, "%-16s  %11d  %12d  %21s  %21s\n", k.KeyHash, k.CallsToday, k.TokensToday, payload, wire)
+	}
+	return nil
+}
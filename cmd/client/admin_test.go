@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	pb "microchat.ai/proto"
+)
+
+// fakeAdminClient answers the admin RPCs with fixed results
+type fakeAdminClient struct {
+	pb.ChatServiceClient
+	sessions []*pb.SessionInfo
+	keys     []*pb.KeyUsage
+}
+
+func (f *fakeAdminClient) ListSessions(ctx context.Context, in *pb.ListSessionsRequest, opts ...grpc.CallOption) (*pb.ListSessionsResponse, error) {
+	return &pb.ListSessionsResponse{Sessions: f.sessions}, nil
+}
+
+func (f *fakeAdminClient) ListKeyUsage(ctx context.Context, in *pb.ListKeyUsageRequest, opts ...grpc.CallOption) (*pb.ListKeyUsageResponse, error) {
+	return &pb.ListKeyUsageResponse{Keys: f.keys}, nil
+}
+
+func newAdminApp(fake *fakeAdminClient) *application {
+	return &application{
+		config: config{apiKey: "admin-key"},
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		grpc:   fake,
+	}
+}
+
+func TestAdminSessionsAndKeys(t *testing.T) {
+	app := newAdminApp(&fakeAdminClient{
+		sessions: []*pb.SessionInfo{{SessionId: "session-1", Title: "CRDTs", MessageCount: 4, SizeBytes: 2048, LastActiveUnix: 1_700_000_000}},
+		keys:     []*pb.KeyUsage{{KeyHash: "1bcefe2243eced99", CallsToday: 7, TokensToday: 1200, PayloadBytesIn: 44, WireBytesIn: 2048}},
+	})
+
+	var out bytes.Buffer
+	if err := app.adminSessions(&out); err != nil {
+		t.Fatalf("Expected sessions to be listed, got %v", err)
+	}
+	if s := out.String(); !strings.Contains(s, "session-1") || !strings.Contains(s, "2.0 KB") || !strings.Contains(s, "CRDTs") || !strings.Contains(s, "1 sessions") {
+		t.Errorf("Unexpected sessions output:\n%s", s)
+	}
+
+	out.Reset()
+	if err := app.adminKeys(&out); err != nil {
+		t.Fatalf("Expected keys to be listed, got %v", err)
+	}
+	if s := out.String(); !strings.Contains(s, "1bcefe2243eced99") || !strings.Contains(s, "1200") || !strings.Contains(s, "2.0 KB / 0 B") {
+		t.Errorf("Unexpected keys output:\n%s", s)
+	}
+
+	out.Reset()
+	newAdminApp(&fakeAdminClient{}).adminSessions(&out)
+	if out.String() != "No active sessions\n" {
+		t.Errorf("Expected no sessions, got %q", out.String())
+	}
+}
+
+func TestAdminMetrics(t *testing.T) {
+	exposition := `# HELP go_goroutines Number of goroutines
+go_goroutines 12
+# HELP microchat_active_sessions Number of active sessions
+microchat_active_sessions 3
+microchat_request_duration_seconds_bucket{method="Chat",le="0.1"} 1
+microchat_request_duration_seconds_count{method="Chat"} 2
+microchat_request_duration_seconds_count{method="StartSession"} 1
+`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer admin-key" {
+			http.Error(w, "Admin access required", http.StatusForbidden)
+			return
+		}
+		io.WriteString(w, exposition)
+	}))
+	defer srv.Close()
+
+	var out bytes.Buffer
+	app := newAdminApp(&fakeAdminClient{})
+	if err := app.adminMetrics(&out, srv.URL+"/metrics"); err != nil {
+		t.Fatalf("Expected metrics, got %v", err)
+	}
+	want := `microchat_active_sessions                 3
+microchat_request_duration_seconds_count
+  method="Chat"          2
+  method="StartSession"  1
+`
+	if out.String() != want {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want, out.String())
+	}
+
+	app.config.apiKey = "dev-key-1"
+	if err := app.adminMetrics(io.Discard, srv.URL+"/metrics"); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected a non-admin key to be denied, got %v", err)
+	}
+}
+
+func TestDefaultMetricsURL(t *testing.T) {
+	tests := map[string]string{
+		"microchat.ai:443":    "http://microchat.ai:9090/metrics",
+		"localhost:4000":      "http://localhost:9090/metrics",
+		"unix:///tmp/mc.sock": "http://localhost:9090/metrics",
+		"[2001:db8::1]:4000":  "http://[2001:db8::1]:9090/metrics",
+	}
+	for addr, want := range tests {
+		if got := defaultMetricsURL(addr); got != want {
+			t.Errorf("defaultMetricsURL(%q) = %q, expected %q", addr, got, want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxMessageSize matches the server's limit on a message, attachments included, so an oversized
+// message is caught before it's sent
+const maxMessageSize = 10 * 1024
+
+// attachment is a local file's contents waiting to be sent with the next message
+type attachment struct {
+	name    string // Base name, so the full path isn't sent
+	content string
+}
+
+// isAttachCommand reports whether input is "/attach", with or without an argument
+func isAttachCommand(input string) bool {
+	return input == attachCommand || strings.HasPrefix(input, attachCommand+" ")
+}
+
+// readAttachment reads a text file to attach, rejecting binary files and files too large to send
+func readAttachment(path string) (attachment, error) {
+	f, err := os.Open(expandHome(path))
+	if err != nil {
+		return attachment{}, err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, maxMessageSize+1))
+	if err != nil {
+		return attachment{}, err
+	}
+	name := filepath.Base(path)
+	if len(data) > maxMessageSize {
+		return attachment{}, fmt.Errorf("%s is larger than the %s message limit", name, formatBytes(maxMessageSize))
+	}
+	if !utf8.Valid(data) || bytes.IndexByte(data, 0) >= 0 {
+		return attachment{}, fmt.Errorf("%s isn't a text file", name)
+	}
+	return attachment{name: name, content: string(data)}, nil
+}
+
+// fence returns the attachment as a fenced block under its file name, with the extension as the
+// block's language
+// The fence is longer than any run of backticks in the file, so the file can't close it early
+func (a attachment) fence() string {
+	longest, run := 0, 0
+	for _, r := range a.content {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", max(3, longest+1))
+	content := strings.TrimSuffix(a.content, "\n")
+	return fmt.Sprintf("%s:\n%s%s\n%s\n%s", a.name, fence, strings.TrimPrefix(filepath.Ext(a.name), "."), content, fence)
+}
+
+// withAttachments appends the attachments to message as fenced blocks
+func withAttachments(message string, attachments []attachment) (string, error) {
+	parts := []string{message}
+	for _, a := range attachments {
+		parts = append(parts, a.fence())
+	}
+	text := strings.Join(parts, "\n\n")
+	if len(text) > maxMessageSize {
+		return "", fmt.Errorf("message with attachments is %s, more than the %s limit", formatBytes(int64(len(text))), formatBytes(maxMessageSize))
+	}
+	return text, nil
+}
+
+// attach reads a file to send with the next message
+func (app *application) attach(path string) error {
+	a, err := readAttachment(path)
+	if err != nil {
+		return err
+	}
+	if _, err := withAttachments("", append(app.attachments, a)); err != nil {
+		return err
+	}
+	app.attachments = append(app.attachments, a)
+	return nil
+}
+
+// handleAttachCommand attaches a file for "/attach <path>", lists the attachments for "/attach" and
+// drops them for "/attach clear"
+func (app *application) handleAttachCommand(input string) {
+	arg := strings.TrimSpace(strings.TrimPrefix(input, attachCommand))
+	switch arg {
+	case "":
+		if len(app.attachments) == 0 {
+			fmt.Printf("No files attached - '%s <path>' to attach one to your next message\n", attachCommand)
+			return
+		}
+		for _, a := range app.attachments {
+			fmt.Printf("Attached: %s (%s)\n", a.name, formatBytes(int64(len(a.content))))
+		}
+	case "clear":
+		app.attachments = nil
+		fmt.Println("Attachments cleared")
+	default:
+		if err := app.attach(arg); err != nil {
+			app.printError("%v", err)
+			return
+		}
+		a := app.attachments[len(app.attachments)-1]
+		fmt.Printf("Attached %s (%s) - it will be sent with your next message\n", a.name, formatBytes(int64(len(a.content))))
+	}
+}
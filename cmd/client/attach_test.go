@@ -0,0 +1,87 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadAttachment(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	a, err := readAttachment(write("main.go", []byte("package main\n")))
+	if err != nil || a.name != "main.go" || a.content != "package main\n" {
+		t.Errorf("Expected main.go to be attached, got %+v, %v", a, err)
+	}
+	if _, err := readAttachment(write("big.txt", make([]byte, maxMessageSize+1))); err == nil {
+		t.Error("Expected a file over the message limit to be rejected")
+	}
+	if _, err := readAttachment(write("image.png", []byte{0x89, 'P', 'N', 'G', 0, 0})); err == nil {
+		t.Error("Expected a binary file to be rejected")
+	}
+	if _, err := readAttachment(filepath.Join(dir, "missing.go")); err == nil {
+		t.Error("Expected a missing file to be an error")
+	}
+}
+
+func TestAttachmentFence(t *testing.T) {
+	a := attachment{name: "main.go", content: "package main\n"}
+	if got, want := a.fence(), "main.go:\n```go\npackage main\n```"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	// A fence in the file doesn't end the block
+	a = attachment{name: "README.md", content: "```bash\nmake\n```\n"}
+	if got, want := a.fence(), "README.md:\n````md\n```bash\nmake\n```\n````"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestHandleMessageWithAttachments(t *testing.T) {
+	fake := &fakeChatClient{reply: "It prints hello"}
+	app := &application{
+		config: config{sessionID: "session-1"},
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		grpc:   fake,
+	}
+
+	path := filepath.Join(t.TempDir(), "hello.py")
+	if err := os.WriteFile(path, []byte("print('hello')\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	app.handleAttachCommand(attachCommand + " " + path)
+	if len(app.attachments) != 1 || !strings.Contains(app.prompt(), "attached (1)") {
+		t.Fatalf("Expected hello.py to be attached, got %v and prompt %q", app.attachments, app.prompt())
+	}
+
+	app.handleMessage("Explain this code")
+	want := "Explain this code\n\nhello.py:\n```py\nprint('hello')\n```"
+	if fake.sent == nil || fake.sent.Message != want {
+		t.Fatalf("Expected the attachment to be sent with the message, got %v", fake.sent)
+	}
+	if len(app.attachments) != 0 {
+		t.Error("Expected the attachments to be dropped once sent")
+	}
+
+	// A message that would exceed the limit with its attachments isn't sent, and keeps them
+	fake.sent = nil
+	app.attachments = []attachment{{name: "notes.txt", content: strings.Repeat("x", maxMessageSize-100)}}
+	app.handleMessage(strings.Repeat("y", 200))
+	if fake.sent != nil || len(app.attachments) != 1 {
+		t.Errorf("Expected the oversized message to be held back with its attachment, got %v sent", fake.sent)
+	}
+	app.handleAttachCommand(attachCommand + " clear")
+	if len(app.attachments) != 0 {
+		t.Error("Expected '/attach clear' to drop the attachments")
+	}
+}
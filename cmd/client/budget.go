@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// budgetThresholds are the shares of -budget, in percent, at which the client warns
+var budgetThresholds = []int64{50, 80, 100}
+
+// byteUnits are the suffixes accepted by parseByteSize, binary like formatBytes, longest first so
+// "MB" isn't read as "B"
+var byteUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"GIB", 1 << 30}, {"MIB", 1 << 20}, {"KIB", 1 << 10},
+	{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10},
+	{"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10},
+	{"B", 1},
+}
+
+// parseByteSize reads a size such as 30MB, 512KB or 1.5GB, or a plain number of bytes
+func parseByteSize(s string) (int64, error) {
+	text := strings.ToUpper(strings.TrimSpace(s))
+	unit := int64(1)
+	for _, u := range byteUnits {
+		if strings.HasSuffix(text, u.suffix) {
+			text, unit = strings.TrimSpace(strings.TrimSuffix(text, u.suffix)), u.size
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(text, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q: must be a size such as 30MB", s)
+	}
+	return int64(n * float64(unit)), nil
+}
+
+// budgetUsed returns the wire bytes sent and received since the client started
+func (app *application) budgetUsed() int64 {
+	_, _, wireOut, wireIn := app.metrics.getLifetimeTotals()
+	return wireOut + wireIn
+}
+
+// checkBudget warns the first time usage crosses each of budgetThresholds
+func (app *application) checkBudget() {
+	if app.config.budget <= 0 {
+		return
+	}
+	used := app.budgetUsed()
+	crossed := app.budgetWarned
+	for crossed < len(budgetThresholds) && used*100 >= budgetThresholds[crossed]*app.config.budget {
+		crossed++
+	}
+	if crossed == app.budgetWarned {
+		return
+	}
+	app.budgetWarned = crossed
+
+	warning := fmt.Sprintf("Warning: %d%% of the bandwidth budget used (%s of %s)",
+		budgetThresholds[crossed-1], formatBytes(used), formatBytes(app.config.budget))
+	if crossed == len(budgetThresholds) && app.config.budgetStrict {
+		warning += " - no more messages will be sent"
+	}
+	fmt.Println(app.theme.errorText(warning))
+}
+
+// overBudget reports whether -budget-strict stops a message from being sent, telling the user when it does
+func (app *application) overBudget() bool {
+	if !app.config.budgetStrict || app.config.budget <= 0 {
+		return false
+	}
+	used := app.budgetUsed()
+	if used < app.config.budget {
+		return false
+	}
+	app.printError("message not sent - the %s bandwidth budget is used up (%s); restart with a larger -budget to keep chatting",
+		formatBytes(app.config.budget), formatBytes(used))
+	return true
+}
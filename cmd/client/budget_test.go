@@ -0,0 +1,79 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+)
+
+func TestParseByteSize(t *testing.T) {
+	tests := map[string]int64{
+		"30MB":    30 << 20,
+		"512kb":   512 << 10,
+		"1.5GB":   3 << 29,
+		"2 MiB":   2 << 20,
+		"100M":    100 << 20,
+		"1048576": 1 << 20,
+		"64B":     64,
+	}
+	for s, want := range tests {
+		if got, err := parseByteSize(s); err != nil || got != want {
+			t.Errorf("parseByteSize(%q) = %d, %v, expected %d", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"", "MB", "lots", "-5MB"} {
+		if _, err := parseByteSize(s); err == nil {
+			t.Errorf("Expected %q to be rejected", s)
+		}
+	}
+}
+
+func TestBudget(t *testing.T) {
+	fake := &fakeChatClient{reply: "hi"}
+	app := &application{
+		config: config{sessionID: "session-1", budget: 1000, budgetStrict: true},
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		grpc:   fake,
+	}
+
+	// Each threshold is warned about once, even when a message crosses several
+	app.metrics.addWireBytes(300, 250)
+	app.checkBudget()
+	if app.budgetWarned != 1 {
+		t.Errorf("Expected the 50%% warning, got %d thresholds", app.budgetWarned)
+	}
+	app.checkBudget()
+	if app.budgetWarned != 1 {
+		t.Errorf("Expected no repeated warning, got %d thresholds", app.budgetWarned)
+	}
+	app.metrics.addWireBytes(200, 300)
+	app.checkBudget()
+	if app.budgetWarned != 3 {
+		t.Errorf("Expected the 80%% and 100%% thresholds, got %d", app.budgetWarned)
+	}
+
+	// -budget-strict refuses to send once the budget is used up
+	app.handleMessage("one more")
+	if fake.sent != nil {
+		t.Error("Expected the message not to be sent over budget")
+	}
+	app.config.budgetStrict = false
+	app.handleMessage("one more")
+	if fake.sent == nil {
+		t.Error("Expected the message to be sent without -budget-strict")
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[int64]string{
+		512:      "512 B",
+		1536:     "1.5 KB",
+		30 << 20: "30.0 MB",
+		3 << 29:  "1.5 GB",
+	}
+	for n, want := range tests {
+		if got := formatBytes(n); got != want {
+			t.Errorf("formatBytes(%d) = %q, expected %q", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/status"
+
+	"microchat.ai/zstd"
+)
+
+// noCompression is the -compress value for sending requests uncompressed
+const noCompression = "none"
+
+// compressors are the values accepted by -compress, in the order they are listed
+var compressors = []string{gzip.Name, zstd.Name, noCompression}
+
+// validateCompressor checks a -compress value
+func validateCompressor(name string) error {
+	if !slices.Contains(compressors, name) {
+		return fmt.Errorf("unknown compressor %q (expected %s)", name, strings.Join(compressors, ", "))
+	}
+	return nil
+}
+
+// compressor returns the gRPC compressor requests are sent with, "" when they're sent uncompressed
+func (app *application) compressor() string {
+	if app.config.compress == noCompression || app.codecMissing.Load() {
+		return ""
+	}
+	return app.config.compress
+}
+
+// compressionLabel names the compression in use for the metrics lines
+func (app *application) compressionLabel() string {
+	if name := app.compressor(); name != "" {
+		return name
+	}
+	return noCompression
+}
+
+// compressionInterceptor compresses requests with the selected codec
+// A server without the codec rejects the request before handling it, so the request is sent again
+// uncompressed, and so are later ones
+func (app *application) compressionInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	name := app.compressor()
+	if name == "" {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	err := invoker(ctx, method, req, reply, cc, append(opts, grpc.UseCompressor(name))...)
+	if !isMissingCodec(err) {
+		return err
+	}
+	app.logger.Warn("the server can't decompress requests, sending them uncompressed", "compressor", name, "error", err)
+	app.codecMissing.Store(true)
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// isMissingCodec reports whether the server rejected a request because it lacks the codec it was compressed with
+func isMissingCodec(err error) bool {
+	s := status.Convert(err)
+	return s.Code() == codes.Unimplemented && strings.Contains(s.Message(), "grpc-encoding")
+}
+
+// compressionRatio returns wire bytes as a share of payload bytes, e.g. "38%", or "-" with no payload
+func compressionRatio(payload, wire int64) string {
+	if payload == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d%%", wire*100/payload)
+}
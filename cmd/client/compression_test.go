@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// callCompressor returns the compressor a call was made with, "" for none
+func callCompressor(opts []grpc.CallOption) string {
+	for _, opt := range opts {
+		if c, ok := opt.(grpc.CompressorCallOption); ok {
+			return c.CompressorType
+		}
+	}
+	return ""
+}
+
+func TestCompressionInterceptor(t *testing.T) {
+	app := &application{
+		config: config{compress: "gzip"},
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	// A server without gzip rejects the request, which is sent again uncompressed
+	var calls []string
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		name := callCompressor(opts)
+		calls = append(calls, name)
+		if name != "" {
+			return status.Errorf(codes.Unimplemented, "grpc: Decompressor is not installed for grpc-encoding %q", name)
+		}
+		return nil
+	}
+	if err := app.compressionInterceptor(context.Background(), "/chat.ChatService/Chat", nil, nil, nil, invoker); err != nil {
+		t.Fatalf("Expected the uncompressed retry to succeed, got %v", err)
+	}
+	if len(calls) != 2 || calls[0] != "gzip" || calls[1] != "" {
+		t.Errorf("Expected a gzip call then an uncompressed one, got %q", calls)
+	}
+
+	// Later requests go uncompressed straight away
+	calls = nil
+	app.compressionInterceptor(context.Background(), "/chat.ChatService/Chat", nil, nil, nil, invoker)
+	if len(calls) != 1 || calls[0] != "" || app.compressionLabel() != "none" {
+		t.Errorf("Expected one uncompressed call, got %q (%s)", calls, app.compressionLabel())
+	}
+
+	// Other Unimplemented errors aren't retried
+	app = &application{config: config{compress: "gzip"}, logger: app.logger}
+	unimplemented := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		return status.Error(codes.Unimplemented, "unknown method ListModels")
+	}
+	if err := app.compressionInterceptor(context.Background(), "/chat.ChatService/ListModels", nil, nil, nil, unimplemented); status.Code(err) != codes.Unimplemented || app.compressionLabel() != "gzip" {
+		t.Errorf("Expected the error to be returned with gzip kept, got %v (%s)", err, app.compressionLabel())
+	}
+
+	if err := validateCompressor("zstd"); err != nil {
+		t.Errorf("Expected zstd to be accepted, got %v", err)
+	}
+	if err := validateCompressor("brotli"); err == nil {
+		t.Error("Expected an unknown compressor to be an error")
+	}
+	if got := compressionRatio(1000, 420); got != "42%" {
+		t.Errorf("Expected 42%%, got %q", got)
+	}
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// fileConfig is the client config file, ~/.config/microchat/config.toml by default
+// Values are defaults: flags override them, and the MICROCHAT_API_KEY, SERVER_NAME and CA_CERT_FILE
+// environment variables take precedence over the matching top-level settings
+// Named profiles under [profiles.<name>] bundle settings for one server; the selected profile's
+// settings replace the top-level ones and, since choosing a profile is explicit, the environment too
+type fileConfig struct {
+	Addr          string   `toml:"addr"`
+	Model         string   `toml:"model"`
+	Metrics       *bool    `toml:"metrics"`
+	MetricsDetail *bool    `toml:"metrics_detail"`
+	MetricsTotal  *bool    `toml:"metrics_total"`
+	SessionTTL    string   `toml:"session_ttl"`
+	Timeout       string   `toml:"timeout"`
+	Retries       *int     `toml:"retries"`
+	RetryDelay    string   `toml:"retry_delay"`
+	RetryMaxWait  string   `toml:"retry_max_wait"`
+	RetryJitter   *float64 `toml:"retry_jitter"`
+	Compress      string   `toml:"compress"`
+	Budget        string   `toml:"budget"`
+	BudgetStrict  *bool    `toml:"budget_strict"`
+	APIKeyEnv     string   `toml:"api_key_env"`  // Environment variable holding the API key
+	APIKeyFile    string   `toml:"api_key_file"` // File holding the API key
+	ServerName    string   `toml:"server_name"`  // TLS server name for development servers
+	CACertFile    string   `toml:"ca_cert_file"` // CA certificate for development servers
+	Theme         string   `toml:"theme"`
+	NoColor       *bool    `toml:"no_color"`
+	NoPager       *bool    `toml:"no_pager"`
+	TimeFormat    string   `toml:"time_format"`
+	Notify        *bool    `toml:"notify"`
+	NotifyAfter   string   `toml:"notify_after"`
+
+	Snippets     map[string]string `toml:"snippets"`      // Prompt templates sent with "/t name"
+	ModelAliases map[string]string `toml:"model_aliases"` // Extra model names, e.g. fast = "GEMINI_2_5_FLASH_LITE"
+
+	Profile  string                `toml:"profile"`  // Profile used when -profile isn't given
+	Profiles map[string]fileConfig `toml:"profiles"` // Named profiles, e.g. local, prod, work
+}
+
+// defaultConfigPath returns $XDG_CONFIG_HOME/microchat/config.toml, falling back to ~/.config
+func defaultConfigPath() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "microchat", "config.toml")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "microchat", "config.toml")
+}
+
+// loadFileConfig reads the config file at path
+// A missing file is only an error when required, i.e. when it was named with -config
+func loadFileConfig(path string, required bool) (*fileConfig, error) {
+	var fc fileConfig
+	if path == "" {
+		return &fc, nil
+	}
+	meta, err := toml.DecodeFile(path, &fc)
+	if errors.Is(err, fs.ErrNotExist) && !required {
+		return &fc, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	// Reject unknown settings so a typo doesn't silently fall back to a default
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		keys := make([]string, len(undecoded))
+		for i, key := range undecoded {
+			keys[i] = key.String()
+		}
+		sort.Strings(keys)
+		return nil, fmt.Errorf("unknown settings in %s: %s", path, strings.Join(keys, ", "))
+	}
+	for name, profile := range fc.Profiles {
+		if profile.Profile != "" || len(profile.Profiles) > 0 {
+			return nil, fmt.Errorf("profile %q in %s can't select or define profiles", name, path)
+		}
+	}
+	return &fc, nil
+}
+
+// withProfile returns the settings with the named profile applied over the top-level ones,
+// along with the profile itself
+// Returns an empty profile when name is ""
+func (fc *fileConfig) withProfile(name string) (*fileConfig, *fileConfig, error) {
+	if name == "" {
+		return fc, &fileConfig{}, nil
+	}
+	profile, ok := fc.Profiles[name]
+	if !ok {
+		names := make([]string, 0, len(fc.Profiles))
+		for n := range fc.Profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return nil, nil, fmt.Errorf("unknown profile %q: the config file defines no profiles", name)
+		}
+		return nil, nil, fmt.Errorf("unknown profile %q (expected one of %s)", name, strings.Join(names, ", "))
+	}
+
+	merged := *fc
+	merged.Profile, merged.Profiles = "", nil
+	for _, s := range []struct{ dst, src *string }{
+		{&merged.Addr, &profile.Addr},
+		{&merged.Model, &profile.Model},
+		{&merged.SessionTTL, &profile.SessionTTL},
+		{&merged.Timeout, &profile.Timeout},
+		{&merged.RetryDelay, &profile.RetryDelay},
+		{&merged.RetryMaxWait, &profile.RetryMaxWait},
+		{&merged.Compress, &profile.Compress},
+		{&merged.Budget, &profile.Budget},
+		{&merged.ServerName, &profile.ServerName},
+		{&merged.CACertFile, &profile.CACertFile},
+		{&merged.Theme, &profile.Theme},
+		{&merged.TimeFormat, &profile.TimeFormat},
+		{&merged.NotifyAfter, &profile.NotifyAfter},
+	} {
+		if *s.src != "" {
+			*s.dst = *s.src
+		}
+	}
+	for _, b := range []struct{ dst, src **bool }{
+		{&merged.Metrics, &profile.Metrics},
+		{&merged.MetricsDetail, &profile.MetricsDetail},
+		{&merged.MetricsTotal, &profile.MetricsTotal},
+		{&merged.NoColor, &profile.NoColor},
+		{&merged.NoPager, &profile.NoPager},
+		{&merged.Notify, &profile.Notify},
+		{&merged.BudgetStrict, &profile.BudgetStrict},
+	} {
+		if *b.src != nil {
+			*b.dst = *b.src
+		}
+	}
+	// A profile's snippets and model aliases add to the top-level ones, replacing any of the same name
+	for _, m := range []struct{ dst, src *map[string]string }{
+		{&merged.Snippets, &profile.Snippets},
+		{&merged.ModelAliases, &profile.ModelAliases},
+	} {
+		if len(*m.src) > 0 {
+			*m.dst = maps.Clone(*m.dst)
+			if *m.dst == nil {
+				*m.dst = make(map[string]string, len(*m.src))
+			}
+			maps.Copy(*m.dst, *m.src)
+		}
+	}
+	if profile.Retries != nil {
+		merged.Retries = profile.Retries
+	}
+	if profile.RetryJitter != nil {
+		merged.RetryJitter = profile.RetryJitter
+	}
+	// A profile's API key reference replaces the top-level one rather than combining with it
+	if profile.hasAPIKey() {
+		merged.APIKeyEnv, merged.APIKeyFile = profile.APIKeyEnv, profile.APIKeyFile
+	}
+	return &merged, &profile, nil
+}
+
+// hasAPIKey reports whether the settings reference an API key
+func (fc *fileConfig) hasAPIKey() bool {
+	return fc.APIKeyEnv != "" || fc.APIKeyFile != ""
+}
+
+// apply copies the file's values into cfg for every setting not given as a flag
+func (fc *fileConfig) apply(cfg *config, setFlags map[string]bool) error {
+	if fc.Addr != "" && !setFlags["addr"] {
+		cfg.serverAddr = fc.Addr
+	}
+	if fc.Model != "" && !setFlags["model"] {
+		cfg.modelString = fc.Model
+	}
+	if fc.Metrics != nil && !setFlags["metrics"] {
+		cfg.metrics = *fc.Metrics
+	}
+	if fc.MetricsDetail != nil && !setFlags["metrics-detail"] {
+		cfg.metricsDetail = *fc.MetricsDetail
+	}
+	if fc.MetricsTotal != nil && !setFlags["metrics-total"] {
+		cfg.metricsTotal = *fc.MetricsTotal
+	}
+	if fc.Compress != "" && !setFlags["compress"] {
+		cfg.compress = fc.Compress
+	}
+	if fc.BudgetStrict != nil && !setFlags["budget-strict"] {
+		cfg.budgetStrict = *fc.BudgetStrict
+	}
+	if fc.Theme != "" && !setFlags["theme"] {
+		cfg.themeName = fc.Theme
+	}
+	if fc.NoColor != nil && !setFlags["no-color"] {
+		cfg.noColor = *fc.NoColor
+	}
+	if fc.NoPager != nil && !setFlags["no-pager"] {
+		cfg.noPager = *fc.NoPager
+	}
+	if fc.TimeFormat != "" && !setFlags["time-format"] {
+		cfg.timeFormat = fc.TimeFormat
+	}
+	if fc.Notify != nil && !setFlags["notify"] {
+		cfg.notify = *fc.Notify
+	}
+	if fc.SessionTTL != "" && !setFlags["session-ttl"] {
+		ttl, err := time.ParseDuration(fc.SessionTTL)
+		if err != nil || ttl < 0 {
+			return fmt.Errorf("invalid session_ttl %q: must be a duration such as 8h", fc.SessionTTL)
+		}
+		cfg.sessionTTL = ttl
+	}
+	if fc.Timeout != "" && !setFlags["timeout"] {
+		timeout, err := time.ParseDuration(fc.Timeout)
+		if err != nil || timeout < 0 {
+			return fmt.Errorf("invalid timeout %q: must be a duration such as 2m", fc.Timeout)
+		}
+		cfg.timeout = timeout
+	}
+	if fc.Retries != nil && !setFlags["retries"] {
+		cfg.retry.attempts = *fc.Retries
+	}
+	if fc.RetryJitter != nil && !setFlags["retry-jitter"] {
+		cfg.retry.jitter = *fc.RetryJitter
+	}
+	for _, d := range []struct {
+		key, flag, value string
+		dst              *time.Duration
+	}{
+		{"retry_delay", "retry-delay", fc.RetryDelay, &cfg.retry.delay},
+		{"retry_max_wait", "retry-max-wait", fc.RetryMaxWait, &cfg.retry.maxWait},
+		{"notify_after", "notify-after", fc.NotifyAfter, &cfg.notifyAfter},
+	} {
+		if d.value == "" || setFlags[d.flag] {
+			continue
+		}
+		wait, err := time.ParseDuration(d.value)
+		if err != nil || wait < 0 {
+			return fmt.Errorf("invalid %s %q: must be a duration such as 5s", d.key, d.value)
+		}
+		*d.dst = wait
+	}
+	if err := validateSnippets(fc.Snippets); err != nil {
+		return err
+	}
+	cfg.snippets = fc.Snippets
+	aliases, err := parseModelAliases(fc.ModelAliases)
+	if err != nil {
+		return err
+	}
+	cfg.modelAliases = aliases
+	if fc.Budget != "" && !setFlags["budget"] {
+		budget, err := parseByteSize(fc.Budget)
+		if err != nil {
+			return fmt.Errorf("invalid budget: %w", err)
+		}
+		cfg.budget = budget
+	}
+	return nil
+}
+
+// apiKey resolves the file's API key reference, returning "" when it has none
+func (fc *fileConfig) apiKey() (string, error) {
+	switch {
+	case fc.APIKeyEnv != "":
+		key := os.Getenv(fc.APIKeyEnv)
+		if key == "" {
+			return "", fmt.Errorf("api_key_env names %s, which is not set", fc.APIKeyEnv)
+		}
+		return key, nil
+	case fc.APIKeyFile != "":
+		data, err := os.ReadFile(expandHome(fc.APIKeyFile))
+		if err != nil {
+			return "", fmt.Errorf("failed to read api_key_file: %w", err)
+		}
+		key := strings.TrimSpace(string(data))
+		if key == "" {
+			return "", fmt.Errorf("api_key_file %s is empty", fc.APIKeyFile)
+		}
+		return key, nil
+	default:
+		return "", nil
+	}
+}
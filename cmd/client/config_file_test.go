@@ -0,0 +1,176 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFileConfig(t *testing.T) {
+	path := writeConfigFile(t, `
+addr = "microchat.ai:443"
+model = "openai"
+metrics = true
+session_ttl = "8h"
+timeout = "2m"
+budget = "30MB"
+budget_strict = true
+api_key_env = "TEST_MICROCHAT_KEY"
+theme = "light"
+no_color = true
+no_pager = true
+time_format = "relative"
+notify = true
+notify_after = "30s"
+retries = 2
+retry_delay = "500ms"
+retry_max_wait = "10s"
+retry_jitter = 0.5
+`)
+	fc, err := loadFileConfig(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config{serverAddr: "localhost:4000", modelString: "gemini"}
+	if err := fc.apply(&cfg, map[string]bool{}); err != nil {
+		t.Fatal(err)
+	}
+	if want := (retryPolicy{attempts: 2, delay: 500 * time.Millisecond, maxWait: 10 * time.Second, jitter: 0.5}); cfg.retry != want {
+		t.Errorf("Expected the file's retry policy %+v, got %+v", want, cfg.retry)
+	}
+	if cfg.serverAddr != "microchat.ai:443" || cfg.modelString != "openai" || !cfg.metrics || cfg.sessionTTL != 8*time.Hour || cfg.timeout != 2*time.Minute ||
+		cfg.themeName != "light" || !cfg.noColor || !cfg.noPager || cfg.timeFormat != "relative" || !cfg.notify || cfg.notifyAfter != 30*time.Second || cfg.budget != 30<<20 || !cfg.budgetStrict {
+		t.Errorf("Expected the file's values, got %+v", cfg)
+	}
+
+	// Flags win over the file
+	cfg = config{serverAddr: "localhost:4000", modelString: "echo"}
+	fc.apply(&cfg, map[string]bool{"addr": true, "model": true, "metrics": true})
+	if cfg.serverAddr != "localhost:4000" || cfg.modelString != "echo" || cfg.metrics {
+		t.Errorf("Expected flags to override the file, got %+v", cfg)
+	}
+
+	t.Setenv("TEST_MICROCHAT_KEY", "key-from-env")
+	if key, err := fc.apiKey(); err != nil || key != "key-from-env" {
+		t.Errorf("Expected the referenced key, got %q (%v)", key, err)
+	}
+}
+
+func TestLoadFileConfigErrors(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.toml")
+	if fc, err := loadFileConfig(missing, false); err != nil || fc == nil {
+		t.Errorf("Expected a missing default config to be ignored, got %v", err)
+	}
+	if _, err := loadFileConfig(missing, true); err == nil {
+		t.Error("Expected a missing -config file to be an error")
+	}
+
+	if _, err := loadFileConfig(writeConfigFile(t, `adress = "x"`), false); err == nil || !strings.Contains(err.Error(), "adress") {
+		t.Errorf("Expected an unknown setting to be reported, got %v", err)
+	}
+	if _, err := loadFileConfig(writeConfigFile(t, `addr = `), false); err == nil {
+		t.Error("Expected invalid TOML to be an error")
+	}
+
+	fc, _ := loadFileConfig(writeConfigFile(t, `session_ttl = "forever"`), false)
+	if err := fc.apply(&config{}, nil); err == nil {
+		t.Error("Expected an invalid session_ttl to be an error")
+	}
+	fc, _ = loadFileConfig(writeConfigFile(t, `retry_max_wait = "a while"`), false)
+	if err := fc.apply(&config{}, nil); err == nil {
+		t.Error("Expected an invalid retry_max_wait to be an error")
+	}
+
+	fc = &fileConfig{APIKeyEnv: "TEST_MICROCHAT_UNSET_KEY"}
+	if _, err := fc.apiKey(); err == nil {
+		t.Error("Expected an unset api_key_env to be an error")
+	}
+}
+
+func TestFileConfigAPIKeyFile(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(keyFile, []byte("key-from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	fc := &fileConfig{APIKeyFile: keyFile}
+	if key, err := fc.apiKey(); err != nil || key != "key-from-file" {
+		t.Errorf("Expected the key file's contents, got %q (%v)", key, err)
+	}
+	if key, err := (&fileConfig{}).apiKey(); err != nil || key != "" {
+		t.Errorf("Expected no key without a reference, got %q (%v)", key, err)
+	}
+}
+
+func TestFileConfigProfiles(t *testing.T) {
+	path := writeConfigFile(t, `
+addr = "localhost:4000"
+model = "echo"
+metrics = true
+api_key_env = "TEST_MICROCHAT_KEY"
+profile = "local"
+
+[profiles.local]
+server_name = "localhost"
+ca_cert_file = "certs/ca.crt"
+
+[profiles.prod]
+addr = "microchat.ai:443"
+model = "gemini"
+metrics = false
+api_key_file = "~/.config/microchat/prod_key"
+`)
+	fc, err := loadFileConfig(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fc.Profile != "local" || len(fc.Profiles) != 2 {
+		t.Fatalf("Unexpected profiles: %q %v", fc.Profile, fc.Profiles)
+	}
+
+	merged, profile, err := fc.withProfile("prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged.Addr != "microchat.ai:443" || merged.Model != "gemini" || *merged.Metrics {
+		t.Errorf("Expected the profile's values, got %+v", merged)
+	}
+	if merged.APIKeyEnv != "" || merged.APIKeyFile == "" || !profile.hasAPIKey() {
+		t.Errorf("Expected the profile's API key reference to replace the top-level one, got %+v", merged)
+	}
+
+	// A profile without a setting inherits the top-level value
+	merged, profile, _ = fc.withProfile("local")
+	if merged.Addr != "localhost:4000" || !*merged.Metrics || merged.APIKeyEnv != "TEST_MICROCHAT_KEY" || profile.hasAPIKey() {
+		t.Errorf("Expected top-level values to be inherited, got %+v", merged)
+	}
+	if merged.ServerName != "localhost" || profile.CACertFile != "certs/ca.crt" {
+		t.Errorf("Expected the profile's TLS settings, got %+v", merged)
+	}
+
+	// No profile leaves the top-level settings
+	if merged, profile, err := fc.withProfile(""); err != nil || merged != fc || profile.hasAPIKey() || profile.Addr != "" {
+		t.Errorf("Expected the file's own settings without a profile, got %+v (%v)", merged, err)
+	}
+
+	if _, _, err := fc.withProfile("work"); err == nil || !strings.Contains(err.Error(), "local, prod") {
+		t.Errorf("Expected an unknown profile to list the known ones, got %v", err)
+	}
+	if _, err := loadFileConfig(writeConfigFile(t, "[profiles.a]\nprofile = \"b\"\n"), true); err == nil {
+		t.Error("Expected a profile selecting another profile to be rejected")
+	}
+	if _, err := loadFileConfig(writeConfigFile(t, "[profiles.a]\nadress = \"x\"\n"), true); err == nil || !strings.Contains(err.Error(), "profiles.a.adress") {
+		t.Errorf("Expected an unknown setting in a profile to be reported, got %v", err)
+	}
+}
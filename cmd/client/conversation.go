@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	pb "microchat.ai/proto"
+)
+
+// The client keeps its own copy of the session's messages in app.conversation, so /history and the
+// token estimate don't need the history fetched again
+// The copy is in sync while it holds messageIndex messages; when the server's count moves by more than
+// the turn just sent, e.g. for tool calls or a summary, it's refetched the next time it's needed
+
+// historyEntries parses and, for client-encrypted sessions, decrypts GetHistory's messages
+// Messages that can't be parsed keep their formatted text with an empty role
+func (app *application) historyEntries(history *pb.GetHistoryResponse) []historyEntry {
+	entries := make([]historyEntry, 0, len(history.Messages))
+	for i, formatted := range history.Messages {
+		entry, ok := parseHistoryMessage(formatted)
+		if !ok {
+			entries = append(entries, historyEntry{text: formatted})
+			continue
+		}
+		if len(history.TimestampsUnix) == len(history.Messages) { // Older servers send no timestamps
+			entry.at = time.Unix(history.TimestampsUnix[i], 0)
+		}
+		if history.Encrypted {
+			text, err := decryptHistoryText(app.config.sessionKey, app.config.sessionID, entry.text)
+			if err != nil {
+				app.logger.Warn("failed to decrypt history message", "error", err)
+				text = "[encrypted message could not be decrypted]"
+			}
+			entry.text = text
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// conversationSynced reports whether the local copy holds every message the server has
+func (app *application) conversationSynced() bool {
+	return len(app.conversation) == int(app.messageIndex)
+}
+
+// conversationHistory returns the session's messages, from the local copy when it's in sync and
+// otherwise from the server, which also brings the copy and the message index back in sync
+func (app *application) conversationHistory() ([]historyEntry, error) {
+	if app.conversationSynced() {
+		return app.conversation, nil
+	}
+	if err := app.syncConversation(); err != nil {
+		return nil, err
+	}
+	return app.conversation, nil
+}
+
+// syncConversation replaces the local copy, the message index and the conversation's token estimate
+// with the server's history
+func (app *application) syncConversation() error {
+	ctx := app.addAuthContext(context.Background())
+	history, err := app.grpc.GetHistory(ctx, &pb.GetHistoryRequest{SessionId: app.config.sessionID})
+	if err != nil {
+		return err
+	}
+	app.conversation = app.historyEntries(history)
+	app.messageIndex = uint32(len(history.Messages))
+	app.tokens.context = app.historyTokens(history)
+	return nil
+}
+
+// recordTurn adds a message and its reply to the local copy
+// When the server reports that the history diverged from what the client last saw, e.g. because the
+// session was continued from another terminal, the copy is resynced and the user told
+func (app *application) recordTurn(req *pb.ChatRequest, resp *pb.ChatResponse, sent, received time.Time) {
+	if resp.HistoryDiverged {
+		app.logger.Debug("session history diverged, resyncing", "client_index", req.MessageIndex, "server_count", resp.MessageCount)
+		if err := app.syncConversation(); err != nil {
+			app.logger.Warn("failed to resync session history", "error", err)
+			return
+		}
+		fmt.Printf("The session changed elsewhere since your last message - resynced its %d messages ('%s' to review them)\n",
+			len(app.conversation), historyCommand)
+		return
+	}
+
+	// Tool calls or a summary changed the count by more than this turn; the copy is refetched when needed
+	if int(req.MessageIndex) != len(app.conversation) || resp.MessageCount != req.MessageIndex+2 {
+		return
+	}
+	app.conversation = append(app.conversation,
+		historyEntry{role: "user", timestamp: sent.UTC().Format(time.TimeOnly), at: sent, text: req.Message},
+		historyEntry{role: "assistant", timestamp: received.UTC().Format(time.TimeOnly), at: received, text: resp.Reply})
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	pb "microchat.ai/proto"
+)
+
+func TestRecordTurn(t *testing.T) {
+	fake := &flakyChatClient{history: []string{
+		"user [09:00:00 UTC]: from another terminal", "assistant [09:00:01 UTC]: Echo: from another terminal",
+		"user [10:00:00 UTC]: hi", "assistant [10:00:01 UTC]: Echo: hi",
+	}}
+	app := &application{
+		config: config{sessionID: "session-1"},
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		grpc:   fake,
+	}
+	sent := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	// A turn the server stored as expected is added to the local copy without asking the server
+	req := &pb.ChatRequest{Message: "hi", MessageIndex: 0}
+	app.messageIndex = 2
+	app.recordTurn(req, &pb.ChatResponse{Reply: "Echo: hi", MessageCount: 2}, sent, sent.Add(time.Second))
+	if len(app.conversation) != 2 || app.conversation[0] != (historyEntry{"user", "10:00:00", sent, "hi"}) ||
+		app.conversation[1] != (historyEntry{"assistant", "10:00:01", sent.Add(time.Second), "Echo: hi"}) {
+		t.Errorf("Expected the turn in the local copy, got %+v", app.conversation)
+	}
+	if entries, err := app.conversationHistory(); err != nil || len(entries) != 2 {
+		t.Errorf("Expected the local copy, got %+v, %v", entries, err)
+	}
+
+	// Tool calls add messages the client didn't see, so the copy is refetched when next needed
+	req = &pb.ChatRequest{Message: "weather?", MessageIndex: 2}
+	app.messageIndex = 6
+	app.recordTurn(req, &pb.ChatResponse{Reply: "Sunny", MessageCount: 6}, sent, sent)
+	if len(app.conversation) != 2 || app.conversationSynced() {
+		t.Errorf("Expected a stale copy, got %+v", app.conversation)
+	}
+	if entries, err := app.conversationHistory(); err != nil || len(entries) != 4 || app.messageIndex != 4 {
+		t.Errorf("Expected the server's history, got %+v, %v (index %d)", entries, err, app.messageIndex)
+	}
+
+	// A diverged history is resynced straight away
+	app.conversation, app.messageIndex = nil, 2
+	req = &pb.ChatRequest{Message: "hi", MessageIndex: 2}
+	app.recordTurn(req, &pb.ChatResponse{Reply: "Echo: hi", MessageCount: 4, HistoryDiverged: true}, sent, sent)
+	if len(app.conversation) != 4 || app.conversation[0].text != "from another terminal" || app.messageIndex != 4 {
+		t.Errorf("Expected the resynced history, got %+v (index %d)", app.conversation, app.messageIndex)
+	}
+	if app.tokens.context == 0 {
+		t.Error("Expected the token estimate to be recomputed from the resynced history")
+	}
+}
+
+func TestHistoryEntries(t *testing.T) {
+	app := &application{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	entries := app.historyEntries(&pb.GetHistoryResponse{Messages: []string{"user [10:00:00 UTC]: hi", "malformed"}})
+	if len(entries) != 2 || entries[0] != (historyEntry{role: "user", timestamp: "10:00:00", text: "hi"}) || entries[1] != (historyEntry{text: "malformed"}) {
+		t.Errorf("Expected parsed entries, got %+v", entries)
+	}
+
+	// Newer servers send each message's full time alongside
+	entries = app.historyEntries(&pb.GetHistoryResponse{Messages: []string{"user [10:00:00 UTC]: hi"}, TimestampsUnix: []int64{1735725600}})
+	if len(entries) != 1 || !entries[0].at.Equal(time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the message's time, got %+v", entries)
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"microchat.ai/cmd/client/lineedit"
+)
+
+// isCopyCommand reports whether input is "/copy", with or without a code block
+func isCopyCommand(input string) bool {
+	return input == copyCommand || strings.HasPrefix(input, copyCommand+" ")
+}
+
+// parseCopyCommand reads which code block "/copy code N" selects, counting from 1
+// Returns 0 for "/copy", which copies the whole reply, and 1 for "/copy code"
+func parseCopyCommand(input string) (int, error) {
+	usage := fmt.Errorf("usage: %s [code [N]], where N is the code block's number in the reply", copyCommand)
+	args := strings.Fields(strings.TrimPrefix(input, copyCommand))
+	switch {
+	case len(args) == 0:
+		return 0, nil
+	case args[0] != "code" || len(args) > 2:
+		return 0, usage
+	case len(args) == 1:
+		return 1, nil
+	}
+	n, err := strconv.Atoi(args[1])
+	if err != nil || n <= 0 {
+		return 0, usage
+	}
+	return n, nil
+}
+
+// codeBlocks returns the contents of text's fenced code blocks, without their fences
+// As with typed input, an opening fence may follow other text, as in "Try this: ```go"; a block is
+// closed by a line holding a fence at least as long, and an unclosed block runs to the end
+func codeBlocks(text string) []string {
+	var blocks []string
+	var block []string
+	open := 0 // Length of the open block's fence, 0 outside a block
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if open == 0 {
+			if openFence(trimmed) { // An even number of fences is inline code, e.g. ```x```
+				i := strings.LastIndex(trimmed, codeFence)
+				start := strings.TrimRight(trimmed[:i], "`")
+				open, block = i+len(codeFence)-len(start), nil
+			}
+			continue
+		}
+		if fence := len(trimmed) - len(strings.TrimLeft(trimmed, "`")); fence >= open && fence == len(trimmed) {
+			blocks = append(blocks, strings.Join(block, "\n"))
+			open = 0
+			continue
+		}
+		block = append(block, line)
+	}
+	if open > 0 && strings.TrimSpace(strings.Join(block, "")) != "" {
+		blocks = append(blocks, strings.TrimSuffix(strings.Join(block, "\n"), "\n"))
+	}
+	return blocks
+}
+
+// copyReply copies the most recent reply, or the code block chosen with "/copy code N", to the clipboard
+func (app *application) copyReply(input string) error {
+	n, err := parseCopyCommand(input)
+	if err != nil {
+		fmt.Println(err)
+		return nil
+	}
+	reply, err := app.latestReply()
+	if err != nil {
+		return err
+	}
+	if reply == "" {
+		fmt.Println("No reply to copy yet")
+		return nil
+	}
+
+	text, what := reply, "the last reply"
+	if n > 0 {
+		blocks := codeBlocks(reply)
+		if n > len(blocks) {
+			app.printError("the last reply has %d code block(s)", len(blocks))
+			return nil
+		}
+		text, what = blocks[n-1], fmt.Sprintf("code block %d of %d", n, len(blocks))
+	}
+
+	var terminal io.Writer
+	if lineedit.IsTerminal(os.Stdout) {
+		terminal = os.Stdout
+	}
+	via, err := copyToClipboard(text, terminal, clipboardCommands())
+	if err != nil {
+		app.printError("can't copy %s: %v", what, err)
+		return nil
+	}
+	fmt.Printf("Copied %s (%s) to the clipboard via %s\n", what, formatBytes(int64(len(text))), via)
+	return nil
+}
+
+// copyToClipboard copies text with the first of commands that's installed and, when terminal is set,
+// with an OSC 52 escape sequence, which the terminal passes to its own clipboard even over SSH
+// Returns how the text was copied
+func copyToClipboard(text string, terminal io.Writer, commands [][]string) (string, error) {
+	var via []string
+	cmdErr := errors.New("no clipboard command found")
+	for _, command := range commands {
+		if _, err := exec.LookPath(command[0]); err != nil {
+			continue
+		}
+		// Output isn't captured: xclip stays running to serve the selection, holding on to any pipes
+		cmd := exec.Command(command[0], command[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		if err := cmd.Run(); err != nil {
+			cmdErr = fmt.Errorf("%s: %w", command[0], err)
+			continue
+		}
+		via = append(via, command[0])
+		break
+	}
+	if terminal != nil {
+		if _, err := io.WriteString(terminal, osc52(text)); err == nil {
+			via = append(via, "the terminal")
+		}
+	}
+	if len(via) == 0 {
+		return "", cmdErr
+	}
+	return strings.Join(via, " and "), nil
+}
+
+// osc52 returns the escape sequence that sets the terminal's clipboard to text
+func osc52(text string) string {
+	return tmuxPassthrough("\033]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a")
+}
+
+// tmuxPassthrough wraps an escape sequence meant for the terminal, which tmux only passes on to the
+// outer terminal when wrapped in its passthrough sequence
+func tmuxPassthrough(seq string) string {
+	if os.Getenv("TMUX") != "" {
+		seq = "\033Ptmux;" + strings.ReplaceAll(seq, "\033", "\033\033") + "\033\\"
+	}
+	return seq
+}
+
+// clipboardCommands lists the system's clipboard commands to try, in order
+// None are used over SSH, where they would copy to the remote machine's clipboard rather than the user's
+func clipboardCommands() [][]string {
+	if os.Getenv("SSH_CONNECTION") != "" {
+		return nil
+	}
+	switch runtime.GOOS {
+	case "darwin":
+		return [][]string{{"pbcopy"}}
+	case "windows":
+		return [][]string{{"clip"}}
+	}
+	var commands [][]string
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		commands = append(commands, []string{"wl-copy"})
+	}
+	if os.Getenv("DISPLAY") != "" {
+		commands = append(commands, []string{"xclip", "-selection", "clipboard"}, []string{"xsel", "--clipboard", "--input"})
+	}
+	return commands
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestParseCopyCommand(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    int
+		wantErr bool
+	}{
+		{"/copy", 0, false},
+		{"/copy code", 1, false},
+		{"/copy code 2", 2, false},
+		{"/copy code 0", 0, true},
+		{"/copy code two", 0, true},
+		{"/copy all", 0, true},
+		{"/copy code 1 2", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseCopyCommand(tt.input)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("parseCopyCommand(%q) = %d, %v; want %d, error %v", tt.input, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestCodeBlocks(t *testing.T) {
+	reply := "Two ways:\n```go\nfmt.Println(\"a\")\n```\nor\n  ````sh\necho ```\n  ````\nUse ```x``` inline or: ```\nx := 1\n```\nand\n```\nunclosed\n"
+	blocks := codeBlocks(reply)
+	want := []string{`fmt.Println("a")`, "echo ```", "x := 1", "unclosed"}
+	if len(blocks) != len(want) {
+		t.Fatalf("Expected %d blocks, got %q", len(want), blocks)
+	}
+	for i := range want {
+		if blocks[i] != want[i] {
+			t.Errorf("Block %d: expected %q, got %q", i+1, want[i], blocks[i])
+		}
+	}
+
+	if blocks := codeBlocks("no code here\n```\n"); len(blocks) != 0 {
+		t.Errorf("Expected no blocks, got %q", blocks)
+	}
+}
+
+func TestCopyToClipboard(t *testing.T) {
+	t.Setenv("TMUX", "")
+
+	// OSC 52 carries the text to the terminal
+	var terminal bytes.Buffer
+	via, err := copyToClipboard("hello", &terminal, nil)
+	if err != nil || via != "the terminal" {
+		t.Errorf("Expected a copy via the terminal, got %q, %v", via, err)
+	}
+	if want := "\033]52;c;" + base64.StdEncoding.EncodeToString([]byte("hello")) + "\a"; terminal.String() != want {
+		t.Errorf("Expected %q, got %q", want, terminal.String())
+	}
+
+	// Without a terminal or clipboard command there's nowhere to copy to
+	if _, err := copyToClipboard("hello", nil, [][]string{{"no-such-clipboard-command"}}); err == nil {
+		t.Error("Expected an error without a clipboard")
+	}
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+	// The first installed command gets the text on stdin
+	path := filepath.Join(t.TempDir(), "clipboard")
+	commands := [][]string{{"no-such-clipboard-command"}, {"sh", "-c", "cat > " + path}, {"false"}}
+	via, err = copyToClipboard("hello", nil, commands)
+	if err != nil || via != "sh" {
+		t.Errorf("Expected a copy via sh, got %q, %v", via, err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "hello" {
+		t.Errorf("Expected the text on the command's stdin, got %q", data)
+	}
+
+	// A failing command is reported
+	if _, err := copyToClipboard("hello", nil, [][]string{{"false"}}); err == nil || !strings.Contains(err.Error(), "false") {
+		t.Errorf("Expected the command's failure, got %v", err)
+	}
+}
+
+func TestOSC52InTmux(t *testing.T) {
+	t.Setenv("TMUX", "/tmp/tmux-1000/default,1,0")
+	got := osc52("hi")
+	if !strings.HasPrefix(got, "\033Ptmux;\033\033]52;c;") || !strings.HasSuffix(got, "\a\033\\") {
+		t.Errorf("Expected the sequence wrapped for tmux, got %q", got)
+	}
+}
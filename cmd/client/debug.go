@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"log/slog"
+	"net"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/grpclog"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/stats"
+)
+
+// debugLogger returns the logger for -debug, which writes everything to out, and sends gRPC's own
+// warnings and errors, such as failed connection attempts, there too
+// gRPC's info logs are left out since they mostly repeat what the stats handler logs
+func debugLogger(out io.Writer) *slog.Logger {
+	grpclog.SetLoggerV2(grpclog.NewLoggerV2(io.Discard, out, out))
+	return slog.New(slog.NewTextHandler(out, &slog.HandlerOptions{Level: slog.LevelDebug}))
+}
+
+// logResolvedAddr logs the addresses the server's host name resolves to, since a stale or unexpected
+// record is a common reason a connection fails
+func (app *application) logResolvedAddr(ctx context.Context) {
+	if strings.HasPrefix(app.config.serverAddr, "unix:") {
+		return
+	}
+	host, _, err := net.SplitHostPort(app.config.serverAddr)
+	if err != nil {
+		return
+	}
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		app.logger.Debug("failed to resolve server address", "host", host, "error", err)
+		return
+	}
+	app.logger.Debug("resolved server address", "host", host, "addrs", addrs)
+}
+
+// logTLSHandshake logs a completed handshake's details; used as tls.Config.VerifyConnection, after
+// the certificate has been verified
+func (app *application) logTLSHandshake(state tls.ConnectionState) error {
+	transport, certificate := describeTransport(credentials.TLSInfo{State: state})
+	chain := make([]string, 0, len(state.PeerCertificates))
+	for _, cert := range state.PeerCertificates {
+		chain = append(chain, cert.Subject.String())
+	}
+	names := []string(nil)
+	if len(state.PeerCertificates) > 0 {
+		names = state.PeerCertificates[0].DNSNames
+	}
+	app.logger.Debug("TLS handshake complete", "server_name", state.ServerName, "transport", transport,
+		"certificate", certificate, "dns_names", names, "chain", chain, "resumed", state.DidResume)
+	return nil
+}
+
+// debugStatsHandler logs connection and per-RPC events for -debug
+type debugStatsHandler struct {
+	logger *slog.Logger
+}
+
+// debugMethodKey carries an RPC's method name to the events logged for it
+type debugMethodKey struct{}
+
+func (h *debugStatsHandler) TagRPC(ctx context.Context, info *stats.RPCTagInfo) context.Context {
+	return context.WithValue(ctx, debugMethodKey{}, info.FullMethodName)
+}
+
+func (h *debugStatsHandler) HandleRPC(ctx context.Context, s stats.RPCStats) {
+	method, _ := ctx.Value(debugMethodKey{}).(string)
+	switch stat := s.(type) {
+	case *stats.Begin:
+		h.logger.Debug("rpc started", "method", method, "fail_fast", stat.FailFast)
+	case *stats.OutHeader:
+		h.logger.Debug("request headers", "method", method, "remote", stat.RemoteAddr, "compression", stat.Compression, "headers", formatMetadata(stat.Header))
+	case *stats.OutPayload:
+		h.logger.Debug("request sent", "method", method, "bytes", stat.Length, "wire_bytes", stat.WireLength)
+	case *stats.InHeader:
+		h.logger.Debug("response headers", "method", method, "compression", stat.Compression, "headers", formatMetadata(stat.Header))
+	case *stats.InPayload:
+		h.logger.Debug("response received", "method", method, "bytes", stat.Length, "wire_bytes", stat.WireLength)
+	case *stats.InTrailer:
+		h.logger.Debug("response trailers", "method", method, "trailers", formatMetadata(stat.Trailer))
+	case *stats.End:
+		h.logger.Debug("rpc finished", "method", method, "duration", stat.EndTime.Sub(stat.BeginTime), "error", stat.Error)
+	}
+}
+
+func (h *debugStatsHandler) TagConn(ctx context.Context, info *stats.ConnTagInfo) context.Context {
+	h.logger.Debug("connection opened", "remote", info.RemoteAddr, "local", info.LocalAddr)
+	return ctx
+}
+
+func (h *debugStatsHandler) HandleConn(ctx context.Context, s stats.ConnStats) {
+	if _, ok := s.(*stats.ConnEnd); ok {
+		h.logger.Debug("connection closed")
+	}
+}
+
+// secretHeaders carry the API key or the session key and are never shown in debug output
+var secretHeaders = map[string]bool{
+	"authorization": true,
+	"x-session-key": true,
+}
+
+// formatMetadata lists headers or trailers as sorted key=value pairs, hiding the API key and session key
+func formatMetadata(md metadata.MD) string {
+	pairs := make([]string, 0, len(md))
+	for key, values := range md {
+		if secretHeaders[key] {
+			values = []string{"[redacted]"}
+		}
+		pairs = append(pairs, key+"="+strings.Join(values, ","))
+	}
+	slices.Sort(pairs)
+	return strings.Join(pairs, " ")
+}
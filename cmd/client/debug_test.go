@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/stats"
+)
+
+func TestFormatMetadata(t *testing.T) {
+	md := metadata.Pairs("authorization", "Bearer secret-key", "x-b", "2", "content-type", "application/grpc", "x-b", "3", "x-session-key", "c2Vzc2lvbi1rZXk=")
+	got := formatMetadata(md)
+	if want := "authorization=[redacted] content-type=application/grpc x-b=2,3 x-session-key=[redacted]"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestDebugStatsHandler(t *testing.T) {
+	var out strings.Builder
+	h := &debugStatsHandler{logger: slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug}))}
+	ctx := h.TagRPC(context.Background(), &stats.RPCTagInfo{FullMethodName: "/chat.ChatService/Chat"})
+
+	begin := time.Now()
+	app := &application{config: config{apiKey: "secret-key", sessionKey: "c2Vzc2lvbi1rZXk="}}
+	header, _ := metadata.FromOutgoingContext(app.addAuthContext(context.Background()))
+	h.HandleRPC(ctx, &stats.OutHeader{Compression: "zstd", Header: header})
+	h.HandleRPC(ctx, &stats.InPayload{Length: 120, WireLength: 80})
+	h.HandleRPC(ctx, &stats.End{BeginTime: begin, EndTime: begin.Add(time.Second), Error: errors.New("boom")})
+
+	logged := out.String()
+	for _, want := range []string{"method=/chat.ChatService/Chat", "compression=zstd", "authorization=[redacted]", "bytes=120 wire_bytes=80", "duration=1s error=boom"} {
+		if !strings.Contains(logged, want) {
+			t.Errorf("Expected %q in the log, got %q", want, logged)
+		}
+	}
+	if strings.Contains(logged, "secret-key") || strings.Contains(logged, "c2Vzc2lvbi1rZXk=") {
+		t.Errorf("Expected the API key and session key to be hidden, got %q", logged)
+	}
+}
+
+func TestLogTLSHandshake(t *testing.T) {
+	var out strings.Builder
+	app := &application{logger: slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug}))}
+	state := tls.ConnectionState{Version: tls.VersionTLS13, CipherSuite: tls.TLS_AES_128_GCM_SHA256, ServerName: "microchat.ai"}
+	if err := app.logTLSHandshake(state); err != nil {
+		t.Fatal(err)
+	}
+	if logged := out.String(); !strings.Contains(logged, "server_name=microchat.ai") || !strings.Contains(logged, "TLS 1.3 (TLS_AES_128_GCM_SHA256)") {
+		t.Errorf("Expected the handshake's details, got %q", logged)
+	}
+}
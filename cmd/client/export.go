@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	pb "microchat.ai/proto"
+)
+
+// exportFormats are the formats accepted by /export, with the file extension used for each
+var exportFormats = map[string]struct {
+	format pb.ExportFormat
+	ext    string
+}{
+	"md":       {pb.ExportFormat_EXPORT_MARKDOWN, ".md"},
+	"markdown": {pb.ExportFormat_EXPORT_MARKDOWN, ".md"},
+	"json":     {pb.ExportFormat_EXPORT_JSON, ".json"},
+}
+
+// isExportCommand reports whether input is "/export", with or without arguments
+func isExportCommand(input string) bool {
+	return input == exportCommand || strings.HasPrefix(input, exportCommand+" ")
+}
+
+// parseExportCommand reads "/export [md|json] [path]", defaulting to Markdown
+// Returns an empty path when none was given, so a name is generated
+func parseExportCommand(input string) (pb.ExportFormat, string, string, error) {
+	args := strings.TrimSpace(strings.TrimPrefix(input, exportCommand))
+	name, path, _ := strings.Cut(args, " ")
+	if name == "" {
+		name = "md"
+	}
+	f, ok := exportFormats[strings.ToLower(name)]
+	if !ok {
+		return 0, "", "", fmt.Errorf("usage: %s md|json [path]", exportCommand)
+	}
+	return f.format, f.ext, expandHome(strings.TrimSpace(path)), nil
+}
+
+// expandHome replaces a leading "~/" with the user's home directory, since commands aren't run through a shell
+func expandHome(path string) string {
+	if !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[2:])
+}
+
+// exportFileName is the default export file, e.g. microchat-1a2b3c4d-20260102-150405.md
+func exportFileName(sessionID, ext string, now time.Time) string {
+	short := sessionID
+	if len(short) > 8 {
+		short = short[:8]
+	}
+	return fmt.Sprintf("microchat-%s-%s%s", short, now.Format("20060102-150405"), ext)
+}
+
+// exportSession writes the session's transcript, rendered by the server with timestamps and metadata,
+// to path or a generated file in the current directory
+// Existing files are never overwritten
+func (app *application) exportSession(input string) error {
+	format, ext, path, err := parseExportCommand(input)
+	if err != nil {
+		fmt.Println(err)
+		return nil
+	}
+	if path == "" {
+		path = exportFileName(app.config.sessionID, ext, time.Now())
+	}
+
+	ctx := app.addAuthContext(context.Background())
+	resp, err := app.grpc.ExportSession(ctx, &pb.ExportSessionRequest{SessionId: app.config.sessionID, Format: format})
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		app.printError("can't write %s: %v", path, err)
+		return nil
+	}
+	content := resp.Content
+	if !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+		app.printError("can't write %s: %v", path, err)
+		return nil
+	}
+	if err := f.Close(); err != nil {
+		app.printError("can't write %s: %v", path, err)
+		return nil
+	}
+
+	fmt.Printf("Exported session to %s\n", path)
+	return nil
+}
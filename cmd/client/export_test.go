@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	pb "microchat.ai/proto"
+)
+
+// fakeExportClient renders every export as fixed content
+type fakeExportClient struct {
+	pb.ChatServiceClient
+	content string
+	req     *pb.ExportSessionRequest
+}
+
+func (f *fakeExportClient) ExportSession(ctx context.Context, in *pb.ExportSessionRequest, opts ...grpc.CallOption) (*pb.ExportSessionResponse, error) {
+	f.req = in
+	return &pb.ExportSessionResponse{SessionId: in.SessionId, Format: in.Format, Content: f.content}, nil
+}
+
+func TestParseExportCommand(t *testing.T) {
+	home, _ := os.UserHomeDir()
+	tests := []struct {
+		input  string
+		format pb.ExportFormat
+		ext    string
+		path   string
+	}{
+		{"/export", pb.ExportFormat_EXPORT_MARKDOWN, ".md", ""},
+		{"/export json", pb.ExportFormat_EXPORT_JSON, ".json", ""},
+		{"/export Markdown notes/chat one.md", pb.ExportFormat_EXPORT_MARKDOWN, ".md", "notes/chat one.md"},
+		{"/export json ~/chat.json", pb.ExportFormat_EXPORT_JSON, ".json", filepath.Join(home, "chat.json")},
+	}
+	for _, tt := range tests {
+		format, ext, path, err := parseExportCommand(tt.input)
+		if err != nil || format != tt.format || ext != tt.ext || path != tt.path {
+			t.Errorf("%q: expected %v %q %q, got %v %q %q (%v)", tt.input, tt.format, tt.ext, tt.path, format, ext, path, err)
+		}
+	}
+
+	if _, _, _, err := parseExportCommand("/export pdf"); err == nil {
+		t.Error("Expected an unknown format to be rejected")
+	}
+	if !isExportCommand("/export json") || isExportCommand("/exporter") {
+		t.Error("Unexpected export command detection")
+	}
+}
+
+func TestExportFileName(t *testing.T) {
+	got := exportFileName("1a2b3c4d-5e6f-4a5b-8c9d-0e1f2a3b4c5d", ".md", time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC))
+	if got != "microchat-1a2b3c4d-20260102-150405.md" {
+		t.Errorf("Unexpected file name %q", got)
+	}
+}
+
+func TestExportSession(t *testing.T) {
+	fake := &fakeExportClient{content: "# microchat.ai session session-1"}
+	app := &application{
+		config: config{sessionID: "session-1"},
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		grpc:   fake,
+	}
+
+	path := filepath.Join(t.TempDir(), "chat.md")
+	if err := app.exportSession("/export md " + path); err != nil {
+		t.Fatal(err)
+	}
+	if fake.req.SessionId != "session-1" || fake.req.Format != pb.ExportFormat_EXPORT_MARKDOWN {
+		t.Errorf("Unexpected request: %v", fake.req)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != fake.content+"\n" {
+		t.Errorf("Expected the rendered transcript, got %q (%v)", data, err)
+	}
+
+	// Existing files are left alone
+	fake.content = "replaced"
+	app.exportSession("/export json " + path)
+	if data, _ := os.ReadFile(path); string(data) == "replaced\n" {
+		t.Error("Expected an existing file not to be overwritten")
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	pb "microchat.ai/proto"
+)
+
+const (
+	healthTimeout = 10 * time.Second // Deadline for -check when -timeout isn't set
+	healthPings   = 3                // Health calls after the first, the fastest of which is the round trip
+)
+
+// healthReport describes the server as seen by -check
+type healthReport struct {
+	addr        string
+	transport   string        // Security of the connection, e.g. "TLS 1.3 (TLS_AES_128_GCM_SHA256)"
+	certificate string        // Server certificate, empty without TLS
+	requests    string        // Compressor requests were sent with
+	replies     string        // Compressor the server replied with
+	connect     time.Duration // First call, including dialing and the TLS handshake
+	roundTrip   time.Duration // Fastest call on the open connection
+}
+
+// checkHealth calls Health on the server and reports the connection's details
+func (app *application) checkHealth() (healthReport, error) {
+	timeout := app.config.timeout
+	if timeout <= 0 {
+		timeout = healthTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	report := healthReport{addr: app.config.serverAddr}
+	var p peer.Peer
+	for i := 0; i <= healthPings; i++ {
+		start := time.Now()
+		resp, err := app.grpc.Health(ctx, &pb.HealthRequest{}, grpc.Peer(&p))
+		elapsed := time.Since(start)
+		if err != nil {
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return report, status.Errorf(codes.DeadlineExceeded, "no reply from %s within %s", app.config.serverAddr, timeout)
+			}
+			return report, err
+		}
+		if !resp.Ok {
+			return report, status.Errorf(codes.Unavailable, "%s reports it isn't healthy", app.config.serverAddr)
+		}
+		switch {
+		case i == 0:
+			report.connect = elapsed
+		case report.roundTrip == 0 || elapsed < report.roundTrip:
+			report.roundTrip = elapsed
+		}
+	}
+
+	report.transport, report.certificate = describeTransport(p.AuthInfo)
+	report.requests = app.compressionLabel()
+	report.replies = app.metrics.getReplyCompression()
+	if report.replies == "" {
+		report.replies = noCompression
+	}
+	return report, nil
+}
+
+// describeTransport names the connection's security and the server's certificate
+func describeTransport(info credentials.AuthInfo) (transport, certificate string) {
+	tlsInfo, ok := info.(credentials.TLSInfo)
+	if !ok {
+		return "plaintext", ""
+	}
+	state := tlsInfo.State
+	transport = fmt.Sprintf("%s (%s)", tls.VersionName(state.Version), tls.CipherSuiteName(state.CipherSuite))
+	if state.NegotiatedProtocol != "" {
+		transport += ", ALPN " + state.NegotiatedProtocol
+	}
+	if len(state.PeerCertificates) == 0 {
+		return transport, ""
+	}
+	cert := state.PeerCertificates[0]
+	certificate = fmt.Sprintf("%s, issued by %s, expires %s", cert.Subject.CommonName, cert.Issuer.CommonName, cert.NotAfter.UTC().Format(time.DateOnly))
+	return transport, certificate
+}
+
+// write prints the report for -check
+func (r healthReport) write(out io.Writer) {
+	fmt.Fprintf(out, "Server:       %s OK\n", r.addr)
+	fmt.Fprintf(out, "Transport:    %s\n", r.transport)
+	if r.certificate != "" {
+		fmt.Fprintf(out, "Certificate:  %s\n", r.certificate)
+	}
+	fmt.Fprintf(out, "Compression:  requests %s, replies %s\n", r.requests, r.replies)
+	fmt.Fprintf(out, "Connect:      %s (first call, including the handshake)\n", r.connect.Round(time.Microsecond))
+	fmt.Fprintf(out, "Round trip:   %s (fastest of %d)\n", r.roundTrip.Round(time.Microsecond), healthPings)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	pb "microchat.ai/proto"
+)
+
+// fakeHealthClient answers Health as a server on a TLS connection would
+type fakeHealthClient struct {
+	pb.ChatServiceClient
+	ok    bool
+	err   error
+	calls int
+}
+
+func (f *fakeHealthClient) Health(ctx context.Context, in *pb.HealthRequest, opts ...grpc.CallOption) (*pb.HealthResponse, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	for _, opt := range opts {
+		if p, ok := opt.(grpc.PeerCallOption); ok {
+			p.PeerAddr.AuthInfo = credentials.TLSInfo{State: tls.ConnectionState{
+				Version:            tls.VersionTLS13,
+				CipherSuite:        tls.TLS_AES_128_GCM_SHA256,
+				NegotiatedProtocol: "h2",
+				PeerCertificates: []*x509.Certificate{{
+					Subject:  pkix.Name{CommonName: "microchat.ai"},
+					Issuer:   pkix.Name{CommonName: "R11"},
+					NotAfter: time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC),
+				}},
+			}}
+		}
+	}
+	return &pb.HealthResponse{Ok: f.ok}, nil
+}
+
+func TestCheckHealth(t *testing.T) {
+	newApp := func(fake *fakeHealthClient) *application {
+		return &application{
+			config: config{serverAddr: "microchat.ai:443", compress: "zstd"},
+			logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+			grpc:   fake,
+		}
+	}
+
+	fake := &fakeHealthClient{ok: true}
+	app := newApp(fake)
+	app.metrics.setReplyCompression("zstd")
+	report, err := app.checkHealth()
+	if err != nil {
+		t.Fatalf("Expected a healthy server, got %v", err)
+	}
+	if fake.calls != healthPings+1 {
+		t.Errorf("Expected %d Health calls, got %d", healthPings+1, fake.calls)
+	}
+	var out bytes.Buffer
+	report.write(&out)
+	for _, want := range []string{
+		"microchat.ai:443 OK",
+		"TLS 1.3 (TLS_AES_128_GCM_SHA256), ALPN h2",
+		"microchat.ai, issued by R11, expires 2026-12-01",
+		"requests zstd, replies zstd",
+		"Round trip:",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected the report to contain %q, got:\n%s", want, out.String())
+		}
+	}
+
+	if _, err := newApp(&fakeHealthClient{ok: false}).checkHealth(); oneShotExitCode(err) != exitUnavailable {
+		t.Errorf("Expected an unhealthy server to exit %d, got %v", exitUnavailable, err)
+	}
+	if _, err := newApp(&fakeHealthClient{err: status.Error(codes.Unavailable, "connection refused")}).checkHealth(); oneShotExitCode(err) != exitUnavailable {
+		t.Errorf("Expected an unreachable server to exit %d, got %v", exitUnavailable, err)
+	}
+}
+
+func TestDescribeTransportPlaintext(t *testing.T) {
+	if transport, certificate := describeTransport(nil); transport != "plaintext" || certificate != "" {
+		t.Errorf("Expected plaintext without a certificate, got %q, %q", transport, certificate)
+	}
+}
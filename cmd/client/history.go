@@ -0,0 +1,128 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// historyEntry is one message from GetHistory, split out of the server's
+// "<role> [HH:MM:SS UTC]: <text>" format
+type historyEntry struct {
+	role      string
+	timestamp string    // HH:MM:SS in UTC
+	at        time.Time // When the message was sent, zero from servers that only send timestamp
+	text      string
+}
+
+// parseHistoryCommand reads the optional message limit from "/history" or "/history N"
+// Returns 0 to show the whole history
+func parseHistoryCommand(input string) (int, error) {
+	arg := strings.TrimSpace(strings.TrimPrefix(input, historyCommand))
+	if arg == "" {
+		return 0, nil
+	}
+	limit, err := strconv.Atoi(arg)
+	if err != nil || limit <= 0 {
+		return 0, fmt.Errorf("usage: %s [N], where N is the number of recent messages to show", historyCommand)
+	}
+	return limit, nil
+}
+
+// isHistoryCommand reports whether input is "/history", with or without a limit
+func isHistoryCommand(input string) bool {
+	return input == historyCommand || strings.HasPrefix(input, historyCommand+" ")
+}
+
+// parseHistoryMessage splits a formatted history message into its role, timestamp and text
+func parseHistoryMessage(formatted string) (historyEntry, bool) {
+	role, rest, ok := strings.Cut(formatted, " [")
+	if !ok {
+		return historyEntry{}, false
+	}
+	timestamp, text, ok := strings.Cut(rest, " UTC]: ")
+	if !ok {
+		return historyEntry{}, false
+	}
+	return historyEntry{role: role, timestamp: timestamp, text: text}, true
+}
+
+// decryptHistoryText opens base64 ciphertext sealed by the server with the client-supplied session key
+// The server binds each message to its session ID, so the same ID must be given here
+func decryptHistoryText(encodedKey, sessionID, text string) (string, error) {
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return "", fmt.Errorf("invalid client key: %w", err)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(text)
+	if err != nil {
+		return "", fmt.Errorf("message is not base64 ciphertext: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(sessionID))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt message: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// historyRoleLabel is how a message's role is shown in /history output
+func historyRoleLabel(role string) string {
+	switch role {
+	case "user":
+		return "You"
+	case "assistant":
+		return "Assistant"
+	case "system":
+		return "System"
+	case "tool":
+		return "Tool"
+	default:
+		return role
+	}
+}
+
+// showHistory prints the session's messages, or only the last limit of them when limit > 0
+func (app *application) showHistory(limit int) error {
+	entries, err := app.conversationHistory()
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("No messages in this session yet")
+		return nil
+	}
+	shown := entries
+	if limit > 0 && limit < len(shown) {
+		shown = shown[len(shown)-limit:]
+	}
+
+	fmt.Printf("--- History: %d of %d messages ---\n", len(shown), len(entries))
+	now := time.Now()
+	for _, entry := range shown {
+		if entry.role == "" {
+			fmt.Println(entry.text) // Not in the server's format, shown as is
+			continue
+		}
+		fmt.Printf("[%s] %s: %s\n", messageTime(entry, app.config.timeFormat, now), app.theme.role(entry.role, historyRoleLabel(entry.role)), entry.text)
+	}
+	fmt.Println("---")
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+)
+
+func TestParseHistoryCommand(t *testing.T) {
+	for input, want := range map[string]int{"/history": 0, "/history 10": 10, "/history  3 ": 3} {
+		limit, err := parseHistoryCommand(input)
+		if err != nil || limit != want {
+			t.Errorf("%q: expected limit %d, got %d (%v)", input, want, limit, err)
+		}
+	}
+	for _, input := range []string{"/history ten", "/history 0", "/history -2"} {
+		if _, err := parseHistoryCommand(input); err == nil {
+			t.Errorf("%q: expected a usage error", input)
+		}
+	}
+	if isHistoryCommand("/historyx") || !isHistoryCommand("/history 5") {
+		t.Error("Expected only /history and /history N to be history commands")
+	}
+}
+
+func TestParseHistoryMessage(t *testing.T) {
+	entry, ok := parseHistoryMessage("assistant [14:03:21 UTC]: Sure: here [it] is")
+	if !ok || entry.role != "assistant" || entry.timestamp != "14:03:21" || entry.text != "Sure: here [it] is" {
+		t.Errorf("Unexpected entry: %+v (ok %v)", entry, ok)
+	}
+	if _, ok := parseHistoryMessage("not a history message"); ok {
+		t.Error("Expected unformatted text to be rejected")
+	}
+}
+
+func TestDecryptHistoryText(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+	encodedKey := base64.StdEncoding.EncodeToString(key)
+
+	// Seal the way the server does: nonce || ciphertext, with the session ID as additional data
+	block, _ := aes.NewCipher(key)
+	aead, _ := cipher.NewGCM(block)
+	nonce := make([]byte, aead.NonceSize())
+	rand.Read(nonce)
+	sealed := base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte("hello"), []byte("session-1")))
+
+	text, err := decryptHistoryText(encodedKey, "session-1", sealed)
+	if err != nil || text != "hello" {
+		t.Errorf("Expected decrypted text, got %q (%v)", text, err)
+	}
+	if _, err := decryptHistoryText(encodedKey, "session-2", sealed); err == nil {
+		t.Error("Expected a message from another session to fail to decrypt")
+	}
+	if _, err := decryptHistoryText(encodedKey, "session-1", "not base64!"); err == nil {
+		t.Error("Expected an error for text that isn't ciphertext")
+	}
+}
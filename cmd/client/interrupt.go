@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// inFlight tracks the request being waited on, so Ctrl+C can cancel it instead of exiting the client
+type inFlight struct {
+	mu        sync.Mutex
+	cancel    context.CancelFunc // nil when no request is in flight
+	cancelled bool               // Ctrl+C has already cancelled the request in flight
+	held      bool               // Ctrl+C belongs to a program in the foreground, such as $PAGER
+}
+
+// start returns a context for a request that interrupt can cancel, and a function to call once the
+// request has finished
+func (f *inFlight) start(parent context.Context) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(parent)
+	f.mu.Lock()
+	f.cancel, f.cancelled = cancel, false
+	f.mu.Unlock()
+
+	return ctx, func() {
+		f.mu.Lock()
+		f.cancel, f.cancelled = nil, false
+		f.mu.Unlock()
+		cancel()
+	}
+}
+
+// hold leaves Ctrl+C to a program in the foreground until the returned function is called, so the
+// client neither cancels nor exits
+func (f *inFlight) hold() func() {
+	f.mu.Lock()
+	f.held = true
+	f.mu.Unlock()
+	return func() {
+		f.mu.Lock()
+		f.held = false
+		f.mu.Unlock()
+	}
+}
+
+// interrupt cancels the request in flight, reporting false when there's none or it was already
+// cancelled, so a second Ctrl+C exits
+// Ctrl+C is ignored, but reported as handled, while held
+func (f *inFlight) interrupt() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.held {
+		return true
+	}
+	if f.cancel == nil || f.cancelled {
+		return false
+	}
+	f.cancel()
+	f.cancelled = true
+	return true
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	pb "microchat.ai/proto"
+)
+
+// slowChatClient doesn't reply to Chat until the request is cancelled
+type slowChatClient struct {
+	pb.ChatServiceClient
+	started chan struct{}
+}
+
+func (f *slowChatClient) Chat(ctx context.Context, in *pb.ChatRequest, opts ...grpc.CallOption) (*pb.ChatResponse, error) {
+	close(f.started)
+	<-ctx.Done()
+	return nil, status.FromContextError(ctx.Err()).Err()
+}
+
+func TestInFlight(t *testing.T) {
+	var f inFlight
+	if f.interrupt() {
+		t.Error("Expected nothing to cancel without a request in flight")
+	}
+
+	ctx, done := f.start(context.Background())
+	if !f.interrupt() || ctx.Err() == nil {
+		t.Error("Expected the first interrupt to cancel the request")
+	}
+	if f.interrupt() {
+		t.Error("Expected a second interrupt to be left to exit the client")
+	}
+	done()
+	if f.interrupt() {
+		t.Error("Expected nothing to cancel once the request is done")
+	}
+
+	// While held, Ctrl+C belongs to the program in the foreground
+	release := f.hold()
+	if !f.interrupt() || !f.interrupt() {
+		t.Error("Expected interrupts to be ignored rather than exit while held")
+	}
+	release()
+	if f.interrupt() {
+		t.Error("Expected an interrupt to exit once released")
+	}
+}
+
+func TestSendMessageCancelled(t *testing.T) {
+	fake := &slowChatClient{started: make(chan struct{})}
+	app := &application{
+		config: config{sessionID: "session-1"},
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		grpc:   fake,
+	}
+
+	result := make(chan error, 1)
+	go func() { result <- app.sendMessage("hello") }()
+	<-fake.started
+	if !app.inFlight.interrupt() {
+		t.Fatal("Expected the request to be cancellable")
+	}
+
+	select {
+	case err := <-result:
+		if !errors.Is(err, errCancelled) {
+			t.Errorf("Expected the request to be cancelled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected sendMessage to return once cancelled")
+	}
+}
+
+func TestSendMessageTimeout(t *testing.T) {
+	app := &application{
+		config: config{sessionID: "session-1", timeout: 20 * time.Millisecond},
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		grpc:   &slowChatClient{started: make(chan struct{})},
+	}
+	err := app.sendMessage("hello")
+	if status.Code(err) != codes.DeadlineExceeded || !strings.Contains(status.Convert(err).Message(), "-timeout") {
+		t.Errorf("Expected a timeout pointing at -timeout, got %v", err)
+	}
+
+	// One-shot requests time out the same way and exit as retryable
+	app.grpc = &slowChatClient{started: make(chan struct{})}
+	err = app.runOneShot(io.Discard, "hello")
+	if oneShotExitCode(err) != exitUnavailable {
+		t.Errorf("Expected exit code %d, got %d (%v)", exitUnavailable, oneShotExitCode(err), err)
+	}
+}
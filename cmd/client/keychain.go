@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/zalando/go-keyring"
+
+	"microchat.ai/cmd/client/lineedit"
+)
+
+// Commands that manage the API key kept in the OS keychain: macOS Keychain, Secret Service on
+// Linux or Windows Credential Manager
+const (
+	loginCommand  = "login"
+	logoutCommand = "logout"
+)
+
+// keychainService names the client's keychain entries, one per profile
+const keychainService = "microchat.ai"
+
+// keychainAccount is the keychain entry holding profile's key, "default" without a profile
+func keychainAccount(profile string) string {
+	if profile == "" {
+		return "default"
+	}
+	return profile
+}
+
+// keychainKey returns the key "login" stored for profile, or the default entry's when the profile has none
+// Returns "" when no key is stored or the keychain can't be used
+func keychainKey(profile string, logger *slog.Logger) string {
+	accounts := []string{keychainAccount(profile)}
+	if profile != "" {
+		accounts = append(accounts, keychainAccount(""))
+	}
+	for _, account := range accounts {
+		key, err := keyring.Get(keychainService, account)
+		if err == nil {
+			return key
+		}
+		if !errors.Is(err, keyring.ErrNotFound) {
+			logger.Debug("can't read the API key from the keychain", "error", err)
+			return ""
+		}
+	}
+	return ""
+}
+
+// runLoginMode stores an API key in the keychain for "login", or deletes it for "logout", and
+// returns the process exit code
+// The key is typed without echo, or piped in, so it doesn't end up in shell history
+func runLoginMode(command, profile string, args []string, in *os.File, out io.Writer) int {
+	if len(args) != 0 {
+		fmt.Fprintf(os.Stderr, "usage: microchat-client [-profile name] %s\n", command)
+		return exitUsage
+	}
+	account := keychainAccount(profile)
+
+	if command == logoutCommand {
+		err := keyring.Delete(keychainService, account)
+		switch {
+		case errors.Is(err, keyring.ErrNotFound):
+			fmt.Fprintf(out, "No API key stored for %s\n", account)
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "Error: can't remove the API key from the keychain: %v\n", err)
+			return exitError
+		default:
+			fmt.Fprintf(out, "Removed the API key for %s from the keychain\n", account)
+		}
+		return 0
+	}
+
+	prompt := "" // Piped keys are read quietly
+	if lineedit.IsTerminal(in) {
+		prompt = fmt.Sprintf("API key for %s: ", account)
+	}
+	key, err := lineedit.New(in, out, nil).ReadSecret(prompt)
+	key = strings.TrimSpace(key)
+	if err != nil && !errors.Is(err, io.EOF) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+	if key == "" || strings.ContainsAny(key, " \t") {
+		fmt.Fprintln(os.Stderr, "Error: no valid API key given")
+		return exitUsage
+	}
+	if err := keyring.Set(keychainService, account, key); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: can't store the API key in the keychain: %v\n", err)
+		return exitError
+	}
+
+	fmt.Fprintf(out, "Stored the API key for %s in the keychain\n", account)
+	if os.Getenv("MICROCHAT_API_KEY") != "" {
+		fmt.Fprintln(out, "MICROCHAT_API_KEY is still set and takes precedence; remove it from your shell profile and .env to use the stored key")
+	}
+	return 0
+}
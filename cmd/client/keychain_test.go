@@ -0,0 +1,75 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/zalando/go-keyring"
+)
+
+// pipedInput returns a file reading text, as when a key is piped to "login"
+func pipedInput(t *testing.T, text string) *os.File {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.Close() })
+	if _, err := w.WriteString(text); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	return r
+}
+
+func TestLoginAndLogout(t *testing.T) {
+	keyring.MockInit()
+	t.Setenv("MICROCHAT_API_KEY", "")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	if key := keychainKey("", logger); key != "" {
+		t.Errorf("Expected no stored key, got %q", key)
+	}
+
+	var out strings.Builder
+	if code := runLoginMode(loginCommand, "", nil, pipedInput(t, "default-key\n"), &out); code != 0 {
+		t.Fatalf("Expected login to succeed, got exit code %d", code)
+	}
+	if !strings.Contains(out.String(), "Stored the API key for default") {
+		t.Errorf("Unexpected output: %q", out.String())
+	}
+	if code := runLoginMode(loginCommand, "work", nil, pipedInput(t, "work-key"), io.Discard); code != 0 {
+		t.Fatalf("Expected login to succeed, got exit code %d", code)
+	}
+
+	// A profile's own key wins, and profiles without one use the default key
+	if key := keychainKey("work", logger); key != "work-key" {
+		t.Errorf("Expected the profile's key, got %q", key)
+	}
+	if key := keychainKey("local", logger); key != "default-key" {
+		t.Errorf("Expected the default key, got %q", key)
+	}
+
+	out.Reset()
+	if code := runLoginMode(logoutCommand, "work", nil, nil, &out); code != 0 || !strings.Contains(out.String(), "Removed") {
+		t.Errorf("Expected logout to remove the key, got exit code %d: %q", code, out.String())
+	}
+	if key := keychainKey("work", logger); key != "default-key" {
+		t.Errorf("Expected the default key once the profile's is removed, got %q", key)
+	}
+	out.Reset()
+	if code := runLoginMode(logoutCommand, "work", nil, nil, &out); code != 0 || !strings.Contains(out.String(), "No API key stored") {
+		t.Errorf("Expected nothing to remove, got exit code %d: %q", code, out.String())
+	}
+
+	// Empty keys and extra arguments are usage errors
+	if code := runLoginMode(loginCommand, "", nil, pipedInput(t, "\n"), io.Discard); code != exitUsage {
+		t.Errorf("Expected an empty key to be rejected, got exit code %d", code)
+	}
+	if code := runLoginMode(loginCommand, "", []string{"key"}, nil, io.Discard); code != exitUsage {
+		t.Errorf("Expected a key argument to be rejected, got exit code %d", code)
+	}
+}
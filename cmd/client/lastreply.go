@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// isSaveCommand reports whether input is "/save", with or without a path
+func isSaveCommand(input string) bool {
+	return input == saveCommand || strings.HasPrefix(input, saveCommand+" ")
+}
+
+// isPipeCommand reports whether input is "/pipe", with or without a command
+func isPipeCommand(input string) bool {
+	return input == pipeCommand || strings.HasPrefix(input, pipeCommand+" ")
+}
+
+// latestReply returns the session's most recent assistant reply
+// A resumed session's reply is taken from its history, since it wasn't received in this run
+func (app *application) latestReply() (string, error) {
+	if app.lastReply != "" || app.messageIndex == 0 {
+		return app.lastReply, nil
+	}
+	entries, err := app.conversationHistory()
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 0 || entries[len(entries)-1].role != "assistant" {
+		return "", nil
+	}
+	return entries[len(entries)-1].text, nil
+}
+
+// saveReply writes the most recent reply to the path in "/save <path>", exactly as received
+// Existing files are never overwritten
+func (app *application) saveReply(input string) error {
+	path := expandHome(strings.TrimSpace(strings.TrimPrefix(input, saveCommand)))
+	if path == "" {
+		fmt.Printf("usage: %s path\n", saveCommand)
+		return nil
+	}
+	reply, err := app.latestReply()
+	if err != nil {
+		return err
+	}
+	if reply == "" {
+		fmt.Println("No reply to save yet")
+		return nil
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		app.printError("can't write %s: %v", path, err)
+		return nil
+	}
+	if !strings.HasSuffix(reply, "\n") {
+		reply += "\n"
+	}
+	if _, err := f.WriteString(reply); err != nil {
+		f.Close()
+		app.printError("can't write %s: %v", path, err)
+		return nil
+	}
+	if err := f.Close(); err != nil {
+		app.printError("can't write %s: %v", path, err)
+		return nil
+	}
+
+	fmt.Printf("Saved the last reply to %s\n", path)
+	return nil
+}
+
+// pipeReply runs the shell command in "/pipe <command>" with the most recent reply on its stdin, e.g.
+// "/pipe pbcopy" or "/pipe python3"
+// The command's output goes to the terminal, and Ctrl+C stops it rather than exiting the client
+func (app *application) pipeReply(input string) error {
+	command := strings.TrimSpace(strings.TrimPrefix(input, pipeCommand))
+	if command == "" {
+		fmt.Printf("usage: %s command\n", pipeCommand)
+		return nil
+	}
+	reply, err := app.latestReply()
+	if err != nil {
+		return err
+	}
+	if reply == "" {
+		fmt.Println("No reply to pipe yet")
+		return nil
+	}
+
+	ctx, done := app.inFlight.start(context.Background())
+	defer done()
+	cmd := shellCommand(ctx, command)
+	cmd.Stdin = strings.NewReader(reply)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	err = cmd.Run()
+	switch {
+	case errors.Is(ctx.Err(), context.Canceled):
+		fmt.Println("command stopped")
+	case err != nil:
+		app.printError("%s: %v", command, err)
+	}
+	return nil
+}
+
+// shellCommand runs command with the user's shell, so pipes and quoting work as typed
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+	return exec.CommandContext(ctx, shell, "-c", command)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveAndPipeReply(t *testing.T) {
+	app := &application{
+		config:    config{sessionID: "session-1"},
+		logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+		lastReply: "package main\n\nfunc main() {}",
+	}
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "main.go")
+	if err := app.saveReply(saveCommand + " " + path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "package main\n\nfunc main() {}\n" {
+		t.Errorf("Expected the reply to be saved, got %q, %v", data, err)
+	}
+
+	// An existing file is left alone
+	app.lastReply = "replaced"
+	app.saveReply(saveCommand + " " + path)
+	if data, _ := os.ReadFile(path); string(data) == "replaced\n" {
+		t.Error("Expected an existing file not to be overwritten")
+	}
+
+	piped := filepath.Join(dir, "piped.txt")
+	app.pipeReply(pipeCommand + " tr a-z A-Z > " + piped)
+	if data, err := os.ReadFile(piped); err != nil || string(data) != "REPLACED" {
+		t.Errorf("Expected the reply on the command's stdin, got %q, %v", data, err)
+	}
+}
+
+func TestLatestReplyFromHistory(t *testing.T) {
+	// A resumed session's reply comes from its history
+	app := &application{
+		config:       config{sessionID: "session-1"},
+		logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+		grpc:         &flakyChatClient{history: []string{"user [10:00:00 UTC]: hi", "assistant [10:00:01 UTC]: Echo: hi"}},
+		messageIndex: 2,
+	}
+	if reply, err := app.latestReply(); err != nil || reply != "Echo: hi" {
+		t.Errorf("Expected the stored reply, got %q, %v", reply, err)
+	}
+
+	// A new session has no reply, without asking the server
+	app = &application{logger: app.logger}
+	if reply, err := app.latestReply(); err != nil || reply != "" {
+		t.Errorf("Expected no reply, got %q, %v", reply, err)
+	}
+}
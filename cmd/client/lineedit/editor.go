@@ -0,0 +1,366 @@
+// Package lineedit reads interactive input lines with cursor movement, shell-style editing
+// shortcuts and a persistent history
+//
+// Supported keys: Left/Right (Ctrl+B/F) move the cursor, Home/End (Ctrl+A/E) jump to the ends,
+// Up/Down (Ctrl+P/N) browse history, Backspace/Delete delete a character, Ctrl+W deletes the
+// previous word, Ctrl+U/K delete to the start/end of the line, Ctrl+L clears the screen,
+// Ctrl+D on an empty line ends input and Ctrl+C interrupts
+//
+// Alt+Enter starts a new row within the line, and pasted text keeps its newlines on terminals
+// that support bracketed paste, so multi-line input is submitted as one line by Enter
+//
+// Editor.Page shows text too long for the terminal a screen at a time, with search
+package lineedit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ContinuationPrompt is shown before the second and later rows of multi-line input
+const ContinuationPrompt = "... "
+
+// Terminal sequences for bracketed paste, where the terminal wraps pasted text in ESC[200~ and
+// ESC[201~ so newlines in it aren't taken as Enter
+const (
+	enableBracketedPaste  = "\x1b[?2004h"
+	disableBracketedPaste = "\x1b[?2004l"
+)
+
+// ErrInterrupted is returned by ReadLine when the user presses Ctrl+C
+var ErrInterrupted = errors.New("interrupted")
+
+// Control keys as read from a terminal in raw mode
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyCtrlH     = 8
+	keyCtrlK     = 11
+	keyCtrlL     = 12
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlU     = 21
+	keyCtrlW     = 23
+	keyEscape    = 27
+	keyBackspace = 127
+)
+
+// Editor reads lines from a terminal, falling back to plain line reading when input isn't one
+type Editor struct {
+	in      *bufio.Reader
+	out     io.Writer
+	fd      int // Terminal file descriptor, -1 when input isn't a terminal
+	history *History
+
+	browse int    // History entry being shown, history.Len() for the line being typed
+	draft  string // The line being typed, kept while browsing history
+	row    int    // Row of the terminal cursor within the line as last drawn
+	paste  bool   // Inside bracketed paste, where newlines are text rather than Enter
+
+	mu    sync.Mutex
+	saved *termState // Terminal state to restore, nil when not in raw mode
+}
+
+// New returns an editor reading from in and echoing to out, recalling lines from history
+func New(in *os.File, out io.Writer, history *History) *Editor {
+	fd := -1
+	if IsTerminal(in) {
+		fd = int(in.Fd())
+	}
+	return &Editor{in: bufio.NewReader(in), out: out, fd: fd, history: history}
+}
+
+// IsTerminal reports whether f is a terminal rather than a pipe or file
+func IsTerminal(f *os.File) bool {
+	return isTerminal(int(f.Fd()))
+}
+
+// Size returns the width and height in characters of the terminal f refers to
+func Size(f *os.File) (width, height int, ok bool) {
+	width, height, err := terminalSize(int(f.Fd()))
+	return width, height, err == nil && width > 0 && height > 0
+}
+
+// Interactive reports whether input is a terminal, so lines can be edited
+func (e *Editor) Interactive() bool {
+	return e.fd >= 0
+}
+
+// ReadLine shows prompt and returns the next line without its line ending
+// The prompt may contain ANSI color sequences, which aren't counted towards its width
+// Returns io.EOF at the end of input (Ctrl+D on an empty line) and ErrInterrupted on Ctrl+C
+func (e *Editor) ReadLine(prompt string) (string, error) {
+	if e.fd < 0 {
+		return e.readPlain(prompt)
+	}
+	if err := e.makeRaw(); err != nil {
+		return e.readPlain(prompt)
+	}
+	defer e.Restore()
+	return e.edit(prompt)
+}
+
+// AddHistory records an entered line; lines read from a pipe aren't recorded
+func (e *Editor) AddHistory(line string) error {
+	if e.fd < 0 {
+		return nil
+	}
+	return e.history.Add(line)
+}
+
+// Restore puts the terminal back in its original mode if ReadLine left it in raw mode
+// Safe to call from a signal handler goroutine before exiting
+func (e *Editor) Restore() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.saved == nil {
+		return
+	}
+	fmt.Fprint(e.out, disableBracketedPaste)
+	restoreTerminal(e.fd, e.saved)
+	e.saved = nil
+}
+
+func (e *Editor) makeRaw() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	saved, err := makeRaw(e.fd)
+	if err != nil {
+		return err
+	}
+	e.saved = saved
+	fmt.Fprint(e.out, enableBracketedPaste)
+	return nil
+}
+
+// readPlain reads a line without editing, for piped input
+func (e *Editor) readPlain(prompt string) (string, error) {
+	fmt.Fprint(e.out, prompt)
+	s, err := e.in.ReadString('\n')
+	if err != nil && (err != io.EOF || s == "") {
+		return "", err
+	}
+	return strings.TrimRight(s, "\r\n"), nil
+}
+
+// edit runs the editing loop on a terminal in raw mode
+func (e *Editor) edit(prompt string) (string, error) {
+	var l line
+	e.browse, e.draft, e.row, e.paste = e.history.Len(), "", 0, false
+	e.refresh(prompt, &l)
+
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			if err == io.EOF && len(l.buf) > 0 {
+				fmt.Fprint(e.out, "\r\n")
+				return l.String(), nil
+			}
+			return "", err
+		}
+
+		if e.paste && (r == '\r' || r == '\n') {
+			l.insert('\n')
+			continue
+		}
+
+		switch r {
+		case '\r', '\n':
+			l.pos = len(l.buf)
+			e.refresh(prompt, &l)
+			fmt.Fprint(e.out, "\r\n")
+			return l.String(), nil
+		case keyCtrlC:
+			fmt.Fprint(e.out, "^C\r\n")
+			return "", ErrInterrupted
+		case keyCtrlD:
+			if len(l.buf) == 0 {
+				fmt.Fprint(e.out, "\r\n")
+				return "", io.EOF
+			}
+			l.deleteForward()
+		case keyCtrlA:
+			l.home()
+		case keyCtrlE:
+			l.end()
+		case keyCtrlB:
+			l.left()
+		case keyCtrlF:
+			l.right()
+		case keyCtrlW:
+			l.deleteWord()
+		case keyCtrlU:
+			l.killToStart()
+		case keyCtrlK:
+			l.killToEnd()
+		case keyCtrlP:
+			e.historyPrev(&l)
+		case keyCtrlN:
+			e.historyNext(&l)
+		case keyCtrlL:
+			fmt.Fprint(e.out, "\x1b[H\x1b[2J")
+			e.row = 0
+		case keyBackspace, keyCtrlH:
+			l.backspace()
+		case keyEscape:
+			e.escape(&l)
+		default:
+			if unicode.IsPrint(r) || r == '\t' {
+				l.insert(r)
+			}
+		}
+		if !e.paste {
+			e.refresh(prompt, &l) // Pasted text is drawn once the paste ends
+		}
+	}
+}
+
+// escape handles the rest of an escape sequence: arrow keys, Home, End, Delete, Alt+Enter and
+// bracketed paste markers
+// Sequences the editor doesn't know are read in full and ignored
+func (e *Editor) escape(l *line) {
+	r, _, err := e.in.ReadRune()
+	if err != nil {
+		return
+	}
+	if r == '\r' || r == '\n' {
+		l.insert('\n') // Alt+Enter
+		return
+	}
+	if r != '[' && r != 'O' {
+		return
+	}
+
+	// CSI sequences are parameter bytes followed by a final byte in 0x40-0x7e, e.g. "3~" or "A"
+	var params strings.Builder
+	for {
+		r, _, err = e.in.ReadRune()
+		if err != nil {
+			return
+		}
+		if r >= 0x40 && r <= 0x7e {
+			break
+		}
+		params.WriteRune(r)
+	}
+
+	switch r {
+	case 'A':
+		e.historyPrev(l)
+	case 'B':
+		e.historyNext(l)
+	case 'C':
+		l.right()
+	case 'D':
+		l.left()
+	case 'H':
+		l.home()
+	case 'F':
+		l.end()
+	case '~':
+		switch params.String() {
+		case "1", "7":
+			l.home()
+		case "4", "8":
+			l.end()
+		case "3":
+			l.deleteForward()
+		case "200":
+			e.paste = true
+		case "201":
+			e.paste = false
+		}
+	}
+}
+
+// historyPrev replaces the line with the previous history entry, saving the line being typed
+func (e *Editor) historyPrev(l *line) {
+	if e.browse == 0 {
+		return
+	}
+	if e.browse == e.history.Len() {
+		e.draft = l.String()
+	}
+	e.browse--
+	l.set(e.history.Entry(e.browse))
+}
+
+// historyNext replaces the line with the next history entry, or the line being typed after the last one
+func (e *Editor) historyNext(l *line) {
+	if e.browse >= e.history.Len() {
+		return
+	}
+	e.browse++
+	if e.browse == e.history.Len() {
+		l.set(e.draft)
+		return
+	}
+	l.set(e.history.Entry(e.browse))
+}
+
+// refresh redraws the prompt and line, leaving the terminal cursor at the edit position
+// Rows after the first are drawn after ContinuationPrompt
+func (e *Editor) refresh(prompt string, l *line) {
+	var b strings.Builder
+	if e.row > 0 {
+		fmt.Fprintf(&b, "\x1b[%dA", e.row)
+	}
+	b.WriteString("\r\x1b[J")
+	rows := strings.Split(l.String(), "\n")
+	for i, text := range rows {
+		if i == 0 {
+			b.WriteString(prompt)
+		} else {
+			b.WriteString("\r\n" + ContinuationPrompt)
+		}
+		b.WriteString(text)
+	}
+
+	// The terminal cursor is now at the end of the last row; move it back to the edit position
+	row, col := l.cursor()
+	if up := len(rows) - 1 - row; up > 0 {
+		fmt.Fprintf(&b, "\x1b[%dA", up)
+	}
+	if row == 0 {
+		col += displayWidth(prompt)
+	} else {
+		col += utf8.RuneCountInString(ContinuationPrompt)
+	}
+	b.WriteString("\r")
+	if col > 0 {
+		fmt.Fprintf(&b, "\x1b[%dC", col)
+	}
+	e.row = row
+	io.WriteString(e.out, b.String())
+}
+
+// displayWidth returns the number of columns s takes up, skipping ANSI escape sequences such as colors
+func displayWidth(s string) int {
+	width := 0
+	for i := 0; i < len(s); {
+		if s[i] == keyEscape && i+1 < len(s) && s[i+1] == '[' {
+			// CSI sequences end with a final byte in 0x40-0x7e
+			i += 2
+			for i < len(s) && (s[i] < 0x40 || s[i] > 0x7e) {
+				i++
+			}
+			i++
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		width++
+		i += size
+	}
+	return width
+}
@@ -0,0 +1,194 @@
+package lineedit
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// newTestEditor returns an editor driven by scripted key presses, as if in raw mode
+func newTestEditor(keys string, history *History) *Editor {
+	return &Editor{in: bufio.NewReader(strings.NewReader(keys)), out: io.Discard, fd: 0, history: history}
+}
+
+func TestEditorEditing(t *testing.T) {
+	tests := []struct {
+		name string
+		keys string
+		want string
+	}{
+		{"plain", "hello\r", "hello"},
+		{"backspace", "helo\x7f\x7fllo\r", "hello"},
+		{"ctrl+a inserts at start", "world\x01hello \r", "hello world"},
+		{"ctrl+e after moving", "ac\x01\x06b\x05d\r", "abcd"},
+		{"arrow keys", "ac\x1b[Db\x1b[C!\r", "abc!"},
+		{"home and end keys", "bc\x1b[Ha\x1b[4~d\r", "abcd"},
+		{"delete key", "abxc\x1b[D\x1b[D\x1b[3~\r", "abc"},
+		{"ctrl+w deletes previous word", "one two  \x17three\r", "one three"},
+		{"ctrl+w mid-line", "one two three\x1b[D\x1b[D\x1b[D\x1b[D\x1b[D\x17\r", "one three"},
+		{"ctrl+u", "junk\x15kept\r", "kept"},
+		{"ctrl+k", "keptjunk\x1b[D\x1b[D\x1b[D\x1b[D\x0b\r", "kept"},
+		{"ctrl+d deletes forward", "abc\x01\x04\r", "bc"},
+		{"unicode", "héllo\x7f\x7f\x7f\x7fello wörld\r", "hello wörld"},
+		{"unknown escape ignored", "a\x1b[15~b\r", "ab"},
+		{"eof after text", "partial", "partial"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEditor(tt.keys, NewHistory("", 10))
+			got, err := e.edit("> ")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestEditorControlKeys(t *testing.T) {
+	if _, err := newTestEditor("abc\x03", NewHistory("", 10)).edit("> "); !errors.Is(err, ErrInterrupted) {
+		t.Errorf("Expected Ctrl+C to interrupt, got %v", err)
+	}
+	if _, err := newTestEditor("\x04", NewHistory("", 10)).edit("> "); err != io.EOF {
+		t.Errorf("Expected Ctrl+D on an empty line to end input, got %v", err)
+	}
+}
+
+func TestEditorHistory(t *testing.T) {
+	history := NewHistory("", 10)
+	history.Add("first")
+	history.Add("second")
+
+	tests := []struct {
+		name string
+		keys string
+		want string
+	}{
+		{"up recalls latest", "\x1b[A\r", "second"},
+		{"up twice", "\x1b[A\x1b[A\r", "first"},
+		{"up stops at oldest", "\x1b[A\x1b[A\x1b[A\r", "first"},
+		{"down returns to draft", "draft\x1b[A\x1b[A\x1b[B\x1b[B\r", "draft"},
+		{"recalled entry can be edited", "\x10!\r", "second!"},
+		{"application cursor keys", "\x1bOA\x1bOA\r", "first"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newTestEditor(tt.keys, history).edit("> ")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestEditorRefresh(t *testing.T) {
+	var out strings.Builder
+	e := newTestEditor("", NewHistory("", 10))
+	e.out = &out
+	e.refresh("> ", &line{buf: []rune("abcd"), pos: 1})
+	if want := "\r\x1b[J> abcd\r\x1b[3C"; out.String() != want {
+		t.Errorf("Expected %q, got %q", want, out.String())
+	}
+
+	// Later rows follow the continuation prompt, and the next redraw starts from the first row
+	out.Reset()
+	e.refresh("> ", &line{buf: []rune("ab\ncd\nef"), pos: 4})
+	if want := "\r\x1b[J> ab\r\n... cd\r\n... ef\x1b[1A\r\x1b[5C"; out.String() != want {
+		t.Errorf("Expected %q, got %q", want, out.String())
+	}
+	out.Reset()
+	e.refresh("> ", &line{})
+	if want := "\x1b[1A\r\x1b[J> \r\x1b[2C"; out.String() != want {
+		t.Errorf("Expected %q, got %q", want, out.String())
+	}
+
+	// Color sequences in the prompt take up no columns
+	out.Reset()
+	e.refresh("\x1b[1;36m> \x1b[0m", &line{buf: []rune("ab"), pos: 2})
+	if want := "\r\x1b[J\x1b[1;36m> \x1b[0mab\r\x1b[4C"; out.String() != want {
+		t.Errorf("Expected %q, got %q", want, out.String())
+	}
+}
+
+func TestEditorMultiline(t *testing.T) {
+	tests := []struct {
+		name string
+		keys string
+		want string
+	}{
+		{"alt+enter", "one\x1b\rtwo\r", "one\ntwo"},
+		{"bracketed paste", "\x1b[200~a\r\nb\rc\x1b[201~ d\r", "a\n\nb\nc d"},
+		{"enter submits after paste", "\x1b[200~x\x1b[201~\rleft over", "x"},
+		{"ctrl+a and ctrl+k work on the current row", "ab\x1b\rcd\x01X\x0b\r", "ab\nX"},
+		{"ctrl+u keeps earlier rows", "ab\x1b\rcd\x15\r", "ab\n"},
+		{"enter from an earlier row submits everything", "ab\x1b\rcd\x1b[D\x1b[D\x1b[D\r", "ab\ncd"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newTestEditor(tt.keys, NewHistory("", 10)).edit("> ")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestEditorReadPlain(t *testing.T) {
+	var out strings.Builder
+	e := &Editor{in: bufio.NewReader(strings.NewReader("one\r\ntwo")), out: &out, fd: -1, history: NewHistory("", 10)}
+	for _, want := range []string{"one", "two"} {
+		got, err := e.ReadLine("> ")
+		if err != nil || got != want {
+			t.Errorf("Expected %q, got %q (%v)", want, got, err)
+		}
+	}
+	if _, err := e.ReadLine("> "); err != io.EOF {
+		t.Errorf("Expected EOF, got %v", err)
+	}
+	if out.String() != "> > > " {
+		t.Errorf("Expected a prompt per line, got %q", out.String())
+	}
+
+	// Piped input isn't recorded in the history
+	e.AddHistory("one")
+	if e.history.Len() != 0 {
+		t.Errorf("Expected piped lines not to be recorded, got %d entries", e.history.Len())
+	}
+}
+
+func TestReadSecret(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    string
+		want    string
+		wantErr error
+	}{
+		{"typed", "s3cret\r", "s3cret", nil},
+		{"backspace and ctrl+u", "junk\x15kex\x7fy\r", "key", nil},
+		{"pasted", "\x1b[200~pasted-key\x1b[201~\r", "pasted-key", nil},
+		{"ctrl+c", "abc\x03", "", ErrInterrupted},
+		{"ctrl+d on empty input", "\x04", "", io.EOF},
+		{"eof after text", "partial", "partial", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newTestEditor(tt.keys, nil).readSecret()
+			if got != tt.want || !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %q, %v; got %q, %v", tt.want, tt.wantErr, got, err)
+			}
+		})
+	}
+}
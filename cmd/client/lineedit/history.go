@@ -0,0 +1,143 @@
+package lineedit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// History is the list of previously entered lines, optionally persisted to a file
+// The file holds one entry per line, with backslashes and newlines escaped so multi-line
+// entries survive a round trip
+type History struct {
+	path    string // Empty to keep history in memory only
+	max     int    // Entries kept, oldest dropped first
+	entries []string
+}
+
+// NewHistory returns an empty history persisted to path, keeping at most max entries
+func NewHistory(path string, max int) *History {
+	return &History{path: path, max: max}
+}
+
+// Load reads the history file, compacting it when it has grown past the limit
+// A missing file isn't an error; it's created by the first Add
+func (h *History) Load() error {
+	if h.path == "" {
+		return nil
+	}
+	f, err := os.Open(h.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var entries []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			entries = append(entries, unescapeEntry(line))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read %s: %w", h.path, err)
+	}
+
+	if len(entries) > h.max {
+		entries = entries[len(entries)-h.max:]
+		h.entries = entries
+		return h.rewrite()
+	}
+	h.entries = entries
+	return nil
+}
+
+// Add records a line, skipping blank lines and repeats of the previous entry
+func (h *History) Add(line string) error {
+	if strings.TrimSpace(line) == "" {
+		return nil
+	}
+	if n := len(h.entries); n > 0 && h.entries[n-1] == line {
+		return nil
+	}
+	h.entries = append(h.entries, line)
+	if len(h.entries) > h.max {
+		h.entries = h.entries[len(h.entries)-h.max:]
+	}
+	if h.path == "" {
+		return nil
+	}
+
+	f, err := os.OpenFile(h.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(escapeEntry(line) + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Len returns the number of entries
+func (h *History) Len() int {
+	return len(h.entries)
+}
+
+// Entry returns the i-th entry, oldest first
+func (h *History) Entry(i int) string {
+	return h.entries[i]
+}
+
+// rewrite replaces the history file with the in-memory entries
+func (h *History) rewrite() error {
+	var b strings.Builder
+	for _, entry := range h.entries {
+		b.WriteString(escapeEntry(entry))
+		b.WriteByte('\n')
+	}
+	tmp := h.path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, h.path)
+}
+
+var entryEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+// escapeEntry encodes an entry so it fits on one line of the history file
+func escapeEntry(entry string) string {
+	return entryEscaper.Replace(entry)
+}
+
+// unescapeEntry reverses escapeEntry; unknown escapes are kept as written
+func unescapeEntry(line string) string {
+	if !strings.Contains(line, `\`) {
+		return line
+	}
+	var b strings.Builder
+	for i := 0; i < len(line); i++ {
+		if line[i] != '\\' || i == len(line)-1 {
+			b.WriteByte(line[i])
+			continue
+		}
+		i++
+		switch line[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case '\\':
+			b.WriteByte('\\')
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(line[i])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,69 @@
+package lineedit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHistoryPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+
+	h := NewHistory(path, 10)
+	if err := h.Load(); err != nil {
+		t.Fatalf("Expected a missing file to load as empty history, got %v", err)
+	}
+	for _, entry := range []string{"first", "", "second", "second", "line one\nline two", `C:\path\n`} {
+		if err := h.Add(entry); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected history file to be private, got %v", info.Mode().Perm())
+	}
+
+	loaded := NewHistory(path, 10)
+	if err := loaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"first", "second", "line one\nline two", `C:\path\n`}
+	if loaded.Len() != len(want) {
+		t.Fatalf("Expected %d entries, got %d", len(want), loaded.Len())
+	}
+	for i, entry := range want {
+		if loaded.Entry(i) != entry {
+			t.Errorf("Entry %d: expected %q, got %q", i, entry, loaded.Entry(i))
+		}
+	}
+}
+
+func TestHistoryLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+
+	h := NewHistory(path, 5)
+	for _, entry := range []string{"1", "2", "3", "4", "5", "6", "7"} {
+		h.Add(entry)
+	}
+	if h.Len() != 5 || h.Entry(0) != "3" {
+		t.Errorf("Expected the oldest entries to be dropped, got %d entries starting %q", h.Len(), h.Entry(0))
+	}
+
+	// The file keeps growing until the next load compacts it
+	loaded := NewHistory(path, 3)
+	if err := loaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Len() != 3 || loaded.Entry(0) != "5" {
+		t.Errorf("Expected the last 3 entries, got %d starting %q", loaded.Len(), loaded.Entry(0))
+	}
+	compacted := NewHistory(path, 10)
+	compacted.Load()
+	if compacted.Len() != 3 {
+		t.Errorf("Expected the file to be compacted to 3 entries, got %d", compacted.Len())
+	}
+}
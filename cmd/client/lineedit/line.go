@@ -0,0 +1,115 @@
+package lineedit
+
+import "unicode"
+
+// line is the text being edited and the cursor position within it, in runes
+type line struct {
+	buf []rune
+	pos int
+}
+
+// set replaces the text and moves the cursor to the end
+func (l *line) set(s string) {
+	l.buf = []rune(s)
+	l.pos = len(l.buf)
+}
+
+func (l *line) String() string {
+	return string(l.buf)
+}
+
+// insert adds r at the cursor
+func (l *line) insert(r rune) {
+	l.buf = append(l.buf, 0)
+	copy(l.buf[l.pos+1:], l.buf[l.pos:])
+	l.buf[l.pos] = r
+	l.pos++
+}
+
+// backspace deletes the rune before the cursor
+func (l *line) backspace() {
+	if l.pos == 0 {
+		return
+	}
+	l.buf = append(l.buf[:l.pos-1], l.buf[l.pos:]...)
+	l.pos--
+}
+
+// deleteForward deletes the rune under the cursor
+func (l *line) deleteForward() {
+	if l.pos == len(l.buf) {
+		return
+	}
+	l.buf = append(l.buf[:l.pos], l.buf[l.pos+1:]...)
+}
+
+// home moves to the start of the cursor's row
+func (l *line) home() { l.pos = l.rowStart() }
+
+// end moves to the end of the cursor's row
+func (l *line) end() { l.pos = l.rowEnd() }
+
+// rowStart returns the position after the newline before the cursor, or 0 on the first row
+func (l *line) rowStart() int {
+	i := l.pos
+	for i > 0 && l.buf[i-1] != '\n' {
+		i--
+	}
+	return i
+}
+
+// rowEnd returns the position of the newline after the cursor, or the end of the text on the last row
+func (l *line) rowEnd() int {
+	i := l.pos
+	for i < len(l.buf) && l.buf[i] != '\n' {
+		i++
+	}
+	return i
+}
+
+// cursor returns the cursor's row and its column within the row
+func (l *line) cursor() (row, col int) {
+	for _, r := range l.buf[:l.pos] {
+		if r == '\n' {
+			row++
+		}
+	}
+	return row, l.pos - l.rowStart()
+}
+
+func (l *line) left() {
+	if l.pos > 0 {
+		l.pos--
+	}
+}
+
+func (l *line) right() {
+	if l.pos < len(l.buf) {
+		l.pos++
+	}
+}
+
+// deleteWord deletes the word before the cursor along with any spaces after it, like Ctrl+W in a shell
+func (l *line) deleteWord() {
+	start := l.pos
+	for start > 0 && unicode.IsSpace(l.buf[start-1]) {
+		start--
+	}
+	for start > 0 && !unicode.IsSpace(l.buf[start-1]) {
+		start--
+	}
+	l.buf = append(l.buf[:start], l.buf[l.pos:]...)
+	l.pos = start
+}
+
+// killToStart deletes from the start of the cursor's row to the cursor
+func (l *line) killToStart() {
+	start := l.rowStart()
+	l.buf = append(l.buf[:start], l.buf[l.pos:]...)
+	l.pos = start
+}
+
+// killToEnd deletes from the cursor to the end of its row
+func (l *line) killToEnd() {
+	l.buf = append(l.buf[:l.pos], l.buf[l.rowEnd():]...)
+}
@@ -0,0 +1,271 @@
+package lineedit
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Terminal sequences for the pager, which draws on the alternate screen so the conversation is
+// left as it was when the pager quits
+const (
+	enterAltScreen = "\x1b[?1049h"
+	exitAltScreen  = "\x1b[?1049l"
+	reverseVideo   = "\x1b[7m"
+	normalVideo    = "\x1b[27m"
+)
+
+// pagerHelp is shown in the status line until a search or message replaces it
+const pagerHelp = "Space/b page, j/k line, g/G top/bottom, / search, n/N next/previous, q quit"
+
+// pager is the state of text being paged
+type pager struct {
+	lines  []string // Text wrapped to the terminal's width
+	width  int      // Terminal's width, which the status line is cut to
+	rows   int      // Lines shown at once, above the status line
+	top    int      // First line shown
+	query  string   // Last search, highlighted wherever it's shown
+	match  int      // Line of the last match, -1 before a search
+	status string   // Message shown in the status line until the next key, e.g. "Pattern not found"
+}
+
+// newPager wraps text to width and pages it rows lines at a time
+func newPager(text string, width, rows int) *pager {
+	return &pager{lines: WrapLines(text, width), width: width, rows: max(1, rows), match: -1}
+}
+
+// WrapLines splits text into the lines it takes up on a terminal width characters wide, with tabs
+// expanded to spaces
+func WrapLines(text string, width int) []string {
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		line = strings.ReplaceAll(line, "\t", "    ")
+		for width > 0 && utf8.RuneCountInString(line) > width {
+			cut := 0
+			for i := 0; i < width; i++ {
+				_, size := utf8.DecodeRuneInString(line[cut:])
+				cut += size
+			}
+			lines = append(lines, line[:cut])
+			line = line[cut:]
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// Page shows text a screen at a time on the alternate screen, returning when the user quits
+// Space/f/PgDn and b/PgUp move a page, Enter/j/Down and k/Up a line, d/u half a page, g/Home and
+// G/End jump to the top and bottom, / searches ignoring case, n/N repeat the search forwards and
+// backwards, and q or Ctrl+C quits
+func (e *Editor) Page(text string) error {
+	if e.fd < 0 {
+		return errors.New("paging needs a terminal")
+	}
+	width, height, err := terminalSize(e.fd)
+	if err != nil {
+		return err
+	}
+	if err := e.makeRaw(); err != nil {
+		return err
+	}
+	defer e.Restore()
+	fmt.Fprint(e.out, enterAltScreen)
+	defer fmt.Fprint(e.out, exitAltScreen)
+	return e.page(newPager(text, width, height-1))
+}
+
+// page runs the pager's key loop until the user quits or input ends
+func (e *Editor) page(p *pager) error {
+	for {
+		e.drawPage(p)
+		r, _, err := e.in.ReadRune()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		p.status = ""
+
+		switch r {
+		case 'q', 'Q', keyCtrlC:
+			return nil
+		case ' ', 'f', keyCtrlF:
+			p.scroll(p.rows)
+		case 'b', keyCtrlB:
+			p.scroll(-p.rows)
+		case '\r', '\n', 'j', keyCtrlN:
+			p.scroll(1)
+		case 'k', keyCtrlP:
+			p.scroll(-1)
+		case 'd':
+			p.scroll(p.rows / 2)
+		case 'u':
+			p.scroll(-p.rows / 2)
+		case 'g':
+			p.top = 0
+		case 'G':
+			p.scroll(len(p.lines))
+		case '/':
+			if query, ok := e.readQuery(p); ok && query != "" {
+				p.query = query
+				p.search(p.top, 1)
+			}
+		case 'n':
+			p.search(p.match+1, 1)
+		case 'N':
+			p.search(p.match-1, -1)
+		case keyEscape:
+			switch key, params := e.readEscape(); {
+			case key == 'A':
+				p.scroll(-1)
+			case key == 'B':
+				p.scroll(1)
+			case key == 'H' || key == '~' && (params == "1" || params == "7"):
+				p.top = 0
+			case key == 'F' || key == '~' && (params == "4" || params == "8"):
+				p.scroll(len(p.lines))
+			case key == '~' && params == "5":
+				p.scroll(-p.rows)
+			case key == '~' && params == "6":
+				p.scroll(p.rows)
+			}
+		}
+	}
+}
+
+// readEscape reads the rest of an escape sequence, returning its final byte and parameters, e.g.
+// '~' and "5" for PgUp
+func (e *Editor) readEscape() (rune, string) {
+	r, _, err := e.in.ReadRune()
+	if err != nil || (r != '[' && r != 'O') {
+		return 0, ""
+	}
+	var params strings.Builder
+	for {
+		r, _, err = e.in.ReadRune()
+		if err != nil {
+			return 0, ""
+		}
+		if r >= 0x40 && r <= 0x7e {
+			return r, params.String()
+		}
+		params.WriteRune(r)
+	}
+}
+
+// readQuery reads a search typed in the status line, reporting false when Ctrl+C cancels it
+func (e *Editor) readQuery(p *pager) (string, bool) {
+	var query []rune
+	for {
+		fmt.Fprintf(e.out, "\x1b[%d;1H\x1b[2K/%s", p.rows+1, string(query))
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return "", false
+		}
+		switch {
+		case r == '\r' || r == '\n':
+			return string(query), true
+		case r == keyCtrlC:
+			return "", false
+		case r == keyBackspace || r == keyCtrlH:
+			if len(query) == 0 {
+				return "", false
+			}
+			query = query[:len(query)-1]
+		case r == keyEscape:
+			e.readEscape()
+		case unicode.IsPrint(r):
+			query = append(query, r)
+		}
+	}
+}
+
+// scroll moves the screen by n lines, down when n is positive, without passing either end
+func (p *pager) scroll(n int) {
+	p.top = max(0, min(p.top+n, len(p.lines)-p.rows))
+}
+
+// search finds the next line from start, in direction dir, that contains the query, and scrolls to it
+func (p *pager) search(start, dir int) {
+	if p.query == "" {
+		p.status = "No previous search"
+		return
+	}
+	for i := start; i >= 0 && i < len(p.lines); i += dir {
+		if begin, _ := indexFold(p.lines[i], p.query); begin >= 0 {
+			p.match = i
+			p.top = 0
+			p.scroll(i)
+			return
+		}
+	}
+	p.status = "Pattern not found: " + p.query
+}
+
+// drawPage redraws the screen from the pager's top line, with a status line below
+func (e *Editor) drawPage(p *pager) {
+	var b strings.Builder
+	b.WriteString("\x1b[H")
+	for i := p.top; i < p.top+p.rows; i++ {
+		b.WriteString("\x1b[2K")
+		if i < len(p.lines) {
+			b.WriteString(highlight(p.lines[i], p.query))
+		} else {
+			b.WriteString("~")
+		}
+		b.WriteString("\r\n")
+	}
+
+	status := p.status
+	if status == "" {
+		last := min(p.top+p.rows, len(p.lines))
+		status = fmt.Sprintf("lines %d-%d of %d (%d%%)  %s", p.top+1, last, len(p.lines), last*100/len(p.lines), pagerHelp)
+	}
+	if lines := WrapLines(status, p.width); len(lines) > 1 {
+		status = lines[0]
+	}
+	b.WriteString("\x1b[2K" + reverseVideo + status + normalVideo)
+	io.WriteString(e.out, b.String())
+}
+
+// highlight shows each match of query in line in reverse video
+func highlight(line, query string) string {
+	if query == "" {
+		return line
+	}
+	var b strings.Builder
+	for {
+		begin, end := indexFold(line, query)
+		if begin < 0 {
+			b.WriteString(line)
+			return b.String()
+		}
+		b.WriteString(line[:begin] + reverseVideo + line[begin:end] + normalVideo)
+		line = line[end:]
+	}
+}
+
+// indexFold returns the byte range of the first match of substr in s, ignoring case, or -1, -1
+func indexFold(s, substr string) (int, int) {
+	n := utf8.RuneCountInString(substr)
+	for begin := range s {
+		end, count := begin, 0
+		for end < len(s) && count < n {
+			_, size := utf8.DecodeRuneInString(s[end:])
+			end += size
+			count++
+		}
+		if count < n {
+			break
+		}
+		if strings.EqualFold(s[begin:end], substr) {
+			return begin, end
+		}
+	}
+	return -1, -1
+}
@@ -0,0 +1,80 @@
+package lineedit
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestWrapLines(t *testing.T) {
+	got := WrapLines("abcdefg\n\tx\n\nwörld\n", 3)
+	want := []string{"abc", "def", "g", "   ", " x", "", "wör", "ld"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestPagerKeys(t *testing.T) {
+	var text strings.Builder
+	for i := 1; i <= 100; i++ {
+		fmt.Fprintf(&text, "line %d\n", i)
+	}
+	text.WriteString("The End\n")
+
+	tests := []struct {
+		name    string
+		keys    string
+		wantTop int
+	}{
+		{"quit", "q", 0},
+		{"page down", " f", 20},
+		{"page up stops at the top", " bb", 0},
+		{"line down and up", "jj\rk", 2},
+		{"arrow and page keys", "\x1b[B\x1b[B\x1b[6~\x1b[A", 11},
+		{"half pages", "ddu", 5},
+		{"bottom and top", "Gg", 0},
+		{"bottom stops at the last page", "G q", 91},
+		{"search", "/LINE 5\rq", 4},
+		{"next and previous match", "/line 5\rnnNq", 49},
+		{"search not found stays put", "j/nothing\rq", 1},
+		{"search cancelled", "/line 9\x03q", 0},
+		{"ctrl+c quits", "\x03j", 0},
+		{"end of input quits", "j", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &Editor{in: bufio.NewReader(strings.NewReader(tt.keys)), out: io.Discard, fd: 0}
+			p := newPager(text.String(), 80, 10)
+			if err := e.page(p); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if p.top != tt.wantTop {
+				t.Errorf("Expected the screen to start at line %d, got %d", tt.wantTop+1, p.top+1)
+			}
+		})
+	}
+}
+
+func TestPagerDraw(t *testing.T) {
+	var out strings.Builder
+	e := &Editor{out: &out}
+	p := newPager("one\nTwo two\nthree", 80, 5)
+	p.query = "two"
+	e.drawPage(p)
+	screen := out.String()
+	if !strings.Contains(screen, reverseVideo+"Two"+normalVideo+" "+reverseVideo+"two"+normalVideo) {
+		t.Errorf("Expected both matches highlighted, got %q", screen)
+	}
+	if !strings.Contains(screen, "~") || !strings.Contains(screen, "lines 1-3 of 3 (100%)") {
+		t.Errorf("Expected filler rows and the position in the status line, got %q", screen)
+	}
+
+	if begin, end := indexFold("Grüße", "SSE"); begin >= 0 || end >= 0 {
+		t.Errorf("Expected no match across a case change in length, got %d-%d", begin, end)
+	}
+	if begin, end := indexFold("HÉllo", "él"); begin != 1 || end != 4 {
+		t.Errorf("Expected a match at bytes 1-4, got %d-%d", begin, end)
+	}
+}
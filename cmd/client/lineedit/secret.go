@@ -0,0 +1,58 @@
+package lineedit
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"unicode"
+)
+
+// ReadSecret shows prompt and reads a line without echoing it, for passwords and API keys
+// Backspace deletes a character and pasted text is taken as typed; input that isn't a terminal is read
+// as a plain line
+// Returns ErrInterrupted on Ctrl+C and io.EOF when input ends before anything was typed
+func (e *Editor) ReadSecret(prompt string) (string, error) {
+	if e.fd < 0 {
+		return e.readPlain(prompt)
+	}
+	if err := e.makeRaw(); err != nil {
+		return "", err
+	}
+	defer e.Restore()
+	fmt.Fprint(e.out, prompt)
+	secret, err := e.readSecret()
+	fmt.Fprint(e.out, "\r\n")
+	return secret, err
+}
+
+// readSecret reads keys until Enter, without echo
+func (e *Editor) readSecret() (string, error) {
+	var secret []rune
+	for {
+		r, _, err := e.in.ReadRune()
+		if errors.Is(err, io.EOF) && len(secret) > 0 {
+			return string(secret), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case r == '\r' || r == '\n':
+			return string(secret), nil
+		case r == keyCtrlC:
+			return "", ErrInterrupted
+		case r == keyCtrlD && len(secret) == 0:
+			return "", io.EOF
+		case r == keyCtrlU:
+			secret = nil
+		case r == keyBackspace || r == keyCtrlH:
+			if len(secret) > 0 {
+				secret = secret[:len(secret)-1]
+			}
+		case r == keyEscape:
+			e.readEscape() // Bracketed paste markers and arrow keys
+		case unicode.IsPrint(r):
+			secret = append(secret, r)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package lineedit
+
+import "errors"
+
+// termState is unused where raw mode isn't supported
+type termState struct{}
+
+// isTerminal reports false so input is read line by line without editing
+func isTerminal(fd int) bool {
+	return false
+}
+
+func terminalSize(fd int) (width, height int, err error) {
+	return 0, 0, errors.New("terminal size is not supported on this platform")
+}
+
+func makeRaw(fd int) (*termState, error) {
+	return nil, errors.New("line editing is not supported on this platform")
+}
+
+func restoreTerminal(fd int, state *termState) error {
+	return nil
+}
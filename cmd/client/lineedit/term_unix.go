@@ -0,0 +1,52 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package lineedit
+
+import "golang.org/x/sys/unix"
+
+// termState is a terminal's mode before the editor changed it
+type termState struct {
+	termios unix.Termios
+}
+
+// isTerminal reports whether fd refers to a terminal
+func isTerminal(fd int) bool {
+	_, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	return err == nil
+}
+
+// terminalSize returns the terminal's width and height in characters
+func terminalSize(fd int) (width, height int, err error) {
+	ws, err := unix.IoctlGetWinsize(fd, unix.TIOCGWINSZ)
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(ws.Col), int(ws.Row), nil
+}
+
+// makeRaw switches the terminal to raw mode so keys arrive one at a time without echo,
+// returning the previous mode
+// Output processing is left on so newlines written elsewhere still return the cursor
+func makeRaw(fd int) (*termState, error) {
+	termios, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	if err != nil {
+		return nil, err
+	}
+	saved := &termState{termios: *termios}
+
+	termios.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	termios.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	termios.Cflag &^= unix.CSIZE | unix.PARENB
+	termios.Cflag |= unix.CS8
+	termios.Cc[unix.VMIN] = 1
+	termios.Cc[unix.VTIME] = 0
+	if err := unix.IoctlSetTermios(fd, ioctlSetTermios, termios); err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+// restoreTerminal puts the terminal back in the mode saved by makeRaw
+func restoreTerminal(fd int, state *termState) error {
+	return unix.IoctlSetTermios(fd, ioctlSetTermios, &state.termios)
+}
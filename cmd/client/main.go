@@ -1,20 +1,22 @@
 package main
 
 import (
-	"bufio"
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/base64"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log/slog"
-	"math"
 	"net"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -22,27 +24,68 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/encoding/gzip"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
+	"microchat.ai/cmd/client/lineedit"
 	pb "microchat.ai/proto"
+	"microchat.ai/version"
 )
 
 const (
-	quitCommand  = "/quit"
-	clearCommand = "/clear"
+	quitCommand    = "/quit"
+	clearCommand   = "/clear"
+	historyCommand = "/history"
+	modelCommand   = "/model"
+	exportCommand  = "/export"
+	systemCommand  = "/system"
+	attachCommand  = "/attach"
+	saveCommand    = "/save"
+	pipeCommand    = "/pipe"
+	copyCommand    = "/copy"
+	snippetCommand = "/t"
+)
+
+const (
+	inputHistoryFile = ".microchat_history" // In the user's home directory
+	inputHistorySize = 1000                 // Entries kept in the history file
 )
 
 type config struct {
-	serverAddr    string
-	model         pb.Model
-	modelString   string // String representation of model for flag parsing
-	sessionID     string // Server-generated UUID session ID
-	metrics       bool   // Show compact session metrics
-	metricsDetail bool   // Show detailed metrics
-	metricsTotal  bool   // Show lifetime metrics alongside session
-	apiKey        string // API key for authentication
+	serverAddr     string
+	model          pb.Model
+	modelString    string        // String representation of model for flag parsing
+	sessionID      string        // Server-generated UUID session ID
+	metrics        bool          // Show compact session metrics
+	metricsDetail  bool          // Show detailed metrics
+	metricsTotal   bool          // Show lifetime metrics alongside session
+	apiKey         string        // API key for authentication
+	sessionTTL     time.Duration // Requested session idle timeout, 0 for server default
+	timeout        time.Duration // Deadline for each chat request, 0 for none
+	retry          retryPolicy   // How connecting, reconnecting and idempotent requests are retried
+	contextWindow  int64         // Model's context window in tokens, 0 for the known size of the model
+	compress       string        // Compressor for requests, see compressors
+	budget         int64         // Wire bytes the client may use before warning it's used up, 0 for no budget
+	budgetStrict   bool          // Refuse to send messages once the budget is used up
+	sessionKey     string        // Base64 client-supplied key the server seals history with, empty to disable
+	statePath      string        // Where the session is saved for -resume, empty to not save it
+	transcriptPath string        // Local file prompts and replies are appended to, empty to disable
+	serverName     string        // TLS server name for development servers
+	caCertFile     string        // CA certificate for development servers
+	profile        string        // Config file profile in use, empty for none
+	systemPrompt   string        // System prompt sent with every message, empty for none
+	themeName      string        // Color theme, see themes
+	noColor        bool          // Print plain text without colors
+	noPager        bool          // Print replies taller than the terminal rather than paging them
+	debug          bool          // Log connection, TLS and per-RPC details to stderr
+	timeFormat     string        // How /history shows message times, see timeFormats
+	notify         bool          // Ring the bell and send a desktop notification when a slow reply arrives
+	notifyAfter    time.Duration // How long a request must take for -notify to notify
+
+	snippets     map[string]string   // Prompt templates from the config file, sent with "/t name"
+	modelAliases map[string]pb.Model // Model names from the config file, lowercase, e.g. fast
 }
 
 type application struct {
@@ -51,7 +94,18 @@ type application struct {
 	conn         *grpc.ClientConn
 	grpc         pb.ChatServiceClient
 	metrics      metrics
-	messageIndex uint32 // Layer 4: Track message count for delta protocol
+	messageIndex uint32           // Layer 4: Track message count for delta protocol
+	conversation []historyEntry   // Local copy of the session's messages, in sync while it holds messageIndex of them
+	transcript   *transcript      // nil unless -log-transcript is set
+	theme        theme            // Output colors, plain when color is off
+	inFlight     inFlight         // Chat request Ctrl+C cancels
+	queue        []string         // Messages typed while the server couldn't be reached, oldest first
+	attachments  []attachment     // Files to send with the next message
+	lastReply    string           // Most recent assistant reply, for /save and /pipe
+	editor       *lineedit.Editor // Interactive input, also used to page long replies; nil outside the chat
+	tokens       tokenEstimate
+	codecMissing atomic.Bool // The server can't decompress -compress, so requests go uncompressed
+	budgetWarned int         // Budget thresholds already warned about
 }
 
 // loadEnv loads environment variables from .env file
@@ -59,7 +113,7 @@ func loadEnv(logger *slog.Logger) error {
 	// Load .env file - check current directory first, then project root
 	if err := godotenv.Load(".env"); err != nil {
 		if err := godotenv.Load("../../.env"); err != nil {
-			logger.Warn("no .env file found, using environment variables only")
+			logger.Info("no .env file found, using environment variables only")
 		}
 	}
 	return nil
@@ -68,34 +122,234 @@ func loadEnv(logger *slog.Logger) error {
 func main() {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 
-	// Load environment variables
-	if err := loadEnv(logger); err != nil {
-		os.Exit(1)
-	}
-
 	var cfg config
-
-	flag.StringVar(&cfg.serverAddr, "addr", "localhost:4000", "gRPC server address")
-	flag.StringVar(&cfg.modelString, "model", "gemini", "LLM model to use (echo, gemini)")
+	var showVersion, check bool
+	var message, messageFile string
+	var resume bool
+	var sessionFlag string
+	var configPath string
+	var metricsURL string
+	var attachPaths []string
+
+	flag.StringVar(&cfg.serverAddr, "addr", "localhost:4000", "gRPC server address (unix:///path/to.sock for a local unix socket)")
+	flag.StringVar(&cfg.modelString, "model", "gemini", "LLM model to use (echo, gemini, openai, or an alias from the config file)")
 	flag.BoolVar(&cfg.metrics, "metrics", false, "show compact session metrics")
 	flag.BoolVar(&cfg.metricsDetail, "metrics-detail", false, "show detailed message and session metrics")
 	flag.BoolVar(&cfg.metricsTotal, "metrics-total", false, "show lifetime metrics alongside session")
+	flag.DurationVar(&cfg.sessionTTL, "session-ttl", 0, "requested session idle timeout (e.g. 30m, 8h), 0 for server default")
+	flag.StringVar(&cfg.compress, "compress", gzip.Name, "compress requests with "+strings.Join(compressors, ", "))
+	flag.Func("budget", "warn at 50%, 80% and 100% of this many wire bytes, e.g. 30MB", func(s string) (err error) {
+		cfg.budget, err = parseByteSize(s)
+		return err
+	})
+	flag.BoolVar(&cfg.budgetStrict, "budget-strict", false, "refuse to send messages once -budget is used up")
+	flag.Int64Var(&cfg.contextWindow, "context-window", 0, "model's context window in tokens, for the warning when a message won't fit (0 for the model's known size)")
+	flag.DurationVar(&cfg.timeout, "timeout", 0, "give up on a reply after this long (e.g. 30s, 5m), 0 to wait for the server")
+	cfg.retry = defaultRetryPolicy
+	flag.IntVar(&cfg.retry.attempts, "retries", defaultRetryPolicy.attempts, "times to retry connecting, a lost chat connection, or a request the server couldn't be reached for")
+	flag.DurationVar(&cfg.retry.delay, "retry-delay", defaultRetryPolicy.delay, "wait before the first retry, doubling for each later one up to 30s")
+	flag.DurationVar(&cfg.retry.maxWait, "retry-max-wait", 0, "give up retrying once the waits add up to this long, 0 for no limit")
+	flag.Float64Var(&cfg.retry.jitter, "retry-jitter", defaultRetryPolicy.jitter, "share of each retry wait, 0-1, taken off at random")
+	flag.BoolVar(&showVersion, "version", false, "print version information and exit")
+	flag.BoolVar(&check, "check", false, "check the server is reachable, report the connection's TLS, compression and latency, and exit")
+	flag.StringVar(&message, "m", "", "send this message (followed by -f or piped input, if any), print the reply and exit")
+	flag.StringVar(&messageFile, "f", "", "send this file's contents (- for stdin), print the reply and exit")
+	flag.BoolVar(&resume, "resume", false, "continue the last interactive session, on its server and model unless -addr or -model is given")
+	flag.StringVar(&sessionFlag, "session", "", "continue the session with this ID")
+	flag.StringVar(&cfg.transcriptPath, "log-transcript", "", "append every prompt and reply to this local file, rotated at 10 MiB")
+	flag.StringVar(&configPath, "config", "", "client config file (default ~/.config/microchat/config.toml)")
+	flag.StringVar(&cfg.profile, "profile", "", "use this named profile from the config file")
+	flag.StringVar(&cfg.themeName, "theme", defaultTheme, "color theme ("+strings.Join(themeNames(), ", ")+")")
+	flag.BoolVar(&cfg.noColor, "no-color", false, "print plain text without colors (also set by NO_COLOR)")
+	flag.BoolVar(&cfg.debug, "debug", false, "log resolved addresses, TLS handshakes, each request's headers and trailers, compression and gRPC's own connection logs to stderr")
+	flag.BoolVar(&cfg.noPager, "no-pager", false, "print replies taller than the terminal instead of showing them in a pager ($PAGER or the built-in one)")
+	flag.BoolVar(&cfg.notify, "notify", false, "ring the bell and send a desktop notification when a reply takes at least -notify-after and the terminal isn't focused")
+	flag.DurationVar(&cfg.notifyAfter, "notify-after", 10*time.Second, "how long a request must take for -notify to notify")
+	flag.StringVar(&cfg.timeFormat, "time-format", timeFormatBoth, "how /history shows message times: "+strings.Join(timeFormats, ", "))
+	flag.StringVar(&metricsURL, "metrics-url", "", "server's Prometheus endpoint for \"admin metrics\" (default http://<-addr host>:"+defaultMetricsPort+"/metrics)")
+	flag.Func("attach", "attach this text file to the first message (repeatable)", func(path string) error {
+		attachPaths = append(attachPaths, path)
+		return nil
+	})
+	flag.StringVar(&cfg.systemPrompt, "system", "", "system prompt for the session, e.g. \"You are a concise assistant\"")
 	flag.Parse()
 
-	// Get API key from environment
+	// "admin metrics|sessions|keys" runs an operator command instead of the chat
+	admin := flag.Arg(0) == adminCommand
+	// "login" and "logout" manage the API key in the OS keychain
+	login := flag.Arg(0) == loginCommand || flag.Arg(0) == logoutCommand
+
+	setFlags := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
+
+	if showVersion {
+		fmt.Println("microchat.ai client", version.Get())
+		return
+	}
+
+	// One-shot mode sends -m, -f or piped input as a single message
+	input, err := oneShotInput(messageFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+
+	// In one-shot mode stdout carries only the reply, and with -check only the report, so logs go to
+	// stderr and only when something's wrong
+	oneShot := message != "" || input != nil
+	if oneShot || check || admin || login {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	}
+	if cfg.debug {
+		logger = debugLogger(os.Stderr)
+	}
+
+	// Defaults from the config file, overridden by flags
+	path := configPath
+	if path == "" {
+		path = defaultConfigPath()
+	}
+	fileCfg, err := loadFileConfig(path, configPath != "")
+	if err != nil {
+		logger.Error("invalid config file", "error", err)
+		os.Exit(exitUsage)
+	}
+	if cfg.profile == "" && resume && sessionFlag == "" {
+		cfg.profile = resumeProfile(fileCfg, logger) // Reconnect with the saved session's profile and key
+	}
+	if cfg.profile == "" {
+		cfg.profile = fileCfg.Profile
+	}
+	fileCfg, profileCfg, err := fileCfg.withProfile(cfg.profile)
+	if err == nil {
+		err = fileCfg.apply(&cfg, setFlags)
+	}
+	if err != nil {
+		logger.Error("invalid config file", "error", err)
+		os.Exit(exitUsage)
+	}
+
+	if err := cfg.retry.validate(); err != nil {
+		logger.Error("invalid retry policy", "error", err)
+		os.Exit(exitUsage)
+	}
+
+	if err := validateTimeFormat(cfg.timeFormat); err != nil {
+		logger.Error("invalid -time-format", "error", err)
+		os.Exit(exitUsage)
+	}
+
+	if err := validateCompressor(cfg.compress); err != nil {
+		logger.Error("invalid -compress", "error", err)
+		os.Exit(exitUsage)
+	}
+
+	// Load environment variables
+	if err := loadEnv(logger); err != nil {
+		os.Exit(1)
+	}
+
+	if login {
+		os.Exit(runLoginMode(flag.Arg(0), cfg.profile, flag.Args()[1:], os.Stdin, os.Stdout))
+	}
+
+	// Get API key from the selected profile, the environment, the config file's reference to it, or
+	// the keychain entry stored by "login"
+	// -check doesn't need one, since Health is unauthenticated
+	// Admin commands prefer MICROCHAT_ADMIN_KEY, since everyday keys usually lack the admin role
 	cfg.apiKey = os.Getenv("MICROCHAT_API_KEY")
-	if cfg.apiKey == "" {
-		logger.Error("MICROCHAT_API_KEY environment variable is required")
+	if adminKey := os.Getenv("MICROCHAT_ADMIN_KEY"); admin && adminKey != "" {
+		cfg.apiKey = adminKey
+	} else if (cfg.apiKey == "" || profileCfg.hasAPIKey()) && !check {
+		if cfg.apiKey, err = fileCfg.apiKey(); err != nil {
+			logger.Error("failed to load API key from config file", "error", err)
+			os.Exit(1)
+		}
+	}
+	if cfg.apiKey == "" && !check {
+		cfg.apiKey = keychainKey(cfg.profile, logger)
+	}
+	if cfg.apiKey == "" && !check {
+		logger.Error("MICROCHAT_API_KEY environment variable (or api_key_env/api_key_file in the config file, a key stored with \"microchat-client login\", or MICROCHAT_ADMIN_KEY for admin commands) is required")
 		os.Exit(1)
 	}
 
+	// TLS settings for development servers: selected profile, environment, config file, then defaults
+	cfg.serverName = firstNonEmpty(profileCfg.ServerName, os.Getenv("SERVER_NAME"), fileCfg.ServerName, "localhost")
+	cfg.caCertFile = firstNonEmpty(expandHome(profileCfg.CACertFile), os.Getenv("CA_CERT_FILE"), expandHome(fileCfg.CACertFile), "certs/ca.crt")
+
+	if check {
+		os.Exit(runCheckMode(cfg, logger))
+	}
+	if admin {
+		os.Exit(runAdminMode(cfg, logger, flag.Args()[1:], metricsURL))
+	}
+
+	// Optional client-supplied key the server seals stored history with
+	cfg.sessionKey = os.Getenv("MICROCHAT_SESSION_KEY")
+	if err := validateSessionKey(cfg.sessionKey); err != nil {
+		logger.Error("invalid MICROCHAT_SESSION_KEY", "error", err)
+		os.Exit(1)
+	}
+
+	if err := validateSystemPrompt(cfg.systemPrompt); err != nil {
+		logger.Error("invalid -system", "error", err)
+		os.Exit(exitUsage)
+	}
+
 	// Parse model string to enum
-	cfg.model = parseModel(cfg.modelString, logger)
+	cfg.model = parseModel(cfg.modelString, cfg.modelAliases, logger)
+
+	// Files from -attach go with the first message
+	var attachments []attachment
+	for _, path := range attachPaths {
+		a, err := readAttachment(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid -attach: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		attachments = append(attachments, a)
+	}
+	if _, err := withAttachments("", attachments); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid -attach: %v\n", err)
+		os.Exit(exitUsage)
+	}
+
+	// Continue an earlier session with -session or -resume
+	cfg.statePath = sessionStatePath()
+	resumeID := resumeTarget(&cfg, sessionFlag, resume, setFlags, logger)
+	explicitSession := sessionFlag != ""
+
+	if oneShot {
+		text, err := oneShotMessage(message, input)
+		if err == nil {
+			text, err = withAttachments(text, attachments)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		cfg.statePath = "" // Scripted requests don't replace the session saved for -resume
+		os.Exit(runOneShotMode(cfg, logger, text, resumeID, explicitSession))
+	}
+
+	colors, err := selectTheme(cfg.themeName, cfg.noColor, lineedit.IsTerminal(os.Stdout))
+	if err != nil {
+		logger.Error("invalid -theme", "error", err)
+		os.Exit(exitUsage)
+	}
 
 	app := &application{
-		config: cfg,
-		logger: logger,
+		config:      cfg,
+		logger:      logger,
+		theme:       colors,
+		attachments: attachments,
+	}
+	if err := app.openTranscript(); err != nil {
+		logger.Error("failed to open transcript", "path", cfg.transcriptPath, "error", err)
+		os.Exit(1)
 	}
+	defer app.transcript.close()
 
 	// Connect to server
 	if err := app.connect(); err != nil {
@@ -104,28 +358,84 @@ func main() {
 	}
 	defer app.conn.Close()
 
-	// Start session and get server-generated session ID
-	if err := app.startSession(); err != nil {
-		logger.Error("failed to start session", "error", err)
+	// Resume the requested session, or start one and get a server-generated session ID
+	resumed, err := app.openSession(resumeID, explicitSession)
+	if err != nil {
+		logger.Error("failed to start session", "resume_session_id", resumeID, "error", err)
 		os.Exit(1)
 	}
 
-	logger.Info("connected to server", "addr", cfg.serverAddr, "model", cfg.modelString, "session_id", app.config.sessionID)
+	logger.Info("connected to server", "addr", app.config.serverAddr, "model", app.config.modelString, "session_id", app.config.sessionID)
+	if resumed {
+		fmt.Printf("Resumed session %s with %d messages - '%s' to review them\n", app.config.sessionID, app.messageIndex, historyCommand)
+	}
 
 	app.startChat()
 }
 
-// parseModel converts string model name to protobuf Model enum
-func parseModel(modelStr string, logger *slog.Logger) pb.Model {
-	switch strings.ToLower(modelStr) {
-	case "gemini":
-		return pb.Model_GEMINI_2_5_FLASH_LITE
-	case "echo":
-		return pb.Model_ECHO
-	default:
-		logger.Warn("unknown model, using default", "requested", modelStr, "default", "gemini")
-		return pb.Model_GEMINI_2_5_FLASH_LITE // Default to gemini
+// runOneShotMode connects, sends a single message and returns the process exit code
+// The message goes to resumeID when given, or a new session
+func runOneShotMode(cfg config, logger *slog.Logger, message, resumeID string, explicitSession bool) int {
+	app := &application{config: cfg, logger: logger}
+	if err := app.openTranscript(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to open transcript: %v\n", err)
+		return exitUsage
+	}
+	defer app.transcript.close()
+
+	if err := app.connect(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+	defer app.conn.Close()
+
+	if _, err := app.openSession(resumeID, explicitSession); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", status.Convert(err).Message())
+		return oneShotExitCode(err)
+	}
+	if err := app.runOneShot(os.Stdout, message); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", status.Convert(err).Message())
+		return oneShotExitCode(err)
+	}
+	return 0
+}
+
+// runCheckMode connects, reports the server's health and returns the process exit code, as in one-shot mode
+func runCheckMode(cfg config, logger *slog.Logger) int {
+	app := &application{config: cfg, logger: logger}
+	if err := app.connect(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+	defer app.conn.Close()
+
+	report, err := app.checkHealth()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: health check of %s failed: %s\n", cfg.serverAddr, status.Convert(err).Message())
+		return oneShotExitCode(err)
+	}
+	report.write(os.Stdout)
+	return 0
+}
+
+// firstNonEmpty returns the first of values that isn't empty
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
 	}
+	return ""
+}
+
+// parseModel converts string model name, or a config file alias, to protobuf Model enum
+func parseModel(modelStr string, aliases map[string]pb.Model, logger *slog.Logger) pb.Model {
+	if model, ok := lookupModel(modelStr, aliases); ok {
+		return model
+	}
+	fallback := modelNames[0] // Default to gemini
+	logger.Warn("unknown model, using default", "requested", modelStr, "default", fallback.name)
+	return fallback.model
 }
 
 // isProductionServer determines if the server address is a production domain
@@ -145,33 +455,40 @@ func isProductionServer(serverAddr string) bool {
 	return strings.Contains(host, ".") && net.ParseIP(host) == nil
 }
 
+// connect sets up the connection, retrying under the retry policy
 func (app *application) connect() error {
-	const maxRetries = 3
-	for attempt := 0; attempt <= maxRetries; attempt++ {
-		if attempt > 0 {
-			delay := time.Duration(math.Pow(2, float64(attempt-1))) * time.Second
-			app.logger.Info("retrying connection", "attempt", attempt+1, "delay", delay)
-			time.Sleep(delay)
-		}
-
+	b := app.config.retry.backoff()
+	for {
 		err := app.attemptConnect()
 		if err == nil {
 			return nil
 		}
-
-		if attempt == maxRetries {
-			return fmt.Errorf("failed to connect after %d attempts: %v", maxRetries+1, err)
+		delay, ok := b.wait()
+		if !ok {
+			return fmt.Errorf("failed to connect after %d attempts: %v", b.retries+1, err)
 		}
+		app.logger.Info("retrying connection", "attempt", b.retries+1, "delay", delay)
+		time.Sleep(delay)
 	}
-	return nil
 }
 
 func (app *application) attemptConnect() error {
 	isProduction := isProductionServer(app.config.serverAddr)
 
 	var creds credentials.TransportCredentials
+	var verifyConnection func(tls.ConnectionState) error // Logs the handshake with -debug
+	if app.config.debug {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		app.logResolvedAddr(ctx)
+		cancel()
+		verifyConnection = app.logTLSHandshake
+	}
 
-	if isProduction {
+	if strings.HasPrefix(app.config.serverAddr, "unix:") {
+		// Unix socket: the server trusts the socket's file permissions instead of TLS
+		creds = insecure.NewCredentials()
+		app.logger.Info("using plaintext connection over unix socket", "addr", app.config.serverAddr)
+	} else if isProduction {
 		// Production: Use system CA certificates for valid certificates
 		host, _, err := net.SplitHostPort(app.config.serverAddr)
 		if err != nil {
@@ -179,21 +496,16 @@ func (app *application) attemptConnect() error {
 		}
 
 		creds = credentials.NewTLS(&tls.Config{
-			ServerName: host,
+			ServerName:       host,
+			VerifyConnection: verifyConnection,
 		})
 		app.logger.Info("using system CA certificates for production server", "host", host)
 	} else {
 		// Development: Use self-signed certificates
-		serverName := os.Getenv("SERVER_NAME")
-		if serverName == "" {
-			serverName = "localhost"
-		}
+		serverName := app.config.serverName
 
 		// Load CA certificate (with default)
-		caPath := os.Getenv("CA_CERT_FILE")
-		if caPath == "" {
-			caPath = "certs/ca.crt"
-		}
+		caPath := app.config.caCertFile
 
 		// Try multiple possible locations for the certificate
 		var fullCaPath string
@@ -205,10 +517,12 @@ func (app *application) attemptConnect() error {
 			fullCaPath = caPath
 			caCert, err = ioutil.ReadFile(fullCaPath)
 		} else {
+			app.logger.Debug("CA certificate not found", "path", caPath, "error", err)
 			// Try relative to project root (backwards compatibility)
 			fullCaPath = "../../" + caPath
 			caCert, err = ioutil.ReadFile(fullCaPath)
 			if err != nil {
+				app.logger.Debug("CA certificate not found", "path", fullCaPath, "error", err)
 				// Try absolute path based on executable location
 				if execPath, execErr := os.Executable(); execErr == nil {
 					execDir := filepath.Dir(execPath)
@@ -230,18 +544,21 @@ func (app *application) attemptConnect() error {
 		}
 
 		creds = credentials.NewTLS(&tls.Config{
-			ServerName: serverName,
-			RootCAs:    caCertPool,
+			ServerName:       serverName,
+			RootCAs:          caCertPool,
+			VerifyConnection: verifyConnection,
 		})
 		app.logger.Info("using self-signed CA certificate for development server", "path", fullCaPath, "server_name", serverName)
 	}
 
 	opts := []grpc.DialOption{
 		grpc.WithTransportCredentials(creds),
-		grpc.WithDefaultCallOptions(grpc.UseCompressor(gzip.Name)),
-		grpc.WithUnaryInterceptor(app.byteTracker),
+		grpc.WithChainUnaryInterceptor(app.retryInterceptor, app.byteTracker, app.compressionInterceptor),
 		grpc.WithStatsHandler(&statsHandler{metrics: &app.metrics}),
 	}
+	if app.config.debug {
+		opts = append(opts, grpc.WithStatsHandler(&debugStatsHandler{logger: app.logger}))
+	}
 
 	conn, err := grpc.NewClient(app.config.serverAddr, opts...)
 	if err != nil {
@@ -253,15 +570,56 @@ func (app *application) attemptConnect() error {
 	return nil
 }
 
-// addAuthContext adds API key to gRPC context
+// validateSessionKey checks that a configured session key is a base64 32-byte key
+func validateSessionKey(encoded string) error {
+	if encoded == "" {
+		return nil
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return fmt.Errorf("key must be base64 encoded: %w", err)
+	}
+	if len(key) != 32 {
+		return fmt.Errorf("key must decode to 32 bytes, got %d", len(key))
+	}
+	return nil
+}
+
+// withTimeout applies the -timeout deadline to a request's context
+func (app *application) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if app.config.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, app.config.timeout)
+}
+
+// timeoutError explains a request that failed because the -timeout deadline passed, returning other errors unchanged
+func (app *application) timeoutError(ctx context.Context, err error) error {
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	return status.Errorf(codes.DeadlineExceeded, "no reply within %s (use -timeout to wait longer)", app.config.timeout)
+}
+
+// addAuthContext adds API key (and session key, if configured) to gRPC context
 func (app *application) addAuthContext(ctx context.Context) context.Context {
 	md := metadata.Pairs("authorization", "Bearer "+app.config.apiKey)
+	if app.config.sessionKey != "" {
+		md.Set("x-session-key", app.config.sessionKey)
+	}
 	return metadata.NewOutgoingContext(ctx, md)
 }
 
+// newStartSessionRequest builds a StartSession request with the configured session TTL
+func (app *application) newStartSessionRequest() *pb.StartSessionRequest {
+	return &pb.StartSessionRequest{
+		IdleTimeoutSeconds: uint32(app.config.sessionTTL / time.Second),
+	}
+}
+
 func (app *application) startSession() error {
 	ctx := app.addAuthContext(context.Background())
-	req := &pb.StartSessionRequest{}
+	req := app.newStartSessionRequest()
 
 	resp, err := app.grpc.StartSession(ctx, req)
 	if err != nil {
@@ -269,12 +627,14 @@ func (app *application) startSession() error {
 	}
 
 	app.config.sessionID = resp.SessionId
+	app.logSessionTTL(resp)
+	app.saveSession()
 	return nil
 }
 
 func (app *application) resetSession() error {
 	ctx := app.addAuthContext(context.Background())
-	req := &pb.StartSessionRequest{}
+	req := app.newStartSessionRequest()
 
 	resp, err := app.grpc.StartSession(ctx, req)
 	if err != nil {
@@ -282,37 +642,74 @@ func (app *application) resetSession() error {
 	}
 
 	app.config.sessionID = resp.SessionId
+	app.logSessionTTL(resp)
 	app.messageIndex = 0
+	app.conversation = nil
 	app.metrics.resetSessionMetrics()
+	app.tokens = tokenEstimate{}
+	app.saveSession()
 	return nil
 }
 
+// logSessionTTL reports when the server adjusted the requested session TTL
+func (app *application) logSessionTTL(resp *pb.StartSessionResponse) {
+	granted := time.Duration(resp.IdleTimeoutSeconds) * time.Second
+	if app.config.sessionTTL > 0 && granted != app.config.sessionTTL {
+		app.logger.Warn("server adjusted session TTL", "requested", app.config.sessionTTL, "granted", granted)
+	}
+}
+
 func (app *application) startChat() {
+	editor := lineedit.New(os.Stdin, os.Stdout, app.loadInputHistory())
+	app.editor = editor
+
 	// Setup signal handling for graceful shutdown
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
+	// The first Ctrl+C while waiting for a reply cancels the request; otherwise the client exits
 	go func() {
-		<-sigChan
-		app.logger.Info("shutting down...")
-		app.conn.Close()
-		os.Exit(0)
+		for sig := range sigChan {
+			if sig == syscall.SIGINT && app.inFlight.interrupt() {
+				continue
+			}
+			editor.Restore()
+			app.logger.Info("shutting down...")
+			app.conn.Close()
+			os.Exit(0)
+		}
 	}()
 
 	app.logger.Info("starting interactive chat - type 'quit' to exit")
 	fmt.Println("microchat.ai client - type your message and press Enter")
-	fmt.Printf("Commands: '%s' to clear, '%s' to exit, Ctrl+C to quit\n", clearCommand, quitCommand)
+	fmt.Printf("Commands: '%s [N]' to show history, '%s md|json [path]' to save it, '%s [name]' to switch model, '%s [text]' to set the system prompt, '%s path' to attach a file, '%s path' or '%s command' to save or pipe the last reply, '%s [code N]' to copy it, '%s name [text]' to send a snippet, '%s' to clear, '%s' to exit, Ctrl+C to cancel a reply or quit\n",
+		historyCommand, exportCommand, modelCommand, systemCommand, attachCommand, saveCommand, pipeCommand, copyCommand, snippetCommand, clearCommand, quitCommand)
+	fmt.Printf("Start a line with '%s' to write several lines until the closing '%s', or use Alt+Enter for a new line\n", codeFence, codeFence)
+	app.printSystemPrompt()
 	fmt.Println("[Starting session - 0 B sent, 0 B received]")
-	fmt.Print("> ")
 
-	scanner := bufio.NewScanner(os.Stdin)
-	for scanner.Scan() {
-		input := strings.TrimSpace(scanner.Text())
+	for {
+		app.flushQueueIfConnected()
+		line, err := readInput(editor, app.prompt())
+		if errors.Is(err, lineedit.ErrInterrupted) {
+			app.logger.Info("shutting down...")
+			break
+		}
+		if err != nil {
+			if err != io.EOF {
+				app.logger.Error("error reading input", "error", err)
+			}
+			break
+		}
 
+		input := strings.TrimSpace(line)
 		if input == "" {
-			fmt.Print("> ")
+			app.flushQueue() // Enter on an empty line retries queued messages
 			continue
 		}
+		if err := editor.AddHistory(input); err != nil {
+			app.logger.Warn("failed to save input history", "error", err)
+		}
 
 		if input == quitCommand {
 			app.logger.Info("goodbye!")
@@ -323,62 +720,169 @@ func (app *application) startChat() {
 			fmt.Print("\033[H\033[2J") // Clear terminal
 			if err := app.resetSession(); err != nil {
 				app.logger.Error("failed to reset session", "error", err)
-				fmt.Printf("Error: Failed to clear session. Please try again.\n")
+				app.printError("Failed to clear session. Please try again.")
 			} else {
 				fmt.Println("microchat.ai client - Session cleared")
-				fmt.Printf("Commands: '%s' to clear, '%s' to exit\n", clearCommand, quitCommand)
+				fmt.Printf("Commands: '%s [N]' to show history, '%s md|json [path]' to save it, '%s [name]' to switch model, '%s [text]' to set the system prompt, '%s path' to attach a file, '%s path' or '%s command' to save or pipe the last reply, '%s [code N]' to copy it, '%s name [text]' to send a snippet, '%s' to clear, '%s' to exit\n",
+					historyCommand, exportCommand, modelCommand, systemCommand, attachCommand, saveCommand, pipeCommand, copyCommand, snippetCommand, clearCommand, quitCommand)
+				app.printSystemPrompt()
 				app.displayMetrics()
 			}
-			fmt.Print("> ")
 			continue
 		}
 
-		if err := app.sendMessage(input); err != nil {
-			grpcStatus, ok := status.FromError(err)
-			if ok {
-				switch grpcStatus.Code() {
-				case codes.Internal, codes.Unavailable:
-					fmt.Printf("Error: %s (server is experiencing issues)\n", grpcStatus.Message())
-				default:
-					fmt.Printf("Error: %s\n", grpcStatus.Message())
-				}
-			} else {
-				app.logger.Error("failed to send message", "error", err)
-				fmt.Printf("Error: Connection failed. Please try again.\n")
+		if isHistoryCommand(input) {
+			limit, err := parseHistoryCommand(input)
+			if err != nil {
+				fmt.Println(err)
+			} else if err := app.showHistory(limit); err != nil {
+				app.printRequestError("failed to get history", err)
+			}
+			continue
+		}
+
+		if isExportCommand(input) {
+			if err := app.exportSession(input); err != nil {
+				app.printRequestError("failed to export session", err)
+			}
+			continue
+		}
+
+		if isModelCommand(input) {
+			if err := app.handleModelCommand(input); err != nil {
+				app.printRequestError("failed to list models", err)
+			}
+			continue
+		}
+
+		if isSystemCommand(input) {
+			app.handleSystemCommand(input)
+			continue
+		}
+
+		if isAttachCommand(input) {
+			app.handleAttachCommand(input)
+			continue
+		}
+
+		if isSaveCommand(input) {
+			if err := app.saveReply(input); err != nil {
+				app.printRequestError("failed to get the last reply", err)
+			}
+			continue
+		}
+
+		if isPipeCommand(input) {
+			if err := app.pipeReply(input); err != nil {
+				app.printRequestError("failed to get the last reply", err)
+			}
+			continue
+		}
+
+		if isCopyCommand(input) {
+			if err := app.copyReply(input); err != nil {
+				app.printRequestError("failed to get the last reply", err)
 			}
+			continue
+		}
+
+		if isSnippetCommand(input) {
+			app.handleSnippetCommand(input)
+			continue
 		}
 
-		fmt.Print("> ")
+		app.handleMessage(input)
 	}
 
-	if err := scanner.Err(); err != nil {
-		app.logger.Error("error reading input", "error", err)
+	if n := len(app.queue); n > 0 {
+		app.printError("%d queued message(s) weren't sent", n)
+	}
+}
+
+// loadInputHistory loads previously entered lines from ~/.microchat_history
+// History is kept in memory only when the home directory or file can't be used
+func (app *application) loadInputHistory() *lineedit.History {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		app.logger.Warn("input history won't be saved", "error", err)
+		return lineedit.NewHistory("", inputHistorySize)
+	}
+	history := lineedit.NewHistory(filepath.Join(home, inputHistoryFile), inputHistorySize)
+	if err := history.Load(); err != nil {
+		app.logger.Warn("failed to load input history", "error", err)
+	}
+	return history
+}
+
+// printRequestError shows a failed RPC to the user, logging errors that didn't come from the server
+func (app *application) printRequestError(action string, err error) {
+	grpcStatus, ok := status.FromError(err)
+	if !ok {
+		app.logger.Error(action, "error", err)
+		app.printError("Connection failed. Please try again.")
+		return
+	}
+	switch grpcStatus.Code() {
+	case codes.Internal, codes.Unavailable:
+		app.printError("%s (server is experiencing issues)", grpcStatus.Message())
+	default:
+		app.printError("%s", grpcStatus.Message())
 	}
 }
 
 func (app *application) sendMessage(message string) error {
-	ctx := app.addAuthContext(context.Background())
+	defer app.checkBudget()
+	ctx, cancel := app.withTimeout(app.addAuthContext(context.Background()))
+	defer cancel()
+	ctx, done := app.inFlight.start(ctx)
+	defer done()
 	req := &pb.ChatRequest{
 		SessionId:    app.config.sessionID, // Server-generated UUID session ID
 		Model:        app.config.model,
 		Message:      message,
 		MessageIndex: app.messageIndex, // Layer 4: Include our message index
+		SystemPrompt: app.config.systemPrompt,
 	}
 
-	resp, err := app.grpc.Chat(ctx, req)
+	app.estimatePrompt(message)
+	sent := time.Now()
+	app.recordTranscript(sent, "You", message)
+	spin := app.waiting()
+	resp, note, err := app.chatWithReconnect(ctx, req, spin)
+	spin.stop()
+	if errors.Is(ctx.Err(), context.Canceled) {
+		// The server may still finish the turn; if it does, the next reply reports the diverged history and the conversation is resynced
+		fmt.Println("request cancelled")
+		return errCancelled
+	}
+	elapsed := time.Since(sent).Round(time.Second)
 	if err != nil {
-		return err
+		app.notifyReply(elapsed, fmt.Sprintf("Request failed after %s", elapsed))
+		return app.timeoutError(ctx, err)
+	}
+	app.notifyReply(elapsed, fmt.Sprintf("Reply ready after %s", elapsed)) // Before the pager, which waits for the user
+	if note != "" {
+		fmt.Println(note)
 	}
+	app.recordTranscript(time.Now(), assistantLabel(app.config.model), resp.Reply)
 
 	// Layer 4: Update our message index from server's response
 	app.messageIndex = resp.MessageCount
+	app.lastReply = resp.Reply
+	app.addTurn(message, resp.Reply)
+	received := time.Now()
 
-	fmt.Printf("Assistant: %s\n", resp.Reply)
+	if lines, ok := app.pageReply(resp.Reply); ok {
+		fmt.Printf("%s [%d-line reply shown in the pager - '%s' to see it again]\n", app.theme.assistant("Assistant:"), lines, historyCommand)
+	} else {
+		fmt.Printf("%s %s\n", app.theme.assistant("Assistant:"), resp.Reply)
+	}
+	app.recordTurn(req, resp, sent, received)
 	app.displayMetrics()
 
 	// Layer 4: Log delta protocol info when detailed metrics enabled
 	if app.config.metricsDetail {
-		fmt.Printf("Delta: Client index=%d, Server count=%d\n",
+		app.printMetrics("Delta: Client index=%d, Server count=%d",
 			req.MessageIndex, resp.MessageCount)
 	}
 
@@ -396,17 +900,20 @@ func (app *application) displayMetrics() {
 		sessionPayloadOut, sessionPayloadIn, sessionWireOut, sessionWireIn := app.metrics.getSessionTotals()
 
 		fmt.Println()
-		fmt.Printf("Message: [Payload: ↑%s ↓%s] [Wire (gzip): ↑%s ↓%s]\n",
-			formatBytes(msgPayloadOut), formatBytes(msgPayloadIn),
-			formatBytes(msgWireOut), formatBytes(msgWireIn))
-		fmt.Printf("Session: [Payload: ↑%s ↓%s] [Wire (gzip): ↑%s ↓%s]\n",
-			formatBytes(sessionPayloadOut), formatBytes(sessionPayloadIn),
+		app.printMetrics("Tokens: %s (estimated prompt)", app.tokenSummary())
+		compression := app.compressionLabel()
+		app.printMetrics("Message: [Payload: ↑%s ↓%s] [Wire (%s): ↑%s ↓%s] [Ratio: ↑%s ↓%s]",
+			formatBytes(msgPayloadOut), formatBytes(msgPayloadIn), compression,
+			formatBytes(msgWireOut), formatBytes(msgWireIn),
+			compressionRatio(msgPayloadOut, msgWireOut), compressionRatio(msgPayloadIn, msgWireIn))
+		app.printMetrics("Session: [Payload: ↑%s ↓%s] [Wire (%s): ↑%s ↓%s]",
+			formatBytes(sessionPayloadOut), formatBytes(sessionPayloadIn), compression,
 			formatBytes(sessionWireOut), formatBytes(sessionWireIn))
 
 		if app.config.metricsTotal {
 			lifetimePayloadOut, lifetimePayloadIn, lifetimeWireOut, lifetimeWireIn := app.metrics.getLifetimeTotals()
-			fmt.Printf("Lifetime: [Payload: ↑%s ↓%s] [Wire (gzip): ↑%s ↓%s]\n",
-				formatBytes(lifetimePayloadOut), formatBytes(lifetimePayloadIn),
+			app.printMetrics("Lifetime: [Payload: ↑%s ↓%s] [Wire (%s): ↑%s ↓%s]",
+				formatBytes(lifetimePayloadOut), formatBytes(lifetimePayloadIn), compression,
 				formatBytes(lifetimeWireOut), formatBytes(lifetimeWireIn))
 		}
 		fmt.Println()
@@ -416,11 +923,11 @@ func (app *application) displayMetrics() {
 
 		if app.config.metricsTotal {
 			_, _, lifetimeWireOut, lifetimeWireIn := app.metrics.getLifetimeTotals()
-			fmt.Printf("[Session: ↑%s ↓%s] [Total: ↑%s ↓%s]\n",
+			app.printMetrics("[Session: ↑%s ↓%s] [Total: ↑%s ↓%s] [%s]",
 				formatBytes(sessionWireOut), formatBytes(sessionWireIn),
-				formatBytes(lifetimeWireOut), formatBytes(lifetimeWireIn))
+				formatBytes(lifetimeWireOut), formatBytes(lifetimeWireIn), app.tokenSummary())
 		} else {
-			fmt.Printf("[↑%s ↓%s]\n", formatBytes(sessionWireOut), formatBytes(sessionWireIn))
+			app.printMetrics("[↑%s ↓%s] [%s]", formatBytes(sessionWireOut), formatBytes(sessionWireIn), app.tokenSummary())
 		}
 
 		// Reset message counters even though we don't display them
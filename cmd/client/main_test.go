@@ -17,13 +17,14 @@ func generateSessionID() string {
 
 func setupTestApp(t *testing.T) *application {
 	// Set required environment variables for testing
-	os.Setenv("CA_CERT_FILE", "certs/ca.crt")
 	os.Setenv("MICROCHAT_API_KEY", "test-key")
 
 	cfg := config{
 		serverAddr: "localhost:4000",
 		model:      pb.Model_GEMINI_2_5_FLASH_LITE,
 		apiKey:     os.Getenv("MICROCHAT_API_KEY"), // Get API key from environment
+		serverName: "localhost",
+		caCertFile: "certs/ca.crt",
 	}
 
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
@@ -31,6 +31,8 @@ type metrics struct {
 	msgWireBytesIn     int64
 	msgWireBytesOut    int64
 
+	replyCompression string // Compressor the last reply was sent with, empty for none
+
 	mu sync.RWMutex
 }
 
@@ -56,6 +58,18 @@ func (m *metrics) addWireBytes(out, in int64) {
 	m.msgWireBytesIn += in
 }
 
+func (m *metrics) setReplyCompression(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.replyCompression = name
+}
+
+func (m *metrics) getReplyCompression() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.replyCompression
+}
+
 func (m *metrics) getSessionPayloadTotals() (int64, int64) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -125,11 +139,16 @@ func (m *metrics) resetSessionMetrics() {
 }
 
 func formatBytes(bytes int64) string {
-	if bytes < kibibyte {
+	switch {
+	case bytes < kibibyte:
 		return fmt.Sprintf("%d B", bytes)
+	case bytes < kibibyte*kibibyte:
+		return fmt.Sprintf("%.1f KB", float64(bytes)/kibibyte)
+	case bytes < kibibyte*kibibyte*kibibyte:
+		return fmt.Sprintf("%.1f MB", float64(bytes)/(kibibyte*kibibyte))
+	default:
+		return fmt.Sprintf("%.1f GB", float64(bytes)/(kibibyte*kibibyte*kibibyte))
 	}
-	kb := float64(bytes) / kibibyte
-	return fmt.Sprintf("%.1f KB", kb)
 }
 
 func (app *application) byteTracker(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
@@ -172,6 +191,7 @@ func (h *statsHandler) HandleRPC(ctx context.Context, s stats.RPCStats) {
 		if stat.WireLength > 0 {
 			h.metrics.addWireBytes(0, int64(stat.WireLength))
 		}
+		h.metrics.setReplyCompression(stat.Compression)
 	case *stats.InTrailer:
 		// Track inbound trailers
 		if stat.WireLength > 0 {
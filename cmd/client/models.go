@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "microchat.ai/proto"
+)
+
+// modelNames are the names accepted by -model and /model, in the order they are listed
+var modelNames = []struct {
+	name  string
+	model pb.Model
+}{
+	{"gemini", pb.Model_GEMINI_2_5_FLASH_LITE},
+	{"echo", pb.Model_ECHO},
+	{"openai", pb.Model_OPENAI_COMPATIBLE},
+	{"local", pb.Model_OPENAI_COMPATIBLE}, // Alias for openai
+}
+
+// modelFromName returns the model for a -model or /model name, or for its enum name such as
+// GEMINI_2_5_FLASH_LITE
+func modelFromName(name string) (pb.Model, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, m := range modelNames {
+		if m.name == name {
+			return m.model, true
+		}
+	}
+	if value, ok := pb.Model_value[strings.ToUpper(name)]; ok {
+		return pb.Model(value), true
+	}
+	return 0, false
+}
+
+// lookupModel returns the model for a name, checking the config file's aliases before the built-in names
+func lookupModel(name string, aliases map[string]pb.Model) (pb.Model, bool) {
+	if model, ok := aliases[strings.ToLower(strings.TrimSpace(name))]; ok {
+		return model, true
+	}
+	return modelFromName(name)
+}
+
+// parseModelAliases resolves the config file's model aliases, e.g. fast = "GEMINI_2_5_FLASH_LITE"
+// An alias names a built-in model, by its name or enum name, and may replace a built-in name
+func parseModelAliases(aliases map[string]string) (map[string]pb.Model, error) {
+	if len(aliases) == 0 {
+		return nil, nil
+	}
+	models := make(map[string]pb.Model, len(aliases))
+	for alias, target := range aliases {
+		if alias == "" || strings.ContainsAny(alias, " \t\n") {
+			return nil, fmt.Errorf("invalid model alias %q: must be a single word", alias)
+		}
+		model, ok := modelFromName(target)
+		if !ok {
+			return nil, fmt.Errorf("model alias %s names unknown model %q (expected one of %s)", alias, target, strings.Join(modelChoices(nil), ", "))
+		}
+		models[strings.ToLower(alias)] = model
+	}
+	return models, nil
+}
+
+// modelChoices lists the names a model can be selected by, the built-in ones first and then the aliases
+func modelChoices(aliases map[string]pb.Model) []string {
+	names := make([]string, 0, len(modelNames)+len(aliases))
+	for _, m := range modelNames {
+		if _, ok := aliases[m.name]; !ok {
+			names = append(names, m.name)
+		}
+	}
+	return append(names, slices.Sorted(maps.Keys(aliases))...)
+}
+
+// modelName returns the name used to select a model, or its enum name for models the client doesn't know
+func modelName(model pb.Model) string {
+	for _, m := range modelNames {
+		if m.model == model {
+			return m.name
+		}
+	}
+	return model.String()
+}
+
+// isModelCommand reports whether input is "/model", with or without a model name
+func isModelCommand(input string) bool {
+	return input == modelCommand || strings.HasPrefix(input, modelCommand+" ")
+}
+
+// handleModelCommand lists the server's models for "/model" and switches to the named model for "/model <name>"
+// The switch is confirmed against the server's ListModels, so a model the server can't serve
+// isn't silently replaced by a fallback provider
+func (app *application) handleModelCommand(input string) error {
+	arg := strings.TrimSpace(strings.TrimPrefix(input, modelCommand))
+
+	ctx := app.addAuthContext(context.Background())
+	resp, err := app.grpc.ListModels(ctx, &pb.ListModelsRequest{})
+	if status.Code(err) == codes.Unimplemented {
+		resp, err = nil, nil // Older servers can't list models; switch without confirming
+	}
+	if err != nil {
+		return err
+	}
+
+	if arg == "" {
+		printModels(resp, app.config.model, app.config.modelAliases)
+		return nil
+	}
+
+	model, ok := lookupModel(arg, app.config.modelAliases)
+	if !ok {
+		fmt.Printf("Unknown model %q (expected one of %s)\n", arg, strings.Join(modelChoices(app.config.modelAliases), ", "))
+		return nil
+	}
+
+	if resp != nil {
+		var info *pb.ModelInfo
+		for _, m := range resp.Models {
+			if m.Model == model {
+				info = m
+			}
+		}
+		switch {
+		case info == nil:
+			fmt.Printf("The server doesn't serve %s; keeping %s\n", arg, modelName(app.config.model))
+			return nil
+		case !info.Available && info.UnavailableReason != "unhealthy":
+			fmt.Printf("%s isn't available on this server (%s); keeping %s\n", info.DisplayName, info.UnavailableReason, modelName(app.config.model))
+			return nil
+		case !info.Available:
+			fmt.Println(app.theme.errorText(fmt.Sprintf("Warning: %s is currently unhealthy, replies may come from a fallback provider", info.DisplayName)))
+		}
+	}
+
+	app.config.model = model
+	app.config.modelString = modelName(model)
+	app.logger.Info("switched model", "model", app.config.modelString)
+	app.saveSession()
+	fmt.Printf("Switched to %s\n", app.config.modelString)
+	return nil
+}
+
+// printModels lists the server's models, marking the current one, followed by the config file's aliases
+func printModels(resp *pb.ListModelsResponse, current pb.Model, aliases map[string]pb.Model) {
+	if resp == nil {
+		fmt.Printf("Current model: %s (the server can't list its models)\n", modelName(current))
+		return
+	}
+	for _, m := range resp.Models {
+		marker := " "
+		if m.Model == current {
+			marker = "*"
+		}
+		availability := "available"
+		if !m.Available {
+			availability = "unavailable: " + m.UnavailableReason
+		}
+		fmt.Printf("%s %-8s %s (%s)\n", marker, modelName(m.Model), m.DisplayName, availability)
+	}
+	if len(aliases) > 0 {
+		pairs := make([]string, 0, len(aliases))
+		for _, alias := range slices.Sorted(maps.Keys(aliases)) {
+			pairs = append(pairs, alias+"="+modelName(aliases[alias]))
+		}
+		fmt.Printf("Aliases: %s\n", strings.Join(pairs, ", "))
+	}
+	fmt.Printf("Use '%s <name>' to switch\n", modelCommand)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	pb "microchat.ai/proto"
+)
+
+// fakeModelsClient serves ListModels from a fixed response
+type fakeModelsClient struct {
+	pb.ChatServiceClient
+	resp *pb.ListModelsResponse
+	err  error
+}
+
+func (f *fakeModelsClient) ListModels(ctx context.Context, in *pb.ListModelsRequest, opts ...grpc.CallOption) (*pb.ListModelsResponse, error) {
+	return f.resp, f.err
+}
+
+func TestModelNames(t *testing.T) {
+	for name, want := range map[string]pb.Model{"gemini": pb.Model_GEMINI_2_5_FLASH_LITE, " Echo ": pb.Model_ECHO, "local": pb.Model_OPENAI_COMPATIBLE} {
+		if model, ok := modelFromName(name); !ok || model != want {
+			t.Errorf("%q: expected %v, got %v (ok %v)", name, want, model, ok)
+		}
+	}
+	if model, ok := modelFromName("gemini_2_5_flash_lite"); !ok || model != pb.Model_GEMINI_2_5_FLASH_LITE {
+		t.Errorf("Expected the enum name to select gemini, got %v (ok %v)", model, ok)
+	}
+	if _, ok := modelFromName("gpt-9"); ok {
+		t.Error("Expected an unknown name to be rejected")
+	}
+	if modelName(pb.Model_OPENAI_COMPATIBLE) != "openai" || modelName(pb.Model(99)) != "99" {
+		t.Errorf("Unexpected model names: %q, %q", modelName(pb.Model_OPENAI_COMPATIBLE), modelName(pb.Model(99)))
+	}
+}
+
+func TestHandleModelCommand(t *testing.T) {
+	fake := &fakeModelsClient{resp: &pb.ListModelsResponse{Models: []*pb.ModelInfo{
+		{Model: pb.Model_GEMINI_2_5_FLASH_LITE, Provider: "gemini", Available: true},
+		{Model: pb.Model_ECHO, Provider: "echo", UnavailableReason: "dev_only"},
+		{Model: pb.Model_OPENAI_COMPATIBLE, Provider: "openai_compat", UnavailableReason: "unhealthy"},
+	}}}
+	app := &application{
+		config: config{model: pb.Model_GEMINI_2_5_FLASH_LITE},
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		grpc:   fake,
+	}
+
+	// Models the server can't serve are refused
+	for _, input := range []string{"/model echo", "/model gpt-9"} {
+		if err := app.handleModelCommand(input); err != nil || app.config.model != pb.Model_GEMINI_2_5_FLASH_LITE {
+			t.Errorf("%q: expected the model to be kept, got %v (%v)", input, app.config.model, err)
+		}
+	}
+
+	// An unhealthy model is switched to with a warning
+	if err := app.handleModelCommand("/model openai"); err != nil || app.config.model != pb.Model_OPENAI_COMPATIBLE {
+		t.Errorf("Expected a switch to openai, got %v (%v)", app.config.model, err)
+	}
+	if err := app.handleModelCommand("/model"); err != nil || app.config.model != pb.Model_OPENAI_COMPATIBLE {
+		t.Errorf("Expected listing models not to switch, got %v (%v)", app.config.model, err)
+	}
+
+	// Aliases from the config file switch like the built-in names
+	app.config.modelAliases = map[string]pb.Model{"fast": pb.Model_GEMINI_2_5_FLASH_LITE}
+	if err := app.handleModelCommand("/model fast"); err != nil || app.config.model != pb.Model_GEMINI_2_5_FLASH_LITE || app.config.modelString != "gemini" {
+		t.Errorf("Expected a switch to gemini through its alias, got %v %q (%v)", app.config.model, app.config.modelString, err)
+	}
+
+	// Servers without ListModels switch without confirming; other errors are returned
+	fake.resp, fake.err = nil, status.Error(codes.Unimplemented, "unknown method")
+	if err := app.handleModelCommand("/model echo"); err != nil || app.config.model != pb.Model_ECHO {
+		t.Errorf("Expected an unconfirmed switch to echo, got %v (%v)", app.config.model, err)
+	}
+	fake.err = status.Error(codes.Unavailable, "down")
+	if err := app.handleModelCommand("/model gemini"); status.Code(err) != codes.Unavailable || app.config.model != pb.Model_ECHO {
+		t.Errorf("Expected the error to be returned without switching, got %v (%v)", app.config.model, err)
+	}
+}
+
+func TestModelAliases(t *testing.T) {
+	aliases, err := parseModelAliases(map[string]string{"Fast": "GEMINI_2_5_FLASH_LITE", "local": "echo", "vllm": "openai"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]pb.Model{"fast": pb.Model_GEMINI_2_5_FLASH_LITE, "FAST": pb.Model_GEMINI_2_5_FLASH_LITE, "local": pb.Model_ECHO, "openai": pb.Model_OPENAI_COMPATIBLE} {
+		if model, ok := lookupModel(name, aliases); !ok || model != want {
+			t.Errorf("%q: expected %v, got %v (ok %v)", name, want, model, ok)
+		}
+	}
+	if got, want := strings.Join(modelChoices(aliases), ","), "gemini,echo,openai,fast,local,vllm"; got != want {
+		t.Errorf("Expected choices %q, got %q", want, got)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	if model := parseModel("vllm", aliases, logger); model != pb.Model_OPENAI_COMPATIBLE {
+		t.Errorf("Expected -model to accept an alias, got %v", model)
+	}
+	if model := parseModel("gpt-9", aliases, logger); model != pb.Model_GEMINI_2_5_FLASH_LITE {
+		t.Errorf("Expected an unknown model to fall back to gemini, got %v", model)
+	}
+
+	for _, bad := range []map[string]string{{"fast": "OLLAMA"}, {"very fast": "gemini"}, {"fast": "slow"}} {
+		if _, err := parseModelAliases(bad); err == nil {
+			t.Errorf("Expected %v to be rejected", bad)
+		}
+	}
+}
+
+func TestFileConfigModelAliases(t *testing.T) {
+	path := writeConfigFile(t, `
+model = "fast"
+
+[model_aliases]
+fast = "GEMINI_2_5_FLASH_LITE"
+dev = "echo"
+
+[profiles.local]
+model = "dev"
+
+[profiles.local.model_aliases]
+dev = "openai"
+`)
+	fc, err := loadFileConfig(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged, _, err := fc.withProfile("local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfg config
+	if err := merged.apply(&cfg, nil); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.modelString != "dev" || cfg.modelAliases["dev"] != pb.Model_OPENAI_COMPATIBLE || cfg.modelAliases["fast"] != pb.Model_GEMINI_2_5_FLASH_LITE {
+		t.Errorf("Expected the profile's aliases over the top-level ones, got %q %v", cfg.modelString, cfg.modelAliases)
+	}
+	if fc.ModelAliases["dev"] != "echo" {
+		t.Errorf("Expected the top-level aliases to be left alone, got %v", fc.ModelAliases)
+	}
+
+	fc, _ = loadFileConfig(writeConfigFile(t, "[model_aliases]\nfast = \"OLLAMA\"\n"), true)
+	if err := fc.apply(&config{}, nil); err == nil || !strings.Contains(err.Error(), "OLLAMA") {
+		t.Errorf("Expected an alias for an unknown model to be an error, got %v", err)
+	}
+}
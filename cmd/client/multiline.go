@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"strings"
+
+	"microchat.ai/cmd/client/lineedit"
+)
+
+// codeFence starts and ends a multi-line block typed or pasted line by line
+const codeFence = "```"
+
+// lineReader reads one line of input; implemented by lineedit.Editor
+type lineReader interface {
+	ReadLine(prompt string) (string, error)
+}
+
+// readInput reads the next message, which continues over several lines while a ``` block is open
+// Input ending inside a block sends what was typed; Ctrl+C inside a block discards it
+func readInput(r lineReader, prompt string) (string, error) {
+	text, err := r.ReadLine(prompt)
+	if err != nil {
+		return "", err
+	}
+	for openFence(text) {
+		line, err := r.ReadLine(lineedit.ContinuationPrompt)
+		if err == io.EOF {
+			break
+		}
+		if errors.Is(err, lineedit.ErrInterrupted) {
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		text += "\n" + line
+	}
+	return text, nil
+}
+
+// openFence reports whether text has a ``` block that hasn't been closed, i.e. an odd number of fences
+// A fence may follow other text, as in "review this: ```go"
+func openFence(text string) bool {
+	return strings.Count(text, codeFence)%2 == 1
+}
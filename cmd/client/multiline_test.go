@@ -0,0 +1,65 @@
+package main
+
+import (
+	"io"
+	"testing"
+
+	"microchat.ai/cmd/client/lineedit"
+)
+
+// scriptedReader returns lines in order, then its error
+type scriptedReader struct {
+	lines   []string
+	err     error
+	prompts []string
+}
+
+func (r *scriptedReader) ReadLine(prompt string) (string, error) {
+	r.prompts = append(r.prompts, prompt)
+	if len(r.lines) == 0 {
+		return "", r.err
+	}
+	line := r.lines[0]
+	r.lines = r.lines[1:]
+	return line, nil
+}
+
+func TestReadInput(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		err   error
+		want  string
+	}{
+		{"single line", []string{"hello", "next"}, io.EOF, "hello"},
+		{"fenced block", []string{"```go", "func main() {", "}", "```", "next"}, io.EOF, "```go\nfunc main() {\n}\n```"},
+		{"text around block", []string{"review this: ```", "x := 1", "``` thanks"}, io.EOF, "review this: ```\nx := 1\n``` thanks"},
+		{"block closed on the same line", []string{"```x := 1```", "next"}, io.EOF, "```x := 1```"},
+		{"pasted block", []string{"```\na\n```", "next"}, io.EOF, "```\na\n```"},
+		{"input ends inside block", []string{"```", "partial"}, io.EOF, "```\npartial"},
+		{"interrupted block is discarded", []string{"```", "draft"}, lineedit.ErrInterrupted, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &scriptedReader{lines: tt.lines, err: tt.err}
+			got, err := readInput(r, "> ")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	r := &scriptedReader{lines: []string{"```", "a", "```"}}
+	readInput(r, "> ")
+	if r.prompts[0] != "> " || r.prompts[1] != lineedit.ContinuationPrompt {
+		t.Errorf("Expected continuation prompts inside a block, got %q", r.prompts)
+	}
+
+	if _, err := readInput(&scriptedReader{err: io.EOF}, "> "); err != io.EOF {
+		t.Errorf("Expected EOF to be returned, got %v", err)
+	}
+}
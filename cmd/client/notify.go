@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"microchat.ai/cmd/client/lineedit"
+)
+
+// notifyTitle heads the desktop notifications -notify sends
+const notifyTitle = "microchat.ai"
+
+// focusCheckTimeout bounds the command asking the window system which window has focus
+const focusCheckTimeout = 2 * time.Second
+
+// macTerminalApps maps $TERM_PROGRAM to the name macOS gives the terminal's process
+var macTerminalApps = map[string]string{
+	"Apple_Terminal": "Terminal",
+	"iTerm.app":      "iTerm2",
+	"WezTerm":        "wezterm-gui",
+	"ghostty":        "ghostty",
+	"vscode":         "Code",
+}
+
+// notifyReply rings the terminal bell and sends a desktop notification once a request that took at
+// least -notify-after has finished, unless the terminal is known to have focus
+func (app *application) notifyReply(elapsed time.Duration, body string) {
+	if !app.config.notify || elapsed < app.config.notifyAfter || !lineedit.IsTerminal(os.Stdout) {
+		return
+	}
+	if focused, known := terminalFocused(); known && focused {
+		return
+	}
+	ssh := os.Getenv("SSH_CONNECTION") != ""
+	if err := notify(os.Stdout, body, notifyCommand(runtime.GOOS, ssh, body), ssh); err != nil {
+		app.logger.Debug("failed to send notification", "error", err)
+	}
+}
+
+// notify rings the terminal bell on terminal and shows a notification with command or, when osc is
+// set, by asking the terminal to show one with an OSC 9 escape sequence, which works over SSH
+func notify(terminal io.Writer, body string, command []string, osc bool) error {
+	seq := "\a"
+	if osc {
+		seq += tmuxPassthrough("\033]9;" + notifyTitle + ": " + oneLine(body) + "\a")
+	}
+	if _, err := io.WriteString(terminal, seq); err != nil {
+		return err
+	}
+	if command == nil {
+		return nil
+	}
+	if _, err := exec.LookPath(command[0]); err != nil {
+		return nil // Only the bell, e.g. on Linux without notify-send
+	}
+	return exec.Command(command[0], command[1:]...).Run()
+}
+
+// notifyCommand returns the command that shows a desktop notification on goos, or nil when there's
+// none or the client runs over SSH, where it would show on the remote machine
+func notifyCommand(goos string, ssh bool, body string) []string {
+	if ssh {
+		return nil
+	}
+	switch goos {
+	case "darwin":
+		script := "display notification " + appleScriptString(body) + " with title " + appleScriptString(notifyTitle)
+		return []string{"osascript", "-e", script}
+	case "windows":
+		return nil
+	default:
+		return []string{"notify-send", "--app-name=" + notifyTitle, "--", notifyTitle, body} // A body starting with - isn't an option
+	}
+}
+
+// terminalFocused asks the window system whether the terminal's window has focus, reporting false for
+// known when it can't tell: only macOS with a recognized $TERM_PROGRAM and X11 terminals that set
+// $WINDOWID, with xdotool installed, can be checked
+func terminalFocused() (focused, known bool) {
+	if os.Getenv("SSH_CONNECTION") != "" || os.Getenv("TMUX") != "" {
+		return false, false // The window system can't see which pane or remote shell is in front
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), focusCheckTimeout)
+	defer cancel()
+	switch {
+	case runtime.GOOS == "darwin" && macTerminalApps[os.Getenv("TERM_PROGRAM")] != "":
+		out, err := exec.CommandContext(ctx, "osascript", "-e",
+			`tell application "System Events" to get name of first application process whose frontmost is true`).Output()
+		if err != nil {
+			return false, false
+		}
+		return strings.TrimSpace(string(out)) == macTerminalApps[os.Getenv("TERM_PROGRAM")], true
+	case os.Getenv("WINDOWID") != "" && os.Getenv("DISPLAY") != "":
+		out, err := exec.CommandContext(ctx, "xdotool", "getactivewindow").Output()
+		if err != nil {
+			return false, false
+		}
+		return sameWindow(string(out), os.Getenv("WINDOWID")), true
+	}
+	return false, false
+}
+
+// sameWindow compares X11 window IDs, which xdotool prints in decimal and terminals may give in hex
+func sameWindow(a, b string) bool {
+	x, errA := strconv.ParseUint(strings.TrimSpace(a), 0, 64)
+	y, errB := strconv.ParseUint(strings.TrimSpace(b), 0, 64)
+	return errA == nil && errB == nil && x == y
+}
+
+// appleScriptString quotes s as an AppleScript string literal
+func appleScriptString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// oneLine flattens text for an escape sequence, which ends at a bell or escape character
+func oneLine(text string) string {
+	return strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f {
+			return ' '
+		}
+		return r
+	}, text)
+}
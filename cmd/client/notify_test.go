@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+)
+
+func TestNotify(t *testing.T) {
+	t.Setenv("TMUX", "")
+
+	var terminal bytes.Buffer
+	if err := notify(&terminal, "Reply ready after 42s", nil, false); err != nil || terminal.String() != "\a" {
+		t.Errorf("Expected only the bell, got %q (%v)", terminal.String(), err)
+	}
+
+	// Over SSH the terminal is asked to show the notification; control characters can't end it early
+	terminal.Reset()
+	if err := notify(&terminal, "Reply\aready", nil, true); err != nil || terminal.String() != "\a\033]9;microchat.ai: Reply ready\a" {
+		t.Errorf("Expected the bell and an OSC 9 notification, got %q (%v)", terminal.String(), err)
+	}
+
+	// A missing notification command leaves just the bell
+	terminal.Reset()
+	if err := notify(&terminal, "Reply ready", []string{"microchat-no-such-command"}, false); err != nil || terminal.String() != "\a" {
+		t.Errorf("Expected the bell without an error, got %q (%v)", terminal.String(), err)
+	}
+}
+
+func TestNotifyCommand(t *testing.T) {
+	if got, want := notifyCommand("darwin", false, `Reply "ready"`), []string{"osascript", "-e", `display notification "Reply \"ready\"" with title "microchat.ai"`}; !slices.Equal(got, want) {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	if got, want := notifyCommand("linux", false, "--urgency=critical"), []string{"notify-send", "--app-name=microchat.ai", "--", "microchat.ai", "--urgency=critical"}; !slices.Equal(got, want) {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	if notifyCommand("linux", true, "Reply ready") != nil || notifyCommand("windows", false, "Reply ready") != nil {
+		t.Error("Expected no command over SSH or on Windows")
+	}
+}
+
+func TestSameWindow(t *testing.T) {
+	if !sameWindow("62914570\n", "0x3c0000a") || !sameWindow("62914570", "62914570") {
+		t.Error("Expected decimal and hex IDs of the same window to match")
+	}
+	if sameWindow("62914571", "62914570") || sameWindow("", "62914570") {
+		t.Error("Expected different or missing windows not to match")
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"microchat.ai/cmd/client/lineedit"
+	pb "microchat.ai/proto"
+)
+
+// Exit codes for one-shot mode (-m, -f or piped input)
+const (
+	exitError       = 1 // The request failed
+	exitUsage       = 2 // Invalid flags or unreadable input, matching the flag package
+	exitUnavailable = 3 // The server was unavailable, rate limited or too slow; retrying later may work
+)
+
+// maxOneShotInput caps how much of a file or piped input is read; the server enforces its own message limit
+const maxOneShotInput = 1 << 20
+
+// oneShotMessage builds the message for one-shot mode: the -m instruction, followed by the contents of input
+// input is the -f file or piped stdin, or nil when there is neither
+func oneShotMessage(instruction string, input io.Reader) (string, error) {
+	var parts []string
+	if strings.TrimSpace(instruction) != "" {
+		parts = append(parts, instruction)
+	}
+	if input != nil {
+		content, err := io.ReadAll(io.LimitReader(input, maxOneShotInput+1))
+		if err != nil {
+			return "", err
+		}
+		if len(content) > maxOneShotInput {
+			return "", fmt.Errorf("input is larger than %d bytes", maxOneShotInput)
+		}
+		if strings.TrimSpace(string(content)) != "" {
+			parts = append(parts, string(content))
+		}
+	}
+	if len(parts) == 0 {
+		return "", fmt.Errorf("nothing to send: the message and input are empty")
+	}
+	return strings.Join(parts, "\n\n"), nil
+}
+
+// oneShotInput opens the content to send after the -m instruction: the -f file ("-" for stdin),
+// or stdin when it's piped rather than a terminal
+// Returns nil when there is no such input, so the client runs interactively unless -m is given
+func oneShotInput(file string) (io.Reader, error) {
+	switch {
+	case file == "-":
+		return os.Stdin, nil
+	case file != "":
+		return os.Open(file)
+	case !lineedit.IsTerminal(os.Stdin):
+		return os.Stdin, nil
+	default:
+		return nil, nil
+	}
+}
+
+// runOneShot sends message, on a new session unless one was resumed, and writes only the reply to out
+func (app *application) runOneShot(out io.Writer, message string) error {
+	if app.config.sessionID == "" {
+		if err := app.startSession(); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := app.withTimeout(app.addAuthContext(context.Background()))
+	defer cancel()
+	app.recordTranscript(time.Now(), "You", message)
+	resp, err := app.grpc.Chat(ctx, &pb.ChatRequest{
+		SessionId:    app.config.sessionID,
+		Model:        app.config.model,
+		Message:      message,
+		SystemPrompt: app.config.systemPrompt,
+	})
+	if err != nil {
+		return app.timeoutError(ctx, err)
+	}
+	app.recordTranscript(time.Now(), assistantLabel(app.config.model), resp.Reply)
+
+	fmt.Fprint(out, resp.Reply)
+	if !strings.HasSuffix(resp.Reply, "\n") {
+		fmt.Fprintln(out)
+	}
+	return nil
+}
+
+// oneShotExitCode maps a failed one-shot request to the client's exit code
+func oneShotExitCode(err error) int {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.DeadlineExceeded:
+		return exitUnavailable
+	default:
+		return exitError
+	}
+}
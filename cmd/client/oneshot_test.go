@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	pb "microchat.ai/proto"
+)
+
+// fakeChatClient starts sessions and replies to Chat with a fixed response
+type fakeChatClient struct {
+	pb.ChatServiceClient
+	reply   string
+	chatErr error
+	sent    *pb.ChatRequest
+}
+
+func (f *fakeChatClient) StartSession(ctx context.Context, in *pb.StartSessionRequest, opts ...grpc.CallOption) (*pb.StartSessionResponse, error) {
+	return &pb.StartSessionResponse{SessionId: "session-1"}, nil
+}
+
+func (f *fakeChatClient) Chat(ctx context.Context, in *pb.ChatRequest, opts ...grpc.CallOption) (*pb.ChatResponse, error) {
+	f.sent = in
+	if f.chatErr != nil {
+		return nil, f.chatErr
+	}
+	return &pb.ChatResponse{Reply: f.reply}, nil
+}
+
+func TestOneShotMessage(t *testing.T) {
+	tests := []struct {
+		instruction string
+		input       io.Reader
+		want        string
+	}{
+		{"hello", nil, "hello"},
+		{"", strings.NewReader("func main() {}\n"), "func main() {}\n"},
+		{"review this", strings.NewReader("diff --git a/x b/x\n"), "review this\n\ndiff --git a/x b/x\n"},
+		{"just the instruction", strings.NewReader(" \n"), "just the instruction"},
+	}
+	for _, tt := range tests {
+		got, err := oneShotMessage(tt.instruction, tt.input)
+		if err != nil || got != tt.want {
+			t.Errorf("%q: expected %q, got %q (%v)", tt.instruction, tt.want, got, err)
+		}
+	}
+
+	if _, err := oneShotMessage("  ", strings.NewReader("")); err == nil {
+		t.Error("Expected an empty message to be an error")
+	}
+	if _, err := oneShotMessage("", strings.NewReader(strings.Repeat("x", maxOneShotInput+1))); err == nil {
+		t.Error("Expected oversized input to be an error")
+	}
+}
+
+func TestOneShotInput(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "prompt.txt")
+	if err := os.WriteFile(file, []byte("from file"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	input, err := oneShotInput(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message, err := oneShotMessage("", input); err != nil || message != "from file" {
+		t.Errorf("Expected the file's contents, got %q (%v)", message, err)
+	}
+	if input, _ := oneShotInput("-"); input != os.Stdin {
+		t.Error("Expected - to read stdin")
+	}
+	if _, err := oneShotInput(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("Expected a missing file to be an error")
+	}
+}
+
+func TestRunOneShot(t *testing.T) {
+	fake := &fakeChatClient{reply: "Hi there"}
+	app := &application{
+		config: config{model: pb.Model_ECHO},
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		grpc:   fake,
+	}
+
+	var out strings.Builder
+	if err := app.runOneShot(&out, "hello"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if out.String() != "Hi there\n" {
+		t.Errorf("Expected only the reply on stdout, got %q", out.String())
+	}
+	if fake.sent.SessionId != "session-1" || fake.sent.Model != pb.Model_ECHO || fake.sent.Message != "hello" {
+		t.Errorf("Unexpected request: %v", fake.sent)
+	}
+
+	fake.chatErr = status.Error(codes.ResourceExhausted, "daily limit reached")
+	out.Reset()
+	err := app.runOneShot(&out, "hello")
+	if oneShotExitCode(err) != exitUnavailable || out.Len() != 0 {
+		t.Errorf("Expected a rate limited request to exit %d without output, got %d and %q", exitUnavailable, oneShotExitCode(err), out.String())
+	}
+	if code := oneShotExitCode(status.Error(codes.InvalidArgument, "bad")); code != exitError {
+		t.Errorf("Expected exit code %d, got %d", exitError, code)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"microchat.ai/cmd/client/lineedit"
+)
+
+// pageReply shows a reply too tall for the terminal in $PAGER, or the built-in pager when it isn't set,
+// so the prompt isn't scrolled off screen
+// Returns the reply's height in lines, and false when it should be printed as usual instead
+func (app *application) pageReply(reply string) (int, bool) {
+	if app.config.noPager || app.editor == nil || !app.editor.Interactive() || !lineedit.IsTerminal(os.Stdout) {
+		return 0, false
+	}
+	width, height, ok := lineedit.Size(os.Stdout)
+	if !ok {
+		return 0, false
+	}
+	// The label shares the reply's first line, and the prompt needs the row below its last
+	lines := len(lineedit.WrapLines("Assistant: "+reply, width))
+	if lines < height {
+		return 0, false
+	}
+
+	var err error
+	if pager := os.Getenv("PAGER"); pager != "" {
+		release := app.inFlight.hold()
+		cmd := shellCommand(context.Background(), pager)
+		if !strings.HasSuffix(reply, "\n") {
+			reply += "\n"
+		}
+		cmd.Stdin = strings.NewReader(reply)
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		err = cmd.Run()
+		release()
+	} else {
+		err = app.editor.Page(reply)
+	}
+	if err != nil {
+		app.logger.Warn("failed to page the reply, printing it instead", "error", err)
+		return 0, false
+	}
+	return lines, true
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/status"
+
+	pb "microchat.ai/proto"
+)
+
+// queueProbeTimeout bounds the check for whether the server is back before queued messages are sent
+const queueProbeTimeout = 5 * time.Second
+
+// errCancelled is returned by sendMessage when Ctrl+C cancelled the request
+var errCancelled = errors.New("request cancelled")
+
+// prompt returns the input prompt, showing how many files are attached to the next message and how many
+// messages are waiting for the connection to return
+func (app *application) prompt() string {
+	prompt := app.theme.user("> ")
+	if len(app.attachments) > 0 {
+		prompt = app.theme.metrics(fmt.Sprintf("attached (%d)", len(app.attachments))) + " " + prompt
+	}
+	if len(app.queue) > 0 {
+		prompt = app.theme.errorText(fmt.Sprintf("queued (%d)", len(app.queue))) + " " + prompt
+	}
+	return prompt
+}
+
+// handleMessage sends a typed message, queueing it when the server can't be reached
+// While messages are queued, new ones join the end of the queue so they're sent in the order typed
+// Attached files go with the message, and are dropped once it has been sent or queued
+func (app *application) handleMessage(message string) {
+	if app.overBudget() {
+		return
+	}
+	if len(app.attachments) > 0 {
+		text, err := withAttachments(message, app.attachments)
+		if err != nil {
+			app.printError("%v - '%s clear' to drop the attachments", err, attachCommand)
+			return
+		}
+		message, app.attachments = text, nil
+	}
+
+	if len(app.queue) > 0 {
+		app.queue = append(app.queue, message)
+		app.flushQueue()
+		return
+	}
+
+	err := app.sendMessage(message)
+	switch {
+	case err == nil, errors.Is(err, errCancelled):
+	case status.Code(err) == codes.Unavailable:
+		app.queue = append(app.queue, message)
+		fmt.Println(app.theme.errorText(fmt.Sprintf("Can't reach the server - message queued (%d); press Enter to retry", len(app.queue))))
+	default:
+		app.printRequestError("failed to send message", err)
+	}
+}
+
+// flushQueue sends queued messages in order once the server can be reached again, stopping at the first
+// that still can't be sent
+func (app *application) flushQueue() {
+	if len(app.queue) == 0 || app.overBudget() {
+		return
+	}
+	if err := app.probeSession(); err != nil {
+		if status.Code(err) == codes.Unavailable {
+			fmt.Println(app.theme.errorText(fmt.Sprintf("Still can't reach the server - %d queued; press Enter to retry", len(app.queue))))
+		} else {
+			app.printRequestError("failed to reconnect", err)
+		}
+		return
+	}
+
+	for len(app.queue) > 0 {
+		message := app.queue[0]
+		fmt.Printf("Sending queued message: %s\n", summarizeText(message))
+		err := app.sendMessage(message)
+		switch {
+		case errors.Is(err, errCancelled):
+			app.queue = app.queue[1:]
+			return // Leave the rest queued rather than sending them straight after a cancel
+		case status.Code(err) == codes.Unavailable:
+			fmt.Println(app.theme.errorText(fmt.Sprintf("Lost the connection again - %d queued; press Enter to retry", len(app.queue))))
+			return
+		case err != nil:
+			app.printRequestError("failed to send queued message", err)
+		}
+		app.queue = app.queue[1:]
+	}
+}
+
+// flushQueueIfConnected sends queued messages when the connection has come back on its own
+func (app *application) flushQueueIfConnected() {
+	if len(app.queue) > 0 && app.conn != nil && app.conn.GetState() == connectivity.Ready {
+		app.flushQueue()
+	}
+}
+
+// probeSession checks that the server can be reached and the session still exists, starting a new
+// session when it was evicted while the connection was down
+func (app *application) probeSession() error {
+	if app.conn != nil {
+		app.conn.ResetConnectBackoff() // Redial now rather than when gRPC's own backoff expires
+	}
+	ctx, cancel := context.WithTimeout(app.addAuthContext(context.Background()), queueProbeTimeout)
+	defer cancel()
+
+	// Wait for the connection within the timeout rather than failing while it's still being redialed
+	history, err := app.grpc.GetHistory(ctx, &pb.GetHistoryRequest{SessionId: app.config.sessionID}, grpc.WaitForReady(true))
+	switch {
+	case err == nil:
+		app.messageIndex = uint32(len(history.Messages))
+		return nil
+	case status.Code(err) == codes.NotFound:
+		if err := app.resetSession(); err != nil {
+			return err
+		}
+		fmt.Println("The session expired while the connection was down; queued messages start a new one")
+		return nil
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return status.Error(codes.Unavailable, "no response from the server")
+	default:
+		return err
+	}
+}
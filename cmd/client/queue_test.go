@@ -0,0 +1,52 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMessageQueue(t *testing.T) {
+	fake := &flakyChatClient{drops: 100}
+	app := &application{
+		config: config{sessionID: "session-1", retry: testRetryPolicy},
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		grpc:   fake,
+	}
+
+	// A message that can't be delivered even after reconnecting is queued
+	app.handleMessage("first")
+	if !slices.Equal(app.queue, []string{"first"}) || !strings.Contains(app.prompt(), "queued (1)") {
+		t.Fatalf("Expected the message to be queued, got %v and prompt %q", app.queue, app.prompt())
+	}
+
+	// While the server is still unreachable, retrying keeps the queue and later messages join its end
+	fake.historyErr = status.Error(codes.Unavailable, "connection refused")
+	app.handleMessage("second")
+	if !slices.Equal(app.queue, []string{"first", "second"}) {
+		t.Fatalf("Expected both messages to be queued in order, got %v", app.queue)
+	}
+
+	// Once the server is back the queue is sent in order
+	fake.historyErr, fake.drops = nil, 0
+	app.handleMessage("third")
+	if len(app.queue) != 0 || !slices.Equal(fake.delivered, []string{"first", "second", "third"}) {
+		t.Errorf("Expected the queue to be flushed in order, got %v delivered and %v queued", fake.delivered, app.queue)
+	}
+	if app.prompt() != "> " {
+		t.Errorf("Expected the plain prompt with nothing queued, got %q", app.prompt())
+	}
+
+	// A session evicted during the outage is replaced before the queue is sent
+	fake.historyErr = status.Error(codes.NotFound, "session not found")
+	app.queue = []string{"fourth"}
+	app.flushQueue()
+	if len(app.queue) != 0 || app.config.sessionID != "session-2" {
+		t.Errorf("Expected the queue to be sent to a new session, got %v queued on %s", app.queue, app.config.sessionID)
+	}
+}
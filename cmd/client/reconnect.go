@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "microchat.ai/proto"
+)
+
+// chatWithReconnect sends a chat request, and when the connection drops redials under the retry policy,
+// checks the session still exists and sends the message again
+// An evicted session is replaced by a new one; note tells the user what happened when it isn't empty
+func (app *application) chatWithReconnect(ctx context.Context, req *pb.ChatRequest, spin *spinner) (resp *pb.ChatResponse, note string, err error) {
+	resp, err = app.grpc.Chat(ctx, req)
+	if status.Code(err) != codes.Unavailable || ctx.Err() != nil {
+		return resp, "", err
+	}
+	app.logger.Warn("connection lost, reconnecting", "error", err)
+
+	// Each attempt's GetHistory isn't retried on its own, since the attempts are retries already
+	b := app.config.retry.backoff()
+	for b.sleep(ctx) {
+		attempt := b.retries
+		spin.relabel(fmt.Sprintf("Reconnecting (attempt %d of %d)", attempt, app.config.retry.attempts))
+		if app.conn != nil {
+			app.conn.ResetConnectBackoff() // Redial now rather than when gRPC's own backoff expires
+		}
+
+		var expired bool
+		resp, expired, err = app.resendMessage(withoutRetries(ctx), req)
+		if status.Code(err) == codes.Unavailable && ctx.Err() == nil {
+			app.logger.Warn("reconnect failed", "attempt", attempt, "error", err)
+			continue
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		app.logger.Info("reconnected", "attempt", attempt, "session_id", app.config.sessionID)
+		if expired {
+			return resp, "Reconnected - the session had expired, so your message started a new one", nil
+		}
+		return resp, "Reconnected", nil
+	}
+	return nil, "", err
+}
+
+// resendMessage checks with GetHistory that the request's session survived the lost connection, starting a
+// new session when it didn't, then sends the message again
+// When the server had already stored the reply before the connection dropped, it's taken from the history
+// instead, so the message isn't sent twice
+func (app *application) resendMessage(ctx context.Context, req *pb.ChatRequest) (resp *pb.ChatResponse, expired bool, err error) {
+	history, err := app.grpc.GetHistory(ctx, &pb.GetHistoryRequest{SessionId: req.SessionId})
+	switch status.Code(err) {
+	case codes.OK:
+		count := uint32(len(history.Messages))
+		if count >= req.MessageIndex+2 {
+			if reply, ok := app.storedReply(history); ok {
+				return &pb.ChatResponse{Reply: reply, MessageCount: count}, false, nil
+			}
+		}
+		req.MessageIndex = count
+	case codes.NotFound:
+		if err := app.resetSession(); err != nil {
+			return nil, false, err
+		}
+		req.SessionId, req.MessageIndex, expired = app.config.sessionID, 0, true
+	default:
+		return nil, false, err
+	}
+
+	resp, err = app.grpc.Chat(ctx, req)
+	return resp, expired, err
+}
+
+// storedReply returns the text of the last message in history when it's an assistant reply
+func (app *application) storedReply(history *pb.GetHistoryResponse) (string, bool) {
+	if len(history.Messages) == 0 {
+		return "", false
+	}
+	entry, ok := parseHistoryMessage(history.Messages[len(history.Messages)-1])
+	if !ok || entry.role != "assistant" {
+		return "", false
+	}
+	if !history.Encrypted {
+		return entry.text, true
+	}
+	text, err := decryptHistoryText(app.config.sessionKey, app.config.sessionID, entry.text)
+	if err != nil {
+		app.logger.Warn("failed to decrypt stored reply", "error", err)
+		return "", false
+	}
+	return text, true
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	pb "microchat.ai/proto"
+)
+
+// flakyChatClient fails its first `drops` Chat requests with Unavailable, as when the link goes down
+type flakyChatClient struct {
+	pb.ChatServiceClient
+	drops      int
+	chats      int
+	history    []string
+	historyErr error
+	started    int
+	delivered  []string // Messages Chat accepted, in order
+}
+
+func (f *flakyChatClient) Chat(ctx context.Context, in *pb.ChatRequest, opts ...grpc.CallOption) (*pb.ChatResponse, error) {
+	f.chats++
+	if f.chats <= f.drops {
+		return nil, status.Error(codes.Unavailable, "connection refused")
+	}
+	f.delivered = append(f.delivered, in.Message)
+	return &pb.ChatResponse{Reply: "Echo: " + in.Message, MessageCount: in.MessageIndex + 2}, nil
+}
+
+func (f *flakyChatClient) GetHistory(ctx context.Context, in *pb.GetHistoryRequest, opts ...grpc.CallOption) (*pb.GetHistoryResponse, error) {
+	if f.historyErr != nil {
+		return nil, f.historyErr
+	}
+	return &pb.GetHistoryResponse{Messages: f.history}, nil
+}
+
+func (f *flakyChatClient) StartSession(ctx context.Context, in *pb.StartSessionRequest, opts ...grpc.CallOption) (*pb.StartSessionResponse, error) {
+	f.started++
+	return &pb.StartSessionResponse{SessionId: "session-2"}, nil
+}
+
+func TestChatWithReconnect(t *testing.T) {
+	newApp := func(fake *flakyChatClient) *application {
+		return &application{
+			config: config{sessionID: "session-1", retry: testRetryPolicy},
+			logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+			grpc:   fake,
+		}
+	}
+	twoMessages := []string{"user [10:00:00 UTC]: hi", "assistant [10:00:01 UTC]: Echo: hi"}
+
+	// The message is sent again once the session is confirmed, from the server's message count
+	fake := &flakyChatClient{drops: 2, history: twoMessages}
+	req := &pb.ChatRequest{SessionId: "session-1", Message: "again", MessageIndex: 1}
+	resp, note, err := newApp(fake).chatWithReconnect(context.Background(), req, nil)
+	if err != nil || resp.Reply != "Echo: again" || note != "Reconnected" || req.MessageIndex != 2 {
+		t.Errorf("Expected the message to be sent again, got %+v %q %v (index %d)", resp, note, err, req.MessageIndex)
+	}
+
+	// A reply stored before the connection dropped is taken from the history instead
+	fake = &flakyChatClient{drops: 1, history: twoMessages}
+	resp, _, err = newApp(fake).chatWithReconnect(context.Background(), &pb.ChatRequest{SessionId: "session-1", Message: "hi"}, nil)
+	if err != nil || resp.Reply != "Echo: hi" || resp.MessageCount != 2 || fake.chats != 1 {
+		t.Errorf("Expected the stored reply without sending again, got %+v %v after %d chats", resp, err, fake.chats)
+	}
+
+	// An evicted session is replaced before sending again
+	fake = &flakyChatClient{drops: 1, historyErr: status.Error(codes.NotFound, "session not found")}
+	app := newApp(fake)
+	resp, note, err = app.chatWithReconnect(context.Background(), &pb.ChatRequest{SessionId: "session-1", Message: "hi"}, nil)
+	if err != nil || resp.Reply != "Echo: hi" || !strings.Contains(note, "expired") || app.config.sessionID != "session-2" || fake.started != 1 {
+		t.Errorf("Expected a new session, got %+v %q %v on %s", resp, note, err, app.config.sessionID)
+	}
+
+	// The request fails once every attempt has
+	fake = &flakyChatClient{drops: testRetryPolicy.attempts + 1}
+	if _, _, err := newApp(fake).chatWithReconnect(context.Background(), &pb.ChatRequest{SessionId: "session-1", Message: "hi"}, nil); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable after %d attempts, got %v", testRetryPolicy.attempts, err)
+	}
+	if fake.chats != testRetryPolicy.attempts+1 {
+		t.Errorf("Expected %d chat requests, got %d", testRetryPolicy.attempts+1, fake.chats)
+	}
+
+	// Other errors aren't retried
+	fake = &flakyChatClient{drops: 1, historyErr: status.Error(codes.PermissionDenied, "denied")}
+	if _, _, err := newApp(fake).chatWithReconnect(context.Background(), &pb.ChatRequest{SessionId: "session-1", Message: "hi"}, nil); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied, got %v", err)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "microchat.ai/proto"
+)
+
+// retryPolicy is how the client retries connecting at startup, reconnecting a chat request that lost
+// its connection, and idempotent requests the server couldn't be reached for
+type retryPolicy struct {
+	attempts int           // Retries after the first try, 0 to never retry
+	delay    time.Duration // Wait before the first retry, doubling for each later one up to maxDelay
+	maxDelay time.Duration
+	maxWait  time.Duration // Total wait across the retries, 0 for no limit
+	jitter   float64       // Share of each wait, 0-1, taken off at random so clients don't retry in step
+}
+
+// defaultRetryPolicy is the policy when -retries, -retry-delay, -retry-max-wait and -retry-jitter aren't set
+var defaultRetryPolicy = retryPolicy{attempts: 5, delay: time.Second, maxDelay: 30 * time.Second, jitter: 0.2}
+
+// validate checks the policy's flags or config file settings
+func (p retryPolicy) validate() error {
+	switch {
+	case p.attempts < 0:
+		return fmt.Errorf("retries must be 0 or more, got %d", p.attempts)
+	case p.delay <= 0:
+		return fmt.Errorf("retry delay must be positive, got %s", p.delay)
+	case p.maxWait < 0:
+		return fmt.Errorf("retry max wait must be 0 or more, got %s", p.maxWait)
+	case p.jitter < 0 || p.jitter > 1:
+		return fmt.Errorf("retry jitter must be between 0 and 1, got %g", p.jitter)
+	}
+	return nil
+}
+
+// backoff counts retries under a retryPolicy
+type backoff struct {
+	policy  retryPolicy
+	retries int           // Retries so far
+	next    time.Duration // Wait before the next retry, before jitter
+	waited  time.Duration
+}
+
+// backoff starts counting retries
+func (p retryPolicy) backoff() *backoff {
+	return &backoff{policy: p, next: p.delay}
+}
+
+// wait returns how long to wait before the next retry, or false once the retries or the total wait
+// are used up
+func (b *backoff) wait() (time.Duration, bool) {
+	p := b.policy
+	if b.retries >= p.attempts {
+		return 0, false
+	}
+	d := b.next
+	b.next = min(b.next*2, max(p.maxDelay, p.delay))
+	if p.jitter > 0 {
+		d -= time.Duration(rand.Float64() * p.jitter * float64(d))
+	}
+	if p.maxWait > 0 {
+		if b.waited >= p.maxWait {
+			return 0, false
+		}
+		d = min(d, p.maxWait-b.waited)
+	}
+	b.waited += d
+	b.retries++
+	return d, true
+}
+
+// sleep waits before the next retry, returning false when the retries are used up or ctx ends first
+func (b *backoff) sleep(ctx context.Context) bool {
+	d, ok := b.wait()
+	if !ok {
+		return false
+	}
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// idempotentMethods are the RPCs that can be sent again safely when the server couldn't be reached
+// Health isn't retried, so -check reports the server as it is
+var idempotentMethods = map[string]bool{
+	pb.ChatService_GetHistory_FullMethodName:        true,
+	pb.ChatService_ExportSession_FullMethodName:     true,
+	pb.ChatService_ListSessions_FullMethodName:      true,
+	pb.ChatService_ListMySessions_FullMethodName:    true,
+	pb.ChatService_SearchHistory_FullMethodName:     true,
+	pb.ChatService_SearchAllSessions_FullMethodName: true,
+	pb.ChatService_KeepAlive_FullMethodName:         true,
+	pb.ChatService_ListKeyUsage_FullMethodName:      true,
+	pb.ChatService_GetQuota_FullMethodName:          true,
+	pb.ChatService_GetServerInfo_FullMethodName:     true,
+	pb.ChatService_ListModels_FullMethodName:        true,
+}
+
+// noRetryKey marks a context whose requests the retry interceptor leaves alone
+type noRetryKey struct{}
+
+// withoutRetries returns a context whose requests aren't retried, for callers with their own retry loop
+func withoutRetries(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noRetryKey{}, true)
+}
+
+// retryInterceptor retries idempotent requests that failed because the server couldn't be reached
+func (app *application) retryInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	if !idempotentMethods[method] || ctx.Value(noRetryKey{}) != nil {
+		return err
+	}
+	b := app.config.retry.backoff()
+	for status.Code(err) == codes.Unavailable && ctx.Err() == nil {
+		if !b.sleep(ctx) {
+			return err
+		}
+		app.logger.Debug("retrying request", "method", method, "retry", b.retries, "error", err)
+		err = invoker(ctx, method, req, reply, cc, opts...)
+	}
+	return err
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "microchat.ai/proto"
+)
+
+// testRetryPolicy retries without slowing tests down
+var testRetryPolicy = retryPolicy{attempts: 5, delay: time.Millisecond, maxDelay: time.Millisecond}
+
+func TestBackoff(t *testing.T) {
+	waits := func(p retryPolicy) []time.Duration {
+		var got []time.Duration
+		b := p.backoff()
+		for d, ok := b.wait(); ok; d, ok = b.wait() {
+			got = append(got, d)
+		}
+		return got
+	}
+	equal := func(got, want []time.Duration) bool {
+		if len(got) != len(want) {
+			return false
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	// Waits double up to the cap
+	p := retryPolicy{attempts: 5, delay: time.Second, maxDelay: 5 * time.Second}
+	if got, want := waits(p), []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}; !equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	// The total wait is capped, cutting the last wait short
+	p.maxWait = 5 * time.Second
+	if got, want := waits(p), []time.Duration{time.Second, 2 * time.Second, 2 * time.Second}; !equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	// No retries at all
+	if got := waits(retryPolicy{delay: time.Second}); len(got) != 0 {
+		t.Errorf("Expected no retries, got %v", got)
+	}
+
+	// Jitter only ever shortens a wait, by at most its share
+	p = retryPolicy{attempts: 100, delay: time.Second, maxDelay: time.Second, jitter: 0.5}
+	for _, d := range waits(p) {
+		if d < 500*time.Millisecond || d > time.Second {
+			t.Fatalf("Expected waits between 500ms and 1s, got %s", d)
+		}
+	}
+}
+
+func TestRetryPolicyValidate(t *testing.T) {
+	if err := defaultRetryPolicy.validate(); err != nil {
+		t.Errorf("Expected the default policy to be valid, got %v", err)
+	}
+	for _, p := range []retryPolicy{
+		{attempts: -1, delay: time.Second},
+		{attempts: 1},
+		{attempts: 1, delay: time.Second, maxWait: -time.Second},
+		{attempts: 1, delay: time.Second, jitter: 1.5},
+	} {
+		if err := p.validate(); err == nil {
+			t.Errorf("Expected %+v to be invalid", p)
+		}
+	}
+}
+
+func TestRetryInterceptor(t *testing.T) {
+	app := &application{
+		config: config{retry: retryPolicy{attempts: 2, delay: time.Millisecond, maxDelay: time.Millisecond}},
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	call := func(ctx context.Context, method string, failures int, code codes.Code) (int, error) {
+		calls := 0
+		invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			calls++
+			if calls <= failures {
+				return status.Error(code, "failed")
+			}
+			return nil
+		}
+		err := app.retryInterceptor(ctx, method, nil, nil, nil, invoker)
+		return calls, err
+	}
+	ctx := context.Background()
+
+	if calls, err := call(ctx, pb.ChatService_GetHistory_FullMethodName, 2, codes.Unavailable); err != nil || calls != 3 {
+		t.Errorf("Expected an idempotent request to succeed on its last retry, got %v after %d calls", err, calls)
+	}
+	if calls, err := call(ctx, pb.ChatService_GetHistory_FullMethodName, 3, codes.Unavailable); status.Code(err) != codes.Unavailable || calls != 3 {
+		t.Errorf("Expected Unavailable once the retries are used up, got %v after %d calls", err, calls)
+	}
+	if calls, _ := call(ctx, pb.ChatService_GetHistory_FullMethodName, 1, codes.PermissionDenied); calls != 1 {
+		t.Errorf("Expected other errors not to be retried, got %d calls", calls)
+	}
+	if calls, _ := call(ctx, pb.ChatService_Chat_FullMethodName, 1, codes.Unavailable); calls != 1 {
+		t.Errorf("Expected Chat not to be retried, got %d calls", calls)
+	}
+	if calls, _ := call(withoutRetries(ctx), pb.ChatService_GetHistory_FullMethodName, 1, codes.Unavailable); calls != 1 {
+		t.Errorf("Expected a request with its own retry loop not to be retried, got %d calls", calls)
+	}
+}
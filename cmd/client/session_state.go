@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "microchat.ai/proto"
+)
+
+// sessionStateFile is where the last interactive session is saved, in the user's home directory
+const sessionStateFile = ".microchat_session"
+
+// sessionState is the last interactive session, saved so -resume can continue it after a restart
+type sessionState struct {
+	ServerAddr   string    `json:"server_addr"`
+	Model        string    `json:"model"`
+	Profile      string    `json:"profile,omitempty"` // Config file profile the session was started with
+	SessionID    string    `json:"session_id"`
+	SystemPrompt string    `json:"system_prompt,omitempty"`
+	SavedAt      time.Time `json:"saved_at"`
+}
+
+// sessionStatePath returns the state file's path, or "" when there's no home directory
+func sessionStatePath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, sessionStateFile)
+}
+
+// loadSessionState reads the saved session, returning nil when none has been saved
+func loadSessionState(path string) (*sessionState, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state sessionState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("invalid session state in %s: %w", path, err)
+	}
+	if state.SessionID == "" {
+		return nil, nil
+	}
+	return &state, nil
+}
+
+// saveSessionState replaces the saved session, writing a temporary file first so a crash can't truncate it
+func saveSessionState(path string, state sessionState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// saveSession records the current session for -resume; a no-op when the client isn't saving state
+func (app *application) saveSession() {
+	if app.config.statePath == "" || app.config.sessionID == "" {
+		return
+	}
+	state := sessionState{
+		ServerAddr:   app.config.serverAddr,
+		Model:        modelName(app.config.model),
+		Profile:      app.config.profile,
+		SessionID:    app.config.sessionID,
+		SystemPrompt: app.config.systemPrompt,
+		SavedAt:      time.Now().UTC(),
+	}
+	if err := saveSessionState(app.config.statePath, state); err != nil {
+		app.logger.Warn("failed to save session state", "path", app.config.statePath, "error", err)
+	}
+}
+
+// resumeProfile returns the profile the last session was started with, so -resume reconnects with the same
+// server and API key; "" when there's no saved session or its profile is no longer in the config file
+func resumeProfile(fc *fileConfig, logger *slog.Logger) string {
+	path := sessionStatePath()
+	if path == "" {
+		return ""
+	}
+	state, err := loadSessionState(path)
+	if err != nil || state == nil || state.Profile == "" {
+		return ""
+	}
+	if _, ok := fc.Profiles[state.Profile]; !ok {
+		logger.Warn("the last session's profile is no longer in the config file", "profile", state.Profile)
+		return ""
+	}
+	return state.Profile
+}
+
+// resumeTarget returns the session to continue: the -session ID, or with -resume the last saved session
+// A saved session's server address, model and system prompt replace the defaults, but not -addr, -model or
+// -system given explicitly
+// Returns "" to start a new session
+func resumeTarget(cfg *config, sessionFlag string, resume bool, setFlags map[string]bool, logger *slog.Logger) string {
+	if sessionFlag != "" {
+		return sessionFlag
+	}
+	if !resume {
+		return ""
+	}
+	if cfg.statePath == "" {
+		logger.Warn("no home directory to load the last session from, starting a new session")
+		return ""
+	}
+
+	state, err := loadSessionState(cfg.statePath)
+	if err != nil {
+		logger.Warn("failed to load the last session, starting a new session", "error", err)
+		return ""
+	}
+	if state == nil {
+		logger.Warn("no previous session to resume, starting a new session")
+		return ""
+	}
+	if state.Profile != cfg.profile {
+		logger.Warn("the last session used a different profile, starting a new session", "profile", state.Profile)
+		return ""
+	}
+
+	if !setFlags["addr"] && state.ServerAddr != "" {
+		cfg.serverAddr = state.ServerAddr
+	}
+	if !setFlags["model"] {
+		if model, ok := lookupModel(state.Model, cfg.modelAliases); ok {
+			cfg.model, cfg.modelString = model, state.Model
+		}
+	}
+	if !setFlags["system"] {
+		cfg.systemPrompt = state.SystemPrompt
+	}
+	return state.SessionID
+}
+
+// resumeSession continues an existing session, checking with GetHistory that it still exists and belongs to this key
+func (app *application) resumeSession(sessionID string) error {
+	ctx := app.addAuthContext(context.Background())
+	resp, err := app.grpc.GetHistory(ctx, &pb.GetHistoryRequest{SessionId: sessionID})
+	if err != nil {
+		return err
+	}
+
+	app.config.sessionID = sessionID
+	app.messageIndex = uint32(len(resp.Messages))
+	app.conversation = app.historyEntries(resp)
+	app.tokens.context = app.historyTokens(resp)
+	return nil
+}
+
+// openSession continues resumeID when given and otherwise starts a new session, reporting whether it resumed
+// A -resume target that has expired is replaced by a new session; an explicit -session that can't be resumed is an error
+func (app *application) openSession(resumeID string, explicit bool) (bool, error) {
+	if resumeID != "" {
+		err := app.resumeSession(resumeID)
+		if err == nil {
+			app.saveSession()
+			return true, nil
+		}
+		if explicit || status.Code(err) != codes.NotFound {
+			return false, err
+		}
+		app.logger.Warn("last session has expired, starting a new session", "session_id", resumeID)
+	}
+	return false, app.startSession()
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	pb "microchat.ai/proto"
+)
+
+// fakeResumeClient serves GetHistory for one known session and starts new sessions
+type fakeResumeClient struct {
+	pb.ChatServiceClient
+	known    string
+	messages []string
+	err      error // Returned by GetHistory for other sessions
+}
+
+func (f *fakeResumeClient) GetHistory(ctx context.Context, in *pb.GetHistoryRequest, opts ...grpc.CallOption) (*pb.GetHistoryResponse, error) {
+	if in.SessionId != f.known {
+		return nil, f.err
+	}
+	return &pb.GetHistoryResponse{SessionId: in.SessionId, Messages: f.messages}, nil
+}
+
+func (f *fakeResumeClient) StartSession(ctx context.Context, in *pb.StartSessionRequest, opts ...grpc.CallOption) (*pb.StartSessionResponse, error) {
+	return &pb.StartSessionResponse{SessionId: "new-session"}, nil
+}
+
+func TestSessionState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state")
+	if state, err := loadSessionState(path); err != nil || state != nil {
+		t.Fatalf("Expected no state before one is saved, got %v (%v)", state, err)
+	}
+
+	saved := sessionState{ServerAddr: "microchat.ai:443", Model: "openai", SessionID: "session-1", SystemPrompt: "Be brief"}
+	if err := saveSessionState(path, saved); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected a private state file, got %v (%v)", info, err)
+	}
+	state, err := loadSessionState(path)
+	if err != nil || state == nil || *state != saved {
+		t.Fatalf("Expected %+v, got %+v (%v)", saved, state, err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// -resume uses the saved server, model and system prompt
+	cfg := config{serverAddr: "localhost:4000", model: pb.Model_GEMINI_2_5_FLASH_LITE, statePath: path}
+	if id := resumeTarget(&cfg, "", true, map[string]bool{"resume": true}, logger); id != "session-1" {
+		t.Errorf("Expected the saved session, got %q", id)
+	}
+	if cfg.serverAddr != "microchat.ai:443" || cfg.model != pb.Model_OPENAI_COMPATIBLE || cfg.systemPrompt != "Be brief" {
+		t.Errorf("Expected the saved server, model and system prompt, got %s, %v and %q", cfg.serverAddr, cfg.model, cfg.systemPrompt)
+	}
+
+	// Explicit flags win over the saved values
+	cfg = config{serverAddr: "localhost:4000", model: pb.Model_ECHO, statePath: path, systemPrompt: "Be verbose"}
+	resumeTarget(&cfg, "", true, map[string]bool{"addr": true, "model": true, "system": true}, logger)
+	if cfg.serverAddr != "localhost:4000" || cfg.model != pb.Model_ECHO || cfg.systemPrompt != "Be verbose" {
+		t.Errorf("Expected explicit flags to be kept, got %s, %v and %q", cfg.serverAddr, cfg.model, cfg.systemPrompt)
+	}
+
+	// -session takes an ID directly, and without either flag a new session is started
+	if id := resumeTarget(&cfg, "session-2", true, nil, logger); id != "session-2" {
+		t.Errorf("Expected -session to win, got %q", id)
+	}
+	if id := resumeTarget(&cfg, "", false, nil, logger); id != "" {
+		t.Errorf("Expected a new session, got %q", id)
+	}
+	// A session saved under another profile belongs to a different server or key
+	saveSessionState(path, sessionState{ServerAddr: "localhost:4000", Model: "echo", Profile: "local", SessionID: "session-3"})
+	cfg = config{statePath: path, profile: "prod"}
+	if id := resumeTarget(&cfg, "", true, nil, logger); id != "" {
+		t.Errorf("Expected a new session for a different profile, got %q", id)
+	}
+	cfg.profile = "local"
+	if id := resumeTarget(&cfg, "", true, nil, logger); id != "session-3" {
+		t.Errorf("Expected the session saved under the same profile, got %q", id)
+	}
+
+	cfg.statePath = filepath.Join(t.TempDir(), "missing")
+	if id := resumeTarget(&cfg, "", true, nil, logger); id != "" {
+		t.Errorf("Expected a new session without saved state, got %q", id)
+	}
+}
+
+func TestOpenSession(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state")
+	fake := &fakeResumeClient{known: "session-1", messages: []string{"user [10:00:00 UTC]: hi", "assistant [10:00:01 UTC]: hello"}, err: status.Error(codes.NotFound, "session not found")}
+	newApp := func() *application {
+		return &application{
+			config: config{serverAddr: "localhost:4000", model: pb.Model_ECHO, statePath: path},
+			logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+			grpc:   fake,
+		}
+	}
+
+	app := newApp()
+	resumed, err := app.openSession("session-1", false)
+	if err != nil || !resumed || app.config.sessionID != "session-1" || app.messageIndex != 2 {
+		t.Errorf("Expected to resume with 2 messages, got %v %q %d (%v)", resumed, app.config.sessionID, app.messageIndex, err)
+	}
+	if state, _ := loadSessionState(path); state == nil || state.SessionID != "session-1" || state.Model != "echo" {
+		t.Errorf("Expected the resumed session to be saved, got %+v", state)
+	}
+
+	// An expired -resume target is replaced by a new session, which becomes the saved one
+	app = newApp()
+	resumed, err = app.openSession("expired", false)
+	if err != nil || resumed || app.config.sessionID != "new-session" {
+		t.Errorf("Expected a new session, got %v %q (%v)", resumed, app.config.sessionID, err)
+	}
+	if state, _ := loadSessionState(path); state == nil || state.SessionID != "new-session" {
+		t.Errorf("Expected the new session to be saved, got %+v", state)
+	}
+
+	// An explicit -session must exist
+	app = newApp()
+	if _, err := app.openSession("expired", true); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound for an explicit session, got %v", err)
+	}
+
+	// Other failures aren't hidden by starting a new session
+	fake.err = status.Error(codes.Unavailable, "down")
+	app = newApp()
+	if _, err := app.openSession("expired", false); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable, got %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// inputPlaceholder is replaced by the text typed after a snippet's name
+const inputPlaceholder = "{input}"
+
+// isSnippetCommand reports whether input is "/t", with or without a snippet name
+func isSnippetCommand(input string) bool {
+	return input == snippetCommand || strings.HasPrefix(input, snippetCommand+" ") || strings.HasPrefix(input, snippetCommand+"\n")
+}
+
+// validateSnippets checks the config file's snippets, whose names are typed after "/t"
+func validateSnippets(snippets map[string]string) error {
+	for name, text := range snippets {
+		if name == "" || strings.ContainsAny(name, " \t\n") {
+			return fmt.Errorf("invalid snippet name %q: must be a single word", name)
+		}
+		if strings.TrimSpace(text) == "" {
+			return fmt.Errorf("snippet %q is empty", name)
+		}
+	}
+	return nil
+}
+
+// expandSnippet fills in a snippet with input, the text typed after its name
+// Every {input} is replaced by it; a snippet without {input} has it appended after a blank line
+func expandSnippet(snippet, input string) (string, error) {
+	if strings.Contains(snippet, inputPlaceholder) {
+		if input == "" {
+			return "", fmt.Errorf("the snippet uses %s, so it needs text after its name", inputPlaceholder)
+		}
+		return strings.ReplaceAll(snippet, inputPlaceholder, input), nil
+	}
+	if input == "" {
+		return snippet, nil
+	}
+	return strings.TrimRight(snippet, "\n") + "\n\n" + input, nil
+}
+
+// listSnippets shows the config file's snippets, one line each
+func (app *application) listSnippets() {
+	if len(app.config.snippets) == 0 {
+		fmt.Println("No snippets defined - add them under [snippets] in the config file")
+		return
+	}
+	names := make([]string, 0, len(app.config.snippets))
+	for name := range app.config.snippets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Println("Snippets:")
+	for _, name := range names {
+		fmt.Printf("  %s: %s\n", name, summarizeText(app.config.snippets[name]))
+	}
+}
+
+// handleSnippetCommand lists the snippets for "/t" and sends the named one, filled in with the rest
+// of the input, for "/t <name> [text]"
+func (app *application) handleSnippetCommand(input string) {
+	arg := strings.TrimSpace(strings.TrimPrefix(input, snippetCommand))
+	if arg == "" {
+		app.listSnippets()
+		return
+	}
+	name, rest, _ := strings.Cut(arg, " ")
+	if i := strings.IndexAny(name, "\t\n"); i >= 0 {
+		name, rest = name[:i], arg[i:]
+	}
+	snippet, ok := app.config.snippets[name]
+	if !ok {
+		fmt.Printf("Unknown snippet %q - '%s' to list them\n", name, snippetCommand)
+		return
+	}
+	message, err := expandSnippet(snippet, strings.TrimSpace(rest))
+	if err != nil {
+		fmt.Printf("%v: '%s %s <text>'\n", err, snippetCommand, name)
+		return
+	}
+	app.handleMessage(message)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+)
+
+func TestExpandSnippet(t *testing.T) {
+	tests := []struct {
+		snippet, input, want string
+		wantErr              bool
+	}{
+		{"Review this code:\n{input}", "func f() {}", "Review this code:\nfunc f() {}", false},
+		{"Translate {input} into French, keeping {input}'s tone", "hi", "Translate hi into French, keeping hi's tone", false},
+		{"Explain this code:\n", "x := 1", "Explain this code:\n\nx := 1", false},
+		{"Summarize the last reply", "", "Summarize the last reply", false},
+		{"Review:\n{input}", "", "", true},
+	}
+	for _, tt := range tests {
+		got, err := expandSnippet(tt.snippet, tt.input)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("expandSnippet(%q, %q) = %q, %v; want %q", tt.snippet, tt.input, got, err, tt.want)
+		}
+	}
+}
+
+func TestValidateSnippets(t *testing.T) {
+	if err := validateSnippets(map[string]string{"review": "Review {input}"}); err != nil {
+		t.Errorf("Expected a valid snippet, got %v", err)
+	}
+	for _, snippets := range []map[string]string{
+		{"code review": "Review {input}"},
+		{"": "Review {input}"},
+		{"review": "  "},
+	} {
+		if err := validateSnippets(snippets); err == nil {
+			t.Errorf("Expected %v to be rejected", snippets)
+		}
+	}
+}
+
+func TestFileConfigSnippets(t *testing.T) {
+	path := writeConfigFile(t, `
+[snippets]
+review = "Review this code:\n{input}"
+explain = "Explain {input}"
+
+[profiles.work.snippets]
+explain = "Explain {input} for a new team member"
+standup = "Write my standup notes from:\n{input}"
+`)
+	fc, err := loadFileConfig(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg config
+	merged, _, _ := fc.withProfile("work")
+	if err := merged.apply(&cfg, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.snippets) != 3 || cfg.snippets["explain"] != "Explain {input} for a new team member" || cfg.snippets["review"] == "" {
+		t.Errorf("Expected the profile's snippets over the top-level ones, got %v", cfg.snippets)
+	}
+	if len(fc.Snippets) != 2 || fc.Snippets["explain"] != "Explain {input}" {
+		t.Errorf("Expected the top-level snippets to be left alone, got %v", fc.Snippets)
+	}
+}
+
+func TestHandleSnippetCommand(t *testing.T) {
+	fake := &fakeChatClient{reply: "Looks fine"}
+	app := &application{
+		config: config{sessionID: "session-1", snippets: map[string]string{
+			"review":  "Review this code:\n{input}",
+			"explain": "Explain this:",
+		}},
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		grpc:   fake,
+	}
+
+	app.handleSnippetCommand("/t review  func f() {}")
+	if want := "Review this code:\nfunc f() {}"; fake.sent == nil || fake.sent.Message != want {
+		t.Fatalf("Expected %q to be sent, got %v", want, fake.sent)
+	}
+
+	// Input typed over several lines follows the name on the next line
+	fake.sent = nil
+	app.handleSnippetCommand("/t explain\nx := 1\ny := 2")
+	if want := "Explain this:\n\nx := 1\ny := 2"; fake.sent == nil || fake.sent.Message != want {
+		t.Fatalf("Expected %q to be sent, got %v", want, fake.sent)
+	}
+
+	// Unknown snippets and missing input aren't sent
+	fake.sent = nil
+	app.handleSnippetCommand("/t reveiw func f() {}")
+	app.handleSnippetCommand("/t review")
+	app.handleSnippetCommand("/t")
+	if fake.sent != nil {
+		t.Errorf("Expected nothing to be sent, got %v", fake.sent)
+	}
+}
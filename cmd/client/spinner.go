@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"microchat.ai/cmd/client/lineedit"
+)
+
+// spinnerFrames are drawn in turn, one per spinnerInterval
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+const spinnerInterval = 100 * time.Millisecond
+
+// spinner animates a waiting indicator with the elapsed seconds on the current terminal line
+// until stop clears it, so a slow reply doesn't look like a hung client
+type spinner struct {
+	out   io.Writer
+	mu    sync.Mutex
+	label string // Guarded by mu, since relabel is called while the spinner is drawing
+	start time.Time
+	done  chan struct{} // Closed by stop
+	exit  chan struct{} // Closed when the drawing goroutine has cleared the line
+}
+
+// startSpinner starts drawing "<frame> label 3s" to out every interval
+func startSpinner(out io.Writer, label string, interval time.Duration) *spinner {
+	s := &spinner{out: out, label: label, start: time.Now(), done: make(chan struct{}), exit: make(chan struct{})}
+	go s.run(interval)
+	return s
+}
+
+func (s *spinner) run(interval time.Duration) {
+	defer close(s.exit)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for frame := 0; ; frame++ {
+		elapsed := int(time.Since(s.start).Seconds())
+		s.mu.Lock()
+		label := s.label
+		s.mu.Unlock()
+		fmt.Fprintf(s.out, "\r%s %s %ds\x1b[K", spinnerFrames[frame%len(spinnerFrames)], label, elapsed)
+		select {
+		case <-s.done:
+			fmt.Fprint(s.out, "\r\x1b[K")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// waiting starts a spinner while a request is in flight, or returns nil when stdout isn't a terminal
+func (app *application) waiting() *spinner {
+	if !lineedit.IsTerminal(os.Stdout) {
+		return nil
+	}
+	return startSpinner(os.Stdout, "Waiting for reply", spinnerInterval)
+}
+
+// relabel changes what the spinner says it's waiting for from the next frame on
+func (s *spinner) relabel(label string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.label = label
+	s.mu.Unlock()
+}
+
+// stop clears the spinner, returning once the line is clear so output that follows isn't overwritten
+// Safe to call on a nil spinner, which is what waiting returns when output isn't a terminal
+func (s *spinner) stop() {
+	if s == nil {
+		return
+	}
+	close(s.done)
+	<-s.exit
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer safe to write from the spinner's goroutine
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestSpinner(t *testing.T) {
+	var out syncBuffer
+	s := startSpinner(&out, "Waiting for reply", time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	s.stop()
+
+	got := out.String()
+	if !strings.Contains(got, spinnerFrames[0]+" Waiting for reply 0s") || !strings.Contains(got, spinnerFrames[1]) {
+		t.Errorf("Expected animated frames with the elapsed time, got %q", got)
+	}
+	if !strings.HasSuffix(got, "\r\x1b[K") {
+		t.Errorf("Expected stop to clear the line, got %q", got)
+	}
+
+	// Nothing is written once stop returns
+	n := len(out.String())
+	time.Sleep(5 * time.Millisecond)
+	if len(out.String()) != n {
+		t.Error("Expected the spinner to stop drawing")
+	}
+
+	var none *spinner
+	none.stop() // No spinner when output isn't a terminal
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxSystemPromptSize matches the server's limit, so an oversized prompt is caught before it's sent
+const maxSystemPromptSize = 4 * 1024
+
+// summaryLength is how many runes of a system prompt or queued message a one-line summary shows
+const summaryLength = 60
+
+// isSystemCommand reports whether input is "/system", with or without an argument
+func isSystemCommand(input string) bool {
+	return input == systemCommand || strings.HasPrefix(input, systemCommand+" ")
+}
+
+// validateSystemPrompt checks that a system prompt fits within the server's limit
+func validateSystemPrompt(prompt string) error {
+	if len(prompt) > maxSystemPromptSize {
+		return fmt.Errorf("system prompt too large: %d bytes (max %d)", len(prompt), maxSystemPromptSize)
+	}
+	return nil
+}
+
+// summarizeText returns the first line of text, shortened to fit on one line of output
+func summarizeText(text string) string {
+	summary, _, more := strings.Cut(strings.TrimSpace(text), "\n")
+	summary = strings.TrimSpace(summary)
+	if runes := []rune(summary); len(runes) > summaryLength {
+		summary, more = string(runes[:summaryLength]), true
+	}
+	if more {
+		summary += "..."
+	}
+	return summary
+}
+
+// printSystemPrompt shows the session's system prompt in the banner, if one is set
+func (app *application) printSystemPrompt() {
+	if app.config.systemPrompt != "" {
+		fmt.Printf("System prompt: %s\n", summarizeText(app.config.systemPrompt))
+	}
+}
+
+// handleSystemCommand shows the system prompt for "/system", replaces it for "/system <text>" and
+// removes it for "/system clear"
+// The prompt is sent with each message, so a change applies from the next reply on
+func (app *application) handleSystemCommand(input string) {
+	arg := strings.TrimSpace(strings.TrimPrefix(input, systemCommand))
+	switch arg {
+	case "":
+		if app.config.systemPrompt == "" {
+			fmt.Printf("No system prompt set - '%s <text>' to set one\n", systemCommand)
+			return
+		}
+		fmt.Printf("System prompt:\n%s\n", app.config.systemPrompt)
+		return
+	case "clear":
+		app.config.systemPrompt = ""
+		fmt.Println("System prompt cleared")
+	default:
+		if err := validateSystemPrompt(arg); err != nil {
+			app.printError("%v", err)
+			return
+		}
+		app.config.systemPrompt = arg
+		fmt.Println("System prompt set")
+	}
+	app.logger.Info("changed system prompt", "length", len(app.config.systemPrompt))
+	app.saveSession()
+}
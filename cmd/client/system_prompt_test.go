@@ -0,0 +1,57 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSummarizeText(t *testing.T) {
+	tests := []struct {
+		prompt string
+		want   string
+	}{
+		{"You are a pirate", "You are a pirate"},
+		{"  You are a pirate\nAlways say arr  ", "You are a pirate..."},
+		{strings.Repeat("a", summaryLength+5), strings.Repeat("a", summaryLength) + "..."},
+	}
+	for _, tt := range tests {
+		if got := summarizeText(tt.prompt); got != tt.want {
+			t.Errorf("summarizeText(%q) = %q, want %q", tt.prompt, got, tt.want)
+		}
+	}
+}
+
+func TestHandleSystemCommand(t *testing.T) {
+	for input, want := range map[string]bool{"/system": true, "/system Be brief": true, "/systems": false, "/sys": false} {
+		if got := isSystemCommand(input); got != want {
+			t.Errorf("isSystemCommand(%q) = %v, want %v", input, got, want)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "state")
+	app := &application{
+		config: config{serverAddr: "localhost:4000", sessionID: "session-1", statePath: path},
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	app.handleSystemCommand("/system   Be brief ")
+	if app.config.systemPrompt != "Be brief" {
+		t.Errorf("Expected the system prompt to be set, got %q", app.config.systemPrompt)
+	}
+	if state, _ := loadSessionState(path); state == nil || state.SystemPrompt != "Be brief" {
+		t.Errorf("Expected the system prompt to be saved with the session, got %+v", state)
+	}
+
+	app.handleSystemCommand("/system " + strings.Repeat("a", maxSystemPromptSize+1))
+	if app.config.systemPrompt != "Be brief" {
+		t.Errorf("Expected an oversized prompt to be rejected, got %d bytes", len(app.config.systemPrompt))
+	}
+
+	app.handleSystemCommand("/system clear")
+	if app.config.systemPrompt != "" {
+		t.Errorf("Expected the system prompt to be cleared, got %q", app.config.systemPrompt)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// defaultTheme is used unless -theme or the config file picks another
+const defaultTheme = "dark"
+
+// theme holds the ANSI SGR parameters used for each kind of chat output, e.g. "1;32" for bold green
+// An empty parameter leaves that output uncolored, so the zero theme prints plain text
+type theme struct {
+	userColor      string // Input prompt and your messages in /history
+	assistantColor string // Assistant label on replies and in /history
+	errorColor     string // Errors and warnings
+	metricsColor   string // Metrics, timing and delta lines
+}
+
+// themes are the color themes accepted by -theme
+var themes = map[string]theme{
+	"dark":  {userColor: "1;36", assistantColor: "1;32", errorColor: "1;31", metricsColor: "2"},
+	"light": {userColor: "1;34", assistantColor: "1;35", errorColor: "31", metricsColor: "90"},
+}
+
+// themeNames returns the theme names in order, for help text and errors
+func themeNames() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// selectTheme returns the named theme, or the plain theme when color is off
+// Color is off with -no-color, when NO_COLOR is set to anything (https://no-color.org) or when stdout
+// isn't a terminal, so pipes and screen readers get plain text
+func selectTheme(name string, noColor, terminal bool) (theme, error) {
+	t, ok := themes[name]
+	if !ok {
+		return theme{}, fmt.Errorf("unknown theme %q (expected %s)", name, strings.Join(themeNames(), " or "))
+	}
+	if noColor || os.Getenv("NO_COLOR") != "" || !terminal {
+		return theme{}, nil
+	}
+	return t, nil
+}
+
+// paint wraps text in the SGR parameters, leaving it unchanged when there are none
+func paint(color, text string) string {
+	if color == "" {
+		return text
+	}
+	return "\x1b[" + color + "m" + text + "\x1b[0m"
+}
+
+func (t theme) user(text string) string      { return paint(t.userColor, text) }
+func (t theme) assistant(text string) string { return paint(t.assistantColor, text) }
+func (t theme) errorText(text string) string { return paint(t.errorColor, text) }
+func (t theme) metrics(text string) string   { return paint(t.metricsColor, text) }
+
+// role colors a /history role label by who sent the message
+func (t theme) role(role, label string) string {
+	switch role {
+	case "user":
+		return t.user(label)
+	case "assistant":
+		return t.assistant(label)
+	default:
+		return label
+	}
+}
+
+// printError shows an error message to the user, e.g. printError("can't write %s", path)
+func (app *application) printError(format string, args ...any) {
+	fmt.Println(app.theme.errorText("Error: " + fmt.Sprintf(format, args...)))
+}
+
+// printMetrics shows a metrics line, e.g. printMetrics("[↑%s ↓%s]", out, in)
+func (app *application) printMetrics(format string, args ...any) {
+	fmt.Println(app.theme.metrics(fmt.Sprintf(format, args...)))
+}
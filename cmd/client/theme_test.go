@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestSelectTheme(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+
+	colors, err := selectTheme("dark", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := colors.assistant("Assistant:"); got != "\x1b[1;32mAssistant:\x1b[0m" {
+		t.Errorf("Expected a colored label, got %q", got)
+	}
+	if got := colors.role("system", "System"); got != "System" {
+		t.Errorf("Expected other roles to stay plain, got %q", got)
+	}
+
+	// Color is off with -no-color, NO_COLOR or output that isn't a terminal
+	for _, tt := range []struct {
+		name     string
+		noColor  bool
+		env      string
+		terminal bool
+	}{
+		{"flag", true, "", true},
+		{"NO_COLOR", false, "1", true},
+		{"pipe", false, "", false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", tt.env)
+			colors, err := selectTheme("light", tt.noColor, tt.terminal)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := colors.errorText("Error: x"); got != "Error: x" {
+				t.Errorf("Expected plain text, got %q", got)
+			}
+		})
+	}
+
+	if _, err := selectTheme("neon", false, true); err == nil {
+		t.Error("Expected an unknown theme to be an error")
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Ways -time-format shows message times in /history
+const (
+	timeFormatBoth     = "both"     // Local time and how long ago, e.g. "14:03:21, 2m ago"
+	timeFormatLocal    = "local"    // Local time, with the date when it isn't today
+	timeFormatRelative = "relative" // How long ago, e.g. "2m ago"
+	timeFormatUTC      = "utc"      // Time of day in UTC, as the server formats it
+)
+
+// timeFormats are the values -time-format accepts
+var timeFormats = []string{timeFormatBoth, timeFormatLocal, timeFormatRelative, timeFormatUTC}
+
+// validateTimeFormat checks -time-format or the config file's time_format
+func validateTimeFormat(format string) error {
+	for _, f := range timeFormats {
+		if f == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown time format %q (expected one of %s)", format, strings.Join(timeFormats, ", "))
+}
+
+// relativeTime says how long before now t was, rounded down to the largest whole unit
+func relativeTime(t, now time.Time) string {
+	d := now.Sub(t)
+	switch {
+	case d < time.Minute: // Also covers small clock differences with the server
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d/time.Minute))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d/time.Hour))
+	default:
+		return fmt.Sprintf("%dd ago", int(d/(24*time.Hour)))
+	}
+}
+
+// localTime shows t in the local time zone, adding the date when it isn't now's, and the year when
+// that isn't now's either
+func localTime(t, now time.Time) string {
+	t, now = t.Local(), now.Local()
+	switch {
+	case t.YearDay() == now.YearDay() && t.Year() == now.Year():
+		return t.Format(time.TimeOnly)
+	case t.Year() == now.Year():
+		return t.Format("Jan 2 15:04:05")
+	default:
+		return t.Format(time.DateTime)
+	}
+}
+
+// messageTime shows when a history entry was sent in the given -time-format
+// Older servers send only the time of day in UTC, which is shown as is
+func messageTime(entry historyEntry, format string, now time.Time) string {
+	if entry.at.IsZero() {
+		return entry.timestamp + " UTC"
+	}
+	switch format {
+	case timeFormatUTC:
+		return entry.at.UTC().Format(time.TimeOnly) + " UTC"
+	case timeFormatLocal:
+		return localTime(entry.at, now)
+	case timeFormatRelative:
+		return relativeTime(entry.at, now)
+	default:
+		return localTime(entry.at, now) + ", " + relativeTime(entry.at, now)
+	}
+}
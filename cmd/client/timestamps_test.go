@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRelativeTime(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	for ago, want := range map[time.Duration]string{
+		-5 * time.Second:               "just now", // The server's clock is a little ahead
+		30 * time.Second:               "just now",
+		2*time.Minute + 59*time.Second: "2m ago",
+		3 * time.Hour:                  "3h ago",
+		50 * time.Hour:                 "2d ago",
+	} {
+		if got := relativeTime(now.Add(-ago), now); got != want {
+			t.Errorf("%v ago: expected %q, got %q", ago, want, got)
+		}
+	}
+}
+
+func TestLocalTime(t *testing.T) {
+	zone := time.FixedZone("CET", 3600)
+	defer func(local *time.Location) { time.Local = local }(time.Local)
+	time.Local = zone
+
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, zone)
+	for at, want := range map[time.Time]string{
+		time.Date(2026, 3, 10, 9, 30, 0, 0, time.UTC): "10:30:00",
+		time.Date(2026, 3, 9, 23, 30, 0, 0, time.UTC): "00:30:00", // Yesterday in UTC, today locally
+		time.Date(2026, 2, 1, 8, 0, 0, 0, time.UTC):   "Feb 1 09:00:00",
+		time.Date(2025, 12, 31, 8, 0, 0, 0, time.UTC): "2025-12-31 09:00:00",
+	} {
+		if got := localTime(at, now); got != want {
+			t.Errorf("%v: expected %q, got %q", at, want, got)
+		}
+	}
+}
+
+func TestMessageTime(t *testing.T) {
+	defer func(local *time.Location) { time.Local = local }(time.Local)
+	time.Local = time.FixedZone("CET", 3600)
+
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	entry := historyEntry{timestamp: "11:58:00", at: now.Add(-2 * time.Minute)}
+	for format, want := range map[string]string{
+		timeFormatBoth:     "12:58:00, 2m ago",
+		timeFormatLocal:    "12:58:00",
+		timeFormatRelative: "2m ago",
+		timeFormatUTC:      "11:58:00 UTC",
+	} {
+		if got := messageTime(entry, format, now); got != want {
+			t.Errorf("%s: expected %q, got %q", format, want, got)
+		}
+	}
+
+	// Older servers only send the time of day in UTC
+	if got := messageTime(historyEntry{timestamp: "11:58:00"}, timeFormatBoth, now); got != "11:58:00 UTC" {
+		t.Errorf("Expected the server's time of day, got %q", got)
+	}
+
+	if validateTimeFormat("relative") != nil || validateTimeFormat("iso") == nil {
+		t.Error("Expected only the known time formats to be accepted")
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"unicode"
+
+	pb "microchat.ai/proto"
+)
+
+// contextWindows are the models' context windows in tokens; models without an entry aren't checked
+// The OpenAI-compatible model depends on what the server runs, so -context-window sets it
+var contextWindows = map[pb.Model]int64{
+	pb.Model_GEMINI_2_5_FLASH_LITE: 1_048_576,
+}
+
+// tokenEstimate tracks locally estimated token counts, since the client can't run the models' tokenizers
+type tokenEstimate struct {
+	prompt  int64 // Last message's prompt: system prompt, conversation so far and the message
+	context int64 // Conversation so far: every message and reply in the session
+}
+
+// estimateTokens approximates how many tokens a model's tokenizer splits text into
+// Words cost one token per five characters, rounded up, and each punctuation mark or symbol one token,
+// as do letters in scripts written without spaces, such as Chinese and Japanese
+func estimateTokens(text string) int64 {
+	var tokens int64
+	word := 0 // Length of the word being read, in runes
+	endWord := func() {
+		tokens += int64(word+4) / 5
+		word = 0
+	}
+	for _, r := range text {
+		switch {
+		case unicode.IsSpace(r):
+			endWord()
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			endWord()
+			tokens++
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word++
+		default:
+			endWord()
+			tokens++
+		}
+	}
+	endWord()
+	return tokens
+}
+
+// contextWindow returns the current model's context window in tokens, 0 when unknown
+func (app *application) contextWindow() int64 {
+	if app.config.contextWindow > 0 {
+		return app.config.contextWindow
+	}
+	return contextWindows[app.config.model]
+}
+
+// estimatePrompt records the estimated prompt for message and warns when it likely exceeds the model's context window
+func (app *application) estimatePrompt(message string) {
+	app.tokens.prompt = estimateTokens(app.config.systemPrompt) + app.tokens.context + estimateTokens(message)
+	window := app.contextWindow()
+	if window > 0 && app.tokens.prompt > window {
+		fmt.Println(app.theme.errorText(fmt.Sprintf(
+			"Warning: this message and the conversation so far are about %s tokens, more than %s's %s token context window; earlier messages may be dropped or the request may fail",
+			formatTokens(app.tokens.prompt), modelName(app.config.model), formatTokens(window))))
+	}
+}
+
+// addTurn adds a message and its reply to the conversation's estimate
+func (app *application) addTurn(message, reply string) {
+	app.tokens.context += estimateTokens(message) + estimateTokens(reply)
+}
+
+// formatTokens shortens a token count, e.g. 950, 12.3k or 1.0M
+func formatTokens(tokens int64) string {
+	switch {
+	case tokens < 1000:
+		return fmt.Sprintf("%d", tokens)
+	case tokens < 1_000_000:
+		return fmt.Sprintf("%.1fk", float64(tokens)/1000)
+	default:
+		return fmt.Sprintf("%.1fM", float64(tokens)/1_000_000)
+	}
+}
+
+// tokenSummary describes the estimated prompt for the metrics line, e.g. "~1.2k tokens"
+func (app *application) tokenSummary() string {
+	summary := "~" + formatTokens(app.tokens.prompt) + " tokens"
+	if window := app.contextWindow(); window > 0 {
+		summary += fmt.Sprintf(" of %s", formatTokens(window))
+	}
+	return summary
+}
+
+// historyTokens estimates the tokens in a resumed session's messages
+func (app *application) historyTokens(history *pb.GetHistoryResponse) int64 {
+	var tokens int64
+	for _, formatted := range history.Messages {
+		entry, ok := parseHistoryMessage(formatted)
+		if !ok {
+			continue
+		}
+		text := entry.text
+		if history.Encrypted {
+			var err error
+			if text, err = decryptHistoryText(app.config.sessionKey, app.config.sessionID, text); err != nil {
+				continue
+			}
+		}
+		tokens += estimateTokens(text)
+	}
+	return tokens
+}
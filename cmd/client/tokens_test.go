@@ -0,0 +1,72 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+
+	pb "microchat.ai/proto"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int64
+	}{
+		{"", 0},
+		{"Hello, world!", 4},           // "Hello" "," "world" "!"
+		{"internationalization", 4},    // 20 letters, five per token
+		{"func main() {}", 6},          // "func" "main" "(" ")" "{" "}"
+		{"  spaced\n\tout  ", 3},       // whitespace is free
+		{"你好世界", 4},                    // one token per character
+		{"The year 2025 was long.", 6}, // "The" "year" "2025" "was" "long" "."
+		{"a b c d", 4},
+	}
+	for _, tt := range tests {
+		if got := estimateTokens(tt.text); got != tt.want {
+			t.Errorf("estimateTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestEstimatePrompt(t *testing.T) {
+	app := &application{
+		config: config{model: pb.Model_GEMINI_2_5_FLASH_LITE, systemPrompt: "Be brief"},
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	app.addTurn("Hello there", "Hi!")
+	if app.tokens.context != 4 {
+		t.Errorf("Expected 4 tokens of conversation, got %d", app.tokens.context)
+	}
+	app.estimatePrompt("How are you?")
+	if app.tokens.prompt != 2+4+4 {
+		t.Errorf("Expected the prompt to include the system prompt, conversation and message, got %d", app.tokens.prompt)
+	}
+	if got := app.tokenSummary(); got != "~10 tokens of 1.0M" {
+		t.Errorf("Expected the summary against Gemini's window, got %q", got)
+	}
+
+	// -context-window sets the window for models the client doesn't know
+	app.config.model, app.config.contextWindow = pb.Model_OPENAI_COMPATIBLE, 8192
+	if window := app.contextWindow(); window != 8192 {
+		t.Errorf("Expected the configured window, got %d", window)
+	}
+	app.config.contextWindow = 0
+	if got := app.tokenSummary(); got != "~10 tokens" {
+		t.Errorf("Expected no window for an unknown model, got %q", got)
+	}
+
+	for tokens, want := range map[int64]string{950: "950", 12_345: "12.3k", 1_048_576: "1.0M"} {
+		if got := formatTokens(tokens); got != want {
+			t.Errorf("formatTokens(%d) = %q, want %q", tokens, got, want)
+		}
+	}
+}
+
+func TestHistoryTokens(t *testing.T) {
+	app := &application{config: config{sessionID: "session-1"}}
+	history := &pb.GetHistoryResponse{Messages: []string{"user [10:00:00 UTC]: Hello there", "assistant [10:00:01 UTC]: Hi!", "malformed"}}
+	if got := app.historyTokens(history); got != 4 {
+		t.Errorf("Expected 4 tokens, got %d", got)
+	}
+}
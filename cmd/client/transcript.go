@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	pb "microchat.ai/proto"
+)
+
+// Transcript rotation limits
+const (
+	transcriptMaxBytes = 10 << 20 // Rotate before the file would grow past 10 MiB
+	transcriptBackups  = 3        // Rotated files kept, path.1 (newest) to path.3
+)
+
+// transcript appends prompts and replies to a local file for -log-transcript, rotating it by size
+// A nil transcript records nothing
+type transcript struct {
+	path     string
+	maxBytes int64
+	backups  int
+	file     *os.File
+	size     int64
+	location *time.Location // Time zone entries are stamped in, UTC when nil
+}
+
+// openTranscript opens path for appending, creating it readable only by the user
+func openTranscript(path string) (*transcript, error) {
+	t := &transcript{path: path, maxBytes: transcriptMaxBytes, backups: transcriptBackups}
+	if err := t.open(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *transcript) open() error {
+	f, err := os.OpenFile(t.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	t.file, t.size = f, info.Size()
+	return nil
+}
+
+// record appends one message, e.g. "[2026-01-02 15:04:05 UTC] [<session>] You:" followed by its text,
+// or "[2026-01-02 16:04:05 +01:00] ..." in a local time zone
+func (t *transcript) record(at time.Time, sessionID, speaker, text string) error {
+	if t == nil {
+		return nil
+	}
+	stamp := at.UTC().Format("2006-01-02 15:04:05 UTC")
+	if t.location != nil {
+		stamp = at.In(t.location).Format("2006-01-02 15:04:05 -07:00")
+	}
+	entry := fmt.Sprintf("[%s] [%s] %s:\n%s\n\n", stamp, sessionID, speaker, strings.TrimRight(text, "\n"))
+
+	if t.size > 0 && t.size+int64(len(entry)) > t.maxBytes {
+		if err := t.rotate(); err != nil {
+			return fmt.Errorf("failed to rotate transcript: %w", err)
+		}
+	}
+	n, err := t.file.WriteString(entry)
+	t.size += int64(n)
+	return err
+}
+
+// rotate shifts path.1 .. path.N-1 up by one, dropping the oldest, moves the current file to path.1
+// and starts a new one
+func (t *transcript) rotate() error {
+	if err := t.file.Close(); err != nil {
+		return err
+	}
+	if err := os.Remove(fmt.Sprintf("%s.%d", t.path, t.backups)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for i := t.backups - 1; i >= 1; i-- {
+		if err := os.Rename(fmt.Sprintf("%s.%d", t.path, i), fmt.Sprintf("%s.%d", t.path, i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(t.path, t.path+".1"); err != nil {
+		return err
+	}
+	return t.open()
+}
+
+// assistantLabel names a reply's speaker in the transcript, e.g. "Assistant (gemini)"
+func assistantLabel(model pb.Model) string {
+	return "Assistant (" + modelName(model) + ")"
+}
+
+// close closes the transcript file
+func (t *transcript) close() error {
+	if t == nil {
+		return nil
+	}
+	return t.file.Close()
+}
+
+// openTranscript starts the -log-transcript file, if one was requested
+// Entries are stamped in the local time zone unless -time-format is utc
+func (app *application) openTranscript() error {
+	if app.config.transcriptPath == "" {
+		return nil
+	}
+	t, err := openTranscript(app.config.transcriptPath)
+	if err != nil {
+		return err
+	}
+	if app.config.timeFormat != timeFormatUTC {
+		t.location = time.Local
+	}
+	app.transcript = t
+	return nil
+}
+
+// recordTranscript appends a message to the transcript, warning rather than failing the chat if it can't be written
+func (app *application) recordTranscript(at time.Time, speaker, text string) {
+	if err := app.transcript.record(at, app.config.sessionID, speaker, text); err != nil {
+		app.logger.Warn("failed to write transcript", "path", app.config.transcriptPath, "error", err)
+	}
+}
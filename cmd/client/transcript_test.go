@@ -0,0 +1,79 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	pb "microchat.ai/proto"
+)
+
+func TestTranscriptRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transcript.log")
+	tr, err := openTranscript(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2026, 1, 2, 15, 4, 5, 0, time.FixedZone("EST", -5*3600))
+	tr.record(at, "session-1", "You", "hello\n")
+	tr.record(at, "session-1", assistantLabel(pb.Model_ECHO), "Echo: hello")
+	tr.close()
+
+	// Reopening appends rather than truncating; entries can be stamped in a local time zone
+	tr, err = openTranscript(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.location = time.FixedZone("CET", 3600)
+	tr.record(at, "session-1", "You", "again")
+	tr.close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[2026-01-02 20:04:05 UTC] [session-1] You:\nhello\n\n" +
+		"[2026-01-02 20:04:05 UTC] [session-1] Assistant (echo):\nEcho: hello\n\n" +
+		"[2026-01-02 21:04:05 +01:00] [session-1] You:\nagain\n\n"
+	if string(data) != want {
+		t.Errorf("Unexpected transcript:\n%s", data)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected a private transcript, got %v", info.Mode().Perm())
+	}
+
+	// A nil transcript records nothing
+	var disabled *transcript
+	if err := disabled.record(at, "session-1", "You", "hi"); err != nil || disabled.close() != nil {
+		t.Error("Expected a nil transcript to be a no-op")
+	}
+}
+
+func TestTranscriptRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transcript.log")
+	tr, err := openTranscript(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.maxBytes, tr.backups = 100, 2
+	defer tr.close()
+
+	// Each entry is over half the limit, so every write after the first rotates
+	for i := 1; i <= 4; i++ {
+		if err := tr.record(time.Now(), "session-1", "You", strings.Repeat("x", 40)+string(rune('0'+i))); err != nil {
+			t.Fatalf("record %d: %v", i, err)
+		}
+	}
+
+	for name, want := range map[string]string{path: "x4", path + ".1": "x3", path + ".2": "x2"} {
+		data, err := os.ReadFile(name)
+		if err != nil || !strings.Contains(string(data), want) {
+			t.Errorf("Expected %s to hold entry %q, got %q (%v)", filepath.Base(name), want, data, err)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Error("Expected only 2 backups to be kept")
+	}
+}
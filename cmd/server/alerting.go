@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"microchat.ai/cmd/server/alerts"
+	"microchat.ai/cmd/server/ratelimit"
+)
+
+const (
+	alertUsageFraction        = 0.9         // Alert when a key's daily calls or session memory reach this fraction of the limit
+	authFailureAlertThreshold = 10          // Failed authentications from one client before alerting
+	authFailureAlertWindow    = time.Minute // Window the auth failures are counted over
+)
+
+// alertProviderUnhealthy raises an alert when an LLM provider fails its health check
+func (app *application) alertProviderUnhealthy(name string, err error) {
+	app.alerts.Notify(alerts.Alert{
+		Event:   alerts.EventProviderUnhealthy,
+		Subject: name,
+		Message: fmt.Sprintf("LLM provider %s is unhealthy; requests fall back to another provider", name),
+		Fields:  map[string]string{"provider": name, "error": err.Error()},
+	})
+}
+
+// checkResourceAlerts raises alerts for API keys near their daily call limit and for
+// session memory near its budget
+// Runs with the periodic metrics update; the notifier suppresses repeats
+func checkResourceAlerts(app *application) {
+	if app.alerts == nil {
+		return
+	}
+
+	limit := app.spendingTracker.limit
+	threshold := int(float64(limit) * alertUsageFraction)
+	nearLimit := make(map[string]int)
+	for key, calls := range app.spendingTracker.Snapshot() {
+		if limit > 0 && calls >= threshold {
+			nearLimit[hashAPIKey(key)] = calls
+		}
+	}
+
+	for keyHash, calls := range nearLimit {
+		app.alerts.Notify(alerts.Alert{
+			Event:   alerts.EventKeyNearLimit,
+			Subject: keyHash,
+			Message: fmt.Sprintf("API key %s has used %d of %d daily calls", keyHash, calls, limit),
+			Fields:  map[string]string{"key_hash": keyHash, "calls_today": strconv.Itoa(calls), "daily_limit": strconv.Itoa(limit)},
+		})
+	}
+
+	totalMemory, memoryBudget := app.sessionStore.GetMemoryUsage()
+	if memoryBudget > 0 && float64(totalMemory) >= float64(memoryBudget)*alertUsageFraction {
+		app.alerts.Notify(alerts.Alert{
+			Event:   alerts.EventSessionMemoryHigh,
+			Message: fmt.Sprintf("Session store is using %d of %d bytes; idle sessions will be evicted", totalMemory, memoryBudget),
+			Fields:  map[string]string{"used_bytes": strconv.Itoa(totalMemory), "budget_bytes": strconv.Itoa(memoryBudget)},
+		})
+	}
+}
+
+// AuthFailureAlertInterceptor raises an alert when a client repeatedly fails authentication,
+// which usually means a misconfigured client or someone guessing keys
+// It must run before AuthInterceptor so it sees the rejection
+func AuthFailureAlertInterceptor(notifier *alerts.Notifier, failures *alerts.FailureCounter, trusted ratelimit.TrustedProxies) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if notifier == nil || status.Code(err) != codes.Unauthenticated {
+			return resp, err
+		}
+
+		clientIP := extractClientIP(ctx, trusted)
+		if count := failures.Record(clientIP); count > 0 {
+			notifier.Notify(alerts.Alert{
+				Event:   alerts.EventAuthFailures,
+				Subject: clientIP,
+				Message: fmt.Sprintf("%d failed authentications from %s in the last %s", count, clientIP, authFailureAlertWindow),
+				Fields:  map[string]string{"client_ip": clientIP, "failures": strconv.Itoa(count), "method": info.FullMethod},
+			})
+		}
+		return resp, err
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"microchat.ai/cmd/server/alerts"
+)
+
+// alertRecorder is a webhook receiver that records the events it was sent
+type alertRecorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func newAlertWebhook(t *testing.T) (*alertRecorder, *alerts.Notifier) {
+	t.Helper()
+	recorder := &alertRecorder{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Event string `json:"event"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		recorder.mu.Lock()
+		recorder.events = append(recorder.events, payload.Event)
+		recorder.mu.Unlock()
+	}))
+	t.Cleanup(server.Close)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	return recorder, alerts.NewNotifier([]string{server.URL}, time.Hour, logger)
+}
+
+func (r *alertRecorder) Events() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.events...)
+}
+
+func TestAuthFailureAlertInterceptor(t *testing.T) {
+	recorder, notifier := newAlertWebhook(t)
+	interceptor := AuthFailureAlertInterceptor(notifier, alerts.NewFailureCounter(3, time.Minute), nil)
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("203.0.113.7"), Port: 4000}})
+	info := &grpc.UnaryServerInfo{FullMethod: "/chat.ChatService/Chat"}
+	rejected := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.Unauthenticated, "invalid API key")
+	}
+	allowed := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+
+	interceptor(ctx, nil, info, allowed)
+	for i := 0; i < 5; i++ {
+		if _, err := interceptor(ctx, nil, info, rejected); status.Code(err) != codes.Unauthenticated {
+			t.Fatalf("expected the auth error to pass through, got %v", err)
+		}
+	}
+	notifier.Wait()
+
+	if events := recorder.Events(); len(events) != 1 || events[0] != alerts.EventAuthFailures {
+		t.Errorf("expected one auth_failures alert, got %v", events)
+	}
+}
+
+func TestCheckResourceAlerts(t *testing.T) {
+	recorder, notifier := newAlertWebhook(t)
+	app := &application{
+		sessionStore:    NewSessionStore(time.Hour, 100, 100, 1024*1024),
+		spendingTracker: NewSpendingTracker(10),
+		alerts:          notifier,
+	}
+	checkResourceAlerts(app)
+	notifier.Wait()
+	if events := recorder.Events(); len(events) != 0 {
+		t.Fatalf("expected no alerts without usage or a memory budget, got %v", events)
+	}
+
+	for i := 0; i < 9; i++ {
+		app.spendingTracker.RecordCall("alice-key")
+	}
+	app.sessionStore.RegisterSession("session-1", "alice-key")
+	if err := app.sessionStore.AppendMessage("session-1", User, "hello"); err != nil {
+		t.Fatalf("failed to append message: %v", err)
+	}
+	used, _ := app.sessionStore.GetMemoryUsage()
+	app.sessionStore.SetMemoryBudget(used + 1)
+
+	checkResourceAlerts(app)
+	checkResourceAlerts(app)
+	notifier.Wait()
+
+	events := recorder.Events()
+	if len(events) != 2 {
+		t.Fatalf("expected one key and one memory alert, got %v", events)
+	}
+	seen := map[string]bool{events[0]: true, events[1]: true}
+	if !seen[alerts.EventKeyNearLimit] || !seen[alerts.EventSessionMemoryHigh] {
+		t.Errorf("expected key_near_daily_limit and session_memory_high alerts, got %v", events)
+	}
+}
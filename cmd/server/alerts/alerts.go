@@ -0,0 +1,221 @@
+// Package alerts delivers operational alerts to Slack or generic HTTP webhooks
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// webhookTimeout bounds each webhook delivery
+const webhookTimeout = 5 * time.Second
+
+// Events that trigger alerts
+const (
+	EventProviderUnhealthy = "provider_unhealthy"   // An LLM provider failed its health check
+	EventKeyNearLimit      = "key_near_daily_limit" // An API key is close to its daily call limit
+	EventSessionMemoryHigh = "session_memory_high"  // The session store is close to its memory budget
+	EventAuthFailures      = "auth_failures"        // A client keeps failing authentication
+)
+
+// Alert is a single notification
+type Alert struct {
+	Event   string            // One of the Event constants
+	Subject string            // What the alert is about (provider, key hash, client IP); rate limited separately
+	Message string            // Human-readable summary
+	Fields  map[string]string // Extra context for the receiver
+}
+
+// Notifier sends alerts to webhooks, suppressing repeats of the same event and subject
+// within the minimum interval so a flapping condition can't flood the receivers
+// A nil Notifier discards alerts
+type Notifier struct {
+	urls        []string
+	minInterval time.Duration
+	client      *http.Client
+	logger      *slog.Logger
+
+	mu   sync.Mutex
+	last map[string]time.Time // Event and subject -> last time sent
+
+	wg sync.WaitGroup // In-flight deliveries
+}
+
+// NewNotifier creates a notifier for the given webhook URLs
+func NewNotifier(urls []string, minInterval time.Duration, logger *slog.Logger) *Notifier {
+	return &Notifier{
+		urls:        urls,
+		minInterval: minInterval,
+		client:      &http.Client{Timeout: webhookTimeout},
+		logger:      logger,
+		last:        make(map[string]time.Time),
+	}
+}
+
+// Notify delivers an alert to every webhook in the background
+// Returns false if the alert was suppressed by rate limiting
+func (n *Notifier) Notify(alert Alert) bool {
+	if n == nil {
+		return false
+	}
+
+	key := alert.Event + "/" + alert.Subject
+	now := time.Now()
+	n.mu.Lock()
+	if last, sent := n.last[key]; sent && now.Sub(last) < n.minInterval {
+		n.mu.Unlock()
+		return false
+	}
+	n.last[key] = now
+	n.mu.Unlock()
+
+	for _, webhookURL := range n.urls {
+		n.wg.Add(1)
+		go func() {
+			defer n.wg.Done()
+			if err := n.deliver(webhookURL, alert, now); err != nil {
+				n.logger.Warn("failed to deliver alert", "event", alert.Event, "error", err)
+			}
+		}()
+	}
+	n.logger.Info("sent alert", "event", alert.Event, "webhooks", len(n.urls))
+	return true
+}
+
+// Wait blocks until in-flight deliveries have finished
+func (n *Notifier) Wait() {
+	if n != nil {
+		n.wg.Wait()
+	}
+}
+
+// genericPayload is the body posted to non-Slack webhooks
+type genericPayload struct {
+	Event   string            `json:"event"`
+	Subject string            `json:"subject,omitempty"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+	Time    time.Time         `json:"time"`
+}
+
+// slackPayload is the body posted to Slack incoming webhooks
+type slackPayload struct {
+	Text string `json:"text"`
+}
+
+// deliver posts an alert to one webhook
+func (n *Notifier) deliver(webhookURL string, alert Alert, at time.Time) error {
+	var payload any = genericPayload{
+		Event:   alert.Event,
+		Subject: alert.Subject,
+		Message: alert.Message,
+		Fields:  alert.Fields,
+		Time:    at.UTC(),
+	}
+	if isSlackWebhook(webhookURL) {
+		payload = slackPayload{Text: slackText(alert)}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode alert: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// isSlackWebhook reports whether a URL is a Slack incoming webhook
+func isSlackWebhook(webhookURL string) bool {
+	parsed, err := url.Parse(webhookURL)
+	return err == nil && parsed.Hostname() == "hooks.slack.com"
+}
+
+// slackText renders an alert as a Slack message
+func slackText(alert Alert) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, ":rotating_light: *microchat.ai %s*: %s", alert.Event, alert.Message)
+
+	keys := make([]string, 0, len(alert.Fields))
+	for k := range alert.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, "\n• %s: %s", k, alert.Fields[k])
+	}
+	return b.String()
+}
+
+// FailureCounter counts failures per subject within a sliding window
+type FailureCounter struct {
+	threshold int
+	window    time.Duration
+
+	mu       sync.Mutex
+	failures map[string][]time.Time
+}
+
+// NewFailureCounter creates a counter that trips after threshold failures within window
+func NewFailureCounter(threshold int, window time.Duration) *FailureCounter {
+	return &FailureCounter{
+		threshold: threshold,
+		window:    window,
+		failures:  make(map[string][]time.Time),
+	}
+}
+
+// Record adds a failure for subject and returns the number of failures in the window
+// once it reaches the threshold, or 0 below it
+func (c *FailureCounter) Record(subject string) int {
+	now := time.Now()
+	cutoff := now.Add(-c.window)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	recent := c.failures[subject][:0]
+	for _, at := range c.failures[subject] {
+		if at.After(cutoff) {
+			recent = append(recent, at)
+		}
+	}
+	recent = append(recent, now)
+	c.failures[subject] = recent
+
+	// Drop subjects that have gone quiet so the map can't grow without bound
+	for other, times := range c.failures {
+		if len(times) > 0 && times[len(times)-1].Before(cutoff) {
+			delete(c.failures, other)
+		}
+	}
+
+	if len(recent) < c.threshold {
+		return 0
+	}
+	return len(recent)
+}
@@ -0,0 +1,115 @@
+package alerts
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNotifier_DeliversAndRateLimits(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	var mu sync.Mutex
+	var bodies []genericPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload genericPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("failed to decode alert: %v", err)
+		}
+		mu.Lock()
+		bodies = append(bodies, payload)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	notifier := NewNotifier([]string{server.URL}, time.Hour, logger)
+	alert := Alert{Event: EventProviderUnhealthy, Subject: "gemini", Message: "down", Fields: map[string]string{"error": "timeout"}}
+
+	if !notifier.Notify(alert) {
+		t.Fatal("expected first alert to be sent")
+	}
+	if notifier.Notify(alert) {
+		t.Error("expected repeated alert to be suppressed")
+	}
+	if !notifier.Notify(Alert{Event: EventProviderUnhealthy, Subject: "ollama", Message: "down"}) {
+		t.Error("expected alert for a different subject to be sent")
+	}
+	notifier.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(bodies) != 2 {
+		t.Fatalf("expected 2 deliveries, got %d", len(bodies))
+	}
+	subjects := map[string]genericPayload{}
+	for _, body := range bodies {
+		subjects[body.Subject] = body
+	}
+	if got := subjects["gemini"]; got.Event != EventProviderUnhealthy || got.Fields["error"] != "timeout" {
+		t.Errorf("unexpected payload for gemini: %+v", got)
+	}
+	if _, ok := subjects["ollama"]; !ok {
+		t.Errorf("expected payload for ollama, got %+v", bodies)
+	}
+}
+
+func TestNotifier_SlackFormat(t *testing.T) {
+	text := slackText(Alert{Event: EventAuthFailures, Message: "10 failures", Fields: map[string]string{"b": "2", "a": "1"}})
+	if !strings.Contains(text, "auth_failures") || !strings.Contains(text, "10 failures") {
+		t.Errorf("expected event and message in Slack text, got %q", text)
+	}
+	if strings.Index(text, "a: 1") > strings.Index(text, "b: 2") {
+		t.Errorf("expected fields in sorted order, got %q", text)
+	}
+
+	if !isSlackWebhook("https://hooks.slack.com/services/T/B/X") || isSlackWebhook("https://example.com/hook") {
+		t.Error("unexpected Slack webhook detection")
+	}
+}
+
+func TestNotifier_DeliveryErrors(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	notifier := NewNotifier([]string{server.URL}, time.Hour, logger)
+	if err := notifier.deliver(server.URL, Alert{Event: EventKeyNearLimit}, time.Now()); err == nil {
+		t.Error("expected error for non-2xx webhook response")
+	}
+
+	var nilNotifier *Notifier
+	if nilNotifier.Notify(Alert{Event: EventKeyNearLimit}) {
+		t.Error("expected nil notifier to discard alerts")
+	}
+	nilNotifier.Wait()
+}
+
+func TestFailureCounter(t *testing.T) {
+	counter := NewFailureCounter(3, time.Minute)
+
+	if counter.Record("10.0.0.1") != 0 || counter.Record("10.0.0.1") != 0 {
+		t.Error("expected no trip below the threshold")
+	}
+	if counter.Record("10.0.0.2") != 0 {
+		t.Error("expected subjects to be counted separately")
+	}
+	if got := counter.Record("10.0.0.1"); got != 3 {
+		t.Errorf("expected trip at the threshold with 3 failures, got %d", got)
+	}
+
+	expired := NewFailureCounter(2, time.Nanosecond)
+	expired.Record("10.0.0.1")
+	time.Sleep(time.Millisecond)
+	if expired.Record("10.0.0.1") != 0 {
+		t.Error("expected failures outside the window to be forgotten")
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"maps"
+	"strings"
+	"sync"
+)
+
+// apiKeySet holds the API keys clients authenticate with (key -> role)
+// Keys can be replaced while the server runs, e.g. when a secrets manager rotates them
+// A nil set holds no keys
+type apiKeySet struct {
+	mu   sync.RWMutex
+	keys map[string]string
+}
+
+// newAPIKeySet creates a set holding the given keys
+func newAPIKeySet(keys map[string]string) *apiKeySet {
+	return &apiKeySet{keys: maps.Clone(keys)}
+}
+
+// parseAPIKeys parses a comma-separated API_KEYS value; keys ending in ":admin" get the admin role
+func parseAPIKeys(value string) map[string]string {
+	keys := make(map[string]string)
+	for _, key := range strings.Split(value, ",") {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		// Check for admin role suffix
+		if strings.HasSuffix(key, ":admin") {
+			keys[strings.TrimSuffix(key, ":admin")] = "admin"
+		} else {
+			keys[key] = "user"
+		}
+	}
+	return keys
+}
+
+// Role returns a key's role, reporting whether the key exists
+func (s *apiKeySet) Role(key string) (string, bool) {
+	if s == nil {
+		return "", false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	role, exists := s.keys[key]
+	return role, exists
+}
+
+// Len returns the number of keys
+func (s *apiKeySet) Len() int {
+	if s == nil {
+		return 0
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.keys)
+}
+
+// Snapshot returns a copy of the keys and their roles
+func (s *apiKeySet) Snapshot() map[string]string {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return maps.Clone(s.keys)
+}
+
+// Replace swaps in a new set of keys; requests already authenticated aren't affected
+func (s *apiKeySet) Replace(keys map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = maps.Clone(keys)
+}
@@ -0,0 +1,126 @@
+// Package archive keeps compressed session archives in S3-compatible object storage or a local
+// directory, so idle sessions can be restored after they leave memory
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"microchat.ai/cmd/server/awsauth"
+)
+
+// requestTimeout bounds each request to an object store
+const requestTimeout = 30 * time.Second
+
+// ErrNotFound is returned by Get for a key with no archived object
+var ErrNotFound = errors.New("archived object not found")
+
+// Object describes one archived object
+type Object struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+}
+
+// Store is a flat namespace of archived objects
+type Store interface {
+	// Name describes the store for logs, without credentials
+	Name() string
+	Put(ctx context.Context, key string, data []byte) error
+	// Get returns ErrNotFound when key doesn't exist
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Delete succeeds when key doesn't exist
+	Delete(ctx context.Context, key string) error
+	List(ctx context.Context) ([]Object, error)
+}
+
+// ParseStore builds a store from a URL:
+//
+//	s3://<bucket>/<prefix>  an S3 bucket, using AWS_REGION and AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY/AWS_SESSION_TOKEN;
+//	                        AWS_ENDPOINT_URL_S3 points it at MinIO or another S3-compatible service
+//	gs://<bucket>/<prefix>  a Google Cloud Storage bucket through its S3-compatible XML API, using the
+//	                        HMAC key in GCS_HMAC_ACCESS_ID/GCS_HMAC_SECRET
+//	file:///<dir>           a local directory
+//
+// getenv supplies the backend's own settings, normally os.Getenv
+func ParseStore(spec string, getenv func(string) string) (Store, error) {
+	parsed, err := url.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid archive store %q: %w", spec, err)
+	}
+
+	switch parsed.Scheme {
+	case "s3":
+		if parsed.Host == "" {
+			return nil, fmt.Errorf("invalid archive store %q: expected s3://<bucket>/<prefix>", spec)
+		}
+		region := getenv("AWS_REGION")
+		if region == "" {
+			region = getenv("AWS_DEFAULT_REGION")
+		}
+		if region == "" {
+			return nil, fmt.Errorf("AWS_REGION is required for an s3 archive store")
+		}
+		creds := awsauth.Credentials{
+			AccessKeyID:     getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    getenv("AWS_SESSION_TOKEN"),
+		}
+		if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+			return nil, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required for an s3 archive store")
+		}
+		endpoint := getenv("AWS_ENDPOINT_URL_S3")
+		if endpoint == "" {
+			endpoint = getenv("AWS_ENDPOINT_URL")
+		}
+		if endpoint == "" {
+			endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", region)
+		}
+		return newS3Store(parsed, endpoint, region, creds), nil
+
+	case "gs":
+		if parsed.Host == "" {
+			return nil, fmt.Errorf("invalid archive store %q: expected gs://<bucket>/<prefix>", spec)
+		}
+		creds := awsauth.Credentials{
+			AccessKeyID:     getenv("GCS_HMAC_ACCESS_ID"),
+			SecretAccessKey: getenv("GCS_HMAC_SECRET"),
+		}
+		if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+			return nil, fmt.Errorf("GCS_HMAC_ACCESS_ID and GCS_HMAC_SECRET are required for a gs archive store")
+		}
+		return newS3Store(parsed, "https://storage.googleapis.com", "auto", creds), nil
+
+	case "file":
+		dir := parsed.Path
+		if dir == "" || parsed.Host != "" {
+			return nil, fmt.Errorf("invalid archive store %q: expected file:///<dir>", spec)
+		}
+		return &DirStore{Dir: dir}, nil
+
+	default:
+		return nil, fmt.Errorf("invalid archive store %q: scheme must be s3, gs or file", spec)
+	}
+}
+
+// newS3Store builds an S3Store for a parsed s3:// or gs:// URL
+func newS3Store(parsed *url.URL, endpoint, region string, creds awsauth.Credentials) *S3Store {
+	prefix := strings.Trim(parsed.Path, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return &S3Store{
+		Endpoint:    strings.TrimRight(endpoint, "/"),
+		Region:      region,
+		Bucket:      parsed.Host,
+		Prefix:      prefix,
+		Credentials: creds,
+		Client:      &http.Client{Timeout: requestTimeout},
+		scheme:      parsed.Scheme,
+	}
+}
@@ -0,0 +1,215 @@
+package archive
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"microchat.ai/cmd/server/awsauth"
+)
+
+func TestParseStore(t *testing.T) {
+	env := map[string]string{
+		"AWS_REGION":            "eu-west-1",
+		"AWS_ACCESS_KEY_ID":     "AKID",
+		"AWS_SECRET_ACCESS_KEY": "secret",
+		"GCS_HMAC_ACCESS_ID":    "GOOG1",
+		"GCS_HMAC_SECRET":       "gsecret",
+	}
+	getenv := func(name string) string { return env[name] }
+
+	store, err := ParseStore("s3://chats/archive/sessions", getenv)
+	if err != nil {
+		t.Fatalf("s3 store: %v", err)
+	}
+	s3 := store.(*S3Store)
+	if s3.Endpoint != "https://s3.eu-west-1.amazonaws.com" || s3.Bucket != "chats" || s3.Prefix != "archive/sessions/" {
+		t.Errorf("unexpected s3 store %+v", s3)
+	}
+	if s3.Name() != "s3://chats/archive/sessions/" {
+		t.Errorf("Name() = %q", s3.Name())
+	}
+
+	store, err = ParseStore("gs://chats", getenv)
+	if err != nil {
+		t.Fatalf("gs store: %v", err)
+	}
+	gs := store.(*S3Store)
+	if gs.Endpoint != "https://storage.googleapis.com" || gs.Prefix != "" || gs.Credentials.AccessKeyID != "GOOG1" {
+		t.Errorf("unexpected gs store %+v", gs)
+	}
+
+	store, err = ParseStore("file:///var/lib/microchat/archive", getenv)
+	if err != nil {
+		t.Fatalf("file store: %v", err)
+	}
+	if dir := store.(*DirStore).Dir; dir != "/var/lib/microchat/archive" {
+		t.Errorf("Dir = %q", dir)
+	}
+
+	for _, spec := range []string{"s3://", "gs:///prefix", "file://relative", "ftp://host/dir"} {
+		if _, err := ParseStore(spec, getenv); err == nil {
+			t.Errorf("ParseStore(%q) should fail", spec)
+		}
+	}
+	if _, err := ParseStore("s3://chats", func(string) string { return "" }); err == nil {
+		t.Error("s3 store without credentials should fail")
+	}
+}
+
+// fakeS3 is an in-memory bucket that checks each request is signed with the payload hash
+type fakeS3 struct {
+	mu       sync.Mutex
+	objects  map[string][]byte
+	pageSize int
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	hash := sha256.Sum256(body)
+	if r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(hash[:]) {
+		http.Error(w, "payload hash mismatch", http.StatusBadRequest)
+		return
+	}
+	if !strings.Contains(r.Header.Get("Authorization"), "Credential=AKID/") {
+		http.Error(w, "unsigned request", http.StatusForbidden)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != "chats" {
+		http.Error(w, "no such bucket", http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && key == "":
+		prefix := r.URL.Query().Get("prefix")
+		var keys []string
+		for k := range f.objects {
+			if strings.HasPrefix(k, prefix) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		start := 0
+		if token := r.URL.Query().Get("continuation-token"); token != "" {
+			fmt.Sscan(token, &start)
+		}
+		end := min(start+f.pageSize, len(keys))
+		fmt.Fprint(w, "<ListBucketResult>")
+		for _, k := range keys[start:end] {
+			fmt.Fprintf(w, "<Contents><Key>%s</Key><Size>%d</Size><LastModified>2026-01-02T03:04:05.000Z</LastModified></Contents>", k, len(f.objects[k]))
+		}
+		if end < len(keys) {
+			fmt.Fprintf(w, "<IsTruncated>true</IsTruncated><NextContinuationToken>%d</NextContinuationToken>", end)
+		}
+		fmt.Fprint(w, "</ListBucketResult>")
+	case r.Method == http.MethodPut:
+		f.objects[key] = body
+	case r.Method == http.MethodGet:
+		data, ok := f.objects[key]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestS3Store(t *testing.T) {
+	fake := &fakeS3{objects: make(map[string][]byte), pageSize: 2}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	store := &S3Store{
+		Endpoint:    server.URL,
+		Region:      "us-east-1",
+		Bucket:      "chats",
+		Prefix:      "archive/",
+		Credentials: awsauth.Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret"},
+		Client:      server.Client(),
+	}
+	testStore(t, store)
+
+	if _, ok := fake.objects["archive/a.json.gz"]; !ok {
+		t.Error("objects should be stored under the prefix")
+	}
+}
+
+func TestDirStore(t *testing.T) {
+	store := &DirStore{Dir: t.TempDir() + "/archive"}
+	testStore(t, store)
+
+	if err := store.Put(context.Background(), "../escape", []byte("x")); err == nil {
+		t.Error("keys leaving the directory should be rejected")
+	}
+}
+
+// testStore exercises the Store contract shared by every backend
+func testStore(t *testing.T, store Store) {
+	t.Helper()
+	ctx := context.Background()
+
+	if objects, err := store.List(ctx); err != nil || len(objects) != 0 {
+		t.Fatalf("empty List() = %v, %v", objects, err)
+	}
+	if _, err := store.Get(ctx, "a.json.gz"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get of a missing key = %v, want ErrNotFound", err)
+	}
+
+	for _, key := range []string{"a.json.gz", "b.json.gz", "c.json.gz"} {
+		if err := store.Put(ctx, key, []byte("data-"+key)); err != nil {
+			t.Fatalf("Put(%s): %v", key, err)
+		}
+	}
+	if err := store.Put(ctx, "a.json.gz", []byte("replaced")); err != nil {
+		t.Fatalf("overwriting Put: %v", err)
+	}
+
+	data, err := store.Get(ctx, "a.json.gz")
+	if err != nil || string(data) != "replaced" {
+		t.Fatalf("Get() = %q, %v", data, err)
+	}
+
+	objects, err := store.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	var keys []string
+	for _, o := range objects {
+		keys = append(keys, o.Key)
+		if o.LastModified.IsZero() || o.LastModified.After(time.Now().Add(time.Minute)) {
+			t.Errorf("object %s has LastModified %v", o.Key, o.LastModified)
+		}
+	}
+	sort.Strings(keys)
+	if strings.Join(keys, ",") != "a.json.gz,b.json.gz,c.json.gz" {
+		t.Errorf("List() keys = %v", keys)
+	}
+
+	if err := store.Delete(ctx, "b.json.gz"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := store.Delete(ctx, "b.json.gz"); err != nil {
+		t.Errorf("deleting a missing key should succeed: %v", err)
+	}
+	if _, err := store.Get(ctx, "b.json.gz"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,109 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DirStore keeps objects as files in a local directory, for single-host deployments and tests
+type DirStore struct {
+	Dir string
+}
+
+// Name describes the store for logs
+func (d *DirStore) Name() string {
+	return "file://" + d.Dir
+}
+
+// path returns the file holding key, rejecting keys that would leave the directory
+func (d *DirStore) path(key string) (string, error) {
+	if key == "" || key != filepath.Base(key) || strings.HasPrefix(key, ".") {
+		return "", fmt.Errorf("invalid archive key %q", key)
+	}
+	return filepath.Join(d.Dir, key), nil
+}
+
+// Put writes an object through a temporary file so readers never see a partial one
+func (d *DirStore) Put(ctx context.Context, key string, data []byte) error {
+	path, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(d.Dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(d.Dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create archive file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write archive file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write archive file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write archive file: %w", err)
+	}
+	return nil
+}
+
+// Get reads an object
+func (d *DirStore) Get(ctx context.Context, key string) ([]byte, error) {
+	path, err := d.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive file: %w", err)
+	}
+	return data, nil
+}
+
+// Delete removes an object
+func (d *DirStore) Delete(ctx context.Context, key string) error {
+	path, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove archive file: %w", err)
+	}
+	return nil
+}
+
+// List returns every object in the directory, skipping temporary files
+func (d *DirStore) List(ctx context.Context) ([]Object, error) {
+	entries, err := os.ReadDir(d.Dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list archive directory: %w", err)
+	}
+
+	var objects []Object
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue // Removed while listing
+		}
+		objects = append(objects, Object{Key: entry.Name(), Size: info.Size(), LastModified: info.ModTime()})
+	}
+	return objects, nil
+}
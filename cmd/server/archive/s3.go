@@ -0,0 +1,174 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"microchat.ai/cmd/server/awsauth"
+)
+
+// S3Store keeps objects under a prefix of a bucket, using path-style requests so it also works with
+// MinIO and the Google Cloud Storage XML API
+type S3Store struct {
+	Endpoint    string // e.g. https://s3.eu-west-1.amazonaws.com
+	Region      string
+	Bucket      string
+	Prefix      string // Prepended to every key; empty or ending in "/"
+	Credentials awsauth.Credentials
+	Client      *http.Client
+	scheme      string // s3 or gs, for Name
+}
+
+// Name describes the store for logs
+func (s *S3Store) Name() string {
+	scheme := s.scheme
+	if scheme == "" {
+		scheme = "s3"
+	}
+	return scheme + "://" + s.Bucket + "/" + s.Prefix
+}
+
+// Put uploads an object, replacing any existing one
+func (s *S3Store) Put(ctx context.Context, key string, data []byte) error {
+	resp, err := s.do(ctx, http.MethodPut, s.objectURL(key), data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return statusError("put", resp)
+	}
+	return nil
+}
+
+// Get downloads an object
+func (s *S3Store) Get(ctx context.Context, key string) ([]byte, error) {
+	resp, err := s.do(ctx, http.MethodGet, s.objectURL(key), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, ErrNotFound
+	default:
+		return nil, statusError("get", resp)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archived object: %w", err)
+	}
+	return data, nil
+}
+
+// Delete removes an object
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, s.objectURL(key), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	}
+	return statusError("delete", resp)
+}
+
+// listResult is the part of a ListObjectsV2 response the store reads
+type listResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+}
+
+// List returns every object under the prefix, following continuation tokens
+func (s *S3Store) List(ctx context.Context) ([]Object, error) {
+	var objects []Object
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {s.Prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		listURL := s.Endpoint + "/" + url.PathEscape(s.Bucket) + "?" + query.Encode()
+
+		resp, err := s.do(ctx, http.MethodGet, listURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			err := statusError("list", resp)
+			resp.Body.Close()
+			return nil, err
+		}
+		var result listResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode object listing: %w", err)
+		}
+
+		for _, c := range result.Contents {
+			objects = append(objects, Object{
+				Key:          strings.TrimPrefix(c.Key, s.Prefix),
+				Size:         c.Size,
+				LastModified: c.LastModified,
+			})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// objectURL returns the path-style URL of a key
+func (s *S3Store) objectURL(key string) string {
+	segments := strings.Split(s.Prefix+key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return s.Endpoint + "/" + url.PathEscape(s.Bucket) + "/" + strings.Join(segments, "/")
+}
+
+// do sends a signed request; S3 requires the payload hash as a header as well as in the signature
+func (s *S3Store) do(ctx context.Context, method, rawURL string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create object store request: %w", err)
+	}
+	bodyHash := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(bodyHash[:]))
+	if body != nil {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+	awsauth.SignV4(req, body, s.Credentials, s.Region, "s3", time.Now())
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("object store request failed: %w", err)
+	}
+	return resp, nil
+}
+
+// statusError describes an unexpected object store response
+func statusError(op string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("object store %s returned status %d: %s", op, resp.StatusCode, strings.TrimSpace(string(body)))
+}
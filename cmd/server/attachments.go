@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"microchat.ai/cmd/server/llm"
+	pb "microchat.ai/proto"
+)
+
+// maxAttachmentsPerMessage bounds how many images a single chat message can carry
+const maxAttachmentsPerMessage = 4
+
+// allowedAttachmentTypes are the image MIME types accepted by multimodal providers
+// Types that net/http can sniff must also match the file's actual content
+var allowedAttachmentTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/webp": true,
+	"image/heic": false, // Not sniffable
+	"image/heif": false, // Not sniffable
+}
+
+// validateAttachments checks attachment count, size and type
+// maxBytes is the per-attachment limit; 0 disables attachments
+func validateAttachments(attachments []*pb.Attachment, maxBytes int) error {
+	if len(attachments) == 0 {
+		return nil
+	}
+	if maxBytes <= 0 {
+		return status.Error(codes.InvalidArgument, "attachments are disabled on this server")
+	}
+	if len(attachments) > maxAttachmentsPerMessage {
+		return status.Errorf(codes.InvalidArgument, "too many attachments: %d (max %d)", len(attachments), maxAttachmentsPerMessage)
+	}
+
+	for i, attachment := range attachments {
+		if len(attachment.Data) == 0 {
+			return status.Errorf(codes.InvalidArgument, "attachment %d is empty", i)
+		}
+		if len(attachment.Data) > maxBytes {
+			return status.Errorf(codes.InvalidArgument, "attachment %d too large: %d bytes (max %d)", i, len(attachment.Data), maxBytes)
+		}
+
+		mimeType := strings.ToLower(attachment.MimeType)
+		sniffable, allowed := allowedAttachmentTypes[mimeType]
+		if !allowed {
+			return status.Errorf(codes.InvalidArgument, "attachment %d has unsupported type %q (supported: image/png, image/jpeg, image/webp, image/heic, image/heif)", i, attachment.MimeType)
+		}
+		if sniffable && http.DetectContentType(attachment.Data) != mimeType {
+			return status.Errorf(codes.InvalidArgument, "attachment %d content does not match type %q", i, attachment.MimeType)
+		}
+	}
+	return nil
+}
+
+// supportsImages reports whether a provider accepts image attachments
+func supportsImages(provider llm.Provider) bool {
+	imageInput, ok := provider.(llm.ImageInput)
+	return ok && imageInput.SupportsImages()
+}
+
+// toLLMAttachments converts request attachments to the provider format
+func toLLMAttachments(attachments []*pb.Attachment) []llm.Attachment {
+	result := make([]llm.Attachment, len(attachments))
+	for i, attachment := range attachments {
+		result[i] = llm.Attachment{MIMEType: strings.ToLower(attachment.MimeType), Data: attachment.Data}
+	}
+	return result
+}
+
+// attachmentNote describes attachments in the stored user message
+// Image bytes are sent to the LLM for the current turn only and never stored in the session
+func attachmentNote(attachments []*pb.Attachment) string {
+	var b strings.Builder
+	for _, attachment := range attachments {
+		fmt.Fprintf(&b, "\n[Attached %s, %.1f KB]", strings.ToLower(attachment.MimeType), float64(len(attachment.Data))/1024)
+	}
+	return b.String()
+}
+
+// maxChatRequestBytes is the gRPC receive limit needed for a chat message with
+// the maximum number of attachments, never lower than gRPC's 4MB default
+func maxChatRequestBytes(maxAttachmentBytes int) int {
+	const defaultMaxRecvMsgSize = 4 * 1024 * 1024
+	const overhead = 64 * 1024 // Message text and protobuf framing
+	return max(defaultMaxRecvMsgSize, maxAttachmentsPerMessage*maxAttachmentBytes+overhead)
+}
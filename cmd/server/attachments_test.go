@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"microchat.ai/cmd/server/llm"
+	pb "microchat.ai/proto"
+)
+
+// pngHeader is the PNG file signature followed by the start of an IHDR chunk
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestValidateAttachments(t *testing.T) {
+	png := &pb.Attachment{MimeType: "image/png", Data: pngHeader}
+
+	tests := []struct {
+		name        string
+		attachments []*pb.Attachment
+		maxBytes    int
+		wantErr     bool
+	}{
+		{"none", nil, 0, false},
+		{"valid png", []*pb.Attachment{png}, 1024, false},
+		{"uppercase type", []*pb.Attachment{{MimeType: "IMAGE/PNG", Data: pngHeader}}, 1024, false},
+		{"heic is not sniffed", []*pb.Attachment{{MimeType: "image/heic", Data: []byte("ftypheic")}}, 1024, false},
+		{"disabled", []*pb.Attachment{png}, 0, true},
+		{"too many", []*pb.Attachment{png, png, png, png, png}, 1024, true},
+		{"too large", []*pb.Attachment{png}, 8, true},
+		{"empty", []*pb.Attachment{{MimeType: "image/png"}}, 1024, true},
+		{"unsupported type", []*pb.Attachment{{MimeType: "application/pdf", Data: []byte("%PDF-1.7")}}, 1024, true},
+		{"content mismatch", []*pb.Attachment{{MimeType: "image/jpeg", Data: pngHeader}}, 1024, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAttachments(tt.attachments, tt.maxBytes)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if err != nil && status.Code(err) != codes.InvalidArgument {
+				t.Errorf("Expected InvalidArgument, got %v", err)
+			}
+		})
+	}
+}
+
+func TestMaxChatRequestBytes(t *testing.T) {
+	if got := maxChatRequestBytes(0); got != 4*1024*1024 {
+		t.Errorf("Expected gRPC default for disabled attachments, got %d", got)
+	}
+	if got := maxChatRequestBytes(2 * 1024 * 1024); got <= 8*1024*1024 {
+		t.Errorf("Expected room for four 2MB attachments, got %d", got)
+	}
+}
+
+func TestChatWithAttachments(t *testing.T) {
+	app, mockProvider := setupTestApplicationWithMock(t)
+	app.config.maxAttachmentBytes = 1024
+	ctx := context.Background()
+
+	startResp, err := app.StartSession(ctx, &pb.StartSessionRequest{})
+	if err != nil {
+		t.Fatalf("Failed to start session: %v", err)
+	}
+	sessionID := startResp.SessionId
+
+	mockProvider.SetResponses("A diagram")
+	_, err = app.Chat(ctx, &pb.ChatRequest{
+		SessionId:   sessionID,
+		Message:     "What is this?",
+		Attachments: []*pb.Attachment{{MimeType: "image/png", Data: pngHeader}},
+	})
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+
+	// Only a note about the image is kept in history, never the bytes
+	stored := app.sessionStore.GetMessages(sessionID)[0].Text
+	if !strings.HasPrefix(stored, "What is this?\n[Attached image/png") {
+		t.Errorf("Expected attachment note in stored message, got %q", stored)
+	}
+
+	// Providers without image support reject attachments before anything is stored
+	app.providerFactory = func(model pb.Model, logger *slog.Logger) llm.Provider {
+		return llm.NewEchoProvider()
+	}
+	_, err = app.Chat(ctx, &pb.ChatRequest{
+		SessionId:   sessionID,
+		Message:     "And this?",
+		Attachments: []*pb.Attachment{{MimeType: "image/png", Data: pngHeader}},
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for provider without image support, got %v", err)
+	}
+	if count := app.sessionStore.GetMessageCount(sessionID); count != 2 {
+		t.Errorf("Expected rejected message not to be stored, got %d messages", count)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/stats"
+)
+
+// unauthenticatedKeyHash labels traffic from requests without a configured API key
+const unauthenticatedKeyHash = "unauthenticated"
+
+// KeyBandwidth is the traffic attributed to one API key since the server started
+// Payload bytes are serialized message sizes (what the client reports as payload);
+// wire bytes add gRPC framing, compression and inbound headers
+type KeyBandwidth struct {
+	KeyHash         string
+	PayloadBytesIn  int64
+	PayloadBytesOut int64
+	WireBytesIn     int64
+	WireBytesOut    int64
+}
+
+// bandwidthTracker is a gRPC stats handler that accounts request and response bytes per API key
+type bandwidthTracker struct {
+	apiKeys *apiKeySet // Configured API keys; anything else is counted as unauthenticated
+
+	mu    sync.Mutex
+	usage map[string]*KeyBandwidth // Key hash -> totals
+}
+
+// bandwidthKey tags an RPC's context with the hash of the API key it is billed to
+type bandwidthKey struct{}
+
+// newBandwidthTracker creates a tracker for the configured API keys
+func newBandwidthTracker(apiKeys *apiKeySet) *bandwidthTracker {
+	return &bandwidthTracker{
+		apiKeys: apiKeys,
+		usage:   make(map[string]*KeyBandwidth),
+	}
+}
+
+// TagRPC attributes the RPC to the API key in its authorization header
+// Stats handlers run before interceptors, so the key is read from metadata directly;
+// unknown keys share one bucket so random tokens can't grow the metric labels
+func (t *bandwidthTracker) TagRPC(ctx context.Context, info *stats.RPCTagInfo) context.Context {
+	keyHash := unauthenticatedKeyHash
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if auth := md.Get("authorization"); len(auth) > 0 {
+			apiKey := strings.TrimPrefix(auth[0], "Bearer ")
+			if _, exists := t.apiKeys.Role(apiKey); exists {
+				keyHash = hashAPIKey(apiKey)
+			}
+		}
+	}
+	return context.WithValue(ctx, bandwidthKey{}, keyHash)
+}
+
+// HandleRPC records payload and wire bytes in both directions
+func (t *bandwidthTracker) HandleRPC(ctx context.Context, s stats.RPCStats) {
+	keyHash, ok := ctx.Value(bandwidthKey{}).(string)
+	if !ok {
+		return
+	}
+
+	switch stat := s.(type) {
+	case *stats.InPayload:
+		t.add(keyHash, int64(stat.Length), 0, int64(stat.WireLength), 0)
+	case *stats.OutPayload:
+		t.add(keyHash, 0, int64(stat.Length), 0, int64(stat.WireLength))
+	case *stats.InHeader:
+		t.add(keyHash, 0, 0, int64(stat.WireLength), 0)
+	}
+}
+
+// TagConn is a no-op; bandwidth is attributed per RPC
+func (t *bandwidthTracker) TagConn(ctx context.Context, info *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+// HandleConn is a no-op; bandwidth is attributed per RPC
+func (t *bandwidthTracker) HandleConn(ctx context.Context, s stats.ConnStats) {}
+
+// add accumulates bytes for a key and mirrors them to Prometheus
+func (t *bandwidthTracker) add(keyHash string, payloadIn, payloadOut, wireIn, wireOut int64) {
+	t.mu.Lock()
+	usage, exists := t.usage[keyHash]
+	if !exists {
+		usage = &KeyBandwidth{KeyHash: keyHash}
+		t.usage[keyHash] = usage
+	}
+	usage.PayloadBytesIn += payloadIn
+	usage.PayloadBytesOut += payloadOut
+	usage.WireBytesIn += wireIn
+	usage.WireBytesOut += wireOut
+	t.mu.Unlock()
+
+	recordKeyBytes(keyHash, payloadIn, payloadOut, wireIn, wireOut)
+}
+
+// Snapshot returns the totals for every key seen so far, ordered by key hash
+func (t *bandwidthTracker) Snapshot() []KeyBandwidth {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	result := make([]KeyBandwidth, 0, len(t.usage))
+	for _, usage := range t.usage {
+		result = append(result, *usage)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].KeyHash < result[j].KeyHash
+	})
+	return result
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/stats"
+)
+
+func TestBandwidthTracker(t *testing.T) {
+	tracker := newBandwidthTracker(newAPIKeySet(map[string]string{"alice-key": "user"}))
+	aliceHash := hashAPIKey("alice-key")
+	wireInBefore := testutil.ToFloat64(keyBytesTotal.WithLabelValues(aliceHash, "in", "wire"))
+
+	rpc := func(authorization string) context.Context {
+		md := metadata.Pairs("authorization", authorization)
+		ctx := metadata.NewIncomingContext(context.Background(), md)
+		return tracker.TagRPC(ctx, &stats.RPCTagInfo{FullMethodName: "/chat.ChatService/Chat"})
+	}
+
+	ctx := rpc("Bearer alice-key")
+	tracker.HandleRPC(ctx, &stats.InHeader{WireLength: 50})
+	tracker.HandleRPC(ctx, &stats.InPayload{Length: 100, WireLength: 105})
+	tracker.HandleRPC(ctx, &stats.OutPayload{Length: 200, WireLength: 205})
+
+	// Unknown keys share a bucket so arbitrary tokens can't create new labels
+	tracker.HandleRPC(rpc("Bearer guessed-key"), &stats.InPayload{Length: 10, WireLength: 15})
+	tracker.HandleRPC(rpc("Bearer another-guess"), &stats.InPayload{Length: 10, WireLength: 15})
+
+	snapshot := tracker.Snapshot()
+	if len(snapshot) != 2 {
+		t.Fatalf("Expected 2 keys, got %+v", snapshot)
+	}
+	usage := map[string]KeyBandwidth{}
+	for _, u := range snapshot {
+		usage[u.KeyHash] = u
+	}
+
+	alice := usage[aliceHash]
+	if alice.PayloadBytesIn != 100 || alice.PayloadBytesOut != 200 || alice.WireBytesIn != 155 || alice.WireBytesOut != 205 {
+		t.Errorf("Unexpected totals for alice: %+v", alice)
+	}
+	if unknown := usage[unauthenticatedKeyHash]; unknown.PayloadBytesIn != 20 {
+		t.Errorf("Expected unknown keys to be counted together, got %+v", unknown)
+	}
+
+	if got := testutil.ToFloat64(keyBytesTotal.WithLabelValues(aliceHash, "in", "wire")) - wireInBefore; got != 155 {
+		t.Errorf("Expected 155 wire bytes in for alice, got %v", got)
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"regexp"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"microchat.ai/cmd/server/llm"
+	pb "microchat.ai/proto"
+)
+
+// piiPatterns match personal data replaced in captured exchanges; message contents are kept
+// otherwise, since debugging a bad reply needs the prompt that produced it
+var piiPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),                  // Email addresses
+	regexp.MustCompile(`\b(?:\d[ -]?){13,19}\b`),                                          // Card and account numbers
+	regexp.MustCompile(`\+?\(?\d{1,4}\)?[ .-]?\(?\d{2,4}\)?[ .-]?\d{3,4}[ .-]?\d{3,4}\b`), // Phone numbers
+	regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`),                                           // US social security numbers
+	regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`),                                     // IPv4 addresses
+	regexp.MustCompile(`\b(?:sk|pk|AIza|ghp|xox[bp])[-_A-Za-z0-9]{16,}\b`),                // Credentials pasted into chat
+}
+
+// redactPII replaces personal data and credentials in text
+func redactPII(text string) string {
+	for _, pattern := range piiPatterns {
+		text = pattern.ReplaceAllString(text, redactedValue)
+	}
+	return text
+}
+
+// capturedExchange is one provider request/response pair after redaction
+type capturedExchange struct {
+	capturedAt  time.Time
+	provider    string
+	model       string
+	sessionHash string
+	keyHash     string
+	messages    []capturedMessage
+	reply       string
+	err         string
+	duration    time.Duration
+	truncated   bool
+}
+
+type capturedMessage struct {
+	role string
+	text string
+}
+
+// llmCapture keeps the most recent provider exchanges in a fixed-size ring buffer,
+// so bad model output can be debugged without turning on verbose logging
+// A nil *llmCapture captures nothing
+type llmCapture struct {
+	mu       sync.Mutex
+	entries  []capturedExchange
+	next     int // Slot the next exchange is written to
+	full     bool
+	maxBytes int // Cap on each captured text
+}
+
+// newLLMCapture creates a capture buffer holding size exchanges, or nil when size is 0
+func newLLMCapture(size, maxBytes int) *llmCapture {
+	if size <= 0 {
+		return nil
+	}
+	return &llmCapture{entries: make([]capturedExchange, size), maxBytes: maxBytes}
+}
+
+// capText redacts text and cuts it to the size cap on a rune boundary, reporting whether it was cut
+func (c *llmCapture) capText(text string) (string, bool) {
+	text = redactPII(text)
+	if c.maxBytes <= 0 || len(text) <= c.maxBytes {
+		return text, false
+	}
+	cut := c.maxBytes
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut] + "…", true
+}
+
+// record adds an exchange, overwriting the oldest once the buffer is full
+func (c *llmCapture) record(e capturedExchange) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[c.next] = e
+	c.next = (c.next + 1) % len(c.entries)
+	if c.next == 0 {
+		c.full = true
+	}
+}
+
+// recent returns up to limit captured exchanges, newest first (limit 0 returns all)
+func (c *llmCapture) recent(limit int) []capturedExchange {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	count := c.next
+	if c.full {
+		count = len(c.entries)
+	}
+	if limit > 0 && limit < count {
+		count = limit
+	}
+
+	exchanges := make([]capturedExchange, 0, count)
+	for i := 1; i <= count; i++ {
+		exchanges = append(exchanges, c.entries[(c.next-i+len(c.entries))%len(c.entries)])
+	}
+	return exchanges
+}
+
+// captureExchange records a Chat call's provider request and reply when capture is enabled
+// Client-encrypted sessions are never captured, since the server must not keep their plaintext
+func (app *application) captureExchange(ctx context.Context, sessionID string, model pb.Model, provider string, messages []llm.Message, reply string, err error, duration time.Duration, clientEncrypted bool) {
+	c := app.llmCapture
+	if c == nil || clientEncrypted {
+		return
+	}
+
+	e := capturedExchange{
+		capturedAt:  time.Now(),
+		provider:    provider,
+		model:       model.String(),
+		sessionHash: hashIdentifier(sessionID),
+		keyHash:     hashAPIKey(apiKeyFromContext(ctx)),
+		duration:    duration,
+	}
+	for _, m := range messages {
+		text, cut := c.capText(m.Text)
+		if len(m.Attachments) > 0 {
+			text += " [image attachments omitted]"
+		}
+		e.messages = append(e.messages, capturedMessage{role: m.Role, text: text})
+		e.truncated = e.truncated || cut
+	}
+	var cut bool
+	e.reply, cut = c.capText(reply)
+	e.truncated = e.truncated || cut
+	if err != nil {
+		e.err, cut = c.capText(err.Error())
+		e.truncated = e.truncated || cut
+	}
+	c.record(e)
+}
+
+// toLLMExchangeProto converts a captured exchange for the ListLLMCaptures response
+func toLLMExchangeProto(e capturedExchange) *pb.LLMExchange {
+	messages := make([]*pb.CapturedMessage, len(e.messages))
+	for i, m := range e.messages {
+		messages[i] = &pb.CapturedMessage{Role: m.role, Text: m.text}
+	}
+	return &pb.LLMExchange{
+		CapturedAtUnix: e.capturedAt.Unix(),
+		Provider:       e.provider,
+		Model:          e.model,
+		SessionHash:    e.sessionHash,
+		KeyHash:        e.keyHash,
+		Messages:       messages,
+		Reply:          e.reply,
+		Error:          e.err,
+		DurationMs:     e.duration.Milliseconds(),
+		Truncated:      e.truncated,
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	pb "microchat.ai/proto"
+)
+
+func TestRedactPII(t *testing.T) {
+	tests := []struct {
+		input string
+		leak  string
+	}{
+		{"mail me at jane.doe@example.com please", "jane.doe@example.com"},
+		{"card 4111 1111 1111 1111 expires soon", "4111 1111 1111 1111"},
+		{"call +1 (555) 123-4567 tonight", "123-4567"},
+		{"my ssn is 123-45-6789", "123-45-6789"},
+		{"server at 192.168.1.20 is down", "192.168.1.20"},
+		{"key sk-abcdefghijklmnopqrstuvwx leaked", "sk-abcdefghijklmnopqrstuvwx"},
+	}
+	for _, tt := range tests {
+		got := redactPII(tt.input)
+		if strings.Contains(got, tt.leak) || !strings.Contains(got, redactedValue) {
+			t.Errorf("redactPII(%q) = %q, expected %q to be redacted", tt.input, got, tt.leak)
+		}
+	}
+
+	if got := redactPII("What is the capital of France?"); got != "What is the capital of France?" {
+		t.Errorf("Expected text without PII to be kept, got %q", got)
+	}
+}
+
+func TestLLMCaptureRing(t *testing.T) {
+	if newLLMCapture(0, 100) != nil {
+		t.Error("Expected capture to be disabled with size 0")
+	}
+	var disabled *llmCapture
+	disabled.record(capturedExchange{reply: "ignored"})
+	if disabled.recent(0) != nil {
+		t.Error("Expected nil capture to hold nothing")
+	}
+
+	c := newLLMCapture(3, 100)
+	for _, reply := range []string{"one", "two", "three", "four"} {
+		c.record(capturedExchange{reply: reply})
+	}
+	recent := c.recent(0)
+	if len(recent) != 3 || recent[0].reply != "four" || recent[2].reply != "two" {
+		t.Errorf("Expected the newest 3 exchanges, newest first, got %+v", recent)
+	}
+	if recent := c.recent(1); len(recent) != 1 || recent[0].reply != "four" {
+		t.Errorf("Expected limit to return the newest exchange, got %+v", recent)
+	}
+
+	small := newLLMCapture(1, 8)
+	if text, cut := small.capText("héllo wörld"); !cut || len(text) > 8+len("…") || !strings.HasPrefix(text, "héllo") {
+		t.Errorf("Expected text cut on a rune boundary, got %q", text)
+	}
+}
+
+func TestListLLMCaptures(t *testing.T) {
+	app, mockProvider := setupTestApplicationWithMock(t)
+	ctx := context.WithValue(context.Background(), "api_key", "alice-key")
+
+	resp, err := app.ListLLMCaptures(ctx, &pb.ListLLMCapturesRequest{})
+	if err != nil || resp.Enabled || len(resp.Exchanges) != 0 {
+		t.Fatalf("Expected capture to be off by default, got %+v, %v", resp, err)
+	}
+
+	app.llmCapture = newLLMCapture(10, 4096)
+	mockProvider.SetResponses("Reply for you")
+	startResp, err := app.StartSession(ctx, &pb.StartSessionRequest{})
+	if err != nil {
+		t.Fatalf("Failed to start session: %v", err)
+	}
+	if _, err := app.Chat(ctx, &pb.ChatRequest{SessionId: startResp.SessionId, Message: "I'm bob@example.com", Model: pb.Model_GEMINI_2_5_FLASH_LITE}); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+
+	resp, err = app.ListLLMCaptures(ctx, &pb.ListLLMCapturesRequest{})
+	if err != nil {
+		t.Fatalf("ListLLMCaptures failed: %v", err)
+	}
+	if !resp.Enabled || len(resp.Exchanges) != 1 {
+		t.Fatalf("Expected one captured exchange, got %+v", resp)
+	}
+	exchange := resp.Exchanges[0]
+	if exchange.KeyHash != hashAPIKey("alice-key") || exchange.SessionHash != hashIdentifier(startResp.SessionId) {
+		t.Errorf("Expected caller and session to be identified by hash, got %+v", exchange)
+	}
+	if len(exchange.Messages) != 1 || strings.Contains(exchange.Messages[0].Text, "bob@example.com") {
+		t.Errorf("Expected the redacted prompt, got %+v", exchange.Messages)
+	}
+	if !strings.Contains(exchange.Reply, "Reply for you") {
+		t.Errorf("Expected the provider reply, got %q", exchange.Reply)
+	}
+}
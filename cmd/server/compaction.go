@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"microchat.ai/cmd/server/llm"
+)
+
+// CompactionPolicy decides what happens when a session reaches its message or size limit
+type CompactionPolicy string
+
+const (
+	CompactionReject     CompactionPolicy = "reject"      // Refuse new messages (ResourceExhausted)
+	CompactionDropOldest CompactionPolicy = "drop_oldest" // Drop the oldest turns to make room
+	CompactionSummarize  CompactionPolicy = "summarize"   // Replace older turns with an LLM summary, dropping turns as a fallback
+)
+
+const (
+	compactionThreshold    = 0.8              // Summarize once a session reaches this fraction of either limit
+	maxSummaryLength       = 2000             // Maximum stored summary length in characters
+	maxSummaryInputLength  = 1000             // Maximum characters of each message sent to the summary prompt
+	summaryTimeout         = 20 * time.Second // Upper bound for the summary generation call
+	summaryMessagePrefix   = "Summary of the earlier conversation: "
+	minMessagesToSummarize = 4 // Too few messages aren't worth an extra LLM call
+)
+
+// ParseCompactionPolicy validates a SESSION_COMPACTION value
+func ParseCompactionPolicy(value string) (CompactionPolicy, error) {
+	switch policy := CompactionPolicy(strings.ToLower(strings.TrimSpace(value))); policy {
+	case CompactionReject, CompactionDropOldest, CompactionSummarize:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown compaction policy %q (expected reject, drop_oldest or summarize)", value)
+	}
+}
+
+// SetCompactionPolicy sets how sessions at their limits make room for new messages
+// The zero value rejects messages like before compaction existed
+func (s *SessionStore) SetCompactionPolicy(policy CompactionPolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.compaction = policy
+}
+
+// compactionPolicy returns the configured policy
+func (s *SessionStore) compactionPolicy() CompactionPolicy {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.compaction == "" {
+		return CompactionReject
+	}
+	return s.compaction
+}
+
+// dropOldestTurn removes the oldest message and any replies up to the next user message,
+// so the remaining history still starts with a user turn
+// Returns false when the policy forbids compaction or there is nothing to drop
+// Caller must hold the shard's write lock
+func (s *SessionStore) dropOldestTurn(shard *sessionShard, sessionID string, session *Session) bool {
+	if s.compactionPolicy() == CompactionReject || len(session.Messages) == 0 {
+		return false
+	}
+
+	end := 1
+	for end < len(session.Messages) && session.Messages[end].Role != User {
+		end++
+	}
+
+	freed := 0
+	for _, msg := range session.Messages[:end] {
+		freed += messageDataSize(msg)
+	}
+
+	// Shift in place so the backing array (and its accounted capacity) is reused
+	remaining := copy(session.Messages, session.Messages[end:])
+	clear(session.Messages[remaining:])
+	session.Messages = session.Messages[:remaining]
+
+	s.resizeSession(session, -freed)
+	s.persistRecord(shard, walRecord{Op: walDrop, SessionID: sessionID, Count: end})
+	recordSessionCompaction(string(CompactionDropOldest))
+	return true
+}
+
+// sizeWithOnly returns the size a session would have if message were its only message
+func sizeWithOnly(session *Session, message Message) int {
+	size := session.sizeBytes + messageDataSize(message)
+	for _, msg := range session.Messages {
+		size -= messageDataSize(msg)
+	}
+	if cap(session.Messages) == 0 {
+		size += messageStructSize
+	}
+	return size
+}
+
+// NeedsSummary reports whether a session is close enough to its limits to summarize older turns
+func (s *SessionStore) NeedsSummary(sessionID string) bool {
+	if s.compactionPolicy() != CompactionSummarize {
+		return false
+	}
+
+	shard := s.shardFor(sessionID)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	session, exists := shard.sessions[sessionID]
+	if !exists || len(session.Messages) < minMessagesToSummarize {
+		return false
+	}
+	return float64(len(session.Messages)) >= compactionThreshold*float64(s.maxMessagesPerSession) ||
+		float64(session.sizeBytes) >= compactionThreshold*float64(s.maxSessionSizeBytes)
+}
+
+// ReplaceWithSummary replaces a session's first count messages with a single system message
+// holding summary, encrypted like any other message in the session
+// through is the timestamp of the last summarized message, guarding against concurrent changes
+func (s *SessionStore) ReplaceWithSummary(sessionID string, count int, through time.Time, summary string, clientCipher *messageCipher) error {
+	shard := s.shardFor(sessionID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	session, exists := shard.sessions[sessionID]
+	if !exists {
+		return fmt.Errorf("session not found")
+	}
+	if count <= 0 || count > len(session.Messages) || !session.Messages[count-1].Timestamp.Equal(through) {
+		return fmt.Errorf("session changed while it was being summarized")
+	}
+
+	message := Message{Role: System, Text: summaryMessagePrefix + summary, Timestamp: session.Messages[count-1].Timestamp}
+	if c := s.cipherFor(shard, sessionID, clientCipher); c != nil {
+		sealed, err := c.seal(sessionID, message.Text)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt summary: %w", err)
+		}
+		message.Text = ""
+		message.Sealed = sealed
+	}
+
+	messages := make([]Message, 0, len(session.Messages)-count+1)
+	messages = append(messages, message)
+	session.Messages = append(messages, session.Messages[count:]...)
+
+	previous := session.sizeBytes
+	session.sizeBytes = computeSessionSize(session)
+	s.mu.Lock()
+	s.totalBytes += session.sizeBytes - previous
+	s.mu.Unlock()
+
+	s.persistRecord(shard, walRecord{Op: walSummarize, SessionID: sessionID, Count: count, Message: &message})
+	recordSessionCompaction(string(CompactionSummarize))
+	return nil
+}
+
+// summaryCutoff returns how many of the oldest messages to summarize: about half,
+// ending just before a user message so the kept history starts with a user turn
+func summaryCutoff(messages []Message) int {
+	for i := len(messages) / 2; i < len(messages); i++ {
+		if messages[i].Role == User {
+			return i
+		}
+	}
+	return 0
+}
+
+// generateSummary asks the provider to condense the given messages into a short summary,
+// returning it with the tokens the call consumed
+func generateSummary(ctx context.Context, provider llm.Provider, messages []Message) (string, int64, error) {
+	var transcript strings.Builder
+	for _, msg := range messages {
+		if msg.Role == Tool {
+			continue
+		}
+		fmt.Fprintf(&transcript, "%s: %s\n", msg.Role, truncateRunes(strings.TrimPrefix(msg.Text, summaryMessagePrefix), maxSummaryInputLength))
+	}
+
+	prompt := "Summarize the following conversation in a few sentences, keeping names, facts and decisions " +
+		"the assistant will need to continue it. Reply with the summary only.\n\n" + transcript.String()
+
+	llmMessages := []llm.Message{{Role: User.String(), Text: prompt}}
+	ctx, usage := llm.WithUsage(ctx)
+	summary, err := provider.GenerateResponse(ctx, llmMessages)
+	tokens := replyTokens(usage, llmMessages, summary, err)
+	if err != nil {
+		return "", tokens, err
+	}
+	summary = strings.TrimSpace(sanitizeForTerminal(summary))
+	if summary == "" {
+		return "", tokens, fmt.Errorf("provider returned an empty summary")
+	}
+	return truncateRunes(summary, maxSummaryLength), tokens, nil
+}
+
+// summarizeSession replaces the older half of a session nearing its limits with an LLM summary
+// Failures are logged and left to the store's drop_oldest fallback
+// The summary call's tokens count against the session's budget and apiKey's daily limit like a reply's
+func (app *application) summarizeSession(ctx context.Context, provider llm.Provider, sessionID, apiKey string, clientCipher *messageCipher) {
+	if !app.sessionStore.NeedsSummary(sessionID) {
+		return
+	}
+
+	messages := app.sessionStore.GetMessagesWithKey(sessionID, clientCipher)
+	cutoff := summaryCutoff(messages)
+	if cutoff == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, summaryTimeout)
+	defer cancel()
+
+	summary, tokens, err := generateSummary(ctx, provider, messages[:cutoff])
+	app.sessionStore.AddSessionTokens(sessionID, tokens)
+	app.spendingTracker.RecordTokens(apiKey, tokens)
+	if err == nil {
+		err = app.sessionStore.ReplaceWithSummary(sessionID, cutoff, messages[cutoff-1].Timestamp, summary, clientCipher)
+	}
+	if err != nil {
+		app.logger.Warn("failed to summarize session", "session_id", sessionID, "error", err)
+		return
+	}
+	app.logger.Info("summarized session", "session_id", sessionID, "summarized_messages", cutoff, "summary_len", len(summary))
+}
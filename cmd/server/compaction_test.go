@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCompactionPolicy(t *testing.T) {
+	for _, value := range []string{"reject", "drop_oldest", " Summarize "} {
+		if _, err := ParseCompactionPolicy(value); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", value, err)
+		}
+	}
+	if _, err := ParseCompactionPolicy("truncate"); err == nil {
+		t.Error("Expected error for unknown policy")
+	}
+}
+
+func TestSessionStore_DropOldestAtMessageLimit(t *testing.T) {
+	store := NewSessionStore(2*time.Hour, 1000, 4, 100*1024)
+	store.SetCompactionPolicy(CompactionDropOldest)
+	store.RegisterSession("session", "")
+
+	for i := range 3 {
+		if err := store.AppendMessage("session", User, fmt.Sprintf("question %d", i)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := store.AppendMessage("session", Assistant, fmt.Sprintf("answer %d", i)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	messages := store.GetMessages("session")
+	if len(messages) != 4 || messages[0].Text != "question 1" || messages[3].Text != "answer 2" {
+		t.Errorf("Expected the oldest turn to be dropped, got %v", store.GetFormattedMessages("session"))
+	}
+	session := storedSession(store, "session")
+	if total, _ := store.GetMemoryUsage(); session.sizeBytes != computeSessionSize(session) || total != session.sizeBytes {
+		t.Errorf("Expected sizes to stay accurate after compaction, got %d (total %d)", session.sizeBytes, total)
+	}
+}
+
+func TestSessionStore_DropOldestAtSizeLimit(t *testing.T) {
+	store := NewSessionStore(2*time.Hour, 1000, 100, sessionStructSize+4*messageStructSize+250)
+	store.SetCompactionPolicy(CompactionDropOldest)
+	store.RegisterSession("session", "")
+
+	for i := range 5 {
+		if err := store.AppendMessage("session", User, strings.Repeat(fmt.Sprint(i), 100)); err != nil {
+			t.Fatalf("Unexpected error on message %d: %v", i, err)
+		}
+	}
+	messages := store.GetMessages("session")
+	if len(messages) != 2 || messages[1].Text[0] != '4' {
+		t.Errorf("Expected only the newest messages to fit, got %d messages", len(messages))
+	}
+
+	// A message too large for an empty session is rejected without dropping history
+	if err := store.AppendMessage("session", User, strings.Repeat("x", 1000)); err == nil {
+		t.Error("Expected size limit error for an oversized message")
+	}
+	if len(store.GetMessages("session")) != 2 {
+		t.Error("Expected history to be kept when the message can never fit")
+	}
+}
+
+func TestSessionStore_RejectPolicyKeepsLimits(t *testing.T) {
+	store := NewSessionStore(2*time.Hour, 1000, 2, 100*1024)
+	store.SetCompactionPolicy(CompactionReject)
+	store.RegisterSession("session", "")
+
+	store.AppendMessage("session", User, "Hello")
+	store.AppendMessage("session", Assistant, "Hi")
+	if err := store.AppendMessage("session", User, "Again"); err == nil {
+		t.Error("Expected message limit error with the reject policy")
+	}
+}
+
+func TestSummaryCutoff(t *testing.T) {
+	messages := []Message{{Role: User}, {Role: Assistant}, {Role: Tool}, {Role: Assistant}, {Role: User}, {Role: Assistant}}
+	if got := summaryCutoff(messages); got != 4 {
+		t.Errorf("Expected cutoff before the next user turn (4), got %d", got)
+	}
+	if got := summaryCutoff(messages[:2]); got != 0 {
+		t.Errorf("Expected no cutoff without a later user turn, got %d", got)
+	}
+}
+
+func TestSummarizeSession(t *testing.T) {
+	app := &application{
+		logger:          slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError})),
+		sessionStore:    NewSessionStore(2*time.Hour, 1000, 10, 100*1024),
+		spendingTracker: NewSpendingTracker(0),
+	}
+	app.sessionStore.SetCompactionPolicy(CompactionSummarize)
+	app.sessionStore.RegisterSession("session", "key-a")
+
+	for i := range 4 {
+		app.sessionStore.AppendMessage("session", User, fmt.Sprintf("question %d", i))
+		app.sessionStore.AppendMessage("session", Assistant, fmt.Sprintf("answer %d", i))
+	}
+
+	provider := &staticProvider{reply: "The user asked four questions."}
+	app.summarizeSession(context.Background(), provider, "session", "key-a", nil)
+
+	messages := app.sessionStore.GetMessages("session")
+	if len(messages) != 5 {
+		t.Fatalf("Expected summary plus the newest 4 messages, got %v", app.sessionStore.GetFormattedMessages("session"))
+	}
+	if messages[0].Role != System || messages[0].Text != summaryMessagePrefix+"The user asked four questions." {
+		t.Errorf("Expected a system summary first, got %+v", messages[0])
+	}
+	if messages[1].Text != "question 2" {
+		t.Errorf("Expected history to resume at a user turn, got %q", messages[1].Text)
+	}
+	session := storedSession(app.sessionStore, "session")
+	if total, _ := app.sessionStore.GetMemoryUsage(); session.sizeBytes != computeSessionSize(session) || total != session.sizeBytes {
+		t.Errorf("Expected sizes to stay accurate after summarizing, got %d (total %d)", session.sizeBytes, total)
+	}
+
+	tokens := app.sessionStore.GetSessionTokens("session")
+	if tokens == 0 || app.spendingTracker.TokensToday("key-a") != tokens {
+		t.Errorf("Expected the summary call's tokens to be charged to the session and key, got %d and %d",
+			tokens, app.spendingTracker.TokensToday("key-a"))
+	}
+
+	// Below the threshold nothing is summarized
+	app.summarizeSession(context.Background(), provider, "session", "key-a", nil)
+	if len(app.sessionStore.GetMessages("session")) != 5 {
+		t.Error("Expected no summary below the compaction threshold")
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+)
+
+// connectionConfig holds gRPC keepalive and connection limits
+// The defaults keep idle connections from long-lived CLI and mobile clients alive through NAT
+// gateways, which typically drop mappings after a few minutes of silence
+type connectionConfig struct {
+	keepaliveTime         time.Duration // Ping a client after this long without activity
+	keepaliveTimeout      time.Duration // Close the connection if a ping isn't answered within this
+	keepaliveMinTime      time.Duration // Shortest client ping interval allowed before the server sends GOAWAY
+	permitWithoutStream   bool          // Allow client pings while no RPC is in flight
+	maxConnectionAge      time.Duration // Close connections after this long so clients rebalance (0 = never)
+	maxConnectionAgeGrace time.Duration // Time in-flight RPCs get to finish after the max age
+	maxConnectionIdle     time.Duration // Close connections without RPCs for this long (0 = never)
+	maxConcurrentStreams  uint32        // Concurrent RPCs per connection (0 = gRPC default)
+	maxRecvMsgBytes       int           // Largest request accepted (0 = gRPC default)
+}
+
+// serverOptions returns the gRPC server options for the configuration
+func (c connectionConfig) serverOptions() []grpc.ServerOption {
+	opts := []grpc.ServerOption{
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			Time:                  c.keepaliveTime,
+			Timeout:               c.keepaliveTimeout,
+			MaxConnectionAge:      c.maxConnectionAge,
+			MaxConnectionAgeGrace: c.maxConnectionAgeGrace,
+			MaxConnectionIdle:     c.maxConnectionIdle,
+		}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             c.keepaliveMinTime,
+			PermitWithoutStream: c.permitWithoutStream,
+		}),
+	}
+	if c.maxRecvMsgBytes > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(c.maxRecvMsgBytes))
+	}
+	if c.maxConcurrentStreams > 0 {
+		opts = append(opts, grpc.MaxConcurrentStreams(c.maxConcurrentStreams))
+	}
+	return opts
+}
+
+// loadConnectionConfig loads keepalive and connection limits from environment variables
+// minRecvMsgBytes is the receive limit chat requests with attachments need
+func loadConnectionConfig(logger *slog.Logger, minRecvMsgBytes int) (connectionConfig, error) {
+	var c connectionConfig
+	durations := []struct {
+		name      string
+		value     *time.Duration
+		def       string
+		allowZero bool
+	}{
+		{"GRPC_KEEPALIVE_TIME", &c.keepaliveTime, "2m", false},
+		{"GRPC_KEEPALIVE_TIMEOUT", &c.keepaliveTimeout, "20s", false},
+		{"GRPC_KEEPALIVE_MIN_TIME", &c.keepaliveMinTime, "30s", true},
+		{"GRPC_MAX_CONNECTION_AGE", &c.maxConnectionAge, "0", true},
+		{"GRPC_MAX_CONNECTION_AGE_GRACE", &c.maxConnectionAgeGrace, "0", true},
+		{"GRPC_MAX_CONNECTION_IDLE", &c.maxConnectionIdle, "0", true},
+	}
+	for _, d := range durations {
+		str := os.Getenv(d.name)
+		if str == "" {
+			str = d.def
+		}
+		parsed, err := time.ParseDuration(str)
+		if err != nil || parsed < 0 || (parsed == 0 && !d.allowZero) {
+			logger.Error("invalid "+d.name+" value", "value", str, "error", err)
+			return c, fmt.Errorf("invalid %s: must be a positive duration", d.name)
+		}
+		*d.value = parsed
+	}
+
+	permitStr := os.Getenv("GRPC_KEEPALIVE_PERMIT_WITHOUT_STREAM")
+	if permitStr == "" {
+		permitStr = "true" // Default to allowing idle clients to keep their connection open
+	}
+	permit, err := strconv.ParseBool(permitStr)
+	if err != nil {
+		logger.Error("invalid GRPC_KEEPALIVE_PERMIT_WITHOUT_STREAM value", "value", permitStr, "error", err)
+		return c, fmt.Errorf("invalid GRPC_KEEPALIVE_PERMIT_WITHOUT_STREAM: %w", err)
+	}
+	c.permitWithoutStream = permit
+
+	streamsStr := os.Getenv("GRPC_MAX_CONCURRENT_STREAMS")
+	if streamsStr == "" {
+		streamsStr = "0" // Default to gRPC's limit
+	}
+	streams, err := strconv.ParseUint(streamsStr, 10, 32)
+	if err != nil {
+		logger.Error("invalid GRPC_MAX_CONCURRENT_STREAMS value", "value", streamsStr, "error", err)
+		return c, fmt.Errorf("invalid GRPC_MAX_CONCURRENT_STREAMS: %w", err)
+	}
+	c.maxConcurrentStreams = uint32(streams)
+
+	c.maxRecvMsgBytes = minRecvMsgBytes
+	if recvStr := os.Getenv("GRPC_MAX_RECV_MSG_SIZE_KB"); recvStr != "" {
+		recvKB, err := strconv.Atoi(recvStr)
+		if err != nil || recvKB*1024 < minRecvMsgBytes {
+			logger.Error("invalid GRPC_MAX_RECV_MSG_SIZE_KB value", "value", recvStr, "min_kb", minRecvMsgBytes/1024, "error", err)
+			return c, fmt.Errorf("invalid GRPC_MAX_RECV_MSG_SIZE_KB: must be at least %d to fit MAX_ATTACHMENT_SIZE_KB attachments", minRecvMsgBytes/1024)
+		}
+		c.maxRecvMsgBytes = recvKB * 1024
+	}
+
+	return c, nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestLoadConnectionConfig(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	c, err := loadConnectionConfig(logger, 4*1024*1024)
+	if err != nil {
+		t.Fatalf("unexpected error with defaults: %v", err)
+	}
+	if c.keepaliveTime != 2*time.Minute || c.keepaliveTimeout != 20*time.Second || c.keepaliveMinTime != 30*time.Second {
+		t.Errorf("unexpected keepalive defaults: %+v", c)
+	}
+	if !c.permitWithoutStream || c.maxConnectionAge != 0 || c.maxConcurrentStreams != 0 || c.maxRecvMsgBytes != 4*1024*1024 {
+		t.Errorf("unexpected connection defaults: %+v", c)
+	}
+	if got := len(c.serverOptions()); got != 3 {
+		t.Errorf("expected keepalive, enforcement and receive size options, got %d", got)
+	}
+
+	t.Setenv("GRPC_KEEPALIVE_TIME", "45s")
+	t.Setenv("GRPC_MAX_CONNECTION_AGE", "30m")
+	t.Setenv("GRPC_MAX_CONNECTION_AGE_GRACE", "1m")
+	t.Setenv("GRPC_KEEPALIVE_PERMIT_WITHOUT_STREAM", "false")
+	t.Setenv("GRPC_MAX_CONCURRENT_STREAMS", "64")
+	t.Setenv("GRPC_MAX_RECV_MSG_SIZE_KB", "8192")
+	c, err = loadConnectionConfig(logger, 4*1024*1024)
+	if err != nil {
+		t.Fatalf("unexpected error with overrides: %v", err)
+	}
+	if c.keepaliveTime != 45*time.Second || c.maxConnectionAge != 30*time.Minute || c.maxConnectionAgeGrace != time.Minute {
+		t.Errorf("expected duration overrides, got %+v", c)
+	}
+	if c.permitWithoutStream || c.maxConcurrentStreams != 64 || c.maxRecvMsgBytes != 8*1024*1024 {
+		t.Errorf("expected limit overrides, got %+v", c)
+	}
+	if got := len(c.serverOptions()); got != 4 {
+		t.Errorf("expected a concurrent streams option, got %d options", got)
+	}
+}
+
+func TestLoadConnectionConfig_Invalid(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	for name, value := range map[string]string{
+		"GRPC_KEEPALIVE_TIME":                  "0",
+		"GRPC_KEEPALIVE_TIMEOUT":               "soon",
+		"GRPC_MAX_CONNECTION_IDLE":             "-1m",
+		"GRPC_KEEPALIVE_PERMIT_WITHOUT_STREAM": "maybe",
+		"GRPC_MAX_CONCURRENT_STREAMS":          "-1",
+		"GRPC_MAX_RECV_MSG_SIZE_KB":            "1024", // Below what attachments need
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(name, value)
+			if _, err := loadConnectionConfig(logger, 4*1024*1024); err == nil {
+				t.Errorf("expected error for %s=%s", name, value)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// encryptionKeySize is the AES-256 key size in bytes
+const encryptionKeySize = 32
+
+// messageCipher encrypts message text at rest using AES-GCM
+// The session ID is bound as additional data so ciphertext can't be moved between sessions
+type messageCipher struct {
+	aead cipher.AEAD
+}
+
+// newMessageCipher creates an AES-256-GCM cipher from a 32-byte key
+func newMessageCipher(key []byte) (*messageCipher, error) {
+	if len(key) != encryptionKeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes, got %d", encryptionKeySize, len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AES cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+
+	return &messageCipher{aead: aead}, nil
+}
+
+// seal encrypts text and returns nonce || ciphertext
+func (c *messageCipher) seal(sessionID string, text string) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return c.aead.Seal(nonce, nonce, []byte(text), []byte(sessionID)), nil
+}
+
+// open decrypts nonce || ciphertext produced by seal
+func (c *messageCipher) open(sessionID string, sealed []byte) (string, error) {
+	nonceSize := c.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return "", fmt.Errorf("ciphertext too short")
+	}
+	plaintext, err := c.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], []byte(sessionID))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt message: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// loadEncryptionKey reads the at-rest encryption key from SESSION_ENCRYPTION_KEY (base64)
+// or SESSION_ENCRYPTION_KEY_FILE (e.g. a file written by a KMS agent)
+// Returns nil when encryption at rest is not configured
+func loadEncryptionKey() ([]byte, error) {
+	encoded := os.Getenv("SESSION_ENCRYPTION_KEY")
+	if keyFile := os.Getenv("SESSION_ENCRYPTION_KEY_FILE"); keyFile != "" {
+		if encoded != "" {
+			return nil, fmt.Errorf("set only one of SESSION_ENCRYPTION_KEY and SESSION_ENCRYPTION_KEY_FILE")
+		}
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read SESSION_ENCRYPTION_KEY_FILE: %w", err)
+		}
+		encoded = strings.TrimSpace(string(data))
+	}
+
+	if encoded == "" {
+		return nil, nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("encryption key must be base64 encoded: %w", err)
+	}
+	if len(key) != encryptionKeySize {
+		return nil, fmt.Errorf("encryption key must decode to %d bytes, got %d", encryptionKeySize, len(key))
+	}
+	return key, nil
+}
+
+// clientKeyMetadataKey carries the client-supplied key the server seals a session's messages with
+// The server encrypts and decrypts with it, so this is not end-to-end encryption: it keeps stored
+// history unreadable without the key, while each request is still handled in plaintext
+const clientKeyMetadataKey = "x-session-key"
+
+// clientCipherFromContext builds a cipher from the client-supplied key in request metadata
+// Returns a nil cipher when the client didn't supply a key
+// The key is used only for the duration of the request and never stored
+func clientCipherFromContext(ctx context.Context) (*messageCipher, string, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, "", nil
+	}
+	values := md.Get(clientKeyMetadataKey)
+	if len(values) == 0 || values[0] == "" {
+		return nil, "", nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(values[0])
+	if err != nil {
+		return nil, "", fmt.Errorf("client encryption key must be base64 encoded")
+	}
+	c, err := newMessageCipher(key)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return c, keyFingerprint(key), nil
+}
+
+// keyFingerprint returns a short, non-reversible identifier for a key
+func keyFingerprint(key []byte) string {
+	hash := sha256.Sum256(key)
+	return hex.EncodeToString(hash[:8])
+}
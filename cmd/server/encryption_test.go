@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func TestMessageCipher_RoundTrip(t *testing.T) {
+	c, err := newMessageCipher(bytes.Repeat([]byte{1}, encryptionKeySize))
+	if err != nil {
+		t.Fatalf("Failed to create cipher: %v", err)
+	}
+
+	sealed, err := c.seal("session-1", "secret message")
+	if err != nil {
+		t.Fatalf("Failed to seal: %v", err)
+	}
+	if bytes.Contains(sealed, []byte("secret message")) {
+		t.Error("Sealed data should not contain plaintext")
+	}
+
+	text, err := c.open("session-1", sealed)
+	if err != nil {
+		t.Fatalf("Failed to open: %v", err)
+	}
+	if text != "secret message" {
+		t.Errorf("Expected 'secret message', got %q", text)
+	}
+
+	// Ciphertext is bound to its session ID
+	if _, err := c.open("session-2", sealed); err == nil {
+		t.Error("Expected error opening ciphertext with a different session ID")
+	}
+
+	if _, err := newMessageCipher([]byte("short")); err == nil {
+		t.Error("Expected error for invalid key size")
+	}
+}
+
+func TestLoadEncryptionKey(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, encryptionKeySize)
+	encoded := base64.StdEncoding.EncodeToString(key)
+
+	// Not configured
+	t.Setenv("SESSION_ENCRYPTION_KEY", "")
+	t.Setenv("SESSION_ENCRYPTION_KEY_FILE", "")
+	if loaded, err := loadEncryptionKey(); err != nil || loaded != nil {
+		t.Errorf("Expected no key and no error, got %v, %v", loaded, err)
+	}
+
+	// From environment
+	t.Setenv("SESSION_ENCRYPTION_KEY", encoded)
+	if loaded, err := loadEncryptionKey(); err != nil || !bytes.Equal(loaded, key) {
+		t.Errorf("Expected key from environment, got %v, %v", loaded, err)
+	}
+
+	// From file
+	keyFile := filepath.Join(t.TempDir(), "session.key")
+	if err := os.WriteFile(keyFile, []byte(encoded+"\n"), 0600); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+	t.Setenv("SESSION_ENCRYPTION_KEY", "")
+	t.Setenv("SESSION_ENCRYPTION_KEY_FILE", keyFile)
+	if loaded, err := loadEncryptionKey(); err != nil || !bytes.Equal(loaded, key) {
+		t.Errorf("Expected key from file, got %v, %v", loaded, err)
+	}
+
+	// Invalid key length
+	t.Setenv("SESSION_ENCRYPTION_KEY_FILE", "")
+	t.Setenv("SESSION_ENCRYPTION_KEY", base64.StdEncoding.EncodeToString([]byte("too short")))
+	if _, err := loadEncryptionKey(); err == nil {
+		t.Error("Expected error for short key")
+	}
+}
+
+func TestClientCipherFromContext(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{3}, encryptionKeySize))
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(clientKeyMetadataKey, key))
+	if c, fingerprint, err := clientCipherFromContext(ctx); err != nil || c == nil || fingerprint == "" {
+		t.Errorf("Expected a cipher from %s, got %v, %q, %v", clientKeyMetadataKey, c, fingerprint, err)
+	}
+	if c, _, err := clientCipherFromContext(metadata.NewIncomingContext(context.Background(), metadata.MD{})); err != nil || c != nil {
+		t.Errorf("Expected no cipher without a key, got %v, %v", c, err)
+	}
+}
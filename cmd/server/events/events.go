@@ -0,0 +1,246 @@
+// Package events posts server lifecycle events to webhooks as HMAC-signed JSON, so billing and
+// alerting systems can follow sessions, limits and provider failovers without scraping metrics
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event types
+const (
+	SessionCreated   = "session.created"   // A session was started, forked or imported
+	SessionEvicted   = "session.evicted"   // The server removed a session (idle timeout or memory pressure)
+	DailyLimitHit    = "daily_limit.hit"   // An API key used up a daily limit
+	ProviderFailover = "provider.failover" // Requests for a model are served by a fallback provider
+)
+
+// Types lists every event type in a stable order
+var Types = []string{SessionCreated, SessionEvicted, DailyLimitHit, ProviderFailover}
+
+const (
+	deliveryTimeout = 5 * time.Second // Bounds each delivery attempt
+	maxAttempts     = 3               // Attempts per webhook before an event is given up on
+	retryBackoff    = time.Second     // Wait before the second attempt, doubling after each failure
+	queueSize       = 1024            // Events waiting for delivery before new ones are dropped
+	repeatInterval  = 5 * time.Minute // Minimum time between events with the same type and subject
+)
+
+// Signature headers sent with every delivery
+const (
+	HeaderEvent     = "X-Microchat-Event"
+	HeaderDelivery  = "X-Microchat-Delivery"
+	HeaderTimestamp = "X-Microchat-Timestamp"
+	HeaderSignature = "X-Microchat-Signature"
+)
+
+// Event is a single lifecycle event
+type Event struct {
+	Type    string            // One of the event type constants
+	Subject string            // Repeats with the same type and subject within 5 minutes are dropped; empty never repeats
+	Data    map[string]string // Event details for the receiver
+}
+
+// payload is the JSON body posted to webhooks
+type payload struct {
+	ID   string            `json:"id"` // Unique per event, for receivers to drop duplicate deliveries
+	Type string            `json:"type"`
+	Time time.Time         `json:"time"`
+	Data map[string]string `json:"data,omitempty"`
+}
+
+// ParseTypes parses a comma-separated list of event types; an empty list selects every type
+func ParseTypes(list string) (map[string]bool, error) {
+	types := make(map[string]bool)
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		known := false
+		for _, t := range Types {
+			known = known || t == name
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown event type %q (valid: %s)", name, strings.Join(Types, ", "))
+		}
+		types[name] = true
+	}
+	if len(types) == 0 {
+		for _, t := range Types {
+			types[t] = true
+		}
+	}
+	return types, nil
+}
+
+// Sign returns the signature of a delivery: the hex HMAC-SHA256 of "<timestamp>.<body>" keyed
+// with the shared secret, prefixed with "sha256="
+// Receivers recompute it and should reject timestamps more than a few minutes old to stop replays
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Emitter delivers events to webhooks from a background queue, so emitting never blocks a request
+// A nil Emitter discards events
+type Emitter struct {
+	urls   []string
+	secret []byte
+	types  map[string]bool
+	client *http.Client
+	logger *slog.Logger
+	queue  chan payload
+	done   chan struct{} // Closed once the queue is drained
+
+	mu     sync.Mutex
+	last   map[string]time.Time // Type and subject -> last time emitted
+	closed bool
+}
+
+// NewEmitter creates an emitter for the given webhook URLs and starts delivering
+// Only event types in types are sent
+func NewEmitter(urls []string, secret string, types map[string]bool, logger *slog.Logger) *Emitter {
+	e := &Emitter{
+		urls:   urls,
+		secret: []byte(secret),
+		types:  types,
+		client: &http.Client{Timeout: deliveryTimeout},
+		logger: logger,
+		queue:  make(chan payload, queueSize),
+		done:   make(chan struct{}),
+		last:   make(map[string]time.Time),
+	}
+	go e.run()
+	return e
+}
+
+// Emit queues an event for delivery to every webhook
+// Returns false if the event type isn't enabled, it repeats a recent event, or the queue is full
+func (e *Emitter) Emit(event Event) bool {
+	if e == nil || !e.types[event.Type] {
+		return false
+	}
+
+	now := time.Now()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return false
+	}
+	if event.Subject != "" {
+		key := event.Type + "/" + event.Subject
+		if last, sent := e.last[key]; sent && now.Sub(last) < repeatInterval {
+			return false
+		}
+		e.last[key] = now
+	}
+
+	select {
+	case e.queue <- payload{ID: uuid.New().String(), Type: event.Type, Time: now.UTC(), Data: event.Data}:
+		return true
+	default:
+		e.logger.Warn("event webhook queue full, dropping event", "type", event.Type)
+		return false
+	}
+}
+
+// Close stops accepting events and waits until queued events are delivered or ctx is done
+func (e *Emitter) Close(ctx context.Context) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	if !e.closed {
+		e.closed = true
+		close(e.queue)
+	}
+	e.mu.Unlock()
+
+	select {
+	case <-e.done:
+	case <-ctx.Done():
+		e.logger.Warn("gave up delivering queued events at shutdown", "remaining", len(e.queue))
+	}
+}
+
+// run delivers queued events in order until the queue is closed
+func (e *Emitter) run() {
+	defer close(e.done)
+	for p := range e.queue {
+		body, err := json.Marshal(p)
+		if err != nil {
+			e.logger.Error("failed to encode event", "type", p.Type, "error", err)
+			continue
+		}
+		for _, webhookURL := range e.urls {
+			if err := e.deliver(webhookURL, p, body); err != nil {
+				e.logger.Warn("failed to deliver event", "type", p.Type, "id", p.ID, "error", err)
+			}
+		}
+	}
+}
+
+// deliver posts an event to one webhook, retrying network errors and 5xx responses with backoff
+func (e *Emitter) deliver(webhookURL string, p payload, body []byte) error {
+	backoff := retryBackoff
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		var retry bool
+		if retry, err = e.post(webhookURL, p, body); err == nil || !retry {
+			return err
+		}
+		if attempt < maxAttempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+	return fmt.Errorf("%w (after %d attempts)", err, maxAttempts)
+}
+
+// post makes one delivery attempt, reporting whether a failure is worth retrying
+// The signature is recomputed per attempt so its timestamp stays fresh
+func (e *Emitter) post(webhookURL string, p payload, body []byte) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), deliveryTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, p.Type)
+	req.Header.Set(HeaderDelivery, p.ID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(e.secret, timestamp, body))
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("webhook request failed: %w", err)
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+		return false, nil
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+		return true, fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,134 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+func TestParseTypes(t *testing.T) {
+	all, err := ParseTypes("")
+	if err != nil || len(all) != len(Types) {
+		t.Errorf("ParseTypes(\"\") = %v, %v, want every type", all, err)
+	}
+	some, err := ParseTypes(" session.created, daily_limit.hit ")
+	if err != nil || len(some) != 2 || !some[SessionCreated] || !some[DailyLimitHit] {
+		t.Errorf("ParseTypes() = %v, %v", some, err)
+	}
+	if _, err := ParseTypes("session.created,session.deleted"); err == nil {
+		t.Error("Expected an unknown event type to be rejected")
+	}
+}
+
+// receiver records deliveries, failing the first failures requests with a 503
+type receiver struct {
+	mu         sync.Mutex
+	failures   int
+	deliveries []*http.Request
+	bodies     [][]byte
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.failures > 0 {
+		r.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	r.deliveries = append(r.deliveries, req)
+	r.bodies = append(r.bodies, body)
+}
+
+func TestEmitter_DeliversSignedEvents(t *testing.T) {
+	recv := &receiver{failures: 1}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+
+	types, _ := ParseTypes("session.created,provider.failover")
+	emitter := NewEmitter([]string{server.URL}, "shared-secret", types, testLogger)
+
+	if !emitter.Emit(Event{Type: SessionCreated, Data: map[string]string{"session_id": "s1"}}) {
+		t.Error("Expected session.created to be queued")
+	}
+	if emitter.Emit(Event{Type: SessionEvicted}) {
+		t.Error("Expected a disabled event type to be dropped")
+	}
+	failover := Event{Type: ProviderFailover, Subject: "gemini->openai", Data: map[string]string{"provider": "openai"}}
+	if !emitter.Emit(failover) || emitter.Emit(failover) {
+		t.Error("Expected a repeated failover within the interval to be dropped")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	emitter.Close(ctx)
+	if emitter.Emit(Event{Type: SessionCreated}) {
+		t.Error("Expected events after Close to be dropped")
+	}
+
+	recv.mu.Lock()
+	defer recv.mu.Unlock()
+	if len(recv.deliveries) != 2 {
+		t.Fatalf("Expected 2 deliveries after a retried failure, got %d", len(recv.deliveries))
+	}
+
+	req, body := recv.deliveries[0], recv.bodies[0]
+	if req.Header.Get(HeaderEvent) != SessionCreated || req.Header.Get(HeaderDelivery) == "" {
+		t.Errorf("Unexpected headers %v", req.Header)
+	}
+	if want := Sign([]byte("shared-secret"), req.Header.Get(HeaderTimestamp), body); req.Header.Get(HeaderSignature) != want {
+		t.Errorf("Signature %q doesn't verify, want %q", req.Header.Get(HeaderSignature), want)
+	}
+	if Sign([]byte("other-secret"), req.Header.Get(HeaderTimestamp), body) == req.Header.Get(HeaderSignature) {
+		t.Error("Expected a different secret to produce a different signature")
+	}
+
+	var p payload
+	if err := json.Unmarshal(body, &p); err != nil {
+		t.Fatalf("Failed to decode payload: %v", err)
+	}
+	if p.Type != SessionCreated || p.Data["session_id"] != "s1" || p.ID != req.Header.Get(HeaderDelivery) || p.Time.IsZero() {
+		t.Errorf("Unexpected payload %+v", p)
+	}
+}
+
+func TestEmitter_NilDiscards(t *testing.T) {
+	var emitter *Emitter
+	if emitter.Emit(Event{Type: SessionCreated}) {
+		t.Error("Expected a nil emitter to discard events")
+	}
+	emitter.Close(context.Background())
+}
+
+func TestEmitter_DoesNotRetryClientErrors(t *testing.T) {
+	var mu sync.Mutex
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts++
+		mu.Unlock()
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	types, _ := ParseTypes("")
+	emitter := NewEmitter([]string{server.URL}, "secret", types, testLogger)
+	emitter.Emit(Event{Type: DailyLimitHit})
+	emitter.Close(context.Background())
+
+	mu.Lock()
+	defer mu.Unlock()
+	if attempts != 1 {
+		t.Errorf("Expected a 4xx response not to be retried, got %d attempts", attempts)
+	}
+}
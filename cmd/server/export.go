@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// sessionExport is the portable transcript format returned by ExportSession
+type sessionExport struct {
+	SessionID    string            `json:"session_id"`
+	Model        string            `json:"model,omitempty"`
+	CreatedAt    time.Time         `json:"created_at"`
+	LastActive   time.Time         `json:"last_active"`
+	ExportedAt   time.Time         `json:"exported_at"`
+	MessageCount int               `json:"message_count"`
+	TotalBytes   int               `json:"total_bytes"`
+	Messages     []exportedMessage `json:"messages"`
+}
+
+// exportedMessage is a single message in an exported transcript
+type exportedMessage struct {
+	Role      string    `json:"role"`
+	Text      string    `json:"text"`
+	Timestamp time.Time `json:"timestamp"`
+	Bytes     int       `json:"bytes"`
+}
+
+// newSessionExport builds an export from a session snapshot
+func newSessionExport(sessionID string, session Session, exportedAt time.Time) sessionExport {
+	export := sessionExport{
+		SessionID:    sessionID,
+		Model:        session.Model,
+		CreatedAt:    session.CreatedAt.UTC(),
+		LastActive:   session.LastActive.UTC(),
+		ExportedAt:   exportedAt.UTC(),
+		MessageCount: len(session.Messages),
+		Messages:     make([]exportedMessage, len(session.Messages)),
+	}
+
+	for i, msg := range session.Messages {
+		export.Messages[i] = exportedMessage{
+			Role:      msg.Role.String(),
+			Text:      msg.Text,
+			Timestamp: msg.Timestamp.UTC(),
+			Bytes:     len(msg.Text),
+		}
+		export.TotalBytes += len(msg.Text)
+	}
+
+	return export
+}
+
+// parseRole converts a role name from an exported transcript
+func parseRole(name string) (Role, error) {
+	for _, role := range []Role{User, Assistant, System, Tool} {
+		if role.String() == name {
+			return role, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown role %q", name)
+}
+
+// parseSessionExport reads a JSON transcript from ExportSession into the plaintext messages of a session
+// Terminal control sequences are stripped, since the transcript may have been edited since export
+func parseSessionExport(content string, now time.Time) (Session, error) {
+	var export sessionExport
+	if err := json.Unmarshal([]byte(content), &export); err != nil {
+		return Session{}, fmt.Errorf("invalid session export: %w", err)
+	}
+	if len(export.Messages) == 0 {
+		return Session{}, fmt.Errorf("session export has no messages")
+	}
+
+	session := Session{Model: export.Model, Messages: make([]Message, len(export.Messages))}
+	for i, msg := range export.Messages {
+		role, err := parseRole(msg.Role)
+		if err != nil {
+			return Session{}, fmt.Errorf("message %d: %w", i+1, err)
+		}
+		if !utf8.ValidString(msg.Text) {
+			return Session{}, fmt.Errorf("message %d: text is not valid UTF-8", i+1)
+		}
+		timestamp := msg.Timestamp.UTC()
+		if timestamp.IsZero() {
+			timestamp = now.UTC()
+		}
+		session.Messages[i] = Message{Role: role, Text: sanitizeForTerminal(msg.Text), Timestamp: timestamp}
+	}
+	return session, nil
+}
+
+// renderJSON renders the export as indented JSON
+func (e sessionExport) renderJSON() (string, error) {
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal session export: %w", err)
+	}
+	return string(data), nil
+}
+
+// renderMarkdown renders the export as a Markdown transcript
+func (e sessionExport) renderMarkdown() string {
+	const timeFormat = "2006-01-02 15:04:05 UTC"
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# microchat.ai session %s\n\n", e.SessionID)
+	if e.Model != "" {
+		fmt.Fprintf(&b, "- **Model:** %s\n", e.Model)
+	}
+	fmt.Fprintf(&b, "- **Created:** %s\n", e.CreatedAt.Format(timeFormat))
+	fmt.Fprintf(&b, "- **Last active:** %s\n", e.LastActive.Format(timeFormat))
+	fmt.Fprintf(&b, "- **Exported:** %s\n", e.ExportedAt.Format(timeFormat))
+	fmt.Fprintf(&b, "- **Messages:** %d\n", e.MessageCount)
+	fmt.Fprintf(&b, "- **Total bytes:** %d\n", e.TotalBytes)
+
+	for _, msg := range e.Messages {
+		fmt.Fprintf(&b, "\n## %s (%s, %d bytes)\n\n%s\n", msg.Role, msg.Timestamp.Format(timeFormat), msg.Bytes, msg.Text)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// ForkSession copies the first count messages of a session (all when count is 0) into a new
+// session owned by owner, keeping its idle timeout, title, model and client encryption
+// Messages are re-encrypted for the fork, since ciphertext is bound to its session ID
+// Returns the number of messages copied
+func (s *SessionStore) ForkSession(sourceID, forkID, owner string, count int, clientCipher *messageCipher) (int, error) {
+	shard := s.shardFor(sourceID)
+	shard.mu.RLock()
+	if _, exists := shard.validSessions[sourceID]; !exists {
+		shard.mu.RUnlock()
+		return 0, fmt.Errorf("session not found")
+	}
+	_, clientEncrypted := shard.clientKeys[sourceID]
+	if clientEncrypted && clientCipher == nil {
+		shard.mu.RUnlock()
+		return 0, fmt.Errorf("session requires the client encryption key")
+	}
+	fingerprint := shard.clientKeys[sourceID]
+	idleTimeout := shard.idleTimeouts[sourceID]
+	c := s.cipherFor(shard, sourceID, clientCipher)
+
+	var source Session
+	if session, exists := shard.sessions[sourceID]; exists {
+		if count == 0 {
+			count = len(session.Messages)
+		}
+		if count > len(session.Messages) {
+			shard.mu.RUnlock()
+			return 0, fmt.Errorf("message count out of range: session has %d messages", len(session.Messages))
+		}
+		source = *session
+		source.Messages = openMessages(sourceID, session.Messages[:count], c)
+		source.Title = s.openTitle(sourceID, session)
+	}
+	shard.mu.RUnlock()
+
+	fork, err := s.newForkedSession(forkID, source, c)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := s.addCopiedSession(forkID, owner, idleTimeout, fingerprint, fork); err != nil {
+		return 0, err
+	}
+	if fork == nil {
+		return 0, nil
+	}
+	return len(fork.Messages), nil
+}
+
+// ImportSession stores the plaintext messages of an uploaded transcript as a new session owned by owner
+// Messages are encrypted like new ones, with the client's key when fingerprint is set, and the session
+// must fit the per-session limits and the memory budget
+// Returns the number of messages stored
+func (s *SessionStore) ImportSession(sessionID, owner string, idleTimeout time.Duration, source Session, fingerprint string, clientCipher *messageCipher) (int, error) {
+	c := s.cipher
+	if fingerprint != "" {
+		c = clientCipher
+	}
+	session, err := s.newForkedSession(sessionID, source, c)
+	if err != nil {
+		return 0, err
+	}
+	if session == nil {
+		return 0, fmt.Errorf("session export has no messages")
+	}
+	if err := s.addCopiedSession(sessionID, owner, idleTimeout, fingerprint, session); err != nil {
+		return 0, err
+	}
+	return len(session.Messages), nil
+}
+
+// addCopiedSession registers a session built from another one and stores its messages
+// A copy adds a whole session at once, so it must fit the budget rather than evict other sessions
+func (s *SessionStore) addCopiedSession(sessionID, owner string, idleTimeout time.Duration, fingerprint string, session *Session) error {
+	s.mu.Lock()
+	overBudget := session != nil && s.maxTotalBytes > 0 && s.totalBytes+session.sizeBytes > s.maxTotalBytes
+	s.mu.Unlock()
+	if overBudget {
+		incrementMessagesRejected("session_size")
+		return fmt.Errorf("not enough session memory: session uses %d bytes", session.sizeBytes)
+	}
+
+	s.RegisterSessionWithTimeout(sessionID, owner, idleTimeout)
+	if fingerprint != "" {
+		s.SetClientKeyFingerprint(sessionID, fingerprint)
+	}
+	if session == nil {
+		return nil
+	}
+
+	shard := s.shardFor(sessionID)
+	shard.mu.Lock()
+	s.restoreSession(shard, sessionID, session)
+	for i := range session.Messages {
+		s.persistRecord(shard, walRecord{Op: walAppend, SessionID: sessionID, Message: &session.Messages[i]})
+	}
+	if session.Model != "" {
+		s.persistRecord(shard, walRecord{Op: walModel, SessionID: sessionID, Model: session.Model})
+	}
+	if session.Title != "" || session.SealedTitle != nil {
+		s.persistRecord(shard, walRecord{Op: walTitle, SessionID: sessionID, Title: session.Title, SealedTitle: session.SealedTitle})
+	}
+	shard.mu.Unlock()
+
+	s.evictExcessSessions()
+	return nil
+}
+
+// newForkedSession builds the stored form of a fork from decrypted source state, checking it
+// fits the per-session limits
+// Returns nil when the source has no messages yet
+func (s *SessionStore) newForkedSession(forkID string, source Session, c *messageCipher) (*Session, error) {
+	if len(source.Messages) == 0 {
+		return nil, nil
+	}
+	if len(source.Messages) > s.maxMessagesPerSession {
+		incrementMessagesRejected("message_count")
+		return nil, fmt.Errorf("session message limit exceeded: maximum %d messages per session", s.maxMessagesPerSession)
+	}
+
+	now := time.Now().UTC()
+	fork := &Session{
+		Messages:   make([]Message, len(source.Messages)),
+		CreatedAt:  now,
+		LastActive: now,
+		Model:      source.Model,
+		Title:      source.Title,
+	}
+	for i, msg := range source.Messages {
+		if c != nil {
+			sealed, err := c.seal(forkID, msg.Text)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encrypt message: %w", err)
+			}
+			msg.Text = ""
+			msg.Sealed = sealed
+		}
+		fork.Messages[i] = msg
+	}
+
+	// Titles are only sealed with the server's key (client-encrypted sessions have none)
+	if s.cipher != nil && fork.Title != "" {
+		sealed, err := s.cipher.seal(forkID, fork.Title)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt title: %w", err)
+		}
+		fork.Title = ""
+		fork.SealedTitle = sealed
+	}
+
+	fork.sizeBytes = computeSessionSize(fork)
+	if fork.sizeBytes > s.maxSessionSizeBytes {
+		incrementMessagesRejected("session_size")
+		return nil, fmt.Errorf("session size limit exceeded: maximum %d bytes per session", s.maxSessionSizeBytes)
+	}
+	return fork, nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionStore_ForkSession(t *testing.T) {
+	store := NewSessionStore(2*time.Hour, 1000, 100, 100*1024)
+	store.EnableEncryption(make([]byte, encryptionKeySize))
+	store.RegisterSessionWithTimeout("source", "key", time.Hour)
+	for _, text := range []string{"q1", "a1", "q2", "a2"} {
+		store.AppendMessage("source", User, text)
+	}
+	store.SetSessionModel("source", "gemini")
+	store.SetSessionTitle("source", "Questions")
+
+	copied, err := store.ForkSession("source", "fork", "key", 2, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if copied != 2 {
+		t.Errorf("Expected 2 messages copied, got %d", copied)
+	}
+
+	fork, exists := store.GetSession("fork")
+	if !exists {
+		t.Fatal("Expected fork to be stored")
+	}
+	if len(fork.Messages) != 2 || fork.Messages[0].Text != "q1" || fork.Messages[1].Text != "a1" {
+		t.Errorf("Expected the fork to decrypt with its own session ID, got %v", store.GetFormattedMessages("fork"))
+	}
+	if fork.Model != "gemini" || fork.Title != "Questions" {
+		t.Errorf("Expected model and title to be copied, got %q and %q", fork.Model, fork.Title)
+	}
+	if !store.IsSessionOwner("fork", "key") || store.GetIdleTimeout("fork") != time.Hour {
+		t.Error("Expected owner and idle timeout to be copied")
+	}
+
+	// The fork is independent of the original
+	store.AppendMessage("fork", User, "branch")
+	if count := store.GetMessageCount("source"); count != 4 {
+		t.Errorf("Expected the original to be unchanged, got %d messages", count)
+	}
+
+	stored := storedSession(store, "fork")
+	if stored.sizeBytes != computeSessionSize(stored) {
+		t.Errorf("Expected cached size %d to match computed size %d", stored.sizeBytes, computeSessionSize(stored))
+	}
+	if count := store.GetSessionCount(); count != 2 {
+		t.Errorf("Expected 2 sessions, got %d", count)
+	}
+}
+
+func TestSessionStore_ForkSessionLimits(t *testing.T) {
+	store := NewSessionStore(2*time.Hour, 1000, 100, 100*1024)
+	store.RegisterSession("source", "key")
+	store.AppendMessage("source", User, "hello")
+
+	if _, err := store.ForkSession("missing", "fork", "key", 0, nil); err == nil {
+		t.Error("Expected error forking an unknown session")
+	}
+	if _, err := store.ForkSession("source", "fork", "key", 2, nil); err == nil {
+		t.Error("Expected error copying more messages than the session has")
+	}
+
+	// A fork that doesn't fit the memory budget is rejected instead of evicting other sessions
+	total, _ := store.GetMemoryUsage()
+	store.SetMemoryBudget(total + 1)
+	if _, err := store.ForkSession("source", "fork", "key", 0, nil); err == nil {
+		t.Error("Expected error when the fork exceeds the memory budget")
+	}
+	if store.IsValidSession("fork") || store.GetSessionCount() != 1 {
+		t.Error("Expected a rejected fork not to be registered")
+	}
+
+	// Client-encrypted sessions need the client's key
+	store.SetMemoryBudget(0)
+	store.RegisterSession("private", "key")
+	store.SetClientKeyFingerprint("private", "fingerprint")
+	if _, err := store.ForkSession("private", "fork", "key", 0, nil); err == nil {
+		t.Error("Expected error forking a client-encrypted session without the key")
+	}
+}
+
+func TestSessionStore_ImportSession(t *testing.T) {
+	store := NewSessionStore(2*time.Hour, 1000, 100, 100*1024)
+	store.EnableEncryption(make([]byte, encryptionKeySize))
+
+	sent := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	source := Session{Model: "gemini", Messages: []Message{
+		{Role: User, Text: "q1", Timestamp: sent},
+		{Role: Assistant, Text: "a1", Timestamp: sent.Add(time.Second)},
+	}}
+	imported, err := store.ImportSession("imported", "key", time.Hour, source, "", nil)
+	if err != nil || imported != 2 {
+		t.Fatalf("ImportSession() = %d, %v", imported, err)
+	}
+
+	if stored := storedSession(store, "imported"); stored.Messages[0].Sealed == nil || stored.Messages[0].Text != "" {
+		t.Error("Expected imported messages to be encrypted at rest")
+	}
+	session, _ := store.GetSession("imported")
+	if session.Messages[1].Text != "a1" || !session.Messages[0].Timestamp.Equal(sent) || session.Model != "gemini" {
+		t.Errorf("Expected messages, timestamps and model to be kept, got %+v", session)
+	}
+	if !store.IsSessionOwner("imported", "key") || store.GetIdleTimeout("imported") != time.Hour {
+		t.Error("Expected owner and idle timeout to be set")
+	}
+
+	// A transcript over the memory budget is rejected without registering the session
+	total, _ := store.GetMemoryUsage()
+	store.SetMemoryBudget(total + 1)
+	if _, err := store.ImportSession("rejected", "key", 0, source, "", nil); err == nil {
+		t.Error("Expected error when the import exceeds the memory budget")
+	}
+	if store.IsValidSession("rejected") {
+		t.Error("Expected a rejected import not to be registered")
+	}
+}
@@ -2,8 +2,10 @@ package main
 
 import (
 	"context"
+	"errors"
 	"os"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -11,7 +13,11 @@ import (
 	"github.com/google/uuid"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"microchat.ai/cmd/server/archive"
+	"microchat.ai/cmd/server/llm"
+	"microchat.ai/cmd/server/moderation"
 	pb "microchat.ai/proto"
+	"microchat.ai/version"
 )
 
 // validateSessionID checks if session ID is valid UUID format
@@ -93,19 +99,173 @@ func (app *application) StartSession(ctx context.Context, req *pb.StartSessionRe
 		recordRequestDuration("StartSession", time.Since(start).Seconds())
 	}()
 
+	if err := app.checkMaintenance("StartSession"); err != nil {
+		return nil, err
+	}
+
+	// A client-supplied key in metadata makes this a client-encrypted session
+	_, clientKeyFingerprint, err := clientCipherFromContext(ctx)
+	if err != nil {
+		incrementGRPCError("StartSession", "InvalidArgument")
+		return nil, status.Errorf(codes.InvalidArgument, "invalid client encryption key: %v", err)
+	}
+
 	sessionID := uuid.New().String()
 
-	// Register the session ID as valid
-	app.sessionStore.RegisterSession(sessionID)
+	// Apply the client's requested idle timeout within server bounds (0 keeps the server default)
+	var idleTimeout time.Duration
+	if req.IdleTimeoutSeconds > 0 {
+		idleTimeout = clampIdleTimeout(time.Duration(req.IdleTimeoutSeconds)*time.Second,
+			app.config.sessionMinIdleTimeout, app.config.sessionMaxIdleTimeout)
+	}
+
+	// Register the session ID as valid and bind it to the caller's API key
+	app.sessionStore.RegisterSessionWithTimeout(sessionID, apiKeyFromContext(ctx), idleTimeout)
+	if clientKeyFingerprint != "" {
+		app.sessionStore.SetClientKeyFingerprint(sessionID, clientKeyFingerprint)
+	}
 
 	// Update metrics
 	incrementSessionsCreated()
 	updateActiveSessions(app.sessionStore.GetSessionCount())
+	app.emitSessionCreated(ctx, sessionID, "start")
 
-	app.logger.Info("created new session", "session_id", sessionID)
+	effectiveTimeout := app.sessionStore.GetIdleTimeout(sessionID)
+	app.logger.Info("created new session", "session_id", sessionID, "idle_timeout", effectiveTimeout,
+		"client_encrypted", clientKeyFingerprint != "")
 
 	return &pb.StartSessionResponse{
-		SessionId: sessionID,
+		SessionId:          sessionID,
+		IdleTimeoutSeconds: uint32(effectiveTimeout / time.Second),
+	}, nil
+}
+
+// clampIdleTimeout bounds a requested idle timeout to the configured range
+// A zero bound is treated as unset
+func clampIdleTimeout(requested, minTimeout, maxTimeout time.Duration) time.Duration {
+	if minTimeout > 0 && requested < minTimeout {
+		return minTimeout
+	}
+	if maxTimeout > 0 && requested > maxTimeout {
+		return maxTimeout
+	}
+	return requested
+}
+
+// ForkSession copies one of the caller's sessions into a new session, so a conversation can branch
+// without changing the original
+func (app *application) ForkSession(ctx context.Context, req *pb.ForkSessionRequest) (*pb.ForkSessionResponse, error) {
+	start := time.Now()
+	defer func() {
+		recordRequestDuration("ForkSession", time.Since(start).Seconds())
+	}()
+
+	if err := app.checkMaintenance("ForkSession"); err != nil {
+		return nil, err
+	}
+
+	if err := validateSessionID(req.SessionId); err != nil {
+		incrementGRPCError("ForkSession", "InvalidArgument")
+		app.logger.Warn("invalid session ID in fork session", "session_id", req.SessionId, "error", err)
+		return nil, err
+	}
+
+	if err := app.authorizeSession(ctx, req.SessionId); err != nil {
+		incrementGRPCError("ForkSession", "NotFound")
+		return nil, err
+	}
+
+	// Forks of client-encrypted sessions are re-encrypted with the same client key
+	clientCipher, err := app.sessionClientCipher(ctx, req.SessionId)
+	if err != nil {
+		incrementGRPCError("ForkSession", status.Code(err).String())
+		return nil, err
+	}
+
+	if available := app.sessionStore.GetMessageCount(req.SessionId); int(req.MessageCount) > available {
+		incrementGRPCError("ForkSession", "InvalidArgument")
+		return nil, status.Errorf(codes.InvalidArgument, "message count out of range: session has %d messages", available)
+	}
+
+	forkID := uuid.New().String()
+	copied, err := app.sessionStore.ForkSession(req.SessionId, forkID, apiKeyFromContext(ctx), int(req.MessageCount), clientCipher)
+	if err != nil {
+		incrementGRPCError("ForkSession", "ResourceExhausted")
+		app.logger.Warn("failed to fork session", "session_id", req.SessionId, "error", err)
+		return nil, status.Errorf(codes.ResourceExhausted, "failed to fork session: %v", err)
+	}
+
+	// Update metrics
+	incrementSessionsCreated()
+	updateActiveSessions(app.sessionStore.GetSessionCount())
+	app.emitSessionCreated(ctx, forkID, "fork")
+
+	effectiveTimeout := app.sessionStore.GetIdleTimeout(forkID)
+	app.logger.Info("forked session", "session_id", req.SessionId, "fork_session_id", forkID, "message_count", copied)
+
+	return &pb.ForkSessionResponse{
+		SessionId:          forkID,
+		MessageCount:       uint32(copied),
+		IdleTimeoutSeconds: uint32(effectiveTimeout / time.Second),
+	}, nil
+}
+
+// ImportSession uploads a transcript from ExportSession into a new session owned by the caller, so history
+// can be carried to another server or recovered after eviction
+// The transcript must fit the per-session message and size limits
+func (app *application) ImportSession(ctx context.Context, req *pb.ImportSessionRequest) (*pb.ImportSessionResponse, error) {
+	start := time.Now()
+	defer func() {
+		recordRequestDuration("ImportSession", time.Since(start).Seconds())
+	}()
+
+	if err := app.checkMaintenance("ImportSession"); err != nil {
+		return nil, err
+	}
+
+	recordRequestSize("ImportSession", len(req.Content))
+
+	// A client-supplied key in metadata makes the imported session client-encrypted
+	clientCipher, clientKeyFingerprint, err := clientCipherFromContext(ctx)
+	if err != nil {
+		incrementGRPCError("ImportSession", "InvalidArgument")
+		return nil, status.Errorf(codes.InvalidArgument, "invalid client encryption key: %v", err)
+	}
+
+	source, err := parseSessionExport(req.Content, time.Now())
+	if err != nil {
+		incrementGRPCError("ImportSession", "InvalidArgument")
+		app.logger.Warn("invalid session import", "content_len", len(req.Content), "error", err)
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	var idleTimeout time.Duration
+	if req.IdleTimeoutSeconds > 0 {
+		idleTimeout = clampIdleTimeout(time.Duration(req.IdleTimeoutSeconds)*time.Second,
+			app.config.sessionMinIdleTimeout, app.config.sessionMaxIdleTimeout)
+	}
+
+	sessionID := uuid.New().String()
+	imported, err := app.sessionStore.ImportSession(sessionID, apiKeyFromContext(ctx), idleTimeout, source, clientKeyFingerprint, clientCipher)
+	if err != nil {
+		incrementGRPCError("ImportSession", "ResourceExhausted")
+		app.logger.Warn("failed to import session", "message_count", len(source.Messages), "error", err)
+		return nil, status.Errorf(codes.ResourceExhausted, "failed to import session: %v", err)
+	}
+
+	// Update metrics
+	incrementSessionsCreated()
+	updateActiveSessions(app.sessionStore.GetSessionCount())
+	app.emitSessionCreated(ctx, sessionID, "import")
+
+	effectiveTimeout := app.sessionStore.GetIdleTimeout(sessionID)
+	app.logger.Info("imported session", "session_id", sessionID, "message_count", imported,
+		"client_encrypted", clientKeyFingerprint != "")
+
+	return &pb.ImportSessionResponse{
+		SessionId:          sessionID,
+		MessageCount:       uint32(imported),
+		IdleTimeoutSeconds: uint32(effectiveTimeout / time.Second),
 	}, nil
 }
 
@@ -117,6 +277,12 @@ func (app *application) Chat(ctx context.Context, req *pb.ChatRequest) (*pb.Chat
 	}()
 
 	recordRequestSize("Chat", len(req.Message))
+	return app.chat(ctx, req, -1)
+}
+
+// chat runs a Chat request; a non-negative editIndex makes the turn replace the user message at
+// that index, discarding the history from it on only once the new message has passed every check
+func (app *application) chat(ctx context.Context, req *pb.ChatRequest, editIndex int) (*pb.ChatResponse, error) {
 	// Validate input parameters
 	if err := validateSessionID(req.SessionId); err != nil {
 		incrementGRPCError("Chat", "InvalidArgument")
@@ -130,6 +296,32 @@ func (app *application) Chat(ctx context.Context, req *pb.ChatRequest) (*pb.Chat
 		return nil, err
 	}
 
+	if err := validateAttachments(req.Attachments, app.config.maxAttachmentBytes); err != nil {
+		incrementGRPCError("Chat", "InvalidArgument")
+		app.logger.Warn("invalid attachments", "session_id", req.SessionId, "attachments", len(req.Attachments), "error", err)
+		return nil, err
+	}
+
+	responseSchema, err := parseResponseSchema(req)
+	if err != nil {
+		incrementGRPCError("Chat", "InvalidArgument")
+		app.logger.Warn("invalid response schema", "session_id", req.SessionId, "error", err)
+		return nil, err
+	}
+
+	templateContext, err := app.templateMessage(req)
+	if err != nil {
+		incrementGRPCError("Chat", status.Code(err).String())
+		app.logger.Warn("invalid prompt template", "session_id", req.SessionId, "template", req.Template, "error", err)
+		return nil, err
+	}
+	systemPromptContext, err := systemPromptMessage(req)
+	if err != nil {
+		incrementGRPCError("Chat", "InvalidArgument")
+		app.logger.Warn("invalid system prompt", "session_id", req.SessionId, "system_prompt_len", len(req.SystemPrompt), "error", err)
+		return nil, err
+	}
+
 	// Check if session ID is valid (was created via StartSession)
 	if !app.sessionStore.IsValidSession(req.SessionId) {
 		incrementGRPCError("Chat", "NotFound")
@@ -137,44 +329,143 @@ func (app *application) Chat(ctx context.Context, req *pb.ChatRequest) (*pb.Chat
 		return nil, status.Error(codes.NotFound, "session not found or not properly created")
 	}
 
+	// Reject sessions created by a different API key (reported as not found to avoid leaking existence)
+	if !app.sessionStore.IsSessionOwner(req.SessionId, apiKeyFromContext(ctx)) {
+		incrementGRPCError("Chat", "NotFound")
+		app.logger.Warn("session owner mismatch", "session_id", req.SessionId)
+		return nil, status.Error(codes.NotFound, "session not found or not properly created")
+	}
+
+	// Client-encrypted sessions need the client's key to read history and store new messages
+	clientCipher, err := app.sessionClientCipher(ctx, req.SessionId)
+	if err != nil {
+		incrementGRPCError("Chat", status.Code(err).String())
+		return nil, err
+	}
+
 	app.logger.Info("received chat request",
 		"session_id", req.SessionId,
 		"model", req.Model,
 		"message_len", len(req.Message),
-		"message_index", req.MessageIndex)
+		"message_index", req.MessageIndex,
+		"attachments", len(req.Attachments),
+		"use_knowledge", req.UseKnowledge,
+		"template", req.Template,
+		"system_prompt_len", len(req.SystemPrompt),
+		"response_format", req.ResponseFormat.String())
+
+	// A session that has used up its token budget can't send more history to the LLM
+	if err := app.checkSessionTokenBudget(req.SessionId); err != nil {
+		incrementGRPCError("Chat", "ResourceExhausted")
+		app.logger.Warn("session token budget exhausted", "session_id", req.SessionId)
+		return nil, err
+	}
+	if !app.spendingTracker.CanSpendTokens(apiKeyFromContext(ctx)) {
+		incrementGRPCError("Chat", "ResourceExhausted")
+		return nil, status.Errorf(codes.ResourceExhausted, "daily token limit exceeded (%d tokens), resets at %s",
+			app.spendingTracker.TokenLimit(), app.spendingTracker.ResetsAt().Format(time.RFC3339))
+	}
+
+	// Moderate user input before it is stored or forwarded to the LLM
+	inputResult := app.moderate(ctx, moderation.StageInput, req.SessionId, req.Message)
+	if inputResult.Action == moderation.ActionBlock {
+		incrementGRPCError("Chat", "InvalidArgument")
+		return nil, status.Error(codes.InvalidArgument, "message blocked by content policy")
+	}
+	userMessage := inputResult.Text
 
 	// Layer 4: Delta protocol - verify client has correct message count
-	currentMessages := app.sessionStore.GetMessages(req.SessionId)
-	currentCount := uint32(len(currentMessages))
+	currentCount := uint32(app.sessionStore.GetMessageCount(req.SessionId))
+	if editIndex >= 0 {
+		currentCount = uint32(editIndex) // What's left once the edited message and later turns are discarded
+	}
 
 	// If client's index doesn't match our count, they may be out of sync
-	// For now, we'll accept the message anyway, but log the discrepancy
-	if req.MessageIndex > 0 && req.MessageIndex != currentCount {
+	// The message is accepted anyway, and the response tells the client to resync its copy of the history
+	historyDiverged := req.MessageIndex > 0 && req.MessageIndex != currentCount
+	if historyDiverged {
 		app.logger.Warn("client message index mismatch",
 			"session_id", req.SessionId,
 			"client_index", req.MessageIndex,
 			"server_count", currentCount)
 	}
 
-	// Store user message in session (Layer 2: structured format)
-	if err := app.sessionStore.AppendMessage(req.SessionId, User, req.Message); err != nil {
-		app.logger.Warn("failed to append user message", "session_id", req.SessionId, "error", err)
-		return nil, status.Errorf(codes.ResourceExhausted, "failed to store message: %v", err)
-	}
-
 	// Get LLM provider based on requested model
 	provider := app.getProvider(req.Model)
 	app.logger.Info("using LLM provider", "provider", provider.Name(), "model", req.Model.String())
 
-	// Get conversation history for LLM
-	messages := app.sessionStore.GetMessagesAsLLMFormat(req.SessionId)
+	if len(req.Attachments) > 0 && !supportsImages(provider) {
+		incrementGRPCError("Chat", "InvalidArgument")
+		return nil, status.Errorf(codes.InvalidArgument, "provider %s does not support image attachments", provider.Name())
+	}
+
+	// Retrieve excerpts from the caller's documents; they go to the LLM but are never stored in the session
+	var knowledgeContext *llm.Message
+	var knowledgeSources []string
+	if req.UseKnowledge {
+		knowledgeContext, knowledgeSources, err = app.retrieveKnowledge(ctx, apiKeyFromContext(ctx), userMessage)
+		if err != nil {
+			incrementGRPCError("Chat", status.Code(err).String())
+			app.logger.Warn("knowledge retrieval failed", "session_id", req.SessionId, "error", err)
+			return nil, err
+		}
+	}
+
+	// An edit discards the replaced message and the turns after it now that its replacement is accepted
+	if editIndex >= 0 {
+		if err := app.sessionStore.TruncateAtUserMessage(req.SessionId, editIndex); err != nil {
+			incrementGRPCError("EditMessage", "InvalidArgument")
+			app.logger.Warn("failed to edit message", "session_id", req.SessionId, "message_index", editIndex, "error", err)
+			return nil, status.Errorf(codes.InvalidArgument, "failed to edit message: %v", err)
+		}
+		app.logger.Info("edited message", "session_id", req.SessionId, "message_index", editIndex, "message_len", len(req.Message))
+	}
 
-	// Generate response using LLM provider
+	// Condense older turns of a session nearing its limits when the summarize policy is enabled
+	app.summarizeSession(ctx, provider, req.SessionId, apiKeyFromContext(ctx), clientCipher)
+
+	// Store user message in session (Layer 2: structured format), noting any attachments
+	turnStart := time.Now().UTC()
+	if err := app.sessionStore.AppendMessageWithKey(req.SessionId, User, userMessage+attachmentNote(req.Attachments), clientCipher); err != nil {
+		app.logger.Warn("failed to append user message", "session_id", req.SessionId, "error", err)
+		return nil, status.Errorf(codes.ResourceExhausted, "failed to store message: %v", err)
+	}
+
+	// Get conversation history for LLM; attachments go with the current message only
+	messages := toLLMMessages(app.sessionStore.GetMessagesWithKey(req.SessionId, clientCipher))
+	if len(req.Attachments) > 0 && len(messages) > 0 {
+		messages[len(messages)-1].Attachments = toLLMAttachments(req.Attachments)
+	}
+	if knowledgeContext != nil {
+		messages = append([]llm.Message{*knowledgeContext}, messages...)
+	}
+	if systemPromptContext != nil {
+		messages = append([]llm.Message{*systemPromptContext}, messages...)
+	}
+	if templateContext != nil {
+		messages = append([]llm.Message{*templateContext}, messages...)
+	}
+
+	// Generate response using LLM provider, counting the tokens it consumes
 	llmStart := time.Now()
-	reply, err := provider.GenerateResponse(ctx, messages)
-	recordLLMCallDuration(provider.Name(), time.Since(llmStart).Seconds())
+	llmCtx, usage := llm.WithUsage(ctx)
+	var reply string
+	if req.ResponseFormat == pb.ResponseFormat_RESPONSE_JSON {
+		reply, err = generateJSONReply(llmCtx, provider, messages, responseSchema)
+	} else {
+		reply, err = app.generateReply(llmCtx, provider, req.SessionId, messages, clientCipher)
+	}
+	recordLLMCallDuration(req.Model.String(), provider.Name(), time.Since(llmStart).Seconds())
+	tokens := replyTokens(usage, messages, reply, err)
+	tokensUsed := app.sessionStore.AddSessionTokens(req.SessionId, tokens)
+	app.spendingTracker.RecordTokens(apiKeyFromContext(ctx), tokens)
+	app.captureExchange(ctx, req.SessionId, req.Model, provider.Name(), messages, reply, err, time.Since(llmStart), clientCipher != nil)
+	app.adaptiveLimit.Observe(time.Since(llmStart), err != nil && ctx.Err() == nil)
+	if err != nil && ctx.Err() != nil {
+		return nil, app.abandonTurn(ctx, req.SessionId, turnStart)
+	}
 	if err != nil {
-		incrementLLMError(provider.Name(), "api_error")
+		incrementLLMError(req.Model.String(), provider.Name(), llm.ErrorType(err))
 		incrementGRPCError("Chat", "Internal")
 		app.logger.Error("LLM provider error", "error", err, "provider", provider.Name())
 		return nil, status.Errorf(codes.Internal, "LLM provider failed: %v", err)
@@ -194,24 +485,69 @@ func (app *application) Chat(ctx context.Context, req *pb.ChatRequest) (*pb.Chat
 	}
 	reply = sanitizedReply
 
+	// Moderate the LLM reply; a blocked reply is withheld but still recorded to keep turns paired
+	outputResult := app.moderate(ctx, moderation.StageOutput, req.SessionId, reply)
+	reply = outputResult.Text
+	if outputResult.Action == moderation.ActionBlock {
+		reply = withheldReply
+	}
+
+	// Validate JSON replies last, so clients scripting against the API only ever receive a valid payload
+	if req.ResponseFormat == pb.ResponseFormat_RESPONSE_JSON {
+		normalized, err := normalizeJSONReply(reply, responseSchema)
+		if err != nil {
+			incrementLLMError(req.Model.String(), provider.Name(), "invalid_json")
+			incrementGRPCError("Chat", "Internal")
+			app.logger.Warn("LLM returned invalid JSON", "session_id", req.SessionId, "provider", provider.Name(), "error", err)
+			return nil, status.Errorf(codes.Internal, "LLM returned an invalid JSON payload: %v", err)
+		}
+		reply = normalized
+	}
+
 	// Store sanitized LLM response in session (Layer 2: structured format)
-	if err := app.sessionStore.AppendMessage(req.SessionId, Assistant, reply); err != nil {
+	if err := app.sessionStore.AppendMessageWithKey(req.SessionId, Assistant, reply, clientCipher); err != nil {
 		app.logger.Warn("failed to append assistant message", "session_id", req.SessionId, "error", err)
 		return nil, status.Errorf(codes.ResourceExhausted, "failed to store response: %v", err)
 	}
+	app.sessionStore.SetSessionModel(req.SessionId, provider.Name())
 
-	// Get updated message count after adding both messages
-	newCount := currentCount + 2 // Added user message and assistant reply
+	// Get updated message count after adding the user message, any tool calls and the reply
+	newCount := uint32(app.sessionStore.GetMessageCount(req.SessionId))
+
+	// Generate a session title after the first exchange (skipped for client-encrypted sessions,
+	// whose titles could otherwise reveal content the server must not store in plaintext)
+	if app.config.sessionTitles && currentCount == 0 && clientCipher == nil {
+		app.generateTitleAsync(req.SessionId, apiKeyFromContext(ctx), provider, userMessage, reply)
+	}
 
 	resp := &pb.ChatResponse{
-		SessionId:    req.SessionId,
-		Reply:        reply,
-		MessageCount: newCount, // Layer 4: Tell client total message count
+		SessionId:        req.SessionId,
+		Reply:            reply,
+		MessageCount:     newCount, // Layer 4: Tell client total message count
+		KnowledgeSources: knowledgeSources,
+		HistoryDiverged:  historyDiverged,
 	}
+	app.setSessionTokens(resp, tokensUsed)
 
 	return resp, nil
 }
 
+// abandonTurn rolls back a Chat turn whose client cancelled or ran out of time before the reply,
+// so the session's history and message count stay what the client last saw
+// Returns the status error for the client's context
+func (app *application) abandonTurn(ctx context.Context, sessionID string, turnStart time.Time) error {
+	removed := app.sessionStore.RollbackTurn(sessionID, turnStart)
+	err := status.FromContextError(ctx.Err())
+	reason := "canceled"
+	if err.Code() == codes.DeadlineExceeded {
+		reason = "deadline_exceeded"
+	}
+	incrementChatTurnsAbandoned(reason)
+	incrementGRPCError("Chat", err.Code().String())
+	app.logger.Warn("chat abandoned by client, rolled back turn", "session_id", sessionID, "reason", reason, "messages_removed", removed)
+	return err.Err()
+}
+
 func (app *application) Health(ctx context.Context, req *pb.HealthRequest) (*pb.HealthResponse, error) {
 	return &pb.HealthResponse{Ok: true}, nil
 }
@@ -223,14 +559,738 @@ func (app *application) GetHistory(ctx context.Context, req *pb.GetHistoryReques
 		return nil, err
 	}
 
+	// Unknown and expired sessions, and sessions created by a different API key, are reported as not found
+	// so clients resuming a session can tell it's gone
+	if err := app.authorizeSession(ctx, req.SessionId); err != nil {
+		return nil, err
+	}
+
 	app.logger.Info("received get history request", "session_id", req.SessionId)
 
-	messages := app.sessionStore.GetFormattedMessages(req.SessionId)
+	// Client-encrypted sessions are always returned as ciphertext for the client to decrypt
+	// Full timestamps go alongside the formatted messages, which only carry the time of day
+	messages := app.sessionStore.GetMessages(req.SessionId)
+	formatted := make([]string, len(messages))
+	timestamps := make([]int64, len(messages))
+	for i, msg := range messages {
+		formatted[i] = msg.FormattedString()
+		timestamps[i] = msg.Timestamp.Unix()
+	}
 
 	resp := &pb.GetHistoryResponse{
+		SessionId:      req.SessionId,
+		Messages:       formatted,
+		Encrypted:      app.sessionStore.GetClientKeyFingerprint(req.SessionId) != "",
+		TimestampsUnix: timestamps,
+	}
+
+	return resp, nil
+}
+
+// KeepAlive marks one of the caller's sessions as active, restarting its idle timeout
+func (app *application) KeepAlive(ctx context.Context, req *pb.KeepAliveRequest) (*pb.KeepAliveResponse, error) {
+	start := time.Now()
+	defer func() {
+		recordRequestDuration("KeepAlive", time.Since(start).Seconds())
+	}()
+
+	if err := validateSessionID(req.SessionId); err != nil {
+		incrementGRPCError("KeepAlive", "InvalidArgument")
+		app.logger.Warn("invalid session ID in keep alive", "session_id", req.SessionId, "error", err)
+		return nil, err
+	}
+
+	if err := app.authorizeSession(ctx, req.SessionId); err != nil {
+		incrementGRPCError("KeepAlive", "NotFound")
+		return nil, err
+	}
+
+	app.sessionStore.TouchSession(req.SessionId)
+	remaining, _ := app.sessionStore.SessionExpiresIn(req.SessionId)
+	app.logger.Info("kept session alive", "session_id", req.SessionId, "expires_in", remaining)
+
+	return &pb.KeepAliveResponse{ExpiresInSeconds: uint32(remaining / time.Second)}, nil
+}
+
+// authorizeSession checks that a session exists and belongs to the caller's API key.
+// Sessions owned by other keys are reported as not found to avoid leaking their existence.
+func (app *application) authorizeSession(ctx context.Context, sessionID string) error {
+	if !app.sessionStore.IsValidSession(sessionID) {
+		return status.Error(codes.NotFound, "session not found or not properly created")
+	}
+	if !app.sessionStore.IsSessionOwner(sessionID, apiKeyFromContext(ctx)) {
+		app.logger.Warn("session owner mismatch", "session_id", sessionID)
+		return status.Error(codes.NotFound, "session not found or not properly created")
+	}
+	return nil
+}
+
+// sessionClientCipher returns the client's cipher for a client-encrypted session,
+// verifying the supplied key matches the one the session was started with
+// Returns a nil cipher for sessions that aren't client-encrypted
+func (app *application) sessionClientCipher(ctx context.Context, sessionID string) (*messageCipher, error) {
+	required := app.sessionStore.GetClientKeyFingerprint(sessionID)
+	if required == "" {
+		return nil, nil
+	}
+
+	clientCipher, fingerprint, err := clientCipherFromContext(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid client encryption key: %v", err)
+	}
+	if clientCipher == nil {
+		return nil, status.Error(codes.FailedPrecondition, "session is encrypted with a client key - supply it to continue")
+	}
+	if fingerprint != required {
+		app.logger.Warn("client encryption key mismatch", "session_id", sessionID)
+		return nil, status.Error(codes.PermissionDenied, "client encryption key does not match session")
+	}
+	return clientCipher, nil
+}
+
+// ExportSession returns the full conversation as a JSON or Markdown transcript
+func (app *application) ExportSession(ctx context.Context, req *pb.ExportSessionRequest) (*pb.ExportSessionResponse, error) {
+	start := time.Now()
+	defer func() {
+		recordRequestDuration("ExportSession", time.Since(start).Seconds())
+	}()
+
+	if err := validateSessionID(req.SessionId); err != nil {
+		incrementGRPCError("ExportSession", "InvalidArgument")
+		app.logger.Warn("invalid session ID in export", "session_id", req.SessionId, "error", err)
+		return nil, err
+	}
+
+	if err := app.authorizeSession(ctx, req.SessionId); err != nil {
+		incrementGRPCError("ExportSession", "NotFound")
+		return nil, err
+	}
+
+	app.logger.Info("received export session request", "session_id", req.SessionId, "format", req.Format.String())
+
+	clientCipher, err := app.sessionClientCipher(ctx, req.SessionId)
+	if err != nil {
+		incrementGRPCError("ExportSession", status.Code(err).String())
+		return nil, err
+	}
+
+	// Sessions without messages yet have no stored state, so export an empty transcript
+	session, _ := app.sessionStore.GetSessionWithKey(req.SessionId, clientCipher)
+	export := newSessionExport(req.SessionId, session, time.Now())
+
+	var content string
+	switch req.Format {
+	case pb.ExportFormat_EXPORT_JSON:
+		rendered, err := export.renderJSON()
+		if err != nil {
+			incrementGRPCError("ExportSession", "Internal")
+			app.logger.Error("failed to render session export", "session_id", req.SessionId, "error", err)
+			return nil, status.Error(codes.Internal, "failed to render session export")
+		}
+		content = rendered
+	case pb.ExportFormat_EXPORT_MARKDOWN:
+		content = export.renderMarkdown()
+	default:
+		incrementGRPCError("ExportSession", "InvalidArgument")
+		return nil, status.Errorf(codes.InvalidArgument, "unsupported export format: %v", req.Format)
+	}
+
+	return &pb.ExportSessionResponse{
 		SessionId: req.SessionId,
-		Messages:  messages,
+		Format:    req.Format,
+		Content:   content,
+	}, nil
+}
+
+// ListSessions returns a summary of all active sessions (admin only)
+func (app *application) ListSessions(ctx context.Context, req *pb.ListSessionsRequest) (*pb.ListSessionsResponse, error) {
+	start := time.Now()
+	defer func() {
+		recordRequestDuration("ListSessions", time.Since(start).Seconds())
+	}()
+
+	sessionsInfo := app.sessionStore.GetAllSessionsInfo()
+	sort.Slice(sessionsInfo, func(i, j int) bool {
+		return sessionsInfo[i].LastActive.After(sessionsInfo[j].LastActive)
+	})
+
+	app.logger.Info("received list sessions request", "session_count", len(sessionsInfo))
+
+	return &pb.ListSessionsResponse{Sessions: toSessionInfoProtos(sessionsInfo)}, nil
+}
+
+// ListKeyUsage returns call counts and request/response bytes per API key (admin only)
+// Keys are identified by hash so the response never exposes credentials
+func (app *application) ListKeyUsage(ctx context.Context, req *pb.ListKeyUsageRequest) (*pb.ListKeyUsageResponse, error) {
+	start := time.Now()
+	defer func() {
+		recordRequestDuration("ListKeyUsage", time.Since(start).Seconds())
+	}()
+
+	apiKeys := app.config.apiKeys.Snapshot()
+	callsToday := make(map[string]int, len(apiKeys))
+	tokensToday := make(map[string]int64, len(apiKeys))
+	for apiKey := range apiKeys {
+		callsToday[hashAPIKey(apiKey)] = app.spendingTracker.CallsToday(apiKey)
+		tokensToday[hashAPIKey(apiKey)] = app.spendingTracker.TokensToday(apiKey)
+	}
+
+	var keys []*pb.KeyUsage
+	for _, usage := range app.bandwidth.Snapshot() {
+		keys = append(keys, &pb.KeyUsage{
+			KeyHash:         usage.KeyHash,
+			CallsToday:      uint32(callsToday[usage.KeyHash]),
+			TokensToday:     uint64(tokensToday[usage.KeyHash]),
+			PayloadBytesIn:  usage.PayloadBytesIn,
+			PayloadBytesOut: usage.PayloadBytesOut,
+			WireBytesIn:     usage.WireBytesIn,
+			WireBytesOut:    usage.WireBytesOut,
+		})
+	}
+
+	app.logger.Info("received list key usage request", "key_count", len(keys))
+
+	return &pb.ListKeyUsageResponse{Keys: keys}, nil
+}
+
+// RotateProviderKey swaps an upstream LLM provider's API key without a restart (admin only)
+// The key is never logged or echoed back; the response carries its hash for confirmation
+func (app *application) RotateProviderKey(ctx context.Context, req *pb.RotateProviderKeyRequest) (*pb.RotateProviderKeyResponse, error) {
+	start := time.Now()
+	defer func() {
+		recordRequestDuration("RotateProviderKey", time.Since(start).Seconds())
+	}()
+
+	provider := strings.TrimSpace(req.GetProvider())
+	key := strings.TrimSpace(req.GetApiKey())
+	if provider == "" || key == "" {
+		incrementGRPCError("RotateProviderKey", "InvalidArgument")
+		return nil, status.Error(codes.InvalidArgument, "provider and API key are required")
 	}
 
+	app.logger.Info("received rotate provider key request", "provider", provider)
+
+	if err := app.rotateProviderKey(provider, key); err != nil {
+		incrementGRPCError("RotateProviderKey", "InvalidArgument")
+		return nil, status.Errorf(codes.InvalidArgument, "failed to rotate API key: %v", err)
+	}
+
+	return &pb.RotateProviderKeyResponse{Provider: provider, KeyHash: hashAPIKey(key)}, nil
+}
+
+// ListLLMCaptures returns recently captured provider exchanges for debugging bad model output (admin only)
+// Capture is opt-in with LLM_CAPTURE_SIZE; captured text is PII-redacted and size-capped
+func (app *application) ListLLMCaptures(ctx context.Context, req *pb.ListLLMCapturesRequest) (*pb.ListLLMCapturesResponse, error) {
+	start := time.Now()
+	defer func() {
+		recordRequestDuration("ListLLMCaptures", time.Since(start).Seconds())
+	}()
+
+	captured := app.llmCapture.recent(int(req.GetLimit()))
+	exchanges := make([]*pb.LLMExchange, len(captured))
+	for i, e := range captured {
+		exchanges[i] = toLLMExchangeProto(e)
+	}
+
+	app.logger.Info("received list LLM captures request", "exchange_count", len(exchanges))
+
+	return &pb.ListLLMCapturesResponse{Enabled: app.llmCapture != nil, Exchanges: exchanges}, nil
+}
+
+// SetMaintenanceMode turns maintenance mode on or off for staged upgrades (admin only)
+// While it's on, new sessions are refused with Unavailable and /readyz reports not ready;
+// existing sessions keep working
+func (app *application) SetMaintenanceMode(ctx context.Context, req *pb.SetMaintenanceModeRequest) (*pb.SetMaintenanceModeResponse, error) {
+	start := time.Now()
+	defer func() {
+		recordRequestDuration("SetMaintenanceMode", time.Since(start).Seconds())
+	}()
+
+	message := strings.TrimSpace(req.GetMessage())
+	if message == "" {
+		message = app.config.maintenanceMessage
+	}
+
+	app.logger.Info("received set maintenance mode request", "enabled", req.GetEnabled())
+
+	app.maintenance.set(req.GetEnabled(), message)
+	enabled, message, since := app.maintenance.status()
+
+	resp := &pb.SetMaintenanceModeResponse{Enabled: enabled, Message: message}
+	if !since.IsZero() {
+		resp.SinceUnix = since.Unix()
+	}
 	return resp, nil
 }
+
+// RestoreSession puts a session archived by idle cleanup back in the store under its original owner (admin only)
+// The archive is deleted once the session is restored; it is archived again if it goes idle
+func (app *application) RestoreSession(ctx context.Context, req *pb.RestoreSessionRequest) (*pb.RestoreSessionResponse, error) {
+	start := time.Now()
+	defer func() {
+		recordRequestDuration("RestoreSession", time.Since(start).Seconds())
+	}()
+
+	if err := validateSessionID(req.GetSessionId()); err != nil {
+		incrementGRPCError("RestoreSession", "InvalidArgument")
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if app.sessionArchiver == nil {
+		incrementGRPCError("RestoreSession", "FailedPrecondition")
+		return nil, status.Error(codes.FailedPrecondition, "session archiving is disabled (set ARCHIVE_STORE)")
+	}
+
+	app.logger.Info("received restore session request", "session_id", req.SessionId)
+
+	saved, err := app.sessionArchiver.load(ctx, req.SessionId)
+	if errors.Is(err, archive.ErrNotFound) {
+		incrementGRPCError("RestoreSession", "NotFound")
+		return nil, status.Errorf(codes.NotFound, "no archive for session %s", req.SessionId)
+	}
+	if err != nil {
+		recordSessionArchiveOp("restore", "error")
+		incrementGRPCError("RestoreSession", "Unavailable")
+		app.logger.Error("failed to load session archive", "session_id", req.SessionId, "error", err)
+		return nil, status.Errorf(codes.Unavailable, "failed to load session archive: %v", err)
+	}
+
+	if err := app.sessionStore.RestoreSession(saved.Session); err != nil {
+		if errors.Is(err, errSessionExists) {
+			incrementGRPCError("RestoreSession", "AlreadyExists")
+			return nil, status.Errorf(codes.AlreadyExists, "session %s is still active", req.SessionId)
+		}
+		recordSessionArchiveOp("restore", "error")
+		incrementGRPCError("RestoreSession", "FailedPrecondition")
+		return nil, status.Errorf(codes.FailedPrecondition, "failed to restore session: %v", err)
+	}
+	recordSessionArchiveOp("restore", "success")
+
+	if err := app.sessionArchiver.store.Delete(ctx, archiveKey(req.SessionId)); err != nil {
+		app.logger.Warn("failed to delete restored session archive", "session_id", req.SessionId, "error", err)
+	}
+
+	return &pb.RestoreSessionResponse{
+		SessionId:      req.SessionId,
+		OwnerKeyHash:   ownerKeyHash(app.sessionStore.savedOwnerID(saved.Session.OwnerID, saved.Session.LegacyOwner)),
+		MessageCount:   uint32(len(saved.Session.Session.Messages)),
+		ArchivedAtUnix: saved.ArchivedAt.Unix(),
+	}, nil
+}
+
+// GetQuota returns the caller's remaining daily calls and the limits that apply to it,
+// so clients can warn before a request fails
+// It doesn't count against the daily limit itself, so it keeps working once the limit is hit
+func (app *application) GetQuota(ctx context.Context, req *pb.GetQuotaRequest) (*pb.GetQuotaResponse, error) {
+	start := time.Now()
+	defer func() {
+		recordRequestDuration("GetQuota", time.Since(start).Seconds())
+	}()
+
+	apiKey := apiKeyFromContext(ctx)
+	limit := app.spendingTracker.limit
+	callsToday := app.spendingTracker.CallsToday(apiKey)
+	tokenLimit := app.spendingTracker.TokenLimit()
+	tokensToday := app.spendingTracker.TokensToday(apiKey)
+
+	methodCosts := make(map[string]uint32)
+	for method, cost := range app.config.rateLimitCosts {
+		if cost != 1 {
+			methodCosts[method] = uint32(cost)
+		}
+	}
+
+	rps := app.config.rateLimitRPS
+	if app.ipLimiter != nil {
+		rps = app.ipLimiter.Rate()
+	}
+
+	app.logger.Info("received get quota request", "calls_today", callsToday, "daily_limit", limit)
+
+	return &pb.GetQuotaResponse{
+		DailyCallLimit:        uint32(limit),
+		CallsToday:            uint32(callsToday),
+		CallsRemaining:        uint32(max(limit-callsToday, 0)),
+		ResetsAtUnix:          app.spendingTracker.ResetsAt().Unix(),
+		RateLimitRps:          float64(rps),
+		RateLimitBurst:        uint32(app.config.rateLimitBurst),
+		MethodCosts:           methodCosts,
+		MaxMessagesPerSession: uint32(app.config.maxMessagesPerSession),
+		MaxSessionSizeBytes:   uint32(app.config.maxSessionSizeBytes),
+		DailyTokenLimit:       uint64(tokenLimit),
+		TokensToday:           uint64(tokensToday),
+		TokensRemaining:       uint64(max(tokenLimit-tokensToday, 0)),
+		SessionTokenBudget:    uint64(app.config.sessionTokenBudget),
+	}, nil
+}
+
+// GetServerInfo reports which build the server is running so clients and operators can
+// correlate behavior with a deployment
+func (app *application) GetServerInfo(ctx context.Context, req *pb.GetServerInfoRequest) (*pb.GetServerInfoResponse, error) {
+	start := time.Now()
+	defer func() {
+		recordRequestDuration("GetServerInfo", time.Since(start).Seconds())
+	}()
+
+	build := version.Get()
+	app.logger.Info("received get server info request")
+
+	return &pb.GetServerInfoResponse{
+		Version:       build.Version,
+		Commit:        build.Commit,
+		BuildDate:     build.BuildDate,
+		GoVersion:     build.GoVersion,
+		StartedAtUnix: app.startedAt.Unix(),
+	}, nil
+}
+
+// ListModels reports the models compiled into the server and whether each is served by its own provider,
+// so clients can offer a model switch without trial and error
+func (app *application) ListModels(ctx context.Context, req *pb.ListModelsRequest) (*pb.ListModelsResponse, error) {
+	start := time.Now()
+	defer func() {
+		recordRequestDuration("ListModels", time.Since(start).Seconds())
+	}()
+
+	var models []*pb.ModelInfo
+	for _, reg := range llm.Registered() {
+		reason := llm.Unavailable(reg, app.providerHealth)
+		for _, model := range reg.Models {
+			models = append(models, &pb.ModelInfo{
+				Model:             model,
+				Provider:          reg.Name,
+				DisplayName:       reg.DisplayName,
+				Available:         reason == "",
+				UnavailableReason: reason,
+			})
+		}
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].Model < models[j].Model })
+
+	app.logger.Info("received list models request", "model_count", len(models))
+
+	return &pb.ListModelsResponse{Models: models}, nil
+}
+
+// toSessionInfoProtos converts session summaries to their protobuf form
+func toSessionInfoProtos(sessionsInfo []SessionInfo) []*pb.SessionInfo {
+	result := make([]*pb.SessionInfo, len(sessionsInfo))
+	for i, info := range sessionsInfo {
+		result[i] = &pb.SessionInfo{
+			SessionId:      info.ID,
+			Title:          info.Title,
+			MessageCount:   uint32(info.MessageCount),
+			SizeBytes:      uint32(info.SizeBytes),
+			LastActiveUnix: info.LastActive.Unix(),
+		}
+	}
+	return result
+}
+
+// ListMySessions returns the sessions created by the caller's API key, most recently active first
+func (app *application) ListMySessions(ctx context.Context, req *pb.ListMySessionsRequest) (*pb.ListMySessionsResponse, error) {
+	start := time.Now()
+	defer func() {
+		recordRequestDuration("ListMySessions", time.Since(start).Seconds())
+	}()
+
+	sessionsInfo := app.sessionStore.GetSessionsInfoForOwner(apiKeyFromContext(ctx))
+	sort.Slice(sessionsInfo, func(i, j int) bool {
+		return sessionsInfo[i].LastActive.After(sessionsInfo[j].LastActive)
+	})
+
+	app.logger.Info("received list my sessions request", "session_count", len(sessionsInfo))
+
+	return &pb.ListMySessionsResponse{Sessions: toSessionInfoProtos(sessionsInfo)}, nil
+}
+
+// SearchHistory returns the messages in one of the caller's sessions matching a query, a page at a time
+func (app *application) SearchHistory(ctx context.Context, req *pb.SearchHistoryRequest) (*pb.SearchHistoryResponse, error) {
+	start := time.Now()
+	defer func() {
+		recordRequestDuration("SearchHistory", time.Since(start).Seconds())
+	}()
+
+	if err := validateSessionID(req.SessionId); err != nil {
+		incrementGRPCError("SearchHistory", "InvalidArgument")
+		app.logger.Warn("invalid session ID in search history", "session_id", req.SessionId, "error", err)
+		return nil, err
+	}
+
+	if err := app.authorizeSession(ctx, req.SessionId); err != nil {
+		incrementGRPCError("SearchHistory", "NotFound")
+		return nil, err
+	}
+
+	match, err := newHistoryMatcher(req.Query, req.Regex)
+	if err != nil {
+		incrementGRPCError("SearchHistory", "InvalidArgument")
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	clientCipher, err := app.sessionClientCipher(ctx, req.SessionId)
+	if err != nil {
+		incrementGRPCError("SearchHistory", status.Code(err).String())
+		return nil, err
+	}
+
+	matches := app.sessionStore.SearchMessages(req.SessionId, match, clientCipher)
+	app.logger.Info("received search history request", "session_id", req.SessionId, "query_len", len(req.Query), "regex", req.Regex, "match_count", len(matches))
+
+	return searchHistoryResponse("SearchHistory", matches, req.PageSize, req.PageToken)
+}
+
+// SearchAllSessions returns messages matching a query across all sessions, a page at a time (admin only)
+// Sessions encrypted with a client-supplied key can't be read by the server and are skipped
+func (app *application) SearchAllSessions(ctx context.Context, req *pb.SearchAllSessionsRequest) (*pb.SearchHistoryResponse, error) {
+	start := time.Now()
+	defer func() {
+		recordRequestDuration("SearchAllSessions", time.Since(start).Seconds())
+	}()
+
+	match, err := newHistoryMatcher(req.Query, req.Regex)
+	if err != nil {
+		incrementGRPCError("SearchAllSessions", "InvalidArgument")
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	matches := app.sessionStore.SearchAllSessions(match)
+	app.logger.Info("received search all sessions request", "query_len", len(req.Query), "regex", req.Regex, "match_count", len(matches))
+
+	return searchHistoryResponse("SearchAllSessions", matches, req.PageSize, req.PageToken)
+}
+
+// searchHistoryResponse converts one page of search matches to their protobuf form
+func searchHistoryResponse(method string, matches []SearchMatch, pageSize uint32, pageToken string) (*pb.SearchHistoryResponse, error) {
+	page, next, err := paginateMatches(matches, pageSize, pageToken)
+	if err != nil {
+		incrementGRPCError(method, "InvalidArgument")
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	resp := &pb.SearchHistoryResponse{
+		Matches:       make([]*pb.SearchMatch, len(page)),
+		NextPageToken: next,
+	}
+	for i, m := range page {
+		resp.Matches[i] = &pb.SearchMatch{
+			SessionId:     m.SessionID,
+			MessageIndex:  uint32(m.Index),
+			Role:          m.Message.Role.String(),
+			Text:          m.Message.Text,
+			TimestampUnix: m.Message.Timestamp.Unix(),
+		}
+	}
+	return resp, nil
+}
+
+// DeleteMessages removes a range of messages from one of the caller's sessions
+func (app *application) DeleteMessages(ctx context.Context, req *pb.DeleteMessagesRequest) (*pb.DeleteMessagesResponse, error) {
+	start := time.Now()
+	defer func() {
+		recordRequestDuration("DeleteMessages", time.Since(start).Seconds())
+	}()
+
+	if err := validateSessionID(req.SessionId); err != nil {
+		incrementGRPCError("DeleteMessages", "InvalidArgument")
+		app.logger.Warn("invalid session ID in delete messages", "session_id", req.SessionId, "error", err)
+		return nil, err
+	}
+
+	if err := app.authorizeSession(ctx, req.SessionId); err != nil {
+		incrementGRPCError("DeleteMessages", "NotFound")
+		return nil, err
+	}
+
+	if err := app.sessionStore.DeleteMessages(req.SessionId, int(req.StartIndex), int(req.Count)); err != nil {
+		incrementGRPCError("DeleteMessages", "InvalidArgument")
+		app.logger.Warn("failed to delete messages", "session_id", req.SessionId, "start_index", req.StartIndex, "count", req.Count, "error", err)
+		return nil, status.Errorf(codes.InvalidArgument, "failed to delete messages: %v", err)
+	}
+
+	messageCount := app.sessionStore.GetMessageCount(req.SessionId)
+	app.logger.Info("deleted messages", "session_id", req.SessionId, "start_index", req.StartIndex, "count", req.Count, "message_count", messageCount)
+
+	return &pb.DeleteMessagesResponse{MessageCount: uint32(messageCount)}, nil
+}
+
+// EditMessage replaces a prior user message, discarding every later turn, and replies to the new text
+// The replacement goes through the same validation, moderation and storage as Chat; if the reply
+// fails, the session is left ending just before the edited message
+func (app *application) EditMessage(ctx context.Context, req *pb.EditMessageRequest) (*pb.ChatResponse, error) {
+	start := time.Now()
+	defer func() {
+		recordRequestDuration("EditMessage", time.Since(start).Seconds())
+	}()
+
+	if err := validateSessionID(req.SessionId); err != nil {
+		incrementGRPCError("EditMessage", "InvalidArgument")
+		app.logger.Warn("invalid session ID in edit message", "session_id", req.SessionId, "error", err)
+		return nil, err
+	}
+
+	if err := validateMessage(req.Message); err != nil {
+		incrementGRPCError("EditMessage", "InvalidArgument")
+		app.logger.Warn("invalid message in edit message", "session_id", req.SessionId, "message_len", len(req.Message), "error", err)
+		return nil, err
+	}
+
+	if err := app.authorizeSession(ctx, req.SessionId); err != nil {
+		incrementGRPCError("EditMessage", "NotFound")
+		return nil, err
+	}
+
+	// Check the client key before discarding anything, since Chat would refuse to store the replacement
+	if _, err := app.sessionClientCipher(ctx, req.SessionId); err != nil {
+		incrementGRPCError("EditMessage", status.Code(err).String())
+		return nil, err
+	}
+
+	if err := app.sessionStore.CheckUserMessage(req.SessionId, int(req.MessageIndex)); err != nil {
+		incrementGRPCError("EditMessage", "InvalidArgument")
+		app.logger.Warn("failed to edit message", "session_id", req.SessionId, "message_index", req.MessageIndex, "error", err)
+		return nil, status.Errorf(codes.InvalidArgument, "failed to edit message: %v", err)
+	}
+
+	// The new turn runs through Chat's checks with the same options as any other turn, and the
+	// history is only truncated once they pass
+	chatReq := &pb.ChatRequest{
+		SessionId:    req.SessionId,
+		Model:        req.Model,
+		Message:      req.Message,
+		MessageIndex: req.MessageIndex,
+	}
+	if options := req.Options; options != nil {
+		chatReq.ResponseFormat = options.ResponseFormat
+		chatReq.ResponseSchema = options.ResponseSchema
+		chatReq.Attachments = options.Attachments
+		chatReq.UseKnowledge = options.UseKnowledge
+		chatReq.Template = options.Template
+		chatReq.TemplateVars = options.TemplateVars
+		chatReq.SystemPrompt = options.SystemPrompt
+	}
+	recordRequestSize("EditMessage", len(req.Message))
+	return app.chat(ctx, chatReq, int(req.MessageIndex))
+}
+
+// maxEmbedTexts bounds how many texts a single Embed request can carry
+const maxEmbedTexts = 256
+
+// Embed returns an embedding vector for each text, batching calls to the provider
+func (app *application) Embed(ctx context.Context, req *pb.EmbedRequest) (*pb.EmbedResponse, error) {
+	start := time.Now()
+	defer func() {
+		recordRequestDuration("Embed", time.Since(start).Seconds())
+	}()
+
+	if len(req.Texts) == 0 {
+		incrementGRPCError("Embed", "InvalidArgument")
+		return nil, status.Error(codes.InvalidArgument, "at least one text is required")
+	}
+	if len(req.Texts) > maxEmbedTexts {
+		incrementGRPCError("Embed", "InvalidArgument")
+		return nil, status.Errorf(codes.InvalidArgument, "too many texts: %d (max %d)", len(req.Texts), maxEmbedTexts)
+	}
+
+	totalBytes := 0
+	for i, text := range req.Texts {
+		if err := validateMessage(text); err != nil {
+			incrementGRPCError("Embed", "InvalidArgument")
+			return nil, status.Errorf(codes.InvalidArgument, "text %d: %s", i, status.Convert(err).Message())
+		}
+		totalBytes += len(text)
+	}
+	recordRequestSize("Embed", totalBytes)
+
+	embedder, err := app.getEmbeddingProvider(req.Model)
+	if err != nil {
+		incrementGRPCError("Embed", status.Code(err).String())
+		app.logger.Warn("embedding provider unavailable", "model", req.Model.String(), "error", err)
+		return nil, err
+	}
+
+	// Charge every text against the caller's daily embedding quota
+	if app.embeddingQuota != nil && !app.embeddingQuota.TryRecordCalls(apiKeyFromContext(ctx), len(req.Texts)) {
+		incrementGRPCError("Embed", "ResourceExhausted")
+		return nil, status.Error(codes.ResourceExhausted, "daily embedding limit exceeded")
+	}
+
+	app.logger.Info("received embed request", "provider", embedder.Name(), "texts", len(req.Texts), "bytes", totalBytes)
+
+	llmStart := time.Now()
+	vectors, err := llm.EmbedBatched(ctx, embedder, req.Texts)
+	recordLLMCallDuration(req.Model.String(), embedder.Name(), time.Since(llmStart).Seconds())
+	if err != nil {
+		incrementLLMError(req.Model.String(), embedder.Name(), llm.ErrorType(err))
+		incrementGRPCError("Embed", "Internal")
+		app.logger.Error("embedding provider error", "error", err, "provider", embedder.Name())
+		return nil, status.Errorf(codes.Internal, "embedding provider failed: %v", err)
+	}
+
+	resp := &pb.EmbedResponse{
+		Provider:   embedder.Name(),
+		Embeddings: make([]*pb.Embedding, len(vectors)),
+	}
+	for i, vector := range vectors {
+		resp.Embeddings[i] = &pb.Embedding{Values: vector}
+	}
+	if len(vectors) > 0 {
+		resp.Dimensions = uint32(len(vectors[0]))
+	}
+
+	return resp, nil
+}
+
+// UploadDocument chunks, embeds and stores a document for retrieval in chats with use_knowledge
+func (app *application) UploadDocument(ctx context.Context, req *pb.UploadDocumentRequest) (*pb.UploadDocumentResponse, error) {
+	start := time.Now()
+	defer func() {
+		recordRequestDuration("UploadDocument", time.Since(start).Seconds())
+	}()
+
+	recordRequestSize("UploadDocument", len(req.Content))
+	if app.knowledge == nil {
+		incrementGRPCError("UploadDocument", "FailedPrecondition")
+		return nil, status.Error(codes.FailedPrecondition, knowledgeDisabledError)
+	}
+
+	name := strings.TrimSpace(sanitizeForTerminal(req.Name))
+	if name == "" || len([]rune(name)) > maxDocumentNameLength {
+		incrementGRPCError("UploadDocument", "InvalidArgument")
+		return nil, status.Errorf(codes.InvalidArgument, "document name must be 1-%d characters", maxDocumentNameLength)
+	}
+
+	doc, err := app.knowledge.Upload(ctx, apiKeyFromContext(ctx), name, req.Content)
+	if err != nil {
+		err = knowledgeStatus(err)
+		incrementGRPCError("UploadDocument", status.Code(err).String())
+		app.logger.Warn("failed to upload document", "bytes", len(req.Content), "error", err)
+		return nil, err
+	}
+
+	app.logger.Info("uploaded document", "document_id", doc.ID, "bytes", doc.Bytes, "chunks", doc.Chunks)
+
+	return &pb.UploadDocumentResponse{DocumentId: doc.ID, ChunkCount: uint32(doc.Chunks)}, nil
+}
+
+// DeleteDocument removes one of the caller's uploaded documents
+func (app *application) DeleteDocument(ctx context.Context, req *pb.DeleteDocumentRequest) (*pb.DeleteDocumentResponse, error) {
+	start := time.Now()
+	defer func() {
+		recordRequestDuration("DeleteDocument", time.Since(start).Seconds())
+	}()
+
+	if app.knowledge == nil {
+		incrementGRPCError("DeleteDocument", "FailedPrecondition")
+		return nil, status.Error(codes.FailedPrecondition, knowledgeDisabledError)
+	}
+
+	if err := app.knowledge.Delete(apiKeyFromContext(ctx), req.DocumentId); err != nil {
+		err = knowledgeStatus(err)
+		incrementGRPCError("DeleteDocument", status.Code(err).String())
+		return nil, err
+	}
+
+	app.logger.Info("deleted document", "document_id", req.DocumentId)
+
+	return &pb.DeleteDocumentResponse{}, nil
+}
@@ -2,14 +2,26 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"log/slog"
 	"os"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"microchat.ai/cmd/server/knowledge"
 	"microchat.ai/cmd/server/llm"
+	"microchat.ai/cmd/server/moderation"
+	"microchat.ai/cmd/server/ratelimit"
+	"microchat.ai/cmd/server/tools"
 	pb "microchat.ai/proto"
+	"microchat.ai/version"
 )
 
 // Test helper to create application instance for grpc handler tests
@@ -85,6 +97,9 @@ func TestDeltaProtocol(t *testing.T) {
 	if resp3.MessageCount != 6 {
 		t.Errorf("Third message: expected count=6, got %d", resp3.MessageCount)
 	}
+	if resp1.HistoryDiverged || resp2.HistoryDiverged || resp3.HistoryDiverged {
+		t.Error("Expected matching indexes not to report diverged history")
+	}
 }
 
 // Edge case: Client sends wrong index
@@ -115,10 +130,13 @@ func TestDeltaProtocolWrongIndex(t *testing.T) {
 	}
 	resp, _ := app.Chat(ctx, req)
 
-	// Should still accept and return correct count
+	// Should still accept and return correct count, flagging that the client is out of sync
 	if resp.MessageCount != 4 {
 		t.Errorf("Wrong index: expected count=4, got %d", resp.MessageCount)
 	}
+	if !resp.HistoryDiverged {
+		t.Error("Wrong index: expected the response to report diverged history")
+	}
 }
 
 // Edge case: Backward compatibility (no index field)
@@ -288,6 +306,14 @@ func TestGetHistoryValidation(t *testing.T) {
 	if err != nil {
 		t.Errorf("Valid session ID should not produce error, got: %v", err)
 	}
+	// Unknown or expired sessions are not found, so clients resuming them can start over
+	req = &pb.GetHistoryRequest{
+		SessionId: "123e4567-e89b-12d3-a456-426614174000",
+	}
+	_, err = app.GetHistory(ctx, req)
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound for an unknown session, got: %v", err)
+	}
 }
 
 // Test with mock provider - success scenarios
@@ -393,6 +419,47 @@ func TestChatWithMockProviderError(t *testing.T) {
 	}
 }
 
+func TestChatAbandonedByClient(t *testing.T) {
+	app, mockProvider := setupTestApplicationWithMock(t)
+	ctx := context.Background()
+
+	startResp, err := app.StartSession(ctx, &pb.StartSessionRequest{})
+	if err != nil {
+		t.Fatalf("Failed to start session: %v", err)
+	}
+	sessionID := startResp.SessionId
+	if _, err := app.Chat(ctx, &pb.ChatRequest{SessionId: sessionID, Message: "first"}); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+
+	mockProvider.SetBehavior(llm.MockBehavior{Latency: llm.LatencyDistribution{Kind: "fixed", Base: time.Minute}})
+
+	// A client whose deadline passes mid-call gets DeadlineExceeded and its message is rolled back
+	deadlineCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	_, err = app.Chat(deadlineCtx, &pb.ChatRequest{SessionId: sessionID, Message: "too slow", MessageIndex: 2})
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("Expected DeadlineExceeded, got %v", err)
+	}
+	if count := app.sessionStore.GetMessageCount(sessionID); count != 2 {
+		t.Errorf("Expected the abandoned turn to be rolled back to 2 messages, got %d", count)
+	}
+
+	// A client that disconnects gets Canceled
+	cancelCtx, cancelChat := context.WithCancel(ctx)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancelChat()
+	}()
+	_, err = app.Chat(cancelCtx, &pb.ChatRequest{SessionId: sessionID, Message: "never mind", MessageIndex: 2})
+	if status.Code(err) != codes.Canceled {
+		t.Errorf("Expected Canceled, got %v", err)
+	}
+	if count := app.sessionStore.GetMessageCount(sessionID); count != 2 {
+		t.Errorf("Expected the cancelled turn to be rolled back to 2 messages, got %d", count)
+	}
+}
+
 // Test that mocked tests run without live dependencies
 func TestMockedTestsRunInIsolation(t *testing.T) {
 	// This test verifies that we can run tests without any external dependencies
@@ -667,3 +734,904 @@ func TestValidateResponseConfigurable(t *testing.T) {
 		})
 	}
 }
+
+// Test that sessions are bound to the API key that created them
+func TestSessionOwnership(t *testing.T) {
+	app, mockProvider := setupTestApplicationWithMock(t)
+	mockProvider.SetResponses("Owner response")
+
+	ownerCtx := context.WithValue(context.Background(), "api_key", "owner-key")
+	otherCtx := context.WithValue(context.Background(), "api_key", "other-key")
+
+	startResp, err := app.StartSession(ownerCtx, &pb.StartSessionRequest{})
+	if err != nil {
+		t.Fatalf("Failed to start session: %v", err)
+	}
+	sessionID := startResp.SessionId
+
+	// Owner can chat and read history
+	if _, err := app.Chat(ownerCtx, &pb.ChatRequest{SessionId: sessionID, Message: "Hello"}); err != nil {
+		t.Fatalf("Owner should be able to chat, got: %v", err)
+	}
+	historyResp, err := app.GetHistory(ownerCtx, &pb.GetHistoryRequest{SessionId: sessionID})
+	if err != nil {
+		t.Fatalf("Owner should be able to get history, got: %v", err)
+	}
+	if len(historyResp.Messages) != 2 {
+		t.Errorf("Expected 2 messages in history, got %d", len(historyResp.Messages))
+	}
+	if ts := historyResp.TimestampsUnix; len(ts) != 2 || time.Since(time.Unix(ts[0], 0)) > time.Minute {
+		t.Errorf("Expected a recent timestamp for each message, got %v", ts)
+	}
+
+	// A different key must not be able to chat in the session
+	_, err = app.Chat(otherCtx, &pb.ChatRequest{SessionId: sessionID, Message: "Hijack"})
+	if err == nil {
+		t.Fatal("Expected error when chatting in a session owned by another key")
+	}
+	if !strings.Contains(err.Error(), "code = NotFound") {
+		t.Errorf("Expected NotFound error code, got: %v", err)
+	}
+
+	// A different key must not be able to read the history
+	_, err = app.GetHistory(otherCtx, &pb.GetHistoryRequest{SessionId: sessionID})
+	if err == nil {
+		t.Fatal("Expected error when reading history of a session owned by another key")
+	}
+	if !strings.Contains(err.Error(), "code = NotFound") {
+		t.Errorf("Expected NotFound error code, got: %v", err)
+	}
+
+	// The rejected message must not have been stored
+	if count := len(app.sessionStore.GetMessages(sessionID)); count != 2 {
+		t.Errorf("Expected 2 messages after rejected request, got %d", count)
+	}
+}
+
+// Test session export in JSON and Markdown formats
+func TestExportSession(t *testing.T) {
+	app, mockProvider := setupTestApplicationWithMock(t)
+	mockProvider.SetResponses("Exported reply")
+	ctx := context.Background()
+
+	startResp, err := app.StartSession(ctx, &pb.StartSessionRequest{})
+	if err != nil {
+		t.Fatalf("Failed to start session: %v", err)
+	}
+	sessionID := startResp.SessionId
+
+	if _, err := app.Chat(ctx, &pb.ChatRequest{SessionId: sessionID, Message: "Export me"}); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+
+	// JSON export should round-trip with metadata
+	jsonResp, err := app.ExportSession(ctx, &pb.ExportSessionRequest{SessionId: sessionID, Format: pb.ExportFormat_EXPORT_JSON})
+	if err != nil {
+		t.Fatalf("JSON export failed: %v", err)
+	}
+	var export sessionExport
+	if err := json.Unmarshal([]byte(jsonResp.Content), &export); err != nil {
+		t.Fatalf("Failed to parse JSON export: %v", err)
+	}
+	if export.SessionID != sessionID {
+		t.Errorf("Expected session ID %s, got %s", sessionID, export.SessionID)
+	}
+	if export.Model != "Mock-Test-Provider" {
+		t.Errorf("Expected model Mock-Test-Provider, got %s", export.Model)
+	}
+	if export.MessageCount != 2 || len(export.Messages) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", export.MessageCount)
+	}
+	if export.Messages[0].Role != "user" || export.Messages[0].Text != "Export me" {
+		t.Errorf("Unexpected first message: %+v", export.Messages[0])
+	}
+	if export.TotalBytes != export.Messages[0].Bytes+export.Messages[1].Bytes {
+		t.Errorf("Total bytes %d doesn't match message bytes", export.TotalBytes)
+	}
+
+	// Markdown export should contain headings and message text
+	mdResp, err := app.ExportSession(ctx, &pb.ExportSessionRequest{SessionId: sessionID, Format: pb.ExportFormat_EXPORT_MARKDOWN})
+	if err != nil {
+		t.Fatalf("Markdown export failed: %v", err)
+	}
+	for _, want := range []string{"# microchat.ai session " + sessionID, "**Model:** Mock-Test-Provider", "## user", "Export me", "## assistant"} {
+		if !strings.Contains(mdResp.Content, want) {
+			t.Errorf("Markdown export missing %q", want)
+		}
+	}
+
+	// Unknown sessions are not exportable
+	_, err = app.ExportSession(ctx, &pb.ExportSessionRequest{SessionId: "123e4567-e89b-12d3-a456-426614174000"})
+	if err == nil || !strings.Contains(err.Error(), "code = NotFound") {
+		t.Errorf("Expected NotFound for unknown session, got: %v", err)
+	}
+}
+
+// Test admin session listing with titles
+func TestListSessions(t *testing.T) {
+	app, mockProvider := setupTestApplicationWithMock(t)
+	mockProvider.SetResponses("Listed reply")
+	ctx := context.Background()
+
+	startResp, err := app.StartSession(ctx, &pb.StartSessionRequest{})
+	if err != nil {
+		t.Fatalf("Failed to start session: %v", err)
+	}
+	sessionID := startResp.SessionId
+
+	if _, err := app.Chat(ctx, &pb.ChatRequest{SessionId: sessionID, Message: "List me"}); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	app.sessionStore.SetSessionTitle(sessionID, "Listing Test")
+
+	resp, err := app.ListSessions(ctx, &pb.ListSessionsRequest{})
+	if err != nil {
+		t.Fatalf("ListSessions failed: %v", err)
+	}
+	if len(resp.Sessions) != 1 {
+		t.Fatalf("Expected 1 session, got %d", len(resp.Sessions))
+	}
+
+	info := resp.Sessions[0]
+	if info.SessionId != sessionID {
+		t.Errorf("Expected session ID %s, got %s", sessionID, info.SessionId)
+	}
+	if info.Title != "Listing Test" {
+		t.Errorf("Expected title 'Listing Test', got %q", info.Title)
+	}
+	if info.MessageCount != 2 {
+		t.Errorf("Expected 2 messages, got %d", info.MessageCount)
+	}
+	if info.LastActiveUnix == 0 {
+		t.Error("Expected last active timestamp to be set")
+	}
+}
+
+// Test that ListMySessions only returns the caller's sessions
+func TestListMySessions(t *testing.T) {
+	app, mockProvider := setupTestApplicationWithMock(t)
+	mockProvider.SetResponses("Reply")
+
+	aliceCtx := context.WithValue(context.Background(), "api_key", "alice-key")
+	bobCtx := context.WithValue(context.Background(), "api_key", "bob-key")
+
+	var aliceSessions []string
+	for i := 0; i < 2; i++ {
+		resp, err := app.StartSession(aliceCtx, &pb.StartSessionRequest{})
+		if err != nil {
+			t.Fatalf("Failed to start session: %v", err)
+		}
+		aliceSessions = append(aliceSessions, resp.SessionId)
+	}
+	if _, err := app.StartSession(bobCtx, &pb.StartSessionRequest{}); err != nil {
+		t.Fatalf("Failed to start session: %v", err)
+	}
+
+	// Only the second session has activity, so it should be listed first
+	if _, err := app.Chat(aliceCtx, &pb.ChatRequest{SessionId: aliceSessions[1], Message: "Hi"}); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+
+	resp, err := app.ListMySessions(aliceCtx, &pb.ListMySessionsRequest{})
+	if err != nil {
+		t.Fatalf("ListMySessions failed: %v", err)
+	}
+	if len(resp.Sessions) != 2 {
+		t.Fatalf("Expected 2 sessions for alice, got %d", len(resp.Sessions))
+	}
+	if resp.Sessions[0].SessionId != aliceSessions[1] || resp.Sessions[0].MessageCount != 2 {
+		t.Errorf("Expected active session first with 2 messages, got %+v", resp.Sessions[0])
+	}
+	if resp.Sessions[1].SessionId != aliceSessions[0] || resp.Sessions[1].MessageCount != 0 {
+		t.Errorf("Expected empty session second, got %+v", resp.Sessions[1])
+	}
+
+	resp, err = app.ListMySessions(bobCtx, &pb.ListMySessionsRequest{})
+	if err != nil {
+		t.Fatalf("ListMySessions failed: %v", err)
+	}
+	if len(resp.Sessions) != 1 {
+		t.Errorf("Expected 1 session for bob, got %d", len(resp.Sessions))
+	}
+}
+
+// Test sessions encrypted with a client-supplied key
+func TestClientKeyEncryption(t *testing.T) {
+	app, mockProvider := setupTestApplicationWithMock(t)
+	mockProvider.SetResponses("Encrypted reply")
+
+	key := base64.StdEncoding.EncodeToString(make([]byte, encryptionKeySize))
+	otherKey := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", encryptionKeySize)))
+	withKey := func(k string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(clientKeyMetadataKey, k))
+	}
+
+	startResp, err := app.StartSession(withKey(key), &pb.StartSessionRequest{})
+	if err != nil {
+		t.Fatalf("Failed to start session: %v", err)
+	}
+	sessionID := startResp.SessionId
+
+	if _, err := app.Chat(withKey(key), &pb.ChatRequest{SessionId: sessionID, Message: "Secret"}); err != nil {
+		t.Fatalf("Chat with key failed: %v", err)
+	}
+
+	_, err = app.Chat(context.Background(), &pb.ChatRequest{SessionId: sessionID, Message: "No key"})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition without key, got %v", err)
+	}
+
+	_, err = app.Chat(withKey(otherKey), &pb.ChatRequest{SessionId: sessionID, Message: "Wrong key"})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied with wrong key, got %v", err)
+	}
+
+	// History is returned as ciphertext even when the key is supplied
+	histResp, err := app.GetHistory(withKey(key), &pb.GetHistoryRequest{SessionId: sessionID})
+	if err != nil {
+		t.Fatalf("GetHistory failed: %v", err)
+	}
+	if !histResp.Encrypted {
+		t.Error("Expected history to be marked encrypted")
+	}
+	if strings.Contains(strings.Join(histResp.Messages, "\n"), "Secret") {
+		t.Error("Expected history not to contain plaintext")
+	}
+
+	// Exports decrypt with the key
+	exportResp, err := app.ExportSession(withKey(key), &pb.ExportSessionRequest{SessionId: sessionID})
+	if err != nil {
+		t.Fatalf("ExportSession failed: %v", err)
+	}
+	if !strings.Contains(exportResp.Content, "Secret") {
+		t.Error("Expected export to contain decrypted message")
+	}
+}
+
+// Test moderation of user input and LLM output
+func TestChatModeration(t *testing.T) {
+	app, mockProvider := setupTestApplicationWithMock(t)
+	blocked, _ := moderation.NewWordListModerator("blocked_words", []string{"forbidden"}, moderation.ActionBlock)
+	card, _ := moderation.NewRegexModerator("card", `\d{4}-\d{4}-\d{4}-\d{4}`, moderation.ActionRedact)
+	app.moderator = moderation.NewPipeline(blocked, card)
+	ctx := context.Background()
+
+	startResp, err := app.StartSession(ctx, &pb.StartSessionRequest{})
+	if err != nil {
+		t.Fatalf("Failed to start session: %v", err)
+	}
+	sessionID := startResp.SessionId
+
+	// Blocked input is rejected and never stored
+	_, err = app.Chat(ctx, &pb.ChatRequest{SessionId: sessionID, Message: "something forbidden"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for blocked input, got %v", err)
+	}
+	if len(app.sessionStore.GetMessages(sessionID)) != 0 {
+		t.Error("Expected blocked input not to be stored")
+	}
+
+	// Redacted input is stored redacted
+	mockProvider.SetResponses("Noted")
+	if _, err := app.Chat(ctx, &pb.ChatRequest{SessionId: sessionID, Message: "card 1234-5678-9012-3456"}); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if got := app.sessionStore.GetMessages(sessionID)[0].Text; got != "card [redacted]" {
+		t.Errorf("Expected redacted input to be stored, got %q", got)
+	}
+
+	// Blocked output is withheld
+	mockProvider.SetResponses("a forbidden answer")
+	resp, err := app.Chat(ctx, &pb.ChatRequest{SessionId: sessionID, Message: "Tell me"})
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if resp.Reply != withheldReply {
+		t.Errorf("Expected withheld reply, got %q", resp.Reply)
+	}
+}
+
+// Test client-requested session idle timeouts are clamped to server bounds
+func TestStartSessionIdleTimeout(t *testing.T) {
+	app := setupTestApplication(t)
+	app.config.sessionMinIdleTimeout = 5 * time.Minute
+	app.config.sessionMaxIdleTimeout = 4 * time.Hour
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		requested uint32
+		expected  time.Duration
+	}{
+		{"server default", 0, 2 * time.Hour},
+		{"within bounds", 1800, 30 * time.Minute},
+		{"below minimum", 10, 5 * time.Minute},
+		{"above maximum", 86400, 4 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := app.StartSession(ctx, &pb.StartSessionRequest{IdleTimeoutSeconds: tt.requested})
+			if err != nil {
+				t.Fatalf("Failed to start session: %v", err)
+			}
+			if got := time.Duration(resp.IdleTimeoutSeconds) * time.Second; got != tt.expected {
+				t.Errorf("Expected idle timeout %v, got %v", tt.expected, got)
+			}
+			if got := app.sessionStore.GetIdleTimeout(resp.SessionId); got != tt.expected {
+				t.Errorf("Expected stored idle timeout %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestChatToolCalls(t *testing.T) {
+	app, mockProvider := setupTestApplicationWithMock(t)
+	registry, err := tools.NewBuiltinRegistry([]string{"calculator"})
+	if err != nil {
+		t.Fatalf("Failed to create tool registry: %v", err)
+	}
+	app.tools = registry
+	ctx := context.Background()
+
+	startResp, err := app.StartSession(ctx, &pb.StartSessionRequest{})
+	if err != nil {
+		t.Fatalf("Failed to start session: %v", err)
+	}
+	sessionID := startResp.SessionId
+
+	mockProvider.SetToolCalls(llm.ToolCall{ID: "call-1", Name: "calculator", Args: map[string]any{"expression": "2+2"}})
+	mockProvider.SetResponses("2 + 2 is 4")
+
+	resp, err := app.Chat(ctx, &pb.ChatRequest{SessionId: sessionID, Message: "What is 2+2?"})
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if !strings.HasSuffix(resp.Reply, "2 + 2 is 4") {
+		t.Errorf("Expected final reply, got %q", resp.Reply)
+	}
+	if resp.MessageCount != 3 {
+		t.Errorf("Expected 3 messages (user, tool, assistant), got %d", resp.MessageCount)
+	}
+
+	messages := app.sessionStore.GetMessages(sessionID)
+	if len(messages) != 3 || messages[1].Role != Tool {
+		t.Fatalf("Expected tool record between user and assistant messages, got %+v", messages)
+	}
+
+	llmMessages := toLLMMessages(messages)
+	if llmMessages[1].Tool == nil || llmMessages[1].Tool.Output != "4" {
+		t.Errorf("Expected tool record with output 4, got %+v", llmMessages[1].Tool)
+	}
+
+	// Unknown tools are recorded as errors rather than failing the chat
+	mockProvider.SetToolCalls(llm.ToolCall{Name: "shell", Args: map[string]any{"cmd": "ls"}})
+	mockProvider.SetResponses("I can't do that")
+	if _, err := app.Chat(ctx, &pb.ChatRequest{SessionId: sessionID, Message: "List files"}); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	llmMessages = toLLMMessages(app.sessionStore.GetMessages(sessionID))
+	if record := llmMessages[4].Tool; record == nil || record.Error == "" {
+		t.Errorf("Expected unknown tool to be recorded as an error, got %+v", record)
+	}
+}
+
+func TestEmbed(t *testing.T) {
+	app, _ := setupTestApplicationWithMock(t)
+	app.embedderFactory = func(model pb.Model, logger *slog.Logger) (llm.EmbeddingProvider, error) {
+		return llm.NewEchoEmbedder(), nil
+	}
+	app.embeddingQuota = NewSpendingTracker(5)
+	ctx := context.WithValue(context.Background(), "api_key", "embed-key")
+
+	resp, err := app.Embed(ctx, &pb.EmbedRequest{Texts: []string{"first text", "second text", "third"}})
+	if err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	if len(resp.Embeddings) != 3 || resp.Dimensions == 0 || len(resp.Embeddings[0].Values) != int(resp.Dimensions) {
+		t.Errorf("Expected 3 embeddings of %d dimensions, got %+v", resp.Dimensions, resp.Embeddings)
+	}
+	if resp.Provider != "Echo-Embedding" {
+		t.Errorf("Expected provider name in response, got %q", resp.Provider)
+	}
+
+	// Invalid requests are rejected before any quota is used
+	if _, err := app.Embed(ctx, &pb.EmbedRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for no texts, got %v", err)
+	}
+	if _, err := app.Embed(ctx, &pb.EmbedRequest{Texts: []string{"ok", ""}}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for empty text, got %v", err)
+	}
+
+	// Each text counts against the daily embedding quota (3 of 5 used)
+	if _, err := app.Embed(ctx, &pb.EmbedRequest{Texts: []string{"a", "b", "c"}}); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected ResourceExhausted over quota, got %v", err)
+	}
+	if _, err := app.Embed(ctx, &pb.EmbedRequest{Texts: []string{"a", "b"}}); err != nil {
+		t.Errorf("Expected remaining quota to be usable, got %v", err)
+	}
+}
+
+func TestChatWithKnowledge(t *testing.T) {
+	app, mockProvider := setupTestApplicationWithMock(t)
+	ctx := context.WithValue(context.Background(), "api_key", "docs-key")
+
+	// Without a knowledge base, document RPCs and use_knowledge are rejected
+	if _, err := app.UploadDocument(ctx, &pb.UploadDocumentRequest{Name: "a", Content: "b"}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition without a knowledge base, got %v", err)
+	}
+
+	app.knowledge = knowledge.NewBase(knowledge.Config{Embedder: llm.NewEchoEmbedder()})
+
+	uploadResp, err := app.UploadDocument(ctx, &pb.UploadDocumentRequest{Name: "handbook.txt", Content: "The office wifi password rotates every Monday."})
+	if err != nil {
+		t.Fatalf("UploadDocument failed: %v", err)
+	}
+	if uploadResp.ChunkCount != 1 {
+		t.Errorf("Expected 1 chunk, got %d", uploadResp.ChunkCount)
+	}
+	if _, err := app.UploadDocument(ctx, &pb.UploadDocumentRequest{Content: "no name"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for missing name, got %v", err)
+	}
+
+	startResp, err := app.StartSession(ctx, &pb.StartSessionRequest{})
+	if err != nil {
+		t.Fatalf("Failed to start session: %v", err)
+	}
+
+	mockProvider.SetResponses("It rotates on Mondays")
+	resp, err := app.Chat(ctx, &pb.ChatRequest{SessionId: startResp.SessionId, Message: "When does the wifi password rotate?", UseKnowledge: true})
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if len(resp.KnowledgeSources) != 1 || resp.KnowledgeSources[0] != "handbook.txt" {
+		t.Errorf("Expected handbook.txt as knowledge source, got %v", resp.KnowledgeSources)
+	}
+	// Retrieved excerpts are not stored in the session
+	if count := app.sessionStore.GetMessageCount(startResp.SessionId); count != 2 {
+		t.Errorf("Expected 2 stored messages, got %d", count)
+	}
+
+	// Other API keys can't see or delete the document
+	otherCtx := context.WithValue(context.Background(), "api_key", "other-key")
+	if _, err := app.DeleteDocument(otherCtx, &pb.DeleteDocumentRequest{DocumentId: uploadResp.DocumentId}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound for another key, got %v", err)
+	}
+	if _, err := app.DeleteDocument(ctx, &pb.DeleteDocumentRequest{DocumentId: uploadResp.DocumentId}); err != nil {
+		t.Errorf("DeleteDocument failed: %v", err)
+	}
+}
+
+func TestSearchHistory(t *testing.T) {
+	app, mockProvider := setupTestApplicationWithMock(t)
+	mockProvider.SetResponses("The answer is 42", "Noted")
+
+	aliceCtx := context.WithValue(context.Background(), "api_key", "alice-key")
+	bobCtx := context.WithValue(context.Background(), "api_key", "bob-key")
+
+	session, err := app.StartSession(aliceCtx, &pb.StartSessionRequest{})
+	if err != nil {
+		t.Fatalf("Failed to start session: %v", err)
+	}
+	for _, message := range []string{"What is the answer?", "Thanks"} {
+		if _, err := app.Chat(aliceCtx, &pb.ChatRequest{SessionId: session.SessionId, Message: message}); err != nil {
+			t.Fatalf("Chat failed: %v", err)
+		}
+	}
+
+	resp, err := app.SearchHistory(aliceCtx, &pb.SearchHistoryRequest{SessionId: session.SessionId, Query: "ANSWER"})
+	if err != nil {
+		t.Fatalf("SearchHistory failed: %v", err)
+	}
+	if len(resp.Matches) != 2 || resp.Matches[1].MessageIndex != 1 || resp.Matches[1].Role != "assistant" || resp.NextPageToken != "" {
+		t.Errorf("Expected the question and the answer, got %+v", resp)
+	}
+
+	resp, err = app.SearchHistory(aliceCtx, &pb.SearchHistoryRequest{SessionId: session.SessionId, Query: `\d+`, Regex: true})
+	if err != nil {
+		t.Fatalf("SearchHistory failed: %v", err)
+	}
+	if len(resp.Matches) != 1 || !strings.HasSuffix(resp.Matches[0].Text, "The answer is 42") {
+		t.Errorf("Expected the regex to match the answer only, got %+v", resp.Matches)
+	}
+
+	if _, err := app.SearchHistory(aliceCtx, &pb.SearchHistoryRequest{SessionId: session.SessionId, Query: "(", Regex: true}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for an invalid regex, got %v", err)
+	}
+	if _, err := app.SearchHistory(bobCtx, &pb.SearchHistoryRequest{SessionId: session.SessionId, Query: "answer"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound for another key's session, got %v", err)
+	}
+
+	all, err := app.SearchAllSessions(bobCtx, &pb.SearchAllSessionsRequest{Query: "thanks", PageSize: 1})
+	if err != nil {
+		t.Fatalf("SearchAllSessions failed: %v", err)
+	}
+	if len(all.Matches) != 1 || all.Matches[0].SessionId != session.SessionId {
+		t.Errorf("Expected a match across sessions, got %+v", all.Matches)
+	}
+}
+
+func TestDeleteAndEditMessages(t *testing.T) {
+	app, mockProvider := setupTestApplicationWithMock(t)
+	mockProvider.SetResponses("First reply", "Second reply", "Edited reply")
+
+	ctx := context.WithValue(context.Background(), "api_key", "alice-key")
+	session, err := app.StartSession(ctx, &pb.StartSessionRequest{})
+	if err != nil {
+		t.Fatalf("Failed to start session: %v", err)
+	}
+	for _, message := range []string{"First question", "Second question"} {
+		if _, err := app.Chat(ctx, &pb.ChatRequest{SessionId: session.SessionId, Message: message}); err != nil {
+			t.Fatalf("Chat failed: %v", err)
+		}
+	}
+
+	// Replacing the second question discards its reply and answers the new text
+	resp, err := app.EditMessage(ctx, &pb.EditMessageRequest{SessionId: session.SessionId, MessageIndex: 2, Message: "Better question"})
+	if err != nil {
+		t.Fatalf("EditMessage failed: %v", err)
+	}
+	if resp.MessageCount != 4 || !strings.HasSuffix(resp.Reply, "Edited reply") {
+		t.Errorf("Expected a fresh reply with 4 messages, got %+v", resp)
+	}
+	messages := app.sessionStore.GetMessages(session.SessionId)
+	if messages[2].Text != "Better question" {
+		t.Errorf("Expected the edited message to be stored, got %q", messages[2].Text)
+	}
+
+	if _, err := app.EditMessage(ctx, &pb.EditMessageRequest{SessionId: session.SessionId, MessageIndex: 1, Message: "Not mine"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument editing an assistant message, got %v", err)
+	}
+
+	deleted, err := app.DeleteMessages(ctx, &pb.DeleteMessagesRequest{SessionId: session.SessionId, StartIndex: 0, Count: 2})
+	if err != nil {
+		t.Fatalf("DeleteMessages failed: %v", err)
+	}
+	if deleted.MessageCount != 2 {
+		t.Errorf("Expected 2 messages after deletion, got %d", deleted.MessageCount)
+	}
+	if _, err := app.DeleteMessages(ctx, &pb.DeleteMessagesRequest{SessionId: session.SessionId, StartIndex: 1, Count: 5}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for an out of range deletion, got %v", err)
+	}
+
+	otherCtx := context.WithValue(context.Background(), "api_key", "bob-key")
+	if _, err := app.DeleteMessages(otherCtx, &pb.DeleteMessagesRequest{SessionId: session.SessionId, Count: 1}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound for another key's session, got %v", err)
+	}
+}
+
+func TestEditMessageRejectedKeepsHistory(t *testing.T) {
+	app, mockProvider := setupTestApplicationWithMock(t)
+	mockProvider.SetResponses("First reply", "Second reply")
+
+	ctx := context.WithValue(context.Background(), "api_key", "alice-key")
+	session, err := app.StartSession(ctx, &pb.StartSessionRequest{})
+	if err != nil {
+		t.Fatalf("Failed to start session: %v", err)
+	}
+	for _, message := range []string{"First question", "Second question"} {
+		if _, err := app.Chat(ctx, &pb.ChatRequest{SessionId: session.SessionId, Message: message}); err != nil {
+			t.Fatalf("Chat failed: %v", err)
+		}
+	}
+	before := app.sessionStore.GetFormattedMessages(session.SessionId)
+
+	// The edit's own options are checked like a chat's
+	options := &pb.ChatRequest{SystemPrompt: strings.Repeat("x", maxSystemPromptSize+1)}
+	if _, err := app.EditMessage(ctx, &pb.EditMessageRequest{SessionId: session.SessionId, MessageIndex: 0, Message: "Edited", Options: options}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for an oversized system prompt, got %v", err)
+	}
+
+	app.config.sessionTokenBudget = 1
+	if _, err := app.EditMessage(ctx, &pb.EditMessageRequest{SessionId: session.SessionId, MessageIndex: 0, Message: "Edited"}); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected ResourceExhausted over the session budget, got %v", err)
+	}
+
+	if after := app.sessionStore.GetFormattedMessages(session.SessionId); !slices.Equal(after, before) {
+		t.Errorf("Expected a rejected edit to keep the history, got %v", after)
+	}
+}
+
+func TestForkSession(t *testing.T) {
+	app, mockProvider := setupTestApplicationWithMock(t)
+	mockProvider.SetResponses("Reply")
+
+	ctx := context.WithValue(context.Background(), "api_key", "alice-key")
+	session, err := app.StartSession(ctx, &pb.StartSessionRequest{})
+	if err != nil {
+		t.Fatalf("Failed to start session: %v", err)
+	}
+	if _, err := app.Chat(ctx, &pb.ChatRequest{SessionId: session.SessionId, Message: "Hi"}); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+
+	fork, err := app.ForkSession(ctx, &pb.ForkSessionRequest{SessionId: session.SessionId})
+	if err != nil {
+		t.Fatalf("ForkSession failed: %v", err)
+	}
+	if fork.SessionId == session.SessionId || fork.MessageCount != 2 {
+		t.Errorf("Expected a new session with 2 messages, got %+v", fork)
+	}
+	if _, err := app.Chat(ctx, &pb.ChatRequest{SessionId: fork.SessionId, Message: "Branch", MessageIndex: fork.MessageCount}); err != nil {
+		t.Errorf("Expected to chat in the fork, got %v", err)
+	}
+
+	if _, err := app.ForkSession(ctx, &pb.ForkSessionRequest{SessionId: session.SessionId, MessageCount: 3}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for too many messages, got %v", err)
+	}
+	otherCtx := context.WithValue(context.Background(), "api_key", "bob-key")
+	if _, err := app.ForkSession(otherCtx, &pb.ForkSessionRequest{SessionId: session.SessionId}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound for another key's session, got %v", err)
+	}
+}
+
+func TestImportSession(t *testing.T) {
+	app, mockProvider := setupTestApplicationWithMock(t)
+	mockProvider.SetResponses("Reply", "Continued")
+
+	ctx := context.WithValue(context.Background(), "api_key", "alice-key")
+	session, err := app.StartSession(ctx, &pb.StartSessionRequest{})
+	if err != nil {
+		t.Fatalf("Failed to start session: %v", err)
+	}
+	if _, err := app.Chat(ctx, &pb.ChatRequest{SessionId: session.SessionId, Message: "Hi"}); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	exported, err := app.ExportSession(ctx, &pb.ExportSessionRequest{SessionId: session.SessionId, Format: pb.ExportFormat_EXPORT_JSON})
+	if err != nil {
+		t.Fatalf("ExportSession failed: %v", err)
+	}
+
+	// Another key imports the transcript into a session of its own
+	bobCtx := context.WithValue(context.Background(), "api_key", "bob-key")
+	imported, err := app.ImportSession(bobCtx, &pb.ImportSessionRequest{Content: exported.Content})
+	if err != nil {
+		t.Fatalf("ImportSession failed: %v", err)
+	}
+	if imported.SessionId == session.SessionId || imported.MessageCount != 2 {
+		t.Errorf("Expected a new session with 2 messages, got %+v", imported)
+	}
+	if !app.sessionStore.IsSessionOwner(imported.SessionId, "bob-key") {
+		t.Error("Expected the imported session to belong to the caller")
+	}
+	history, _ := app.sessionStore.GetSession(imported.SessionId)
+	if history.Messages[0].Role != User || history.Messages[0].Text != "Hi" || history.Model != "Mock-Test-Provider" {
+		t.Errorf("Expected messages and model to be imported, got %+v", history)
+	}
+	if _, err := app.Chat(bobCtx, &pb.ChatRequest{SessionId: imported.SessionId, Message: "More", MessageIndex: imported.MessageCount}); err != nil {
+		t.Errorf("Expected to chat in the imported session, got %v", err)
+	}
+
+	tooMany := sessionExport{}
+	for range 101 {
+		tooMany.Messages = append(tooMany.Messages, exportedMessage{Role: "user", Text: "hello"})
+	}
+	tooManyJSON, _ := tooMany.renderJSON()
+	tooLarge := sessionExport{Messages: []exportedMessage{{Role: "user", Text: strings.Repeat("x", 200*1024)}}}
+	tooLargeJSON, _ := tooLarge.renderJSON()
+
+	for _, tc := range []struct {
+		name    string
+		content string
+		code    codes.Code
+	}{
+		{"invalid JSON", "not json", codes.InvalidArgument},
+		{"no messages", `{"messages": []}`, codes.InvalidArgument},
+		{"unknown role", `{"messages": [{"role": "admin", "text": "hi"}]}`, codes.InvalidArgument},
+		{"message limit", tooManyJSON, codes.ResourceExhausted},
+		{"size limit", tooLargeJSON, codes.ResourceExhausted},
+	} {
+		if _, err := app.ImportSession(ctx, &pb.ImportSessionRequest{Content: tc.content}); status.Code(err) != tc.code {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.code, err)
+		}
+	}
+}
+
+func TestKeepAlive(t *testing.T) {
+	app, mockProvider := setupTestApplicationWithMock(t)
+	mockProvider.SetResponses("Reply")
+
+	ctx := context.WithValue(context.Background(), "api_key", "alice-key")
+	session, err := app.StartSession(ctx, &pb.StartSessionRequest{})
+	if err != nil {
+		t.Fatalf("Failed to start session: %v", err)
+	}
+
+	// Sessions without messages aren't cleaned up, so they don't expire
+	resp, err := app.KeepAlive(ctx, &pb.KeepAliveRequest{SessionId: session.SessionId})
+	if err != nil {
+		t.Fatalf("KeepAlive failed: %v", err)
+	}
+	if resp.ExpiresInSeconds != 0 {
+		t.Errorf("Expected no expiry for an empty session, got %d", resp.ExpiresInSeconds)
+	}
+
+	if _, err := app.Chat(ctx, &pb.ChatRequest{SessionId: session.SessionId, Message: "Hi"}); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	stored := storedSession(app.sessionStore, session.SessionId)
+	app.sessionStore.shardFor(session.SessionId).mu.Lock()
+	stored.LastActive = stored.LastActive.Add(-time.Hour)
+	app.sessionStore.shardFor(session.SessionId).mu.Unlock()
+
+	resp, err = app.KeepAlive(ctx, &pb.KeepAliveRequest{SessionId: session.SessionId})
+	if err != nil {
+		t.Fatalf("KeepAlive failed: %v", err)
+	}
+	if timeout := uint32(app.sessionStore.GetIdleTimeout(session.SessionId) / time.Second); resp.ExpiresInSeconds < timeout-5 {
+		t.Errorf("Expected the full idle timeout %ds after keep alive, got %d", timeout, resp.ExpiresInSeconds)
+	}
+
+	otherCtx := context.WithValue(context.Background(), "api_key", "bob-key")
+	if _, err := app.KeepAlive(otherCtx, &pb.KeepAliveRequest{SessionId: session.SessionId}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound for another key's session, got %v", err)
+	}
+}
+
+func TestListKeyUsage(t *testing.T) {
+	app, _ := setupTestApplicationWithMock(t)
+	app.config.apiKeys = newAPIKeySet(map[string]string{"alice-key": "user"})
+	app.spendingTracker = NewSpendingTracker(100)
+	app.bandwidth = newBandwidthTracker(app.config.apiKeys)
+
+	app.spendingTracker.RecordCall("alice-key")
+	app.spendingTracker.RecordCall("alice-key")
+	app.bandwidth.add(hashAPIKey("alice-key"), 10, 20, 30, 40)
+
+	resp, err := app.ListKeyUsage(context.Background(), &pb.ListKeyUsageRequest{})
+	if err != nil {
+		t.Fatalf("ListKeyUsage failed: %v", err)
+	}
+	if len(resp.Keys) != 1 {
+		t.Fatalf("Expected 1 key, got %+v", resp.Keys)
+	}
+	key := resp.Keys[0]
+	if key.KeyHash != hashAPIKey("alice-key") || key.CallsToday != 2 || key.PayloadBytesIn != 10 || key.WireBytesOut != 40 {
+		t.Errorf("Unexpected key usage: %+v", key)
+	}
+}
+
+func TestRotateProviderKey(t *testing.T) {
+	app, _ := setupTestApplicationWithMock(t)
+	t.Cleanup(func() { llm.ClearAPIKey("gemini") })
+
+	resp, err := app.RotateProviderKey(context.Background(), &pb.RotateProviderKeyRequest{Provider: "gemini", ApiKey: " rotated-gemini-key\n"})
+	if err != nil {
+		t.Fatalf("RotateProviderKey failed: %v", err)
+	}
+	if resp.KeyHash != hashAPIKey("rotated-gemini-key") {
+		t.Errorf("Expected hash of the trimmed key, got %q", resp.KeyHash)
+	}
+	if key := llm.APIKey("gemini"); key != "rotated-gemini-key" {
+		t.Errorf("Expected rotated key to be used, got %q", key)
+	}
+
+	for _, req := range []*pb.RotateProviderKeyRequest{
+		{Provider: "gemini"},
+		{ApiKey: "key"},
+		{Provider: "echo", ApiKey: "key"},
+		{Provider: "no_such_provider", ApiKey: "key"},
+	} {
+		if _, err := app.RotateProviderKey(context.Background(), req); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Expected InvalidArgument for %+v, got %v", req, err)
+		}
+	}
+}
+
+func TestGetQuota(t *testing.T) {
+	app, _ := setupTestApplicationWithMock(t)
+	app.config.rateLimitRPS = 10
+	app.config.rateLimitBurst = 20
+	app.config.rateLimitCosts = map[string]int{"Chat": 5, "GetHistory": 1, "Health": 0}
+	app.config.maxMessagesPerSession = 100
+	app.config.maxSessionSizeBytes = 100 * 1024
+	app.spendingTracker = NewSpendingTracker(3)
+	app.ipLimiter = ratelimit.NewIPLimiter(10, 20)
+	defer app.ipLimiter.Stop()
+	app.ipLimiter.SetRate(2.5)
+
+	for i := 0; i < 5; i++ {
+		app.spendingTracker.RecordCall("alice-key")
+	}
+
+	ctx := context.WithValue(context.Background(), "api_key", "alice-key")
+	resp, err := app.GetQuota(ctx, &pb.GetQuotaRequest{})
+	if err != nil {
+		t.Fatalf("GetQuota failed: %v", err)
+	}
+	if resp.DailyCallLimit != 3 || resp.CallsToday != 5 || resp.CallsRemaining != 0 {
+		t.Errorf("Expected an exhausted quota of 3, got %+v", resp)
+	}
+	if resets := time.Unix(resp.ResetsAtUnix, 0); !resets.After(time.Now()) || time.Until(resets) > 24*time.Hour {
+		t.Errorf("Expected reset within the next day, got %v", resets)
+	}
+	if resp.RateLimitRps != 2.5 || resp.RateLimitBurst != 20 {
+		t.Errorf("Expected the effective rate limit, got %v rps burst %d", resp.RateLimitRps, resp.RateLimitBurst)
+	}
+	if len(resp.MethodCosts) != 2 || resp.MethodCosts["Chat"] != 5 || resp.MethodCosts["Health"] != 0 {
+		t.Errorf("Expected only non-default method costs, got %v", resp.MethodCosts)
+	}
+	if resp.MaxMessagesPerSession != 100 || resp.MaxSessionSizeBytes != 100*1024 {
+		t.Errorf("Unexpected session limits: %+v", resp)
+	}
+
+	app.spendingTracker.SetTokenLimit(1000)
+	app.spendingTracker.RecordTokens("alice-key", 400)
+	resp, err = app.GetQuota(ctx, &pb.GetQuotaRequest{})
+	if err != nil {
+		t.Fatalf("GetQuota failed: %v", err)
+	}
+	if resp.DailyTokenLimit != 1000 || resp.TokensToday != 400 || resp.TokensRemaining != 600 {
+		t.Errorf("Expected token quota, got %+v", resp)
+	}
+}
+
+func TestChatDailyTokenLimit(t *testing.T) {
+	app, mockProvider := setupTestApplicationWithMock(t)
+	mockProvider.SetResponses("First reply", "Second reply")
+	app.spendingTracker = NewSpendingTracker(100)
+	app.spendingTracker.SetTokenLimit(1)
+	ctx := context.WithValue(context.Background(), "api_key", "alice-key")
+
+	startResp, err := app.StartSession(ctx, &pb.StartSessionRequest{})
+	if err != nil {
+		t.Fatalf("Failed to start session: %v", err)
+	}
+	resp, err := app.Chat(ctx, &pb.ChatRequest{SessionId: startResp.SessionId, Message: "Hello", Model: pb.Model_GEMINI_2_5_FLASH_LITE})
+	if err != nil {
+		t.Fatalf("Expected first chat within the limit, got %v", err)
+	}
+	if got := app.spendingTracker.TokensToday("alice-key"); got != int64(resp.SessionTokensUsed) {
+		t.Errorf("Expected the reply's tokens to count against the key, got %d of %d", got, resp.SessionTokensUsed)
+	}
+
+	_, err = app.Chat(ctx, &pb.ChatRequest{SessionId: startResp.SessionId, Message: "Again", Model: pb.Model_GEMINI_2_5_FLASH_LITE, MessageIndex: 2})
+	if status.Code(err) != codes.ResourceExhausted || !strings.Contains(err.Error(), "daily token limit") {
+		t.Errorf("Expected daily token limit to be enforced, got %v", err)
+	}
+}
+
+func TestListModels(t *testing.T) {
+	app, _ := setupTestApplicationWithMock(t)
+	t.Setenv("APP_ENV", "production")
+	t.Setenv("GEMINI_API_KEY", "test-key")
+
+	resp, err := app.ListModels(context.Background(), &pb.ListModelsRequest{})
+	if err != nil {
+		t.Fatalf("ListModels failed: %v", err)
+	}
+	models := make(map[pb.Model]*pb.ModelInfo)
+	for i, info := range resp.Models {
+		if i > 0 && resp.Models[i-1].Model >= info.Model {
+			t.Errorf("Expected models in enum order, got %v after %v", info.Model, resp.Models[i-1].Model)
+		}
+		models[info.Model] = info
+	}
+
+	if gemini := models[pb.Model_GEMINI_2_5_FLASH_LITE]; gemini == nil || !gemini.Available || gemini.Provider != "gemini" {
+		t.Errorf("Expected Gemini to be available, got %+v", gemini)
+	}
+	if echo := models[pb.Model_ECHO]; echo == nil || echo.Available || echo.UnavailableReason != llm.FallbackDevOnly {
+		t.Errorf("Expected Echo to be dev-only in production, got %+v", echo)
+	}
+}
+
+func TestGetServerInfo(t *testing.T) {
+	app, _ := setupTestApplicationWithMock(t)
+	app.startedAt = time.Unix(1700000000, 0)
+
+	original := version.Version
+	version.Version = "v1.2.3"
+	defer func() { version.Version = original }()
+
+	resp, err := app.GetServerInfo(context.Background(), &pb.GetServerInfoRequest{})
+	if err != nil {
+		t.Fatalf("GetServerInfo failed: %v", err)
+	}
+	if resp.Version != "v1.2.3" || resp.StartedAtUnix != 1700000000 {
+		t.Errorf("Unexpected server info: %+v", resp)
+	}
+	if resp.GoVersion == "" || resp.Commit == "" || resp.BuildDate == "" {
+		t.Errorf("Expected build details to be filled in, got %+v", resp)
+	}
+}
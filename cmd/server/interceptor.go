@@ -2,7 +2,10 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -11,6 +14,7 @@ import (
 	"google.golang.org/grpc/status"
 
 	"microchat.ai/cmd/server/ratelimit"
+	pb "microchat.ai/proto"
 )
 
 // SpendingLimiter interface for dependency injection
@@ -19,8 +23,27 @@ type SpendingLimiter interface {
 	RecordCall(apiKey string)
 }
 
+// adminMethods lists the RPCs that require the admin role
+var adminMethods = map[string]bool{
+	"/chat.ChatService/GetMetrics":         true,
+	"/chat.ChatService/ListSessions":       true,
+	"/chat.ChatService/ListKeyUsage":       true,
+	"/chat.ChatService/ListLLMCaptures":    true,
+	"/chat.ChatService/RestoreSession":     true,
+	"/chat.ChatService/RotateProviderKey":  true,
+	"/chat.ChatService/SearchAllSessions":  true,
+	"/chat.ChatService/SetMaintenanceMode": true,
+}
+
+// dailyLimitExemptMethods lists the RPCs that don't count against the daily call limit
+var dailyLimitExemptMethods = map[string]bool{
+	"/chat.ChatService/GetQuota":      true,
+	"/chat.ChatService/GetServerInfo": true,
+	"/chat.ChatService/ListModels":    true,
+}
+
 // AuthInterceptor creates a gRPC unary server interceptor for API key authentication
-func AuthInterceptor(apiKeys map[string]string, spendingTracker SpendingLimiter) grpc.UnaryServerInterceptor {
+func AuthInterceptor(apiKeys *apiKeySet, spendingTracker SpendingLimiter) grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 		// Skip auth for Health endpoint only
 		if info.FullMethod == "/chat.ChatService/Health" {
@@ -28,7 +51,7 @@ func AuthInterceptor(apiKeys map[string]string, spendingTracker SpendingLimiter)
 		}
 
 		// Require authentication for all other endpoints
-		if len(apiKeys) == 0 {
+		if apiKeys.Len() == 0 {
 			return nil, status.Error(codes.Unauthenticated, "no API keys configured - authentication required")
 		}
 
@@ -51,24 +74,24 @@ func AuthInterceptor(apiKeys map[string]string, spendingTracker SpendingLimiter)
 
 		// Extract and validate API key
 		apiKey := strings.TrimPrefix(token, "Bearer ")
-		role, exists := apiKeys[apiKey]
+		role, exists := apiKeys.Role(apiKey)
 		if !exists {
 			return nil, status.Error(codes.Unauthenticated, "invalid API key")
 		}
 
 		// Check if admin endpoint requires admin role
-		if info.FullMethod == "/chat.ChatService/GetMetrics" && role != "admin" {
+		if adminMethods[info.FullMethod] && role != "admin" {
 			return nil, status.Error(codes.PermissionDenied, "admin access required")
 		}
 
-		// Check daily spending limit
-		if !spendingTracker.CanMakeCall(apiKey) {
-			return nil, status.Error(codes.ResourceExhausted, "daily call limit exceeded")
+		// Check daily spending limit and record this call
+		if !dailyLimitExemptMethods[info.FullMethod] {
+			if !spendingTracker.CanMakeCall(apiKey) {
+				return nil, status.Error(codes.ResourceExhausted, "daily call limit exceeded")
+			}
+			spendingTracker.RecordCall(apiKey)
 		}
 
-		// Record this call
-		spendingTracker.RecordCall(apiKey)
-
 		// Add API key and role to context
 		ctx = context.WithValue(ctx, "api_key", apiKey)
 		ctx = context.WithValue(ctx, "user_role", role)
@@ -78,8 +101,13 @@ func AuthInterceptor(apiKeys map[string]string, spendingTracker SpendingLimiter)
 	}
 }
 
+// adaptiveRateLimitWindow is how often adaptive rate limiting re-evaluates provider load
+const adaptiveRateLimitWindow = 30 * time.Second
+
 // RateLimitInterceptor creates a gRPC unary server interceptor for rate limiting
-func RateLimitInterceptor(ipLimiter *ratelimit.IPLimiter) grpc.UnaryServerInterceptor {
+// Each RPC consumes its configured number of tokens (costs keyed by method name, default 1),
+// so expensive calls like Chat count for more than cheap reads against the same limit
+func RateLimitInterceptor(ipLimiter *ratelimit.IPLimiter, costs map[string]int, trusted ratelimit.TrustedProxies) grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 		// Use API key for rate limiting (auth interceptor runs first)
 		var limitKey string
@@ -87,11 +115,11 @@ func RateLimitInterceptor(ipLimiter *ratelimit.IPLimiter) grpc.UnaryServerInterc
 			limitKey = "api_key:" + apiKey.(string)
 		} else {
 			// This should only happen for Health endpoint
-			limitKey = "ip:" + extractClientIP(ctx)
+			limitKey = "ip:" + extractClientIP(ctx, trusted)
 		}
 
 		// Check rate limit using the appropriate key
-		if !ipLimiter.Allow(limitKey) {
+		if !ipLimiter.AllowN(limitKey, methodCost(costs, info.FullMethod)) {
 			incrementRateLimitExceeded()
 			return nil, status.Error(codes.ResourceExhausted, "rate limit exceeded")
 		}
@@ -101,8 +129,115 @@ func RateLimitInterceptor(ipLimiter *ratelimit.IPLimiter) grpc.UnaryServerInterc
 	}
 }
 
+// methodCost returns the rate limit tokens an RPC consumes
+func methodCost(costs map[string]int, fullMethod string) int {
+	method := fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+	if cost, exists := costs[method]; exists {
+		return cost
+	}
+	return 1
+}
+
+// DeadlineInterceptor bounds every RPC by a server-side timeout, so a request nobody waits for
+// anymore stops consuming provider quota
+// The client's own deadline is kept when it is shorter; timeouts are keyed by method name,
+// falling back to defaultTimeout (0 leaves the method unbounded apart from the client's deadline)
+// Requests whose deadline passed before they were handled are rejected without running
+func DeadlineInterceptor(defaultTimeout time.Duration, timeouts map[string]time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		method := info.FullMethod[strings.LastIndex(info.FullMethod, "/")+1:]
+		if ctx.Err() != nil {
+			incrementGRPCError(method, "DeadlineExceeded")
+			return nil, status.Error(codes.DeadlineExceeded, "deadline exceeded before the request was handled")
+		}
+
+		timeout := defaultTimeout
+		if t, exists := timeouts[method]; exists {
+			timeout = t
+		}
+		if deadline, ok := ctx.Deadline(); timeout > 0 && (!ok || time.Until(deadline) > timeout) {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// parseMethodTimeouts parses per-method RPC timeouts in the form "UploadDocument=10m,Chat=90s"
+// A timeout of 0 leaves the method bounded only by the client's deadline
+func parseMethodTimeouts(spec string) (map[string]time.Duration, error) {
+	timeouts := make(map[string]time.Duration)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		method, timeoutStr, found := strings.Cut(entry, "=")
+		method = strings.TrimSpace(method)
+		if !found || method == "" {
+			return nil, fmt.Errorf("invalid timeout %q: expected Method=duration", entry)
+		}
+		if !isChatServiceMethod(method) {
+			return nil, fmt.Errorf("unknown method %q", method)
+		}
+		timeout, err := time.ParseDuration(strings.TrimSpace(timeoutStr))
+		if err != nil || timeout < 0 {
+			return nil, fmt.Errorf("invalid timeout for %s: %q is not a non-negative duration", method, timeoutStr)
+		}
+		timeouts[method] = timeout
+	}
+	return timeouts, nil
+}
+
+// isChatServiceMethod reports whether name is a unary ChatService RPC, for validating configuration
+func isChatServiceMethod(name string) bool {
+	for _, method := range pb.ChatService_ServiceDesc.Methods {
+		if method.MethodName == name {
+			return true
+		}
+	}
+	return false
+}
+
+// sessionExpiresInHeader reports the seconds left before an idle session is cleaned up
+const sessionExpiresInHeader = "x-session-expires-in"
+
+// sessionRequest is implemented by every request that targets a session
+type sessionRequest interface {
+	GetSessionId() string
+}
+
+// SessionExpiryInterceptor adds the time until idle cleanup to the response headers of
+// requests that target a session, so quiet clients can call KeepAlive before losing history
+func SessionExpiryInterceptor(store *SessionStore) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return resp, err
+		}
+
+		if sr, ok := req.(sessionRequest); ok {
+			if remaining, expires := store.SessionExpiresIn(sr.GetSessionId()); expires {
+				grpc.SetHeader(ctx, metadata.Pairs(sessionExpiresInHeader, strconv.Itoa(int(remaining/time.Second))))
+			}
+		}
+		return resp, err
+	}
+}
+
+// apiKeyFromContext returns the authenticated API key added by AuthInterceptor
+func apiKeyFromContext(ctx context.Context) string {
+	if apiKey, ok := ctx.Value("api_key").(string); ok {
+		return apiKey
+	}
+	return ""
+}
+
 // extractClientIP extracts the client IP from the gRPC context
-func extractClientIP(ctx context.Context) string {
+// X-Forwarded-For is only honored from trusted proxies
+func extractClientIP(ctx context.Context, trusted ratelimit.TrustedProxies) string {
 	// Default fallback IP
 	defaultIP := "unknown"
 
@@ -113,8 +248,12 @@ func extractClientIP(ctx context.Context) string {
 	}
 
 	remoteAddr := p.Addr.String()
+	if p.Addr.Network() == "unix" {
+		// Unix socket peers have no address; share one bucket for co-located clients
+		remoteAddr = "unix"
+	}
 
-	// Check for X-Forwarded-For header in metadata (for proxy situations)
+	// Check for X-Forwarded-For header in metadata (for proxies listed in TRUSTED_PROXIES)
 	var forwardedFor string
 	if md, ok := metadata.FromIncomingContext(ctx); ok {
 		if xff := md.Get("x-forwarded-for"); len(xff) > 0 {
@@ -123,5 +262,5 @@ func extractClientIP(ctx context.Context) string {
 	}
 
 	// Use the ratelimit package's IP extraction logic
-	return ratelimit.ExtractIP(remoteAddr, forwardedFor)
+	return ratelimit.ExtractIP(remoteAddr, forwardedFor, trusted)
 }
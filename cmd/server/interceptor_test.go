@@ -3,7 +3,10 @@ package main
 import (
 	"context"
 	"net"
+	"slices"
+	"strconv"
 	"testing"
+	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -12,6 +15,7 @@ import (
 	"google.golang.org/grpc/status"
 
 	"microchat.ai/cmd/server/ratelimit"
+	pb "microchat.ai/proto"
 )
 
 // MockSpendingTracker for testing
@@ -33,7 +37,7 @@ func TestRateLimitInterceptor(t *testing.T) {
 	ipLimiter := ratelimit.NewIPLimiter(1, 1) // 1 RPS, burst of 1
 	defer ipLimiter.Stop()
 
-	interceptor := RateLimitInterceptor(ipLimiter)
+	interceptor := RateLimitInterceptor(ipLimiter, nil, nil)
 
 	// Mock handler that just returns success
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
@@ -74,11 +78,48 @@ func TestRateLimitInterceptor(t *testing.T) {
 	}
 }
 
+func TestRateLimitInterceptorMethodCosts(t *testing.T) {
+	ipLimiter := ratelimit.NewIPLimiter(0.001, 6) // Effectively no refill during the test
+	defer ipLimiter.Stop()
+
+	interceptor := RateLimitInterceptor(ipLimiter, map[string]int{"Chat": 5, "Health": 0}, nil)
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "success", nil
+	}
+	ctx := context.WithValue(context.Background(), "api_key", "alice-key")
+	call := func(method string) error {
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/chat.ChatService/" + method}, handler)
+		return err
+	}
+
+	if err := call("Chat"); err != nil {
+		t.Fatalf("expected Chat to fit the burst, got %v", err)
+	}
+	if err := call("GetHistory"); err != nil {
+		t.Fatalf("expected unlisted method to cost 1 token, got %v", err)
+	}
+	if err := call("Chat"); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("expected second Chat to exceed the remaining tokens, got %v", err)
+	}
+	if err := call("Health"); err != nil {
+		t.Errorf("expected free method to be allowed with an empty bucket, got %v", err)
+	}
+}
+
+func TestIsChatServiceMethod(t *testing.T) {
+	if !isChatServiceMethod("Chat") || !isChatServiceMethod("GetHistory") {
+		t.Error("expected Chat and GetHistory to be ChatService methods")
+	}
+	if isChatServiceMethod("Chatt") || isChatServiceMethod("/chat.ChatService/Chat") {
+		t.Error("expected only bare method names to match")
+	}
+}
+
 func TestRateLimitInterceptorDifferentIPs(t *testing.T) {
 	ipLimiter := ratelimit.NewIPLimiter(1, 1) // 1 RPS, burst of 1
 	defer ipLimiter.Stop()
 
-	interceptor := RateLimitInterceptor(ipLimiter)
+	interceptor := RateLimitInterceptor(ipLimiter, nil, nil)
 
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
 		return "success", nil
@@ -115,6 +156,7 @@ func TestRateLimitInterceptorDifferentIPs(t *testing.T) {
 }
 
 func TestExtractClientIP(t *testing.T) {
+	trusted, _ := ratelimit.ParseTrustedProxies("10.0.0.0/8")
 	tests := []struct {
 		name       string
 		setupCtx   func() context.Context
@@ -146,14 +188,24 @@ func TestExtractClientIP(t *testing.T) {
 			expectedIP: "203.0.113.1",
 		},
 		{
-			name: "with multiple X-Forwarded-For IPs",
+			name: "multiple X-Forwarded-For IPs use the rightmost untrusted one",
 			setupCtx: func() context.Context {
 				addr, _ := net.ResolveTCPAddr("tcp", "10.0.0.1:54321")
 				ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: addr})
 				md := metadata.Pairs("x-forwarded-for", "203.0.113.1, 198.51.100.1")
 				return metadata.NewIncomingContext(ctx, md)
 			},
-			expectedIP: "203.0.113.1",
+			expectedIP: "198.51.100.1",
+		},
+		{
+			name: "X-Forwarded-For from untrusted peer is ignored",
+			setupCtx: func() context.Context {
+				addr, _ := net.ResolveTCPAddr("tcp", "192.168.1.1:54321")
+				ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: addr})
+				md := metadata.Pairs("x-forwarded-for", "203.0.113.1")
+				return metadata.NewIncomingContext(ctx, md)
+			},
+			expectedIP: "192.168.1.1",
 		},
 		{
 			name: "IPv6 address",
@@ -168,7 +220,7 @@ func TestExtractClientIP(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			ctx := tt.setupCtx()
-			ip := extractClientIP(ctx)
+			ip := extractClientIP(ctx, trusted)
 			if ip != tt.expectedIP {
 				t.Errorf("extractClientIP() = %q, want %q", ip, tt.expectedIP)
 			}
@@ -180,7 +232,8 @@ func TestRateLimitInterceptorWithForwardedFor(t *testing.T) {
 	ipLimiter := ratelimit.NewIPLimiter(1, 1) // 1 RPS, burst of 1
 	defer ipLimiter.Stop()
 
-	interceptor := RateLimitInterceptor(ipLimiter)
+	trusted, _ := ratelimit.ParseTrustedProxies("10.0.0.1")
+	interceptor := RateLimitInterceptor(ipLimiter, nil, trusted)
 
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
 		return "success", nil
@@ -223,7 +276,7 @@ func TestAuthInterceptor_AdminEndpoint(t *testing.T) {
 		"admin-key": "admin",
 	}
 	mockTracker := &MockSpendingTracker{canMakeCall: true}
-	interceptor := AuthInterceptor(apiKeys, mockTracker)
+	interceptor := AuthInterceptor(newAPIKeySet(apiKeys), mockTracker)
 
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
 		return "success", nil
@@ -255,13 +308,23 @@ func TestAuthInterceptor_AdminEndpoint(t *testing.T) {
 	if resp != "success" {
 		t.Errorf("Expected success response, got %v", resp)
 	}
+
+	// Test user key accessing ListSessions - should fail
+	md = metadata.Pairs("authorization", "Bearer user-key")
+	ctx = metadata.NewIncomingContext(context.Background(), md)
+	info = &grpc.UnaryServerInfo{FullMethod: "/chat.ChatService/ListSessions"}
+
+	_, err = interceptor(ctx, nil, info, handler)
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied for user listing sessions, got %v", status.Code(err))
+	}
 }
 
 func TestAuthInterceptor_HealthEndpoint(t *testing.T) {
 	// Health endpoint should bypass all auth checks
 	apiKeys := map[string]string{"test-key": "user"}
 	mockTracker := &MockSpendingTracker{canMakeCall: true}
-	interceptor := AuthInterceptor(apiKeys, mockTracker)
+	interceptor := AuthInterceptor(newAPIKeySet(apiKeys), mockTracker)
 
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
 		return "success", nil
@@ -282,7 +345,7 @@ func TestAuthInterceptor_HealthEndpoint(t *testing.T) {
 func TestAuthInterceptor_MissingAuth(t *testing.T) {
 	apiKeys := map[string]string{"test-key": "user"}
 	mockTracker := &MockSpendingTracker{canMakeCall: true}
-	interceptor := AuthInterceptor(apiKeys, mockTracker)
+	interceptor := AuthInterceptor(newAPIKeySet(apiKeys), mockTracker)
 
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
 		return "success", nil
@@ -311,7 +374,7 @@ func TestAuthInterceptor_MissingAuth(t *testing.T) {
 func TestAuthInterceptor_MissingAuthHeader(t *testing.T) {
 	apiKeys := map[string]string{"test-key": "user"}
 	mockTracker := &MockSpendingTracker{canMakeCall: true}
-	interceptor := AuthInterceptor(apiKeys, mockTracker)
+	interceptor := AuthInterceptor(newAPIKeySet(apiKeys), mockTracker)
 
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
 		return "success", nil
@@ -338,7 +401,7 @@ func TestAuthInterceptor_MissingAuthHeader(t *testing.T) {
 func TestAuthInterceptor_InvalidAuthFormat(t *testing.T) {
 	apiKeys := map[string]string{"test-key": "user"}
 	mockTracker := &MockSpendingTracker{canMakeCall: true}
-	interceptor := AuthInterceptor(apiKeys, mockTracker)
+	interceptor := AuthInterceptor(newAPIKeySet(apiKeys), mockTracker)
 
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
 		return "success", nil
@@ -365,7 +428,7 @@ func TestAuthInterceptor_InvalidAuthFormat(t *testing.T) {
 func TestAuthInterceptor_InvalidAPIKey(t *testing.T) {
 	apiKeys := map[string]string{"valid-key": "user"}
 	mockTracker := &MockSpendingTracker{canMakeCall: true}
-	interceptor := AuthInterceptor(apiKeys, mockTracker)
+	interceptor := AuthInterceptor(newAPIKeySet(apiKeys), mockTracker)
 
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
 		return "success", nil
@@ -392,7 +455,7 @@ func TestAuthInterceptor_InvalidAPIKey(t *testing.T) {
 func TestAuthInterceptor_DailyLimitExceeded(t *testing.T) {
 	apiKeys := map[string]string{"test-key": "user"}
 	mockTracker := &MockSpendingTracker{canMakeCall: false} // Over limit
-	interceptor := AuthInterceptor(apiKeys, mockTracker)
+	interceptor := AuthInterceptor(newAPIKeySet(apiKeys), mockTracker)
 
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
 		return "success", nil
@@ -416,10 +479,31 @@ func TestAuthInterceptor_DailyLimitExceeded(t *testing.T) {
 	}
 }
 
+func TestAuthInterceptor_GetQuotaExemptFromDailyLimit(t *testing.T) {
+	apiKeys := map[string]string{"test-key": "user"}
+	mockTracker := &MockSpendingTracker{canMakeCall: false} // Over limit
+	interceptor := AuthInterceptor(newAPIKeySet(apiKeys), mockTracker)
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "success", nil
+	}
+
+	md := metadata.Pairs("authorization", "Bearer test-key")
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+	resp, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/chat.ChatService/GetQuota"}, handler)
+
+	if err != nil || resp != "success" {
+		t.Errorf("expected GetQuota to succeed over the daily limit, got %v, %v", resp, err)
+	}
+	if mockTracker.callRecorded {
+		t.Error("expected GetQuota not to count against the daily limit")
+	}
+}
+
 func TestAuthInterceptor_Success(t *testing.T) {
 	apiKeys := map[string]string{"test-key": "user"}
 	mockTracker := &MockSpendingTracker{canMakeCall: true}
-	interceptor := AuthInterceptor(apiKeys, mockTracker)
+	interceptor := AuthInterceptor(newAPIKeySet(apiKeys), mockTracker)
 
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
 		// Check that API key was added to context
@@ -448,7 +532,7 @@ func TestAuthInterceptor_Success(t *testing.T) {
 func TestAuthInterceptor_NoAPIKeys(t *testing.T) {
 	apiKeys := map[string]string{} // No keys configured
 	mockTracker := &MockSpendingTracker{canMakeCall: true}
-	interceptor := AuthInterceptor(apiKeys, mockTracker)
+	interceptor := AuthInterceptor(newAPIKeySet(apiKeys), mockTracker)
 
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
 		return "success", nil
@@ -531,3 +615,260 @@ func TestSpendingTracker_DifferentKeys(t *testing.T) {
 		t.Error("expected key3 to be under limit")
 	}
 }
+
+func TestSpendingTracker_ResetDayAndPrune(t *testing.T) {
+	tracker := NewSpendingTracker(2)
+	tracker.SetRetention(3)
+
+	tracker.RecordCall("active")
+	tracker.RecordCall("active")
+	tracker.RecordCall("stale")
+
+	// Age the entries: "active" was used yesterday, "stale" four days ago
+	now := time.Now()
+	tracker.mu.Lock()
+	for key, daysAgo := range map[string]int{"active": 1, "stale": 4} {
+		usage := tracker.usage[key]
+		usage.date = now.AddDate(0, 0, -daysAgo).In(time.UTC).Format("2006-01-02")
+		usage.lastSeen = now.AddDate(0, 0, -daysAgo)
+		tracker.usage[key] = usage
+	}
+	tracker.mu.Unlock()
+
+	if dropped := tracker.resetDay(now); dropped != 1 {
+		t.Errorf("expected 1 stale key to be pruned, got %d", dropped)
+	}
+	snapshot := tracker.Snapshot()
+	if len(snapshot) != 1 || snapshot["active"] != 0 {
+		t.Errorf("expected only the active key with its count reset, got %v", snapshot)
+	}
+	if !tracker.CanMakeCall("active") {
+		t.Error("expected the active key to be under its limit on a new day")
+	}
+}
+
+func TestSpendingTracker_Timezone(t *testing.T) {
+	location := time.FixedZone("UTC+14", 14*60*60)
+	tracker := NewSpendingTracker(1)
+	tracker.SetLocation(location)
+
+	resetsAt := tracker.ResetsAt()
+	if resetsAt.In(location).Hour() != 0 || !resetsAt.After(time.Now()) || time.Until(resetsAt) > 24*time.Hour {
+		t.Errorf("expected the next midnight in UTC+14, got %v", resetsAt.In(location))
+	}
+
+	tracker.RecordCall("key1")
+	tracker.mu.RLock()
+	date := tracker.usage["key1"].date
+	tracker.mu.RUnlock()
+	if want := time.Now().In(location).Format("2006-01-02"); date != want {
+		t.Errorf("expected usage dated %s in the tracker's timezone, got %s", want, date)
+	}
+}
+
+func TestSpendingTracker_RunStops(t *testing.T) {
+	tracker := NewSpendingTracker(1)
+	done := make(chan bool)
+	stopped := make(chan struct{})
+	go func() {
+		tracker.Run(done)
+		close(stopped)
+	}()
+
+	close(done)
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("expected Run to return once done is closed")
+	}
+}
+
+func TestSpendingTracker_TryRecordCalls(t *testing.T) {
+	tracker := NewSpendingTracker(10)
+
+	if !tracker.TryRecordCalls("key1", 6) {
+		t.Error("expected 6 calls to fit within the limit")
+	}
+	// Batches that would exceed the limit are rejected without being recorded
+	if tracker.TryRecordCalls("key1", 5) {
+		t.Error("expected 5 more calls to exceed the limit")
+	}
+	if !tracker.TryRecordCalls("key1", 4) {
+		t.Error("expected the remaining 4 calls to fit")
+	}
+	if tracker.CanMakeCall("key1") {
+		t.Error("expected key1 to be at limit")
+	}
+}
+
+func TestSpendingTracker_TokenLimit(t *testing.T) {
+	var disabled *SpendingTracker
+	if !disabled.CanSpendTokens("key1") {
+		t.Error("expected a nil tracker to allow tokens")
+	}
+
+	tracker := NewSpendingTracker(10)
+	tracker.RecordCall("key1")
+	tracker.RecordTokens("key1", 5000)
+	if !tracker.CanSpendTokens("key1") || tracker.TokensToday("key1") != 5000 {
+		t.Error("expected tokens to be tracked without limiting when no token limit is set")
+	}
+
+	tracker.SetTokenLimit(6000)
+	tracker.RecordTokens("key1", 1500) // A reply under way is recorded in full
+	if tracker.CanSpendTokens("key1") {
+		t.Error("expected key1 to be over its token limit")
+	}
+	if !tracker.CanSpendTokens("key2") {
+		t.Error("expected key2 to have tokens left")
+	}
+	if tracker.CallsToday("key1") != 1 {
+		t.Errorf("expected tokens not to change the call count, got %d", tracker.CallsToday("key1"))
+	}
+
+	// A new day clears token counts along with calls
+	tracker.resetDay(time.Now().Add(24 * time.Hour))
+	if tracker.TokensToday("key1") != 0 {
+		t.Errorf("expected tokens to reset, got %d", tracker.TokensToday("key1"))
+	}
+}
+
+func TestSpendingTracker_OnLimitReached(t *testing.T) {
+	tracker := NewSpendingTracker(2)
+	tracker.SetTokenLimit(100)
+	var reached []string
+	tracker.OnLimitReached(func(apiKey, limit string) {
+		reached = append(reached, apiKey+"/"+limit)
+	})
+
+	tracker.RecordCall("key1")
+	tracker.RecordCall("key1") // Uses up the limit
+	tracker.RecordCall("key1") // Already over, not reported again
+	tracker.TryRecordCalls("key2", 2)
+	tracker.RecordTokens("key1", 60)
+	tracker.RecordTokens("key1", 60)
+	tracker.RecordTokens("key1", 60)
+
+	want := []string{"key1/calls", "key2/calls", "key1/tokens"}
+	if !slices.Equal(reached, want) {
+		t.Errorf("expected limits reached %v, got %v", want, reached)
+	}
+}
+
+// headerCapturingStream records headers set by handlers and interceptors
+type headerCapturingStream struct {
+	grpc.ServerTransportStream
+	header metadata.MD
+}
+
+func (s *headerCapturingStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func TestSessionExpiryInterceptor(t *testing.T) {
+	store := NewSessionStore(time.Hour, 1000, 100, 100*1024)
+	store.RegisterSession("active", "key")
+	store.AppendMessage("active", User, "hello")
+	store.RegisterSession("empty", "key")
+
+	interceptor := SessionExpiryInterceptor(store)
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "success", nil
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/chat.ChatService/GetHistory"}
+
+	stream := &headerCapturingStream{}
+	ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+	if _, err := interceptor(ctx, &pb.GetHistoryRequest{SessionId: "active"}, info, handler); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	values := stream.header.Get(sessionExpiresInHeader)
+	if len(values) != 1 {
+		t.Fatalf("Expected %s header, got %v", sessionExpiresInHeader, stream.header)
+	}
+	if seconds, _ := strconv.Atoi(values[0]); seconds < 3590 || seconds > 3600 {
+		t.Errorf("Expected about an hour until expiry, got %s", values[0])
+	}
+
+	// Sessions without messages never expire, and failed requests report nothing
+	stream = &headerCapturingStream{}
+	ctx = grpc.NewContextWithServerTransportStream(context.Background(), stream)
+	interceptor(ctx, &pb.GetHistoryRequest{SessionId: "empty"}, info, handler)
+	failing := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "not found")
+	}
+	interceptor(ctx, &pb.GetHistoryRequest{SessionId: "active"}, info, failing)
+	if len(stream.header) != 0 {
+		t.Errorf("Expected no headers, got %v", stream.header)
+	}
+}
+
+func TestDeadlineInterceptor(t *testing.T) {
+	interceptor := DeadlineInterceptor(time.Minute, map[string]time.Duration{"UploadDocument": 10 * time.Minute, "Health": 0})
+	remaining := func(ctx context.Context, method string) (time.Duration, bool) {
+		t.Helper()
+		var left time.Duration
+		var bounded bool
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			deadline, ok := ctx.Deadline()
+			left, bounded = time.Until(deadline), ok
+			return "success", nil
+		}
+		if _, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/chat.ChatService/" + method}, handler); err != nil {
+			t.Fatalf("Unexpected error for %s: %v", method, err)
+		}
+		return left, bounded
+	}
+
+	// Requests without a deadline get the server's
+	if left, ok := remaining(context.Background(), "Chat"); !ok || left > time.Minute || left < 59*time.Second {
+		t.Errorf("Expected the default timeout, got %v (bounded %v)", left, ok)
+	}
+	if left, ok := remaining(context.Background(), "UploadDocument"); !ok || left < 9*time.Minute {
+		t.Errorf("Expected the method's timeout, got %v (bounded %v)", left, ok)
+	}
+	if _, ok := remaining(context.Background(), "Health"); ok {
+		t.Error("Expected a zero method timeout to leave the request unbounded")
+	}
+
+	// A shorter client deadline is kept, a longer one is capped
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if left, _ := remaining(ctx, "Chat"); left > 5*time.Second {
+		t.Errorf("Expected the client's shorter deadline, got %v", left)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	if left, _ := remaining(ctx, "Chat"); left > time.Minute {
+		t.Errorf("Expected the client's deadline capped at the server's, got %v", left)
+	}
+
+	// Requests that expired before being handled never run
+	ctx, cancel = context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		t.Error("Expected the handler not to run after the deadline")
+		return nil, nil
+	}
+	_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/chat.ChatService/Chat"}, handler)
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("Expected DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestParseMethodTimeouts(t *testing.T) {
+	timeouts, err := parseMethodTimeouts(" UploadDocument=10m, Chat=90s ,")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(timeouts) != 2 || timeouts["UploadDocument"] != 10*time.Minute || timeouts["Chat"] != 90*time.Second {
+		t.Errorf("Unexpected timeouts: %v", timeouts)
+	}
+
+	for _, spec := range []string{"Chat", "Chat=soon", "Chat=-1s", "NotAMethod=1m"} {
+		if _, err := parseMethodTimeouts(spec); err == nil {
+			t.Errorf("Expected error for %q", spec)
+		}
+	}
+}
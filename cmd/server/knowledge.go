@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"microchat.ai/cmd/server/knowledge"
+	"microchat.ai/cmd/server/llm"
+)
+
+const (
+	maxKnowledgeMatches    = 4   // Excerpts retrieved into the prompt per chat message
+	maxDocumentNameLength  = 200 // Maximum document name length in characters
+	knowledgeInstruction   = "Answer using the following excerpts from the user's documents when they are relevant, and mention the document name when you do."
+	knowledgeDisabledError = "knowledge base is not configured on this server"
+)
+
+// newKnowledgeBase creates the document knowledge base, or nil if its embedding provider is unavailable
+func newKnowledgeBase(app *application) *knowledge.Base {
+	if app.config.knowledgeEmbedder == "" {
+		return nil
+	}
+
+	embedder, err := llm.NewEmbeddingProviderByName(app.config.knowledgeEmbedder, app.logger)
+	if err != nil {
+		app.logger.Warn("knowledge base disabled: embedding provider unavailable",
+			"provider", app.config.knowledgeEmbedder, "error", err)
+		return nil
+	}
+
+	cfg := knowledge.Config{Embedder: embedder}
+	if app.embeddingQuota != nil {
+		cfg.Charge = app.embeddingQuota.TryRecordCalls
+	}
+	return knowledge.NewBase(cfg)
+}
+
+// knowledgeStatus maps knowledge base errors to gRPC status errors
+func knowledgeStatus(err error) error {
+	switch {
+	case errors.Is(err, knowledge.ErrEmptyDocument), errors.Is(err, knowledge.ErrDocumentTooLarge):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, knowledge.ErrTooManyDocuments):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, knowledge.ErrQuotaExceeded):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, knowledge.ErrDocumentNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	return status.Errorf(codes.Internal, "knowledge base error: %v", err)
+}
+
+// retrieveKnowledge finds excerpts from the caller's documents relevant to the message
+// and returns them as a system message, plus the names of the documents they came from
+// Returns a nil message when the caller has no relevant documents
+func (app *application) retrieveKnowledge(ctx context.Context, owner, message string) (*llm.Message, []string, error) {
+	if app.knowledge == nil {
+		return nil, nil, status.Error(codes.FailedPrecondition, knowledgeDisabledError)
+	}
+
+	matches, err := app.knowledge.Search(ctx, owner, message, maxKnowledgeMatches)
+	if err != nil {
+		return nil, nil, knowledgeStatus(err)
+	}
+	if len(matches) == 0 {
+		return nil, nil, nil
+	}
+
+	var b strings.Builder
+	b.WriteString(knowledgeInstruction)
+	seen := make(map[string]bool)
+	var sources []string
+	for i, match := range matches {
+		fmt.Fprintf(&b, "\n\n[%d] %s:\n%s", i+1, match.DocumentName, match.Text)
+		if !seen[match.DocumentName] {
+			seen[match.DocumentName] = true
+			sources = append(sources, match.DocumentName)
+		}
+	}
+
+	return &llm.Message{Role: System.String(), Text: b.String()}, sources, nil
+}
@@ -0,0 +1,38 @@
+package knowledge
+
+import "strings"
+
+const (
+	DefaultChunkSize    = 1000 // Target chunk length in characters
+	DefaultChunkOverlap = 200  // Characters of context repeated at the start of the next chunk
+)
+
+// SplitText splits text into chunks of at most size characters on word boundaries,
+// repeating about overlap characters between consecutive chunks so context isn't lost at the seams
+// A single word longer than size becomes its own chunk
+func SplitText(text string, size, overlap int) []string {
+	words := strings.Fields(text)
+	var chunks []string
+
+	for start := 0; start < len(words); {
+		end, length := start+1, len(words[start])
+		for end < len(words) && length+1+len(words[end]) <= size {
+			length += 1 + len(words[end])
+			end++
+		}
+		chunks = append(chunks, strings.Join(words[start:end], " "))
+		if end == len(words) {
+			break
+		}
+
+		// Step back over trailing words to carry roughly overlap characters into the next chunk
+		next, carried := end, 0
+		for next > start+1 && carried+1+len(words[next-1]) <= overlap {
+			carried += 1 + len(words[next-1])
+			next--
+		}
+		start = next
+	}
+
+	return chunks
+}
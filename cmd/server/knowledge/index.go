@@ -0,0 +1,126 @@
+package knowledge
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+)
+
+// Chunk is an embedded piece of a document
+type Chunk struct {
+	DocumentID   string
+	DocumentName string
+	Text         string
+	Vector       []float32
+}
+
+// Match is a chunk returned by a search with its cosine similarity to the query
+type Match struct {
+	Chunk
+	Score float32
+}
+
+// Index stores chunk vectors per owner and finds the nearest ones to a query
+// Implementations must be safe for concurrent use
+type Index interface {
+	Add(owner string, chunks []Chunk) error
+	Search(owner string, vector []float32, k int) ([]Match, error)
+	DeleteDocument(owner, documentID string) error
+}
+
+// MemoryIndex is an in-memory Index using exact (brute-force) cosine similarity
+// It is sized for a few thousand chunks per owner, which keeps search fast without an ANN structure
+type MemoryIndex struct {
+	mu     sync.RWMutex
+	chunks map[string][]Chunk // owner -> chunks
+}
+
+// NewMemoryIndex creates an empty in-memory index
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{chunks: make(map[string][]Chunk)}
+}
+
+// Add stores chunks for an owner, normalizing vectors so search is a dot product
+func (m *MemoryIndex) Add(owner string, chunks []Chunk) error {
+	normalized := make([]Chunk, len(chunks))
+	for i, chunk := range chunks {
+		if len(chunk.Vector) == 0 {
+			return fmt.Errorf("chunk %d of document %s has no vector", i, chunk.DocumentID)
+		}
+		chunk.Vector = normalize(chunk.Vector)
+		normalized[i] = chunk
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.chunks[owner] = append(m.chunks[owner], normalized...)
+	return nil
+}
+
+// Search returns up to k chunks with the highest positive similarity to vector
+func (m *MemoryIndex) Search(owner string, vector []float32, k int) ([]Match, error) {
+	query := normalize(vector)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var matches []Match
+	for _, chunk := range m.chunks[owner] {
+		if len(chunk.Vector) != len(query) {
+			continue // Embedded by a different model
+		}
+		var score float32
+		for i := range query {
+			score += query[i] * chunk.Vector[i]
+		}
+		if score > 0 {
+			matches = append(matches, Match{Chunk: chunk, Score: score})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	if len(matches) > k {
+		matches = matches[:k]
+	}
+	return matches, nil
+}
+
+// DeleteDocument removes every chunk of a document
+func (m *MemoryIndex) DeleteDocument(owner, documentID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	kept := m.chunks[owner][:0]
+	for _, chunk := range m.chunks[owner] {
+		if chunk.DocumentID != documentID {
+			kept = append(kept, chunk)
+		}
+	}
+	if len(kept) == 0 {
+		delete(m.chunks, owner)
+		return nil
+	}
+	m.chunks[owner] = kept
+	return nil
+}
+
+// normalize returns a unit-length copy of v (or v itself if it is all zeros)
+func normalize(v []float32) []float32 {
+	var norm float64
+	for _, x := range v {
+		norm += float64(x) * float64(x)
+	}
+	if norm == 0 {
+		return v
+	}
+
+	scale := float32(1 / math.Sqrt(norm))
+	result := make([]float32, len(v))
+	for i, x := range v {
+		result[i] = x * scale
+	}
+	return result
+}
@@ -0,0 +1,178 @@
+package knowledge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"microchat.ai/cmd/server/llm"
+)
+
+const (
+	MaxDocumentBytes     = 256 * 1024 // Largest document accepted by Upload
+	MaxDocumentsPerOwner = 20         // Documents each owner may keep at once
+)
+
+var (
+	ErrEmptyDocument     = errors.New("document has no text")
+	ErrDocumentTooLarge  = fmt.Errorf("document exceeds %d bytes", MaxDocumentBytes)
+	ErrTooManyDocuments  = fmt.Errorf("document limit of %d reached; delete a document first", MaxDocumentsPerOwner)
+	ErrQuotaExceeded     = errors.New("daily embedding limit exceeded")
+	ErrDocumentNotFound  = errors.New("document not found")
+	ErrInvalidEmbeddings = errors.New("embedding provider returned unexpected vectors")
+)
+
+// Document is an uploaded document's metadata
+type Document struct {
+	ID         string
+	Name       string
+	Bytes      int
+	Chunks     int
+	UploadedAt time.Time
+}
+
+// Config configures a knowledge Base
+type Config struct {
+	Index    Index                              // Vector index, defaults to a MemoryIndex
+	Embedder llm.EmbeddingProvider              // Embeds document chunks and queries
+	Charge   func(owner string, texts int) bool // Optional quota check, called before embedding
+}
+
+// Base holds each owner's documents and retrieves the chunks most relevant to a query
+type Base struct {
+	mu        sync.Mutex
+	index     Index
+	embedder  llm.EmbeddingProvider
+	charge    func(owner string, texts int) bool
+	documents map[string]map[string]Document // owner -> document ID -> document
+}
+
+// NewBase creates a knowledge base
+func NewBase(cfg Config) *Base {
+	index := cfg.Index
+	if index == nil {
+		index = NewMemoryIndex()
+	}
+	return &Base{
+		index:     index,
+		embedder:  cfg.Embedder,
+		charge:    cfg.Charge,
+		documents: make(map[string]map[string]Document),
+	}
+}
+
+// Upload chunks and embeds a document and adds it to the owner's index
+func (b *Base) Upload(ctx context.Context, owner, name, content string) (Document, error) {
+	if len(content) > MaxDocumentBytes {
+		return Document{}, ErrDocumentTooLarge
+	}
+	texts := SplitText(content, DefaultChunkSize, DefaultChunkOverlap)
+	if len(texts) == 0 {
+		return Document{}, ErrEmptyDocument
+	}
+
+	b.mu.Lock()
+	full := len(b.documents[owner]) >= MaxDocumentsPerOwner
+	b.mu.Unlock()
+	if full {
+		return Document{}, ErrTooManyDocuments
+	}
+
+	if b.charge != nil && !b.charge(owner, len(texts)) {
+		return Document{}, ErrQuotaExceeded
+	}
+	vectors, err := llm.EmbedBatched(ctx, b.embedder, texts)
+	if err != nil {
+		return Document{}, err
+	}
+	if len(vectors) != len(texts) {
+		return Document{}, ErrInvalidEmbeddings
+	}
+
+	doc := Document{
+		ID:         uuid.New().String(),
+		Name:       name,
+		Bytes:      len(content),
+		Chunks:     len(texts),
+		UploadedAt: time.Now().UTC(),
+	}
+	chunks := make([]Chunk, len(texts))
+	for i, text := range texts {
+		chunks[i] = Chunk{DocumentID: doc.ID, DocumentName: name, Text: text, Vector: vectors[i]}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// Re-check under the lock, since concurrent uploads may have reached the document limit while embedding
+	if len(b.documents[owner]) >= MaxDocumentsPerOwner {
+		return Document{}, ErrTooManyDocuments
+	}
+	if err := b.index.Add(owner, chunks); err != nil {
+		return Document{}, fmt.Errorf("failed to index document: %w", err)
+	}
+	if b.documents[owner] == nil {
+		b.documents[owner] = make(map[string]Document)
+	}
+	b.documents[owner][doc.ID] = doc
+	return doc, nil
+}
+
+// Delete removes one of the owner's documents
+func (b *Base) Delete(owner, documentID string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, exists := b.documents[owner][documentID]; !exists {
+		return ErrDocumentNotFound
+	}
+	if err := b.index.DeleteDocument(owner, documentID); err != nil {
+		return fmt.Errorf("failed to remove document from index: %w", err)
+	}
+	delete(b.documents[owner], documentID)
+	if len(b.documents[owner]) == 0 {
+		delete(b.documents, owner)
+	}
+	return nil
+}
+
+// Documents returns the owner's documents, oldest first
+func (b *Base) Documents(owner string) []Document {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	docs := make([]Document, 0, len(b.documents[owner]))
+	for _, doc := range b.documents[owner] {
+		docs = append(docs, doc)
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		return docs[i].UploadedAt.Before(docs[j].UploadedAt)
+	})
+	return docs
+}
+
+// Search returns up to k of the owner's chunks most relevant to query
+// Owners without documents get no matches and use no quota
+func (b *Base) Search(ctx context.Context, owner, query string, k int) ([]Match, error) {
+	b.mu.Lock()
+	empty := len(b.documents[owner]) == 0
+	b.mu.Unlock()
+	if empty {
+		return nil, nil
+	}
+
+	if b.charge != nil && !b.charge(owner, 1) {
+		return nil, ErrQuotaExceeded
+	}
+	vectors, err := b.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != 1 {
+		return nil, ErrInvalidEmbeddings
+	}
+	return b.index.Search(owner, vectors[0], k)
+}
@@ -0,0 +1,140 @@
+package knowledge
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"microchat.ai/cmd/server/llm"
+)
+
+func TestSplitText(t *testing.T) {
+	if chunks := SplitText("  \n ", 100, 10); len(chunks) != 0 {
+		t.Errorf("Expected no chunks for blank text, got %v", chunks)
+	}
+
+	text := strings.Repeat("alpha beta gamma delta ", 50) // 1150 characters
+	chunks := SplitText(text, 200, 50)
+	if len(chunks) < 6 {
+		t.Fatalf("Expected text to be split into several chunks, got %d", len(chunks))
+	}
+	for i, chunk := range chunks {
+		if len(chunk) > 200 {
+			t.Errorf("Chunk %d is %d characters, expected at most 200", i, len(chunk))
+		}
+	}
+
+	// Consecutive chunks overlap, so together they hold more text than the original
+	total := 0
+	for _, chunk := range chunks {
+		total += len(chunk)
+	}
+	if total <= len(strings.TrimSpace(text)) {
+		t.Errorf("Expected overlapping chunks, got %d characters for %d of text", total, len(text))
+	}
+
+	// Oversized words are kept whole rather than dropped
+	long := strings.Repeat("x", 300)
+	if chunks := SplitText("a "+long+" b", 100, 20); len(chunks) != 3 || chunks[1] != long {
+		t.Errorf("Expected oversized word as its own chunk, got %d chunks", len(chunks))
+	}
+}
+
+func TestMemoryIndex(t *testing.T) {
+	index := NewMemoryIndex()
+	index.Add("alice", []Chunk{
+		{DocumentID: "d1", Text: "x axis", Vector: []float32{1, 0}},
+		{DocumentID: "d2", Text: "y axis", Vector: []float32{0, 3}},
+		{DocumentID: "d2", Text: "diagonal", Vector: []float32{1, 1}},
+	})
+
+	matches, err := index.Search("alice", []float32{2, 0.1}, 2)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(matches) != 2 || matches[0].Text != "x axis" || matches[1].Text != "diagonal" {
+		t.Errorf("Expected x axis then diagonal, got %+v", matches)
+	}
+
+	// Owners are isolated
+	if matches, _ := index.Search("bob", []float32{1, 0}, 2); len(matches) != 0 {
+		t.Errorf("Expected no matches for another owner, got %+v", matches)
+	}
+
+	index.DeleteDocument("alice", "d1")
+	matches, _ = index.Search("alice", []float32{1, 0}, 5)
+	for _, match := range matches {
+		if match.DocumentID == "d1" {
+			t.Error("Expected deleted document's chunks to be removed")
+		}
+	}
+
+	if err := index.Add("alice", []Chunk{{DocumentID: "d3"}}); err == nil {
+		t.Error("Expected error for chunk without a vector")
+	}
+}
+
+func TestBase(t *testing.T) {
+	charged := 0
+	base := NewBase(Config{
+		Embedder: llm.NewEchoEmbedder(),
+		Charge: func(owner string, texts int) bool {
+			charged += texts
+			return charged <= 10
+		},
+	})
+	ctx := context.Background()
+
+	if matches, err := base.Search(ctx, "alice", "anything", 3); err != nil || matches != nil || charged != 0 {
+		t.Errorf("Expected no search or charge without documents, got %v (err %v, charged %d)", matches, err, charged)
+	}
+
+	doc, err := base.Upload(ctx, "alice", "pets.txt", "Our cat is called Miso and sleeps all day.")
+	if err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	base.Upload(ctx, "alice", "finance.txt", "Quarterly revenue grew by ten percent.")
+
+	matches, err := base.Search(ctx, "alice", "What is the cat called?", 1)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(matches) != 1 || matches[0].DocumentName != "pets.txt" {
+		t.Errorf("Expected pets.txt to be most relevant, got %+v", matches)
+	}
+
+	if _, err := base.Upload(ctx, "alice", "empty.txt", " "); !errors.Is(err, ErrEmptyDocument) {
+		t.Errorf("Expected ErrEmptyDocument, got %v", err)
+	}
+	if _, err := base.Upload(ctx, "alice", "big.txt", strings.Repeat("a", MaxDocumentBytes+1)); !errors.Is(err, ErrDocumentTooLarge) {
+		t.Errorf("Expected ErrDocumentTooLarge, got %v", err)
+	}
+
+	if err := base.Delete("bob", doc.ID); !errors.Is(err, ErrDocumentNotFound) {
+		t.Errorf("Expected other owners not to delete the document, got %v", err)
+	}
+	if err := base.Delete("alice", doc.ID); err != nil {
+		t.Errorf("Delete failed: %v", err)
+	}
+	if docs := base.Documents("alice"); len(docs) != 1 || docs[0].Name != "finance.txt" {
+		t.Errorf("Expected only finance.txt to remain, got %+v", docs)
+	}
+
+	charged = 10
+	if _, err := base.Upload(ctx, "alice", "more.txt", "over quota"); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Expected ErrQuotaExceeded, got %v", err)
+	}
+}
+
+func TestBase_DocumentLimit(t *testing.T) {
+	base := NewBase(Config{Embedder: llm.NewEchoEmbedder()})
+	for i := 0; i < MaxDocumentsPerOwner; i++ {
+		if _, err := base.Upload(context.Background(), "alice", "doc", "some text"); err != nil {
+			t.Fatalf("Upload %d failed: %v", i, err)
+		}
+	}
+	if _, err := base.Upload(context.Background(), "alice", "doc", "some text"); !errors.Is(err, ErrTooManyDocuments) {
+		t.Errorf("Expected ErrTooManyDocuments, got %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"microchat.ai/cmd/server/events"
+	pb "microchat.ai/proto"
+)
+
+// emitSessionCreated sends a session.created event; via says how: "start", "fork" or "import"
+func (app *application) emitSessionCreated(ctx context.Context, sessionID, via string) {
+	app.events.Emit(events.Event{
+		Type: events.SessionCreated,
+		Data: map[string]string{
+			"session_id": sessionID,
+			"key_hash":   hashAPIKey(apiKeyFromContext(ctx)),
+			"via":        via,
+		},
+	})
+}
+
+// emitSessionEvicted sends a session.evicted event; it is the session store's removal callback
+func (app *application) emitSessionEvicted(sessionID, owner, reason string) {
+	app.events.Emit(events.Event{
+		Type: events.SessionEvicted,
+		Data: map[string]string{
+			"session_id": sessionID,
+			"key_hash":   ownerKeyHash(owner),
+			"reason":     reason,
+		},
+	})
+}
+
+// emitDailyLimitHit sends a daily_limit.hit event for the call that used up a key's limit
+// limit is "calls", "tokens" or "embeddings"
+func (app *application) emitDailyLimitHit(apiKey, limit string) {
+	tracker := app.spendingTracker
+	value := strconv.Itoa(tracker.limit)
+	switch limit {
+	case "tokens":
+		value = strconv.FormatInt(tracker.TokenLimit(), 10)
+	case "embeddings":
+		tracker = app.embeddingQuota
+		value = strconv.Itoa(tracker.limit)
+	}
+	keyHash := hashAPIKey(apiKey)
+	app.events.Emit(events.Event{
+		Type:    events.DailyLimitHit,
+		Subject: keyHash + "/" + limit,
+		Data: map[string]string{
+			"key_hash":  keyHash,
+			"limit":     limit,
+			"value":     value,
+			"resets_at": tracker.ResetsAt().Format(time.RFC3339),
+		},
+	})
+}
+
+// emitProviderFailover sends a provider.failover event when a fallback provider serves a model
+// Repeats for the same model and provider are suppressed for a few minutes
+func (app *application) emitProviderFailover(model pb.Model, requested, provider, reason string) {
+	app.events.Emit(events.Event{
+		Type:    events.ProviderFailover,
+		Subject: model.String() + "/" + provider,
+		Data: map[string]string{
+			"model":     model.String(),
+			"requested": requested,
+			"provider":  provider,
+			"reason":    reason,
+		},
+	})
+}
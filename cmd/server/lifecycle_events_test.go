@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"microchat.ai/cmd/server/events"
+	pb "microchat.ai/proto"
+)
+
+func TestLifecycleEvents(t *testing.T) {
+	var mu sync.Mutex
+	received := make(map[string][]map[string]string) // Event type -> data of each delivery
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get(events.HeaderSignature) != events.Sign([]byte("secret"), r.Header.Get(events.HeaderTimestamp), body) {
+			t.Errorf("event %s has an invalid signature", r.Header.Get(events.HeaderEvent))
+		}
+		var p struct {
+			Type string            `json:"type"`
+			Data map[string]string `json:"data"`
+		}
+		json.Unmarshal(body, &p)
+		mu.Lock()
+		received[p.Type] = append(received[p.Type], p.Data)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	app, mockProvider := setupTestApplicationWithMock(t)
+	mockProvider.SetResponses("Reply")
+	app.spendingTracker = NewSpendingTracker(1)
+	types, _ := events.ParseTypes("")
+	app.events = events.NewEmitter([]string{server.URL}, "secret", types, app.logger)
+	app.sessionStore.OnSessionRemoved(app.emitSessionEvicted)
+	app.spendingTracker.OnLimitReached(app.emitDailyLimitHit)
+
+	ctx := context.WithValue(context.Background(), "api_key", "alice-key")
+	session, err := app.StartSession(ctx, &pb.StartSessionRequest{})
+	if err != nil {
+		t.Fatalf("Failed to start session: %v", err)
+	}
+	if _, err := app.Chat(ctx, &pb.ChatRequest{SessionId: session.SessionId, Message: "Hi"}); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	storedSession(app.sessionStore, session.SessionId).LastActive = time.Now().UTC().Add(-3 * time.Hour)
+	app.sessionStore.CleanupIdleSessions()
+	app.spendingTracker.RecordCall("alice-key")
+	app.emitProviderFailover(pb.Model_GEMINI_2_5_FLASH_LITE, "gemini", "echo", "unhealthy")
+	app.emitProviderFailover(pb.Model_GEMINI_2_5_FLASH_LITE, "gemini", "echo", "unhealthy") // Suppressed repeat
+
+	closeCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	app.events.Close(closeCtx)
+
+	mu.Lock()
+	defer mu.Unlock()
+	keyHash := hashAPIKey("alice-key")
+	if got := received[events.SessionCreated]; len(got) != 1 || got[0]["session_id"] != session.SessionId || got[0]["key_hash"] != keyHash || got[0]["via"] != "start" {
+		t.Errorf("Unexpected session.created events %v", got)
+	}
+	if got := received[events.SessionEvicted]; len(got) != 1 || got[0]["session_id"] != session.SessionId || got[0]["reason"] != "idle_cleanup" {
+		t.Errorf("Unexpected session.evicted events %v", got)
+	}
+	if got := received[events.DailyLimitHit]; len(got) != 1 || got[0]["key_hash"] != keyHash || got[0]["limit"] != "calls" || got[0]["value"] != "1" {
+		t.Errorf("Unexpected daily_limit.hit events %v", got)
+	}
+	if got := received[events.ProviderFailover]; len(got) != 1 || got[0]["provider"] != "echo" || got[0]["reason"] != "unhealthy" {
+		t.Errorf("Unexpected provider.failover events %v", got)
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/reflection"
+
+	"microchat.ai/cmd/server/alerts"
+	pb "microchat.ai/proto"
+)
+
+// listenerConfig describes one address to serve gRPC on
+type listenerConfig struct {
+	name     string      // As configured, for logs
+	network  string      // "tcp", "tcp4", "tcp6" or "unix"
+	address  string      // host:port, or the socket path for unix
+	certFile string      // TLS certificate; empty serves plaintext
+	keyFile  string      // TLS private key
+	mode     fs.FileMode // Unix socket permissions
+
+	proxyProtocol bool // Expect a PROXY protocol header with the real client address on each connection, from TRUSTED_PROXIES only
+}
+
+// parseListeners parses a comma-separated list of listener URLs:
+//
+//	tls://host:port          TLS with the default certificate (tls4:// and tls6:// pin the IP version)
+//	tls://host:port?cert=c&key=k  TLS with its own certificate
+//	tcp://host:port          plaintext, for internal networks behind a TLS-terminating proxy (tcp4://, tcp6://)
+//	unix:///path?mode=0660   plaintext unix socket (mode defaults to defaultMode)
+//
+// TCP listeners behind an L4 load balancer can add proxy=true to read the client address from
+// a PROXY protocol header
+func parseListeners(value, defaultCert, defaultKey string, defaultMode fs.FileMode) ([]listenerConfig, error) {
+	var listeners []listenerConfig
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parsed, err := url.Parse(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid listener %q: %w", entry, err)
+		}
+
+		l := listenerConfig{name: entry, mode: defaultMode}
+		query := parsed.Query()
+		switch parsed.Scheme {
+		case "tls", "tls4", "tls6":
+			l.network = "tcp" + strings.TrimPrefix(parsed.Scheme, "tls")
+			l.address = parsed.Host
+			l.certFile, l.keyFile = defaultCert, defaultKey
+			if query.Has("cert") || query.Has("key") {
+				if query.Get("cert") == "" || query.Get("key") == "" {
+					return nil, fmt.Errorf("invalid listener %q: cert and key must be set together", entry)
+				}
+				l.certFile, l.keyFile = query.Get("cert"), query.Get("key")
+			}
+		case "tcp", "tcp4", "tcp6":
+			l.network = parsed.Scheme
+			l.address = parsed.Host
+		case "unix":
+			l.network = "unix"
+			l.address = parsed.Path
+			if modeStr := query.Get("mode"); modeStr != "" {
+				mode, err := strconv.ParseUint(modeStr, 8, 32)
+				if err != nil || mode > 0o777 {
+					return nil, fmt.Errorf("invalid listener %q: mode must be octal permissions like 0660", entry)
+				}
+				l.mode = fs.FileMode(mode)
+			}
+		default:
+			return nil, fmt.Errorf("invalid listener %q: scheme must be tls, tcp or unix", entry)
+		}
+
+		if proxyStr := query.Get("proxy"); proxyStr != "" {
+			l.proxyProtocol, err = strconv.ParseBool(proxyStr)
+			if err != nil || (l.proxyProtocol && l.network == "unix") {
+				return nil, fmt.Errorf("invalid listener %q: proxy must be true or false, on TCP listeners only", entry)
+			}
+		}
+
+		if l.network != "unix" {
+			if _, port, err := net.SplitHostPort(l.address); err != nil || port == "" {
+				return nil, fmt.Errorf("invalid listener %q: expected host:port", entry)
+			}
+		} else if l.address == "" {
+			return nil, fmt.Errorf("invalid listener %q: missing socket path", entry)
+		}
+		if seen[l.network+" "+l.address] {
+			return nil, fmt.Errorf("duplicate listener %q", entry)
+		}
+		seen[l.network+" "+l.address] = true
+		listeners = append(listeners, l)
+	}
+
+	if len(listeners) == 0 {
+		return nil, fmt.Errorf("no listeners configured")
+	}
+	return listeners, nil
+}
+
+// openListeners binds every configured listener, each with its own gRPC server
+// Listeners opened before a failure are closed again
+func openListeners(app *application, configs []listenerConfig) ([]grpcListener, error) {
+	authFailures := alerts.NewFailureCounter(authFailureAlertThreshold, authFailureAlertWindow)
+	tlsCreds := make(map[[2]string]credentials.TransportCredentials) // Certificate and key -> credentials
+
+	var listeners []grpcListener
+	closeAll := func() {
+		for _, l := range listeners {
+			l.listener.Close()
+		}
+	}
+
+	for _, cfg := range configs {
+		creds := insecure.NewCredentials()
+		if cfg.certFile != "" {
+			pair := [2]string{cfg.certFile, cfg.keyFile}
+			if _, loaded := tlsCreds[pair]; !loaded && cfg.certFile == secretTLSCert {
+				// Served from the certificate store so rotated certificates apply to new connections
+				tlsCreds[pair] = credentials.NewTLS(&tls.Config{GetCertificate: app.config.certificates.GetCertificate})
+			} else if !loaded {
+				loadedCreds, err := credentials.NewServerTLSFromFile(cfg.certFile, cfg.keyFile)
+				if err != nil {
+					closeAll()
+					return nil, fmt.Errorf("failed to load TLS credentials for %s: %w", cfg.name, err)
+				}
+				tlsCreds[pair] = loadedCreds
+			}
+			creds = tlsCreds[pair]
+		}
+
+		var lis net.Listener
+		var err error
+		if cfg.network == "unix" {
+			lis, err = listenUnix(cfg.address, cfg.mode)
+		} else {
+			lis, err = net.Listen(cfg.network, cfg.address)
+		}
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("failed to listen on %s: %w", cfg.name, err)
+		}
+
+		if cfg.proxyProtocol {
+			lis = &proxyListener{Listener: lis, trusted: app.config.trustedProxies}
+		}
+
+		if cfg.certFile == "" && cfg.network != "unix" && !isLoopbackListener(lis) {
+			app.logger.Warn("serving plaintext gRPC on a non-loopback address - use only behind a TLS-terminating proxy", "listener", cfg.name)
+		}
+		listeners = append(listeners, grpcListener{name: cfg.name, listener: lis, server: newGRPCServer(app, creds, authFailures)})
+	}
+	return listeners, nil
+}
+
+// isLoopbackListener reports whether a TCP listener only accepts local connections
+func isLoopbackListener(lis net.Listener) bool {
+	addr, ok := lis.Addr().(*net.TCPAddr)
+	return ok && addr.IP.IsLoopback()
+}
+
+// grpcListener is one address the server accepts connections on, with its own gRPC server
+// since transport credentials are a per-server option
+type grpcListener struct {
+	name     string
+	listener net.Listener
+	server   *grpc.Server
+}
+
+// newGRPCServer creates a gRPC server for the application with the given transport credentials
+// All listeners share the interceptors' state, so limits and alerts apply across them
+func newGRPCServer(app *application, creds credentials.TransportCredentials, authFailures *alerts.FailureCounter) *grpc.Server {
+	opts := append(app.config.connection.serverOptions(),
+		grpc.Creds(creds),
+		grpc.StatsHandler(app.bandwidth),
+		grpc.ChainUnaryInterceptor(
+			DeadlineInterceptor(app.config.rpcTimeout, app.config.rpcTimeouts),
+			AuthFailureAlertInterceptor(app.alerts, authFailures, app.config.trustedProxies),
+			AuthInterceptor(app.config.apiKeys, app.spendingTracker),
+			RateLimitInterceptor(app.ipLimiter, app.config.rateLimitCosts, app.config.trustedProxies),
+			SessionExpiryInterceptor(app.sessionStore),
+		),
+	)
+	s := grpc.NewServer(opts...)
+	pb.RegisterChatServiceServer(s, app)
+
+	// Enable reflection in development only
+	if app.config.env == "development" {
+		reflection.Register(s)
+	}
+	return s
+}
+
+// listenUnix listens on a unix socket, replacing a stale socket left by a previous run and
+// restricting access to the given file mode
+// Requests over the socket still need an API key; the mode limits who can connect at all
+func listenUnix(path string, mode fs.FileMode) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&fs.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+	}
+
+	lis, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		lis.Close()
+		return nil, fmt.Errorf("failed to set socket permissions: %w", err)
+	}
+	return lis, nil
+}
+
+// serveListeners starts serving every listener in the background
+func serveListeners(listeners []grpcListener, app *application) {
+	for _, l := range listeners {
+		go func() {
+			app.logger.Info("starting gRPC server", "listener", l.name, "addr", l.listener.Addr(), "env", app.config.env)
+			if err := l.server.Serve(l.listener); err != nil {
+				app.logger.Error("failed to serve", "listener", l.name, "error", err)
+			}
+		}()
+	}
+}
+
+// stopListeners gracefully stops every listener together, waiting for in-flight RPCs on all of them
+func stopListeners(listeners []grpcListener) {
+	var wg sync.WaitGroup
+	for _, l := range listeners {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.server.GracefulStop()
+		}()
+	}
+	wg.Wait()
+}
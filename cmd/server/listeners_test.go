@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"microchat.ai/cmd/server/alerts"
+	"microchat.ai/cmd/server/ratelimit"
+	pb "microchat.ai/proto"
+)
+
+func TestListenUnix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "microchat.sock")
+
+	lis, err := listenUnix(path, 0o600)
+	if err != nil {
+		t.Fatalf("listenUnix failed: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("expected socket file: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("expected owner-only permissions, got %v", info.Mode().Perm())
+	}
+
+	// A socket left behind by a crashed server is replaced
+	lis.(*net.UnixListener).SetUnlinkOnClose(false)
+	lis.Close()
+	lis, err = listenUnix(path, 0o660)
+	if err != nil {
+		t.Fatalf("expected stale socket to be replaced: %v", err)
+	}
+	lis.Close()
+
+	// Regular files are never removed
+	file := filepath.Join(t.TempDir(), "not-a-socket")
+	os.WriteFile(file, []byte("data"), 0o600)
+	if _, err := listenUnix(file, 0o600); err == nil {
+		t.Error("expected error when the path is a regular file")
+	}
+}
+
+func TestUnixListenerServesWithAuth(t *testing.T) {
+	app, _ := setupTestApplicationWithMock(t)
+	app.logger = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	app.config.apiKeys = newAPIKeySet(map[string]string{"alice-key": "user"})
+	app.spendingTracker = NewSpendingTracker(100)
+	app.ipLimiter = ratelimit.NewIPLimiter(100, 100)
+	defer app.ipLimiter.Stop()
+	app.bandwidth = newBandwidthTracker(app.config.apiKeys)
+
+	path := filepath.Join(t.TempDir(), "microchat.sock")
+	lis, err := listenUnix(path, 0o600)
+	if err != nil {
+		t.Fatalf("listenUnix failed: %v", err)
+	}
+	listeners := []grpcListener{{name: "unix", listener: lis, server: newGRPCServer(app, insecure.NewCredentials(), alerts.NewFailureCounter(10, time.Minute))}}
+	serveListeners(listeners, app)
+	defer stopListeners(listeners)
+
+	conn, err := grpc.NewClient("unix://"+path, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to dial socket: %v", err)
+	}
+	defer conn.Close()
+	client := pb.NewChatServiceClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := client.StartSession(ctx, &pb.StartSessionRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected API key to be required over the socket, got %v", err)
+	}
+
+	authCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer alice-key")
+	if _, err := client.StartSession(authCtx, &pb.StartSessionRequest{}); err != nil {
+		t.Errorf("expected authenticated request over the socket to succeed, got %v", err)
+	}
+}
+
+func TestExtractClientIP_UnixSocket(t *testing.T) {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.UnixAddr{Name: "@", Net: "unix"}})
+	if got := extractClientIP(ctx, nil); got != "unix" {
+		t.Errorf("expected unix socket peers to share one client IP, got %q", got)
+	}
+}
+
+func TestParseListeners(t *testing.T) {
+	listeners, err := parseListeners(
+		"tls://:4000, tls6://[::1]:4443?cert=ext.crt&key=ext.key, tcp4://10.0.0.5:4001, unix:///run/microchat.sock?mode=0660",
+		"default.crt", "default.key", 0o600,
+	)
+	if err != nil {
+		t.Fatalf("parseListeners failed: %v", err)
+	}
+	want := []listenerConfig{
+		{name: "tls://:4000", network: "tcp", address: ":4000", certFile: "default.crt", keyFile: "default.key", mode: 0o600},
+		{name: "tls6://[::1]:4443?cert=ext.crt&key=ext.key", network: "tcp6", address: "[::1]:4443", certFile: "ext.crt", keyFile: "ext.key", mode: 0o600},
+		{name: "tcp4://10.0.0.5:4001", network: "tcp4", address: "10.0.0.5:4001", mode: 0o600},
+		{name: "unix:///run/microchat.sock?mode=0660", network: "unix", address: "/run/microchat.sock", mode: 0o660},
+	}
+	if len(listeners) != len(want) {
+		t.Fatalf("expected %d listeners, got %+v", len(want), listeners)
+	}
+	for i := range want {
+		if listeners[i] != want[i] {
+			t.Errorf("listener %d: expected %+v, got %+v", i, want[i], listeners[i])
+		}
+	}
+
+	proxied, err := parseListeners("tcp://:4000?proxy=true", "c", "k", 0o600)
+	if err != nil || !proxied[0].proxyProtocol {
+		t.Errorf("expected proxy=true to enable the PROXY protocol, got %+v, %v", proxied, err)
+	}
+
+	for _, value := range []string{
+		"",
+		"http://:4000",
+		"tcp://localhost",
+		"tls://:4000?cert=only.crt",
+		"unix://",
+		"unix:///tmp/s.sock?mode=999",
+		"tcp://:4000,tcp://:4000",
+		"tcp://:4000?proxy=maybe",
+		"unix:///tmp/s.sock?proxy=true",
+	} {
+		if _, err := parseListeners(value, "c", "k", 0o600); err == nil {
+			t.Errorf("expected error for %q", value)
+		}
+	}
+}
+
+func TestOpenListeners(t *testing.T) {
+	app, _ := setupTestApplicationWithMock(t)
+	app.logger = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	socket := filepath.Join(t.TempDir(), "microchat.sock")
+
+	listeners, err := openListeners(app, []listenerConfig{
+		{name: "internal", network: "tcp4", address: "127.0.0.1:0"},
+		{name: "local", network: "unix", address: socket, mode: 0o600},
+	})
+	if err != nil {
+		t.Fatalf("openListeners failed: %v", err)
+	}
+	if len(listeners) != 2 || listeners[0].server == listeners[1].server {
+		t.Fatalf("expected a separate server per listener, got %+v", listeners)
+	}
+	if !isLoopbackListener(listeners[0].listener) {
+		t.Error("expected 127.0.0.1 listener to be loopback")
+	}
+	serveListeners(listeners, app)
+	stopListeners(listeners)
+	if _, err := os.Stat(socket); !os.IsNotExist(err) {
+		t.Errorf("expected socket to be removed when draining, got %v", err)
+	}
+
+	// A listener that fails to start closes the ones opened before it
+	_, err = openListeners(app, []listenerConfig{
+		{name: "internal", network: "tcp4", address: "127.0.0.1:0"},
+		{name: "external", network: "tcp", address: "127.0.0.1:0", certFile: "missing.crt", keyFile: "missing.key"},
+	})
+	if err == nil {
+		t.Error("expected error for missing TLS certificate")
+	}
+}
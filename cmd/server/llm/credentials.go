@@ -0,0 +1,70 @@
+package llm
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// apiKeys holds upstream API keys rotated at runtime, which take precedence over the environment
+// Providers are created per request, so a rotated key is used from the next call while calls
+// already in flight finish on the client they started with
+var apiKeys = struct {
+	mu   sync.RWMutex
+	keys map[string]string // Provider name -> API key
+}{keys: make(map[string]string)}
+
+// APIKey returns a provider's current upstream API key: the last rotated key, or else the
+// value of its APIKeyEnv variable
+func APIKey(provider string) string {
+	apiKeys.mu.RLock()
+	key, rotated := apiKeys.keys[provider]
+	apiKeys.mu.RUnlock()
+	if rotated {
+		return key
+	}
+
+	reg, ok := LookupName(provider)
+	if !ok || reg.APIKeyEnv == "" {
+		return ""
+	}
+	return os.Getenv(reg.APIKeyEnv)
+}
+
+// SetAPIKey rotates a provider's upstream API key without a restart
+func SetAPIKey(provider, key string) error {
+	reg, ok := LookupName(provider)
+	if !ok {
+		return fmt.Errorf("unknown provider %q", provider)
+	}
+	if reg.APIKeyEnv == "" {
+		return fmt.Errorf("provider %q doesn't use an API key", provider)
+	}
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return fmt.Errorf("API key for %s cannot be empty", provider)
+	}
+
+	apiKeys.mu.Lock()
+	defer apiKeys.mu.Unlock()
+	apiKeys.keys[provider] = key
+	return nil
+}
+
+// ClearAPIKey drops a provider's rotated key so its APIKeyEnv variable applies again
+func ClearAPIKey(provider string) {
+	apiKeys.mu.Lock()
+	defer apiKeys.mu.Unlock()
+	delete(apiKeys.keys, provider)
+}
+
+// ProviderForAPIKeyEnv returns the provider whose API key is read from an environment variable
+func ProviderForAPIKeyEnv(name string) (string, bool) {
+	for _, reg := range Registered() {
+		if reg.APIKeyEnv != "" && reg.APIKeyEnv == name {
+			return reg.Name, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,44 @@
+package llm
+
+import "testing"
+
+func TestAPIKeyRotation(t *testing.T) {
+	echo, _ := LookupName("echo")
+	Register(Registration{Name: "rotation_test", APIKeyEnv: "ROTATION_TEST_API_KEY", New: echo.New})
+	defer func() {
+		registry.mu.Lock()
+		delete(registry.byName, "rotation_test")
+		registry.mu.Unlock()
+		ClearAPIKey("rotation_test")
+	}()
+
+	t.Setenv("ROTATION_TEST_API_KEY", "from-env")
+	if key := APIKey("rotation_test"); key != "from-env" {
+		t.Errorf("expected key from the environment, got %q", key)
+	}
+	if name, ok := ProviderForAPIKeyEnv("ROTATION_TEST_API_KEY"); !ok || name != "rotation_test" {
+		t.Errorf("expected provider for env var, got %q, %v", name, ok)
+	}
+
+	if err := SetAPIKey("rotation_test", " rotated \n"); err != nil {
+		t.Fatalf("SetAPIKey failed: %v", err)
+	}
+	if key := APIKey("rotation_test"); key != "rotated" {
+		t.Errorf("expected rotated key to override the environment, got %q", key)
+	}
+
+	ClearAPIKey("rotation_test")
+	if key := APIKey("rotation_test"); key != "from-env" {
+		t.Errorf("expected cleared key to fall back to the environment, got %q", key)
+	}
+
+	if err := SetAPIKey("rotation_test", " "); err == nil {
+		t.Error("expected empty key to be rejected")
+	}
+	if err := SetAPIKey("echo", "key"); err == nil {
+		t.Error("expected provider without an API key to be rejected")
+	}
+	if err := SetAPIKey("no_such_provider", "key"); err == nil {
+		t.Error("expected unknown provider to be rejected")
+	}
+}
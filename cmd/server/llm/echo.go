@@ -3,8 +3,52 @@ package llm
 import (
 	"context"
 	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"math"
+	"os"
+	"strings"
+	"unicode"
+
+	pb "microchat.ai/proto"
 )
 
+func init() {
+	Register(Registration{
+		Name:        "echo",
+		DisplayName: "Echo (Dev/Test)",
+		Models:      []pb.Model{pb.Model_ECHO},
+		DevOnly:     true,
+		New:         newEchoOrSimulatedProvider,
+		NewEmbedder: func(logger *slog.Logger) (EmbeddingProvider, error) { return NewEchoEmbedder(), nil },
+		Validate: func() error {
+			if spec := os.Getenv("MOCK_PROVIDER_BEHAVIOR"); spec != "" {
+				if _, err := ParseMockBehavior(spec); err != nil {
+					return fmt.Errorf("invalid MOCK_PROVIDER_BEHAVIOR: %w", err)
+				}
+			}
+			return nil
+		},
+	})
+}
+
+// newEchoOrSimulatedProvider serves echo requests, or a mock with simulated latency and
+// failures when MOCK_PROVIDER_BEHAVIOR is set (for load testing against a slow provider)
+func newEchoOrSimulatedProvider(logger *slog.Logger) (Provider, error) {
+	spec := os.Getenv("MOCK_PROVIDER_BEHAVIOR")
+	if spec == "" {
+		return NewEchoProvider(), nil
+	}
+
+	behavior, err := ParseMockBehavior(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MOCK_PROVIDER_BEHAVIOR: %w", err)
+	}
+	mock := NewMockProvider("Simulated")
+	mock.SetBehavior(behavior)
+	return mock, nil
+}
+
 // EchoProvider implements Provider interface with simple echo functionality
 type EchoProvider struct{}
 
@@ -35,7 +79,72 @@ func (e *EchoProvider) GenerateResponse(ctx context.Context, messages []Message)
 	return fmt.Sprintf("Echo: %s", lastUserMessage), nil
 }
 
+// GenerateResponseStream streams the echo response word by word
+func (e *EchoProvider) GenerateResponseStream(ctx context.Context, messages []Message) (<-chan Chunk, error) {
+	reply, err := e.GenerateResponse(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	return StreamText(ctx, reply), nil
+}
+
+// HealthCheck always succeeds since echo has no dependencies
+func (e *EchoProvider) HealthCheck(ctx context.Context) error {
+	return nil
+}
+
 // Name returns the provider name
 func (e *EchoProvider) Name() string {
 	return "Echo"
 }
+
+// echoEmbeddingDimensions is the vector size produced by EchoEmbedder
+const echoEmbeddingDimensions = 256
+
+// EchoEmbedder produces deterministic bag-of-words embeddings without any external API,
+// so retrieval features can be developed and tested offline
+type EchoEmbedder struct{}
+
+// NewEchoEmbedder creates a new echo embedder
+func NewEchoEmbedder() EmbeddingProvider {
+	return &EchoEmbedder{}
+}
+
+// Embed hashes each lowercase word into a fixed-size vector and normalizes it,
+// so texts sharing words have a high cosine similarity
+func (e *EchoEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vector := make([]float32, echoEmbeddingDimensions)
+		for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+		}) {
+			h := fnv.New32a()
+			h.Write([]byte(word))
+			vector[h.Sum32()%echoEmbeddingDimensions]++
+		}
+
+		var norm float64
+		for _, v := range vector {
+			norm += float64(v) * float64(v)
+		}
+		if norm > 0 {
+			scale := float32(1 / math.Sqrt(norm))
+			for j := range vector {
+				vector[j] *= scale
+			}
+		}
+		vectors[i] = vector
+	}
+	return vectors, nil
+}
+
+// MaxBatchSize implements EmbeddingProvider
+func (e *EchoEmbedder) MaxBatchSize() int {
+	return 100
+}
+
+// Name returns the embedding provider name
+func (e *EchoEmbedder) Name() string {
+	return "Echo-Embedding"
+}
@@ -0,0 +1,76 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	pb "microchat.ai/proto"
+)
+
+// EmbeddingProvider turns text into vectors, as a building block for retrieval features
+// Vectors from different providers aren't comparable, so there is no fallback between them
+type EmbeddingProvider interface {
+	// Embed returns one vector per text, in order
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	// MaxBatchSize is the most texts the provider accepts in one Embed call
+	MaxBatchSize() int
+	Name() string
+}
+
+// NewEmbeddingProvider creates the embedding provider for a model
+func NewEmbeddingProvider(model pb.Model, logger *slog.Logger) (EmbeddingProvider, error) {
+	reg, ok := Lookup(model)
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown model %s", model)
+	}
+	return newEmbedder(reg, logger)
+}
+
+// NewEmbeddingProviderByName creates the embedding provider of a registered provider, e.g. "gemini"
+func NewEmbeddingProviderByName(name string, logger *slog.Logger) (EmbeddingProvider, error) {
+	reg, ok := LookupName(name)
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown provider %q", name)
+	}
+	return newEmbedder(reg, logger)
+}
+
+// newEmbedder creates a registration's embedding provider, enforcing dev-only restrictions
+func newEmbedder(reg Registration, logger *slog.Logger) (EmbeddingProvider, error) {
+	if reg.DevOnly && os.Getenv("APP_ENV") != "development" {
+		return nil, status.Errorf(codes.InvalidArgument, "provider %s is only available in development", reg.Name)
+	}
+	if reg.NewEmbedder == nil {
+		return nil, status.Errorf(codes.Unimplemented, "provider %s does not support embeddings", reg.Name)
+	}
+
+	embedder, err := reg.NewEmbedder(logger)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to create %s embedding provider: %v", reg.Name, err)
+	}
+	return embedder, nil
+}
+
+// EmbedBatched embeds any number of texts, splitting them into batches the provider accepts
+func EmbedBatched(ctx context.Context, provider EmbeddingProvider, texts []string) ([][]float32, error) {
+	batchSize := max(provider.MaxBatchSize(), 1)
+	vectors := make([][]float32, 0, len(texts))
+
+	for start := 0; start < len(texts); start += batchSize {
+		batch := texts[start:min(start+batchSize, len(texts))]
+		embedded, err := provider.Embed(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		if len(embedded) != len(batch) {
+			return nil, status.Error(codes.Internal, fmt.Sprintf("%s returned %d embeddings for %d texts", provider.Name(), len(embedded), len(batch)))
+		}
+		vectors = append(vectors, embedded...)
+	}
+
+	return vectors, nil
+}
@@ -0,0 +1,83 @@
+package llm
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	pb "microchat.ai/proto"
+)
+
+func TestEchoEmbedder(t *testing.T) {
+	vectors, err := NewEchoEmbedder().Embed(context.Background(), []string{
+		"The cat sat on the mat",
+		"the CAT sat on the mat!",
+		"Quarterly revenue grew",
+		"",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	similarity := func(a, b []float32) float32 {
+		var dot float32
+		for i := range a {
+			dot += a[i] * b[i]
+		}
+		return dot
+	}
+	if s := similarity(vectors[0], vectors[1]); s < 0.99 {
+		t.Errorf("expected identical word sets to match, got similarity %f", s)
+	}
+	if s := similarity(vectors[0], vectors[2]); s > 0.5 {
+		t.Errorf("expected unrelated texts to differ, got similarity %f", s)
+	}
+	if s := similarity(vectors[3], vectors[3]); s != 0 {
+		t.Errorf("expected zero vector for empty text, got %f", s)
+	}
+}
+
+func TestNewEmbeddingProvider(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	t.Setenv("APP_ENV", "production")
+	if _, err := NewEmbeddingProvider(pb.Model_ECHO, logger); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected dev-only embedder to be rejected in production, got %v", err)
+	}
+	if _, err := NewEmbeddingProvider(pb.Model(99), logger); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected unknown model to be rejected, got %v", err)
+	}
+
+	t.Setenv("APP_ENV", "development")
+	embedder, err := NewEmbeddingProvider(pb.Model_ECHO, logger)
+	if err != nil || embedder.Name() != "Echo-Embedding" {
+		t.Errorf("expected echo embedder in development, got %v (err %v)", embedder, err)
+	}
+}
+
+// countingEmbedder records batch sizes
+type countingEmbedder struct {
+	batches []int
+}
+
+func (c *countingEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	c.batches = append(c.batches, len(texts))
+	return make([][]float32, len(texts)), nil
+}
+
+func (c *countingEmbedder) MaxBatchSize() int { return 3 }
+func (c *countingEmbedder) Name() string      { return "counting" }
+
+func TestEmbedBatched(t *testing.T) {
+	embedder := &countingEmbedder{}
+	vectors, err := EmbedBatched(context.Background(), embedder, make([]string, 7))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(vectors) != 7 || len(embedder.batches) != 3 || embedder.batches[2] != 1 {
+		t.Errorf("expected 7 vectors in batches of 3, 3, 1, got %d vectors in %v", len(vectors), embedder.batches)
+	}
+}
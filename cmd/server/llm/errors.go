@@ -0,0 +1,26 @@
+package llm
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorType classifies a provider error for the microchat_llm_errors_total error_type label
+func ErrorType(err error) string {
+	switch status.Code(err) {
+	case codes.Unauthenticated, codes.PermissionDenied:
+		return "auth"
+	case codes.ResourceExhausted:
+		return "quota"
+	case codes.FailedPrecondition:
+		return "safety_block"
+	case codes.InvalidArgument, codes.NotFound:
+		return "invalid_request"
+	case codes.DeadlineExceeded:
+		return "timeout"
+	case codes.Canceled:
+		return "cancelled"
+	default:
+		return "api_error"
+	}
+}
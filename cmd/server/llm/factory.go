@@ -1,6 +1,7 @@
 package llm
 
 import (
+	"cmp"
 	"fmt"
 	"log/slog"
 	"os"
@@ -8,56 +9,122 @@ import (
 	pb "microchat.ai/proto"
 )
 
+// Providers the factory falls back to when the requested one isn't available
+const (
+	defaultProviderName  = "gemini" // Served for unknown models in production
+	fallbackProviderName = "echo"   // Last resort when the default is unhealthy or can't be created
+)
+
+// Reasons a request is served by a different provider than the one registered for its model
+const (
+	FallbackUnknownModel = "unknown_model" // No provider is registered for the model
+	FallbackDevOnly      = "dev_only"      // Development provider requested in production
+	FallbackNotCompiled  = "not_compiled"  // Provider isn't compiled into this build
+	FallbackUnhealthy    = "unhealthy"     // Provider's last health check failed
+	FallbackCreateFailed = "create_failed" // Provider couldn't be created (e.g. missing credentials)
+)
+
+// NotConfigured is why a provider whose required settings are missing can't serve its models
+const NotConfigured = "not_configured"
+
+// Selection describes how the provider for a request was chosen
+type Selection struct {
+	Requested      string // Provider registered for the model, empty for unknown models
+	FallbackReason string // Why the last fallback happened, empty when the requested provider serves the request
+}
+
 // NewProvider creates a provider based on the model type
 func NewProvider(model pb.Model, logger *slog.Logger) Provider {
-	// Check if we're in development mode for Echo provider
+	return NewProviderWithHealth(model, logger, nil)
+}
+
+// NewProviderWithHealth creates a provider based on the model type, routing away
+// from providers the health monitor reports as unhealthy
+func NewProviderWithHealth(model pb.Model, logger *slog.Logger, health *HealthMonitor) Provider {
+	provider, _ := SelectProvider(model, logger, health)
+	return provider
+}
+
+// SelectProvider is NewProviderWithHealth that also reports whether, and why, the
+// request fell back to another provider
+func SelectProvider(model pb.Model, logger *slog.Logger, health *HealthMonitor) (Provider, Selection) {
+	// Check if we're in development mode for dev-only providers
 	isDev := os.Getenv("APP_ENV") == "development"
 
-	switch model {
-	case pb.Model_GEMINI_2_5_FLASH_LITE:
-		provider, err := NewGeminiProvider(logger)
-		if err != nil {
-			logger.Warn("failed to create Gemini provider, falling back to Echo", "error", err)
-			return NewEchoProvider()
-		}
-		return provider
-	case pb.Model_ECHO:
-		if !isDev {
-			logger.Warn("Echo provider requested in production environment, falling back to Gemini", "model", model.String())
-			provider, err := NewGeminiProvider(logger)
-			if err != nil {
-				logger.Error("failed to create Gemini fallback provider", "error", err)
-				return NewEchoProvider() // Last resort
-			}
-			return provider
-		}
-		logger.Info("using Echo provider for development", "model", model.String())
-		return NewEchoProvider()
-	default:
-		// Default to Gemini for unknown models in production, Echo in development
-		if isDev {
-			logger.Info("unknown model in development, using Echo provider", "model", model.String())
-			return NewEchoProvider()
-		} else {
-			logger.Warn("unknown model in production, falling back to Gemini", "model", model.String())
-			provider, err := NewGeminiProvider(logger)
-			if err != nil {
-				logger.Error("failed to create Gemini fallback provider", "error", err)
-				return NewEchoProvider() // Last resort
-			}
+	reg, ok := Lookup(model)
+	switch {
+	case !ok && isDev:
+		logger.Info("unknown model in development, using fallback provider", "model", model.String(), "provider", fallbackProviderName)
+		return newFallbackProvider(logger), Selection{FallbackReason: FallbackUnknownModel}
+	case !ok:
+		logger.Warn("unknown model in production, using default provider", "model", model.String(), "provider", defaultProviderName)
+		provider, reason := newProviderOrFallback(defaultProviderName, logger, health)
+		return provider, Selection{FallbackReason: cmp.Or(reason, FallbackUnknownModel)}
+	case reg.DevOnly && !isDev:
+		logger.Warn("development provider requested in production environment, using default provider",
+			"model", model.String(), "provider", defaultProviderName)
+		provider, reason := newProviderOrFallback(defaultProviderName, logger, health)
+		return provider, Selection{Requested: reg.Name, FallbackReason: cmp.Or(reason, FallbackDevOnly)}
+	}
+
+	if reg.DevOnly {
+		logger.Info("using development provider", "model", model.String(), "provider", reg.Name)
+	}
+	provider, reason := newProviderOrFallback(reg.Name, logger, health)
+	return provider, Selection{Requested: reg.Name, FallbackReason: reason}
+}
+
+// newProviderOrFallback creates the named provider, falling back to the fallback provider
+// as a last resort when it is unregistered, its last health check failed, or it can't be created
+// Returns the fallback reason, or "" when the named provider was created
+func newProviderOrFallback(name string, logger *slog.Logger, health *HealthMonitor) (Provider, string) {
+	reg, ok := LookupName(name)
+	if !ok {
+		logger.Warn("provider not compiled in, falling back", "provider", name, "fallback", fallbackProviderName)
+		return newFallbackProvider(logger), FallbackNotCompiled
+	}
+
+	if health != nil && !health.IsHealthy(name) {
+		logger.Warn("provider unhealthy, falling back", "provider", name, "fallback", fallbackProviderName)
+		return newFallbackProvider(logger), FallbackUnhealthy
+	}
+
+	provider, err := reg.New(logger)
+	if err != nil {
+		logger.Warn("failed to create provider, falling back", "provider", name, "fallback", fallbackProviderName, "error", err)
+		return newFallbackProvider(logger), FallbackCreateFailed
+	}
+	return provider, ""
+}
+
+// newFallbackProvider creates the last-resort provider
+func newFallbackProvider(logger *slog.Logger) Provider {
+	if reg, ok := LookupName(fallbackProviderName); ok {
+		if provider, err := reg.New(logger); err == nil {
 			return provider
 		}
 	}
+	return NewEchoProvider()
+}
+
+// Unavailable reports why requests for a provider's models would currently be served by another
+// provider (FallbackDevOnly, NotConfigured or FallbackUnhealthy), or "" when it serves them itself
+func Unavailable(reg Registration, health *HealthMonitor) string {
+	switch {
+	case reg.DevOnly && os.Getenv("APP_ENV") != "development":
+		return FallbackDevOnly
+	case reg.Configured != nil && !reg.Configured():
+		return NotConfigured
+	case health != nil && !health.IsHealthy(reg.Name):
+		return FallbackUnhealthy
+	}
+	return ""
 }
 
 // GetProviderName returns a human-readable name for the model
 func GetProviderName(model pb.Model) string {
-	switch model {
-	case pb.Model_GEMINI_2_5_FLASH_LITE:
-		return "Gemini-2.5-Flash-Lite"
-	case pb.Model_ECHO:
-		return "Echo (Dev/Test)"
-	default:
-		return fmt.Sprintf("Unknown Model %d", int(model))
+	if reg, ok := Lookup(model); ok {
+		return reg.DisplayName
 	}
+	return fmt.Sprintf("Unknown Model %d", int(model))
 }
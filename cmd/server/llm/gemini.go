@@ -1,18 +1,53 @@
+//go:build !no_gemini
+
 package llm
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"iter"
 	"log/slog"
+	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"google.golang.org/genai"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	pb "microchat.ai/proto"
 )
 
+func init() {
+	Register(Registration{
+		Name:        "gemini",
+		DisplayName: "Gemini-2.5-Flash-Lite",
+		Models:      []pb.Model{pb.Model_GEMINI_2_5_FLASH_LITE},
+		New:         NewGeminiProvider,
+		NewEmbedder: NewGeminiEmbedder,
+		Configured:  func() bool { return APIKey("gemini") != "" },
+		APIKeyEnv:   "GEMINI_API_KEY",
+		Validate: func() error {
+			if _, err := GeminiSafetySettings(); err != nil {
+				return err
+			}
+			if _, err := LoadKeyPoolConfig("gemini"); err != nil {
+				return err
+			}
+			if _, err := LoadTimeout("gemini", geminiModel(), defaultGeminiTimeout); err != nil {
+				return err
+			}
+			if _, err := LoadTimeout("gemini", geminiEmbeddingModel(), defaultGeminiTimeout); err != nil {
+				return err
+			}
+			_, err := LoadRetryPolicy("gemini")
+			return err
+		},
+	})
+}
+
 // GeminiClient interface for testing
 type GeminiClient interface {
 	Models() GeminiModels
@@ -20,17 +55,44 @@ type GeminiClient interface {
 
 type GeminiModels interface {
 	GenerateContent(ctx context.Context, model string, content []*genai.Content, opts *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error)
+	GenerateContentStream(ctx context.Context, model string, content []*genai.Content, opts *genai.GenerateContentConfig) iter.Seq2[*genai.GenerateContentResponse, error]
+	Get(ctx context.Context, model string, config *genai.GetModelConfig) (*genai.Model, error)
+	EmbedContent(ctx context.Context, model string, contents []*genai.Content, config *genai.EmbedContentConfig) (*genai.EmbedContentResponse, error)
 }
 
 // GeminiProvider implements Provider interface using Google's Gemini API
 type GeminiProvider struct {
-	client GeminiClient
-	logger *slog.Logger
+	client    GeminiClient
+	logger    *slog.Logger
+	retry     RetryPolicy                               // Zero value falls back to DefaultRetryPolicy
+	timeout   time.Duration                             // Per-call deadline (0 = defaultGeminiTimeout)
+	apiKey    string                                    // Pooled key the client uses (empty for injected clients)
+	newClient func(apiKey string) (GeminiClient, error) // Creates a client for another pooled key (nil = no failover)
 }
 
 // NewGeminiProvider creates a new Gemini provider
 func NewGeminiProvider(logger *slog.Logger) (Provider, error) {
-	apiKey := os.Getenv("GEMINI_API_KEY")
+	apiKey, err := AcquireAPIKey("gemini")
+	if err != nil {
+		return nil, fmt.Errorf("GEMINI_API_KEY environment variable not set")
+	}
+	client, err := newGenaiClient(apiKey)
+	if err != nil {
+		return nil, err
+	}
+	retry, err := LoadRetryPolicy("gemini")
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := LoadTimeout("gemini", geminiModel(), defaultGeminiTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return &GeminiProvider{client: client, logger: logger, retry: retry, timeout: timeout, apiKey: apiKey, newClient: newGenaiClient}, nil
+}
+
+// newGenaiClient creates a Gemini API client with one of the GEMINI_API_KEY keys
+func newGenaiClient(apiKey string) (GeminiClient, error) {
 	if apiKey == "" {
 		return nil, fmt.Errorf("GEMINI_API_KEY environment variable not set")
 	}
@@ -43,8 +105,7 @@ func NewGeminiProvider(logger *slog.Logger) (Provider, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Gemini client: %w", err)
 	}
-
-	return &GeminiProvider{client: &genaiClientWrapper{client: client}, logger: logger}, nil
+	return &genaiClientWrapper{client: client}, nil
 }
 
 // genaiClientWrapper adapts the real genai.Client to our interface
@@ -64,31 +125,143 @@ func (w *genaiModelsWrapper) GenerateContent(ctx context.Context, model string,
 	return w.models.GenerateContent(ctx, model, content, opts)
 }
 
-// GenerateResponse sends the conversation history to Gemini and returns the response
-func (g *GeminiProvider) GenerateResponse(ctx context.Context, messages []Message) (string, error) {
-	model := os.Getenv("GEMINI_MODEL")
-	if model == "" {
-		model = "gemini-2.5-flash-lite" // default
+func (w *genaiModelsWrapper) GenerateContentStream(ctx context.Context, model string, content []*genai.Content, opts *genai.GenerateContentConfig) iter.Seq2[*genai.GenerateContentResponse, error] {
+	return w.models.GenerateContentStream(ctx, model, content, opts)
+}
+
+func (w *genaiModelsWrapper) Get(ctx context.Context, model string, config *genai.GetModelConfig) (*genai.Model, error) {
+	return w.models.Get(ctx, model, config)
+}
+
+func (w *genaiModelsWrapper) EmbedContent(ctx context.Context, model string, contents []*genai.Content, config *genai.EmbedContentConfig) (*genai.EmbedContentResponse, error) {
+	return w.models.EmbedContent(ctx, model, contents, config)
+}
+
+// geminiSafetyCategories maps each configurable harm category to its env var
+var geminiSafetyCategories = []struct {
+	category genai.HarmCategory
+	envVar   string
+}{
+	{genai.HarmCategoryHarassment, "GEMINI_SAFETY_HARASSMENT"},
+	{genai.HarmCategoryHateSpeech, "GEMINI_SAFETY_HATE_SPEECH"},
+	{genai.HarmCategorySexuallyExplicit, "GEMINI_SAFETY_SEXUALLY_EXPLICIT"},
+	{genai.HarmCategoryDangerousContent, "GEMINI_SAFETY_DANGEROUS_CONTENT"},
+}
+
+// defaultSafetyThreshold applies to any category without an explicit threshold
+const defaultSafetyThreshold = genai.HarmBlockThresholdBlockMediumAndAbove
+
+// parseSafetyThreshold converts a config value like BLOCK_ONLY_HIGH to a threshold
+func parseSafetyThreshold(value string) (genai.HarmBlockThreshold, error) {
+	threshold := genai.HarmBlockThreshold(strings.ToUpper(strings.TrimSpace(value)))
+	switch threshold {
+	case genai.HarmBlockThresholdBlockLowAndAbove,
+		genai.HarmBlockThresholdBlockMediumAndAbove,
+		genai.HarmBlockThresholdBlockOnlyHigh,
+		genai.HarmBlockThresholdBlockNone,
+		genai.HarmBlockThresholdOff:
+		return threshold, nil
+	default:
+		return "", fmt.Errorf("unknown safety threshold %q (expected BLOCK_LOW_AND_ABOVE, BLOCK_MEDIUM_AND_ABOVE, BLOCK_ONLY_HIGH, BLOCK_NONE or OFF)", value)
+	}
+}
+
+// defaultSafetySettings blocks medium and above in every category
+func defaultSafetySettings() []*genai.SafetySetting {
+	settings := make([]*genai.SafetySetting, len(geminiSafetyCategories))
+	for i, c := range geminiSafetyCategories {
+		settings[i] = &genai.SafetySetting{Category: c.category, Threshold: defaultSafetyThreshold}
+	}
+	return settings
+}
+
+// GeminiSafetySettings builds per-category safety settings from GEMINI_SAFETY_* env vars
+// Unset categories use BLOCK_MEDIUM_AND_ABOVE
+func GeminiSafetySettings() ([]*genai.SafetySetting, error) {
+	settings := defaultSafetySettings()
+	for i, c := range geminiSafetyCategories {
+		value := os.Getenv(c.envVar)
+		if value == "" {
+			continue
+		}
+		threshold, err := parseSafetyThreshold(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", c.envVar, err)
+		}
+		settings[i].Threshold = threshold
+	}
+	return settings, nil
+}
+
+// buildGeminiContents maps conversation messages to Gemini user/model turns
+// Consecutive messages from the same role are merged into one turn, and
+// system messages are collected into a separate system instruction
+func buildGeminiContents(messages []Message) ([]*genai.Content, *genai.Content) {
+	var contents []*genai.Content
+	var systemParts []*genai.Part
+
+	for _, msg := range messages {
+		if msg.Role == "system" {
+			systemParts = append(systemParts, genai.NewPartFromText(msg.Text))
+			continue
+		}
+
+		// Tool exchanges become a model function call followed by the user's function response
+		if msg.Role == "tool" && msg.Tool != nil {
+			contents = appendGeminiPart(contents, genai.RoleModel,
+				&genai.Part{FunctionCall: &genai.FunctionCall{ID: msg.Tool.Call.ID, Name: msg.Tool.Call.Name, Args: msg.Tool.Call.Args}})
+			response := map[string]any{"output": msg.Tool.Output}
+			if msg.Tool.Error != "" {
+				response = map[string]any{"error": msg.Tool.Error}
+			}
+			contents = appendGeminiPart(contents, genai.RoleUser,
+				&genai.Part{FunctionResponse: &genai.FunctionResponse{ID: msg.Tool.Call.ID, Name: msg.Tool.Call.Name, Response: response}})
+			continue
+		}
+
+		var role genai.Role = genai.RoleUser
+		if msg.Role == "assistant" {
+			role = genai.RoleModel
+		}
+
+		contents = appendGeminiPart(contents, role, genai.NewPartFromText(msg.Text))
+		for _, attachment := range msg.Attachments {
+			contents = appendGeminiPart(contents, role, genai.NewPartFromBytes(attachment.Data, attachment.MIMEType))
+		}
+	}
+
+	var systemInstruction *genai.Content
+	if len(systemParts) > 0 {
+		systemInstruction = genai.NewContentFromParts(systemParts, genai.RoleUser)
+	}
+	return contents, systemInstruction
+}
+
+// geminiModel returns the configured Gemini model name
+func geminiModel() string {
+	if model := os.Getenv("GEMINI_MODEL"); model != "" {
+		return model
+	}
+	return "gemini-2.5-flash-lite" // default
+}
+
+// callTimeout returns the deadline for one Gemini call
+func (g *GeminiProvider) callTimeout() time.Duration {
+	if g.timeout > 0 {
+		return g.timeout
 	}
+	return defaultGeminiTimeout
+}
+
+// prepareRequest builds the model name, contents and generation config for a conversation
+func (g *GeminiProvider) prepareRequest(messages []Message) (string, []*genai.Content, *genai.GenerateContentConfig, error) {
+	model := geminiModel()
 
 	// Configure safety settings for content filtering
-	safetySettings := []*genai.SafetySetting{
-		{
-			Category:  genai.HarmCategoryHarassment,
-			Threshold: genai.HarmBlockThresholdBlockMediumAndAbove,
-		},
-		{
-			Category:  genai.HarmCategoryHateSpeech,
-			Threshold: genai.HarmBlockThresholdBlockMediumAndAbove,
-		},
-		{
-			Category:  genai.HarmCategorySexuallyExplicit,
-			Threshold: genai.HarmBlockThresholdBlockMediumAndAbove,
-		},
-		{
-			Category:  genai.HarmCategoryDangerousContent,
-			Threshold: genai.HarmBlockThresholdBlockMediumAndAbove,
-		},
+	safetySettings, err := GeminiSafetySettings()
+	if err != nil {
+		g.logger.Warn("invalid Gemini safety settings, using defaults", "error", err)
+		safetySettings = defaultSafetySettings()
 	}
 
 	// Configure max output tokens (default: 2048 tokens ≈ 1500 words)
@@ -104,80 +277,309 @@ func (g *GeminiProvider) GenerateResponse(ctx context.Context, messages []Messag
 		MaxOutputTokens: maxTokens,
 	}
 
-	// Convert our messages to Gemini format
-	var parts []*genai.Part
-	for _, msg := range messages {
-		parts = append(parts, genai.NewPartFromText(fmt.Sprintf("%s: %s", msg.Role, msg.Text)))
+	// Convert our messages to role-structured Gemini content
+	content, systemInstruction := buildGeminiContents(messages)
+
+	// If no conversation turns, return error
+	if len(content) == 0 {
+		return "", nil, nil, status.Error(codes.InvalidArgument, "no messages to process")
+	}
+
+	// System prompts come from system-role messages, falling back to GEMINI_SYSTEM_INSTRUCTION
+	if systemInstruction == nil {
+		if instruction := os.Getenv("GEMINI_SYSTEM_INSTRUCTION"); instruction != "" {
+			systemInstruction = genai.NewContentFromText(instruction, genai.RoleUser)
+		}
 	}
+	generateConfig.SystemInstruction = systemInstruction
 
-	// If no messages, return error
-	if len(parts) == 0 {
-		return "", status.Error(codes.InvalidArgument, "no messages to process")
+	return model, content, generateConfig, nil
+}
+
+// appendGeminiPart adds a part to the conversation, merging it into the last turn if the role matches
+func appendGeminiPart(contents []*genai.Content, role genai.Role, part *genai.Part) []*genai.Content {
+	if last := len(contents) - 1; last >= 0 && contents[last].Role == string(role) {
+		contents[last].Parts = append(contents[last].Parts, part)
+		return contents
+	}
+	return append(contents, genai.NewContentFromParts([]*genai.Part{part}, role))
+}
+
+// GenerateResponse sends the conversation history to Gemini and returns the response
+func (g *GeminiProvider) GenerateResponse(ctx context.Context, messages []Message) (string, error) {
+	model, content, generateConfig, err := g.prepareRequest(messages)
+	if err != nil {
+		return "", err
+	}
+
+	result, err := g.generateWithRetry(ctx, model, content, generateConfig, func(r *genai.GenerateContentResponse) bool {
+		return r.Text() != ""
+	})
+	if err != nil {
+		return "", err
+	}
+	return result.Text(), nil
+}
+
+// SupportsImages implements ImageInput; Gemini models are multimodal
+func (g *GeminiProvider) SupportsImages() bool {
+	return true
+}
+
+// GenerateJSON uses Gemini's JSON mode, constraining the reply to schema when given
+func (g *GeminiProvider) GenerateJSON(ctx context.Context, messages []Message, schema map[string]any) (string, error) {
+	model, content, generateConfig, err := g.prepareRequest(messages)
+	if err != nil {
+		return "", err
+	}
+
+	generateConfig.ResponseMIMEType = "application/json"
+	if schema != nil {
+		generateConfig.ResponseJsonSchema = schema
 	}
 
-	// Create content with parts
-	content := []*genai.Content{{Parts: parts}}
+	result, err := g.generateWithRetry(ctx, model, content, generateConfig, func(r *genai.GenerateContentResponse) bool {
+		return r.Text() != ""
+	})
+	if err != nil {
+		return "", err
+	}
+	return result.Text(), nil
+}
 
-	// Retry with exponential backoff
-	var lastErr error
-	backoffDurations := []time.Duration{1 * time.Second, 2 * time.Second, 4 * time.Second}
+// GenerateWithTools lets Gemini either answer or request calls to the given tools
+func (g *GeminiProvider) GenerateWithTools(ctx context.Context, messages []Message, tools []ToolDefinition) (ToolTurn, error) {
+	model, content, generateConfig, err := g.prepareRequest(messages)
+	if err != nil {
+		return ToolTurn{}, err
+	}
 
-	for attempt := 0; attempt < 3; attempt++ {
-		// Check if context is already cancelled before attempting
-		if ctx.Err() == context.Canceled {
-			return "", status.Error(codes.Canceled, "request cancelled")
+	declarations := make([]*genai.FunctionDeclaration, len(tools))
+	for i, tool := range tools {
+		declarations[i] = &genai.FunctionDeclaration{
+			Name:                 tool.Name,
+			Description:          tool.Description,
+			ParametersJsonSchema: tool.Parameters,
 		}
+	}
+	generateConfig.Tools = []*genai.Tool{{FunctionDeclarations: declarations}}
 
-		if attempt > 0 {
-			g.logger.Warn("retrying Gemini API call", "attempt", attempt+1, "backoff", backoffDurations[attempt-1])
-			time.Sleep(backoffDurations[attempt-1])
+	result, err := g.generateWithRetry(ctx, model, content, generateConfig, func(r *genai.GenerateContentResponse) bool {
+		return r.Text() != "" || len(r.FunctionCalls()) > 0
+	})
+	if err != nil {
+		return ToolTurn{}, err
+	}
+
+	turn := ToolTurn{Text: result.Text()}
+	for _, call := range result.FunctionCalls() {
+		turn.Calls = append(turn.Calls, ToolCall{ID: call.ID, Name: call.Name, Args: call.Args})
+	}
+	return turn, nil
+}
+
+// generateWithRetry calls Gemini under the provider's retry policy, treating responses rejected by accept as empty
+// A call failing on the API key's quota or credentials is retried once with each other pooled key
+func (g *GeminiProvider) generateWithRetry(ctx context.Context, model string, content []*genai.Content, generateConfig *genai.GenerateContentConfig, accept func(*genai.GenerateContentResponse) bool) (*genai.GenerateContentResponse, error) {
+	tried := map[string]bool{g.apiKey: true}
+	for {
+		result, err := g.generateWithPolicy(ctx, model, content, generateConfig, accept)
+		if err == nil || !KeyError(err) || !g.switchKey(tried) {
+			return result, err
 		}
+	}
+}
+
+// switchKey moves the provider to a pooled key it hasn't tried yet, reporting whether it did
+func (g *GeminiProvider) switchKey(tried map[string]bool) bool {
+	if g.apiKey == "" || g.newClient == nil {
+		return false
+	}
+	apiKey, err := AcquireAPIKey("gemini")
+	if err != nil || tried[apiKey] {
+		return false
+	}
+	client, err := g.newClient(apiKey)
+	if err != nil {
+		return false
+	}
+	tried[apiKey] = true
+	g.logger.Warn("Gemini API key failed, switching to another pooled key")
+	g.client, g.apiKey = client, apiKey
+	return true
+}
 
-		// Create timeout context (30 seconds)
-		timeoutCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+// generateWithPolicy makes one Gemini call with the current key under the provider's retry policy
+func (g *GeminiProvider) generateWithPolicy(ctx context.Context, model string, content []*genai.Content, generateConfig *genai.GenerateContentConfig, accept func(*genai.GenerateContentResponse) bool) (*genai.GenerateContentResponse, error) {
+	policy := g.retry.orDefault()
 
-		// Generate content using Gemini with safety settings and token limits
-		result, err := g.client.Models().GenerateContent(timeoutCtx, model, content, generateConfig)
-		cancel() // Always cancel the timeout context
+	var result *genai.GenerateContentResponse
+	err := policy.Do(ctx, g.logger, "gemini", func(ctx context.Context, attempt int) error {
+		timeoutCtx, cancel := context.WithTimeout(ctx, g.callTimeout())
+		defer cancel()
 
+		// Generate content using Gemini with safety settings and token limits
+		resp, err := g.client.Models().GenerateContent(timeoutCtx, model, content, generateConfig)
 		if err != nil {
-			lastErr = err
-			g.logger.Warn("Gemini API call failed", "attempt", attempt+1, "error", err)
+			g.logger.Warn("Gemini API call failed", "attempt", attempt, "error", err)
 
 			// Check if this is a timeout or context cancellation
-			if timeoutCtx.Err() == context.DeadlineExceeded {
-				lastErr = status.Error(codes.DeadlineExceeded, "Gemini API timeout")
-			} else if ctx.Err() == context.Canceled {
-				// Don't retry if the original context was cancelled
-				return "", status.Error(codes.Canceled, "request cancelled")
+			if ctx.Err() == context.Canceled {
+				return status.Error(codes.Canceled, "request cancelled")
+			} else if timeoutCtx.Err() == context.DeadlineExceeded {
+				return status.Error(codes.DeadlineExceeded, "Gemini API timeout")
 			}
+			err = classifyGeminiError(err)
+			ReportAPIKey("gemini", g.apiKey, err)
+			return err
+		}
+		ReportAPIKey("gemini", g.apiKey, nil)
 
-			// Continue to next attempt
-			continue
+		// A safety block is deterministic, so retrying would return the same empty reply
+		if reason := geminiBlockReason(resp); reason != "" {
+			g.logger.Warn("Gemini blocked the response", "attempt", attempt, "reason", reason)
+			return status.Errorf(codes.FailedPrecondition, "Gemini blocked the response: %s", reason)
 		}
 
-		// Extract text from response
-		text := result.Text()
-		if text == "" {
-			lastErr = fmt.Errorf("Gemini returned empty response")
-			g.logger.Warn("Gemini returned empty response", "attempt", attempt+1)
-			continue
+		// Reject empty responses
+		if !accept(resp) {
+			g.logger.Warn("Gemini returned empty response", "attempt", attempt)
+			return fmt.Errorf("Gemini returned empty response")
 		}
 
-		g.logger.Info("Gemini API call successful", "attempt", attempt+1)
-		return text, nil
+		g.logger.Info("Gemini API call successful", "attempt", attempt)
+		recordGeminiUsage(ctx, resp)
+		result = resp
+		return nil
+	})
+	if err == nil {
+		return result, nil
 	}
 
-	// All attempts failed
-	g.logger.Error("all Gemini API attempts failed", "error", lastErr)
+	g.logger.Error("all Gemini API attempts failed", "error", err)
 
 	// Return appropriate gRPC status code
-	if grpcStatus, ok := status.FromError(lastErr); ok {
-		return "", grpcStatus.Err()
+	if grpcStatus, ok := status.FromError(err); ok {
+		return nil, grpcStatus.Err()
 	}
 
 	// Default to unavailable for unknown errors
-	return "", status.Error(codes.Unavailable, fmt.Sprintf("Gemini API failed after 3 attempts: %v", lastErr))
+	return nil, status.Error(codes.Unavailable, fmt.Sprintf("Gemini API failed after %d attempts: %v", policy.MaxAttempts, err))
+}
+
+// recordGeminiUsage adds the tokens Gemini reported for a response to the context's Usage
+// Thinking tokens are billed as output, so they count with the reply
+func recordGeminiUsage(ctx context.Context, resp *genai.GenerateContentResponse) {
+	if resp == nil || resp.UsageMetadata == nil {
+		return
+	}
+	meta := resp.UsageMetadata
+	RecordUsage(ctx, int64(meta.PromptTokenCount), int64(meta.CandidatesTokenCount)+int64(meta.ThoughtsTokenCount))
+}
+
+// classifyGeminiError maps Gemini API errors to gRPC status codes so the retry policy
+// can skip errors that can never succeed (bad key, quota, invalid request)
+// Errors without an HTTP status (network failures) are returned unchanged
+func classifyGeminiError(err error) error {
+	var apiErr genai.APIError
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+
+	var code codes.Code
+	switch {
+	case apiErr.Code == http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case apiErr.Code == http.StatusForbidden:
+		code = codes.PermissionDenied
+	case apiErr.Code == http.StatusTooManyRequests:
+		code = codes.ResourceExhausted
+	case apiErr.Code == http.StatusNotFound:
+		code = codes.NotFound
+	case apiErr.Code >= 400 && apiErr.Code < 500:
+		code = codes.InvalidArgument
+	default:
+		code = codes.Unavailable
+	}
+	return status.Errorf(code, "Gemini API error %d: %s", apiErr.Code, apiErr.Message)
+}
+
+// geminiBlockReason reports why Gemini's safety filters blocked a prompt or reply, if they did
+func geminiBlockReason(resp *genai.GenerateContentResponse) string {
+	if resp.PromptFeedback != nil && resp.PromptFeedback.BlockReason != "" {
+		return string(resp.PromptFeedback.BlockReason)
+	}
+	if len(resp.Candidates) == 0 || resp.Candidates[0] == nil {
+		return ""
+	}
+	switch reason := resp.Candidates[0].FinishReason; reason {
+	case genai.FinishReasonSafety, genai.FinishReasonBlocklist, genai.FinishReasonProhibitedContent,
+		genai.FinishReasonSPII, genai.FinishReasonImageSafety:
+		return string(reason)
+	}
+	return ""
+}
+
+// GenerateResponseStream streams Gemini's response as it is generated
+// Streams are not retried, since a partial response may already have been delivered
+func (g *GeminiProvider) GenerateResponseStream(ctx context.Context, messages []Message) (<-chan Chunk, error) {
+	model, content, generateConfig, err := g.prepareRequest(messages)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := newChunkStream()
+	go func() {
+		defer close(chunks)
+
+		// The timeout covers the whole stream
+		timeoutCtx, cancel := context.WithTimeout(ctx, g.callTimeout())
+		defer cancel()
+
+		var usage *genai.GenerateContentResponse
+		for result, err := range g.client.Models().GenerateContentStream(timeoutCtx, model, content, generateConfig) {
+			if err != nil {
+				g.logger.Warn("Gemini stream failed", "error", err)
+				switch {
+				case timeoutCtx.Err() == context.DeadlineExceeded:
+					err = status.Error(codes.DeadlineExceeded, "Gemini API timeout")
+				case ctx.Err() == context.Canceled:
+					err = status.Error(codes.Canceled, "request cancelled")
+				default:
+					ReportAPIKey("gemini", g.apiKey, classifyGeminiError(err))
+					err = status.Error(codes.Unavailable, fmt.Sprintf("Gemini stream failed: %v", err))
+				}
+				sendChunk(ctx, chunks, Chunk{Err: err})
+				return
+			}
+
+			// Each chunk carries the running totals, so the last one seen is the call's usage
+			if result.UsageMetadata != nil {
+				usage = result
+			}
+			if text := result.Text(); text != "" {
+				if !sendChunk(ctx, chunks, Chunk{Text: text}) {
+					return
+				}
+			}
+		}
+		if ctx.Err() != nil {
+			endCancelled(ctx, chunks) // The iterator may stop early without reporting the cancellation
+			return
+		}
+		ReportAPIKey("gemini", g.apiKey, nil)
+		recordGeminiUsage(ctx, usage)
+	}()
+
+	return chunks, nil
+}
+
+// HealthCheck verifies the API key and model by fetching model metadata, which uses no tokens
+func (g *GeminiProvider) HealthCheck(ctx context.Context) error {
+	if _, err := g.client.Models().Get(ctx, geminiModel(), nil); err != nil {
+		return fmt.Errorf("Gemini health check failed: %w", err)
+	}
+	return nil
 }
 
 // Name returns the provider name
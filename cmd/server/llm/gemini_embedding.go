@@ -0,0 +1,128 @@
+//go:build !no_gemini
+
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"google.golang.org/genai"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultGeminiEmbeddingModel = "gemini-embedding-001"
+	geminiEmbeddingBatchSize    = 100 // Gemini API limit per batch request
+)
+
+// GeminiEmbedder implements EmbeddingProvider using Gemini embedding models
+type GeminiEmbedder struct {
+	client  GeminiClient
+	model   string
+	logger  *slog.Logger
+	retry   RetryPolicy   // Shares the Gemini provider's GEMINI_RETRY_* policy
+	timeout time.Duration // Per-call deadline from GEMINI_TIMEOUT or GEMINI_TIMEOUT_<MODEL> (0 = defaultGeminiTimeout)
+}
+
+// NewGeminiEmbedder creates a Gemini embedding provider
+// The model defaults to gemini-embedding-001 and can be overridden with GEMINI_EMBEDDING_MODEL
+func NewGeminiEmbedder(logger *slog.Logger) (EmbeddingProvider, error) {
+	apiKey, err := AcquireAPIKey("gemini")
+	if err != nil {
+		return nil, fmt.Errorf("GEMINI_API_KEY environment variable not set")
+	}
+	client, err := newGenaiClient(apiKey)
+	if err != nil {
+		return nil, err
+	}
+
+	model := geminiEmbeddingModel()
+	retry, err := LoadRetryPolicy("gemini")
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := LoadTimeout("gemini", model, defaultGeminiTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return &GeminiEmbedder{client: client, model: model, logger: logger, retry: retry, timeout: timeout}, nil
+}
+
+// geminiEmbeddingModel returns the configured Gemini embedding model name
+func geminiEmbeddingModel() string {
+	if model := os.Getenv("GEMINI_EMBEDDING_MODEL"); model != "" {
+		return model
+	}
+	return defaultGeminiEmbeddingModel
+}
+
+// Embed sends one batch request with a content per text, retrying under the Gemini retry policy
+func (g *GeminiEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+
+	var vectors [][]float32
+	err := g.retry.Do(ctx, g.logger, "gemini", func(ctx context.Context, attempt int) error {
+		var err error
+		vectors, err = g.embedOnce(ctx, texts)
+		return err
+	})
+	return vectors, err
+}
+
+// embedOnce makes a single embedding call
+func (g *GeminiEmbedder) embedOnce(ctx context.Context, texts []string) ([][]float32, error) {
+
+	contents := make([]*genai.Content, len(texts))
+	for i, text := range texts {
+		contents[i] = genai.NewContentFromText(text, genai.RoleUser)
+	}
+
+	timeout := g.timeout
+	if timeout <= 0 {
+		timeout = defaultGeminiTimeout
+	}
+	timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result, err := g.client.Models().EmbedContent(timeoutCtx, g.model, contents, nil)
+	if err != nil {
+		g.logger.Warn("Gemini embedding call failed", "model", g.model, "texts", len(texts), "error", err)
+		switch {
+		case errors.Is(ctx.Err(), context.Canceled):
+			return nil, status.Error(codes.Canceled, "request cancelled")
+		case errors.Is(timeoutCtx.Err(), context.DeadlineExceeded):
+			return nil, status.Error(codes.DeadlineExceeded, "Gemini embedding timeout")
+		default:
+			if classified, ok := status.FromError(classifyGeminiError(err)); ok {
+				return nil, classified.Err()
+			}
+			return nil, status.Error(codes.Unavailable, fmt.Sprintf("Gemini embedding failed: %v", err))
+		}
+	}
+
+	vectors := make([][]float32, len(result.Embeddings))
+	for i, embedding := range result.Embeddings {
+		if embedding == nil || len(embedding.Values) == 0 {
+			return nil, status.Error(codes.Unavailable, "Gemini returned an empty embedding")
+		}
+		vectors[i] = embedding.Values
+	}
+	return vectors, nil
+}
+
+// MaxBatchSize implements EmbeddingProvider
+func (g *GeminiEmbedder) MaxBatchSize() int {
+	return geminiEmbeddingBatchSize
+}
+
+// Name returns the embedding provider name
+func (g *GeminiEmbedder) Name() string {
+	return "Gemini-Embedding"
+}
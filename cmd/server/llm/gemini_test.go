@@ -1,16 +1,21 @@
+//go:build !no_gemini
+
 package llm
 
 import (
 	"context"
 	"errors"
+	"iter"
 	"log/slog"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"google.golang.org/genai"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	pb "microchat.ai/proto"
 )
 
 func TestGeminiProvider_GenerateResponse_EmptyMessages(t *testing.T) {
@@ -55,12 +60,117 @@ func TestGeminiProvider_Name(t *testing.T) {
 	}
 }
 
+func TestGeminiSafetySettings(t *testing.T) {
+	settings, err := GeminiSafetySettings()
+	if err != nil {
+		t.Fatalf("unexpected error with defaults: %v", err)
+	}
+	for _, s := range settings {
+		if s.Threshold != genai.HarmBlockThresholdBlockMediumAndAbove {
+			t.Errorf("expected default threshold for %s, got %s", s.Category, s.Threshold)
+		}
+	}
+
+	t.Setenv("GEMINI_SAFETY_HARASSMENT", "block_only_high")
+	t.Setenv("GEMINI_SAFETY_DANGEROUS_CONTENT", "BLOCK_LOW_AND_ABOVE")
+	settings, err = GeminiSafetySettings()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[genai.HarmCategory]genai.HarmBlockThreshold{
+		genai.HarmCategoryHarassment:       genai.HarmBlockThresholdBlockOnlyHigh,
+		genai.HarmCategoryHateSpeech:       genai.HarmBlockThresholdBlockMediumAndAbove,
+		genai.HarmCategorySexuallyExplicit: genai.HarmBlockThresholdBlockMediumAndAbove,
+		genai.HarmCategoryDangerousContent: genai.HarmBlockThresholdBlockLowAndAbove,
+	}
+	for _, s := range settings {
+		if s.Threshold != expected[s.Category] {
+			t.Errorf("expected %s for %s, got %s", expected[s.Category], s.Category, s.Threshold)
+		}
+	}
+
+	t.Setenv("GEMINI_SAFETY_HATE_SPEECH", "BLOCK_EVERYTHING")
+	if _, err := GeminiSafetySettings(); err == nil {
+		t.Error("expected error for unknown threshold")
+	}
+}
+
+func TestBuildGeminiContents(t *testing.T) {
+	contents, system := buildGeminiContents([]Message{
+		{Role: "system", Text: "Be brief."},
+		{Role: "user", Text: "Hi"},
+		{Role: "assistant", Text: "Hello!"},
+		{Role: "user", Text: "First"},
+		{Role: "user", Text: "Second"},
+	})
+
+	if system == nil || len(system.Parts) != 1 || system.Parts[0].Text != "Be brief." {
+		t.Fatalf("expected system instruction from system message, got %+v", system)
+	}
+
+	expected := []struct {
+		role  string
+		parts int
+	}{
+		{genai.RoleUser, 1},
+		{genai.RoleModel, 1},
+		{genai.RoleUser, 2}, // consecutive user messages merged into one turn
+	}
+	if len(contents) != len(expected) {
+		t.Fatalf("expected %d turns, got %d", len(expected), len(contents))
+	}
+	for i, e := range expected {
+		if contents[i].Role != e.role || len(contents[i].Parts) != e.parts {
+			t.Errorf("turn %d: expected role %s with %d parts, got role %s with %d parts",
+				i, e.role, e.parts, contents[i].Role, len(contents[i].Parts))
+		}
+	}
+	if contents[0].Parts[0].Text != "Hi" {
+		t.Errorf("expected message text without role prefix, got %q", contents[0].Parts[0].Text)
+	}
+}
+
+func TestBuildGeminiContents_Attachments(t *testing.T) {
+	contents, _ := buildGeminiContents([]Message{
+		{Role: "user", Text: "What is this?", Attachments: []Attachment{{MIMEType: "image/png", Data: []byte("png")}}},
+	})
+
+	if len(contents) != 1 || len(contents[0].Parts) != 2 {
+		t.Fatalf("expected one user turn with text and image parts, got %+v", contents)
+	}
+	if blob := contents[0].Parts[1].InlineData; blob == nil || blob.MIMEType != "image/png" || string(blob.Data) != "png" {
+		t.Errorf("expected inline image data, got %+v", contents[0].Parts[1])
+	}
+}
+
+func TestBuildGeminiContents_ToolCalls(t *testing.T) {
+	call := ToolCall{ID: "call-1", Name: "calculator", Args: map[string]any{"expression": "2+2"}}
+	contents, _ := buildGeminiContents([]Message{
+		{Role: "user", Text: "What is 2+2?"},
+		{Role: "tool", Tool: &ToolExchange{Call: call, Output: "4"}},
+	})
+
+	if len(contents) != 3 {
+		t.Fatalf("expected user turn, function call and function response, got %d turns", len(contents))
+	}
+	if fc := contents[1].Parts[0].FunctionCall; contents[1].Role != genai.RoleModel || fc == nil || fc.Name != "calculator" {
+		t.Errorf("expected model function call, got %+v", contents[1])
+	}
+	fr := contents[2].Parts[0].FunctionResponse
+	if contents[2].Role != genai.RoleUser || fr == nil || fr.Response["output"] != "4" {
+		t.Errorf("expected user function response with output, got %+v", contents[2])
+	}
+}
+
 // MockGenaiClient implements GeminiClient interface for testing
 type MockGenaiClient struct {
 	shouldFail   bool
 	failAttempts int
 	responseText string
 	callDelay    time.Duration
+	failWith     error               // Returned by every GenerateContent call when set
+	blockReason  genai.BlockedReason // Simulates a prompt blocked by safety filters
+	calls        int
 }
 
 type MockModels struct {
@@ -81,6 +191,14 @@ func (m *MockModels) GenerateContent(ctx context.Context, model string, content
 		}
 	}
 
+	m.client.calls++
+	if m.client.failWith != nil {
+		return nil, m.client.failWith
+	}
+	if m.client.blockReason != "" {
+		return &genai.GenerateContentResponse{PromptFeedback: &genai.GenerateContentResponsePromptFeedback{BlockReason: m.client.blockReason}}, nil
+	}
+
 	// Simulate failures for retry testing
 	if m.client.failAttempts > 0 {
 		m.client.failAttempts--
@@ -105,6 +223,127 @@ func (m *MockModels) GenerateContent(ctx context.Context, model string, content
 	}, nil
 }
 
+func (m *MockModels) GenerateContentStream(ctx context.Context, model string, content []*genai.Content, opts *genai.GenerateContentConfig) iter.Seq2[*genai.GenerateContentResponse, error] {
+	return func(yield func(*genai.GenerateContentResponse, error) bool) {
+		if m.client.shouldFail {
+			yield(nil, errors.New("simulated Gemini API failure"))
+			return
+		}
+		for _, word := range strings.SplitAfter(m.client.responseText, " ") {
+			resp := &genai.GenerateContentResponse{
+				Candidates: []*genai.Candidate{{Content: &genai.Content{Parts: []*genai.Part{genai.NewPartFromText(word)}}}},
+			}
+			if !yield(resp, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (m *MockModels) Get(ctx context.Context, model string, config *genai.GetModelConfig) (*genai.Model, error) {
+	if m.client.shouldFail {
+		return nil, errors.New("simulated Gemini API failure")
+	}
+	return &genai.Model{Name: model}, nil
+}
+
+func (m *MockModels) EmbedContent(ctx context.Context, model string, contents []*genai.Content, config *genai.EmbedContentConfig) (*genai.EmbedContentResponse, error) {
+	if m.client.shouldFail {
+		return nil, errors.New("simulated Gemini API failure")
+	}
+	resp := &genai.EmbedContentResponse{}
+	for i := range contents {
+		resp.Embeddings = append(resp.Embeddings, &genai.ContentEmbedding{Values: []float32{float32(i), 1}})
+	}
+	return resp, nil
+}
+
+func TestGeminiEmbedder(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	embedder := &GeminiEmbedder{client: &MockGenaiClient{}, model: defaultGeminiEmbeddingModel, logger: logger, retry: RetryPolicy{MaxAttempts: 1}}
+
+	vectors, err := EmbedBatched(context.Background(), embedder, make([]string, 250))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(vectors) != 250 {
+		t.Fatalf("expected 250 vectors, got %d", len(vectors))
+	}
+	// Batches of 100: the third batch restarts indexing at 0
+	if vectors[99][0] != 99 || vectors[200][0] != 0 {
+		t.Errorf("expected vectors in input order across batches, got %v and %v", vectors[99], vectors[200])
+	}
+
+	embedder.client = &MockGenaiClient{shouldFail: true}
+	if _, err := embedder.Embed(context.Background(), []string{"x"}); status.Code(err) != codes.Unavailable {
+		t.Errorf("expected Unavailable on API failure, got %v", err)
+	}
+}
+
+func TestGeminiProvider_HealthCheck(t *testing.T) {
+	provider := &GeminiProvider{client: &MockGenaiClient{}}
+	if err := provider.HealthCheck(context.Background()); err != nil {
+		t.Errorf("expected healthy provider, got %v", err)
+	}
+
+	provider.client = &MockGenaiClient{shouldFail: true}
+	if err := provider.HealthCheck(context.Background()); err == nil {
+		t.Error("expected health check to fail")
+	}
+}
+
+func TestGeminiProvider_GenerateResponseStream(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	provider := &GeminiProvider{
+		client: &MockGenaiClient{responseText: "streamed Gemini reply"},
+		logger: logger,
+	}
+
+	chunks, err := provider.GenerateResponseStream(context.Background(), []Message{{Role: "user", Text: "Hi"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var count int
+	var text strings.Builder
+	for chunk := range chunks {
+		if chunk.Err != nil {
+			t.Fatalf("unexpected stream error: %v", chunk.Err)
+		}
+		count++
+		text.WriteString(chunk.Text)
+	}
+	if text.String() != "streamed Gemini reply" || count != 3 {
+		t.Errorf("expected 3 chunks forming the reply, got %d chunks: %q", count, text.String())
+	}
+
+	provider.client = &MockGenaiClient{shouldFail: true}
+	chunks, err = provider.GenerateResponseStream(context.Background(), []Message{{Role: "user", Text: "Hi"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := CollectStream(chunks); status.Code(err) != codes.Unavailable {
+		t.Errorf("expected Unavailable stream error, got %v", err)
+	}
+
+	// Cancelling mid-stream is reported rather than ending the stream as if the reply were complete
+	provider.client = &MockGenaiClient{responseText: strings.Repeat("word ", 100)}
+	ctx, cancel := context.WithCancel(context.Background())
+	chunks, err = provider.GenerateResponseStream(ctx, []Message{{Role: "user", Text: "Hi"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	<-chunks
+	cancel()
+	if _, err := CollectStream(chunks); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled from a cancelled stream, got %v", err)
+	}
+
+	if _, err := provider.GenerateResponseStream(context.Background(), nil); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for empty messages, got %v", err)
+	}
+}
+
 func TestGeminiProvider_GenerateResponse_RetrySuccess(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
 
@@ -139,6 +378,82 @@ func TestGeminiProvider_GenerateResponse_RetrySuccess(t *testing.T) {
 	}
 }
 
+func TestGeminiProvider_GenerateResponse_NonRetryable(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	messages := []Message{{Role: "user", Text: "Hello"}}
+
+	tests := []struct {
+		name      string
+		client    *MockGenaiClient
+		code      codes.Code
+		errorType string
+	}{
+		{"invalid key", &MockGenaiClient{failWith: genai.APIError{Code: 401, Message: "API key not valid"}}, codes.Unauthenticated, "auth"},
+		{"permission", &MockGenaiClient{failWith: genai.APIError{Code: 403, Message: "permission denied"}}, codes.PermissionDenied, "auth"},
+		{"quota", &MockGenaiClient{failWith: genai.APIError{Code: 429, Message: "quota exceeded"}}, codes.ResourceExhausted, "quota"},
+		{"bad request", &MockGenaiClient{failWith: genai.APIError{Code: 400, Message: "invalid argument"}}, codes.InvalidArgument, "invalid_request"},
+		{"safety block", &MockGenaiClient{blockReason: genai.BlockedReasonSafety}, codes.FailedPrecondition, "safety_block"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &GeminiProvider{client: tt.client, logger: logger}
+
+			start := time.Now()
+			_, err := provider.GenerateResponse(context.Background(), messages)
+			if status.Code(err) != tt.code {
+				t.Fatalf("expected %v, got %v", tt.code, err)
+			}
+			if tt.client.calls != 1 || time.Since(start) > time.Second {
+				t.Errorf("expected a single attempt without backoff, got %d calls in %v", tt.client.calls, time.Since(start))
+			}
+			if got := ErrorType(err); got != tt.errorType {
+				t.Errorf("expected error type %q, got %q", tt.errorType, got)
+			}
+		})
+	}
+
+	// Server errors are still retried
+	client := &MockGenaiClient{failWith: genai.APIError{Code: 503, Message: "overloaded"}}
+	provider := &GeminiProvider{client: client, logger: logger, retry: RetryPolicy{MaxAttempts: 2, BaseBackoff: time.Millisecond, RetryableCodes: []codes.Code{codes.Unavailable}}}
+	if _, err := provider.GenerateResponse(context.Background(), messages); status.Code(err) != codes.Unavailable || client.calls != 2 {
+		t.Errorf("expected 503 to be retried, got %v after %d calls", err, client.calls)
+	}
+}
+
+func TestGeminiProvider_KeyFailover(t *testing.T) {
+	resetKeyPool(t, "gemini")
+	t.Setenv("GEMINI_API_KEY", "key-a,key-b")
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	exhausted := &MockGenaiClient{failWith: genai.APIError{Code: 429, Message: "quota exceeded"}}
+	healthy := &MockGenaiClient{responseText: "Hello from key-b"}
+	clients := map[string]*MockGenaiClient{"key-a": exhausted, "key-b": healthy}
+
+	apiKey, _ := AcquireAPIKey("gemini")
+	provider := &GeminiProvider{
+		client: clients[apiKey],
+		logger: logger,
+		apiKey: apiKey,
+		newClient: func(apiKey string) (GeminiClient, error) {
+			return clients[apiKey], nil
+		},
+	}
+
+	response, err := provider.GenerateResponse(context.Background(), []Message{{Role: "user", Text: "Hello"}})
+	if err != nil || response != "Hello from key-b" {
+		t.Fatalf("expected failover to the other key, got %q, %v", response, err)
+	}
+	if exhausted.calls != 1 || healthy.calls != 1 {
+		t.Errorf("expected one call per key, got %d and %d", exhausted.calls, healthy.calls)
+	}
+
+	// With every key exhausted the quota error is returned
+	healthy.failWith = genai.APIError{Code: 429, Message: "quota exceeded"}
+	if _, err := provider.GenerateResponse(context.Background(), []Message{{Role: "user", Text: "Hello"}}); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("expected ResourceExhausted once every key is exhausted, got %v", err)
+	}
+}
+
 func TestGeminiProvider_GenerateResponse_TimeoutWithRetry(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
 
@@ -176,3 +491,38 @@ func TestGeminiProvider_GenerateResponse_TimeoutWithRetry(t *testing.T) {
 		t.Fatalf("took too long, expected timeouts to fail fast: %v", duration)
 	}
 }
+
+func TestNewProviderWithHealth(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	t.Setenv("GEMINI_API_KEY", "test-key")
+	t.Setenv("APP_ENV", "production")
+
+	gemini := NewMockProvider("gemini")
+	monitor := NewHealthMonitor([]HealthProbe{
+		{Name: "gemini", New: func() (Provider, error) { return gemini, nil }},
+	}, logger)
+
+	monitor.CheckAll(context.Background())
+	if _, ok := NewProviderWithHealth(pb.Model_GEMINI_2_5_FLASH_LITE, logger, monitor).(*GeminiProvider); !ok {
+		t.Error("expected Gemini provider while Gemini is healthy")
+	}
+
+	gemini.SetError("quota exhausted")
+	monitor.CheckAll(context.Background())
+	if _, ok := NewProviderWithHealth(pb.Model_GEMINI_2_5_FLASH_LITE, logger, monitor).(*EchoProvider); !ok {
+		t.Error("expected Echo fallback while Gemini is unhealthy")
+	}
+}
+
+func TestRecordGeminiUsage(t *testing.T) {
+	ctx, usage := WithUsage(context.Background())
+	recordGeminiUsage(ctx, nil)
+	recordGeminiUsage(ctx, &genai.GenerateContentResponse{UsageMetadata: &genai.GenerateContentResponseUsageMetadata{
+		PromptTokenCount:     100,
+		CandidatesTokenCount: 20,
+		ThoughtsTokenCount:   5,
+	}})
+	if input, output, reported := usage.Tokens(); !reported || input != 100 || output != 25 {
+		t.Errorf("expected thinking tokens to count as output, got %d/%d (reported %v)", input, output, reported)
+	}
+}
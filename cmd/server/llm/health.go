@@ -0,0 +1,171 @@
+package llm
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// healthCheckTimeout bounds each provider health check
+const healthCheckTimeout = 10 * time.Second
+
+// ProviderHealth is the most recent health check result for a provider
+type ProviderHealth struct {
+	Healthy     bool      `json:"healthy"`
+	LastChecked time.Time `json:"last_checked"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// HealthProbe constructs a provider to health check
+type HealthProbe struct {
+	Name string
+	New  func() (Provider, error)
+}
+
+// DefaultHealthProbes returns a probe for every registered provider serving this environment
+// Providers missing their required config are skipped rather than reported unhealthy
+func DefaultHealthProbes(logger *slog.Logger) []HealthProbe {
+	isDev := os.Getenv("APP_ENV") == "development"
+
+	var probes []HealthProbe
+	for _, reg := range Registered() {
+		if reg.DevOnly && !isDev {
+			continue
+		}
+		// Unconfigured providers are skipped, except the production default which must be available
+		if reg.Configured != nil && !reg.Configured() && (isDev || reg.Name != defaultProviderName) {
+			continue
+		}
+		newProvider := reg.New
+		probes = append(probes, HealthProbe{
+			Name: reg.Name,
+			New:  func() (Provider, error) { return newProvider(logger) },
+		})
+	}
+	return probes
+}
+
+// HealthMonitor periodically health checks providers and records the results
+type HealthMonitor struct {
+	mu     sync.RWMutex
+	health map[string]ProviderHealth
+	probes []HealthProbe
+	logger *slog.Logger
+
+	onUnhealthy func(name string, err error) // Called when a provider becomes unhealthy
+}
+
+// NewHealthMonitor creates a monitor for the given probes
+func NewHealthMonitor(probes []HealthProbe, logger *slog.Logger) *HealthMonitor {
+	return &HealthMonitor{
+		health: make(map[string]ProviderHealth),
+		probes: probes,
+		logger: logger,
+	}
+}
+
+// OnUnhealthy registers a callback for providers becoming unhealthy, e.g. to raise an alert
+// It is called with the monitor's lock held, so it must not block or call back into the monitor
+func (h *HealthMonitor) OnUnhealthy(fn func(name string, err error)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.onUnhealthy = fn
+}
+
+// CheckAll runs every probe once and records the results
+func (h *HealthMonitor) CheckAll(ctx context.Context) {
+	for _, probe := range h.probes {
+		h.record(probe.Name, h.check(ctx, probe))
+	}
+}
+
+// check constructs the probe's provider and runs its health check
+func (h *HealthMonitor) check(ctx context.Context, probe HealthProbe) error {
+	provider, err := probe.New()
+	if err != nil {
+		return err
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	return provider.HealthCheck(checkCtx)
+}
+
+// record stores a check result, logging transitions between healthy and unhealthy
+func (h *HealthMonitor) record(name string, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	previous, known := h.health[name]
+	current := ProviderHealth{Healthy: err == nil, LastChecked: time.Now()}
+	if err != nil {
+		current.LastError = err.Error()
+	}
+	h.health[name] = current
+
+	switch {
+	case current.Healthy && known && !previous.Healthy:
+		h.logger.Info("provider recovered", "provider", name)
+	case !current.Healthy && (!known || previous.Healthy):
+		h.logger.Warn("provider unhealthy", "provider", name, "error", err)
+		if h.onUnhealthy != nil {
+			h.onUnhealthy(name, err)
+		}
+	}
+}
+
+// Run checks all providers immediately and then on every interval until ctx is done
+func (h *HealthMonitor) Run(ctx context.Context, interval time.Duration) {
+	h.CheckAll(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			h.CheckAll(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// IsHealthy reports whether a provider passed its last check
+// Providers that haven't been checked yet are assumed healthy
+func (h *HealthMonitor) IsHealthy(name string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	health, known := h.health[name]
+	return !known || health.Healthy
+}
+
+// Ready reports whether every monitored provider passed its last check
+// Returns false until the first round of checks has completed
+func (h *HealthMonitor) Ready() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, probe := range h.probes {
+		health, known := h.health[probe.Name]
+		if !known || !health.Healthy {
+			return false
+		}
+	}
+	return true
+}
+
+// Snapshot returns a copy of the latest health results keyed by provider name
+func (h *HealthMonitor) Snapshot() map[string]ProviderHealth {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	snapshot := make(map[string]ProviderHealth, len(h.health))
+	for name, health := range h.health {
+		snapshot[name] = health
+	}
+	return snapshot
+}
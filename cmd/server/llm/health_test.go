@@ -0,0 +1,66 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+)
+
+func TestHealthMonitor(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	healthy := NewMockProvider("healthy")
+	failing := NewMockProvider("failing")
+	failing.SetError("provider down")
+
+	monitor := NewHealthMonitor([]HealthProbe{
+		{Name: "healthy", New: func() (Provider, error) { return healthy, nil }},
+		{Name: "failing", New: func() (Provider, error) { return failing, nil }},
+		{Name: "broken", New: func() (Provider, error) { return nil, errors.New("no API key") }},
+	}, logger)
+
+	if monitor.Ready() {
+		t.Error("expected monitor not to be ready before the first check")
+	}
+	if !monitor.IsHealthy("failing") {
+		t.Error("expected unchecked providers to be assumed healthy")
+	}
+
+	monitor.CheckAll(context.Background())
+
+	if !monitor.IsHealthy("healthy") {
+		t.Error("expected healthy provider to pass")
+	}
+	if monitor.IsHealthy("failing") || monitor.IsHealthy("broken") {
+		t.Error("expected failing and broken providers to be unhealthy")
+	}
+	if monitor.Ready() {
+		t.Error("expected monitor not to be ready with unhealthy providers")
+	}
+	if got := monitor.Snapshot()["broken"].LastError; got != "no API key" {
+		t.Errorf("expected constructor error to be recorded, got %q", got)
+	}
+
+}
+
+func TestHealthMonitor_OnUnhealthy(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	failing := NewMockProvider("failing")
+	failing.SetError("provider down")
+
+	monitor := NewHealthMonitor([]HealthProbe{
+		{Name: "failing", New: func() (Provider, error) { return failing, nil }},
+	}, logger)
+
+	var unhealthy []string
+	monitor.OnUnhealthy(func(name string, err error) {
+		unhealthy = append(unhealthy, name)
+	})
+
+	monitor.CheckAll(context.Background())
+	monitor.CheckAll(context.Background())
+	if len(unhealthy) != 1 || unhealthy[0] != "failing" {
+		t.Errorf("expected one callback on the transition to unhealthy, got %v", unhealthy)
+	}
+}
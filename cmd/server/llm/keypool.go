@@ -0,0 +1,235 @@
+package llm
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Key selection strategies for a provider with several upstream API keys
+const (
+	KeySelectionRoundRobin  = "round_robin"  // Take turns between keys
+	KeySelectionLeastErrors = "least_errors" // Prefer the key with the fewest failures since its last success
+)
+
+// defaultKeyCooldown is how long a key that hit its upstream quota or was rejected is skipped
+const defaultKeyCooldown = time.Minute
+
+// KeyPoolConfig controls how a provider picks between its upstream API keys
+type KeyPoolConfig struct {
+	Selection string        // KeySelectionRoundRobin or KeySelectionLeastErrors
+	Cooldown  time.Duration // How long an exhausted or rejected key is skipped
+}
+
+// LoadKeyPoolConfig reads a provider's key pool settings from <PROVIDER>_KEY_SELECTION and
+// <PROVIDER>_KEY_COOLDOWN, e.g. GEMINI_KEY_SELECTION for the "gemini" provider
+func LoadKeyPoolConfig(provider string) (KeyPoolConfig, error) {
+	cfg := KeyPoolConfig{Selection: KeySelectionRoundRobin, Cooldown: defaultKeyCooldown}
+	prefix := strings.ToUpper(provider) + "_KEY_"
+
+	if v := os.Getenv(prefix + "SELECTION"); v != "" {
+		switch v = strings.ToLower(strings.TrimSpace(v)); v {
+		case KeySelectionRoundRobin, KeySelectionLeastErrors:
+			cfg.Selection = v
+		default:
+			return cfg, fmt.Errorf("invalid %sSELECTION %q: must be %s or %s", prefix, v, KeySelectionRoundRobin, KeySelectionLeastErrors)
+		}
+	}
+
+	if v := os.Getenv(prefix + "COOLDOWN"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return cfg, fmt.Errorf("invalid %sCOOLDOWN %q: must be a non-negative duration", prefix, v)
+		}
+		cfg.Cooldown = d
+	}
+
+	return cfg, nil
+}
+
+// KeyStats is the upstream usage of one pooled API key
+type KeyStats struct {
+	Key            string    // The API key; hash it before logging or exporting
+	Calls          uint64    // Upstream calls made with the key
+	Errors         uint64    // Calls that failed because of the key (quota or auth)
+	QuotaErrors    uint64    // Calls rejected because the key's upstream quota was used up
+	ExhaustedUntil time.Time // Zero unless the key is cooling down
+}
+
+// pooledKey tracks one key's usage
+type pooledKey struct {
+	KeyStats
+	failures int // Key errors since the last success
+}
+
+// keyPool picks between a provider's API keys and tracks their upstream usage
+type keyPool struct {
+	mu     sync.Mutex
+	source string // Configured value the pool was built from, to notice rotations
+	keys   []*pooledKey
+	next   int // Round-robin position
+}
+
+// keyPools holds each provider's pool; pools outlive the per-request provider instances
+var keyPools = struct {
+	mu    sync.Mutex
+	pools map[string]*keyPool // Provider name -> pool
+}{pools: make(map[string]*keyPool)}
+
+// splitAPIKeys parses a comma-separated list of API keys
+func splitAPIKeys(value string) []string {
+	var keys []string
+	for _, key := range strings.Split(value, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// poolFor returns a provider's key pool, rebuilding it when its keys were rotated
+// Keys kept across a rotation keep their stats
+func poolFor(provider string) *keyPool {
+	source := APIKey(provider)
+
+	keyPools.mu.Lock()
+	defer keyPools.mu.Unlock()
+
+	pool := keyPools.pools[provider]
+	if pool != nil && pool.source == source {
+		return pool
+	}
+
+	previous := make(map[string]*pooledKey)
+	if pool != nil {
+		pool.mu.Lock()
+		for _, k := range pool.keys {
+			previous[k.Key] = k
+		}
+		pool.mu.Unlock()
+	}
+
+	pool = &keyPool{source: source}
+	for _, key := range splitAPIKeys(source) {
+		if k, ok := previous[key]; ok {
+			pool.keys = append(pool.keys, k)
+			continue
+		}
+		pool.keys = append(pool.keys, &pooledKey{KeyStats: KeyStats{Key: key}})
+	}
+	keyPools.pools[provider] = pool
+	return pool
+}
+
+// AcquireAPIKey picks one of a provider's upstream API keys for a call
+// The provider's API key variable may hold several comma-separated keys; keys cooling down after a
+// quota or auth error are skipped, and when every key is cooling down the one available soonest is used
+func AcquireAPIKey(provider string) (string, error) {
+	cfg, err := LoadKeyPoolConfig(provider)
+	if err != nil {
+		return "", err
+	}
+	pool := poolFor(provider)
+
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if len(pool.keys) == 0 {
+		return "", fmt.Errorf("no API key configured for %s", provider)
+	}
+
+	now := time.Now()
+	var best *pooledKey
+	bestIndex := 0
+	for i := range pool.keys {
+		// Walk from the round-robin position so ties take turns
+		index := (pool.next + i) % len(pool.keys)
+		k := pool.keys[index]
+		if best == nil || keyPreferred(k, best, now, cfg.Selection) {
+			best, bestIndex = k, index
+		}
+		if cfg.Selection == KeySelectionRoundRobin && !k.ExhaustedUntil.After(now) {
+			break
+		}
+	}
+	pool.next = (bestIndex + 1) % len(pool.keys)
+	return best.Key, nil
+}
+
+// keyPreferred reports whether candidate is a better pick than current
+func keyPreferred(candidate, current *pooledKey, now time.Time, selection string) bool {
+	candidateReady, currentReady := !candidate.ExhaustedUntil.After(now), !current.ExhaustedUntil.After(now)
+	switch {
+	case candidateReady != currentReady:
+		return candidateReady
+	case !candidateReady:
+		return candidate.ExhaustedUntil.Before(current.ExhaustedUntil)
+	case selection == KeySelectionLeastErrors:
+		return candidate.failures < current.failures
+	}
+	return false
+}
+
+// ReportAPIKey records the outcome of an upstream call made with a pooled key
+// Quota and auth errors count against the key and cool it down; other errors say nothing about the key
+func ReportAPIKey(provider, key string, err error) {
+	if key == "" {
+		return
+	}
+	cfg, cfgErr := LoadKeyPoolConfig(provider)
+	if cfgErr != nil {
+		cfg = KeyPoolConfig{Cooldown: defaultKeyCooldown}
+	}
+	pool := poolFor(provider)
+
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	for _, k := range pool.keys {
+		if k.Key != key {
+			continue
+		}
+		k.Calls++
+		switch status.Code(err) {
+		case codes.OK:
+			k.failures = 0
+			k.ExhaustedUntil = time.Time{}
+		case codes.ResourceExhausted:
+			k.QuotaErrors++
+			fallthrough
+		case codes.Unauthenticated, codes.PermissionDenied:
+			k.Errors++
+			k.failures++
+			k.ExhaustedUntil = time.Now().Add(cfg.Cooldown)
+		}
+		return
+	}
+}
+
+// KeyError reports whether err means the API key itself failed, so another key may succeed
+func KeyError(err error) bool {
+	switch status.Code(err) {
+	case codes.ResourceExhausted, codes.Unauthenticated, codes.PermissionDenied:
+		return true
+	}
+	return false
+}
+
+// APIKeyStats returns the upstream usage of each of a provider's API keys, in configured order
+func APIKeyStats(provider string) []KeyStats {
+	pool := poolFor(provider)
+
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	stats := make([]KeyStats, len(pool.keys))
+	for i, k := range pool.keys {
+		stats[i] = k.KeyStats
+	}
+	return stats
+}
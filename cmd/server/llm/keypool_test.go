@@ -0,0 +1,120 @@
+package llm
+
+import (
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// resetKeyPool drops a provider's pool and rotated key so tests start from the environment
+func resetKeyPool(t *testing.T, provider string) {
+	t.Helper()
+	t.Cleanup(func() {
+		ClearAPIKey(provider)
+		keyPools.mu.Lock()
+		delete(keyPools.pools, provider)
+		keyPools.mu.Unlock()
+	})
+}
+
+func TestAcquireAPIKey_RoundRobin(t *testing.T) {
+	resetKeyPool(t, "gemini")
+	t.Setenv("GEMINI_API_KEY", "key-a, key-b,key-c")
+
+	var picked []string
+	for range 4 {
+		key, err := AcquireAPIKey("gemini")
+		if err != nil {
+			t.Fatalf("AcquireAPIKey failed: %v", err)
+		}
+		picked = append(picked, key)
+	}
+	if picked[0] != "key-a" || picked[1] != "key-b" || picked[2] != "key-c" || picked[3] != "key-a" {
+		t.Errorf("expected keys to take turns, got %v", picked)
+	}
+
+	// An exhausted key is skipped until its cooldown ends
+	ReportAPIKey("gemini", "key-b", status.Error(codes.ResourceExhausted, "quota"))
+	for range 4 {
+		if key, _ := AcquireAPIKey("gemini"); key == "key-b" {
+			t.Fatal("expected exhausted key to be skipped")
+		}
+	}
+
+	stats := APIKeyStats("gemini")
+	if len(stats) != 3 || stats[1].QuotaErrors != 1 || stats[1].Errors != 1 || stats[1].ExhaustedUntil.IsZero() {
+		t.Errorf("expected quota error to be tracked per key, got %+v", stats)
+	}
+
+	// Errors that aren't the key's fault don't cool it down
+	ReportAPIKey("gemini", "key-a", status.Error(codes.Unavailable, "overloaded"))
+	if stats := APIKeyStats("gemini"); stats[0].Errors != 0 || stats[0].Calls != 1 {
+		t.Errorf("expected server error not to count against the key, got %+v", stats[0])
+	}
+}
+
+func TestAcquireAPIKey_AllExhausted(t *testing.T) {
+	resetKeyPool(t, "gemini")
+	t.Setenv("GEMINI_API_KEY", "key-a,key-b")
+
+	ReportAPIKey("gemini", "key-a", status.Error(codes.ResourceExhausted, "quota"))
+	ReportAPIKey("gemini", "key-b", status.Error(codes.ResourceExhausted, "quota"))
+	if key, err := AcquireAPIKey("gemini"); err != nil || key != "key-a" {
+		t.Errorf("expected the key available soonest, got %q, %v", key, err)
+	}
+}
+
+func TestAcquireAPIKey_LeastErrors(t *testing.T) {
+	resetKeyPool(t, "gemini")
+	t.Setenv("GEMINI_API_KEY", "key-a,key-b,key-c")
+	t.Setenv("GEMINI_KEY_SELECTION", "least_errors")
+	t.Setenv("GEMINI_KEY_COOLDOWN", "0s")
+
+	ReportAPIKey("gemini", "key-a", status.Error(codes.PermissionDenied, "denied"))
+	ReportAPIKey("gemini", "key-a", status.Error(codes.PermissionDenied, "denied"))
+	ReportAPIKey("gemini", "key-b", status.Error(codes.ResourceExhausted, "quota"))
+
+	if key, _ := AcquireAPIKey("gemini"); key != "key-c" {
+		t.Errorf("expected the key without failures, got %q", key)
+	}
+
+	// A success clears a key's failures
+	ReportAPIKey("gemini", "key-a", nil)
+	ReportAPIKey("gemini", "key-c", status.Error(codes.ResourceExhausted, "quota"))
+	if key, _ := AcquireAPIKey("gemini"); key != "key-a" {
+		t.Errorf("expected recovered key, got %q", key)
+	}
+}
+
+func TestAcquireAPIKey_Rotation(t *testing.T) {
+	resetKeyPool(t, "gemini")
+	t.Setenv("GEMINI_API_KEY", "key-a,key-b")
+	ReportAPIKey("gemini", "key-b", nil)
+
+	if err := SetAPIKey("gemini", "key-b,key-c"); err != nil {
+		t.Fatalf("SetAPIKey failed: %v", err)
+	}
+	stats := APIKeyStats("gemini")
+	if len(stats) != 2 || stats[0].Key != "key-b" || stats[0].Calls != 1 || stats[1].Key != "key-c" {
+		t.Errorf("expected rotated pool to keep stats for retained keys, got %+v", stats)
+	}
+
+	t.Setenv("GEMINI_API_KEY", "")
+	ClearAPIKey("gemini")
+	if _, err := AcquireAPIKey("gemini"); err == nil {
+		t.Error("expected error without keys")
+	}
+}
+
+func TestLoadKeyPoolConfig(t *testing.T) {
+	t.Setenv("GEMINI_KEY_SELECTION", "fastest")
+	if _, err := LoadKeyPoolConfig("gemini"); err == nil {
+		t.Error("expected unknown selection to be rejected")
+	}
+	t.Setenv("GEMINI_KEY_SELECTION", "")
+	t.Setenv("GEMINI_KEY_COOLDOWN", "-1s")
+	if _, err := LoadKeyPoolConfig("gemini"); err == nil {
+		t.Error("expected negative cooldown to be rejected")
+	}
+}
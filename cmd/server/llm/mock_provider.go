@@ -4,15 +4,117 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+// LatencyDistribution describes how long each simulated provider call takes
+type LatencyDistribution struct {
+	Kind   string        // "fixed", "uniform" or "normal"; empty means no latency
+	Base   time.Duration // Fixed value, uniform minimum, or normal mean
+	Spread time.Duration // Uniform range (max - min), or normal standard deviation
+}
+
+// Sample draws a latency from the distribution, never negative
+func (d LatencyDistribution) Sample() time.Duration {
+	var latency time.Duration
+	switch d.Kind {
+	case "fixed":
+		latency = d.Base
+	case "uniform":
+		latency = d.Base + time.Duration(rand.Float64()*float64(d.Spread))
+	case "normal":
+		latency = d.Base + time.Duration(rand.NormFloat64()*float64(d.Spread))
+	}
+	return max(latency, 0)
+}
+
+// MockBehavior configures simulated slow or flaky provider behavior
+type MockBehavior struct {
+	Latency    LatencyDistribution // Delay before the response (or first streamed chunk)
+	ErrorRate  float64             // Fraction of calls that fail, 0-1
+	ChunkDelay time.Duration       // Delay between streamed chunks
+}
+
+// ParseMockBehavior parses a spec like "latency=normal:800ms:200ms,error_rate=0.05,chunk_delay=20ms"
+// Latency forms: fixed:<d>, uniform:<min>:<max>, normal:<mean>:<stddev>
+func ParseMockBehavior(spec string) (MockBehavior, error) {
+	var behavior MockBehavior
+
+	for _, field := range strings.Split(spec, ",") {
+		key, value, found := strings.Cut(strings.TrimSpace(field), "=")
+		if !found {
+			return behavior, fmt.Errorf("invalid mock behavior field %q (expected key=value)", field)
+		}
+
+		switch key {
+		case "latency":
+			latency, err := parseLatencyDistribution(value)
+			if err != nil {
+				return behavior, err
+			}
+			behavior.Latency = latency
+		case "error_rate":
+			rate, err := strconv.ParseFloat(value, 64)
+			if err != nil || rate < 0 || rate > 1 {
+				return behavior, fmt.Errorf("invalid error_rate %q (expected 0-1)", value)
+			}
+			behavior.ErrorRate = rate
+		case "chunk_delay":
+			delay, err := time.ParseDuration(value)
+			if err != nil || delay < 0 {
+				return behavior, fmt.Errorf("invalid chunk_delay %q", value)
+			}
+			behavior.ChunkDelay = delay
+		default:
+			return behavior, fmt.Errorf("unknown mock behavior field %q", key)
+		}
+	}
+
+	return behavior, nil
+}
+
+// parseLatencyDistribution parses fixed:<d>, uniform:<min>:<max> or normal:<mean>:<stddev>
+func parseLatencyDistribution(value string) (LatencyDistribution, error) {
+	parts := strings.Split(value, ":")
+	durations := make([]time.Duration, len(parts)-1)
+	for i, part := range parts[1:] {
+		d, err := time.ParseDuration(part)
+		if err != nil || d < 0 {
+			return LatencyDistribution{}, fmt.Errorf("invalid latency duration %q", part)
+		}
+		durations[i] = d
+	}
+
+	switch {
+	case parts[0] == "fixed" && len(durations) == 1:
+		return LatencyDistribution{Kind: "fixed", Base: durations[0]}, nil
+	case parts[0] == "uniform" && len(durations) == 2 && durations[1] >= durations[0]:
+		return LatencyDistribution{Kind: "uniform", Base: durations[0], Spread: durations[1] - durations[0]}, nil
+	case parts[0] == "normal" && len(durations) == 2:
+		return LatencyDistribution{Kind: "normal", Base: durations[0], Spread: durations[1]}, nil
+	default:
+		return LatencyDistribution{}, fmt.Errorf("invalid latency %q (expected fixed:<d>, uniform:<min>:<max> or normal:<mean>:<stddev>)", value)
+	}
+}
+
 // MockProvider is a test implementation of the Provider interface
+// Safe for concurrent use so benchmarks can share one instance
 type MockProvider struct {
+	mu            sync.Mutex
 	name          string
 	responses     []string
 	responseIndex int
 	shouldError   bool
 	errorMessage  string
+	behavior      MockBehavior
+	toolCalls     []ToolCall // Returned by the next GenerateWithTools call
 }
 
 // NewMockProvider creates a new mock provider with configurable responses
@@ -27,30 +129,69 @@ func NewMockProvider(name string) *MockProvider {
 
 // SetResponses configures the mock to return specific responses in sequence
 func (m *MockProvider) SetResponses(responses ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.responses = responses
 	m.responseIndex = 0
 }
 
 // SetError configures the mock to return an error
 func (m *MockProvider) SetError(errorMessage string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.shouldError = true
 	m.errorMessage = errorMessage
 }
 
 // ClearError configures the mock to stop returning errors
 func (m *MockProvider) ClearError() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.shouldError = false
 	m.errorMessage = ""
 }
 
-// GenerateResponse implements the Provider interface
-func (m *MockProvider) GenerateResponse(ctx context.Context, messages []Message) (string, error) {
+// SetBehavior configures simulated latency, intermittent errors and streaming speed
+func (m *MockProvider) SetBehavior(behavior MockBehavior) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.behavior = behavior
+}
+
+// SetToolCalls configures the next GenerateWithTools call to request these tool calls
+func (m *MockProvider) SetToolCalls(calls ...ToolCall) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.toolCalls = calls
+}
+
+// simulateCall applies configured latency and intermittent failures
+func (m *MockProvider) simulateCall(ctx context.Context, behavior MockBehavior) error {
+	if latency := behavior.Latency.Sample(); latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-ctx.Done():
+			return status.Error(codes.Canceled, "request cancelled")
+		}
+	}
+
+	if behavior.ErrorRate > 0 && rand.Float64() < behavior.ErrorRate {
+		return status.Error(codes.Unavailable, "simulated provider failure")
+	}
+	return nil
+}
+
+// nextResponse returns the next configured response and the current behavior
+func (m *MockProvider) nextResponse(messages []Message) (string, MockBehavior, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if m.shouldError {
-		return "", errors.New(m.errorMessage)
+		return "", m.behavior, errors.New(m.errorMessage)
 	}
 
 	if len(m.responses) == 0 {
-		return "Default mock response", nil
+		return "Default mock response", m.behavior, nil
 	}
 
 	// Cycle through responses
@@ -63,9 +204,104 @@ func (m *MockProvider) GenerateResponse(ctx context.Context, messages []Message)
 		response = fmt.Sprintf("Mock response to: '%s' - %s", lastMessage.Text, response)
 	}
 
+	return response, m.behavior, nil
+}
+
+// GenerateResponse implements the Provider interface
+func (m *MockProvider) GenerateResponse(ctx context.Context, messages []Message) (string, error) {
+	response, behavior, err := m.nextResponse(messages)
+	if err != nil {
+		return "", err
+	}
+	if err := m.simulateCall(ctx, behavior); err != nil {
+		return "", err
+	}
 	return response, nil
 }
 
+// GenerateResponseStream implements the Provider interface by streaming the response word by word
+// Latency applies before the first chunk (time to first token), then ChunkDelay between chunks
+func (m *MockProvider) GenerateResponseStream(ctx context.Context, messages []Message) (<-chan Chunk, error) {
+	response, behavior, err := m.nextResponse(messages)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := newChunkStream()
+	go func() {
+		defer close(chunks)
+
+		if err := m.simulateCall(ctx, behavior); err != nil {
+			sendChunk(ctx, chunks, Chunk{Err: err})
+			return
+		}
+
+		for i, word := range strings.SplitAfter(response, " ") {
+			if i > 0 && behavior.ChunkDelay > 0 {
+				select {
+				case <-time.After(behavior.ChunkDelay):
+				case <-ctx.Done():
+					endCancelled(ctx, chunks)
+					return
+				}
+			}
+			if !sendChunk(ctx, chunks, Chunk{Text: word}) {
+				return
+			}
+		}
+	}()
+
+	return chunks, nil
+}
+
+// GenerateWithTools implements ToolCaller, requesting any configured tool calls once
+// and otherwise answering like GenerateResponse
+func (m *MockProvider) GenerateWithTools(ctx context.Context, messages []Message, tools []ToolDefinition) (ToolTurn, error) {
+	m.mu.Lock()
+	calls := m.toolCalls
+	m.toolCalls = nil
+	m.mu.Unlock()
+
+	if len(calls) > 0 {
+		return ToolTurn{Calls: calls}, nil
+	}
+
+	reply, err := m.GenerateResponse(ctx, messages)
+	if err != nil {
+		return ToolTurn{}, err
+	}
+	return ToolTurn{Text: reply}, nil
+}
+
+// GenerateJSON implements JSONGenerator by returning the next configured response verbatim,
+// so tests control whether the payload is valid
+func (m *MockProvider) GenerateJSON(ctx context.Context, messages []Message, schema map[string]any) (string, error) {
+	response, behavior, err := m.nextResponse(nil) // No message context prefix
+	if err != nil {
+		return "", err
+	}
+	if err := m.simulateCall(ctx, behavior); err != nil {
+		return "", err
+	}
+	return response, nil
+}
+
+// SupportsImages implements ImageInput so tests can exercise attachments
+func (m *MockProvider) SupportsImages() bool {
+	return true
+}
+
+// HealthCheck implements the Provider interface, failing while the mock is configured to error
+func (m *MockProvider) HealthCheck(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.shouldError {
+		return errors.New(m.errorMessage)
+	}
+	return nil
+}
+
 // Name implements the Provider interface
 func (m *MockProvider) Name() string {
 	return m.name
@@ -73,7 +309,11 @@ func (m *MockProvider) Name() string {
 
 // Reset resets the mock's state
 func (m *MockProvider) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.responseIndex = 0
 	m.shouldError = false
 	m.errorMessage = ""
+	m.behavior = MockBehavior{}
+	m.toolCalls = nil
 }
@@ -0,0 +1,355 @@
+//go:build !no_openai
+
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	pb "microchat.ai/proto"
+)
+
+func init() {
+	Register(Registration{
+		Name:        "openai_compat",
+		DisplayName: "OpenAI-Compatible",
+		Models:      []pb.Model{pb.Model_OPENAI_COMPATIBLE},
+		New:         NewOpenAICompatProvider,
+		Configured:  func() bool { return os.Getenv("OPENAI_COMPAT_BASE_URL") != "" },
+		APIKeyEnv:   "OPENAI_COMPAT_API_KEY",
+		Validate:    validateOpenAICompatConfig,
+	})
+}
+
+// OpenAICompatProvider implements Provider against any OpenAI-compatible
+// /v1/chat/completions endpoint (vLLM, llama.cpp server, LM Studio, ...)
+type OpenAICompatProvider struct {
+	baseURL   string // e.g. http://localhost:8000/v1
+	model     string
+	apiKey    string // Optional; most self-hosted servers don't require one
+	maxTokens int
+	client    *http.Client
+	logger    *slog.Logger
+	retry     RetryPolicy
+	timeout   time.Duration // Bounds each call to the endpoint
+}
+
+// validateOpenAICompatConfig checks the endpoint settings when the provider is configured
+func validateOpenAICompatConfig() error {
+	baseURL := os.Getenv("OPENAI_COMPAT_BASE_URL")
+	if baseURL == "" {
+		return nil
+	}
+	parsed, err := url.Parse(baseURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("OPENAI_COMPAT_BASE_URL must be an http(s) URL, got %q", baseURL)
+	}
+	if os.Getenv("OPENAI_COMPAT_MODEL") == "" {
+		return fmt.Errorf("OPENAI_COMPAT_MODEL is required when OPENAI_COMPAT_BASE_URL is set")
+	}
+	if _, err := LoadTimeout("openai_compat", os.Getenv("OPENAI_COMPAT_MODEL"), defaultOpenAICompatTimeout); err != nil {
+		return err
+	}
+	_, err = LoadRetryPolicy("openai_compat")
+	return err
+}
+
+// NewOpenAICompatProvider creates a provider from OPENAI_COMPAT_* environment variables
+func NewOpenAICompatProvider(logger *slog.Logger) (Provider, error) {
+	baseURL := os.Getenv("OPENAI_COMPAT_BASE_URL")
+	if baseURL == "" {
+		return nil, fmt.Errorf("OPENAI_COMPAT_BASE_URL environment variable not set")
+	}
+	if err := validateOpenAICompatConfig(); err != nil {
+		return nil, err
+	}
+
+	// Configure max output tokens (default: 2048, same as Gemini)
+	maxTokens := 2048
+	if maxTokensEnv := os.Getenv("OPENAI_COMPAT_MAX_TOKENS"); maxTokensEnv != "" {
+		if parsed, err := strconv.Atoi(maxTokensEnv); err == nil && parsed > 0 {
+			maxTokens = parsed
+		}
+	}
+
+	retry, err := LoadRetryPolicy("openai_compat")
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := LoadTimeout("openai_compat", os.Getenv("OPENAI_COMPAT_MODEL"), defaultOpenAICompatTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OpenAICompatProvider{
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		model:     os.Getenv("OPENAI_COMPAT_MODEL"),
+		apiKey:    APIKey("openai_compat"),
+		maxTokens: maxTokens,
+		client:    &http.Client{},
+		logger:    logger,
+		retry:     retry,
+		timeout:   timeout,
+	}, nil
+}
+
+// openAIMessage is a chat message in the OpenAI wire format
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIChatRequest struct {
+	Model          string                `json:"model"`
+	Messages       []openAIMessage       `json:"messages"`
+	MaxTokens      int                   `json:"max_tokens,omitempty"`
+	Stream         bool                  `json:"stream,omitempty"`
+	ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
+}
+
+// openAIResponseFormat selects JSON mode, optionally constrained by a schema
+type openAIResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *openAIJSONSchema `json:"json_schema,omitempty"`
+}
+
+type openAIJSONSchema struct {
+	Name   string         `json:"name"`
+	Schema map[string]any `json:"schema"`
+}
+
+type openAIChatResponse struct {
+	Choices []struct {
+		Message openAIMessage `json:"message"`
+		Delta   openAIMessage `json:"delta"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int64 `json:"prompt_tokens"`
+		CompletionTokens int64 `json:"completion_tokens"`
+	} `json:"usage"` // Omitted by some servers
+}
+
+// newRequest builds an authenticated request to the endpoint
+func (p *OpenAICompatProvider) newRequest(ctx context.Context, method, path string, body any) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, p.baseURL+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+	return req, nil
+}
+
+// chatRequest converts the conversation to an OpenAI chat completion request
+func (p *OpenAICompatProvider) chatRequest(messages []Message, stream bool) (openAIChatRequest, error) {
+	if len(messages) == 0 {
+		return openAIChatRequest{}, status.Error(codes.InvalidArgument, "no messages to process")
+	}
+
+	req := openAIChatRequest{Model: p.model, MaxTokens: p.maxTokens, Stream: stream}
+	for _, msg := range messages {
+		if msg.Role == "tool" {
+			continue // Tool calling isn't supported by this provider
+		}
+		req.Messages = append(req.Messages, openAIMessage{Role: msg.Role, Content: msg.Text})
+	}
+	return req, nil
+}
+
+// do sends a chat completion request and checks the response status
+func (p *OpenAICompatProvider) do(ctx context.Context, body openAIChatRequest) (*http.Response, error) {
+	req, err := p.newRequest(ctx, http.MethodPost, "/chat/completions", body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("endpoint returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return resp, nil
+}
+
+// toStatusError maps transport errors to gRPC status codes like the Gemini provider
+func toStatusError(ctx context.Context, timeoutCtx context.Context, err error) error {
+	switch {
+	case errors.Is(ctx.Err(), context.Canceled):
+		return status.Error(codes.Canceled, "request cancelled")
+	case errors.Is(timeoutCtx.Err(), context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, "OpenAI-compatible endpoint timeout")
+	default:
+		return status.Error(codes.Unavailable, fmt.Sprintf("OpenAI-compatible endpoint failed: %v", err))
+	}
+}
+
+// GenerateResponse sends the conversation to the endpoint and returns the reply
+func (p *OpenAICompatProvider) GenerateResponse(ctx context.Context, messages []Message) (string, error) {
+	body, err := p.chatRequest(messages, false)
+	if err != nil {
+		return "", err
+	}
+	return p.complete(ctx, body)
+}
+
+// GenerateJSON requests a JSON reply using the endpoint's response_format option
+func (p *OpenAICompatProvider) GenerateJSON(ctx context.Context, messages []Message, schema map[string]any) (string, error) {
+	body, err := p.chatRequest(messages, false)
+	if err != nil {
+		return "", err
+	}
+
+	body.ResponseFormat = &openAIResponseFormat{Type: "json_object"}
+	if schema != nil {
+		body.ResponseFormat = &openAIResponseFormat{
+			Type:       "json_schema",
+			JSONSchema: &openAIJSONSchema{Name: "response", Schema: schema},
+		}
+	}
+	return p.complete(ctx, body)
+}
+
+// complete sends a non-streaming chat completion request under the retry policy and returns the reply text
+func (p *OpenAICompatProvider) complete(ctx context.Context, body openAIChatRequest) (string, error) {
+	var reply string
+	err := p.retry.Do(ctx, p.logger, "openai_compat", func(ctx context.Context, attempt int) error {
+		var err error
+		reply, err = p.completeOnce(ctx, body)
+		return err
+	})
+	return reply, err
+}
+
+// completeOnce makes a single chat completion call
+func (p *OpenAICompatProvider) completeOnce(ctx context.Context, body openAIChatRequest) (string, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	resp, err := p.do(timeoutCtx, body)
+	if err != nil {
+		p.logger.Warn("OpenAI-compatible call failed", "error", err)
+		return "", toStatusError(ctx, timeoutCtx, err)
+	}
+	defer resp.Body.Close()
+
+	var decoded openAIChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		return "", status.Error(codes.Unavailable, fmt.Sprintf("failed to decode OpenAI-compatible response: %v", err))
+	}
+	if len(decoded.Choices) == 0 || decoded.Choices[0].Message.Content == "" {
+		return "", status.Error(codes.Unavailable, "OpenAI-compatible endpoint returned empty response")
+	}
+	if decoded.Usage != nil {
+		RecordUsage(ctx, decoded.Usage.PromptTokens, decoded.Usage.CompletionTokens)
+	}
+
+	return decoded.Choices[0].Message.Content, nil
+}
+
+// GenerateResponseStream streams the reply using server-sent events
+func (p *OpenAICompatProvider) GenerateResponseStream(ctx context.Context, messages []Message) (<-chan Chunk, error) {
+	body, err := p.chatRequest(messages, true)
+	if err != nil {
+		return nil, err
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	resp, err := p.do(timeoutCtx, body)
+	if err != nil {
+		cancel()
+		p.logger.Warn("OpenAI-compatible stream failed", "error", err)
+		return nil, toStatusError(ctx, timeoutCtx, err)
+	}
+
+	chunks := newChunkStream()
+	go func() {
+		defer close(chunks)
+		defer cancel()
+		defer resp.Body.Close()
+
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+			if !ok {
+				continue // Blank separators and SSE comments
+			}
+			if data == "[DONE]" {
+				return
+			}
+
+			var event openAIChatResponse
+			if err := json.Unmarshal([]byte(data), &event); err != nil {
+				sendChunk(ctx, chunks, Chunk{Err: status.Error(codes.Unavailable, fmt.Sprintf("invalid stream event: %v", err))})
+				return
+			}
+			if len(event.Choices) == 0 || event.Choices[0].Delta.Content == "" {
+				continue
+			}
+			if !sendChunk(ctx, chunks, Chunk{Text: event.Choices[0].Delta.Content}) {
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			sendChunk(ctx, chunks, Chunk{Err: toStatusError(ctx, timeoutCtx, err)})
+		} else if ctx.Err() != nil {
+			endCancelled(ctx, chunks)
+		}
+	}()
+
+	return chunks, nil
+}
+
+// HealthCheck lists the endpoint's models, which every OpenAI-compatible server supports
+func (p *OpenAICompatProvider) HealthCheck(ctx context.Context) error {
+	req, err := p.newRequest(ctx, http.MethodGet, "/models", nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("OpenAI-compatible health check failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("OpenAI-compatible health check returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Name returns the provider name
+func (p *OpenAICompatProvider) Name() string {
+	return "OpenAI-Compatible"
+}
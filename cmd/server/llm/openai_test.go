@@ -0,0 +1,132 @@
+//go:build !no_openai
+
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newOpenAICompatTestServer serves a minimal OpenAI-compatible API
+func newOpenAICompatTestServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/models", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"data":[{"id":"local-model"}]}`)
+	})
+	mux.HandleFunc("/v1/chat/completions", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req openAIChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Model != "local-model" || len(req.Messages) != 2 || req.Messages[1].Role != "assistant" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+
+		if req.Stream {
+			w.Header().Set("Content-Type", "text/event-stream")
+			for _, word := range []string{"Hello", " from", " vLLM"} {
+				fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":%q}}]}\n\n", word)
+			}
+			fmt.Fprint(w, "data: [DONE]\n\n")
+			return
+		}
+		if req.ResponseFormat != nil {
+			fmt.Fprintf(w, `{"choices":[{"message":{"role":"assistant","content":%q}}]}`, `{"format":"`+req.ResponseFormat.Type+`"}`)
+			return
+		}
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"Hello from vLLM"}}],"usage":{"prompt_tokens":12,"completion_tokens":4}}`)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestOpenAICompatProvider(t *testing.T) {
+	server := newOpenAICompatTestServer(t)
+	t.Setenv("OPENAI_COMPAT_BASE_URL", server.URL+"/v1/")
+	t.Setenv("OPENAI_COMPAT_MODEL", "local-model")
+	t.Setenv("OPENAI_COMPAT_API_KEY", "secret")
+	t.Setenv("OPENAI_COMPAT_RETRY_BASE_BACKOFF", "1ms")
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	provider, err := NewOpenAICompatProvider(logger)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx := context.Background()
+	messages := []Message{{Role: "user", Text: "Hi"}, {Role: "assistant", Text: "Hello"}}
+
+	usageCtx, usage := WithUsage(ctx)
+	reply, err := provider.GenerateResponse(usageCtx, messages)
+	if err != nil || reply != "Hello from vLLM" {
+		t.Errorf("expected reply, got %q (err %v)", reply, err)
+	}
+	if input, output, reported := usage.Tokens(); !reported || input != 12 || output != 4 {
+		t.Errorf("expected reported token usage, got %d/%d (reported %v)", input, output, reported)
+	}
+
+	jsonGenerator := provider.(JSONGenerator)
+	if reply, err := jsonGenerator.GenerateJSON(ctx, messages, nil); err != nil || reply != `{"format":"json_object"}` {
+		t.Errorf("expected JSON mode reply, got %q (err %v)", reply, err)
+	}
+	if reply, err := jsonGenerator.GenerateJSON(ctx, messages, map[string]any{"type": "object"}); err != nil || reply != `{"format":"json_schema"}` {
+		t.Errorf("expected JSON schema reply, got %q (err %v)", reply, err)
+	}
+
+	chunks, err := provider.GenerateResponseStream(ctx, messages)
+	if err != nil {
+		t.Fatalf("unexpected stream error: %v", err)
+	}
+	if streamed, err := CollectStream(chunks); err != nil || streamed != "Hello from vLLM" {
+		t.Errorf("expected streamed reply, got %q (err %v)", streamed, err)
+	}
+
+	if err := provider.HealthCheck(ctx); err != nil {
+		t.Errorf("expected healthy endpoint, got %v", err)
+	}
+
+	if _, err := provider.GenerateResponse(ctx, nil); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for empty messages, got %v", err)
+	}
+
+	t.Setenv("OPENAI_COMPAT_API_KEY", "wrong")
+	provider, _ = NewOpenAICompatProvider(logger)
+	if _, err := provider.GenerateResponse(ctx, messages); status.Code(err) != codes.Unavailable {
+		t.Errorf("expected Unavailable for rejected request, got %v", err)
+	}
+}
+
+func TestValidateOpenAICompatConfig(t *testing.T) {
+	t.Setenv("OPENAI_COMPAT_BASE_URL", "")
+	if err := validateOpenAICompatConfig(); err != nil {
+		t.Errorf("expected unconfigured provider to be valid, got %v", err)
+	}
+
+	t.Setenv("OPENAI_COMPAT_BASE_URL", "localhost:8000")
+	t.Setenv("OPENAI_COMPAT_MODEL", "local-model")
+	if err := validateOpenAICompatConfig(); err == nil {
+		t.Error("expected error for URL without scheme")
+	}
+
+	t.Setenv("OPENAI_COMPAT_BASE_URL", "http://localhost:8000/v1")
+	t.Setenv("OPENAI_COMPAT_MODEL", "")
+	if err := validateOpenAICompatConfig(); err == nil {
+		t.Error("expected error without a model name")
+	}
+}
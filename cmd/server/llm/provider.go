@@ -1,15 +1,145 @@
 package llm
 
-import "context"
+import (
+	"context"
+	"strings"
+)
 
 // Provider defines the interface for LLM providers
 type Provider interface {
 	GenerateResponse(ctx context.Context, messages []Message) (string, error)
+	// GenerateResponseStream streams the response in chunks
+	// The channel is closed when the response is complete; a chunk with Err set is always last,
+	// and a stream cut short by cancelling ctx always ends with one
+	GenerateResponseStream(ctx context.Context, messages []Message) (<-chan Chunk, error)
+	// HealthCheck reports whether the provider can currently serve requests
+	HealthCheck(ctx context.Context) error
 	Name() string
 }
 
 // Message represents a single message in the conversation
 type Message struct {
-	Role string // "user" or "assistant"
-	Text string
+	Role        string // "user", "assistant", "system" or "tool"
+	Text        string
+	Tool        *ToolExchange // Set for "tool" messages
+	Attachments []Attachment  // Images sent with a "user" message
+}
+
+// Attachment is binary content, such as an image, sent alongside a message
+type Attachment struct {
+	MIMEType string
+	Data     []byte
+}
+
+// ImageInput is implemented by providers that accept image attachments
+type ImageInput interface {
+	SupportsImages() bool
+}
+
+// ToolDefinition describes a function the model may call
+type ToolDefinition struct {
+	Name        string
+	Description string
+	Parameters  map[string]any // JSON schema for the arguments object
+}
+
+// ToolCall is a model's request to run a tool
+type ToolCall struct {
+	ID   string         `json:"id,omitempty"`
+	Name string         `json:"name"`
+	Args map[string]any `json:"args,omitempty"`
+}
+
+// ToolExchange records a tool call and its outcome
+type ToolExchange struct {
+	Call   ToolCall `json:"call"`
+	Output string   `json:"output,omitempty"`
+	Error  string   `json:"error,omitempty"`
+}
+
+// ToolTurn is a model turn that either answers in Text or requests tool Calls
+type ToolTurn struct {
+	Text  string
+	Calls []ToolCall
+}
+
+// ToolCaller is implemented by providers that support function calling
+type ToolCaller interface {
+	GenerateWithTools(ctx context.Context, messages []Message, tools []ToolDefinition) (ToolTurn, error)
+}
+
+// JSONGenerator is implemented by providers with a native JSON output mode
+// schema is an optional JSON Schema the reply should conform to
+type JSONGenerator interface {
+	GenerateJSON(ctx context.Context, messages []Message, schema map[string]any) (string, error)
+}
+
+// Chunk is a piece of a streamed response
+type Chunk struct {
+	Text string // Incremental text, to be appended to previous chunks
+	Err  error  // Set on the final chunk if the stream failed
+}
+
+// newChunkStream makes a stream's channel, with a slot so endCancelled can always deliver its chunk
+func newChunkStream() chan Chunk {
+	return make(chan Chunk, 1)
+}
+
+// sendChunk delivers a chunk unless the context is cancelled first, in which case the stream is
+// ended with the context's error and false is returned
+func sendChunk(ctx context.Context, chunks chan Chunk, chunk Chunk) bool {
+	select {
+	case chunks <- chunk:
+		return true
+	case <-ctx.Done():
+		endCancelled(ctx, chunks)
+		return false
+	}
+}
+
+// endCancelled puts the context's error last in a stream without waiting for a receiver, replacing
+// any chunk still unread in the slot, so a cancelled stream is never mistaken for a complete one
+// It must only be called by the stream's sender, right before closing it
+func endCancelled(ctx context.Context, chunks chan Chunk) {
+	select {
+	case chunks <- Chunk{Err: ctx.Err()}:
+		return
+	default:
+	}
+	select {
+	case <-chunks:
+	default:
+	}
+	chunks <- Chunk{Err: ctx.Err()} // The slot is free and only this goroutine sends
+}
+
+// StreamText streams an already complete response word by word
+// Used by providers without native streaming
+func StreamText(ctx context.Context, text string) <-chan Chunk {
+	chunks := newChunkStream()
+	go func() {
+		defer close(chunks)
+		for _, word := range strings.SplitAfter(text, " ") {
+			if word == "" {
+				continue
+			}
+			if !sendChunk(ctx, chunks, Chunk{Text: word}) {
+				return
+			}
+		}
+	}()
+	return chunks
+}
+
+// CollectStream reads a stream to completion and returns the full text, or the text so far and the
+// error a failed or cancelled stream ends with
+func CollectStream(chunks <-chan Chunk) (string, error) {
+	var b strings.Builder
+	for chunk := range chunks {
+		if chunk.Err != nil {
+			return b.String(), chunk.Err
+		}
+		b.WriteString(chunk.Text)
+	}
+	return b.String(), nil
 }
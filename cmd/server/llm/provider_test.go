@@ -0,0 +1,144 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStreamText(t *testing.T) {
+	var chunks []string
+	for chunk := range StreamText(context.Background(), "one two three") {
+		chunks = append(chunks, chunk.Text)
+	}
+	if len(chunks) != 3 || chunks[0] != "one " || chunks[2] != "three" {
+		t.Errorf("expected word chunks, got %q", chunks)
+	}
+}
+
+func TestStreamText_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	chunks := StreamText(ctx, strings.Repeat("word ", 100))
+	first := <-chunks
+	cancel()
+	time.Sleep(10 * time.Millisecond) // Let the stream fill its slot and notice the cancellation
+
+	text, err := CollectStream(chunks)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a cancelled stream to end with context.Canceled, got %v", err)
+	}
+	if len(first.Text+text) >= len(strings.Repeat("word ", 100)) {
+		t.Errorf("expected a partial response, got %d bytes", len(first.Text+text))
+	}
+}
+
+func TestEchoProvider_GenerateResponseStream(t *testing.T) {
+	chunks, err := NewEchoProvider().GenerateResponseStream(context.Background(), []Message{{Role: "user", Text: "hello there"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	text, err := CollectStream(chunks)
+	if err != nil {
+		t.Fatalf("unexpected stream error: %v", err)
+	}
+	if text != "Echo: hello there" {
+		t.Errorf("expected streamed echo, got %q", text)
+	}
+}
+
+func TestParseMockBehavior(t *testing.T) {
+	behavior, err := ParseMockBehavior("latency=uniform:100ms:300ms,error_rate=0.25,chunk_delay=20ms")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := MockBehavior{
+		Latency:    LatencyDistribution{Kind: "uniform", Base: 100 * time.Millisecond, Spread: 200 * time.Millisecond},
+		ErrorRate:  0.25,
+		ChunkDelay: 20 * time.Millisecond,
+	}
+	if behavior != expected {
+		t.Errorf("expected %+v, got %+v", expected, behavior)
+	}
+
+	for _, bad := range []string{"latency=slow", "latency=uniform:2s:1s", "error_rate=2", "chunk_delay=-1s", "speed=fast", "latency"} {
+		if _, err := ParseMockBehavior(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestLatencyDistribution_Sample(t *testing.T) {
+	uniform := LatencyDistribution{Kind: "uniform", Base: 10 * time.Millisecond, Spread: 5 * time.Millisecond}
+	normal := LatencyDistribution{Kind: "normal", Base: time.Millisecond, Spread: 10 * time.Millisecond}
+	for range 100 {
+		if d := uniform.Sample(); d < 10*time.Millisecond || d > 15*time.Millisecond {
+			t.Fatalf("uniform sample out of range: %v", d)
+		}
+		if d := normal.Sample(); d < 0 {
+			t.Fatalf("normal sample should never be negative: %v", d)
+		}
+	}
+	if d := (LatencyDistribution{}).Sample(); d != 0 {
+		t.Errorf("expected no latency by default, got %v", d)
+	}
+}
+
+func TestMockProvider_SimulatedBehavior(t *testing.T) {
+	ctx := context.Background()
+	messages := []Message{{Role: "user", Text: "hi"}}
+
+	mock := NewMockProvider("mock")
+	mock.SetResponses("one two three")
+	mock.SetBehavior(MockBehavior{
+		Latency:    LatencyDistribution{Kind: "fixed", Base: 20 * time.Millisecond},
+		ChunkDelay: 5 * time.Millisecond,
+	})
+
+	start := time.Now()
+	if _, err := mock.GenerateResponse(ctx, messages); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("expected simulated latency, call took %v", elapsed)
+	}
+
+	chunks, err := mock.GenerateResponseStream(ctx, messages)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if text, err := CollectStream(chunks); err != nil || !strings.HasSuffix(text, "one two three") {
+		t.Errorf("expected streamed response, got %q (err %v)", text, err)
+	}
+
+	// Cancelling between chunks ends the stream with the context's error
+	mock.SetBehavior(MockBehavior{ChunkDelay: time.Minute})
+	cancelled, cancel := context.WithCancel(ctx)
+	chunks, err = mock.GenerateResponseStream(cancelled, messages)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	<-chunks
+	cancel()
+	if _, err := CollectStream(chunks); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled from a cancelled stream, got %v", err)
+	}
+
+	// Cancellation interrupts simulated latency
+	mock.SetBehavior(MockBehavior{Latency: LatencyDistribution{Kind: "fixed", Base: time.Minute}})
+	cancelled, cancel = context.WithCancel(ctx)
+	cancel()
+	if _, err := mock.GenerateResponse(cancelled, messages); status.Code(err) != codes.Canceled {
+		t.Errorf("expected Canceled, got %v", err)
+	}
+
+	// An error rate of 1 fails every call
+	mock.SetBehavior(MockBehavior{ErrorRate: 1})
+	if _, err := mock.GenerateResponse(ctx, messages); status.Code(err) != codes.Unavailable {
+		t.Errorf("expected simulated Unavailable error, got %v", err)
+	}
+}
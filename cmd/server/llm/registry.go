@@ -0,0 +1,108 @@
+package llm
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+
+	pb "microchat.ai/proto"
+)
+
+// Registration describes a provider the factory can create
+// Providers register themselves from an init function, so optional providers
+// can be left out of a build with build tags
+type Registration struct {
+	Name        string                                               // Stable identifier used for routing, health and metrics
+	DisplayName string                                               // Human-readable name
+	Models      []pb.Model                                           // Models served by this provider
+	DevOnly     bool                                                 // Only served when APP_ENV=development
+	New         func(logger *slog.Logger) (Provider, error)          // Constructor
+	NewEmbedder func(logger *slog.Logger) (EmbeddingProvider, error) // Embedding constructor (nil = no embeddings)
+	Configured  func() bool                                          // Reports whether required config is present (nil = always)
+	APIKeyEnv   string                                               // Env var with the upstream API key, rotatable with SetAPIKey (empty = no key)
+	Validate    func() error                                         // Checks optional config at startup (nil = nothing to check)
+}
+
+// registry holds all registered providers
+var registry = struct {
+	mu      sync.RWMutex
+	byName  map[string]Registration
+	byModel map[pb.Model]string
+}{
+	byName:  make(map[string]Registration),
+	byModel: make(map[pb.Model]string),
+}
+
+// Register adds a provider to the registry
+// Panics on duplicate names or models, since that is a programming error
+func Register(r Registration) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if r.Name == "" || r.New == nil {
+		panic("llm: provider registration requires a name and constructor")
+	}
+	if _, exists := registry.byName[r.Name]; exists {
+		panic(fmt.Sprintf("llm: provider %q registered twice", r.Name))
+	}
+	for _, model := range r.Models {
+		if existing, exists := registry.byModel[model]; exists {
+			panic(fmt.Sprintf("llm: model %s already served by provider %q", model, existing))
+		}
+	}
+
+	registry.byName[r.Name] = r
+	for _, model := range r.Models {
+		registry.byModel[model] = r.Name
+	}
+}
+
+// Lookup returns the registration serving a model
+func Lookup(model pb.Model) (Registration, bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	name, ok := registry.byModel[model]
+	if !ok {
+		return Registration{}, false
+	}
+	return registry.byName[name], true
+}
+
+// LookupName returns the registration with the given name
+func LookupName(name string) (Registration, bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	r, ok := registry.byName[name]
+	return r, ok
+}
+
+// Registered returns all registrations sorted by name
+func Registered() []Registration {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	registrations := make([]Registration, 0, len(registry.byName))
+	for _, r := range registry.byName {
+		registrations = append(registrations, r)
+	}
+	sort.Slice(registrations, func(i, j int) bool {
+		return registrations[i].Name < registrations[j].Name
+	})
+	return registrations
+}
+
+// ValidateConfig runs every registered provider's startup config validation
+func ValidateConfig() error {
+	for _, r := range Registered() {
+		if r.Validate == nil {
+			continue
+		}
+		if err := r.Validate(); err != nil {
+			return fmt.Errorf("%s provider: %w", r.Name, err)
+		}
+	}
+	return nil
+}
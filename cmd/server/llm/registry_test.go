@@ -0,0 +1,96 @@
+package llm
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+
+	pb "microchat.ai/proto"
+)
+
+func TestRegistry(t *testing.T) {
+	echo, ok := Lookup(pb.Model_ECHO)
+	if !ok || echo.Name != "echo" || !echo.DevOnly {
+		t.Fatalf("expected dev-only echo registration, got %+v", echo)
+	}
+	if _, ok := Lookup(pb.Model(999)); ok {
+		t.Error("expected unknown model not to be registered")
+	}
+	if GetProviderName(pb.Model_ECHO) != "Echo (Dev/Test)" {
+		t.Errorf("unexpected display name: %s", GetProviderName(pb.Model_ECHO))
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected duplicate registration to panic")
+		}
+	}()
+	Register(Registration{Name: "echo", New: echo.New})
+}
+
+func TestNewProvider_DevOnlyInProduction(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	t.Setenv("APP_ENV", "development")
+	if _, ok := NewProvider(pb.Model_ECHO, logger).(*EchoProvider); !ok {
+		t.Error("expected Echo provider in development")
+	}
+
+	// Without a Gemini key the production default can't be created, so the fallback is used
+	t.Setenv("APP_ENV", "production")
+	t.Setenv("GEMINI_API_KEY", "")
+	if _, ok := NewProvider(pb.Model_ECHO, logger).(*EchoProvider); !ok {
+		t.Error("expected fallback provider when the production default is unavailable")
+	}
+}
+
+func TestSelectProvider_FallbackReasons(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	t.Setenv("APP_ENV", "development")
+	if _, selection := SelectProvider(pb.Model_ECHO, logger, nil); selection != (Selection{Requested: "echo"}) {
+		t.Errorf("expected no fallback for echo in development, got %+v", selection)
+	}
+	if _, selection := SelectProvider(pb.Model(999), logger, nil); selection.FallbackReason != FallbackUnknownModel {
+		t.Errorf("expected unknown_model fallback, got %+v", selection)
+	}
+
+	t.Setenv("APP_ENV", "production")
+	t.Setenv("GEMINI_API_KEY", "")
+	provider, selection := SelectProvider(pb.Model_GEMINI_2_5_FLASH_LITE, logger, nil)
+	if selection.Requested != "gemini" || selection.FallbackReason != FallbackCreateFailed {
+		t.Errorf("expected create_failed fallback without a Gemini key, got %+v", selection)
+	}
+	if _, ok := provider.(*EchoProvider); !ok {
+		t.Errorf("expected Echo fallback, got %s", provider.Name())
+	}
+
+	t.Setenv("GEMINI_API_KEY", "test-key")
+	if _, selection := SelectProvider(pb.Model_ECHO, logger, nil); selection.FallbackReason != FallbackDevOnly {
+		t.Errorf("expected dev_only fallback in production, got %+v", selection)
+	}
+}
+
+func TestUnavailable(t *testing.T) {
+	echo, _ := Lookup(pb.Model_ECHO)
+	gemini, _ := Lookup(pb.Model_GEMINI_2_5_FLASH_LITE)
+
+	t.Setenv("APP_ENV", "development")
+	if reason := Unavailable(echo, nil); reason != "" {
+		t.Errorf("expected echo to be available in development, got %q", reason)
+	}
+
+	t.Setenv("APP_ENV", "production")
+	if reason := Unavailable(echo, nil); reason != FallbackDevOnly {
+		t.Errorf("expected echo to be dev_only in production, got %q", reason)
+	}
+
+	t.Setenv("GEMINI_API_KEY", "")
+	if reason := Unavailable(gemini, nil); reason != NotConfigured {
+		t.Errorf("expected gemini without a key to be not_configured, got %q", reason)
+	}
+	t.Setenv("GEMINI_API_KEY", "test-key")
+	if reason := Unavailable(gemini, nil); reason != "" {
+		t.Errorf("expected gemini with a key to be available, got %q", reason)
+	}
+}
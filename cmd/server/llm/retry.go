@@ -0,0 +1,196 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RetryPolicy controls how a provider retries failed calls
+type RetryPolicy struct {
+	MaxAttempts    int           // Total attempts, including the first
+	BaseBackoff    time.Duration // Wait before the second attempt
+	Multiplier     float64       // Growth of the wait after each further attempt
+	MaxBackoff     time.Duration // Upper bound for a single wait (0 = unbounded)
+	Jitter         float64       // Random extra wait as a fraction of the backoff, so clients don't retry in lockstep
+	RetryableCodes []codes.Code  // Status codes worth retrying; other errors fail immediately
+	Budget         time.Duration // Overall limit across attempts and waits (0 = none)
+}
+
+// DefaultRetryPolicy retries transient failures 3 times with 1s, 2s backoff plus up to 20% jitter
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseBackoff: 1 * time.Second,
+		Multiplier:  2,
+		MaxBackoff:  8 * time.Second,
+		Jitter:      0.2,
+		// Unknown covers plain errors from provider SDKs that don't carry a status code
+		RetryableCodes: []codes.Code{codes.Unavailable, codes.DeadlineExceeded, codes.Internal, codes.Unknown},
+	}
+}
+
+// LoadRetryPolicy reads a provider's retry policy from <PROVIDER>_RETRY_* environment variables,
+// starting from DefaultRetryPolicy, e.g. GEMINI_RETRY_MAX_ATTEMPTS for the "gemini" provider
+func LoadRetryPolicy(provider string) (RetryPolicy, error) {
+	policy := DefaultRetryPolicy()
+	prefix := strings.ToUpper(provider) + "_RETRY_"
+
+	if v := os.Getenv(prefix + "MAX_ATTEMPTS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 10 {
+			return policy, fmt.Errorf("invalid %sMAX_ATTEMPTS %q: must be 1-10", prefix, v)
+		}
+		policy.MaxAttempts = n
+	}
+
+	durations := []struct {
+		name   string
+		target *time.Duration
+	}{
+		{"BASE_BACKOFF", &policy.BaseBackoff},
+		{"MAX_BACKOFF", &policy.MaxBackoff},
+		{"BUDGET", &policy.Budget},
+	}
+	for _, d := range durations {
+		if v := os.Getenv(prefix + d.name); v != "" {
+			parsed, err := time.ParseDuration(v)
+			if err != nil || parsed < 0 {
+				return policy, fmt.Errorf("invalid %s%s %q: must be a non-negative duration", prefix, d.name, v)
+			}
+			*d.target = parsed
+		}
+	}
+
+	if v := os.Getenv(prefix + "MULTIPLIER"); v != "" {
+		m, err := strconv.ParseFloat(v, 64)
+		if err != nil || m < 1 {
+			return policy, fmt.Errorf("invalid %sMULTIPLIER %q: must be at least 1", prefix, v)
+		}
+		policy.Multiplier = m
+	}
+
+	if v := os.Getenv(prefix + "JITTER"); v != "" {
+		j, err := strconv.ParseFloat(v, 64)
+		if err != nil || j < 0 || j > 1 {
+			return policy, fmt.Errorf("invalid %sJITTER %q: must be between 0 and 1", prefix, v)
+		}
+		policy.Jitter = j
+	}
+
+	if v := os.Getenv(prefix + "CODES"); v != "" {
+		policy.RetryableCodes = nil
+		for _, name := range strings.Split(v, ",") {
+			var code codes.Code
+			if err := code.UnmarshalJSON([]byte(strconv.Quote(strings.ToUpper(strings.TrimSpace(name))))); err != nil {
+				return policy, fmt.Errorf("invalid %sCODES entry %q: expected a gRPC code name such as UNAVAILABLE", prefix, name)
+			}
+			policy.RetryableCodes = append(policy.RetryableCodes, code)
+		}
+	}
+
+	return policy, nil
+}
+
+// orDefault returns the default policy for an unset (zero) policy
+func (p RetryPolicy) orDefault() RetryPolicy {
+	if p.MaxAttempts == 0 {
+		return DefaultRetryPolicy()
+	}
+	return p
+}
+
+// Retryable reports whether err is worth retrying under this policy
+func (p RetryPolicy) Retryable(err error) bool {
+	code := status.Code(err)
+	for _, retryable := range p.RetryableCodes {
+		if code == retryable {
+			return true
+		}
+	}
+	return false
+}
+
+// Backoff returns the wait before the given attempt (2 for the first retry)
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	backoff := float64(p.BaseBackoff)
+	for i := 2; i < attempt; i++ {
+		backoff *= p.Multiplier
+	}
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		return p.MaxBackoff
+	}
+	return time.Duration(backoff)
+}
+
+// jittered stretches a backoff by a random amount up to Jitter
+func (p RetryPolicy) jittered(backoff time.Duration) time.Duration {
+	if p.Jitter <= 0 {
+		return backoff
+	}
+	return backoff + time.Duration(rand.Float64()*p.Jitter*float64(backoff))
+}
+
+// Do calls fn until it succeeds, fails with a non-retryable error, or the policy is exhausted,
+// returning the last error. fn receives the 1-based attempt number
+// Cancellation of ctx stops retrying immediately with codes.Canceled; a retry that couldn't start
+// before ctx's deadline (normally the client's gRPC deadline) isn't attempted
+func (p RetryPolicy) Do(ctx context.Context, logger *slog.Logger, provider string, fn func(ctx context.Context, attempt int) error) error {
+	p = p.orDefault()
+
+	var deadline time.Time
+	if p.Budget > 0 {
+		deadline = time.Now().Add(p.Budget)
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= p.MaxAttempts; attempt++ {
+		if attempt > 1 {
+			backoff := p.jittered(p.Backoff(attempt))
+			if !deadline.IsZero() && time.Until(deadline) < backoff {
+				logger.Warn("retry budget exhausted", "provider", provider, "attempt", attempt, "budget", p.Budget)
+				break
+			}
+			if ctxDeadline, ok := ctx.Deadline(); ok && time.Until(ctxDeadline) < backoff {
+				logger.Warn("request deadline too close to retry", "provider", provider, "attempt", attempt)
+				break
+			}
+			logger.Warn("retrying provider call", "provider", provider, "attempt", attempt, "backoff", backoff)
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return contextError(ctx)
+			}
+		}
+		if ctx.Err() != nil {
+			return contextError(ctx)
+		}
+
+		lastErr = fn(ctx, attempt)
+		if lastErr == nil {
+			return nil
+		}
+		if !p.Retryable(lastErr) {
+			return lastErr
+		}
+	}
+
+	return lastErr
+}
+
+// contextError converts a done context's error to a status error
+func contextError(ctx context.Context) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return status.Error(codes.DeadlineExceeded, "request deadline exceeded")
+	}
+	return status.Error(codes.Canceled, "request cancelled")
+}
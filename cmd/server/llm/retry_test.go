@@ -0,0 +1,173 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestLoadRetryPolicy(t *testing.T) {
+	policy, err := LoadRetryPolicy("test")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if policy.MaxAttempts != 3 || policy.BaseBackoff != time.Second {
+		t.Errorf("expected default policy, got %+v", policy)
+	}
+
+	t.Setenv("TEST_RETRY_MAX_ATTEMPTS", "5")
+	t.Setenv("TEST_RETRY_BASE_BACKOFF", "250ms")
+	t.Setenv("TEST_RETRY_MAX_BACKOFF", "1s")
+	t.Setenv("TEST_RETRY_BUDGET", "10s")
+	t.Setenv("TEST_RETRY_CODES", "unavailable, RESOURCE_EXHAUSTED")
+	policy, err = LoadRetryPolicy("test")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if policy.MaxAttempts != 5 || policy.BaseBackoff != 250*time.Millisecond || policy.MaxBackoff != time.Second || policy.Budget != 10*time.Second {
+		t.Errorf("expected configured policy, got %+v", policy)
+	}
+	if !policy.Retryable(status.Error(codes.ResourceExhausted, "")) || policy.Retryable(status.Error(codes.Internal, "")) {
+		t.Errorf("expected only configured codes to be retryable, got %v", policy.RetryableCodes)
+	}
+
+	for name, value := range map[string]string{
+		"TEST_RETRY_MAX_ATTEMPTS": "0",
+		"TEST_RETRY_BASE_BACKOFF": "soon",
+		"TEST_RETRY_CODES":        "SOMETIMES",
+		"TEST_RETRY_MULTIPLIER":   "0.5",
+		"TEST_RETRY_JITTER":       "2",
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(name, value)
+			if _, err := LoadRetryPolicy("test"); err == nil {
+				t.Errorf("expected error for %s=%s", name, value)
+			}
+		})
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{BaseBackoff: time.Second, Multiplier: 2, MaxBackoff: 3 * time.Second}
+	for attempt, want := range map[int]time.Duration{2: time.Second, 3: 2 * time.Second, 4: 3 * time.Second} {
+		if got := policy.Backoff(attempt); got != want {
+			t.Errorf("attempt %d: expected %v, got %v", attempt, want, got)
+		}
+	}
+}
+
+func TestRetryPolicy_Do(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	policy := RetryPolicy{MaxAttempts: 3, BaseBackoff: time.Millisecond, Multiplier: 2, RetryableCodes: []codes.Code{codes.Unavailable}}
+	ctx := context.Background()
+
+	calls := 0
+	err := policy.Do(ctx, logger, "test", func(ctx context.Context, attempt int) error {
+		calls++
+		if attempt < 3 {
+			return status.Error(codes.Unavailable, "flaky")
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("expected success on third attempt, got %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	err = policy.Do(ctx, logger, "test", func(ctx context.Context, attempt int) error {
+		calls++
+		return status.Error(codes.InvalidArgument, "bad request")
+	})
+	if status.Code(err) != codes.InvalidArgument || calls != 1 {
+		t.Errorf("expected non-retryable error to fail immediately, got %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	err = policy.Do(ctx, logger, "test", func(ctx context.Context, attempt int) error {
+		calls++
+		return status.Error(codes.Unavailable, "down")
+	})
+	if status.Code(err) != codes.Unavailable || calls != 3 {
+		t.Errorf("expected last error after all attempts, got %v after %d calls", err, calls)
+	}
+
+	// The budget stops retrying when the next backoff wouldn't fit
+	budgeted := policy
+	budgeted.BaseBackoff = time.Second
+	budgeted.Budget = 500 * time.Millisecond
+	calls = 0
+	start := time.Now()
+	_ = budgeted.Do(ctx, logger, "test", func(ctx context.Context, attempt int) error {
+		calls++
+		return status.Error(codes.Unavailable, "down")
+	})
+	if calls != 1 || time.Since(start) > 100*time.Millisecond {
+		t.Errorf("expected budget to stop retries, got %d calls in %v", calls, time.Since(start))
+	}
+
+	// Cancellation interrupts the backoff
+	cancelCtx, cancel := context.WithCancel(ctx)
+	slow := policy
+	slow.BaseBackoff = time.Minute
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	err = slow.Do(cancelCtx, logger, "test", func(ctx context.Context, attempt int) error {
+		return errors.New("plain errors are Unknown")
+	})
+	if status.Code(err) != codes.Unknown {
+		t.Errorf("expected Unknown to be non-retryable under this policy, got %v", err)
+	}
+	slow.RetryableCodes = []codes.Code{codes.Unknown}
+	err = slow.Do(cancelCtx, logger, "test", func(ctx context.Context, attempt int) error {
+		return errors.New("transient")
+	})
+	if status.Code(err) != codes.Canceled {
+		t.Errorf("expected Canceled during backoff, got %v", err)
+	}
+
+	// A client deadline that the next backoff wouldn't fit in stops retrying
+	deadlineCtx, cancelDeadline := context.WithTimeout(ctx, 500*time.Millisecond)
+	defer cancelDeadline()
+	unbudgeted := budgeted
+	unbudgeted.Budget = 0
+	calls = 0
+	start = time.Now()
+	err = unbudgeted.Do(deadlineCtx, logger, "test", func(ctx context.Context, attempt int) error {
+		calls++
+		return status.Error(codes.Unavailable, "down")
+	})
+	if calls != 1 || status.Code(err) != codes.Unavailable || time.Since(start) > 100*time.Millisecond {
+		t.Errorf("expected deadline to stop retries, got %v after %d calls in %v", err, calls, time.Since(start))
+	}
+
+	// An expired deadline is reported as DeadlineExceeded rather than Canceled
+	expiredCtx, cancelExpired := context.WithDeadline(ctx, time.Now().Add(-time.Second))
+	defer cancelExpired()
+	err = policy.Do(expiredCtx, logger, "test", func(ctx context.Context, attempt int) error {
+		t.Error("expected no attempt after the deadline")
+		return nil
+	})
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("expected DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestRetryPolicy_Jitter(t *testing.T) {
+	policy := RetryPolicy{Jitter: 0.5}
+	for range 100 {
+		if got := policy.jittered(time.Second); got < time.Second || got > 1500*time.Millisecond {
+			t.Fatalf("expected jittered backoff in [1s, 1.5s], got %v", got)
+		}
+	}
+	if got := (RetryPolicy{}).jittered(time.Second); got != time.Second {
+		t.Errorf("expected no jitter by default, got %v", got)
+	}
+}
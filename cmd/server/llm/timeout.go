@@ -0,0 +1,53 @@
+package llm
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Default per-call timeouts when none is configured
+const (
+	defaultGeminiTimeout       = 30 * time.Second
+	defaultOpenAICompatTimeout = 60 * time.Second
+)
+
+// LoadTimeout reads how long a single call to one of a provider's models may take, starting from def
+// <PROVIDER>_TIMEOUT sets the provider-wide value and <PROVIDER>_TIMEOUT_<MODEL> overrides it for one
+// upstream model, e.g. OPENAI_COMPAT_TIMEOUT_LLAMA3_1_8B for model "llama3.1:8b"
+func LoadTimeout(provider, model string, def time.Duration) (time.Duration, error) {
+	prefix := strings.ToUpper(provider) + "_TIMEOUT"
+	names := []string{prefix}
+	if model != "" {
+		names = append(names, prefix+"_"+timeoutEnvSuffix(model))
+	}
+
+	timeout := def
+	for _, name := range names {
+		v := os.Getenv(name)
+		if v == "" {
+			continue
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return def, fmt.Errorf("invalid %s %q: must be a positive duration", name, v)
+		}
+		timeout = d
+	}
+	return timeout, nil
+}
+
+// timeoutEnvSuffix turns a model name into an environment variable suffix:
+// upper case, with every character other than letters and digits replaced by an underscore
+func timeoutEnvSuffix(model string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, model)
+}
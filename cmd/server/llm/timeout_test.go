@@ -0,0 +1,33 @@
+package llm
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadTimeout(t *testing.T) {
+	timeout, err := LoadTimeout("test", "llama3.1:8b", 30*time.Second)
+	if err != nil || timeout != 30*time.Second {
+		t.Fatalf("expected default timeout, got %v, %v", timeout, err)
+	}
+
+	t.Setenv("TEST_TIMEOUT", "2m")
+	if timeout, err = LoadTimeout("test", "llama3.1:8b", 30*time.Second); err != nil || timeout != 2*time.Minute {
+		t.Errorf("expected provider timeout, got %v, %v", timeout, err)
+	}
+
+	t.Setenv("TEST_TIMEOUT_LLAMA3_1_8B", "5m")
+	if timeout, err = LoadTimeout("test", "llama3.1:8b", 30*time.Second); err != nil || timeout != 5*time.Minute {
+		t.Errorf("expected model timeout, got %v, %v", timeout, err)
+	}
+	if timeout, err = LoadTimeout("test", "other-model", 30*time.Second); err != nil || timeout != 2*time.Minute {
+		t.Errorf("expected provider timeout for another model, got %v, %v", timeout, err)
+	}
+
+	for _, value := range []string{"soon", "0s", "-1s"} {
+		t.Setenv("TEST_TIMEOUT", value)
+		if _, err := LoadTimeout("test", "", 30*time.Second); err == nil {
+			t.Errorf("expected error for TEST_TIMEOUT=%s", value)
+		}
+	}
+}
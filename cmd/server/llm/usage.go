@@ -0,0 +1,52 @@
+package llm
+
+import (
+	"context"
+	"sync"
+)
+
+// Usage accumulates the tokens consumed by provider calls made with a context from WithUsage
+// Providers that report token counts record them; callers estimate for providers that don't
+type Usage struct {
+	mu           sync.Mutex
+	inputTokens  int64
+	outputTokens int64
+	reported     bool
+}
+
+type usageKey struct{}
+
+// WithUsage returns a context whose provider calls add their token counts to the returned Usage
+func WithUsage(ctx context.Context) (context.Context, *Usage) {
+	usage := &Usage{}
+	return context.WithValue(ctx, usageKey{}, usage), usage
+}
+
+// RecordUsage adds the tokens a provider reported for one call to the context's Usage, if any
+func RecordUsage(ctx context.Context, inputTokens, outputTokens int64) {
+	usage, ok := ctx.Value(usageKey{}).(*Usage)
+	if !ok {
+		return
+	}
+	usage.mu.Lock()
+	defer usage.mu.Unlock()
+	usage.inputTokens += inputTokens
+	usage.outputTokens += outputTokens
+	usage.reported = true
+}
+
+// Tokens returns the input and output tokens recorded so far, and whether any provider reported them
+func (u *Usage) Tokens() (input, output int64, reported bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.inputTokens, u.outputTokens, u.reported
+}
+
+// EstimateTokens approximates the tokens in text for providers that don't report usage,
+// at roughly four bytes per token for English text
+func EstimateTokens(text string) int64 {
+	if text == "" {
+		return 0
+	}
+	return int64(len(text)+3) / 4
+}
@@ -0,0 +1,35 @@
+package llm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestUsage(t *testing.T) {
+	// Recording without a Usage in the context is a no-op
+	RecordUsage(context.Background(), 10, 5)
+
+	ctx, usage := WithUsage(context.Background())
+	if _, _, reported := usage.Tokens(); reported {
+		t.Error("expected no usage before any call")
+	}
+	RecordUsage(ctx, 10, 5)
+	RecordUsage(ctx, 20, 7) // e.g. a second tool-calling round
+	if input, output, reported := usage.Tokens(); !reported || input != 30 || output != 12 {
+		t.Errorf("expected accumulated usage, got %d/%d (reported %v)", input, output, reported)
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	tests := map[string]int64{
+		"":         0,
+		"a":        1,
+		"abcd":     1,
+		"abcdefgh": 2,
+	}
+	for text, want := range tests {
+		if got := EstimateTokens(text); got != want {
+			t.Errorf("EstimateTokens(%q) = %d, want %d", text, got, want)
+		}
+	}
+}
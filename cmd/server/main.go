@@ -2,30 +2,38 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"io/fs"
 	"log/slog"
-	"net"
 	"net/http"
 	_ "net/http/pprof"
+	"net/url"
 	"os"
 	"os/signal"
 	"strconv"
 	"strings"
-	"sync"
 	"syscall"
 	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"golang.org/x/time/rate"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
 	_ "google.golang.org/grpc/encoding/gzip"
-	"google.golang.org/grpc/reflection"
 
+	"microchat.ai/cmd/server/alerts"
+	"microchat.ai/cmd/server/archive"
+	"microchat.ai/cmd/server/events"
+	"microchat.ai/cmd/server/knowledge"
 	"microchat.ai/cmd/server/llm"
+	"microchat.ai/cmd/server/moderation"
+	"microchat.ai/cmd/server/prompts"
 	"microchat.ai/cmd/server/ratelimit"
+	"microchat.ai/cmd/server/secrets"
+	"microchat.ai/cmd/server/tools"
 	pb "microchat.ai/proto"
+	"microchat.ai/version"
+	_ "microchat.ai/zstd"
 )
 
 type config struct {
@@ -33,27 +41,59 @@ type config struct {
 	env                    string
 	sessionCleanupInterval time.Duration
 	sessionIdleTimeout     time.Duration
+	sessionMinIdleTimeout  time.Duration // Lower bound for client-requested idle timeouts
+	sessionMaxIdleTimeout  time.Duration // Upper bound for client-requested idle timeouts
 	rateLimitRPS           rate.Limit
 	rateLimitBurst         int
-	apiKeys                map[string]string // API keys for authentication (key -> role)
-	dailyCallLimit         int               // Daily call limit per API key
-	maxSessions            int               // Maximum number of concurrent sessions
-	maxMessagesPerSession  int               // Maximum messages per session
-	maxSessionSizeBytes    int               // Maximum memory per session in bytes
-	pprofPort              int               // Port for pprof profiling server (localhost only)
-	metricsPort            int               // Port for Prometheus metrics server (network accessible)
-}
-
-// SpendingTracker tracks daily usage per API key
-type SpendingTracker struct {
-	mu    sync.RWMutex
-	usage map[string]keyUsage // API key -> usage data
-	limit int                 // Daily call limit
-}
-
-type keyUsage struct {
-	date  string // YYYY-MM-DD format
-	calls int    // Number of calls today
+	rateLimitCosts         map[string]int            // Rate limit tokens consumed per RPC method (unlisted methods cost 1)
+	rpcTimeout             time.Duration             // Server-side bound on each RPC (0 = only the client's deadline)
+	rpcTimeouts            map[string]time.Duration  // Per-method overrides of rpcTimeout
+	trustedProxies         ratelimit.TrustedProxies  // Peers whose x-forwarded-for header is honored
+	adaptiveRateLimit      *ratelimit.AdaptiveConfig // Thresholds for tightening limits under LLM load (nil disables)
+	apiKeys                *apiKeySet                // API keys for authentication (key -> role)
+	dailyCallLimit         int                       // Daily call limit per API key
+	dailyTokenLimit        int64                     // Daily LLM token limit per API key (0 = unlimited)
+	embeddingDailyLimit    int                       // Daily number of texts each API key may embed
+	dailyLimitLocation     *time.Location            // Timezone whose midnight resets daily limits
+	usageRetentionDays     int                       // Days a key's daily usage entry is kept after its last call
+	knowledgeEmbedder      string                    // Provider that embeds uploaded documents (empty disables the knowledge base)
+	maxSessions            int                       // Maximum number of concurrent sessions
+	maxMessagesPerSession  int                       // Maximum messages per session
+	maxSessionSizeBytes    int                       // Maximum memory per session in bytes
+	sessionTokenBudget     int64                     // Maximum LLM tokens per session (0 = unlimited)
+	maxTotalSessionBytes   int                       // Server-wide session memory budget in bytes (0 disables)
+	sessionCompaction      CompactionPolicy          // What happens when a session reaches its message or size limit
+	sessionDataDir         string                    // Directory for the session WAL and snapshots (empty keeps sessions in memory only)
+	snapshotInterval       time.Duration             // How often to snapshot sessions and truncate the WAL
+	archiveStore           archive.Store             // Where idle sessions are archived instead of deleted (nil deletes them)
+	archiveRetention       time.Duration             // How long session archives are kept (0 keeps them until restored)
+	maintenanceMode        bool                      // Start in maintenance mode, refusing new sessions
+	maintenanceMessage     string                    // Message returned to clients refused during maintenance
+	pprofPort              int                       // Port for pprof profiling server (localhost only)
+	metricsPort            int                       // Port for Prometheus metrics server (network accessible)
+	sessionTitles          bool                      // Generate session titles via the LLM after the first exchange
+	logRedaction           bool                      // Redact message contents, API keys and session IDs from logs
+	moderationBlockedWords []string                  // Words that block a message or reply outright
+	moderationRulesFile    string                    // Path to regex moderation rules ("<action> <pattern>" per line)
+	moderationAPIURL       string                    // External moderation API endpoint
+	providerHealthInterval time.Duration             // How often to health check LLM providers
+	toolsEnabled           []string                  // Built-in tools the LLM may call (empty disables tool calling)
+	maxAttachmentBytes     int                       // Maximum size of each image attachment in bytes (0 disables attachments)
+	promptTemplatesFile    string                    // Path to operator-defined prompt templates (JSON)
+	alertWebhookURLs       []string                  // Slack or generic webhooks for operational alerts (empty disables alerting)
+	alertMinInterval       time.Duration             // Minimum time between repeats of the same alert
+	eventWebhookURLs       []string                  // Webhooks receiving signed lifecycle events (empty disables events)
+	eventWebhookSecret     string                    // Shared secret the event payloads are signed with
+	eventTypes             map[string]bool           // Lifecycle event types sent to the webhooks
+	listeners              []listenerConfig          // Addresses to serve gRPC on, each with its own TLS configuration
+	connection             connectionConfig          // gRPC keepalive and connection limits
+	secretsSource          secrets.Source            // Vault or AWS Secrets Manager secret (nil when secrets come from the environment)
+	secretValues           map[string]string         // Managed secrets loaded from secretsSource at startup
+	secretsRefreshInterval time.Duration             // How often to fetch rotated secrets (0 disables refresh)
+	certificates           *certificateStore         // TLS certificate from TLS_CERT_PEM/TLS_KEY_PEM (nil when loaded from files)
+	providerKeyFiles       map[string]string         // Upstream API key files to watch for rotation, by provider name
+	llmCaptureSize         int                       // Provider exchanges kept for the ListLLMCaptures RPC (0 disables capture)
+	llmCaptureMaxBytes     int                       // Cap on each captured message, reply or error
 }
 
 type application struct {
@@ -62,59 +102,45 @@ type application struct {
 	sessionStore    *SessionStore
 	ipLimiter       *ratelimit.IPLimiter
 	spendingTracker *SpendingTracker
-	providerFactory func(pb.Model, *slog.Logger) llm.Provider // For dependency injection in tests
+	embeddingQuota  *SpendingTracker                                            // Texts embedded per API key per day
+	bandwidth       *bandwidthTracker                                           // Request and response bytes per API key
+	startedAt       time.Time                                                   // When the server process started
+	alerts          *alerts.Notifier                                            // nil when no alert webhooks are configured
+	events          *events.Emitter                                             // nil when no event webhooks are configured
+	adaptiveLimit   *ratelimit.Adaptive                                         // nil when adaptive rate limiting is disabled
+	knowledge       *knowledge.Base                                             // nil when no embedding provider is available
+	moderator       *moderation.Pipeline                                        // nil when moderation is disabled
+	providerHealth  *llm.HealthMonitor                                          // nil disables health-based routing
+	prompts         *prompts.Set                                                // nil when no prompt templates are configured
+	tools           *tools.Registry                                             // nil disables tool calling
+	llmCapture      *llmCapture                                                 // nil when LLM exchange capture is disabled
+	sessionArchiver *sessionArchiver                                            // nil when idle sessions are deleted rather than archived
+	maintenance     maintenanceMode                                             // Refuses new sessions during staged upgrades
+	providerFactory func(pb.Model, *slog.Logger) llm.Provider                   // For dependency injection in tests
+	embedderFactory func(pb.Model, *slog.Logger) (llm.EmbeddingProvider, error) // For dependency injection in tests
 	pb.UnimplementedChatServiceServer
 }
 
 // getProvider returns the appropriate LLM provider for the requested model
+// Fallbacks to another provider are counted so they don't go unnoticed in production
 func (app *application) getProvider(model pb.Model) llm.Provider {
 	if app.providerFactory != nil {
 		return app.providerFactory(model, app.logger)
 	}
-	return llm.NewProvider(model, app.logger)
-}
-
-// NewSpendingTracker creates a new spending tracker
-func NewSpendingTracker(dailyLimit int) *SpendingTracker {
-	return &SpendingTracker{
-		usage: make(map[string]keyUsage),
-		limit: dailyLimit,
-	}
-}
-
-// CanMakeCall checks if API key can make another call today
-func (st *SpendingTracker) CanMakeCall(apiKey string) bool {
-	st.mu.Lock()
-	defer st.mu.Unlock()
-
-	today := time.Now().Format("2006-01-02")
-	usage, exists := st.usage[apiKey]
-
-	if !exists || usage.date != today {
-		// New day or new key - can make call
-		return true
+	provider, selection := llm.SelectProvider(model, app.logger, app.providerHealth)
+	if selection.FallbackReason != "" {
+		incrementLLMFallback(model.String(), provider.Name(), selection.FallbackReason)
+		app.emitProviderFailover(model, selection.Requested, provider.Name(), selection.FallbackReason)
 	}
-
-	return usage.calls < st.limit
+	return provider
 }
 
-// RecordCall records a call for an API key
-func (st *SpendingTracker) RecordCall(apiKey string) {
-	st.mu.Lock()
-	defer st.mu.Unlock()
-
-	today := time.Now().Format("2006-01-02")
-	usage, exists := st.usage[apiKey]
-
-	if !exists || usage.date != today {
-		// New day or new key - reset usage
-		st.usage[apiKey] = keyUsage{date: today, calls: 1}
-		return
+// getEmbeddingProvider returns the embedding provider for the requested model
+func (app *application) getEmbeddingProvider(model pb.Model) (llm.EmbeddingProvider, error) {
+	if app.embedderFactory != nil {
+		return app.embedderFactory(model, app.logger)
 	}
-
-	// Increment call count
-	usage.calls++
-	st.usage[apiKey] = usage
+	return llm.NewEmbeddingProvider(model, app.logger)
 }
 
 // loadConfig loads configuration from environment variables
@@ -128,6 +154,30 @@ func loadConfig(logger *slog.Logger) (config, error) {
 		}
 	}
 
+	// Load secrets from Vault or AWS Secrets Manager before anything reads them
+	var err error
+	cfg.secretsSource, cfg.secretValues, err = loadSecrets(logger)
+	if err != nil {
+		logger.Error("failed to load secrets", "error", err)
+		return cfg, fmt.Errorf("failed to load secrets: %w", err)
+	}
+	refreshStr := os.Getenv("SECRETS_REFRESH_INTERVAL")
+	if refreshStr == "" {
+		refreshStr = "5m" // Default to 5 minutes
+	}
+	cfg.secretsRefreshInterval, err = time.ParseDuration(refreshStr)
+	if err != nil || cfg.secretsRefreshInterval < 0 {
+		logger.Error("invalid SECRETS_REFRESH_INTERVAL value", "value", refreshStr, "error", err)
+		return cfg, fmt.Errorf("invalid SECRETS_REFRESH_INTERVAL: must be a non-negative duration")
+	}
+
+	// Upstream API keys may be read from files, e.g. GEMINI_API_KEY_FILE, and rotated by rewriting them
+	cfg.providerKeyFiles, err = loadProviderKeyFiles()
+	if err != nil {
+		logger.Error("failed to load provider key files", "error", err)
+		return cfg, err
+	}
+
 	// Parse port (required)
 	portStr := os.Getenv("PORT")
 	if portStr == "" {
@@ -174,6 +224,29 @@ func loadConfig(logger *slog.Logger) (config, error) {
 	}
 	cfg.sessionIdleTimeout = timeout
 
+	// Parse bounds for client-requested session idle timeouts (with defaults)
+	minTimeoutStr := os.Getenv("SESSION_MIN_IDLE_TIMEOUT")
+	if minTimeoutStr == "" {
+		minTimeoutStr = "5m" // Default to 5 minutes
+	}
+	minTimeout, err := time.ParseDuration(minTimeoutStr)
+	if err != nil || minTimeout <= 0 {
+		logger.Error("invalid SESSION_MIN_IDLE_TIMEOUT value", "value", minTimeoutStr, "error", err)
+		return cfg, fmt.Errorf("invalid SESSION_MIN_IDLE_TIMEOUT: %w", err)
+	}
+	cfg.sessionMinIdleTimeout = minTimeout
+
+	maxTimeoutStr := os.Getenv("SESSION_MAX_IDLE_TIMEOUT")
+	if maxTimeoutStr == "" {
+		maxTimeoutStr = "24h" // Default to 24 hours
+	}
+	maxTimeout, err := time.ParseDuration(maxTimeoutStr)
+	if err != nil || maxTimeout < minTimeout {
+		logger.Error("invalid SESSION_MAX_IDLE_TIMEOUT value", "value", maxTimeoutStr, "min", minTimeoutStr, "error", err)
+		return cfg, fmt.Errorf("invalid SESSION_MAX_IDLE_TIMEOUT: must be a duration >= SESSION_MIN_IDLE_TIMEOUT")
+	}
+	cfg.sessionMaxIdleTimeout = maxTimeout
+
 	// Parse rate limiting configuration
 	rpsStr := os.Getenv("RATE_LIMIT_RPS")
 	if rpsStr == "" {
@@ -197,25 +270,80 @@ func loadConfig(logger *slog.Logger) (config, error) {
 	}
 	cfg.rateLimitBurst = burstInt
 
-	// Parse API keys (comma-separated, with optional :admin suffix)
-	apiKeysStr := os.Getenv("API_KEYS")
-	cfg.apiKeys = make(map[string]string)
-	if apiKeysStr != "" {
-		keys := strings.Split(apiKeysStr, ",")
-		for _, key := range keys {
-			key = strings.TrimSpace(key)
-			if key != "" {
-				// Check for admin role suffix
-				if strings.HasSuffix(key, ":admin") {
-					keyPart := strings.TrimSuffix(key, ":admin")
-					cfg.apiKeys[keyPart] = "admin"
-				} else {
-					cfg.apiKeys[key] = "user"
-				}
+	// Parse per-method rate limit costs (e.g. "Chat=5,GetHistory=1,Health=0")
+	cfg.rateLimitCosts, err = ratelimit.ParseCosts(os.Getenv("RATE_LIMIT_COSTS"))
+	if err != nil {
+		logger.Error("invalid RATE_LIMIT_COSTS value", "error", err)
+		return cfg, fmt.Errorf("invalid RATE_LIMIT_COSTS: %w", err)
+	}
+	for method, cost := range cfg.rateLimitCosts {
+		if !isChatServiceMethod(method) {
+			logger.Error("invalid RATE_LIMIT_COSTS value", "method", method)
+			return cfg, fmt.Errorf("invalid RATE_LIMIT_COSTS: unknown method %q", method)
+		}
+		if cost > cfg.rateLimitBurst {
+			logger.Error("invalid RATE_LIMIT_COSTS value", "method", method, "cost", cost, "burst", cfg.rateLimitBurst)
+			return cfg, fmt.Errorf("invalid RATE_LIMIT_COSTS: %s costs %d tokens but RATE_LIMIT_BURST is %d, so it could never run", method, cost, cfg.rateLimitBurst)
+		}
+	}
+
+	// Parse the server-side RPC timeout (with default) and per-method overrides
+	rpcTimeoutStr := os.Getenv("RPC_TIMEOUT")
+	if rpcTimeoutStr == "" {
+		rpcTimeoutStr = "2m" // Default to 2 minutes, enough for a Chat that exhausts its retries
+	}
+	cfg.rpcTimeout, err = time.ParseDuration(rpcTimeoutStr)
+	if err != nil || cfg.rpcTimeout < 0 {
+		logger.Error("invalid RPC_TIMEOUT value", "value", rpcTimeoutStr, "error", err)
+		return cfg, fmt.Errorf("invalid RPC_TIMEOUT: must be a non-negative duration")
+	}
+	cfg.rpcTimeouts, err = parseMethodTimeouts(os.Getenv("RPC_TIMEOUTS"))
+	if err != nil {
+		logger.Error("invalid RPC_TIMEOUTS value", "error", err)
+		return cfg, fmt.Errorf("invalid RPC_TIMEOUTS: %w", err)
+	}
+
+	// Parse trusted proxies (comma-separated CIDRs; x-forwarded-for is ignored from anyone else)
+	cfg.trustedProxies, err = ratelimit.ParseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		logger.Error("invalid TRUSTED_PROXIES value", "error", err)
+		return cfg, fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+
+	// Parse adaptive rate limiting (disabled by default)
+	adaptiveStr := os.Getenv("ADAPTIVE_RATE_LIMIT")
+	if adaptiveStr == "" {
+		adaptiveStr = "false"
+	}
+	adaptiveBool, err := strconv.ParseBool(adaptiveStr)
+	if err != nil {
+		logger.Error("invalid ADAPTIVE_RATE_LIMIT value", "value", adaptiveStr, "error", err)
+		return cfg, fmt.Errorf("invalid ADAPTIVE_RATE_LIMIT: %w", err)
+	}
+	if adaptiveBool {
+		adaptive := ratelimit.DefaultAdaptiveConfig()
+		if latencyStr := os.Getenv("ADAPTIVE_LATENCY_THRESHOLD"); latencyStr != "" {
+			latency, err := time.ParseDuration(latencyStr)
+			if err != nil || latency <= 0 {
+				logger.Error("invalid ADAPTIVE_LATENCY_THRESHOLD value", "value", latencyStr, "error", err)
+				return cfg, fmt.Errorf("invalid ADAPTIVE_LATENCY_THRESHOLD: %w", err)
 			}
+			adaptive.LatencyThreshold = latency
 		}
+		if errorRateStr := os.Getenv("ADAPTIVE_ERROR_RATE_THRESHOLD"); errorRateStr != "" {
+			errorRate, err := strconv.ParseFloat(errorRateStr, 64)
+			if err != nil || errorRate <= 0 || errorRate > 1 {
+				logger.Error("invalid ADAPTIVE_ERROR_RATE_THRESHOLD value", "value", errorRateStr, "error", err)
+				return cfg, fmt.Errorf("invalid ADAPTIVE_ERROR_RATE_THRESHOLD: must be between 0 and 1")
+			}
+			adaptive.ErrorRateThreshold = errorRate
+		}
+		cfg.adaptiveRateLimit = &adaptive
 	}
 
+	// Parse API keys (comma-separated, with optional :admin suffix)
+	cfg.apiKeys = newAPIKeySet(parseAPIKeys(os.Getenv("API_KEYS")))
+
 	// Parse daily call limit (with default)
 	limitStr := os.Getenv("DAILY_CALL_LIMIT")
 	if limitStr == "" {
@@ -228,6 +356,62 @@ func loadConfig(logger *slog.Logger) (config, error) {
 	}
 	cfg.dailyCallLimit = limitInt
 
+	// Parse daily token limit (0 disables)
+	tokenLimitStr := os.Getenv("DAILY_TOKEN_LIMIT")
+	if tokenLimitStr == "" {
+		tokenLimitStr = "0" // Default to unlimited
+	}
+	cfg.dailyTokenLimit, err = strconv.ParseInt(tokenLimitStr, 10, 64)
+	if err != nil || cfg.dailyTokenLimit < 0 {
+		logger.Error("invalid DAILY_TOKEN_LIMIT value", "value", tokenLimitStr, "error", err)
+		return cfg, fmt.Errorf("invalid DAILY_TOKEN_LIMIT: must be a non-negative integer")
+	}
+
+	// Parse daily embedding limit (with default)
+	embeddingLimitStr := os.Getenv("EMBEDDING_DAILY_LIMIT")
+	if embeddingLimitStr == "" {
+		embeddingLimitStr = "10000" // Default to 10000 texts per day
+	}
+	embeddingLimitInt, err := strconv.Atoi(embeddingLimitStr)
+	if err != nil || embeddingLimitInt <= 0 {
+		logger.Error("invalid EMBEDDING_DAILY_LIMIT value", "value", embeddingLimitStr, "error", err)
+		return cfg, fmt.Errorf("invalid EMBEDDING_DAILY_LIMIT: %w", err)
+	}
+	cfg.embeddingDailyLimit = embeddingLimitInt
+
+	// Parse the timezone daily limits reset in (with default)
+	timezoneStr := os.Getenv("DAILY_LIMIT_TIMEZONE")
+	if timezoneStr == "" {
+		timezoneStr = "UTC" // Default to resetting at midnight UTC
+	}
+	location, err := time.LoadLocation(timezoneStr)
+	if err != nil {
+		logger.Error("invalid DAILY_LIMIT_TIMEZONE value", "value", timezoneStr, "error", err)
+		return cfg, fmt.Errorf("invalid DAILY_LIMIT_TIMEZONE: %w", err)
+	}
+	cfg.dailyLimitLocation = location
+
+	// Parse daily usage retention (with default)
+	retentionStr := os.Getenv("DAILY_USAGE_RETENTION_DAYS")
+	if retentionStr == "" {
+		retentionStr = strconv.Itoa(defaultUsageRetentionDays)
+	}
+	retentionInt, err := strconv.Atoi(retentionStr)
+	if err != nil || retentionInt < 1 {
+		logger.Error("invalid DAILY_USAGE_RETENTION_DAYS value", "value", retentionStr, "error", err)
+		return cfg, fmt.Errorf("invalid DAILY_USAGE_RETENTION_DAYS: must be at least 1")
+	}
+	cfg.usageRetentionDays = retentionInt
+
+	// Get knowledge base embedding provider (with default)
+	cfg.knowledgeEmbedder = os.Getenv("KNOWLEDGE_EMBEDDING_PROVIDER")
+	if cfg.knowledgeEmbedder == "" {
+		cfg.knowledgeEmbedder = "gemini" // Default to Gemini embeddings
+	}
+	if cfg.knowledgeEmbedder == "off" {
+		cfg.knowledgeEmbedder = ""
+	}
+
 	// Parse session limits (with defaults)
 	maxSessionsStr := os.Getenv("MAX_SESSIONS")
 	if maxSessionsStr == "" {
@@ -262,6 +446,82 @@ func loadConfig(logger *slog.Logger) (config, error) {
 	}
 	cfg.maxSessionSizeBytes = maxSizeInt * 1024 // Convert KB to bytes
 
+	// Parse per-session token budget (0 disables)
+	tokenBudgetStr := os.Getenv("SESSION_TOKEN_BUDGET")
+	if tokenBudgetStr == "" {
+		tokenBudgetStr = "0" // Default to unlimited
+	}
+	cfg.sessionTokenBudget, err = strconv.ParseInt(tokenBudgetStr, 10, 64)
+	if err != nil || cfg.sessionTokenBudget < 0 {
+		logger.Error("invalid SESSION_TOKEN_BUDGET value", "value", tokenBudgetStr, "error", err)
+		return cfg, fmt.Errorf("invalid SESSION_TOKEN_BUDGET: must be a non-negative integer")
+	}
+
+	// Parse server-wide session memory budget (0 disables)
+	maxTotalMemoryStr := os.Getenv("MAX_TOTAL_SESSION_MEMORY_MB")
+	if maxTotalMemoryStr == "" {
+		maxTotalMemoryStr = "0"
+	}
+	maxTotalMemoryInt, err := strconv.Atoi(maxTotalMemoryStr)
+	if err != nil || maxTotalMemoryInt < 0 {
+		logger.Error("invalid MAX_TOTAL_SESSION_MEMORY_MB value", "value", maxTotalMemoryStr, "error", err)
+		return cfg, fmt.Errorf("invalid MAX_TOTAL_SESSION_MEMORY_MB: %w", err)
+	}
+	cfg.maxTotalSessionBytes = maxTotalMemoryInt * 1024 * 1024 // Convert MB to bytes
+
+	// Parse session compaction policy (with default)
+	compactionStr := os.Getenv("SESSION_COMPACTION")
+	if compactionStr == "" {
+		compactionStr = string(CompactionDropOldest)
+	}
+	cfg.sessionCompaction, err = ParseCompactionPolicy(compactionStr)
+	if err != nil {
+		logger.Error("invalid SESSION_COMPACTION value", "value", compactionStr, "error", err)
+		return cfg, fmt.Errorf("invalid SESSION_COMPACTION: %w", err)
+	}
+
+	// Parse session persistence settings (optional)
+	cfg.sessionDataDir = os.Getenv("SESSION_DATA_DIR")
+	snapshotStr := os.Getenv("SESSION_SNAPSHOT_INTERVAL")
+	if snapshotStr == "" {
+		snapshotStr = "5m"
+	}
+	cfg.snapshotInterval, err = time.ParseDuration(snapshotStr)
+	if err != nil || cfg.snapshotInterval <= 0 {
+		logger.Error("invalid SESSION_SNAPSHOT_INTERVAL value", "value", snapshotStr, "error", err)
+		return cfg, fmt.Errorf("invalid SESSION_SNAPSHOT_INTERVAL: %w", err)
+	}
+
+	// Parse session archival settings (optional)
+	if archiveStr := os.Getenv("ARCHIVE_STORE"); archiveStr != "" {
+		cfg.archiveStore, err = archive.ParseStore(archiveStr, os.Getenv)
+		if err != nil {
+			logger.Error("invalid ARCHIVE_STORE value", "error", err)
+			return cfg, fmt.Errorf("invalid ARCHIVE_STORE: %w", err)
+		}
+	}
+	archiveRetentionStr := os.Getenv("ARCHIVE_RETENTION")
+	if archiveRetentionStr == "" {
+		archiveRetentionStr = "720h" // Default to 30 days
+	}
+	cfg.archiveRetention, err = time.ParseDuration(archiveRetentionStr)
+	if err != nil || cfg.archiveRetention < 0 {
+		logger.Error("invalid ARCHIVE_RETENTION value", "value", archiveRetentionStr, "error", err)
+		return cfg, fmt.Errorf("invalid ARCHIVE_RETENTION: must be a non-negative duration")
+	}
+
+	// Parse maintenance mode (off by default; toggled at runtime with SetMaintenanceMode)
+	maintenanceStr := os.Getenv("MAINTENANCE_MODE")
+	if maintenanceStr == "" {
+		maintenanceStr = "false"
+	}
+	cfg.maintenanceMode, err = strconv.ParseBool(maintenanceStr)
+	if err != nil {
+		logger.Error("invalid MAINTENANCE_MODE value", "value", maintenanceStr, "error", err)
+		return cfg, fmt.Errorf("invalid MAINTENANCE_MODE: %w", err)
+	}
+	cfg.maintenanceMessage = strings.TrimSpace(os.Getenv("MAINTENANCE_MESSAGE"))
+
 	// Parse pprof port (with default)
 	pprofPortStr := os.Getenv("PPROF_PORT")
 	if pprofPortStr == "" {
@@ -286,11 +546,222 @@ func loadConfig(logger *slog.Logger) (config, error) {
 	}
 	cfg.metricsPort = metricsPortInt
 
+	// Parse session title generation toggle (with default)
+	titlesStr := os.Getenv("SESSION_TITLES")
+	if titlesStr == "" {
+		titlesStr = "true" // Default to generating titles
+	}
+	titlesBool, err := strconv.ParseBool(titlesStr)
+	if err != nil {
+		logger.Error("invalid SESSION_TITLES value", "value", titlesStr, "error", err)
+		return cfg, fmt.Errorf("invalid SESSION_TITLES: %w", err)
+	}
+	cfg.sessionTitles = titlesBool
+
+	// Parse log redaction toggle (with default)
+	redactionStr := os.Getenv("LOG_REDACTION")
+	if redactionStr == "" {
+		redactionStr = "true" // Default to redacting PII from logs
+	}
+	redactionBool, err := strconv.ParseBool(redactionStr)
+	if err != nil {
+		logger.Error("invalid LOG_REDACTION value", "value", redactionStr, "error", err)
+		return cfg, fmt.Errorf("invalid LOG_REDACTION: %w", err)
+	}
+	cfg.logRedaction = redactionBool
+
+	// Parse debug capture of LLM exchanges (opt-in)
+	captureSizeStr := os.Getenv("LLM_CAPTURE_SIZE")
+	if captureSizeStr == "" {
+		captureSizeStr = "0" // Default to capture disabled
+	}
+	cfg.llmCaptureSize, err = strconv.Atoi(captureSizeStr)
+	if err != nil || cfg.llmCaptureSize < 0 || cfg.llmCaptureSize > 1000 {
+		logger.Error("invalid LLM_CAPTURE_SIZE value", "value", captureSizeStr, "error", err)
+		return cfg, fmt.Errorf("invalid LLM_CAPTURE_SIZE: must be between 0 and 1000")
+	}
+	captureBytesStr := os.Getenv("LLM_CAPTURE_MAX_BYTES")
+	if captureBytesStr == "" {
+		captureBytesStr = "4096" // Default to 4KB per captured text
+	}
+	cfg.llmCaptureMaxBytes, err = strconv.Atoi(captureBytesStr)
+	if err != nil || cfg.llmCaptureMaxBytes < 1 {
+		logger.Error("invalid LLM_CAPTURE_MAX_BYTES value", "value", captureBytesStr, "error", err)
+		return cfg, fmt.Errorf("invalid LLM_CAPTURE_MAX_BYTES: must be a positive integer")
+	}
+
+	// Validate provider settings (e.g. Gemini safety thresholds) so a typo fails at startup rather than per request
+	if err := llm.ValidateConfig(); err != nil {
+		logger.Error("invalid LLM provider settings", "error", err)
+		return cfg, err
+	}
+
+	// Parse content moderation settings (all optional)
+	if wordsStr := os.Getenv("MODERATION_BLOCKED_WORDS"); wordsStr != "" {
+		for _, word := range strings.Split(wordsStr, ",") {
+			if word = strings.TrimSpace(word); word != "" {
+				cfg.moderationBlockedWords = append(cfg.moderationBlockedWords, word)
+			}
+		}
+	}
+	cfg.moderationRulesFile = os.Getenv("MODERATION_RULES_FILE")
+	cfg.moderationAPIURL = os.Getenv("MODERATION_API_URL")
+
+	// Parse provider health check interval (with default)
+	healthIntervalStr := os.Getenv("PROVIDER_HEALTH_INTERVAL")
+	if healthIntervalStr == "" {
+		healthIntervalStr = "1m" // Default to checking every minute
+	}
+	healthInterval, err := time.ParseDuration(healthIntervalStr)
+	if err != nil || healthInterval <= 0 {
+		logger.Error("invalid PROVIDER_HEALTH_INTERVAL value", "value", healthIntervalStr, "error", err)
+		return cfg, fmt.Errorf("invalid PROVIDER_HEALTH_INTERVAL: %w", err)
+	}
+	cfg.providerHealthInterval = healthInterval
+
+	// Parse max attachment size (with default)
+	attachmentSizeStr := os.Getenv("MAX_ATTACHMENT_SIZE_KB")
+	if attachmentSizeStr == "" {
+		attachmentSizeStr = "1024" // Default to 1MB per image
+	}
+	attachmentSizeInt, err := strconv.Atoi(attachmentSizeStr)
+	if err != nil || attachmentSizeInt < 0 || attachmentSizeInt > 4096 {
+		logger.Error("invalid MAX_ATTACHMENT_SIZE_KB value", "value", attachmentSizeStr, "error", err)
+		return cfg, fmt.Errorf("invalid MAX_ATTACHMENT_SIZE_KB: %w", err)
+	}
+	cfg.maxAttachmentBytes = attachmentSizeInt * 1024 // Convert KB to bytes
+
+	// Get prompt templates file (optional)
+	cfg.promptTemplatesFile = os.Getenv("PROMPT_TEMPLATES_FILE")
+
+	// Parse enabled tools (optional)
+	if toolsStr := os.Getenv("TOOLS_ENABLED"); toolsStr != "" {
+		for _, name := range strings.Split(toolsStr, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				cfg.toolsEnabled = append(cfg.toolsEnabled, name)
+			}
+		}
+	}
+
+	// Parse unix socket permissions (with default)
+	socketModeStr := os.Getenv("GRPC_UNIX_SOCKET_MODE")
+	if socketModeStr == "" {
+		socketModeStr = "0600" // Default to owner-only access
+	}
+	socketMode, err := strconv.ParseUint(socketModeStr, 8, 32)
+	if err != nil || socketMode > 0o777 {
+		logger.Error("invalid GRPC_UNIX_SOCKET_MODE value", "value", socketModeStr, "error", err)
+		return cfg, fmt.Errorf("invalid GRPC_UNIX_SOCKET_MODE: must be octal permissions like 0660")
+	}
+
+	// Parse gRPC listeners (defaults to TLS on PORT, plus the unix socket if configured)
+	certFile := os.Getenv("TLS_CERT_FILE")
+	if certFile == "" {
+		certFile = "certs/server.crt"
+	}
+	keyFile := os.Getenv("TLS_KEY_FILE")
+	if keyFile == "" {
+		keyFile = "certs/server.key"
+	}
+	if certPEM := os.Getenv("TLS_CERT_PEM"); certPEM != "" {
+		// TLS material from a secrets manager replaces the certificate files
+		cfg.certificates, err = newCertificateStore(certPEM, os.Getenv("TLS_KEY_PEM"))
+		if err != nil {
+			logger.Error("invalid TLS_CERT_PEM value", "error", err)
+			return cfg, err
+		}
+		certFile, keyFile = secretTLSCert, secretTLSKey
+	}
+	if listenersStr := os.Getenv("GRPC_LISTENERS"); listenersStr != "" {
+		cfg.listeners, err = parseListeners(listenersStr, certFile, keyFile, fs.FileMode(socketMode))
+		if err != nil {
+			logger.Error("invalid GRPC_LISTENERS value", "value", listenersStr, "error", err)
+			return cfg, fmt.Errorf("invalid GRPC_LISTENERS: %w", err)
+		}
+	} else {
+		proxyStr := os.Getenv("GRPC_PROXY_PROTOCOL")
+		if proxyStr == "" {
+			proxyStr = "false"
+		}
+		proxyProtocol, err := strconv.ParseBool(proxyStr)
+		if err != nil {
+			logger.Error("invalid GRPC_PROXY_PROTOCOL value", "value", proxyStr, "error", err)
+			return cfg, fmt.Errorf("invalid GRPC_PROXY_PROTOCOL: %w", err)
+		}
+		cfg.listeners = []listenerConfig{{name: "tcp", network: "tcp", address: fmt.Sprintf(":%d", cfg.port), certFile: certFile, keyFile: keyFile, proxyProtocol: proxyProtocol}}
+		if socketPath := os.Getenv("GRPC_UNIX_SOCKET"); socketPath != "" {
+			cfg.listeners = append(cfg.listeners, listenerConfig{name: "unix", network: "unix", address: socketPath, mode: fs.FileMode(socketMode)})
+		}
+	}
+
+	for _, l := range cfg.listeners {
+		if l.proxyProtocol && len(cfg.trustedProxies) == 0 {
+			logger.Error("PROXY protocol needs TRUSTED_PROXIES to list the load balancer's addresses", "listener", l.name)
+			return cfg, fmt.Errorf("PROXY protocol on listener %s requires TRUSTED_PROXIES", l.name)
+		}
+	}
+
+	// Parse gRPC keepalive and connection limits
+	cfg.connection, err = loadConnectionConfig(logger, maxChatRequestBytes(cfg.maxAttachmentBytes))
+	if err != nil {
+		return cfg, err
+	}
+
+	// Parse alert webhooks (comma-separated)
+	if webhooksStr := os.Getenv("ALERT_WEBHOOK_URLS"); webhooksStr != "" {
+		for _, webhookURL := range strings.Split(webhooksStr, ",") {
+			if webhookURL = strings.TrimSpace(webhookURL); webhookURL == "" {
+				continue
+			}
+			if parsed, err := url.Parse(webhookURL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+				logger.Error("invalid ALERT_WEBHOOK_URLS entry", "value", webhookURL)
+				return cfg, fmt.Errorf("invalid ALERT_WEBHOOK_URLS: %q is not an http(s) URL", webhookURL)
+			}
+			cfg.alertWebhookURLs = append(cfg.alertWebhookURLs, webhookURL)
+		}
+	}
+
+	// Parse minimum interval between repeated alerts (with default)
+	alertIntervalStr := os.Getenv("ALERT_MIN_INTERVAL")
+	if alertIntervalStr == "" {
+		alertIntervalStr = "15m" // Default to at most one alert per condition every 15 minutes
+	}
+	alertInterval, err := time.ParseDuration(alertIntervalStr)
+	if err != nil || alertInterval < 0 {
+		logger.Error("invalid ALERT_MIN_INTERVAL value", "value", alertIntervalStr, "error", err)
+		return cfg, fmt.Errorf("invalid ALERT_MIN_INTERVAL: %w", err)
+	}
+	cfg.alertMinInterval = alertInterval
+
+	// Parse lifecycle event webhooks (comma-separated)
+	if webhooksStr := os.Getenv("EVENT_WEBHOOK_URLS"); webhooksStr != "" {
+		for _, webhookURL := range strings.Split(webhooksStr, ",") {
+			if webhookURL = strings.TrimSpace(webhookURL); webhookURL == "" {
+				continue
+			}
+			if parsed, err := url.Parse(webhookURL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+				logger.Error("invalid EVENT_WEBHOOK_URLS entry", "value", webhookURL)
+				return cfg, fmt.Errorf("invalid EVENT_WEBHOOK_URLS: %q is not an http(s) URL", webhookURL)
+			}
+			cfg.eventWebhookURLs = append(cfg.eventWebhookURLs, webhookURL)
+		}
+	}
+	cfg.eventWebhookSecret = os.Getenv("EVENT_WEBHOOK_SECRET")
+	if len(cfg.eventWebhookURLs) > 0 && cfg.eventWebhookSecret == "" {
+		logger.Error("EVENT_WEBHOOK_SECRET is required with EVENT_WEBHOOK_URLS")
+		return cfg, fmt.Errorf("EVENT_WEBHOOK_SECRET is required with EVENT_WEBHOOK_URLS")
+	}
+	cfg.eventTypes, err = events.ParseTypes(os.Getenv("EVENT_WEBHOOK_EVENTS"))
+	if err != nil {
+		logger.Error("invalid EVENT_WEBHOOK_EVENTS value", "error", err)
+		return cfg, fmt.Errorf("invalid EVENT_WEBHOOK_EVENTS: %w", err)
+	}
+
 	return cfg, nil
 }
 
 // adminAuthWrapper wraps HTTP handlers with admin authentication
-func adminAuthWrapper(next http.HandlerFunc, apiKeys map[string]string) http.HandlerFunc {
+func adminAuthWrapper(next http.HandlerFunc, apiKeys *apiKeySet) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Extract Bearer token from Authorization header
 		auth := r.Header.Get("Authorization")
@@ -308,7 +779,7 @@ func adminAuthWrapper(next http.HandlerFunc, apiKeys map[string]string) http.Han
 
 		// Extract and validate API key
 		apiKey := strings.TrimPrefix(auth, bearerPrefix)
-		role, exists := apiKeys[apiKey]
+		role, exists := apiKeys.Role(apiKey)
 		if !exists || role != "admin" {
 			http.Error(w, "Admin access required", http.StatusForbidden)
 			return
@@ -320,58 +791,168 @@ func adminAuthWrapper(next http.HandlerFunc, apiKeys map[string]string) http.Han
 }
 
 func main() {
+	showVersion := flag.Bool("version", false, "print version information and exit")
+	validateOnly := flag.Bool("validate-config", false, "check configuration, TLS files, ports and provider credentials, then exit")
+	flag.Parse()
+	if *showVersion {
+		fmt.Println("microchat.ai server", version.Get())
+		return
+	}
+
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	build := version.Get()
+	logger.Info("microchat.ai server", "version", build.Version, "commit", build.Commit, "build_date", build.BuildDate)
 
 	cfg, err := loadConfig(logger)
 	if err != nil {
 		os.Exit(1)
 	}
 
+	// Check the configuration without starting; exits non-zero if the server wouldn't run
+	if *validateOnly {
+		results := validateConfig(context.Background(), cfg, logger)
+		if printValidationResults(os.Stdout, results) {
+			os.Exit(1)
+		}
+		fmt.Println("configuration OK")
+		return
+	}
+
+	// Redact PII from all logs unless explicitly disabled for debugging
+	if cfg.logRedaction {
+		logger = slog.New(newRedactingHandler(logger.Handler()))
+	} else {
+		logger.Warn("log redaction disabled - logs may contain message contents, API keys and session IDs")
+	}
+
 	app := &application{
 		config:          cfg,
 		logger:          logger,
 		sessionStore:    NewSessionStore(cfg.sessionIdleTimeout, cfg.maxSessions, cfg.maxMessagesPerSession, cfg.maxSessionSizeBytes),
 		ipLimiter:       ratelimit.NewIPLimiter(cfg.rateLimitRPS, cfg.rateLimitBurst),
 		spendingTracker: NewSpendingTracker(cfg.dailyCallLimit),
+		embeddingQuota:  NewSpendingTracker(cfg.embeddingDailyLimit),
+		bandwidth:       newBandwidthTracker(cfg.apiKeys),
+		startedAt:       time.Now(),
+	}
+	if cfg.maintenanceMode {
+		app.maintenance.set(true, cfg.maintenanceMessage)
+		logger.Warn("starting in maintenance mode, new sessions are refused")
+	}
+	app.sessionStore.SetCompactionPolicy(cfg.sessionCompaction)
+	app.spendingTracker.SetTokenLimit(cfg.dailyTokenLimit)
+	if app.llmCapture = newLLMCapture(cfg.llmCaptureSize, cfg.llmCaptureMaxBytes); app.llmCapture != nil {
+		logger.Warn("LLM exchange capture enabled, redacted prompts and replies are kept in memory", "size", cfg.llmCaptureSize)
+	}
+	for _, tracker := range []*SpendingTracker{app.spendingTracker, app.embeddingQuota} {
+		tracker.SetLocation(cfg.dailyLimitLocation)
+		tracker.SetRetention(cfg.usageRetentionDays)
+	}
+	if cfg.maxTotalSessionBytes > 0 {
+		app.sessionStore.SetMemoryBudget(cfg.maxTotalSessionBytes)
+		logger.Info("session memory budget enabled", "max_total_mb", cfg.maxTotalSessionBytes/(1024*1024))
 	}
 
-	// create gRPC server with compression and TLS
-	certFile := os.Getenv("TLS_CERT_FILE")
-	if certFile == "" {
-		certFile = "certs/server.crt"
+	// Enable encryption at rest for stored messages if a key is configured
+	encryptionKey, err := loadEncryptionKey()
+	if err != nil {
+		logger.Error("failed to load session encryption key", "error", err)
+		os.Exit(1)
 	}
-	keyFile := os.Getenv("TLS_KEY_FILE")
-	if keyFile == "" {
-		keyFile = "certs/server.key"
+	if encryptionKey != nil {
+		if err := app.sessionStore.EnableEncryption(encryptionKey); err != nil {
+			logger.Error("failed to enable session encryption", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("session encryption at rest enabled")
+	}
+
+	// Recover sessions from disk and log new mutations if persistence is configured
+	if cfg.sessionDataDir != "" {
+		if encryptionKey == nil {
+			logger.Warn("session persistence without SESSION_ENCRYPTION_KEY - messages are written to disk in plaintext")
+		}
+		if err := app.sessionStore.EnablePersistence(cfg.sessionDataDir, logger); err != nil {
+			logger.Error("failed to enable session persistence", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	// Archive idle sessions to object storage instead of deleting them if a store is configured
+	if cfg.archiveStore != nil {
+		app.sessionArchiver = &sessionArchiver{store: cfg.archiveStore, retention: cfg.archiveRetention, logger: logger}
+		app.sessionStore.SetIdleArchiver(app.sessionArchiver.archive)
+		logger.Info("session archiving enabled", "store", cfg.archiveStore.Name(), "retention", cfg.archiveRetention)
+	}
+
+	// Send operational alerts to webhooks if any are configured
+	if len(cfg.alertWebhookURLs) > 0 {
+		app.alerts = alerts.NewNotifier(cfg.alertWebhookURLs, cfg.alertMinInterval, logger)
+		logger.Info("alert webhooks enabled", "webhooks", len(cfg.alertWebhookURLs), "min_interval", cfg.alertMinInterval)
+	}
+
+	// Send signed lifecycle events to webhooks if any are configured
+	if len(cfg.eventWebhookURLs) > 0 {
+		app.events = events.NewEmitter(cfg.eventWebhookURLs, cfg.eventWebhookSecret, cfg.eventTypes, logger)
+		app.sessionStore.OnSessionRemoved(app.emitSessionEvicted)
+		app.spendingTracker.OnLimitReached(app.emitDailyLimitHit)
+		app.embeddingQuota.OnLimitReached(func(apiKey, _ string) { app.emitDailyLimitHit(apiKey, "embeddings") })
+		logger.Info("event webhooks enabled", "webhooks", len(cfg.eventWebhookURLs), "events", len(cfg.eventTypes))
 	}
 
-	creds, err := credentials.NewServerTLSFromFile(certFile, keyFile)
+	// Build the content moderation pipeline if any moderators are configured
+	app.moderator, err = newModerationPipeline(cfg)
 	if err != nil {
-		logger.Error("failed to load TLS credentials", "error", err)
+		logger.Error("failed to configure content moderation", "error", err)
 		os.Exit(1)
 	}
+	if app.moderator != nil {
+		logger.Info("content moderation enabled", "moderators", app.moderator.Len())
+	}
+
+	// Enable retrieval over uploaded documents if an embedding provider is available
+	app.knowledge = newKnowledgeBase(app)
 
-	// Create gRPC server with auth and rate limiting interceptors
-	s := grpc.NewServer(
-		grpc.Creds(creds),
-		grpc.ChainUnaryInterceptor(
-			AuthInterceptor(cfg.apiKeys, app.spendingTracker),
-			RateLimitInterceptor(app.ipLimiter),
-		),
-	)
+	// Load operator-defined prompt templates
+	if cfg.promptTemplatesFile != "" {
+		app.prompts, err = loadPromptTemplates(cfg.promptTemplatesFile)
+		if err != nil {
+			logger.Error("failed to load prompt templates", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("prompt templates loaded", "templates", app.prompts.Names())
+	}
+
+	// Enable tool calling if any tools are configured
+	if len(cfg.toolsEnabled) > 0 {
+		app.tools, err = tools.NewBuiltinRegistry(cfg.toolsEnabled)
+		if err != nil {
+			logger.Error("failed to configure tools", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("tool calling enabled", "tools", cfg.toolsEnabled)
+	}
 
-	// register service
-	pb.RegisterChatServiceServer(s, app)
+	// Health check LLM providers in the background so routing and readiness use live data
+	app.providerHealth = llm.NewHealthMonitor(llm.DefaultHealthProbes(logger), logger)
+	app.providerHealth.OnUnhealthy(app.alertProviderUnhealthy)
+	healthCtx, stopHealthChecks := context.WithCancel(context.Background())
+	go app.providerHealth.Run(healthCtx, cfg.providerHealthInterval)
 
-	// Enable reflection in development only
-	if cfg.env == "development" {
-		reflection.Register(s)
+	// Pick up secrets rotated in Vault or AWS Secrets Manager, and provider keys rotated on disk
+	secretsCtx, stopSecretsRefresh := context.WithCancel(context.Background())
+	if cfg.secretsSource != nil && cfg.secretsRefreshInterval > 0 {
+		go secrets.Watch(secretsCtx, cfg.secretsSource, cfg.secretsRefreshInterval, cfg.secretValues, logger, app.applySecretChanges)
+	}
+	if len(cfg.providerKeyFiles) > 0 {
+		logProviderKeyFiles(logger, cfg.providerKeyFiles)
+		go watchProviderKeyFiles(secretsCtx, app, cfg.providerKeyFiles, providerKeyFileInterval)
 	}
 
-	// Listen on TCP
-	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.port))
+	// Create a gRPC server on each listener with auth, rate limiting and session expiry interceptors
+	listeners, err := openListeners(app, cfg.listeners)
 	if err != nil {
-		logger.Error("failed to listen", "error", err)
+		logger.Error("failed to start listeners", "error", err)
 		os.Exit(1)
 	}
 
@@ -384,13 +965,69 @@ func main() {
 		for {
 			select {
 			case <-ticker.C:
-				app.sessionStore.CleanupIdleSessions()
+				if removed := app.sessionStore.CleanupIdleSessions(); removed > 0 {
+					app.logger.Info("removed idle sessions", "count", removed)
+				}
 			case <-done:
 				return
 			}
 		}
 	}()
 
+	// Delete session archives past ARCHIVE_RETENTION
+	archiveCtx, stopArchivePruning := context.WithCancel(context.Background())
+	if app.sessionArchiver != nil && cfg.archiveRetention > 0 {
+		go app.sessionArchiver.runPruning(archiveCtx, archivePruneInterval)
+	}
+
+	// Adjust rate limits to LLM provider load
+	if cfg.adaptiveRateLimit != nil {
+		app.adaptiveLimit = ratelimit.NewAdaptive(app.ipLimiter, cfg.rateLimitRPS, *cfg.adaptiveRateLimit)
+		updateEffectiveRateLimit(float64(cfg.rateLimitRPS))
+		logger.Info("adaptive rate limiting enabled", "latency_threshold", cfg.adaptiveRateLimit.LatencyThreshold, "error_rate_threshold", cfg.adaptiveRateLimit.ErrorRateThreshold)
+		go func() {
+			ticker := time.NewTicker(adaptiveRateLimitWindow)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ticker.C:
+					previous := app.adaptiveLimit.Factor()
+					effective := app.adaptiveLimit.Evaluate()
+					updateEffectiveRateLimit(float64(effective))
+					if factor := app.adaptiveLimit.Factor(); factor != previous {
+						app.logger.Warn("adjusted rate limit for provider load", "effective_rps", float64(effective), "factor", factor)
+					}
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+
+	// Reset daily limits at midnight and prune keys that have gone quiet
+	go app.spendingTracker.Run(done)
+	go app.embeddingQuota.Run(done)
+
+	// Start snapshot goroutine for session persistence
+	if cfg.sessionDataDir != "" {
+		go func() {
+			ticker := time.NewTicker(cfg.snapshotInterval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ticker.C:
+					if err := app.sessionStore.Snapshot(); err != nil {
+						app.logger.Error("failed to snapshot sessions", "error", err)
+					}
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+
 	// Setup graceful shutdown
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
@@ -423,6 +1060,9 @@ func main() {
 	// Register Prometheus metrics endpoint with admin authentication
 	metricsMux.Handle("/metrics", adminAuthWrapper(promhttp.Handler().ServeHTTP, cfg.apiKeys))
 
+	// Readiness probe is unauthenticated so orchestrators can poll it
+	metricsMux.HandleFunc("/readyz", app.readinessHandler)
+
 	metricsServer := &http.Server{
 		Addr:    metricsAddr,
 		Handler: metricsMux,
@@ -438,13 +1078,8 @@ func main() {
 	// Start metrics updater
 	startMetricsUpdater(app)
 
-	// Start server in goroutine
-	go func() {
-		logger.Info("starting gRPC server", "addr", lis.Addr(), "env", cfg.env)
-		if err := s.Serve(lis); err != nil {
-			logger.Error("failed to serve", "error", err)
-		}
-	}()
+	// Start serving on every listener
+	serveListeners(listeners, app)
 
 	// Wait for shutdown signal
 	<-sigChan
@@ -456,6 +1091,11 @@ func main() {
 	// Stop rate limiter cleanup
 	app.ipLimiter.Stop()
 
+	// Stop provider health checks
+	stopHealthChecks()
+	stopSecretsRefresh()
+	stopArchivePruning()
+
 	// Gracefully stop both HTTP servers
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -470,7 +1110,18 @@ func main() {
 		logger.Error("failed to shutdown metrics server", "error", err)
 	}
 
-	// Gracefully stop the gRPC server
-	s.GracefulStop()
+	// Gracefully stop the gRPC servers
+	stopListeners(listeners)
+
+	// Write a final snapshot once no more chats can change sessions
+	if err := app.sessionStore.ClosePersistence(); err != nil {
+		logger.Error("failed to write final session snapshot", "error", err)
+	}
+
+	// Let alerts and events raised during shutdown finish delivering
+	app.alerts.Wait()
+	eventsCtx, cancelEvents := context.WithTimeout(context.Background(), 10*time.Second)
+	app.events.Close(eventsCtx)
+	cancelEvents()
 	logger.Info("server stopped")
 }
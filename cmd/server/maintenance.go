@@ -0,0 +1,56 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// defaultMaintenanceMessage is returned to clients when maintenance mode is enabled without a message
+const defaultMaintenanceMessage = "server is under maintenance, try again shortly"
+
+// maintenanceMode is the server-wide maintenance toggle used for staged upgrades
+// While enabled, new sessions are refused and the readiness endpoint reports not ready;
+// existing sessions keep working so in-progress conversations aren't cut off
+type maintenanceMode struct {
+	mu      sync.RWMutex
+	enabled bool
+	message string
+	since   time.Time // When maintenance mode was last enabled
+}
+
+// set enables or disables maintenance mode; an empty message uses the default
+func (m *maintenanceMode) set(enabled bool, message string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if message == "" {
+		message = defaultMaintenanceMessage
+	}
+	if enabled && !m.enabled {
+		m.since = time.Now()
+	}
+	if !enabled {
+		message, m.since = "", time.Time{}
+	}
+	m.enabled, m.message = enabled, message
+}
+
+// status returns whether maintenance mode is enabled, the client-facing message and when it was enabled
+func (m *maintenanceMode) status() (enabled bool, message string, since time.Time) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.enabled, m.message, m.since
+}
+
+// checkMaintenance returns an Unavailable error for RPCs that create sessions while maintenance mode is on
+func (app *application) checkMaintenance(method string) error {
+	enabled, message, _ := app.maintenance.status()
+	if !enabled {
+		return nil
+	}
+	incrementGRPCError(method, "Unavailable")
+	return status.Error(codes.Unavailable, message)
+}
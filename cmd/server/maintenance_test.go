@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	pb "microchat.ai/proto"
+)
+
+func TestSetMaintenanceMode(t *testing.T) {
+	app, _ := setupTestApplicationWithMock(t)
+	ctx := context.WithValue(context.Background(), "api_key", "alice-key")
+
+	existing, err := app.StartSession(ctx, &pb.StartSessionRequest{})
+	if err != nil {
+		t.Fatalf("StartSession failed: %v", err)
+	}
+
+	resp, err := app.SetMaintenanceMode(ctx, &pb.SetMaintenanceModeRequest{Enabled: true, Message: "upgrading to v2"})
+	if err != nil {
+		t.Fatalf("SetMaintenanceMode failed: %v", err)
+	}
+	if !resp.Enabled || resp.Message != "upgrading to v2" || resp.SinceUnix == 0 {
+		t.Errorf("Unexpected response enabling maintenance mode: %+v", resp)
+	}
+
+	// New sessions are refused with the maintenance message
+	_, err = app.StartSession(ctx, &pb.StartSessionRequest{})
+	if status.Code(err) != codes.Unavailable || status.Convert(err).Message() != "upgrading to v2" {
+		t.Errorf("Expected Unavailable with maintenance message, got %v", err)
+	}
+	if _, err := app.ForkSession(ctx, &pb.ForkSessionRequest{SessionId: existing.SessionId}); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable for ForkSession, got %v", err)
+	}
+
+	// Existing sessions keep working
+	if _, err := app.Chat(ctx, &pb.ChatRequest{SessionId: existing.SessionId, Message: "hello"}); err != nil {
+		t.Errorf("Expected chat in an existing session to work during maintenance, got %v", err)
+	}
+
+	// Readiness flips so load balancers drain the instance
+	rec := httptest.NewRecorder()
+	app.readinessHandler(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	var ready readinessResponse
+	if err := json.NewDecoder(rec.Body).Decode(&ready); err != nil {
+		t.Fatalf("Failed to decode readiness response: %v", err)
+	}
+	if rec.Code != http.StatusServiceUnavailable || ready.Ready || !ready.Maintenance {
+		t.Errorf("Expected 503 not ready in maintenance, got %d %+v", rec.Code, ready)
+	}
+
+	resp, err = app.SetMaintenanceMode(ctx, &pb.SetMaintenanceModeRequest{Enabled: false})
+	if err != nil {
+		t.Fatalf("SetMaintenanceMode failed: %v", err)
+	}
+	if resp.Enabled || resp.Message != "" || resp.SinceUnix != 0 {
+		t.Errorf("Unexpected response disabling maintenance mode: %+v", resp)
+	}
+	if _, err := app.StartSession(ctx, &pb.StartSessionRequest{}); err != nil {
+		t.Errorf("Expected StartSession to work after maintenance, got %v", err)
+	}
+	rec = httptest.NewRecorder()
+	app.readinessHandler(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 after maintenance, got %d", rec.Code)
+	}
+}
+
+func TestSetMaintenanceMode_DefaultMessage(t *testing.T) {
+	app, _ := setupTestApplicationWithMock(t)
+	ctx := context.WithValue(context.Background(), "api_key", "alice-key")
+
+	resp, err := app.SetMaintenanceMode(ctx, &pb.SetMaintenanceModeRequest{Enabled: true})
+	if err != nil {
+		t.Fatalf("SetMaintenanceMode failed: %v", err)
+	}
+	if resp.Message != defaultMaintenanceMessage {
+		t.Errorf("Expected default message, got %q", resp.Message)
+	}
+
+	app.config.maintenanceMessage = "back at 10:00 UTC"
+	if resp, _ = app.SetMaintenanceMode(ctx, &pb.SetMaintenanceModeRequest{Enabled: true}); resp.Message != "back at 10:00 UTC" {
+		t.Errorf("Expected configured message, got %q", resp.Message)
+	}
+}
@@ -3,10 +3,14 @@ package main
 import (
 	"crypto/sha256"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"microchat.ai/cmd/server/llm"
+	"microchat.ai/version"
 )
 
 var (
@@ -25,7 +29,7 @@ var (
 			Help:    "Duration of LLM provider calls in seconds",
 			Buckets: []float64{0.1, 0.5, 1.0, 2.0, 5.0, 10.0, 20.0, 30.0},
 		},
-		[]string{"provider"},
+		[]string{"model", "provider"},
 	)
 
 	activeSessions = promauto.NewGauge(
@@ -58,6 +62,13 @@ var (
 		[]string{"method"},
 	)
 
+	effectiveRateLimitRPS = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "microchat_rate_limit_effective_rps",
+			Help: "Requests per second currently allowed per API key after adaptive adjustment for provider load",
+		},
+	)
+
 	// Business metrics - API usage
 	apiKeysTotal = promauto.NewGauge(
 		prometheus.GaugeOpts{
@@ -74,6 +85,14 @@ var (
 		[]string{"key_hash"},
 	)
 
+	keyBytesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "microchat_key_bytes_total",
+			Help: "Bytes received and sent per API key, as serialized payload or on the wire with gRPC framing",
+		},
+		[]string{"key_hash", "direction", "kind"},
+	)
+
 	apiKeysOverLimit = promauto.NewGauge(
 		prometheus.GaugeOpts{
 			Name: "microchat_api_keys_over_limit",
@@ -97,6 +116,75 @@ var (
 		},
 	)
 
+	sessionMemoryHeadroomBytes = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "microchat_session_memory_headroom_bytes",
+			Help: "Remaining room under MAX_TOTAL_SESSION_MEMORY_MB in bytes (unset when no budget is configured)",
+		},
+	)
+
+	// Distribution of current sessions, replaced on every metrics update pass
+	sessionMessageCounts = newSnapshotHistogram(
+		"microchat_session_messages",
+		"Messages per active session, sampled on each metrics update (compare with MAX_MESSAGES_PER_SESSION)",
+		[]float64{0, 2, 5, 10, 20, 50, 100, 200, 500},
+	)
+
+	sessionSizeBytes = newSnapshotHistogram(
+		"microchat_session_size_bytes",
+		"Memory per active session in bytes, sampled on each metrics update (compare with MAX_SESSION_SIZE_KB)",
+		[]float64{1024, 5 * 1024, 10 * 1024, 25 * 1024, 50 * 1024, 100 * 1024, 250 * 1024, 500 * 1024, 1024 * 1024},
+	)
+
+	// Session eviction and cleanup
+	sessionsRemovedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "microchat_sessions_removed_total",
+			Help: "Total number of sessions removed by the server, by reason (lru_eviction, memory_pressure, idle_cleanup, idle_archive)",
+		},
+		[]string{"reason"},
+	)
+
+	sessionArchiveOpsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "microchat_session_archive_operations_total",
+			Help: "Total number of session archive operations with ARCHIVE_STORE, by operation (archive, restore, prune) and result (success, error)",
+		},
+		[]string{"operation", "result"},
+	)
+
+	messagesRejectedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "microchat_messages_rejected_total",
+			Help: "Total number of messages rejected by per-session limits, by limit (message_count, session_size)",
+		},
+		[]string{"limit"},
+	)
+
+	chatTurnsAbandonedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "microchat_chat_turns_abandoned_total",
+			Help: "Total number of Chat turns rolled back because the client cancelled or its deadline passed, by reason (canceled, deadline_exceeded)",
+		},
+		[]string{"reason"},
+	)
+
+	sessionCompactionsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "microchat_session_compactions_total",
+			Help: "Total number of session compactions at the message or size limit, by policy (drop_oldest, summarize)",
+		},
+		[]string{"policy"},
+	)
+
+	sessionCleanupDuration = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "microchat_session_cleanup_duration_seconds",
+			Help:    "Duration of idle session cleanup passes in seconds",
+			Buckets: []float64{0.0001, 0.001, 0.01, 0.05, 0.1, 0.5, 1.0},
+		},
+	)
+
 	// Error tracking
 	grpcErrors = promauto.NewCounterVec(
 		prometheus.CounterOpts{
@@ -111,7 +199,67 @@ var (
 			Name: "microchat_llm_errors_total",
 			Help: "Total number of LLM provider errors",
 		},
-		[]string{"provider", "error_type"},
+		[]string{"model", "provider", "error_type"},
+	)
+
+	llmFallbacks = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "microchat_llm_fallbacks_total",
+			Help: "Requests served by a different provider than the one registered for the requested model",
+		},
+		[]string{"model", "provider", "reason"},
+	)
+
+	// Provider health
+	providerHealthy = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "microchat_provider_healthy",
+			Help: "Whether the LLM provider passed its last health check (1 healthy, 0 unhealthy)",
+		},
+		[]string{"provider"},
+	)
+
+	// Upstream API key pool, tracked per key so one exhausted key is visible before it matters
+	upstreamKeyCalls = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "microchat_upstream_key_calls",
+			Help: "Upstream LLM calls made with each pooled provider API key since startup",
+		},
+		[]string{"provider", "key_hash"},
+	)
+
+	upstreamKeyQuotaErrors = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "microchat_upstream_key_quota_errors",
+			Help: "Upstream calls rejected because the provider API key's quota was used up, since startup",
+		},
+		[]string{"provider", "key_hash"},
+	)
+
+	upstreamKeyExhausted = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "microchat_upstream_key_exhausted",
+			Help: "Whether the provider API key is being skipped after a quota or auth error (1 skipped, 0 in use)",
+		},
+		[]string{"provider", "key_hash"},
+	)
+
+	// Tool calling
+	toolCalls = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "microchat_tool_calls_total",
+			Help: "Total number of tool calls executed by tool and outcome",
+		},
+		[]string{"tool", "outcome"},
+	)
+
+	// Content moderation
+	moderationActions = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "microchat_moderation_actions_total",
+			Help: "Total number of moderation checks by stage and resulting action",
+		},
+		[]string{"stage", "action"},
 	)
 
 	// Server configuration info metrics
@@ -123,6 +271,14 @@ var (
 		[]string{"max_sessions", "max_messages_per_session", "max_session_size_kb", "rate_limit_rps", "rate_limit_burst"},
 	)
 
+	buildInfo = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "microchat_build_info",
+			Help: "Build of the running server as labels, always 1",
+		},
+		[]string{"version", "commit", "build_date", "go_version"},
+	)
+
 	serverStartTime = promauto.NewGauge(
 		prometheus.GaugeOpts{
 			Name: "microchat_server_start_time_seconds",
@@ -143,14 +299,18 @@ func recordRequestDuration(method string, seconds float64) {
 	requestDuration.WithLabelValues(method).Observe(seconds)
 }
 
-func recordLLMCallDuration(provider string, seconds float64) {
-	llmCallDuration.WithLabelValues(provider).Observe(seconds)
+func recordLLMCallDuration(model string, provider string, seconds float64) {
+	llmCallDuration.WithLabelValues(model, provider).Observe(seconds)
 }
 
 func incrementRateLimitExceeded() {
 	rateLimitExceededTotal.Inc()
 }
 
+func updateEffectiveRateLimit(rps float64) {
+	effectiveRateLimitRPS.Set(rps)
+}
+
 func recordRequestSize(method string, bytes int) {
 	requestBytes.WithLabelValues(method).Observe(float64(bytes))
 }
@@ -161,7 +321,8 @@ func updateAPIKeyMetrics(totalKeys int, usage map[string]int, limit int, keysOve
 	dailyCallLimit.Set(float64(limit))
 	apiKeysOverLimit.Set(float64(keysOverLimit))
 
-	// Update per-key usage (using hash of key for privacy)
+	// Update per-key usage (using hash of key for privacy), dropping keys the tracker has pruned
+	apiCallsToday.Reset()
 	for keyHash, calls := range usage {
 		apiCallsToday.WithLabelValues(keyHash).Set(float64(calls))
 	}
@@ -174,12 +335,98 @@ func updateTotalSessionMemory(bytes int) {
 	totalSessionMemoryBytes.Set(float64(bytes))
 }
 
+func updateSessionMemoryHeadroom(bytes int) {
+	sessionMemoryHeadroomBytes.Set(float64(bytes))
+}
+
+func updateSessionDistribution(messageCounts, sizes []int) {
+	sessionMessageCounts.Update(messageCounts)
+	sessionSizeBytes.Update(sizes)
+}
+
+func recordKeyBytes(keyHash string, payloadIn, payloadOut, wireIn, wireOut int64) {
+	for _, c := range []struct {
+		direction, kind string
+		bytes           int64
+	}{
+		{"in", "payload", payloadIn},
+		{"out", "payload", payloadOut},
+		{"in", "wire", wireIn},
+		{"out", "wire", wireOut},
+	} {
+		if c.bytes > 0 {
+			keyBytesTotal.WithLabelValues(keyHash, c.direction, c.kind).Add(float64(c.bytes))
+		}
+	}
+}
+
+func recordSessionsRemoved(reason string, count int) {
+	sessionsRemovedTotal.WithLabelValues(reason).Add(float64(count))
+}
+
+func recordSessionArchiveOp(operation, result string) {
+	sessionArchiveOpsTotal.WithLabelValues(operation, result).Inc()
+}
+
+func incrementMessagesRejected(limit string) {
+	messagesRejectedTotal.WithLabelValues(limit).Inc()
+}
+
+func incrementChatTurnsAbandoned(reason string) {
+	chatTurnsAbandonedTotal.WithLabelValues(reason).Inc()
+}
+
+func recordSessionCompaction(policy string) {
+	sessionCompactionsTotal.WithLabelValues(policy).Inc()
+}
+
+func recordSessionCleanupDuration(seconds float64) {
+	sessionCleanupDuration.Observe(seconds)
+}
+
 func incrementGRPCError(method string, grpcCode string) {
 	grpcErrors.WithLabelValues(method, grpcCode).Inc()
 }
 
-func incrementLLMError(provider string, errorType string) {
-	llmErrors.WithLabelValues(provider, errorType).Inc()
+func incrementLLMError(model string, provider string, errorType string) {
+	llmErrors.WithLabelValues(model, provider, errorType).Inc()
+}
+
+func incrementLLMFallback(model string, provider string, reason string) {
+	llmFallbacks.WithLabelValues(model, provider, reason).Inc()
+}
+
+func updateProviderHealth(provider string, healthy bool) {
+	value := 0.0
+	if healthy {
+		value = 1.0
+	}
+	providerHealthy.WithLabelValues(provider).Set(value)
+}
+
+// updateUpstreamKeyMetrics exports each pooled provider key's usage, dropping keys rotated out
+func updateUpstreamKeyMetrics(provider string, stats []llm.KeyStats, now time.Time) {
+	for _, gauge := range []*prometheus.GaugeVec{upstreamKeyCalls, upstreamKeyQuotaErrors, upstreamKeyExhausted} {
+		gauge.DeletePartialMatch(prometheus.Labels{"provider": provider})
+	}
+	for _, key := range stats {
+		keyHash := hashAPIKey(key.Key)
+		upstreamKeyCalls.WithLabelValues(provider, keyHash).Set(float64(key.Calls))
+		upstreamKeyQuotaErrors.WithLabelValues(provider, keyHash).Set(float64(key.QuotaErrors))
+		exhausted := 0.0
+		if key.ExhaustedUntil.After(now) {
+			exhausted = 1.0
+		}
+		upstreamKeyExhausted.WithLabelValues(provider, keyHash).Set(exhausted)
+	}
+}
+
+func recordToolCall(tool string, outcome string) {
+	toolCalls.WithLabelValues(tool, outcome).Inc()
+}
+
+func recordModerationAction(stage string, action string) {
+	moderationActions.WithLabelValues(stage, action).Inc()
 }
 
 // hashAPIKey creates a privacy-preserving hash of an API key for metrics
@@ -188,35 +435,101 @@ func hashAPIKey(key string) string {
 	return fmt.Sprintf("%x", hash[:8]) // Use first 8 bytes for short hash
 }
 
+// snapshotHistogram is a histogram of the current session population rather than of events
+// Each update replaces the previous distribution, so long-lived sessions aren't observed again
+// on every pass the way a regular histogram would count them
+type snapshotHistogram struct {
+	desc    *prometheus.Desc
+	buckets []float64
+
+	mu     sync.Mutex
+	count  uint64
+	sum    float64
+	counts map[float64]uint64 // Cumulative count per bucket upper bound
+}
+
+// newSnapshotHistogram creates and registers a snapshot histogram
+func newSnapshotHistogram(name, help string, buckets []float64) *snapshotHistogram {
+	h := &snapshotHistogram{
+		desc:    prometheus.NewDesc(name, help, nil, nil),
+		buckets: buckets,
+		counts:  make(map[float64]uint64, len(buckets)),
+	}
+	prometheus.MustRegister(h)
+	return h
+}
+
+// Update replaces the distribution with the given values
+func (h *snapshotHistogram) Update(values []int) {
+	counts := make(map[float64]uint64, len(h.buckets))
+	var sum float64
+	for _, v := range values {
+		sum += float64(v)
+		for _, bound := range h.buckets {
+			if float64(v) <= bound {
+				counts[bound]++
+			}
+		}
+	}
+
+	h.mu.Lock()
+	h.count, h.sum, h.counts = uint64(len(values)), sum, counts
+	h.mu.Unlock()
+}
+
+// Describe implements prometheus.Collector
+func (h *snapshotHistogram) Describe(ch chan<- *prometheus.Desc) {
+	ch <- h.desc
+}
+
+// Collect implements prometheus.Collector
+func (h *snapshotHistogram) Collect(ch chan<- prometheus.Metric) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ch <- prometheus.MustNewConstHistogram(h.desc, h.count, h.sum, h.counts)
+}
+
 // updateBusinessMetrics collects and updates all business metrics
 func updateBusinessMetrics(app *application) {
 	// Update session metrics
 	updateActiveSessions(app.sessionStore.GetSessionCount())
 
 	// Update API key metrics
-	app.spendingTracker.mu.RLock()
-	totalKeys := len(app.config.apiKeys)
+	totalKeys := app.config.apiKeys.Len()
 	keysOverLimit := 0
 	usage := make(map[string]int)
 
-	for key, usageData := range app.spendingTracker.usage {
-		keyHash := hashAPIKey(key)
-		usage[keyHash] = usageData.calls
-		if usageData.calls >= app.spendingTracker.limit {
+	for key, calls := range app.spendingTracker.Snapshot() {
+		usage[hashAPIKey(key)] = calls
+		if calls >= app.spendingTracker.limit {
 			keysOverLimit++
 		}
 	}
-	app.spendingTracker.mu.RUnlock()
 
 	updateAPIKeyMetrics(totalKeys, usage, app.spendingTracker.limit, keysOverLimit)
 
 	// Update session memory metrics (aggregate only - no per-session tracking)
-	sessionsInfo := app.sessionStore.GetAllSessionsInfo()
-	totalMemory := 0
-	for _, info := range sessionsInfo {
-		totalMemory += info.SizeBytes
-	}
+	totalMemory, memoryBudget := app.sessionStore.GetMemoryUsage()
 	updateTotalSessionMemory(totalMemory)
+	if memoryBudget > 0 {
+		updateSessionMemoryHeadroom(memoryBudget - totalMemory)
+	}
+	updateSessionDistribution(app.sessionStore.SessionSizes())
+
+	// Update upstream API key pool metrics
+	now := time.Now()
+	for _, reg := range llm.Registered() {
+		if reg.APIKeyEnv != "" {
+			updateUpstreamKeyMetrics(reg.Name, llm.APIKeyStats(reg.Name), now)
+		}
+	}
+
+	// Update provider health metrics
+	if app.providerHealth != nil {
+		for name, health := range app.providerHealth.Snapshot() {
+			updateProviderHealth(name, health.Healthy)
+		}
+	}
 }
 
 // initializeServerMetrics sets up one-time server configuration metrics
@@ -224,6 +537,10 @@ func initializeServerMetrics(cfg config) {
 	// Set server start time
 	serverStartTime.Set(float64(time.Now().Unix()))
 
+	// Set build information as labels
+	build := version.Get()
+	buildInfo.WithLabelValues(build.Version, build.Commit, build.BuildDate, build.GoVersion).Set(1)
+
 	// Set server configuration as labels
 	serverConfigInfo.WithLabelValues(
 		fmt.Sprintf("%d", cfg.maxSessions),
@@ -247,6 +564,7 @@ func startMetricsUpdater(app *application) {
 			select {
 			case <-ticker.C:
 				updateBusinessMetrics(app)
+				checkResourceAlerts(app)
 			}
 		}
 	}()
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"microchat.ai/cmd/server/moderation"
+)
+
+// withheldReply replaces an LLM reply blocked by output moderation
+const withheldReply = "[response withheld by content policy]"
+
+// newModerationPipeline builds the moderation pipeline from config
+// Returns nil when no moderation is configured
+func newModerationPipeline(cfg config) (*moderation.Pipeline, error) {
+	var moderators []moderation.Moderator
+
+	if len(cfg.moderationBlockedWords) > 0 {
+		words, err := moderation.NewWordListModerator("blocked_words", cfg.moderationBlockedWords, moderation.ActionBlock)
+		if err != nil {
+			return nil, err
+		}
+		moderators = append(moderators, words)
+	}
+
+	if cfg.moderationRulesFile != "" {
+		f, err := os.Open(cfg.moderationRulesFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open moderation rules: %w", err)
+		}
+		defer f.Close()
+
+		rules, err := moderation.ParseRules(cfg.moderationRulesFile, f)
+		if err != nil {
+			return nil, err
+		}
+		moderators = append(moderators, rules...)
+	}
+
+	if cfg.moderationAPIURL != "" {
+		moderators = append(moderators, moderation.NewHTTPModerator(cfg.moderationAPIURL))
+	}
+
+	if len(moderators) == 0 {
+		return nil, nil
+	}
+	return moderation.NewPipeline(moderators...), nil
+}
+
+// moderate runs text through the moderation pipeline, recording metrics and logging matches
+// Moderation failures are logged and the text is allowed through, so an unavailable
+// moderation API doesn't take chat down with it
+func (app *application) moderate(ctx context.Context, stage moderation.Stage, sessionID string, text string) moderation.Result {
+	if app.moderator == nil {
+		return moderation.Result{Action: moderation.ActionAllow, Text: text}
+	}
+
+	result, err := app.moderator.Check(ctx, stage, text)
+	if err != nil {
+		recordModerationAction(string(stage), "error")
+		app.logger.Error("moderation check failed", "session_id", sessionID, "stage", stage, "error", err)
+		return moderation.Result{Action: moderation.ActionAllow, Text: text}
+	}
+
+	recordModerationAction(string(stage), result.Action.String())
+	if result.Action != moderation.ActionAllow {
+		app.logger.Warn("content moderated", "session_id", sessionID, "stage", stage,
+			"action", result.Action.String(), "rules", result.Rules)
+	}
+	return result
+}
@@ -0,0 +1,89 @@
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// httpModeratorTimeout bounds each call to the external moderation API
+const httpModeratorTimeout = 5 * time.Second
+
+// HTTPModerator delegates decisions to an external moderation API
+//
+// The API receives a POST with {"stage": "input", "text": "..."} and responds with
+// {"action": "allow|flag|redact|block", "rule": "...", "text": "..."}, where text is
+// the replacement for redact actions
+type HTTPModerator struct {
+	url    string
+	client *http.Client
+}
+
+type httpModerationRequest struct {
+	Stage Stage  `json:"stage"`
+	Text  string `json:"text"`
+}
+
+type httpModerationResponse struct {
+	Action string `json:"action"`
+	Rule   string `json:"rule"`
+	Text   string `json:"text"`
+}
+
+// NewHTTPModerator creates a moderator that calls the API at url
+func NewHTTPModerator(url string) *HTTPModerator {
+	return &HTTPModerator{
+		url:    url,
+		client: &http.Client{Timeout: httpModeratorTimeout},
+	}
+}
+
+func (m *HTTPModerator) Name() string {
+	return "api"
+}
+
+func (m *HTTPModerator) Moderate(ctx context.Context, stage Stage, text string) (Verdict, error) {
+	body, err := json.Marshal(httpModerationRequest{Stage: stage, Text: text})
+	if err != nil {
+		return Verdict{}, fmt.Errorf("failed to encode moderation request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.url, bytes.NewReader(body))
+	if err != nil {
+		return Verdict{}, fmt.Errorf("failed to create moderation request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return Verdict{}, fmt.Errorf("moderation API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Verdict{}, fmt.Errorf("moderation API returned status %d", resp.StatusCode)
+	}
+
+	var decoded httpModerationResponse
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		return Verdict{}, fmt.Errorf("failed to decode moderation response: %w", err)
+	}
+
+	action, err := ParseAction(decoded.Action)
+	if err != nil {
+		return Verdict{}, err
+	}
+
+	rule := decoded.Rule
+	if rule == "" {
+		rule = m.Name()
+	}
+	verdict := Verdict{Action: action, Rule: rule, Text: decoded.Text}
+	if action == ActionRedact && verdict.Text == "" {
+		verdict.Text = RedactedText
+	}
+	return verdict, nil
+}
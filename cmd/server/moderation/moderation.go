@@ -0,0 +1,128 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Action is what the pipeline does with text that matches a rule
+// Actions are ordered by severity so the strongest verdict wins
+type Action int
+
+const (
+	ActionAllow  Action = iota // Pass through unchanged
+	ActionFlag                 // Pass through unchanged, but log and count the match
+	ActionRedact               // Replace the matched content
+	ActionBlock                // Reject the text entirely
+)
+
+// String returns the config/metrics name of the action
+func (a Action) String() string {
+	switch a {
+	case ActionAllow:
+		return "allow"
+	case ActionFlag:
+		return "flag"
+	case ActionRedact:
+		return "redact"
+	case ActionBlock:
+		return "block"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseAction converts a config string to an Action
+func ParseAction(s string) (Action, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "allow":
+		return ActionAllow, nil
+	case "flag":
+		return ActionFlag, nil
+	case "redact":
+		return ActionRedact, nil
+	case "block":
+		return ActionBlock, nil
+	default:
+		return ActionAllow, fmt.Errorf("unknown moderation action %q (expected allow, flag, redact or block)", s)
+	}
+}
+
+// Stage identifies which side of the conversation is being moderated
+type Stage string
+
+const (
+	StageInput  Stage = "input"  // User message before it reaches the LLM
+	StageOutput Stage = "output" // LLM reply before it reaches the user
+)
+
+// RedactedText replaces content removed by a redact action
+const RedactedText = "[redacted]"
+
+// Verdict is a single moderator's decision about a piece of text
+type Verdict struct {
+	Action Action
+	Rule   string // Name of the matching rule, for logs and metrics
+	Text   string // Replacement text when Action is ActionRedact
+}
+
+// Moderator checks text at a given stage
+// Implementations must be safe for concurrent use
+type Moderator interface {
+	Moderate(ctx context.Context, stage Stage, text string) (Verdict, error)
+	Name() string
+}
+
+// Result is the combined outcome of running every moderator in a pipeline
+type Result struct {
+	Action Action   // Most severe action taken
+	Text   string   // Text after any redactions
+	Rules  []string // Rules that matched, in pipeline order
+}
+
+// Pipeline runs moderators in order
+// A block stops the pipeline; redactions are passed on to later moderators
+type Pipeline struct {
+	moderators []Moderator
+}
+
+// NewPipeline creates a pipeline from the given moderators
+func NewPipeline(moderators ...Moderator) *Pipeline {
+	return &Pipeline{moderators: moderators}
+}
+
+// Len returns the number of moderators in the pipeline
+func (p *Pipeline) Len() int {
+	return len(p.moderators)
+}
+
+// Check runs text through the pipeline
+// On a moderator error the result so far is returned along with the error
+func (p *Pipeline) Check(ctx context.Context, stage Stage, text string) (Result, error) {
+	result := Result{Action: ActionAllow, Text: text}
+
+	for _, m := range p.moderators {
+		verdict, err := m.Moderate(ctx, stage, result.Text)
+		if err != nil {
+			return result, fmt.Errorf("moderator %s: %w", m.Name(), err)
+		}
+		if verdict.Action == ActionAllow {
+			continue
+		}
+
+		result.Rules = append(result.Rules, verdict.Rule)
+		if verdict.Action > result.Action {
+			result.Action = verdict.Action
+		}
+
+		switch verdict.Action {
+		case ActionBlock:
+			return result, nil
+		case ActionRedact:
+			result.Text = verdict.Text
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,131 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseAction(t *testing.T) {
+	for _, name := range []string{"allow", "flag", "redact", "block"} {
+		action, err := ParseAction(name)
+		if err != nil {
+			t.Fatalf("Unexpected error for %q: %v", name, err)
+		}
+		if action.String() != name {
+			t.Errorf("Expected %q to round-trip, got %q", name, action.String())
+		}
+	}
+	if _, err := ParseAction("delete"); err == nil {
+		t.Error("Expected error for unknown action")
+	}
+}
+
+func TestPipelineCheck(t *testing.T) {
+	ssn, _ := NewRegexModerator("ssn", `\b\d{3}-\d{2}-\d{4}\b`, ActionRedact)
+	password, _ := NewRegexModerator("password", `(?i)password`, ActionFlag)
+	banned, _ := NewWordListModerator("words", []string{"forbidden", "banned phrase"}, ActionBlock)
+	pipeline := NewPipeline(ssn, password, banned)
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		text     string
+		action   Action
+		expected string
+		rules    int
+	}{
+		{"clean", "hello there", ActionAllow, "hello there", 0},
+		{"flagged", "my Password is safe", ActionFlag, "my Password is safe", 1},
+		{"redacted", "ssn 123-45-6789 ok", ActionRedact, "ssn [redacted] ok", 1},
+		{"redact then flag", "password 123-45-6789", ActionRedact, "password [redacted]", 2},
+		{"blocked", "this is FORBIDDEN", ActionBlock, "this is FORBIDDEN", 1},
+		{"word boundaries", "unforbiddenly", ActionAllow, "unforbiddenly", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := pipeline.Check(ctx, StageInput, tt.text)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result.Action != tt.action {
+				t.Errorf("Expected action %v, got %v", tt.action, result.Action)
+			}
+			if result.Text != tt.expected {
+				t.Errorf("Expected text %q, got %q", tt.expected, result.Text)
+			}
+			if len(result.Rules) != tt.rules {
+				t.Errorf("Expected %d matched rules, got %v", tt.rules, result.Rules)
+			}
+		})
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	rules := `
+# comment lines are skipped
+block (?i)bad thing
+redact \d{16}
+`
+	moderators, err := ParseRules("rules.txt", strings.NewReader(rules))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(moderators) != 2 {
+		t.Fatalf("Expected 2 rules, got %d", len(moderators))
+	}
+	if moderators[0].Name() != "rules.txt:3" {
+		t.Errorf("Expected rule named by line, got %q", moderators[0].Name())
+	}
+
+	for _, bad := range []string{"block", "erase foo", "block ("} {
+		if _, err := ParseRules("rules.txt", strings.NewReader(bad)); err == nil {
+			t.Errorf("Expected error for rule %q", bad)
+		}
+	}
+}
+
+func TestHTTPModerator(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req httpModerationRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp := httpModerationResponse{Action: "allow"}
+		if req.Stage == StageOutput && strings.Contains(req.Text, "toxic") {
+			resp = httpModerationResponse{Action: "block", Rule: "toxicity"}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	pipeline := NewPipeline(NewHTTPModerator(server.URL))
+	ctx := context.Background()
+
+	result, err := pipeline.Check(ctx, StageInput, "toxic input")
+	if err != nil || result.Action != ActionAllow {
+		t.Errorf("Expected input to be allowed, got %v (err %v)", result.Action, err)
+	}
+
+	result, err = pipeline.Check(ctx, StageOutput, "toxic output")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Action != ActionBlock || result.Rules[0] != "toxicity" {
+		t.Errorf("Expected output blocked by toxicity rule, got %+v", result)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	if _, err := NewPipeline(NewHTTPModerator(failing.URL)).Check(ctx, StageInput, "hi"); err == nil {
+		t.Error("Expected error when moderation API fails")
+	}
+}
@@ -0,0 +1,94 @@
+package moderation
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// RegexModerator applies a single regular expression with a fixed action
+type RegexModerator struct {
+	name    string
+	pattern *regexp.Regexp
+	action  Action
+}
+
+// NewRegexModerator compiles pattern into a moderator that applies action on match
+func NewRegexModerator(name string, pattern string, action Action) (*RegexModerator, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid moderation pattern %q: %w", pattern, err)
+	}
+	return &RegexModerator{name: name, pattern: re, action: action}, nil
+}
+
+// NewWordListModerator builds a case-insensitive whole-word moderator from a word list
+func NewWordListModerator(name string, words []string, action Action) (*RegexModerator, error) {
+	var quoted []string
+	for _, word := range words {
+		if word = strings.TrimSpace(word); word != "" {
+			quoted = append(quoted, regexp.QuoteMeta(word))
+		}
+	}
+	if len(quoted) == 0 {
+		return nil, fmt.Errorf("word list %s is empty", name)
+	}
+	return NewRegexModerator(name, `(?i)\b(?:`+strings.Join(quoted, "|")+`)\b`, action)
+}
+
+func (m *RegexModerator) Name() string {
+	return m.name
+}
+
+func (m *RegexModerator) Moderate(ctx context.Context, stage Stage, text string) (Verdict, error) {
+	if !m.pattern.MatchString(text) {
+		return Verdict{Action: ActionAllow}, nil
+	}
+
+	verdict := Verdict{Action: m.action, Rule: m.name}
+	if m.action == ActionRedact {
+		verdict.Text = m.pattern.ReplaceAllString(text, RedactedText)
+	}
+	return verdict, nil
+}
+
+// ParseRules reads regex rules, one per line, in the form "<action> <pattern>"
+// Blank lines and lines starting with # are ignored
+// Rules are named "<source>:<line>" so matches can be traced back to the file
+func ParseRules(source string, r io.Reader) ([]Moderator, error) {
+	var moderators []Moderator
+
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		actionStr, pattern, found := strings.Cut(line, " ")
+		pattern = strings.TrimSpace(pattern)
+		if !found || pattern == "" {
+			return nil, fmt.Errorf("%s:%d: expected \"<action> <pattern>\"", source, lineNum)
+		}
+		action, err := ParseAction(actionStr)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", source, lineNum, err)
+		}
+
+		m, err := NewRegexModerator(fmt.Sprintf("%s:%d", source, lineNum), pattern, action)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", source, lineNum, err)
+		}
+		moderators = append(moderators, m)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read moderation rules: %w", err)
+	}
+
+	return moderators, nil
+}
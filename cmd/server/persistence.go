@@ -0,0 +1,541 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WAL operations, one per kind of store mutation
+const (
+	walRegister  = "register"
+	walClientKey = "client_key"
+	walAppend    = "append"
+	walDrop      = "drop"
+	walSummarize = "summarize"
+	walDelete    = "delete"
+	walModel     = "model"
+	walTitle     = "title"
+	walTokens    = "tokens"
+	walRemove    = "remove"
+	walRestore   = "restore"
+)
+
+const (
+	snapshotFileName = "snapshot.json"
+	walFilePrefix    = "wal-"
+	walFileSuffix    = ".log"
+)
+
+// walRecord is a single store mutation appended to the write-ahead log
+// Seq orders records per session so replay can skip mutations already in the snapshot
+type walRecord struct {
+	Seq         uint64        `json:"seq"`
+	Op          string        `json:"op"`
+	SessionID   string        `json:"session_id"`
+	OwnerID     string        `json:"owner_id,omitempty"`
+	LegacyOwner string        `json:"owner,omitempty"` // Raw API key written by older servers, hashed on replay
+	IdleTimeout time.Duration `json:"idle_timeout,omitempty"`
+	ClientKey   string        `json:"client_key,omitempty"`
+	Message     *Message      `json:"message,omitempty"`
+	Index       int           `json:"index,omitempty"`
+	Count       int           `json:"count,omitempty"`
+	Model       string        `json:"model,omitempty"`
+	Title       string        `json:"title,omitempty"`
+	SealedTitle []byte        `json:"sealed_title,omitempty"`
+	Tokens      int64         `json:"tokens,omitempty"`  // Session's token total after the mutation
+	Session     *Session      `json:"session,omitempty"` // Whole session put back from an archive
+}
+
+// storeSnapshot is the full store state written periodically to snapshot.json
+type storeSnapshot struct {
+	CreatedAt            time.Time         `json:"created_at"`
+	WALGeneration        int               `json:"wal_generation"` // First WAL generation not contained in the snapshot
+	TotalSessionsCreated int64             `json:"total_sessions_created"`
+	Sessions             []snapshotSession `json:"sessions"`
+}
+
+// snapshotSession is one registered session, with its messages if it has any
+type snapshotSession struct {
+	ID          string        `json:"id"`
+	OwnerID     string        `json:"owner_id"`
+	LegacyOwner string        `json:"owner,omitempty"` // Raw API key written by older servers, hashed when restored
+	IdleTimeout time.Duration `json:"idle_timeout,omitempty"`
+	ClientKey   string        `json:"client_key,omitempty"`
+	Seq         uint64        `json:"seq"`
+	Session     *Session      `json:"session,omitempty"`
+}
+
+// sessionPersistence appends store mutations to a write-ahead log in dir and rotates it on each snapshot
+// Records are written without fsync, so they survive a process crash but not necessarily a power loss
+type sessionPersistence struct {
+	dir    string
+	logger *slog.Logger
+	seq    atomic.Uint64 // Last assigned record sequence number
+
+	mu  sync.Mutex // Guards the current WAL file
+	wal *os.File
+	gen int // Generation of the current WAL file
+
+	snapshotMu sync.Mutex // Serializes snapshots
+}
+
+// EnablePersistence recovers sessions from dir and logs every later mutation there
+// It must be called before the store is used, after EnableEncryption so the same key seals the log
+func (s *SessionStore) EnablePersistence(dir string, logger *slog.Logger) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create session data directory: %w", err)
+	}
+
+	p := &sessionPersistence{dir: dir, logger: logger}
+	restored, err := s.recover(p)
+	if err != nil {
+		return err
+	}
+	s.persist = p
+
+	// Compact the recovered log into a fresh snapshot, which also opens the next WAL
+	if err := s.Snapshot(); err != nil {
+		return err
+	}
+	s.evictExcessSessions()
+
+	logger.Info("session persistence enabled", "dir", dir, "restored_sessions", restored)
+	return nil
+}
+
+// recover loads the snapshot and replays the WAL files written after it in generation order
+// Returns the number of sessions with messages after recovery
+func (s *SessionStore) recover(p *sessionPersistence) (int, error) {
+	firstGen := 0
+	data, err := os.ReadFile(filepath.Join(p.dir, snapshotFileName))
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return 0, fmt.Errorf("failed to read session snapshot: %w", err)
+	default:
+		var snapshot storeSnapshot
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			return 0, fmt.Errorf("failed to parse session snapshot: %w", err)
+		}
+		s.restoreSnapshot(snapshot, p)
+		firstGen = snapshot.WALGeneration
+		p.gen = firstGen
+	}
+
+	gens, err := p.walGenerations()
+	if err != nil {
+		return 0, err
+	}
+	for _, gen := range gens {
+		if gen < firstGen {
+			continue // Left behind by a crash right after the snapshot was written
+		}
+		if err := s.replayWAL(p, gen); err != nil {
+			return 0, err
+		}
+		p.gen = gen
+	}
+
+	return s.GetSessionCount(), nil
+}
+
+// restoreSnapshot loads snapshot state into an empty store
+func (s *SessionStore) restoreSnapshot(snapshot storeSnapshot, p *sessionPersistence) {
+	s.mu.Lock()
+	s.totalSessionsCreated = snapshot.TotalSessionsCreated
+	s.mu.Unlock()
+
+	// Restore least recently used first so the LRU order matches activity
+	// Registrations without messages have no LRU position and sort first
+	sort.SliceStable(snapshot.Sessions, func(i, j int) bool {
+		a, b := snapshot.Sessions[i].Session, snapshot.Sessions[j].Session
+		return b != nil && (a == nil || a.LastActive.Before(b.LastActive))
+	})
+
+	for _, saved := range snapshot.Sessions {
+		shard := s.shardFor(saved.ID)
+		s.restoreRegistration(shard, saved)
+		shard.seqs[saved.ID] = saved.Seq
+		p.observeSeq(saved.Seq)
+
+		if saved.Session != nil {
+			s.restoreSession(shard, saved.ID, saved.Session)
+		}
+	}
+}
+
+// restoreRegistration registers a saved session under its owner with its idle timeout and client key
+// Caller must hold the shard's write lock or be recovering before the store is shared
+func (s *SessionStore) restoreRegistration(shard *sessionShard, saved snapshotSession) {
+	owner := s.savedOwnerID(saved.OwnerID, saved.LegacyOwner)
+	shard.validSessions[saved.ID] = owner
+	if saved.IdleTimeout > 0 {
+		shard.idleTimeouts[saved.ID] = saved.IdleTimeout
+	}
+	if saved.ClientKey != "" {
+		shard.clientKeys[saved.ID] = saved.ClientKey
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ownerSessions[owner] == nil {
+		s.ownerSessions[owner] = make(map[string]bool)
+	}
+	s.ownerSessions[owner][saved.ID] = true
+}
+
+// restoreSession adds a recovered session with its cached size and LRU position
+// Caller must hold the shard's write lock or be recovering before the store is shared
+func (s *SessionStore) restoreSession(shard *sessionShard, sessionID string, session *Session) {
+	session.sizeBytes = computeSessionSize(session)
+	shard.sessions[sessionID] = session
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessionCount++
+	s.totalBytes += session.sizeBytes
+	s.updateSessionOrder(sessionID)
+}
+
+// replayWAL applies one WAL file; a torn final record from a crash mid-write is ignored
+func (s *SessionStore) replayWAL(p *sessionPersistence, gen int) error {
+	file, err := os.Open(p.walPath(gen))
+	if err != nil {
+		return fmt.Errorf("failed to open session WAL: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		var rec walRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			p.logger.Warn("skipping unreadable session WAL record", "file", p.walPath(gen), "line", line, "error", err)
+			continue
+		}
+		p.observeSeq(rec.Seq)
+		s.applyRecord(rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read session WAL: %w", err)
+	}
+	return nil
+}
+
+// applyRecord replays a mutation unless the session already reflects it
+func (s *SessionStore) applyRecord(rec walRecord) {
+	shard := s.shardFor(rec.SessionID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if rec.Op == walRegister {
+		// Registrations logged after the snapshot's rotation aren't in its total, even for the
+		// sessions it holds, whose records are otherwise skipped
+		s.mu.Lock()
+		s.totalSessionsCreated++
+		s.mu.Unlock()
+	}
+	if rec.Seq <= shard.seqs[rec.SessionID] {
+		return
+	}
+
+	_, registered := shard.validSessions[rec.SessionID]
+	session := shard.sessions[rec.SessionID]
+
+	switch rec.Op {
+	case walRegister:
+		owner := s.savedOwnerID(rec.OwnerID, rec.LegacyOwner)
+		shard.validSessions[rec.SessionID] = owner
+		if rec.IdleTimeout > 0 {
+			shard.idleTimeouts[rec.SessionID] = rec.IdleTimeout
+		}
+		s.mu.Lock()
+		if s.ownerSessions[owner] == nil {
+			s.ownerSessions[owner] = make(map[string]bool)
+		}
+		s.ownerSessions[owner][rec.SessionID] = true
+		s.mu.Unlock()
+	case walClientKey:
+		if registered {
+			shard.clientKeys[rec.SessionID] = rec.ClientKey
+		}
+	case walAppend:
+		if !registered || rec.Message == nil {
+			return
+		}
+		if session == nil {
+			session = &Session{CreatedAt: rec.Message.Timestamp}
+			s.restoreSession(shard, rec.SessionID, session)
+		}
+		session.Messages = append(session.Messages, *rec.Message)
+		session.LastActive = rec.Message.Timestamp
+		s.resizeSession(session, computeSessionSize(session)-session.sizeBytes)
+		s.mu.Lock()
+		s.updateSessionOrder(rec.SessionID)
+		s.mu.Unlock()
+	case walDrop, walSummarize:
+		if session == nil || rec.Count > len(session.Messages) {
+			return
+		}
+		kept := session.Messages[rec.Count:]
+		if rec.Op == walSummarize && rec.Message != nil {
+			kept = append([]Message{*rec.Message}, kept...)
+		}
+		session.Messages = slices.Clone(kept)
+		s.resizeSession(session, computeSessionSize(session)-session.sizeBytes)
+	case walDelete:
+		if session == nil || rec.Index < 0 || rec.Index+rec.Count > len(session.Messages) {
+			return
+		}
+		session.Messages = slices.Delete(session.Messages, rec.Index, rec.Index+rec.Count)
+		s.resizeSession(session, computeSessionSize(session)-session.sizeBytes)
+	case walModel:
+		if session != nil {
+			s.resizeSession(session, len(rec.Model)-len(session.Model))
+			session.Model = rec.Model
+		}
+	case walTitle:
+		if session != nil {
+			s.resizeSession(session, len(rec.Title)+cap(rec.SealedTitle)-len(session.Title)-cap(session.SealedTitle))
+			session.Title = rec.Title
+			session.SealedTitle = rec.SealedTitle
+		}
+	case walTokens:
+		if session != nil {
+			session.TokensUsed = rec.Tokens
+		}
+	case walRemove:
+		s.removeSession(shard, rec.SessionID)
+		return // removeSession forgets the session's sequence number
+	case walRestore:
+		if registered || rec.Session == nil {
+			return
+		}
+		s.restoreRegistration(shard, snapshotSession{ID: rec.SessionID, OwnerID: rec.OwnerID, LegacyOwner: rec.LegacyOwner, IdleTimeout: rec.IdleTimeout, ClientKey: rec.ClientKey})
+		s.restoreSession(shard, rec.SessionID, rec.Session)
+	}
+
+	shard.seqs[rec.SessionID] = rec.Seq
+}
+
+// persistRecord assigns the next sequence number to a mutation and appends it to the WAL
+// Caller must hold the shard's write lock, which keeps each session's records in order
+func (s *SessionStore) persistRecord(shard *sessionShard, rec walRecord) {
+	if s.persist == nil {
+		return
+	}
+	rec.Seq = s.persist.seq.Add(1)
+	if rec.Op != walRemove {
+		shard.seqs[rec.SessionID] = rec.Seq
+	}
+	if err := s.persist.append(rec); err != nil {
+		s.persist.logger.Error("failed to write session WAL record", "op", rec.Op, "error", err)
+	}
+}
+
+// append writes a record to the current WAL file
+func (p *sessionPersistence) append(rec walRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.wal == nil {
+		return fmt.Errorf("session WAL is closed")
+	}
+	_, err = p.wal.Write(data)
+	return err
+}
+
+// observeSeq keeps the sequence counter ahead of every recovered record
+func (p *sessionPersistence) observeSeq(seq uint64) {
+	for {
+		current := p.seq.Load()
+		if seq <= current || p.seq.CompareAndSwap(current, seq) {
+			return
+		}
+	}
+}
+
+// rotate closes the current WAL and starts the next generation
+// Every record in earlier generations is already applied to the in-memory store
+func (p *sessionPersistence) rotate() (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.wal != nil {
+		if err := p.wal.Sync(); err != nil {
+			return 0, fmt.Errorf("failed to sync session WAL: %w", err)
+		}
+		p.wal.Close()
+		p.wal = nil
+	}
+
+	file, err := os.OpenFile(p.walPath(p.gen+1), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open session WAL: %w", err)
+	}
+	p.gen++
+	p.wal = file
+	return p.gen, nil
+}
+
+// walPath returns the file name of a WAL generation
+func (p *sessionPersistence) walPath(gen int) string {
+	return filepath.Join(p.dir, fmt.Sprintf("%s%08d%s", walFilePrefix, gen, walFileSuffix))
+}
+
+// walGenerations lists the WAL generations present in the data directory, oldest first
+func (p *sessionPersistence) walGenerations() ([]int, error) {
+	entries, err := os.ReadDir(p.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list session data directory: %w", err)
+	}
+
+	var gens []int
+	for _, entry := range entries {
+		name, ok := strings.CutPrefix(entry.Name(), walFilePrefix)
+		if !ok {
+			continue
+		}
+		if gen, err := strconv.Atoi(strings.TrimSuffix(name, walFileSuffix)); err == nil {
+			gens = append(gens, gen)
+		}
+	}
+	sort.Ints(gens)
+	return gens, nil
+}
+
+// Snapshot writes the full store state to disk and deletes the WAL files it supersedes
+// Mutations made while the snapshot is written go to the new WAL generation; replay
+// skips any of them the snapshot already contains
+func (s *SessionStore) Snapshot() error {
+	p := s.persist
+	if p == nil {
+		return nil
+	}
+	p.snapshotMu.Lock()
+	defer p.snapshotMu.Unlock()
+
+	// The total is read as the WAL rotates, so each registration is counted either in the snapshot
+	// or when the new generation is replayed, never both
+	s.mu.Lock()
+	total := s.totalSessionsCreated
+	gen, err := p.rotate()
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	snapshot := storeSnapshot{CreatedAt: time.Now().UTC(), WALGeneration: gen, TotalSessionsCreated: total}
+	for _, shard := range s.shards {
+		shard.mu.RLock()
+		for sessionID := range shard.validSessions {
+			snapshot.Sessions = append(snapshot.Sessions, shard.snapshotOf(sessionID))
+		}
+		shard.mu.RUnlock()
+	}
+
+	if err := writeFileAtomic(filepath.Join(p.dir, snapshotFileName), snapshot); err != nil {
+		return err
+	}
+
+	// Earlier generations are fully contained in the snapshot
+	gens, err := p.walGenerations()
+	if err != nil {
+		return err
+	}
+	for _, old := range gens {
+		if old < gen {
+			if err := os.Remove(p.walPath(old)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				p.logger.Warn("failed to remove old session WAL", "file", p.walPath(old), "error", err)
+			}
+		}
+	}
+	return nil
+}
+
+// snapshotOf copies a registered session's state, including its messages if it has any
+// Caller must hold the shard's lock
+func (shard *sessionShard) snapshotOf(sessionID string) snapshotSession {
+	saved := snapshotSession{
+		ID:          sessionID,
+		OwnerID:     shard.validSessions[sessionID],
+		IdleTimeout: shard.idleTimeouts[sessionID],
+		ClientKey:   shard.clientKeys[sessionID],
+		Seq:         shard.seqs[sessionID],
+	}
+	if session, exists := shard.sessions[sessionID]; exists {
+		copied := *session
+		copied.Messages = slices.Clone(session.Messages)
+		saved.Session = &copied
+	}
+	return saved
+}
+
+// ClosePersistence writes a final snapshot and closes the WAL
+func (s *SessionStore) ClosePersistence() error {
+	p := s.persist
+	if p == nil {
+		return nil
+	}
+	if err := s.Snapshot(); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.wal == nil {
+		return nil
+	}
+	err := p.wal.Close()
+	p.wal = nil
+	return err
+}
+
+// writeFileAtomic writes JSON to a temporary file, syncs it and renames it into place
+func writeFileAtomic(path string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode session snapshot: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create session snapshot: %w", err)
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write session snapshot: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to sync session snapshot: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close session snapshot: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to replace session snapshot: %w", err)
+	}
+	return nil
+}
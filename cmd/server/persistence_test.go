@@ -0,0 +1,299 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// openPersistentStore creates a store that recovers from and logs to dir
+func openPersistentStore(t *testing.T, dir string) *SessionStore {
+	t.Helper()
+	store := NewSessionStore(2*time.Hour, 1000, 100, 100*1024)
+	store.SetCompactionPolicy(CompactionDropOldest)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	if err := store.EnablePersistence(dir, logger); err != nil {
+		t.Fatalf("Failed to enable persistence: %v", err)
+	}
+	return store
+}
+
+func TestSessionStore_RecoversFromWAL(t *testing.T) {
+	dir := t.TempDir()
+	store := openPersistentStore(t, dir)
+
+	store.RegisterSessionWithTimeout("kept", "key-a", time.Hour)
+	store.AppendMessage("kept", User, "hello")
+	store.AppendMessage("kept", Assistant, "hi there")
+	store.SetSessionModel("kept", "gemini")
+	store.AddSessionTokens("kept", 120)
+	store.SetSessionTitle("kept", "Greetings")
+	store.AppendMessage("kept", User, "deleted later")
+	store.DeleteMessages("kept", 2, 1)
+
+	store.RegisterSession("removed", "key-b")
+	store.AppendMessage("removed", User, "bye")
+	shard := store.shardFor("removed")
+	shard.mu.Lock()
+	store.removeSession(shard, "removed")
+	shard.mu.Unlock()
+
+	// Simulate a crash: the WAL is never snapshotted or closed
+	recovered := openPersistentStore(t, dir)
+
+	session, exists := recovered.GetSession("kept")
+	if !exists {
+		t.Fatal("Expected session to be recovered")
+	}
+	if len(session.Messages) != 2 || session.Messages[0].Text != "hello" || session.Messages[1].Text != "hi there" {
+		t.Errorf("Expected messages to be recovered, got %v", recovered.GetFormattedMessages("kept"))
+	}
+	if session.Model != "gemini" || session.Title != "Greetings" {
+		t.Errorf("Expected model and title to be recovered, got %q and %q", session.Model, session.Title)
+	}
+	if session.TokensUsed != 120 {
+		t.Errorf("Expected token usage to be recovered, got %d", session.TokensUsed)
+	}
+	if !recovered.IsSessionOwner("kept", "key-a") {
+		t.Error("Expected session owner to be recovered")
+	}
+	if timeout := recovered.GetIdleTimeout("kept"); timeout != time.Hour {
+		t.Errorf("Expected idle timeout to be recovered, got %v", timeout)
+	}
+	if recovered.IsValidSession("removed") {
+		t.Error("Expected removed session to stay removed")
+	}
+	if count := recovered.GetSessionCount(); count != 1 {
+		t.Errorf("Expected 1 session, got %d", count)
+	}
+	if total, _ := recovered.GetMemoryUsage(); total != storedSession(recovered, "kept").sizeBytes {
+		t.Errorf("Expected memory usage %d to match the recovered session", total)
+	}
+}
+
+func TestSessionStore_RecoversFromSnapshotAndWAL(t *testing.T) {
+	dir := t.TempDir()
+	store := openPersistentStore(t, dir)
+
+	store.RegisterSession("session", "key")
+	for _, text := range []string{"one", "two", "three"} {
+		store.AppendMessage("session", User, text)
+	}
+	if err := store.Snapshot(); err != nil {
+		t.Fatalf("Failed to snapshot: %v", err)
+	}
+	store.AppendMessage("session", User, "four")
+	if err := store.ReplaceWithSummary("session", 2, storedSession(store, "session").Messages[1].Timestamp, "counting", nil); err != nil {
+		t.Fatalf("Failed to summarize: %v", err)
+	}
+
+	recovered := openPersistentStore(t, dir)
+
+	messages := recovered.GetMessages("session")
+	if len(messages) != 3 || messages[0].Role != System || messages[1].Text != "three" || messages[2].Text != "four" {
+		t.Errorf("Expected summary and later messages, got %v", recovered.GetFormattedMessages("session"))
+	}
+	if created := recovered.GetTotalSessionsCreated(); created != 1 {
+		t.Errorf("Expected 1 session created, got %d", created)
+	}
+
+	// Recovery compacts everything into a fresh snapshot and a single WAL
+	logs, _ := filepath.Glob(filepath.Join(dir, walFilePrefix+"*"))
+	if len(logs) != 1 {
+		t.Errorf("Expected old WAL files to be removed, got %v", logs)
+	}
+}
+
+func TestSessionStore_RecoveryIgnoresTornRecord(t *testing.T) {
+	dir := t.TempDir()
+	store := openPersistentStore(t, dir)
+	store.RegisterSession("session", "key")
+	store.AppendMessage("session", User, "complete")
+
+	// A crash mid-write leaves a partial last line
+	file, err := os.OpenFile(store.persist.walPath(store.persist.gen), os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		t.Fatalf("Failed to open WAL: %v", err)
+	}
+	file.WriteString(`{"seq":99,"op":"append","session_id":"sess`)
+	file.Close()
+
+	recovered := openPersistentStore(t, dir)
+	if messages := recovered.GetMessages("session"); len(messages) != 1 || messages[0].Text != "complete" {
+		t.Errorf("Expected the complete message only, got %v", recovered.GetFormattedMessages("session"))
+	}
+}
+
+func TestSessionStore_PersistsSealedMessages(t *testing.T) {
+	dir := t.TempDir()
+	key := make([]byte, encryptionKeySize)
+
+	store := NewSessionStore(2*time.Hour, 1000, 100, 100*1024)
+	store.EnableEncryption(key)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	if err := store.EnablePersistence(dir, logger); err != nil {
+		t.Fatalf("Failed to enable persistence: %v", err)
+	}
+	store.RegisterSession("session", "key")
+	store.AppendMessage("session", User, "top secret")
+	if err := store.ClosePersistence(); err != nil {
+		t.Fatalf("Failed to close persistence: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, snapshotFileName))
+	if err != nil {
+		t.Fatalf("Failed to read snapshot: %v", err)
+	}
+	if strings.Contains(string(data), "top secret") {
+		t.Error("Expected the snapshot to hold ciphertext only")
+	}
+
+	recovered := NewSessionStore(2*time.Hour, 1000, 100, 100*1024)
+	recovered.EnableEncryption(key)
+	if err := recovered.EnablePersistence(dir, logger); err != nil {
+		t.Fatalf("Failed to enable persistence: %v", err)
+	}
+	if messages := recovered.GetMessages("session"); len(messages) != 1 || messages[0].Text != "top secret" {
+		t.Errorf("Expected the message to decrypt after recovery, got %v", messages)
+	}
+}
+
+func TestSessionStore_PersistsOwnerIDOnly(t *testing.T) {
+	dir := t.TempDir()
+	store := openPersistentStore(t, dir)
+	store.RegisterSession("session", "secret-api-key")
+	store.AppendMessage("session", User, "hello")
+
+	var wal []byte
+	walFiles, _ := filepath.Glob(filepath.Join(dir, walFilePrefix+"*"+walFileSuffix))
+	for _, path := range walFiles {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read WAL: %v", err)
+		}
+		wal = append(wal, data...)
+	}
+	if !strings.Contains(string(wal), "hello") {
+		t.Fatal("Expected the WAL to hold the session")
+	}
+	if err := store.ClosePersistence(); err != nil {
+		t.Fatalf("Failed to close persistence: %v", err)
+	}
+	snapshot, err := os.ReadFile(filepath.Join(dir, snapshotFileName))
+	if err != nil {
+		t.Fatalf("Failed to read snapshot: %v", err)
+	}
+	for name, data := range map[string][]byte{"WAL": wal, "snapshot": snapshot} {
+		if strings.Contains(string(data), "secret-api-key") {
+			t.Errorf("Expected the %s not to contain the API key", name)
+		}
+	}
+
+	recovered := openPersistentStore(t, dir)
+	if !recovered.IsSessionOwner("session", "secret-api-key") || recovered.IsSessionOwner("session", "other-key") {
+		t.Error("Expected ownership to be checked against the recovered owner ID")
+	}
+	if infos := recovered.GetSessionsInfoForOwner("secret-api-key"); len(infos) != 1 {
+		t.Errorf("Expected the owner's session to be listed, got %v", infos)
+	}
+}
+
+func TestSessionStore_OwnerIDKeyedByEncryptionKey(t *testing.T) {
+	plain := NewSessionStore(2*time.Hour, 1000, 100, 100*1024)
+	keyed := NewSessionStore(2*time.Hour, 1000, 100, 100*1024)
+	keyed.EnableEncryption(make([]byte, encryptionKeySize))
+
+	a, b := plain.ownerID("key"), keyed.ownerID("key")
+	if a == b {
+		t.Error("Expected the encryption key to change the owner ID")
+	}
+	if ownerKeyHash(a) != hashAPIKey("key") || ownerKeyHash(b) != hashAPIKey("key") {
+		t.Errorf("Expected owner IDs to start with the key hash, got %q and %q", a, b)
+	}
+}
+
+func TestSessionStore_SnapshotCountsEachSessionOnce(t *testing.T) {
+	dir := t.TempDir()
+	store := openPersistentStore(t, dir)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				id := fmt.Sprintf("session-%d-%d", w, i)
+				store.RegisterSession(id, "key")
+				if i%3 == 0 {
+					shard := store.shardFor(id)
+					shard.mu.Lock()
+					store.removeSession(shard, id)
+					shard.mu.Unlock()
+				}
+			}
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	for snapshotting := true; snapshotting; {
+		if err := store.Snapshot(); err != nil {
+			t.Fatalf("Failed to snapshot: %v", err)
+		}
+		select {
+		case <-done:
+			snapshotting = false
+		default:
+		}
+	}
+
+	// Simulate a crash after the last snapshot
+	recovered := openPersistentStore(t, dir)
+	if total := recovered.GetTotalSessionsCreated(); total != 800 {
+		t.Errorf("Expected 800 sessions created, got %d", total)
+	}
+}
+
+func TestSessionStore_RestoresLRUOrderAroundRegistrations(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	session := func(age time.Duration) *Session {
+		return &Session{Messages: []Message{{Role: User, Text: "hi", Timestamp: now.Add(-age)}}, LastActive: now.Add(-age)}
+	}
+
+	// Registrations without messages are interleaved with sessions out of activity order
+	snapshot := storeSnapshot{CreatedAt: now, Sessions: []snapshotSession{
+		{ID: "newest", OwnerID: "owner", Seq: 1, Session: session(time.Minute)},
+		{ID: "empty-1", OwnerID: "owner", Seq: 2},
+		{ID: "oldest", OwnerID: "owner", Seq: 3, Session: session(3 * time.Minute)},
+		{ID: "empty-2", OwnerID: "owner", Seq: 4},
+		{ID: "middle", OwnerID: "owner", Seq: 5, Session: session(2 * time.Minute)},
+	}}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		t.Fatalf("Failed to encode snapshot: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, snapshotFileName), data, 0o600); err != nil {
+		t.Fatalf("Failed to write snapshot: %v", err)
+	}
+
+	recovered := openPersistentStore(t, dir)
+	var order []string
+	for elem := recovered.sessionOrder.Front(); elem != nil; elem = elem.Next() {
+		order = append(order, elem.Value.(string))
+	}
+	if strings.Join(order, ",") != "oldest,middle,newest" {
+		t.Errorf("Expected least recently used first, got %v", order)
+	}
+	if !recovered.IsValidSession("empty-1") || !recovered.IsValidSession("empty-2") {
+		t.Error("Expected registrations without messages to be restored")
+	}
+}
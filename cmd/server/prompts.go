@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"microchat.ai/cmd/server/llm"
+	"microchat.ai/cmd/server/prompts"
+	pb "microchat.ai/proto"
+)
+
+// loadPromptTemplates reads the operator's prompt templates from a JSON file
+func loadPromptTemplates(path string) (*prompts.Set, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open prompt templates: %w", err)
+	}
+	defer f.Close()
+
+	return prompts.Parse(path, f)
+}
+
+// templateMessage renders the prompt template selected by a chat request as a system message
+// Returns nil when the request doesn't select a template
+func (app *application) templateMessage(req *pb.ChatRequest) (*llm.Message, error) {
+	if req.Template == "" {
+		if len(req.TemplateVars) > 0 {
+			return nil, status.Error(codes.InvalidArgument, "template_vars requires a template")
+		}
+		return nil, nil
+	}
+	if app.prompts == nil {
+		return nil, status.Error(codes.InvalidArgument, "no prompt templates are configured on this server")
+	}
+
+	system, err := app.prompts.Render(req.Template, req.TemplateVars)
+	if errors.Is(err, prompts.ErrUnknownTemplate) {
+		return nil, status.Errorf(codes.NotFound, "%v (available: %v)", err, app.prompts.Names())
+	}
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &llm.Message{Role: System.String(), Text: system}, nil
+}
+
+// maxSystemPromptSize caps a caller-written system prompt, which is sent to the LLM with every message
+const maxSystemPromptSize = 4 * 1024
+
+// systemPromptMessage returns the chat request's own system prompt as a system message
+// Returns nil when the request doesn't set one
+func systemPromptMessage(req *pb.ChatRequest) (*llm.Message, error) {
+	if strings.TrimSpace(req.SystemPrompt) == "" {
+		return nil, nil
+	}
+	if len(req.SystemPrompt) > maxSystemPromptSize {
+		return nil, status.Errorf(codes.InvalidArgument, "system prompt too large: %d bytes (max %d)", len(req.SystemPrompt), maxSystemPromptSize)
+	}
+	return &llm.Message{Role: System.String(), Text: req.SystemPrompt}, nil
+}
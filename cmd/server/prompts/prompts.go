@@ -0,0 +1,138 @@
+package prompts
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	maxVariableLength = 1024 // Maximum length of a single variable value
+	maxVariables      = 32   // Maximum number of variables in a request
+)
+
+// ErrUnknownTemplate is returned when a request names a template that isn't configured
+var ErrUnknownTemplate = errors.New("unknown prompt template")
+
+// variablePattern matches {{name}} placeholders
+var variablePattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// Template is a named system prompt with {{variable}} placeholders
+type Template struct {
+	Name        string            `json:"-"`
+	Description string            `json:"description,omitempty"`
+	System      string            `json:"system"`             // System prompt text
+	Defaults    map[string]string `json:"defaults,omitempty"` // Values for variables the request doesn't set
+	Variables   []string          `json:"-"`                  // Placeholders used in System, sorted
+}
+
+// Set is a collection of templates by name
+type Set struct {
+	templates map[string]Template
+}
+
+// Parse reads templates from a JSON object mapping names to templates, e.g.
+//
+//	{"reviewer": {"description": "Code review", "system": "You review {{language}} code.", "defaults": {"language": "Go"}}}
+func Parse(source string, r io.Reader) (*Set, error) {
+	var raw map[string]Template
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("%s: invalid prompt templates: %w", source, err)
+	}
+
+	set := &Set{templates: make(map[string]Template, len(raw))}
+	for name, tmpl := range raw {
+		if strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("%s: template name cannot be empty", source)
+		}
+		if strings.TrimSpace(tmpl.System) == "" {
+			return nil, fmt.Errorf("%s: template %q has no system prompt", source, name)
+		}
+
+		tmpl.Name = name
+		seen := make(map[string]bool)
+		for _, match := range variablePattern.FindAllStringSubmatch(tmpl.System, -1) {
+			if !seen[match[1]] {
+				seen[match[1]] = true
+				tmpl.Variables = append(tmpl.Variables, match[1])
+			}
+		}
+		sort.Strings(tmpl.Variables)
+		for variable := range tmpl.Defaults {
+			if !seen[variable] {
+				return nil, fmt.Errorf("%s: template %q has a default for unused variable %q", source, name, variable)
+			}
+		}
+		set.templates[name] = tmpl
+	}
+	return set, nil
+}
+
+// Names returns the configured template names, sorted
+func (s *Set) Names() []string {
+	names := make([]string, 0, len(s.templates))
+	for name := range s.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get returns a template by name
+func (s *Set) Get(name string) (Template, bool) {
+	tmpl, ok := s.templates[name]
+	return tmpl, ok
+}
+
+// Render fills a template's placeholders from vars, falling back to its defaults
+// Every placeholder must have a value, and vars may only set the template's variables
+func (s *Set) Render(name string, vars map[string]string) (string, error) {
+	tmpl, ok := s.templates[name]
+	if !ok {
+		return "", fmt.Errorf("%w %q", ErrUnknownTemplate, name)
+	}
+	if len(vars) > maxVariables {
+		return "", fmt.Errorf("too many template variables: %d (max %d)", len(vars), maxVariables)
+	}
+
+	values := make(map[string]string, len(tmpl.Variables))
+	for variable, value := range tmpl.Defaults {
+		values[variable] = value
+	}
+	for variable, value := range vars {
+		if !tmpl.uses(variable) {
+			return "", fmt.Errorf("template %q has no variable %q", name, variable)
+		}
+		if len(value) > maxVariableLength {
+			return "", fmt.Errorf("template variable %q too long: %d bytes (max %d)", variable, len(value), maxVariableLength)
+		}
+		values[variable] = value
+	}
+
+	var missing []string
+	for _, variable := range tmpl.Variables {
+		if _, ok := values[variable]; !ok {
+			missing = append(missing, variable)
+		}
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("template %q requires variables: %s", name, strings.Join(missing, ", "))
+	}
+
+	// Substitute in a single pass so values containing {{...}} aren't expanded again
+	return variablePattern.ReplaceAllStringFunc(tmpl.System, func(placeholder string) string {
+		return values[variablePattern.FindStringSubmatch(placeholder)[1]]
+	}), nil
+}
+
+// uses reports whether the template has a placeholder for variable
+func (t Template) uses(variable string) bool {
+	i := sort.SearchStrings(t.Variables, variable)
+	return i < len(t.Variables) && t.Variables[i] == variable
+}
@@ -0,0 +1,77 @@
+package prompts
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const testTemplates = `{
+	"reviewer": {
+		"description": "Code review persona",
+		"system": "You review {{ language }} code. Focus on {{focus}}. Be strict about {{focus}}.",
+		"defaults": {"language": "Go"}
+	},
+	"terse": {"system": "Answer in one sentence."}
+}`
+
+func TestParse(t *testing.T) {
+	set, err := Parse("test", strings.NewReader(testTemplates))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if names := set.Names(); len(names) != 2 || names[0] != "reviewer" || names[1] != "terse" {
+		t.Errorf("Expected sorted template names, got %v", names)
+	}
+	reviewer, _ := set.Get("reviewer")
+	if len(reviewer.Variables) != 2 || reviewer.Variables[0] != "focus" || reviewer.Variables[1] != "language" {
+		t.Errorf("Expected variables focus and language, got %v", reviewer.Variables)
+	}
+
+	invalid := []string{
+		`not json`,
+		`{"empty": {"system": " "}}`,
+		`{"typo": {"sytem": "x"}}`,
+		`{"unused": {"system": "Hi", "defaults": {"name": "x"}}}`,
+	}
+	for _, input := range invalid {
+		if _, err := Parse("test", strings.NewReader(input)); err == nil {
+			t.Errorf("Expected error for %s", input)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	set, err := Parse("test", strings.NewReader(testTemplates))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	got, err := set.Render("reviewer", map[string]string{"focus": "error handling"})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if want := "You review Go code. Focus on error handling. Be strict about error handling."; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	// Request values override defaults and are never expanded again
+	got, _ = set.Render("reviewer", map[string]string{"language": "Rust", "focus": "{{language}}"})
+	if want := "You review Rust code. Focus on {{language}}. Be strict about {{language}}."; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	if _, err := set.Render("missing", nil); !errors.Is(err, ErrUnknownTemplate) {
+		t.Errorf("Expected ErrUnknownTemplate, got %v", err)
+	}
+	if _, err := set.Render("reviewer", nil); err == nil || !strings.Contains(err.Error(), "focus") {
+		t.Errorf("Expected missing variable error naming focus, got %v", err)
+	}
+	if _, err := set.Render("terse", map[string]string{"extra": "x"}); err == nil {
+		t.Error("Expected error for variable the template doesn't use")
+	}
+	if _, err := set.Render("reviewer", map[string]string{"focus": strings.Repeat("x", maxVariableLength+1)}); err == nil {
+		t.Error("Expected error for oversized variable")
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"microchat.ai/cmd/server/prompts"
+	pb "microchat.ai/proto"
+)
+
+func TestTemplateMessage(t *testing.T) {
+	app, _ := setupTestApplicationWithMock(t)
+
+	if msg, err := app.templateMessage(&pb.ChatRequest{}); msg != nil || err != nil {
+		t.Errorf("Expected no template message, got %v (err %v)", msg, err)
+	}
+	if _, err := app.templateMessage(&pb.ChatRequest{Template: "pirate"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument without configured templates, got %v", err)
+	}
+
+	set, err := prompts.Parse("test", strings.NewReader(`{"pirate": {"system": "Talk like a pirate named {{name}}."}}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	app.prompts = set
+
+	msg, err := app.templateMessage(&pb.ChatRequest{Template: "pirate", TemplateVars: map[string]string{"name": "Anne"}})
+	if err != nil {
+		t.Fatalf("templateMessage failed: %v", err)
+	}
+	if msg.Role != "system" || msg.Text != "Talk like a pirate named Anne." {
+		t.Errorf("Expected rendered system message, got %+v", msg)
+	}
+
+	if _, err := app.templateMessage(&pb.ChatRequest{Template: "ninja"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound for unknown template, got %v", err)
+	}
+	if _, err := app.templateMessage(&pb.ChatRequest{Template: "pirate"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for missing variable, got %v", err)
+	}
+	if _, err := app.templateMessage(&pb.ChatRequest{TemplateVars: map[string]string{"name": "Anne"}}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for variables without a template, got %v", err)
+	}
+
+	// Chat rejects invalid templates before storing anything
+	startResp, err := app.StartSession(context.Background(), &pb.StartSessionRequest{})
+	if err != nil {
+		t.Fatalf("Failed to start session: %v", err)
+	}
+	if _, err := app.Chat(context.Background(), &pb.ChatRequest{SessionId: startResp.SessionId, Message: "Ahoy", Template: "ninja"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound from Chat, got %v", err)
+	}
+	if _, err := app.Chat(context.Background(), &pb.ChatRequest{SessionId: startResp.SessionId, Message: "Ahoy", Template: "pirate", TemplateVars: map[string]string{"name": "Anne"}}); err != nil {
+		t.Errorf("Chat with template failed: %v", err)
+	}
+	if count := app.sessionStore.GetMessageCount(startResp.SessionId); count != 2 {
+		t.Errorf("Expected the system prompt not to be stored, got %d messages", count)
+	}
+}
+
+func TestSystemPromptMessage(t *testing.T) {
+	if msg, err := systemPromptMessage(&pb.ChatRequest{SystemPrompt: "  "}); msg != nil || err != nil {
+		t.Errorf("Expected no system message for a blank prompt, got %v (err %v)", msg, err)
+	}
+	msg, err := systemPromptMessage(&pb.ChatRequest{SystemPrompt: "You are terse."})
+	if err != nil {
+		t.Fatalf("systemPromptMessage failed: %v", err)
+	}
+	if msg.Role != "system" || msg.Text != "You are terse." {
+		t.Errorf("Expected system message, got %+v", msg)
+	}
+	tooLarge := strings.Repeat("a", maxSystemPromptSize+1)
+	if _, err := systemPromptMessage(&pb.ChatRequest{SystemPrompt: tooLarge}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for an oversized prompt, got %v", err)
+	}
+
+	// Chat applies the prompt without storing it, and rejects oversized prompts before storing anything
+	app, _ := setupTestApplicationWithMock(t)
+	startResp, err := app.StartSession(context.Background(), &pb.StartSessionRequest{})
+	if err != nil {
+		t.Fatalf("Failed to start session: %v", err)
+	}
+	if _, err := app.Chat(context.Background(), &pb.ChatRequest{SessionId: startResp.SessionId, Message: "Hi", SystemPrompt: tooLarge}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument from Chat, got %v", err)
+	}
+	if _, err := app.Chat(context.Background(), &pb.ChatRequest{SessionId: startResp.SessionId, Message: "Hi", SystemPrompt: "You are terse."}); err != nil {
+		t.Errorf("Chat with system prompt failed: %v", err)
+	}
+	if count := app.sessionStore.GetMessageCount(startResp.SessionId); count != 2 {
+		t.Errorf("Expected the system prompt not to be stored, got %d messages", count)
+	}
+}
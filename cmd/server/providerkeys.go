@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"microchat.ai/cmd/server/llm"
+)
+
+// providerKeyFileInterval is how often provider key files are checked for a new key
+const providerKeyFileInterval = 30 * time.Second
+
+// rotateProviderKey swaps a provider's upstream API key without a restart
+// Providers are created per request, so the next call uses the new key while calls in flight
+// finish on the client they started with; health is rechecked so routing sees the new key
+func (app *application) rotateProviderKey(provider, key string) error {
+	if err := llm.SetAPIKey(provider, key); err != nil {
+		return err
+	}
+	app.logger.Info("rotated provider API key", "provider", provider, "key_hash", hashAPIKey(strings.TrimSpace(key)))
+	if app.providerHealth != nil {
+		go app.providerHealth.CheckAll(context.Background())
+	}
+	return nil
+}
+
+// loadProviderKeyFiles reads the key file named by each provider's <APIKeyEnv>_FILE variable,
+// e.g. GEMINI_API_KEY_FILE, so keys can be rotated by rewriting the file
+// Returns the files to watch by provider name
+func loadProviderKeyFiles() (map[string]string, error) {
+	files := make(map[string]string)
+	for _, reg := range llm.Registered() {
+		if reg.APIKeyEnv == "" {
+			continue
+		}
+		path := os.Getenv(reg.APIKeyEnv + "_FILE")
+		if path == "" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s_FILE: %w", reg.APIKeyEnv, err)
+		}
+		if err := llm.SetAPIKey(reg.Name, string(data)); err != nil {
+			return nil, fmt.Errorf("invalid %s_FILE: %w", reg.APIKeyEnv, err)
+		}
+		files[reg.Name] = path
+	}
+	return files, nil
+}
+
+// watchProviderKeyFiles re-reads provider key files every interval until ctx is done,
+// rotating keys whose file changed
+// A file that can't be read, e.g. mid-rewrite, keeps the current key until the next check
+func watchProviderKeyFiles(ctx context.Context, app *application, files map[string]string, interval time.Duration) {
+	current := make(map[string]string, len(files))
+	for provider := range files {
+		current[provider] = llm.APIKey(provider)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			for provider, path := range files {
+				data, err := os.ReadFile(path)
+				if err != nil {
+					app.logger.Warn("failed to read provider key file", "provider", provider, "error", err)
+					continue
+				}
+				key := strings.TrimSpace(string(data))
+				if key == "" || key == current[provider] {
+					continue
+				}
+				if err := app.rotateProviderKey(provider, key); err != nil {
+					app.logger.Warn("failed to rotate provider API key", "provider", provider, "error", err)
+					continue
+				}
+				current[provider] = key
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// logProviderKeyFiles notes which providers read their key from a file
+func logProviderKeyFiles(logger *slog.Logger, files map[string]string) {
+	for provider, path := range files {
+		logger.Info("watching provider key file", "provider", provider, "path", path, "interval", providerKeyFileInterval)
+	}
+}
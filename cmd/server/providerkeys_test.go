@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"microchat.ai/cmd/server/llm"
+)
+
+func TestProviderKeyFiles(t *testing.T) {
+	t.Cleanup(func() { llm.ClearAPIKey("gemini") })
+	path := filepath.Join(t.TempDir(), "gemini.key")
+	if err := os.WriteFile(path, []byte("file-key\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GEMINI_API_KEY", "env-key")
+	t.Setenv("GEMINI_API_KEY_FILE", path)
+
+	files, err := loadProviderKeyFiles()
+	if err != nil {
+		t.Fatalf("loadProviderKeyFiles failed: %v", err)
+	}
+	if files["gemini"] != path || llm.APIKey("gemini") != "file-key" {
+		t.Fatalf("Expected key file to override the environment, got %v and %q", files, llm.APIKey("gemini"))
+	}
+
+	app, _ := setupTestApplicationWithMock(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		watchProviderKeyFiles(ctx, app, files, 10*time.Millisecond)
+		close(done)
+	}()
+
+	if err := os.WriteFile(path, []byte("rotated-key\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for llm.APIKey("gemini") != "rotated-key" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+	if key := llm.APIKey("gemini"); key != "rotated-key" {
+		t.Errorf("Expected rewritten key file to rotate the key, got %q", key)
+	}
+
+	t.Setenv("GEMINI_API_KEY_FILE", filepath.Join(t.TempDir(), "missing.key"))
+	if _, err := loadProviderKeyFiles(); err == nil {
+		t.Error("Expected missing key file to fail startup")
+	}
+}
+
+func TestUpdateUpstreamKeyMetrics(t *testing.T) {
+	now := time.Now()
+	updateUpstreamKeyMetrics("metrics_test", []llm.KeyStats{
+		{Key: "key-a", Calls: 5, QuotaErrors: 2, ExhaustedUntil: now.Add(time.Minute)},
+		{Key: "key-b", Calls: 3},
+	}, now)
+
+	if got := testutil.ToFloat64(upstreamKeyQuotaErrors.WithLabelValues("metrics_test", hashAPIKey("key-a"))); got != 2 {
+		t.Errorf("Expected 2 quota errors, got %v", got)
+	}
+	if got := testutil.ToFloat64(upstreamKeyExhausted.WithLabelValues("metrics_test", hashAPIKey("key-a"))); got != 1 {
+		t.Errorf("Expected exhausted key to be flagged, got %v", got)
+	}
+
+	// Keys rotated out of the pool are dropped
+	updateUpstreamKeyMetrics("metrics_test", []llm.KeyStats{{Key: "key-b", Calls: 4}}, now)
+	if got := testutil.CollectAndCount(upstreamKeyCalls); got != 1 {
+		t.Errorf("Expected only the remaining key to be exported, got %d series", got)
+	}
+}
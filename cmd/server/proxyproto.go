@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"microchat.ai/cmd/server/ratelimit"
+)
+
+// proxyHeaderTimeout bounds how long a connection may take to send its PROXY protocol header
+const proxyHeaderTimeout = 5 * time.Second
+
+// proxyV2Signature starts every PROXY protocol v2 header
+var proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// proxyListener accepts connections from an L4 load balancer that prefixes each one with a
+// HAProxy PROXY protocol (v1 or v2) header carrying the real client address
+// Any client can send the header, so only peers in trusted (TRUSTED_PROXIES) may connect:
+// connections from anyone else are closed, as are trusted ones without a header
+type proxyListener struct {
+	net.Listener
+	trusted ratelimit.TrustedProxies
+}
+
+// Accept wraps the next connection from a trusted peer; the header is read on first use, in
+// the connection's own goroutine, so a slow client can't stall the accept loop
+func (l *proxyListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		if addr, ok := conn.RemoteAddr().(*net.TCPAddr); ok && l.trusted.Contains(addr.IP) {
+			return &proxyConn{Conn: conn}, nil
+		}
+		conn.Close()
+	}
+}
+
+// proxyConn reports the client address from the PROXY header as its remote address
+type proxyConn struct {
+	net.Conn
+
+	once   sync.Once
+	reader *bufio.Reader
+	remote net.Addr
+	err    error
+}
+
+// init reads the PROXY header once
+func (c *proxyConn) init() {
+	c.once.Do(func() {
+		c.Conn.SetReadDeadline(time.Now().Add(proxyHeaderTimeout))
+		c.reader = bufio.NewReader(c.Conn)
+		c.remote, c.err = readProxyHeader(c.reader)
+		c.Conn.SetReadDeadline(time.Time{})
+		if c.err != nil {
+			c.Conn.Close()
+		}
+		if c.remote == nil {
+			// LOCAL commands (load balancer health checks) and unknown families keep the real peer
+			c.remote = c.Conn.RemoteAddr()
+		}
+	})
+}
+
+// Read returns the data following the PROXY header
+func (c *proxyConn) Read(b []byte) (int, error) {
+	c.init()
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.reader.Read(b)
+}
+
+// RemoteAddr returns the client address from the PROXY header
+func (c *proxyConn) RemoteAddr() net.Addr {
+	c.init()
+	return c.remote
+}
+
+// readProxyHeader reads a v1 or v2 PROXY header, returning the source address it carries,
+// or nil when the header doesn't describe a TCP client
+func readProxyHeader(r *bufio.Reader) (net.Addr, error) {
+	signature, err := r.Peek(len(proxyV2Signature))
+	if err == nil && bytes.Equal(signature, proxyV2Signature) {
+		return readProxyV2(r)
+	}
+	if prefix, err := r.Peek(6); err == nil && string(prefix) == "PROXY " {
+		return readProxyV1(r)
+	}
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read PROXY protocol header: %w", err)
+	}
+	return nil, fmt.Errorf("missing PROXY protocol header")
+}
+
+// readProxyV1 parses the text form: "PROXY TCP4 <src> <dst> <sport> <dport>\r\n"
+func readProxyV1(r *bufio.Reader) (net.Addr, error) {
+	const maxV1Length = 107 // Longest valid v1 header, from the specification
+
+	var line []byte
+	for len(line) <= maxV1Length {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read PROXY protocol header: %w", err)
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+	}
+	if len(line) > maxV1Length || !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, fmt.Errorf("invalid PROXY protocol v1 header")
+	}
+
+	fields := strings.Fields(string(line[:len(line)-2]))
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, fmt.Errorf("invalid PROXY protocol v1 header")
+	}
+	ip := net.ParseIP(fields[2])
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if ip == nil || err != nil {
+		return nil, fmt.Errorf("invalid PROXY protocol v1 source address")
+	}
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
+
+// readProxyV2 parses the binary form
+func readProxyV2(r *bufio.Reader) (net.Addr, error) {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("failed to read PROXY protocol header: %w", err)
+	}
+	if header[12]>>4 != 2 {
+		return nil, fmt.Errorf("unsupported PROXY protocol version %d", header[12]>>4)
+	}
+	command, family := header[12]&0x0f, header[13]
+
+	body := make([]byte, binary.BigEndian.Uint16(header[14:16]))
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, fmt.Errorf("failed to read PROXY protocol addresses: %w", err)
+	}
+
+	switch {
+	case command == 0x0:
+		// LOCAL: the load balancer's own connection, e.g. a health check
+		return nil, nil
+	case command != 0x1:
+		return nil, fmt.Errorf("unsupported PROXY protocol command %d", command)
+	case family == 0x11 && len(body) >= 12: // TCP over IPv4
+		return &net.TCPAddr{IP: net.IP(body[0:4]), Port: int(binary.BigEndian.Uint16(body[8:10]))}, nil
+	case family == 0x21 && len(body) >= 36: // TCP over IPv6
+		return &net.TCPAddr{IP: net.IP(body[0:16]), Port: int(binary.BigEndian.Uint16(body[32:34]))}, nil
+	default:
+		return nil, nil
+	}
+}
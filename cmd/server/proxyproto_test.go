@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"microchat.ai/cmd/server/ratelimit"
+)
+
+// proxyV2Header builds a binary PROXY header for a TCP source address
+func proxyV2Header(command byte, src *net.TCPAddr) []byte {
+	var body []byte
+	family := byte(0x11)
+	if ip4 := src.IP.To4(); ip4 != nil {
+		body = append(append(body, ip4...), 10, 0, 0, 1)
+	} else {
+		family = 0x21
+		body = append(append(body, src.IP.To16()...), net.IPv6loopback...)
+	}
+	body = binary.BigEndian.AppendUint16(body, uint16(src.Port))
+	body = binary.BigEndian.AppendUint16(body, 4000)
+
+	header := append([]byte{}, proxyV2Signature...)
+	header = append(header, 0x20|command, family)
+	header = binary.BigEndian.AppendUint16(header, uint16(len(body)))
+	return append(header, body...)
+}
+
+func TestReadProxyHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   []byte
+		want    string // Expected source address, "" for none
+		wantErr bool
+	}{
+		{"v1 TCP4", []byte("PROXY TCP4 203.0.113.7 10.0.0.1 51234 4000\r\n"), "203.0.113.7:51234", false},
+		{"v1 TCP6", []byte("PROXY TCP6 2001:db8::1 ::1 51234 4000\r\n"), "[2001:db8::1]:51234", false},
+		{"v1 UNKNOWN", []byte("PROXY UNKNOWN\r\n"), "", false},
+		{"v1 bad address", []byte("PROXY TCP4 not-an-ip 10.0.0.1 51234 4000\r\n"), "", true},
+		{"v1 too long", []byte("PROXY TCP4 " + strings.Repeat("1", 120) + "\r\n"), "", true},
+		{"v2 TCP4", proxyV2Header(0x1, &net.TCPAddr{IP: net.ParseIP("198.51.100.4"), Port: 443}), "198.51.100.4:443", false},
+		{"v2 TCP6", proxyV2Header(0x1, &net.TCPAddr{IP: net.ParseIP("2001:db8::2"), Port: 8443}), "[2001:db8::2]:8443", false},
+		{"v2 LOCAL", proxyV2Header(0x0, &net.TCPAddr{IP: net.ParseIP("198.51.100.4"), Port: 443}), "", false},
+		{"missing header", []byte("PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n"), "", true},
+		{"empty", nil, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bufio.NewReader(bytes.NewReader(append(tt.input, "payload"...)))
+			addr, err := readProxyHeader(r)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			got := ""
+			if addr != nil {
+				got = addr.String()
+			}
+			if got != tt.want {
+				t.Errorf("expected source %q, got %q", tt.want, got)
+			}
+			if rest, _ := io.ReadAll(r); string(rest) != "payload" {
+				t.Errorf("expected the header to be consumed exactly, got %q remaining", rest)
+			}
+		})
+	}
+}
+
+func TestProxyListener(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	lis := &proxyListener{Listener: inner, trusted: loopbackProxies(t)}
+	defer lis.Close()
+
+	go func() {
+		conn, err := net.Dial("tcp", inner.Addr().String())
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("PROXY TCP4 203.0.113.7 10.0.0.1 51234 4000\r\nhello"))
+		io.Copy(io.Discard, conn)
+	}()
+
+	conn, err := lis.Accept()
+	if err != nil {
+		t.Fatalf("accept failed: %v", err)
+	}
+	defer conn.Close()
+
+	if got := conn.RemoteAddr().String(); got != "203.0.113.7:51234" {
+		t.Errorf("expected client address from the header, got %s", got)
+	}
+	buf := make([]byte, 5)
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "hello" {
+		t.Errorf("expected data after the header, got %q, %v", buf, err)
+	}
+}
+
+func TestProxyListener_RejectsMissingHeader(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	lis := &proxyListener{Listener: inner, trusted: loopbackProxies(t)}
+	defer lis.Close()
+
+	go func() {
+		conn, err := net.Dial("tcp", inner.Addr().String())
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n"))
+		io.Copy(io.Discard, conn)
+	}()
+
+	conn, err := lis.Accept()
+	if err != nil {
+		t.Fatalf("accept failed: %v", err)
+	}
+	if _, err := conn.Read(make([]byte, 1)); err == nil {
+		t.Error("expected reads to fail without a PROXY header")
+	}
+}
+
+// loopbackProxies trusts the test's own connections
+func loopbackProxies(t *testing.T) ratelimit.TrustedProxies {
+	t.Helper()
+	trusted, err := ratelimit.ParseTrustedProxies("127.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return trusted
+}
+
+func TestProxyListener_ClosesUntrustedPeers(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	trusted, _ := ratelimit.ParseTrustedProxies("10.0.0.0/8")
+	lis := &proxyListener{Listener: inner, trusted: trusted}
+	defer lis.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		if conn, err := lis.Accept(); err == nil {
+			accepted <- conn
+		}
+	}()
+
+	conn, err := net.Dial("tcp", inner.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("PROXY TCP4 203.0.113.7 10.0.0.1 51234 4000\r\nhello"))
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err == nil || errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("expected the server to close a connection from an untrusted peer, got %v", err)
+	}
+	select {
+	case conn := <-accepted:
+		t.Errorf("expected no connection to be accepted, got one from %s", conn.RemoteAddr())
+	default:
+	}
+}
@@ -0,0 +1,114 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// AdaptiveConfig controls when an Adaptive limiter tightens and relaxes
+type AdaptiveConfig struct {
+	LatencyThreshold   time.Duration // Average LLM latency per window above which limits tighten
+	ErrorRateThreshold float64       // LLM error rate per window above which limits tighten
+	MinSamples         int           // Windows with fewer LLM calls than this are treated as healthy
+	MinFactor          float64       // Lowest fraction of the configured rate limits can tighten to
+	HealthyWindows     int           // Consecutive healthy windows required before relaxing
+}
+
+// DefaultAdaptiveConfig returns the thresholds used when only the load signals are configured
+func DefaultAdaptiveConfig() AdaptiveConfig {
+	return AdaptiveConfig{
+		LatencyThreshold:   10 * time.Second,
+		ErrorRateThreshold: 0.25,
+		MinSamples:         10,
+		MinFactor:          0.1,
+		HealthyWindows:     3,
+	}
+}
+
+// Adaptive scales an IPLimiter's rate with LLM provider load
+// Limits halve after each window where latency or errors exceed their thresholds, and recover
+// gradually only once the provider is comfortably below them (under 75% of the latency threshold
+// and half the error threshold) for several windows, so limits don't flap around a threshold
+type Adaptive struct {
+	limiter *IPLimiter
+	baseRPS rate.Limit
+	config  AdaptiveConfig
+
+	mu            sync.Mutex
+	calls         int
+	failures      int
+	totalLatency  time.Duration
+	factor        float64 // Current fraction of baseRPS in effect
+	healthyStreak int
+}
+
+// NewAdaptive creates an adaptive controller for a limiter configured at baseRPS
+func NewAdaptive(limiter *IPLimiter, baseRPS rate.Limit, config AdaptiveConfig) *Adaptive {
+	return &Adaptive{
+		limiter: limiter,
+		baseRPS: baseRPS,
+		config:  config,
+		factor:  1,
+	}
+}
+
+// Observe records the outcome of one LLM call in the current window
+// A nil Adaptive ignores observations
+func (a *Adaptive) Observe(latency time.Duration, failed bool) {
+	if a == nil {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.calls++
+	a.totalLatency += latency
+	if failed {
+		a.failures++
+	}
+}
+
+// Evaluate closes the current window, adjusts the limiter and returns the effective rate
+func (a *Adaptive) Evaluate() rate.Limit {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	overloaded, healthy := true, false
+	if a.calls < a.config.MinSamples {
+		overloaded, healthy = false, true
+	} else {
+		avgLatency := a.totalLatency / time.Duration(a.calls)
+		errorRate := float64(a.failures) / float64(a.calls)
+		overloaded = avgLatency > a.config.LatencyThreshold || errorRate > a.config.ErrorRateThreshold
+		healthy = avgLatency < a.config.LatencyThreshold*3/4 && errorRate < a.config.ErrorRateThreshold/2
+	}
+	a.calls, a.failures, a.totalLatency = 0, 0, 0
+
+	switch {
+	case overloaded:
+		a.healthyStreak = 0
+		a.factor = max(a.factor/2, a.config.MinFactor)
+	case healthy:
+		a.healthyStreak++
+		if a.healthyStreak >= a.config.HealthyWindows && a.factor < 1 {
+			a.factor = min(a.factor*1.5, 1)
+			a.healthyStreak = 0
+		}
+	default:
+		// Between the thresholds: hold the current limit
+		a.healthyStreak = 0
+	}
+
+	effective := a.baseRPS * rate.Limit(a.factor)
+	a.limiter.SetRate(effective)
+	return effective
+}
+
+// Factor returns the fraction of the configured rate currently in effect
+func (a *Adaptive) Factor() float64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.factor
+}
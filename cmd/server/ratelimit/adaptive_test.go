@@ -0,0 +1,84 @@
+package ratelimit
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestAdaptive_TightensAndRelaxesWithHysteresis(t *testing.T) {
+	limiter := NewIPLimiter(10, 20)
+	defer limiter.Stop()
+
+	config := AdaptiveConfig{
+		LatencyThreshold:   time.Second,
+		ErrorRateThreshold: 0.2,
+		MinSamples:         4,
+		MinFactor:          0.2,
+		HealthyWindows:     2,
+	}
+	adaptive := NewAdaptive(limiter, 10, config)
+	observe := func(n int, latency time.Duration, failures int) {
+		for i := 0; i < n; i++ {
+			adaptive.Observe(latency, i < failures)
+		}
+	}
+
+	// Slow provider halves the limit each window down to the floor
+	observe(5, 2*time.Second, 0)
+	if got := adaptive.Evaluate(); got != 5 {
+		t.Errorf("expected limit to halve to 5 rps, got %v", got)
+	}
+	observe(5, 100*time.Millisecond, 2) // 40% errors
+	adaptive.Evaluate()
+	observe(5, 2*time.Second, 0)
+	if got := adaptive.Evaluate(); got != 2 {
+		t.Errorf("expected limit to stop at the 20%% floor, got %v", got)
+	}
+	if limiter.rps != 2 {
+		t.Errorf("expected limiter rate to follow, got %v", limiter.rps)
+	}
+
+	// Latency just under the threshold holds the limit instead of relaxing
+	observe(5, 900*time.Millisecond, 0)
+	adaptive.Evaluate()
+	observe(5, 900*time.Millisecond, 0)
+	if got := adaptive.Evaluate(); got != 2 {
+		t.Errorf("expected limit to hold between thresholds, got %v", got)
+	}
+
+	// Relaxing needs consecutive healthy windows; quiet windows count as healthy
+	observe(5, 100*time.Millisecond, 0)
+	if got := adaptive.Evaluate(); got != 2 {
+		t.Errorf("expected no relaxation after one healthy window, got %v", got)
+	}
+	if got := adaptive.Evaluate(); math.Abs(float64(got)-3) > 1e-9 {
+		t.Errorf("expected limit to grow by half after two healthy windows, got %v", got)
+	}
+	for i := 0; i < 10; i++ {
+		adaptive.Evaluate()
+	}
+	if adaptive.Factor() != 1 || limiter.rps != 10 {
+		t.Errorf("expected limit to recover to the configured rate, got factor %v rps %v", adaptive.Factor(), limiter.rps)
+	}
+}
+
+func TestAdaptive_NilIgnoresObservations(t *testing.T) {
+	var adaptive *Adaptive
+	adaptive.Observe(time.Second, true)
+}
+
+func TestIPLimiterSetRate(t *testing.T) {
+	limiter := NewIPLimiter(10, 1)
+	defer limiter.Stop()
+
+	limiter.Allow("10.0.0.1")
+	limiter.SetRate(0.5)
+	if got := limiter.limiters["10.0.0.1"].limiter.Limit(); got != 0.5 {
+		t.Errorf("expected existing limiter to use the new rate, got %v", got)
+	}
+	limiter.Allow("10.0.0.2")
+	if got := limiter.limiters["10.0.0.2"].limiter.Limit(); got != 0.5 {
+		t.Errorf("expected new limiter to use the new rate, got %v", got)
+	}
+}
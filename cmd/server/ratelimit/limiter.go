@@ -1,7 +1,9 @@
 package ratelimit
 
 import (
+	"fmt"
 	"net"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -45,6 +47,16 @@ func NewIPLimiter(rps rate.Limit, burst int) *IPLimiter {
 
 // Allow checks if a request from the given IP is allowed
 func (il *IPLimiter) Allow(ip string) bool {
+	return il.AllowN(ip, 1)
+}
+
+// AllowN checks if a request costing n tokens from the given IP is allowed
+// Requests costing 0 tokens are always allowed and don't create a limiter
+func (il *IPLimiter) AllowN(ip string, n int) bool {
+	if n <= 0 {
+		return true
+	}
+
 	il.mu.Lock()
 	defer il.mu.Unlock()
 
@@ -61,7 +73,49 @@ func (il *IPLimiter) Allow(ip string) bool {
 		entry.lastSeen = time.Now()
 	}
 
-	return entry.limiter.Allow()
+	return entry.limiter.AllowN(time.Now(), n)
+}
+
+// ParseCosts parses per-method token costs in the form "Chat=5,GetHistory=1,Health=0"
+// Methods that aren't listed cost one token
+func ParseCosts(spec string) (map[string]int, error) {
+	costs := make(map[string]int)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		method, costStr, found := strings.Cut(entry, "=")
+		method = strings.TrimSpace(method)
+		if !found || method == "" {
+			return nil, fmt.Errorf("invalid cost %q: expected Method=tokens", entry)
+		}
+		cost, err := strconv.Atoi(strings.TrimSpace(costStr))
+		if err != nil || cost < 0 {
+			return nil, fmt.Errorf("invalid cost for %s: %q is not a non-negative integer", method, costStr)
+		}
+		costs[method] = cost
+	}
+	return costs, nil
+}
+
+// Rate returns the current refill rate in tokens per second
+func (il *IPLimiter) Rate() rate.Limit {
+	il.mu.RLock()
+	defer il.mu.RUnlock()
+	return il.rps
+}
+
+// SetRate changes the refill rate of every limiter, including ones created later
+// Burst capacity is unchanged so requests costing several tokens can still run
+func (il *IPLimiter) SetRate(rps rate.Limit) {
+	il.mu.Lock()
+	defer il.mu.Unlock()
+
+	il.rps = rps
+	for _, entry := range il.limiters {
+		entry.limiter.SetLimit(rps)
+	}
 }
 
 // cleanupWorker periodically removes stale limiters to prevent memory leaks
@@ -104,26 +158,76 @@ func (il *IPLimiter) GetActiveCount() int {
 	return len(il.limiters)
 }
 
-// ExtractIP extracts the real client IP from various sources
-func ExtractIP(remoteAddr string, forwardedFor string) string {
-	// First try X-Forwarded-For header (handles proxies/load balancers)
-	if forwardedFor != "" {
-		// X-Forwarded-For can contain multiple IPs: "client, proxy1, proxy2"
-		// The first IP is typically the original client
-		ips := strings.Split(forwardedFor, ",")
-		if len(ips) > 0 {
-			ip := strings.TrimSpace(ips[0])
-			if net.ParseIP(ip) != nil {
-				return ip
+// TrustedProxies is the set of networks whose X-Forwarded-For headers are believed
+type TrustedProxies []*net.IPNet
+
+// ParseTrustedProxies parses a comma-separated list of CIDRs or bare IPs
+func ParseTrustedProxies(spec string) (TrustedProxies, error) {
+	var trusted TrustedProxies
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: not an IP or CIDR", entry)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
 			}
+			trusted = append(trusted, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+		}
+		trusted = append(trusted, network)
+	}
+	return trusted, nil
+}
+
+// Contains reports whether an IP belongs to a trusted proxy
+func (t TrustedProxies) Contains(ip net.IP) bool {
+	for _, network := range t {
+		if network.Contains(ip) {
+			return true
 		}
 	}
+	return false
+}
 
-	// Fall back to remote address
+// ExtractIP extracts the real client IP from various sources
+// X-Forwarded-For is only honored when the direct peer is a trusted proxy, since any client
+// can send the header; the chain is then walked from the right, skipping trusted proxies,
+// so a client can't spoof its address by prepending entries
+func ExtractIP(remoteAddr string, forwardedFor string, trusted TrustedProxies) string {
 	host, _, err := net.SplitHostPort(remoteAddr)
 	if err != nil {
 		// If we can't split host:port, assume it's just an IP
-		return remoteAddr
+		host = remoteAddr
+	}
+
+	peerIP := net.ParseIP(host)
+	if forwardedFor == "" || peerIP == nil || !trusted.Contains(peerIP) {
+		return host
+	}
+
+	// X-Forwarded-For lists "client, proxy1, proxy2"; each proxy appends the address it saw
+	ips := strings.Split(forwardedFor, ",")
+	for i := len(ips) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(ips[i]))
+		if ip == nil {
+			// A malformed entry can't be attributed; fall back to the last address we trust
+			break
+		}
+		if !trusted.Contains(ip) || i == 0 {
+			return ip.String()
+		}
+		host = ip.String()
 	}
 	return host
 }
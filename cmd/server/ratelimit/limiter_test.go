@@ -1,6 +1,7 @@
 package ratelimit
 
 import (
+	"net"
 	"testing"
 	"time"
 )
@@ -119,6 +120,11 @@ func TestIPLimiterCleanup(t *testing.T) {
 }
 
 func TestExtractIP(t *testing.T) {
+	trusted, err := ParseTrustedProxies("10.0.0.0/8, 172.16.0.5")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
 	tests := []struct {
 		name         string
 		remoteAddr   string
@@ -144,10 +150,28 @@ func TestExtractIP(t *testing.T) {
 			expected:     "203.0.113.1",
 		},
 		{
-			name:         "forwarded header multiple IPs",
+			name:         "forwarded header from untrusted peer is ignored",
+			remoteAddr:   "192.168.1.1:12345",
+			forwardedFor: "203.0.113.1",
+			expected:     "192.168.1.1",
+		},
+		{
+			name:         "forwarded header multiple IPs uses rightmost untrusted",
 			remoteAddr:   "10.0.0.1:12345",
 			forwardedFor: "203.0.113.1, 198.51.100.1, 192.0.2.1",
-			expected:     "203.0.113.1",
+			expected:     "192.0.2.1",
+		},
+		{
+			name:         "forwarded header skips trusted proxies in the chain",
+			remoteAddr:   "10.0.0.1:12345",
+			forwardedFor: "203.0.113.1, 198.51.100.1, 172.16.0.5, 10.1.2.3",
+			expected:     "198.51.100.1",
+		},
+		{
+			name:         "forwarded header of only trusted proxies uses leftmost",
+			remoteAddr:   "10.0.0.1:12345",
+			forwardedFor: "10.0.0.9, 172.16.0.5",
+			expected:     "10.0.0.9",
 		},
 		{
 			name:         "forwarded header with spaces",
@@ -161,6 +185,12 @@ func TestExtractIP(t *testing.T) {
 			forwardedFor: "not-an-ip",
 			expected:     "10.0.0.1",
 		},
+		{
+			name:         "invalid entry falls back to last trusted hop",
+			remoteAddr:   "10.0.0.1:12345",
+			forwardedFor: "not-an-ip, 172.16.0.5",
+			expected:     "172.16.0.5",
+		},
 		{
 			name:         "IPv6 with port",
 			remoteAddr:   "[2001:db8::1]:8080",
@@ -171,13 +201,46 @@ func TestExtractIP(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := ExtractIP(tt.remoteAddr, tt.forwardedFor)
+			result := ExtractIP(tt.remoteAddr, tt.forwardedFor, trusted)
 			if result != tt.expected {
 				t.Errorf("ExtractIP(%q, %q) = %q, want %q",
 					tt.remoteAddr, tt.forwardedFor, result, tt.expected)
 			}
 		})
 	}
+
+	if ip := ExtractIP("10.0.0.1:12345", "203.0.113.1", nil); ip != "10.0.0.1" {
+		t.Errorf("expected forwarded header to be ignored with no trusted proxies, got %q", ip)
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	trusted, err := ParseTrustedProxies(" 10.0.0.0/8, 192.168.1.10 ,fd00::/8,")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(trusted) != 3 {
+		t.Fatalf("expected 3 networks, got %d", len(trusted))
+	}
+	for _, ip := range []string{"10.20.30.40", "192.168.1.10", "fd00::1"} {
+		if !trusted.Contains(net.ParseIP(ip)) {
+			t.Errorf("expected %s to be trusted", ip)
+		}
+	}
+	for _, ip := range []string{"192.168.1.11", "203.0.113.1", "2001:db8::1"} {
+		if trusted.Contains(net.ParseIP(ip)) {
+			t.Errorf("expected %s not to be trusted", ip)
+		}
+	}
+
+	if trusted, err := ParseTrustedProxies(""); err != nil || len(trusted) != 0 {
+		t.Errorf("expected empty spec to trust nothing, got %v, %v", trusted, err)
+	}
+	for _, spec := range []string{"10.0.0.0/33", "proxy.internal", "10.0.0"} {
+		if _, err := ParseTrustedProxies(spec); err == nil {
+			t.Errorf("expected error for %q", spec)
+		}
+	}
 }
 
 func TestIPLimiterConcurrency(t *testing.T) {
@@ -234,3 +297,40 @@ func TestIPLimiterStop(t *testing.T) {
 		t.Error("Stop() did not complete within 1 second")
 	}
 }
+
+func TestIPLimiterAllowN(t *testing.T) {
+	limiter := NewIPLimiter(0.001, 5)
+	defer limiter.Stop()
+
+	if !limiter.AllowN("10.0.0.1", 0) || limiter.GetActiveCount() != 0 {
+		t.Error("expected free requests to be allowed without creating a limiter")
+	}
+	if !limiter.AllowN("10.0.0.1", 4) {
+		t.Error("expected request within the burst to be allowed")
+	}
+	if limiter.AllowN("10.0.0.1", 2) {
+		t.Error("expected request costing more than the remaining tokens to be denied")
+	}
+	if !limiter.AllowN("10.0.0.1", 1) {
+		t.Error("expected request costing the remaining token to be allowed")
+	}
+}
+
+func TestParseCosts(t *testing.T) {
+	costs, err := ParseCosts(" Chat=5, GetHistory = 1,Health=0,")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(costs) != 3 || costs["Chat"] != 5 || costs["GetHistory"] != 1 || costs["Health"] != 0 {
+		t.Errorf("unexpected costs: %v", costs)
+	}
+
+	if costs, err := ParseCosts(""); err != nil || len(costs) != 0 {
+		t.Errorf("expected empty spec to give no costs, got %v, %v", costs, err)
+	}
+	for _, spec := range []string{"Chat", "=5", "Chat=-1", "Chat=five"} {
+		if _, err := ParseCosts(spec); err == nil {
+			t.Errorf("expected error for %q", spec)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"microchat.ai/cmd/server/llm"
+)
+
+// readinessResponse is the body returned by the readiness endpoint
+type readinessResponse struct {
+	Ready       bool                          `json:"ready"`
+	Maintenance bool                          `json:"maintenance"`
+	Providers   map[string]llm.ProviderHealth `json:"providers"`
+}
+
+// readinessHandler reports 200 when every monitored LLM provider is healthy, 503 otherwise
+// Maintenance mode also reports 503, so load balancers drain the instance before an upgrade
+func (app *application) readinessHandler(w http.ResponseWriter, r *http.Request) {
+	resp := readinessResponse{Ready: true, Providers: map[string]llm.ProviderHealth{}}
+	if app.providerHealth != nil {
+		resp.Ready = app.providerHealth.Ready()
+		resp.Providers = app.providerHealth.Snapshot()
+	}
+	if enabled, _, _ := app.maintenance.status(); enabled {
+		resp.Ready, resp.Maintenance = false, true
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !resp.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		app.logger.Error("failed to write readiness response", "error", err)
+	}
+}
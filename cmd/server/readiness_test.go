@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"microchat.ai/cmd/server/llm"
+)
+
+func TestReadinessHandler(t *testing.T) {
+	app := setupTestApplication(t)
+	provider := llm.NewMockProvider("mock")
+	app.providerHealth = llm.NewHealthMonitor([]llm.HealthProbe{
+		{Name: "mock", New: func() (llm.Provider, error) { return provider, nil }},
+	}, app.logger)
+
+	check := func(expectedStatus int) readinessResponse {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		app.readinessHandler(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		if rec.Code != expectedStatus {
+			t.Errorf("Expected status %d, got %d", expectedStatus, rec.Code)
+		}
+		var resp readinessResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode readiness response: %v", err)
+		}
+		return resp
+	}
+
+	// Not ready until providers have been checked
+	check(http.StatusServiceUnavailable)
+
+	app.providerHealth.CheckAll(context.Background())
+	if resp := check(http.StatusOK); !resp.Ready || !resp.Providers["mock"].Healthy {
+		t.Errorf("Expected ready with healthy provider, got %+v", resp)
+	}
+
+	provider.SetError("down")
+	app.providerHealth.CheckAll(context.Background())
+	if resp := check(http.StatusServiceUnavailable); resp.Providers["mock"].LastError != "down" {
+		t.Errorf("Expected provider error in response, got %+v", resp)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"strings"
+)
+
+// redactedValue replaces sensitive attribute values in log output
+const redactedValue = "[REDACTED]"
+
+// contentLogKeys carry user or model content and are always replaced
+var contentLogKeys = map[string]bool{
+	"message": true,
+	"text":    true,
+	"content": true,
+	"reply":   true,
+	"prompt":  true,
+	"title":   true,
+}
+
+// secretLogKeys carry credentials and are always replaced
+var secretLogKeys = map[string]bool{
+	"api_key":       true,
+	"authorization": true,
+	"token":         true,
+	"session_key":   true,
+}
+
+// identifierLogKeys identify a session and are replaced with a stable hash,
+// so log lines for the same session can still be correlated
+var identifierLogKeys = map[string]bool{
+	"session_id":      true,
+	"fork_session_id": true,
+}
+
+// redactingHandler is a slog middleware that strips message contents, credentials
+// and session identifiers before records reach the underlying handler
+type redactingHandler struct {
+	next slog.Handler
+}
+
+// newRedactingHandler wraps next with PII redaction
+func newRedactingHandler(next slog.Handler) *redactingHandler {
+	return &redactingHandler{next: next}
+}
+
+func (h *redactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *redactingHandler) Handle(ctx context.Context, r slog.Record) error {
+	redacted := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		redacted.AddAttrs(redactAttr(a))
+		return true
+	})
+	return h.next.Handle(ctx, redacted)
+}
+
+func (h *redactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = redactAttr(a)
+	}
+	return &redactingHandler{next: h.next.WithAttrs(redacted)}
+}
+
+func (h *redactingHandler) WithGroup(name string) slog.Handler {
+	return &redactingHandler{next: h.next.WithGroup(name)}
+}
+
+// redactAttr returns a copy of a with sensitive values replaced, recursing into groups
+func redactAttr(a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+
+	if a.Value.Kind() == slog.KindGroup {
+		group := a.Value.Group()
+		redacted := make([]slog.Attr, len(group))
+		for i, ga := range group {
+			redacted[i] = redactAttr(ga)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(redacted...)}
+	}
+
+	key := strings.ToLower(a.Key)
+	switch {
+	case contentLogKeys[key], secretLogKeys[key]:
+		return slog.String(a.Key, redactedValue)
+	case identifierLogKeys[key]:
+		return slog.String(a.Key, hashIdentifier(a.Value.String()))
+	}
+	return a
+}
+
+// hashIdentifier returns a short, non-reversible stand-in for an identifier
+func hashIdentifier(id string) string {
+	if id == "" {
+		return ""
+	}
+	hash := sha256.Sum256([]byte(id))
+	return "h:" + hex.EncodeToString(hash[:6])
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRedactingHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(newRedactingHandler(slog.NewTextHandler(&buf, nil)))
+
+	logger.Info("chat",
+		"session_id", "3f2c1a7e-session",
+		"message", "my private question",
+		"api_key", "secret-key",
+		"message_len", 19,
+		slog.Group("request", "prompt", "hidden prompt"),
+	)
+	logger.With("reply", "model answer").Info("done")
+
+	output := buf.String()
+	for _, leaked := range []string{"3f2c1a7e-session", "my private question", "secret-key", "hidden prompt", "model answer"} {
+		if strings.Contains(output, leaked) {
+			t.Errorf("Expected %q to be redacted, got: %s", leaked, output)
+		}
+	}
+	if !strings.Contains(output, "message_len=19") {
+		t.Errorf("Expected non-sensitive attributes to be kept, got: %s", output)
+	}
+	if !strings.Contains(output, "session_id="+hashIdentifier("3f2c1a7e-session")) {
+		t.Errorf("Expected session ID to be replaced with its hash, got: %s", output)
+	}
+}
+
+func TestHashIdentifierIsStable(t *testing.T) {
+	if hashIdentifier("abc") != hashIdentifier("abc") {
+		t.Error("Expected identical identifiers to hash identically")
+	}
+	if hashIdentifier("abc") == hashIdentifier("abd") {
+		t.Error("Expected different identifiers to hash differently")
+	}
+}
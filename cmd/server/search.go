@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	maxSearchQueryLength  = 256 // Maximum query length in characters
+	defaultSearchPageSize = 20  // Matches per page when the client doesn't ask for a size
+	maxSearchPageSize     = 100 // Upper bound for client-requested page sizes
+)
+
+// SearchMatch is a stored message matching a history search
+type SearchMatch struct {
+	SessionID string
+	Index     int // Position of the message in the session history
+	Message   Message
+}
+
+// newHistoryMatcher builds a matcher for a search query: a case-insensitive substring,
+// or an RE2 regular expression (linear time, so safe on untrusted input) when regex is set
+func newHistoryMatcher(query string, regex bool) (func(string) bool, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, fmt.Errorf("query cannot be empty")
+	}
+	if len([]rune(query)) > maxSearchQueryLength {
+		return nil, fmt.Errorf("query too long: maximum %d characters", maxSearchQueryLength)
+	}
+
+	if regex {
+		re, err := regexp.Compile(query)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression: %w", err)
+		}
+		return re.MatchString, nil
+	}
+
+	lowered := strings.ToLower(query)
+	return func(text string) bool {
+		return strings.Contains(strings.ToLower(text), lowered)
+	}, nil
+}
+
+// SearchMessages returns the messages in a session whose text matches, in history order
+// Client-encrypted sessions can only be searched with the client's key
+func (s *SessionStore) SearchMessages(sessionID string, match func(string) bool, clientCipher *messageCipher) []SearchMatch {
+	shard := s.shardFor(sessionID)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	return s.searchSession(shard, sessionID, match, s.cipherFor(shard, sessionID, clientCipher))
+}
+
+// SearchAllSessions returns matching messages across all sessions, ordered by session ID
+// then history order so pages stay stable between requests
+// Client-encrypted sessions are skipped since the server can't read them
+func (s *SessionStore) SearchAllSessions(match func(string) bool) []SearchMatch {
+	var matches []SearchMatch
+	for _, shard := range s.shards {
+		shard.mu.RLock()
+		for sessionID := range shard.sessions {
+			if _, clientEncrypted := shard.clientKeys[sessionID]; clientEncrypted {
+				continue
+			}
+			matches = append(matches, s.searchSession(shard, sessionID, match, s.cipher)...)
+		}
+		shard.mu.RUnlock()
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].SessionID != matches[j].SessionID {
+			return matches[i].SessionID < matches[j].SessionID
+		}
+		return matches[i].Index < matches[j].Index
+	})
+	return matches
+}
+
+// searchSession matches the decrypted messages of one session
+// Caller must hold the shard lock
+func (s *SessionStore) searchSession(shard *sessionShard, sessionID string, match func(string) bool, c *messageCipher) []SearchMatch {
+	session, exists := shard.sessions[sessionID]
+	if !exists {
+		return nil
+	}
+
+	var matches []SearchMatch
+	for i, msg := range openMessages(sessionID, session.Messages, c) {
+		if msg.Sealed == nil && match(msg.Text) {
+			matches = append(matches, SearchMatch{SessionID: sessionID, Index: i, Message: msg})
+		}
+	}
+	return matches
+}
+
+// paginateMatches returns one page of matches and the token for the next page
+// Page tokens are offsets into the full result list; an empty next token means the last page
+func paginateMatches(matches []SearchMatch, pageSize uint32, pageToken string) ([]SearchMatch, string, error) {
+	offset := 0
+	if pageToken != "" {
+		parsed, err := strconv.Atoi(pageToken)
+		if err != nil || parsed < 0 {
+			return nil, "", fmt.Errorf("invalid page token")
+		}
+		offset = parsed
+	}
+
+	size := int(pageSize)
+	if size == 0 {
+		size = defaultSearchPageSize
+	}
+	size = min(size, maxSearchPageSize)
+
+	if offset >= len(matches) {
+		return nil, "", nil
+	}
+	end := min(offset+size, len(matches))
+
+	next := ""
+	if end < len(matches) {
+		next = strconv.Itoa(end)
+	}
+	return matches[offset:end], next, nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestNewHistoryMatcher(t *testing.T) {
+	substring, err := newHistoryMatcher("Paris", false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !substring("the capital is paris.") || substring("the capital is Rome") {
+		t.Error("Expected a case-insensitive substring match")
+	}
+
+	regex, err := newHistoryMatcher(`\bv\d+\.\d+\b`, true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !regex("upgrade to v1.24 first") || regex("upgrade to version one") {
+		t.Error("Expected a regular expression match")
+	}
+
+	for _, tc := range []struct {
+		query string
+		regex bool
+	}{
+		{"   ", false},
+		{"(unclosed", true},
+		{string(make([]rune, maxSearchQueryLength+1)), false},
+	} {
+		if _, err := newHistoryMatcher(tc.query, tc.regex); err == nil {
+			t.Errorf("Expected error for query %q (regex=%v)", tc.query, tc.regex)
+		}
+	}
+}
+
+func TestSessionStore_SearchMessages(t *testing.T) {
+	store := NewSessionStore(2*time.Hour, 1000, 100, 100*1024)
+	store.EnableEncryption(make([]byte, encryptionKeySize))
+	store.RegisterSession("session", "key")
+	store.AppendMessage("session", User, "What is the capital of France?")
+	store.AppendMessage("session", Assistant, "The capital of France is Paris.")
+	store.AppendMessage("session", User, "And Italy?")
+
+	match, _ := newHistoryMatcher("france", false)
+	matches := store.SearchMessages("session", match, nil)
+	if len(matches) != 2 || matches[0].Index != 0 || matches[1].Index != 1 {
+		t.Fatalf("Expected the first two messages to match, got %+v", matches)
+	}
+	if matches[1].Message.Text != "The capital of France is Paris." || matches[1].Message.Role != Assistant {
+		t.Errorf("Expected decrypted assistant message, got %+v", matches[1].Message)
+	}
+
+	// Client-encrypted sessions can't be searched without the key, nor across sessions
+	store.RegisterSession("private", "key")
+	store.SetClientKeyFingerprint("private", "fingerprint")
+	clientCipher, _ := newMessageCipher(make([]byte, encryptionKeySize))
+	store.AppendMessageWithKey("private", User, "France again", clientCipher)
+
+	if matches := store.SearchMessages("private", match, nil); len(matches) != 0 {
+		t.Errorf("Expected no matches without the client key, got %+v", matches)
+	}
+	if matches := store.SearchMessages("private", match, clientCipher); len(matches) != 1 {
+		t.Errorf("Expected 1 match with the client key, got %+v", matches)
+	}
+	if matches := store.SearchAllSessions(match); len(matches) != 2 {
+		t.Errorf("Expected client-encrypted sessions to be skipped, got %+v", matches)
+	}
+}
+
+func TestSessionStore_SearchAllSessionsOrder(t *testing.T) {
+	store := NewSessionStore(2*time.Hour, 1000, 100, 100*1024)
+	for _, id := range []string{"c", "a", "b"} {
+		store.RegisterSession(id, "key")
+		store.AppendMessage(id, User, "needle one")
+		store.AppendMessage(id, Assistant, "haystack")
+		store.AppendMessage(id, User, "needle two")
+	}
+
+	match, _ := newHistoryMatcher("needle", false)
+	matches := store.SearchAllSessions(match)
+
+	var got []string
+	for _, m := range matches {
+		got = append(got, fmt.Sprintf("%s%d", m.SessionID, m.Index))
+	}
+	if fmt.Sprint(got) != "[a0 a2 b0 b2 c0 c2]" {
+		t.Errorf("Expected matches ordered by session then index, got %v", got)
+	}
+}
+
+func TestPaginateMatches(t *testing.T) {
+	matches := make([]SearchMatch, 5)
+	for i := range matches {
+		matches[i].Index = i
+	}
+
+	page, next, err := paginateMatches(matches, 2, "")
+	if err != nil || len(page) != 2 || page[0].Index != 0 || next != "2" {
+		t.Fatalf("Unexpected first page: %+v, next %q, err %v", page, next, err)
+	}
+	page, next, _ = paginateMatches(matches, 2, next)
+	if len(page) != 2 || page[0].Index != 2 || next != "4" {
+		t.Fatalf("Unexpected second page: %+v, next %q", page, next)
+	}
+	page, next, _ = paginateMatches(matches, 2, next)
+	if len(page) != 1 || page[0].Index != 4 || next != "" {
+		t.Fatalf("Unexpected last page: %+v, next %q", page, next)
+	}
+
+	if page, _, _ := paginateMatches(make([]SearchMatch, 500), 0, ""); len(page) != defaultSearchPageSize {
+		t.Errorf("Expected default page size, got %d", len(page))
+	}
+	if page, _, _ := paginateMatches(make([]SearchMatch, 500), 1000, ""); len(page) != maxSearchPageSize {
+		t.Errorf("Expected page size to be capped, got %d", len(page))
+	}
+	if _, _, err := paginateMatches(matches, 2, "bogus"); err == nil {
+		t.Error("Expected error for invalid page token")
+	}
+}
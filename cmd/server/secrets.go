@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"microchat.ai/cmd/server/llm"
+	"microchat.ai/cmd/server/secrets"
+)
+
+// managedSecrets are the settings a secrets source may provide; other values in the secret are ignored
+var managedSecrets = []string{
+	"API_KEYS",
+	"GEMINI_API_KEY",
+	"OPENAI_COMPAT_API_KEY",
+	"SESSION_ENCRYPTION_KEY",
+	"TLS_CERT_PEM",
+	"TLS_KEY_PEM",
+}
+
+// Listener certificate and key names standing for the TLS material held in TLS_CERT_PEM and TLS_KEY_PEM
+const (
+	secretTLSCert = "secret:TLS_CERT_PEM"
+	secretTLSKey  = "secret:TLS_KEY_PEM"
+)
+
+// loadSecrets fetches the secrets source named by SECRETS_SOURCE and exports its values as
+// environment variables, so the rest of the configuration reads them like any other setting
+// Secrets take precedence over the same variables set in the environment or .env
+// Returns a nil source when SECRETS_SOURCE isn't set
+func loadSecrets(logger *slog.Logger) (secrets.Source, map[string]string, error) {
+	spec := os.Getenv("SECRETS_SOURCE")
+	if spec == "" {
+		return nil, nil, nil
+	}
+	source, err := secrets.ParseSource(spec, os.Getenv)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	values, err := source.Fetch(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch secrets from %s: %w", source.Name(), err)
+	}
+
+	values = managedSecretValues(values)
+	var loaded []string
+	for name, value := range values {
+		if os.Getenv(name) != "" {
+			logger.Warn("secret overrides environment variable", "name", name)
+		}
+		os.Setenv(name, value)
+		loaded = append(loaded, name)
+	}
+	slices.Sort(loaded)
+	logger.Info("loaded secrets", "source", source.Name(), "names", loaded)
+	return source, values, nil
+}
+
+// managedSecretValues drops values that aren't managed secrets
+func managedSecretValues(values map[string]string) map[string]string {
+	managed := make(map[string]string)
+	for name, value := range values {
+		if slices.Contains(managedSecrets, name) {
+			managed[name] = value
+		}
+	}
+	return managed
+}
+
+// applySecretChanges applies secrets rotated in the source while the server runs
+// API keys and TLS certificates are swapped in place, and LLM providers are created per request
+// so they pick up new credentials on the next call; anything else needs a restart
+func (app *application) applySecretChanges(changed map[string]string) {
+	changed = managedSecretValues(changed)
+	tlsChanged := false
+	var applied []string
+	for name, value := range changed {
+		os.Setenv(name, value)
+		switch name {
+		case "API_KEYS":
+			app.config.apiKeys.Replace(parseAPIKeys(value))
+		case "GEMINI_API_KEY", "OPENAI_COMPAT_API_KEY":
+			provider, ok := llm.ProviderForAPIKeyEnv(name)
+			if !ok {
+				continue
+			}
+			if err := app.rotateProviderKey(provider, value); err != nil {
+				app.logger.Warn("failed to rotate provider API key", "provider", provider, "error", err)
+				continue
+			}
+		case "TLS_CERT_PEM", "TLS_KEY_PEM":
+			tlsChanged = true
+			continue
+		default:
+			app.logger.Warn("secret changed, restart to apply", "name", name)
+			continue
+		}
+		applied = append(applied, name)
+	}
+
+	if tlsChanged {
+		if app.config.certificates == nil {
+			app.logger.Warn("TLS certificate secret changed, restart to serve it")
+		} else if err := app.config.certificates.Set(os.Getenv("TLS_CERT_PEM"), os.Getenv("TLS_KEY_PEM")); err != nil {
+			// A certificate and key rotated separately may not match until both have been fetched
+			app.logger.Warn("failed to apply rotated TLS certificate, keeping current one", "error", err)
+		} else {
+			applied = append(applied, "TLS_CERT_PEM", "TLS_KEY_PEM")
+		}
+	}
+
+	if len(applied) > 0 {
+		slices.Sort(applied)
+		app.logger.Info("applied rotated secrets", "names", applied)
+	}
+}
+
+// certificateStore holds a TLS certificate that can be replaced without restarting listeners
+// New connections get the current certificate; established ones keep theirs
+type certificateStore struct {
+	cert atomic.Pointer[tls.Certificate]
+}
+
+// newCertificateStore creates a store holding a PEM-encoded certificate and key
+func newCertificateStore(certPEM, keyPEM string) (*certificateStore, error) {
+	store := &certificateStore{}
+	if err := store.Set(certPEM, keyPEM); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// Set replaces the certificate, leaving the current one in place if the new pair is invalid
+func (s *certificateStore) Set(certPEM, keyPEM string) error {
+	cert, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM))
+	if err != nil {
+		return fmt.Errorf("invalid TLS_CERT_PEM/TLS_KEY_PEM: %w", err)
+	}
+	s.cert.Store(&cert)
+	return nil
+}
+
+// GetCertificate returns the current certificate, for tls.Config
+func (s *certificateStore) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return s.cert.Load(), nil
+}
+
+// loadKeyPair loads a listener's certificate and key, from files or from the certificate store
+// when the listener uses the TLS secret
+func loadKeyPair(certFile, keyFile string, store *certificateStore) (tls.Certificate, error) {
+	if certFile == secretTLSCert {
+		if store == nil {
+			return tls.Certificate{}, fmt.Errorf("TLS_CERT_PEM and TLS_KEY_PEM are not set")
+		}
+		return *store.cert.Load(), nil
+	}
+	return tls.LoadX509KeyPair(certFile, keyFile)
+}
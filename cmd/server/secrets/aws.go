@@ -0,0 +1,75 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"microchat.ai/cmd/server/awsauth"
+)
+
+// AWSCredentials are static or temporary AWS credentials
+type AWSCredentials = awsauth.Credentials
+
+// AWSSource reads a secret from AWS Secrets Manager
+// The secret string must be a JSON object, the format the console's key/value editor produces
+type AWSSource struct {
+	Endpoint    string // e.g. https://secretsmanager.eu-west-1.amazonaws.com
+	Region      string
+	SecretID    string // Name or ARN
+	Credentials AWSCredentials
+	Client      *http.Client
+}
+
+// Name describes the source for logs
+func (a *AWSSource) Name() string {
+	return "aws-secretsmanager://" + a.SecretID
+}
+
+// Fetch reads the current version of the secret
+func (a *AWSSource) Fetch(ctx context.Context) (map[string]string, error) {
+	body, err := json.Marshal(map[string]string{"SecretId": a.SecretID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.Endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create secrets manager request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	awsauth.SignV4(req, body, a.Credentials, a.Region, "secretsmanager", time.Now())
+
+	resp, err := a.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("secrets manager request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		errBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("secrets manager returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(errBody)))
+	}
+
+	var result struct {
+		SecretString *string `json:"SecretString"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode secrets manager response: %w", err)
+	}
+	if result.SecretString == nil {
+		return nil, fmt.Errorf("secret %s has no string value (binary secrets aren't supported)", a.SecretID)
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(*result.SecretString), &raw); err != nil {
+		return nil, fmt.Errorf("secret %s must be a JSON object of key/value pairs", a.SecretID)
+	}
+	return decodeValues(raw)
+}
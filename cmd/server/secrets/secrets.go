@@ -0,0 +1,156 @@
+// Package secrets fetches server secrets from HashiCorp Vault or AWS Secrets Manager so they
+// don't have to sit in .env files on disk
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// fetchTimeout bounds each request to a secrets backend
+const fetchTimeout = 10 * time.Second
+
+// Source is a secrets backend holding a flat set of named values
+type Source interface {
+	// Name describes the source for logs, without credentials
+	Name() string
+	// Fetch returns every value in the secret
+	Fetch(ctx context.Context) (map[string]string, error)
+}
+
+// ParseSource builds a source from a URL:
+//
+//	vault://<mount>/<path>           a Vault KV v2 secret, using VAULT_ADDR and VAULT_TOKEN (or VAULT_TOKEN_FILE)
+//	aws-secretsmanager://<secret-id> an AWS Secrets Manager secret holding a JSON object, using AWS_REGION
+//	                                 and AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY/AWS_SESSION_TOKEN
+//
+// getenv supplies the backend's own settings, normally os.Getenv
+func ParseSource(spec string, getenv func(string) string) (Source, error) {
+	parsed, err := url.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secrets source %q: %w", spec, err)
+	}
+
+	switch parsed.Scheme {
+	case "vault":
+		mount, path := parsed.Host, strings.Trim(parsed.Path, "/")
+		if mount == "" || path == "" {
+			return nil, fmt.Errorf("invalid secrets source %q: expected vault://<mount>/<path>", spec)
+		}
+		addr := getenv("VAULT_ADDR")
+		if addr == "" {
+			return nil, fmt.Errorf("VAULT_ADDR is required for a vault secrets source")
+		}
+		if getenv("VAULT_TOKEN") == "" && getenv("VAULT_TOKEN_FILE") == "" {
+			return nil, fmt.Errorf("VAULT_TOKEN or VAULT_TOKEN_FILE is required for a vault secrets source")
+		}
+		return &VaultSource{
+			Addr:      strings.TrimRight(addr, "/"),
+			Token:     getenv("VAULT_TOKEN"),
+			TokenFile: getenv("VAULT_TOKEN_FILE"),
+			Namespace: getenv("VAULT_NAMESPACE"),
+			Mount:     mount,
+			Path:      path,
+			Client:    &http.Client{Timeout: fetchTimeout},
+		}, nil
+
+	case "aws-secretsmanager":
+		secretID := parsed.Host + parsed.Path
+		if secretID == "" {
+			return nil, fmt.Errorf("invalid secrets source %q: expected aws-secretsmanager://<secret-id>", spec)
+		}
+		region := getenv("AWS_REGION")
+		if region == "" {
+			region = getenv("AWS_DEFAULT_REGION")
+		}
+		if region == "" {
+			return nil, fmt.Errorf("AWS_REGION is required for an aws-secretsmanager secrets source")
+		}
+		creds := AWSCredentials{
+			AccessKeyID:     getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    getenv("AWS_SESSION_TOKEN"),
+		}
+		if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+			return nil, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required for an aws-secretsmanager secrets source")
+		}
+		endpoint := getenv("AWS_ENDPOINT_URL_SECRETS_MANAGER")
+		if endpoint == "" {
+			endpoint = getenv("AWS_ENDPOINT_URL")
+		}
+		if endpoint == "" {
+			endpoint = fmt.Sprintf("https://secretsmanager.%s.amazonaws.com", region)
+		}
+		return &AWSSource{
+			Endpoint:    strings.TrimRight(endpoint, "/"),
+			Region:      region,
+			SecretID:    secretID,
+			Credentials: creds,
+			Client:      &http.Client{Timeout: fetchTimeout},
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("invalid secrets source %q: scheme must be vault or aws-secretsmanager", spec)
+	}
+}
+
+// decodeValues converts a JSON object of secret values to strings
+// Numbers and booleans keep their JSON form; nested objects and arrays are rejected
+func decodeValues(raw map[string]json.RawMessage) (map[string]string, error) {
+	values := make(map[string]string, len(raw))
+	for name, value := range raw {
+		var s string
+		if err := json.Unmarshal(value, &s); err == nil {
+			values[name] = s
+			continue
+		}
+		if trimmed := strings.TrimSpace(string(value)); strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+			return nil, fmt.Errorf("secret value %s must be a string", name)
+		}
+		values[name] = string(value)
+	}
+	return values, nil
+}
+
+// Watch fetches the source every interval until ctx is done, calling onChange with the values
+// that were added or changed since the last fetch; initial holds the values already applied
+// Failed fetches are logged and retried on the next interval, keeping the current values
+func Watch(ctx context.Context, source Source, interval time.Duration, initial map[string]string, logger *slog.Logger, onChange func(changed map[string]string)) {
+	current := maps.Clone(initial)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			fetchCtx, cancel := context.WithTimeout(ctx, fetchTimeout)
+			values, err := source.Fetch(fetchCtx)
+			cancel()
+			if err != nil {
+				logger.Warn("failed to refresh secrets, keeping current values", "source", source.Name(), "error", err)
+				continue
+			}
+
+			changed := make(map[string]string)
+			for name, value := range values {
+				if previous, ok := current[name]; !ok || previous != value {
+					changed[name] = value
+				}
+			}
+			current = values
+			if len(changed) > 0 {
+				onChange(changed)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func envFunc(env map[string]string) func(string) string {
+	return func(name string) string { return env[name] }
+}
+
+func TestParseSource(t *testing.T) {
+	vaultEnv := envFunc(map[string]string{"VAULT_ADDR": "https://vault.internal:8200/", "VAULT_TOKEN": "s.token"})
+	source, err := ParseSource("vault://secret/microchat/prod", vaultEnv)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	vault, ok := source.(*VaultSource)
+	if !ok || vault.Addr != "https://vault.internal:8200" || vault.Mount != "secret" || vault.Path != "microchat/prod" {
+		t.Errorf("unexpected vault source: %+v", source)
+	}
+
+	awsEnv := envFunc(map[string]string{"AWS_REGION": "eu-west-1", "AWS_ACCESS_KEY_ID": "AKID", "AWS_SECRET_ACCESS_KEY": "secret"})
+	source, err = ParseSource("aws-secretsmanager://microchat/prod", awsEnv)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	aws, ok := source.(*AWSSource)
+	if !ok || aws.SecretID != "microchat/prod" || aws.Endpoint != "https://secretsmanager.eu-west-1.amazonaws.com" {
+		t.Errorf("unexpected aws source: %+v", source)
+	}
+
+	for spec, env := range map[string]func(string) string{
+		"vault://secret":                     vaultEnv,
+		"vault://secret/microchat":           envFunc(map[string]string{"VAULT_TOKEN": "s.token"}),
+		"aws-secretsmanager://microchat":     envFunc(map[string]string{"AWS_REGION": "eu-west-1"}),
+		"aws-secretsmanager://":              awsEnv,
+		"file:///etc/microchat/secrets.json": vaultEnv,
+	} {
+		if _, err := ParseSource(spec, env); err == nil {
+			t.Errorf("expected error for %q", spec)
+		}
+	}
+}
+
+func TestVaultSourceFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/secret/data/microchat/prod" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("X-Vault-Token") != "s.from-file" {
+			http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"data":{"data":{"API_KEYS":"k1,k2:admin","GEMINI_API_KEY":"gk","PORT":4000},"metadata":{"version":3}}}`))
+	}))
+	defer server.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("s.from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	source := &VaultSource{Addr: server.URL, Token: "s.ignored", TokenFile: tokenFile, Mount: "secret", Path: "microchat/prod", Client: server.Client()}
+	values, err := source.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if values["API_KEYS"] != "k1,k2:admin" || values["GEMINI_API_KEY"] != "gk" || values["PORT"] != "4000" {
+		t.Errorf("unexpected values: %v", values)
+	}
+
+	source.TokenFile = ""
+	if _, err := source.Fetch(context.Background()); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("expected permission error, got %v", err)
+	}
+}
+
+func TestAWSSourceFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(auth, "/eu-west-1/secretsmanager/aws4_request") {
+			http.Error(w, "bad signature", http.StatusForbidden)
+			return
+		}
+		if r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" || r.Header.Get("X-Amz-Security-Token") != "session" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		var req map[string]string
+		if json.Unmarshal(body, &req) != nil || req["SecretId"] != "microchat/prod" {
+			http.Error(w, "bad body", http.StatusBadRequest)
+			return
+		}
+		secret, _ := json.Marshal(map[string]string{"API_KEYS": "k1", "TLS_CERT_PEM": "-----BEGIN CERTIFICATE-----\n..."})
+		json.NewEncoder(w).Encode(map[string]string{"Name": "microchat/prod", "SecretString": string(secret)})
+	}))
+	defer server.Close()
+
+	source := &AWSSource{
+		Endpoint:    server.URL,
+		Region:      "eu-west-1",
+		SecretID:    "microchat/prod",
+		Credentials: AWSCredentials{AccessKeyID: "AKID", SecretAccessKey: "secret", SessionToken: "session"},
+		Client:      server.Client(),
+	}
+	values, err := source.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if values["API_KEYS"] != "k1" || !strings.HasPrefix(values["TLS_CERT_PEM"], "-----BEGIN CERTIFICATE-----\n") {
+		t.Errorf("unexpected values: %v", values)
+	}
+}
+
+// staticSource returns queued results in order, repeating the last one
+type staticSource struct {
+	mu      sync.Mutex
+	results []map[string]string
+}
+
+func (s *staticSource) Name() string { return "static" }
+
+func (s *staticSource) Fetch(ctx context.Context) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := s.results[0]
+	if len(s.results) > 1 {
+		s.results = s.results[1:]
+	}
+	if result == nil {
+		return nil, context.DeadlineExceeded
+	}
+	return result, nil
+}
+
+func TestWatch(t *testing.T) {
+	source := &staticSource{results: []map[string]string{
+		{"API_KEYS": "k1", "GEMINI_API_KEY": "g1"}, // Unchanged
+		nil, // Failed fetch keeps current values
+		{"API_KEYS": "k1,k2", "GEMINI_API_KEY": "g1"},
+	}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := make(chan map[string]string, 1)
+	done := make(chan struct{})
+	go func() {
+		Watch(ctx, source, 5*time.Millisecond, map[string]string{"API_KEYS": "k1", "GEMINI_API_KEY": "g1"},
+			slog.New(slog.NewTextHandler(io.Discard, nil)), func(changed map[string]string) { changes <- changed })
+		close(done)
+	}()
+
+	select {
+	case changed := <-changes:
+		if len(changed) != 1 || changed["API_KEYS"] != "k1,k2" {
+			t.Errorf("expected only API_KEYS to change, got %v", changed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected a change notification")
+	}
+
+	select {
+	case changed := <-changes:
+		t.Errorf("expected no further changes, got %v", changed)
+	case <-time.After(30 * time.Millisecond):
+	}
+
+	cancel()
+	<-done
+}
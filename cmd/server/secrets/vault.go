@@ -0,0 +1,84 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// VaultSource reads a secret from a Vault KV version 2 secrets engine
+type VaultSource struct {
+	Addr      string // Vault server, e.g. https://vault.internal:8200
+	Token     string // Static token; ignored when TokenFile is set
+	TokenFile string // Token written by Vault Agent, re-read on every fetch so renewals are picked up
+	Namespace string // Enterprise namespace, optional
+	Mount     string // KV engine mount, e.g. "secret"
+	Path      string // Secret path within the mount
+	Client    *http.Client
+}
+
+// Name describes the source for logs
+func (v *VaultSource) Name() string {
+	return "vault://" + v.Mount + "/" + v.Path
+}
+
+// Fetch reads the latest version of the secret
+func (v *VaultSource) Fetch(ctx context.Context) (map[string]string, error) {
+	token := v.Token
+	if v.TokenFile != "" {
+		data, err := os.ReadFile(v.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read VAULT_TOKEN_FILE: %w", err)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+
+	endpoint := fmt.Sprintf("%s/v1/%s/data/%s", v.Addr, url.PathEscape(v.Mount), escapePath(v.Path))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create vault request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if v.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.Namespace)
+	}
+
+	resp, err := v.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("vault request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		// Vault error bodies don't contain secret values, but keep them short in logs
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("vault returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var result struct {
+		Data struct {
+			Data map[string]json.RawMessage `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode vault response: %w", err)
+	}
+	if result.Data.Data == nil {
+		return nil, fmt.Errorf("vault secret %s has no data (deleted, or not a KV v2 mount?)", v.Name())
+	}
+	return decodeValues(result.Data.Data)
+}
+
+// escapePath escapes each segment of a slash-separated secret path
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
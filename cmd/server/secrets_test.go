@@ -0,0 +1,130 @@
+package main
+
+import (
+	"crypto/tls"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"microchat.ai/cmd/server/llm"
+)
+
+// readTestCert returns a freshly generated certificate and key as PEM
+func readTestCert(t *testing.T) (string, string) {
+	t.Helper()
+	certFile, keyFile := writeTestCert(t, time.Now().Add(90*24*time.Hour))
+	certPEM, err := os.ReadFile(certFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM, err := os.ReadFile(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(certPEM), string(keyPEM)
+}
+
+func TestLoadSecrets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/secret/data/microchat" || r.Header.Get("X-Vault-Token") != "s.test" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"data":{"data":{"API_KEYS":"vault-key:admin","GEMINI_API_KEY":"vault-gemini","PORT":"9999"}}}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("SECRETS_SOURCE", "vault://secret/microchat")
+	t.Setenv("VAULT_ADDR", server.URL)
+	t.Setenv("VAULT_TOKEN", "s.test")
+	t.Setenv("API_KEYS", "env-key")
+	t.Setenv("GEMINI_API_KEY", "")
+	t.Setenv("PORT", "4000")
+
+	source, values, err := loadSecrets(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("loadSecrets failed: %v", err)
+	}
+	if source == nil || len(values) != 2 {
+		t.Fatalf("Expected the two managed secrets, got %v", values)
+	}
+	if os.Getenv("API_KEYS") != "vault-key:admin" || os.Getenv("GEMINI_API_KEY") != "vault-gemini" {
+		t.Error("Expected secrets to be exported over the environment")
+	}
+	if os.Getenv("PORT") != "4000" {
+		t.Error("Expected values that aren't managed secrets to be ignored")
+	}
+
+	t.Setenv("VAULT_TOKEN", "s.wrong")
+	if _, _, err := loadSecrets(slog.New(slog.NewTextHandler(io.Discard, nil))); err == nil {
+		t.Error("Expected a failed fetch to fail startup")
+	}
+}
+
+func TestApplySecretChanges(t *testing.T) {
+	certPEM, keyPEM := readTestCert(t)
+	newCertPEM, newKeyPEM := readTestCert(t)
+	t.Setenv("API_KEYS", "old-key")
+	t.Setenv("GEMINI_API_KEY", "old-gemini")
+	t.Setenv("TLS_CERT_PEM", certPEM)
+	t.Setenv("TLS_KEY_PEM", keyPEM)
+
+	app, _ := setupTestApplicationWithMock(t)
+	t.Cleanup(func() { llm.ClearAPIKey("gemini") })
+	app.config.apiKeys = newAPIKeySet(parseAPIKeys("old-key"))
+	store, err := newCertificateStore(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("newCertificateStore failed: %v", err)
+	}
+	app.config.certificates = store
+	original, _ := store.GetCertificate(&tls.ClientHelloInfo{})
+
+	app.applySecretChanges(map[string]string{
+		"API_KEYS":       "new-key,new-admin:admin",
+		"GEMINI_API_KEY": "new-gemini",
+		"TLS_CERT_PEM":   newCertPEM,
+		"TLS_KEY_PEM":    newKeyPEM,
+	})
+
+	if _, exists := app.config.apiKeys.Role("old-key"); exists {
+		t.Error("Expected rotated-out key to be rejected")
+	}
+	if role, _ := app.config.apiKeys.Role("new-admin"); role != "admin" {
+		t.Errorf("Expected new admin key, got role %q", role)
+	}
+	if key := llm.APIKey("gemini"); key != "new-gemini" {
+		t.Errorf("Expected provider key to be rotated, got %q", key)
+	}
+	rotated, _ := store.GetCertificate(&tls.ClientHelloInfo{})
+	if rotated == original {
+		t.Error("Expected certificate to be rotated")
+	}
+
+	// A key that doesn't match the certificate keeps the current certificate
+	app.applySecretChanges(map[string]string{"TLS_CERT_PEM": certPEM})
+	if current, _ := store.GetCertificate(&tls.ClientHelloInfo{}); current != rotated {
+		t.Error("Expected mismatched certificate and key to be ignored")
+	}
+}
+
+func TestLoadKeyPair(t *testing.T) {
+	if _, err := loadKeyPair(secretTLSCert, secretTLSKey, nil); err == nil {
+		t.Error("Expected error without a certificate store")
+	}
+
+	certPEM, keyPEM := readTestCert(t)
+	store, err := newCertificateStore(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("newCertificateStore failed: %v", err)
+	}
+	if cert, err := loadKeyPair(secretTLSCert, secretTLSKey, store); err != nil || len(cert.Certificate) == 0 {
+		t.Errorf("Expected certificate from the store, got %v", err)
+	}
+	if _, err := newCertificateStore(certPEM, "not a key"); err == nil {
+		t.Error("Expected invalid key to be rejected")
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"time"
+
+	"microchat.ai/cmd/server/archive"
+)
+
+const (
+	sessionArchiveVersion = 1
+	archivePruneInterval  = time.Hour        // How often archives past ARCHIVE_RETENTION are deleted
+	archiveOpTimeout      = 30 * time.Second // Bounds one archive upload during idle cleanup
+)
+
+// errSessionExists is returned when restoring a session that is still in the store
+var errSessionExists = errors.New("session already exists")
+
+// sessionArchive is the gzip-compressed JSON document an idle session is archived as
+// Message text stays sealed when encryption at rest is enabled, so restoring needs the same key
+type sessionArchive struct {
+	Version    int             `json:"version"`
+	ArchivedAt time.Time       `json:"archived_at"`
+	Session    snapshotSession `json:"session"`
+}
+
+// SetIdleArchiver makes idle cleanup archive each session before removing it
+// A session whose archive fails stays in the store and is retried on the next cleanup
+// It must be called before the store is used
+func (s *SessionStore) SetIdleArchiver(archive func(snapshotSession) error) {
+	s.archiver = archive
+}
+
+// archiveIdleSessions archives and removes idle sessions, uploading without holding shard locks
+// A session that became active again during its upload is kept
+func (s *SessionStore) archiveIdleSessions(now time.Time) int {
+	removed := 0
+	for _, shard := range s.shards {
+		var idle []snapshotSession
+		shard.mu.RLock()
+		for sessionID, session := range shard.sessions {
+			if session.LastActive.Before(now.Add(-s.sessionIdleTimeout(shard, sessionID))) {
+				idle = append(idle, shard.snapshotOf(sessionID))
+			}
+		}
+		shard.mu.RUnlock()
+
+		for _, saved := range idle {
+			if err := s.archiver(saved); err != nil {
+				continue
+			}
+			shard.mu.Lock()
+			if session, exists := shard.sessions[saved.ID]; exists && session.LastActive.Equal(saved.Session.LastActive) {
+				s.removeSession(shard, saved.ID)
+				s.notifyRemoved(saved.ID, saved.OwnerID, "idle_archive")
+				removed++
+			}
+			shard.mu.Unlock()
+		}
+	}
+	return removed
+}
+
+// RestoreSession puts an archived session back in the store under its original owner
+// The session counts as active from now on, so the next cleanup doesn't archive it again
+func (s *SessionStore) RestoreSession(saved snapshotSession) error {
+	if saved.Session == nil {
+		return fmt.Errorf("archived session %s has no messages", saved.ID)
+	}
+	// Archives from older servers hold the raw API key, which mustn't reach the WAL
+	saved.OwnerID = s.savedOwnerID(saved.OwnerID, saved.LegacyOwner)
+	saved.LegacyOwner = ""
+
+	shard := s.shardFor(saved.ID)
+	shard.mu.Lock()
+	if _, exists := shard.validSessions[saved.ID]; exists {
+		shard.mu.Unlock()
+		return errSessionExists
+	}
+
+	session := *saved.Session
+	session.Messages = slices.Clone(saved.Session.Messages)
+	session.LastActive = time.Now().UTC()
+	s.restoreRegistration(shard, saved)
+	s.restoreSession(shard, saved.ID, &session)
+	s.persistRecord(shard, walRecord{
+		Op:          walRestore,
+		SessionID:   saved.ID,
+		OwnerID:     saved.OwnerID,
+		IdleTimeout: saved.IdleTimeout,
+		ClientKey:   saved.ClientKey,
+		Session:     &session,
+	})
+	shard.mu.Unlock()
+
+	s.evictExcessSessions()
+	return nil
+}
+
+// sessionArchiver writes idle sessions to an object store and deletes archives past their retention
+type sessionArchiver struct {
+	store     archive.Store
+	retention time.Duration // 0 keeps archives until they're restored
+	logger    *slog.Logger
+}
+
+// archiveKey is the object a session is archived under
+func archiveKey(sessionID string) string {
+	return sessionID + ".json.gz"
+}
+
+// archive uploads a session; it is the store's idle archiver
+func (a *sessionArchiver) archive(saved snapshotSession) error {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	err := json.NewEncoder(gz).Encode(sessionArchive{
+		Version:    sessionArchiveVersion,
+		ArchivedAt: time.Now().UTC(),
+		Session:    saved,
+	})
+	if err == nil {
+		err = gz.Close()
+	}
+	if err != nil {
+		recordSessionArchiveOp("archive", "error")
+		a.logger.Error("failed to encode session archive", "session_id", saved.ID, "error", err)
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), archiveOpTimeout)
+	defer cancel()
+	if err := a.store.Put(ctx, archiveKey(saved.ID), buf.Bytes()); err != nil {
+		recordSessionArchiveOp("archive", "error")
+		a.logger.Error("failed to archive idle session", "session_id", saved.ID, "store", a.store.Name(), "error", err)
+		return err
+	}
+	recordSessionArchiveOp("archive", "success")
+	a.logger.Debug("archived idle session", "session_id", saved.ID, "bytes", buf.Len())
+	return nil
+}
+
+// load downloads and decodes a session's archive; a missing archive returns archive.ErrNotFound
+func (a *sessionArchiver) load(ctx context.Context, sessionID string) (sessionArchive, error) {
+	var saved sessionArchive
+	data, err := a.store.Get(ctx, archiveKey(sessionID))
+	if err != nil {
+		return saved, err
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return saved, fmt.Errorf("failed to decompress session archive: %w", err)
+	}
+	decoded, err := io.ReadAll(gz)
+	if err != nil {
+		return saved, fmt.Errorf("failed to decompress session archive: %w", err)
+	}
+	if err := json.Unmarshal(decoded, &saved); err != nil {
+		return saved, fmt.Errorf("failed to parse session archive: %w", err)
+	}
+	if saved.Version != sessionArchiveVersion {
+		return saved, fmt.Errorf("unsupported session archive version %d", saved.Version)
+	}
+	if saved.Session.ID != sessionID {
+		return saved, fmt.Errorf("archive holds session %s, not %s", saved.Session.ID, sessionID)
+	}
+	return saved, nil
+}
+
+// prune deletes archives last written before the retention period, returning how many were deleted
+func (a *sessionArchiver) prune(ctx context.Context, now time.Time) (int, error) {
+	if a.retention <= 0 {
+		return 0, nil
+	}
+	objects, err := a.store.List(ctx)
+	if err != nil {
+		recordSessionArchiveOp("prune", "error")
+		return 0, err
+	}
+
+	cutoff := now.Add(-a.retention)
+	deleted := 0
+	for _, object := range objects {
+		if !object.LastModified.Before(cutoff) {
+			continue
+		}
+		if err := a.store.Delete(ctx, object.Key); err != nil {
+			recordSessionArchiveOp("prune", "error")
+			return deleted, err
+		}
+		deleted++
+	}
+	recordSessionArchiveOp("prune", "success")
+	return deleted, nil
+}
+
+// runPruning deletes expired archives every interval until ctx is cancelled
+func (a *sessionArchiver) runPruning(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			deleted, err := a.prune(ctx, time.Now())
+			if err != nil {
+				a.logger.Error("failed to prune session archives", "store", a.store.Name(), "error", err)
+			}
+			if deleted > 0 {
+				a.logger.Info("deleted expired session archives", "count", deleted)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
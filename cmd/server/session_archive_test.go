@@ -0,0 +1,210 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"microchat.ai/cmd/server/archive"
+	pb "microchat.ai/proto"
+)
+
+// newTestArchiver archives to a temporary directory
+func newTestArchiver(t *testing.T, retention time.Duration) *sessionArchiver {
+	t.Helper()
+	return &sessionArchiver{
+		store:     &archive.DirStore{Dir: t.TempDir()},
+		retention: retention,
+		logger:    slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError})),
+	}
+}
+
+func TestSessionStore_ArchivesIdleSessions(t *testing.T) {
+	archiver := newTestArchiver(t, 0)
+	store := NewSessionStore(2*time.Hour, 1000, 100, 100*1024)
+	store.SetIdleArchiver(archiver.archive)
+
+	store.RegisterSessionWithTimeout("idle", "key-a", time.Hour)
+	store.AppendMessage("idle", User, "hello")
+	store.AppendMessage("idle", Assistant, "hi there")
+	store.SetSessionTitle("idle", "Greetings")
+	store.RegisterSession("active", "key-b")
+	store.AppendMessage("active", User, "still here")
+	storedSession(store, "idle").LastActive = time.Now().UTC().Add(-3 * time.Hour)
+
+	if removed := store.CleanupIdleSessions(); removed != 1 {
+		t.Fatalf("Expected 1 archived session, got %d", removed)
+	}
+	if store.IsValidSession("idle") || !store.IsValidSession("active") {
+		t.Fatal("Expected only the idle session to leave the store")
+	}
+
+	saved, err := archiver.load(context.Background(), "idle")
+	if err != nil {
+		t.Fatalf("Failed to load archive: %v", err)
+	}
+	if saved.Session.OwnerID != store.ownerID("key-a") || saved.Session.IdleTimeout != time.Hour || len(saved.Session.Session.Messages) != 2 {
+		t.Errorf("Unexpected archive contents %+v", saved.Session)
+	}
+	f, err := os.Open(filepath.Join(archiver.store.(*archive.DirStore).Dir, archiveKey("idle")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := io.ReadAll(gz); strings.Contains(string(data), "key-a") {
+		t.Error("Expected the archive not to contain the API key")
+	}
+
+	if err := store.RestoreSession(saved.Session); err != nil {
+		t.Fatalf("Failed to restore session: %v", err)
+	}
+	session, exists := store.GetSession("idle")
+	if !exists || len(session.Messages) != 2 || session.Title != "Greetings" {
+		t.Fatalf("Expected restored session with its messages, got %+v", session)
+	}
+	if !store.IsSessionOwner("idle", "key-a") || store.GetIdleTimeout("idle") != time.Hour {
+		t.Error("Expected owner and idle timeout to be restored")
+	}
+	if time.Since(session.LastActive) > time.Minute {
+		t.Error("Expected a restored session to count as active")
+	}
+	if store.CleanupIdleSessions() != 0 {
+		t.Error("Expected a restored session not to be archived again immediately")
+	}
+	if err := store.RestoreSession(saved.Session); !errors.Is(err, errSessionExists) {
+		t.Errorf("Expected restoring an active session to fail, got %v", err)
+	}
+}
+
+func TestSessionStore_ArchiveFailureKeepsSession(t *testing.T) {
+	store := NewSessionStore(2*time.Hour, 1000, 100, 100*1024)
+	store.SetIdleArchiver(func(snapshotSession) error { return errors.New("bucket unavailable") })
+
+	store.RegisterSession("idle", "key-a")
+	store.AppendMessage("idle", User, "hello")
+	storedSession(store, "idle").LastActive = time.Now().UTC().Add(-3 * time.Hour)
+
+	if removed := store.CleanupIdleSessions(); removed != 0 {
+		t.Errorf("Expected no sessions removed when archiving fails, got %d", removed)
+	}
+	if !store.IsValidSession("idle") {
+		t.Error("Expected the session to be kept for the next cleanup")
+	}
+}
+
+func TestSessionStore_RestoredSessionPersists(t *testing.T) {
+	dir := t.TempDir()
+	store := openPersistentStore(t, dir)
+
+	restored := snapshotSession{
+		ID:          "restored",
+		LegacyOwner: "key-a",
+		Session: &Session{
+			Messages:  []Message{{Role: User, Text: "hello", Timestamp: time.Now().UTC().Add(-3 * time.Hour)}},
+			CreatedAt: time.Now().UTC().Add(-3 * time.Hour),
+		},
+	}
+	if err := store.RestoreSession(restored); err != nil {
+		t.Fatalf("Failed to restore session: %v", err)
+	}
+
+	// Simulate a crash: the restore is only in the WAL
+	recovered := openPersistentStore(t, dir)
+	session, exists := recovered.GetSession("restored")
+	if !exists || len(session.Messages) != 1 || session.Messages[0].Text != "hello" {
+		t.Fatalf("Expected restored session to be recovered, got %+v", session)
+	}
+	if !recovered.IsSessionOwner("restored", "key-a") {
+		t.Error("Expected restored owner to be recovered")
+	}
+
+	// The archive came from an older server holding the raw API key, which the WAL gets as an owner ID
+	walFiles, _ := filepath.Glob(filepath.Join(dir, walFilePrefix+"*"+walFileSuffix))
+	for _, path := range walFiles {
+		if data, _ := os.ReadFile(path); strings.Contains(string(data), "key-a") {
+			t.Errorf("Expected %s not to contain the API key", filepath.Base(path))
+		}
+	}
+}
+
+func TestSessionArchiver_Prune(t *testing.T) {
+	archiver := newTestArchiver(t, 24*time.Hour)
+	ctx := context.Background()
+	for _, id := range []string{"old", "new"} {
+		if err := archiver.archive(snapshotSession{ID: id, Session: &Session{}}); err != nil {
+			t.Fatalf("Failed to archive: %v", err)
+		}
+	}
+	dir := archiver.store.(*archive.DirStore).Dir
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, archiveKey("old")), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	deleted, err := archiver.prune(ctx, time.Now())
+	if err != nil || deleted != 1 {
+		t.Fatalf("prune() = %d, %v, want 1 deleted", deleted, err)
+	}
+	if _, err := archiver.load(ctx, "old"); !errors.Is(err, archive.ErrNotFound) {
+		t.Errorf("Expected the expired archive to be deleted, got %v", err)
+	}
+	if _, err := archiver.load(ctx, "new"); err != nil {
+		t.Errorf("Expected the recent archive to be kept, got %v", err)
+	}
+}
+
+func TestRestoreSession(t *testing.T) {
+	app, _ := setupTestApplicationWithMock(t)
+	ctx := context.Background()
+	sessionID := uuid.New().String()
+
+	_, err := app.RestoreSession(ctx, &pb.RestoreSessionRequest{SessionId: sessionID})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition with archiving disabled, got %v", err)
+	}
+
+	app.sessionArchiver = newTestArchiver(t, 0)
+	app.sessionStore.SetIdleArchiver(app.sessionArchiver.archive)
+
+	if _, err := app.RestoreSession(ctx, &pb.RestoreSessionRequest{SessionId: "not-a-uuid"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for a malformed session ID, got %v", err)
+	}
+	if _, err := app.RestoreSession(ctx, &pb.RestoreSessionRequest{SessionId: sessionID}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound without an archive, got %v", err)
+	}
+
+	app.sessionStore.RegisterSession(sessionID, "alice-key")
+	app.sessionStore.AppendMessage(sessionID, User, "hello")
+	storedSession(app.sessionStore, sessionID).LastActive = time.Now().UTC().Add(-3 * time.Hour)
+	app.sessionStore.CleanupIdleSessions()
+
+	resp, err := app.RestoreSession(ctx, &pb.RestoreSessionRequest{SessionId: sessionID})
+	if err != nil {
+		t.Fatalf("Failed to restore session: %v", err)
+	}
+	if resp.OwnerKeyHash != hashAPIKey("alice-key") || resp.MessageCount != 1 || resp.ArchivedAtUnix == 0 {
+		t.Errorf("Unexpected response %+v", resp)
+	}
+	if !app.sessionStore.IsSessionOwner(sessionID, "alice-key") {
+		t.Error("Expected the session to be restored to its owner")
+	}
+
+	// The archive is consumed by the restore
+	if _, err := app.sessionArchiver.load(ctx, sessionID); !errors.Is(err, archive.ErrNotFound) {
+		t.Errorf("Expected the archive to be deleted after restoring, got %v", err)
+	}
+}
@@ -1,9 +1,19 @@
 package main
 
 import (
+	"container/list"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"hash/fnv"
+	"slices"
+	"strings"
 	"sync"
 	"time"
+	"unsafe"
 
 	"microchat.ai/cmd/server/llm"
 )
@@ -15,6 +25,7 @@ const (
 	User Role = iota
 	Assistant
 	System
+	Tool // Tool call record; Text holds a JSON-encoded llm.ToolExchange
 )
 
 // String returns the string representation of a Role
@@ -26,6 +37,8 @@ func (r Role) String() string {
 		return "assistant"
 	case System:
 		return "system"
+	case Tool:
+		return "tool"
 	default:
 		return "unknown"
 	}
@@ -36,133 +49,545 @@ func (r Role) String() string {
 type Message struct {
 	Role      Role      `json:"role"`
 	Text      string    `json:"text"`
+	Sealed    []byte    `json:"sealed,omitempty"` // Encrypted text when encryption at rest is enabled
 	Timestamp time.Time `json:"timestamp"`
 }
 
 // FormattedString returns the message with UTC timestamp for debugging/testing
+// Sealed messages render their ciphertext as base64
 func (m Message) FormattedString() string {
+	text := m.Text
+	if m.Sealed != nil {
+		text = base64.StdEncoding.EncodeToString(m.Sealed)
+	}
 	return fmt.Sprintf("%s [%s UTC]: %s",
 		m.Role.String(),
 		m.Timestamp.UTC().Format("15:04:05"),
-		m.Text)
+		text)
 }
 
 // Session represents a conversation session with messages and last activity timestamp
 // Layer 3: Session management as specified in the architecture document
 type Session struct {
-	Messages   []Message `json:"messages"`
-	LastActive time.Time `json:"last_active"`
+	Messages    []Message `json:"messages"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastActive  time.Time `json:"last_active"`
+	Model       string    `json:"model"`                  // Provider that produced the latest reply
+	TokensUsed  int64     `json:"tokens_used,omitempty"`  // LLM tokens consumed by the session's replies
+	Title       string    `json:"title"`                  // Auto-generated after the first exchange
+	SealedTitle []byte    `json:"sealed_title,omitempty"` // Encrypted title when encryption at rest is enabled
+	sizeBytes   int       // Cached memory usage, kept current by every mutation
+}
+
+// SessionInfo summarizes a session for listings and metrics
+type SessionInfo struct {
+	ID           string
+	Title        string
+	MessageCount int
+	SizeBytes    int
+	LastActive   time.Time
+}
+
+// sessionShardCount is the number of independently locked shards sessions are spread across
+const sessionShardCount = 64
+
+// sessionShard holds the per-session state for the session IDs that hash to it
+type sessionShard struct {
+	mu            sync.RWMutex
+	sessions      map[string]*Session
+	validSessions map[string]string        // Track sessions created via StartSession (session ID -> owner ID, see ownerID)
+	idleTimeouts  map[string]time.Duration // Per-session idle timeouts requested by clients
+	clientKeys    map[string]string        // Fingerprints of client-supplied encryption keys per session
+	seqs          map[string]uint64        // Sequence number of the last persisted mutation per session
 }
 
 // SessionStore provides thread-safe storage for conversation history
+// Sessions are sharded by ID so appends to unrelated sessions don't contend; state spanning
+// sessions (owners, LRU order, counts) is guarded by mu, which is always acquired after a shard lock
 // Layer 3: Session management as specified in the architecture document
 type SessionStore struct {
-	mu                    sync.RWMutex
-	sessions              map[string]*Session
-	validSessions         map[string]bool // Track sessions created via StartSession
+	shards                [sessionShardCount]*sessionShard
+	mu                    sync.Mutex
+	ownerSessions         map[string]map[string]bool // Track session IDs per owner ID
+	sessionOrder          *list.List                 // Session IDs from least to most recently used, for LRU eviction
+	orderIndex            map[string]*list.Element   // Session ID -> element in sessionOrder, for O(1) touch and removal
+	sessionCount          int                        // Sessions with stored messages
+	totalBytes            int                        // Sum of all cached session sizes
+	maxTotalBytes         int                        // Server-wide memory budget across sessions (0 = unlimited)
+	compaction            CompactionPolicy           // How sessions at their limits make room for new messages
+	totalSessionsCreated  int64                      // Track total sessions created
 	idleTimeout           time.Duration
 	maxSessions           int
 	maxMessagesPerSession int
 	maxSessionSizeBytes   int
-	sessionOrder          []string // For LRU eviction
-	totalSessionsCreated  int64    // Track total sessions created
+	cipher                *messageCipher                        // Encrypts message text at rest when set
+	ownerKey              []byte                                // Keys the hash in owner IDs, derived from the at-rest key when set
+	persist               *sessionPersistence                   // Logs mutations to disk for crash recovery when set
+	archiver              func(snapshotSession) error           // Archives idle sessions before cleanup removes them when set
+	onRemoved             func(sessionID, owner, reason string) // Called when the server evicts a session
 }
 
 // NewSessionStore creates a new SessionStore instance
 func NewSessionStore(idleTimeout time.Duration, maxSessions, maxMessagesPerSession, maxSessionSizeBytes int) *SessionStore {
-	return &SessionStore{
-		sessions:              make(map[string]*Session),
-		validSessions:         make(map[string]bool),
+	s := &SessionStore{
+		ownerSessions:         make(map[string]map[string]bool),
+		sessionOrder:          list.New(),
+		orderIndex:            make(map[string]*list.Element),
 		idleTimeout:           idleTimeout,
 		maxSessions:           maxSessions,
 		maxMessagesPerSession: maxMessagesPerSession,
 		maxSessionSizeBytes:   maxSessionSizeBytes,
-		sessionOrder:          make([]string, 0),
 	}
+	for i := range s.shards {
+		s.shards[i] = &sessionShard{
+			sessions:      make(map[string]*Session),
+			validSessions: make(map[string]string),
+			idleTimeouts:  make(map[string]time.Duration),
+			clientKeys:    make(map[string]string),
+			seqs:          make(map[string]uint64),
+		}
+	}
+	return s
+}
+
+// shardFor returns the shard holding a session
+func (s *SessionStore) shardFor(sessionID string) *sessionShard {
+	h := fnv.New32a()
+	h.Write([]byte(sessionID))
+	return s.shards[h.Sum32()%sessionShardCount]
 }
 
 // RegisterSession registers a session ID as valid (created via StartSession)
-func (s *SessionStore) RegisterSession(sessionID string) {
+// and binds it to the API key that created it
+func (s *SessionStore) RegisterSession(sessionID string, owner string) {
+	s.RegisterSessionWithTimeout(sessionID, owner, 0)
+}
+
+// RegisterSessionWithTimeout registers a session with its own idle timeout
+// A zero timeout uses the store's default idle timeout
+func (s *SessionStore) RegisterSessionWithTimeout(sessionID string, apiKey string, idleTimeout time.Duration) {
+	owner := s.ownerID(apiKey)
+	shard := s.shardFor(sessionID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	shard.validSessions[sessionID] = owner
+	if idleTimeout > 0 {
+		shard.idleTimeouts[sessionID] = idleTimeout
+	}
+
 	s.mu.Lock()
-	defer s.mu.Unlock()
-	s.validSessions[sessionID] = true
+	if s.ownerSessions[owner] == nil {
+		s.ownerSessions[owner] = make(map[string]bool)
+	}
+	s.ownerSessions[owner][sessionID] = true
 	s.totalSessionsCreated++
+	// Logged before unlocking so Snapshot sees the count and the WAL agree on the registration
+	s.persistRecord(shard, walRecord{Op: walRegister, SessionID: sessionID, OwnerID: owner, IdleTimeout: idleTimeout})
+	s.mu.Unlock()
+}
+
+// removeSession deletes a session from all tracking structures
+// Caller must hold the shard's write lock
+func (s *SessionStore) removeSession(shard *sessionShard, sessionID string) {
+	owner, registered := shard.validSessions[sessionID]
+	session, hasMessages := shard.sessions[sessionID]
+	delete(shard.sessions, sessionID)
+	delete(shard.validSessions, sessionID)
+	delete(shard.idleTimeouts, sessionID)
+	delete(shard.clientKeys, sessionID)
+	delete(shard.seqs, sessionID)
+	s.persistRecord(shard, walRecord{Op: walRemove, SessionID: sessionID})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if registered {
+		delete(s.ownerSessions[owner], sessionID)
+		if len(s.ownerSessions[owner]) == 0 {
+			delete(s.ownerSessions, owner)
+		}
+	}
+	if hasMessages {
+		s.sessionCount--
+		s.totalBytes -= session.sizeBytes
+		s.removeFromOrder(sessionID)
+	}
+}
+
+// SetMemoryBudget caps the memory used by all sessions together
+// Least recently used sessions are evicted while the budget is exceeded; 0 disables the cap
+func (s *SessionStore) SetMemoryBudget(maxTotalBytes int) {
+	s.mu.Lock()
+	s.maxTotalBytes = maxTotalBytes
+	s.mu.Unlock()
+
+	s.evictExcessSessions()
+}
+
+// GetMemoryUsage returns the memory used by all sessions and the configured budget (0 = unlimited)
+func (s *SessionStore) GetMemoryUsage() (totalBytes int, maxTotalBytes int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.totalBytes, s.maxTotalBytes
+}
+
+// SessionSizes returns the message count and memory usage of every active session, in no
+// particular order
+func (s *SessionStore) SessionSizes() (messageCounts []int, sizes []int) {
+	for _, shard := range s.shards {
+		shard.mu.RLock()
+		for sessionID := range shard.validSessions {
+			count, size := 0, 0
+			if session, exists := shard.sessions[sessionID]; exists {
+				count, size = len(session.Messages), session.sizeBytes
+			}
+			messageCounts = append(messageCounts, count)
+			sizes = append(sizes, size)
+		}
+		shard.mu.RUnlock()
+	}
+	return messageCounts, sizes
+}
+
+// SetClientKeyFingerprint marks a session as encrypted with a client-supplied key
+// Messages in such sessions can only be stored or decrypted with that key
+func (s *SessionStore) SetClientKeyFingerprint(sessionID string, fingerprint string) {
+	shard := s.shardFor(sessionID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	shard.clientKeys[sessionID] = fingerprint
+	s.persistRecord(shard, walRecord{Op: walClientKey, SessionID: sessionID, ClientKey: fingerprint})
+}
+
+// GetClientKeyFingerprint returns the client key fingerprint for a session, or "" if none
+func (s *SessionStore) GetClientKeyFingerprint(sessionID string) string {
+	shard := s.shardFor(sessionID)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+	return shard.clientKeys[sessionID]
+}
+
+// cipherFor returns the cipher protecting a session's messages: the client's key for
+// client-encrypted sessions (nil if not supplied), otherwise the server's at-rest cipher
+// Caller must hold the shard lock
+func (s *SessionStore) cipherFor(shard *sessionShard, sessionID string, clientCipher *messageCipher) *messageCipher {
+	if _, exists := shard.clientKeys[sessionID]; exists {
+		return clientCipher
+	}
+	return s.cipher
+}
+
+// GetIdleTimeout returns the idle timeout that applies to a session
+func (s *SessionStore) GetIdleTimeout(sessionID string) time.Duration {
+	shard := s.shardFor(sessionID)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+	return s.sessionIdleTimeout(shard, sessionID)
+}
+
+// sessionIdleTimeout returns the session's idle timeout, falling back to the store default
+// Caller must hold the shard lock
+func (s *SessionStore) sessionIdleTimeout(shard *sessionShard, sessionID string) time.Duration {
+	if timeout, exists := shard.idleTimeouts[sessionID]; exists {
+		return timeout
+	}
+	return s.idleTimeout
+}
+
+// SessionExpiresIn returns how long until a session is removed for being idle
+// Returns false for sessions without messages, which aren't subject to idle cleanup
+func (s *SessionStore) SessionExpiresIn(sessionID string) (time.Duration, bool) {
+	shard := s.shardFor(sessionID)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	session, exists := shard.sessions[sessionID]
+	if !exists {
+		return 0, false
+	}
+	remaining := time.Until(session.LastActive.Add(s.sessionIdleTimeout(shard, sessionID)))
+	return max(remaining, 0), true
+}
+
+// TouchSession marks a session as active without adding a message, postponing idle cleanup
+// Returns false if the session has no messages
+func (s *SessionStore) TouchSession(sessionID string) bool {
+	shard := s.shardFor(sessionID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	session, exists := shard.sessions[sessionID]
+	if !exists {
+		return false
+	}
+	session.LastActive = time.Now().UTC()
+	s.mu.Lock()
+	s.updateSessionOrder(sessionID)
+	s.mu.Unlock()
+	return true
 }
 
 // IsValidSession checks if a session ID was created via StartSession
 func (s *SessionStore) IsValidSession(sessionID string) bool {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	return s.validSessions[sessionID]
+	shard := s.shardFor(sessionID)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+	_, exists := shard.validSessions[sessionID]
+	return exists
 }
 
-// getSessionSize calculates the memory usage of a session in bytes
-func (s *SessionStore) getSessionSize(session *Session) int {
-	size := 0
+// IsSessionOwner checks if a session was created by the given API key
+func (s *SessionStore) IsSessionOwner(sessionID string, apiKey string) bool {
+	shard := s.shardFor(sessionID)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+	owner, exists := shard.validSessions[sessionID]
+	return exists && hmac.Equal([]byte(owner), []byte(s.ownerID(apiKey)))
+}
+
+// ownerID identifies the API key owning a session, so the key itself is never persisted or archived
+// The ID is the key's short hash, as in metrics and events, followed by an HMAC-SHA256 of the key
+// keyed from SESSION_ENCRYPTION_KEY when it is set
+func (s *SessionStore) ownerID(apiKey string) string {
+	mac := hmac.New(sha256.New, s.ownerKey)
+	mac.Write([]byte(apiKey))
+	return hashAPIKey(apiKey) + "." + hex.EncodeToString(mac.Sum(nil))
+}
+
+// ownerKeyHash returns the short key hash an owner ID starts with
+func ownerKeyHash(owner string) string {
+	keyHash, _, _ := strings.Cut(owner, ".")
+	return keyHash
+}
+
+// savedOwnerID returns a persisted session's owner ID, hashing the raw API key older servers stored
+func (s *SessionStore) savedOwnerID(ownerID, legacyOwner string) string {
+	if legacyOwner != "" {
+		return s.ownerID(legacyOwner)
+	}
+	return ownerID
+}
+
+// EnableEncryption encrypts message text at rest with AES-256-GCM using the given key
+// Must be called before any messages are stored
+func (s *SessionStore) EnableEncryption(key []byte) error {
+	c, err := newMessageCipher(key)
+	if err != nil {
+		return err
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("microchat session owner"))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cipher = c
+	s.ownerKey = mac.Sum(nil)
+	return nil
+}
+
+// Fixed in-memory sizes of the stored structs, including string and slice headers
+var (
+	messageStructSize = int(unsafe.Sizeof(Message{}))
+	sessionStructSize = int(unsafe.Sizeof(Session{}))
+)
+
+// messageDataSize returns the heap memory referenced by a message beyond its struct
+// The struct itself is accounted for by the capacity of the session's message slice
+func messageDataSize(msg Message) int {
+	return len(msg.Text) + cap(msg.Sealed)
+}
+
+// computeSessionSize calculates a session's memory usage from scratch: the session struct,
+// the full capacity of its message slice, message text and ciphertext, and metadata strings
+func computeSessionSize(session *Session) int {
+	size := sessionStructSize + cap(session.Messages)*messageStructSize
 	for _, msg := range session.Messages {
-		size += len(msg.Text) + len(msg.Role.String()) + 24 // approximate timestamp size
+		size += messageDataSize(msg)
 	}
-	return size
+	return size + len(session.Model) + len(session.Title) + cap(session.SealedTitle)
 }
 
-// evictOldestSession removes the oldest session to make room for new ones
-func (s *SessionStore) evictOldestSession() {
-	if len(s.sessionOrder) == 0 {
-		return
+// openTitle returns a session's title, decrypting it if encryption at rest is enabled
+func (s *SessionStore) openTitle(sessionID string, session *Session) string {
+	if session.SealedTitle == nil || s.cipher == nil {
+		return session.Title
+	}
+	title, err := s.cipher.open(sessionID, session.SealedTitle)
+	if err != nil {
+		return ""
 	}
+	return title
+}
 
-	oldestSessionID := s.sessionOrder[0]
-	s.sessionOrder = s.sessionOrder[1:]
+// getSessionSize returns the cached memory usage of a session in bytes
+func (s *SessionStore) getSessionSize(session *Session) int {
+	return session.sizeBytes
+}
+
+// openMessages returns a copy of stored messages with encrypted text decrypted
+// Messages stay sealed when no cipher is available (client-encrypted sessions read without the key)
+func openMessages(sessionID string, messages []Message, c *messageCipher) []Message {
+	result := make([]Message, len(messages))
+	copy(result, messages)
+
+	for i := range result {
+		if result[i].Sealed == nil || c == nil {
+			continue
+		}
+		text, err := c.open(sessionID, result[i].Sealed)
+		if err != nil {
+			text = "[message could not be decrypted]"
+		}
+		result[i].Text = text
+		result[i].Sealed = nil
+	}
+
+	return result
+}
+
+// evictExcessSessions removes least recently used sessions until the store is within maxSessions
+// and the memory budget; the most recently used session is never evicted for memory
+// Must be called without holding any lock, since the oldest session may live in any shard
+func (s *SessionStore) evictExcessSessions() {
+	for {
+		s.mu.Lock()
+		oldest := s.sessionOrder.Front()
+		reason := ""
+		switch {
+		case oldest == nil:
+		case s.sessionCount > s.maxSessions:
+			reason = "lru_eviction"
+		case s.maxTotalBytes > 0 && s.totalBytes > s.maxTotalBytes && s.sessionOrder.Len() > 1:
+			reason = "memory_pressure"
+		}
+		if reason == "" {
+			s.mu.Unlock()
+			return
+		}
+		oldestSessionID := oldest.Value.(string)
+		s.mu.Unlock()
+
+		s.evictSession(oldestSessionID, reason)
+	}
+}
+
+// evictSession removes a session if it is still the least recently used one
+// It may have been touched between choosing and locking its shard, in which case the caller picks again
+func (s *SessionStore) evictSession(sessionID string, reason string) {
+	shard := s.shardFor(sessionID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	s.mu.Lock()
+	oldest := s.sessionOrder.Front()
+	stillOldest := oldest != nil && oldest.Value.(string) == sessionID
+	s.mu.Unlock()
+
+	if stillOldest {
+		owner := shard.validSessions[sessionID]
+		s.removeSession(shard, sessionID)
+		recordSessionsRemoved(reason, 1)
+		s.notifyRemoved(sessionID, owner, reason)
+	}
+}
 
-	delete(s.sessions, oldestSessionID)
-	delete(s.validSessions, oldestSessionID)
+// OnSessionRemoved sets a callback for sessions the server evicts for idleness or memory pressure,
+// with the owner ID (see ownerID) and the removal reason
+// It is called with the session's shard locked, so it must be quick and must not use the store
+// It must be set before the store is used
+func (s *SessionStore) OnSessionRemoved(fn func(sessionID, owner, reason string)) {
+	s.onRemoved = fn
+}
+
+// notifyRemoved reports an evicted session to the removal callback, if any
+func (s *SessionStore) notifyRemoved(sessionID, owner, reason string) {
+	if s.onRemoved != nil {
+		s.onRemoved(sessionID, owner, reason)
+	}
 }
 
 // updateSessionOrder moves a session to the end (most recently used)
+// Caller must hold s.mu
 func (s *SessionStore) updateSessionOrder(sessionID string) {
-	// Remove from current position
-	for i, id := range s.sessionOrder {
-		if id == sessionID {
-			s.sessionOrder = append(s.sessionOrder[:i], s.sessionOrder[i+1:]...)
-			break
-		}
+	if elem, exists := s.orderIndex[sessionID]; exists {
+		s.sessionOrder.MoveToBack(elem)
+		return
+	}
+	s.orderIndex[sessionID] = s.sessionOrder.PushBack(sessionID)
+}
+
+// removeFromOrder drops a session from the LRU order
+// Caller must hold s.mu
+func (s *SessionStore) removeFromOrder(sessionID string) {
+	if elem, exists := s.orderIndex[sessionID]; exists {
+		s.sessionOrder.Remove(elem)
+		delete(s.orderIndex, sessionID)
 	}
-	// Add to end
-	s.sessionOrder = append(s.sessionOrder, sessionID)
 }
 
 // AppendMessage adds a structured message to the session history
 // Only works with valid session IDs and enforces limits
 func (s *SessionStore) AppendMessage(sessionID string, role Role, text string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	return s.AppendMessageWithKey(sessionID, role, text, nil)
+}
+
+// AppendMessageWithKey adds a message, encrypting it with the client's key for client-encrypted sessions
+func (s *SessionStore) AppendMessageWithKey(sessionID string, role Role, text string, clientCipher *messageCipher) error {
+	shard := s.shardFor(sessionID)
+	shard.mu.Lock()
+	created, err := s.appendMessage(shard, sessionID, role, text, clientCipher)
+	shard.mu.Unlock()
+
+	// A new session or message may push the store over its limits; evict once the shard lock is released
+	if created || err == nil {
+		s.evictExcessSessions()
+	}
+	return err
+}
 
+// appendMessage stores a message and reports whether it created the session
+// Caller must hold the shard's write lock
+func (s *SessionStore) appendMessage(shard *sessionShard, sessionID string, role Role, text string, clientCipher *messageCipher) (bool, error) {
 	// Check if session ID is valid (was created via StartSession)
-	if !s.validSessions[sessionID] {
-		return fmt.Errorf("invalid session ID: session not found or not properly created")
+	if _, exists := shard.validSessions[sessionID]; !exists {
+		return false, fmt.Errorf("invalid session ID: session not found or not properly created")
 	}
 
+	// Client-encrypted sessions must never store plaintext
+	_, clientEncrypted := shard.clientKeys[sessionID]
+	if clientEncrypted && clientCipher == nil {
+		return false, fmt.Errorf("session requires the client encryption key")
+	}
+	c := s.cipherFor(shard, sessionID, clientCipher)
+
 	now := time.Now().UTC()
 
 	// Create session if it doesn't exist
-	if s.sessions[sessionID] == nil {
-		// Check if we need to evict sessions to stay under the limit
-		for len(s.sessions) >= s.maxSessions {
-			s.evictOldestSession()
-		}
-
-		s.sessions[sessionID] = &Session{
+	created := false
+	if shard.sessions[sessionID] == nil {
+		session := &Session{
 			Messages:   make([]Message, 0),
+			CreatedAt:  now,
 			LastActive: now,
 		}
-		s.sessionOrder = append(s.sessionOrder, sessionID)
+		session.sizeBytes = computeSessionSize(session)
+		shard.sessions[sessionID] = session
+		s.mu.Lock()
+		s.sessionCount++
+		s.totalBytes += session.sizeBytes
+		s.updateSessionOrder(sessionID)
+		s.mu.Unlock()
+		created = true
 	}
 
-	session := s.sessions[sessionID]
+	session := shard.sessions[sessionID]
 
-	// Check message limit per session
-	if len(session.Messages) >= s.maxMessagesPerSession {
-		return fmt.Errorf("session message limit exceeded: maximum %d messages per session", s.maxMessagesPerSession)
+	// Check message limit per session, compacting older turns if the policy allows
+	for len(session.Messages) >= s.maxMessagesPerSession {
+		if !s.dropOldestTurn(shard, sessionID, session) {
+			incrementMessagesRejected("message_count")
+			return created, fmt.Errorf("session message limit exceeded: maximum %d messages per session", s.maxMessagesPerSession)
+		}
 	}
 
 	// Create new message
@@ -172,38 +597,275 @@ func (s *SessionStore) AppendMessage(sessionID string, role Role, text string) e
 		Timestamp: now,
 	}
 
-	// Check session size limit
-	newSessionSize := s.getSessionSize(session) + len(text) + len(role.String()) + 24
-	if newSessionSize > s.maxSessionSizeBytes {
-		return fmt.Errorf("session size limit exceeded: maximum %d bytes per session", s.maxSessionSizeBytes)
+	// Encrypt message text at rest if enabled
+	if c != nil {
+		sealed, err := c.seal(sessionID, text)
+		if err != nil {
+			return created, fmt.Errorf("failed to encrypt message: %w", err)
+		}
+		message.Text = ""
+		message.Sealed = sealed
+	}
+
+	// Check session size limit, including any growth of the message slice's backing array,
+	// compacting older turns if the policy allows
+	messages := append(session.Messages, message)
+	newSessionSize := session.sizeBytes + messageDataSize(message) + (cap(messages)-cap(session.Messages))*messageStructSize
+	for newSessionSize > s.maxSessionSizeBytes {
+		if cap(messages) == cap(session.Messages) {
+			messages[len(messages)-1] = Message{} // Don't keep the rejected text reachable from the spare capacity
+		}
+		// Don't drop history for a message that wouldn't fit even on its own
+		if sizeWithOnly(session, message) > s.maxSessionSizeBytes || !s.dropOldestTurn(shard, sessionID, session) {
+			incrementMessagesRejected("session_size")
+			return created, fmt.Errorf("session size limit exceeded: maximum %d bytes per session", s.maxSessionSizeBytes)
+		}
+		messages = append(session.Messages, message)
+		newSessionSize = session.sizeBytes + messageDataSize(message) + (cap(messages)-cap(session.Messages))*messageStructSize
 	}
 
 	// Add message to session
-	session.Messages = append(session.Messages, message)
+	session.Messages = messages
+	s.mu.Lock()
+	s.totalBytes += newSessionSize - session.sizeBytes
+	s.updateSessionOrder(sessionID) // Update LRU order
+	s.mu.Unlock()
+	session.sizeBytes = newSessionSize
 	session.LastActive = now
+	s.persistRecord(shard, walRecord{Op: walAppend, SessionID: sessionID, Message: &message})
 
-	// Update LRU order
-	s.updateSessionOrder(sessionID)
+	return created, nil
+}
 
-	return nil
+// resizeSession adjusts a session's cached size and the store total by delta bytes
+// Caller must hold the shard's write lock
+func (s *SessionStore) resizeSession(session *Session, delta int) {
+	session.sizeBytes += delta
+	s.mu.Lock()
+	s.totalBytes += delta
+	s.mu.Unlock()
 }
 
 // GetMessages returns all structured messages for a session
 // Returns empty slice if session doesn't exist
 func (s *SessionStore) GetMessages(sessionID string) []Message {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	return s.GetMessagesWithKey(sessionID, nil)
+}
 
-	if session, exists := s.sessions[sessionID]; exists {
+// GetMessagesWithKey returns all messages, decrypting client-encrypted sessions with the client's key
+// Without the key, messages of client-encrypted sessions are returned sealed
+func (s *SessionStore) GetMessagesWithKey(sessionID string, clientCipher *messageCipher) []Message {
+	shard := s.shardFor(sessionID)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	if session, exists := shard.sessions[sessionID]; exists {
 		// Return a copy to prevent external modification
-		result := make([]Message, len(session.Messages))
-		copy(result, session.Messages)
-		return result
+		return openMessages(sessionID, session.Messages, s.cipherFor(shard, sessionID, clientCipher))
 	}
 
 	return []Message{}
 }
 
+// GetMessageCount returns the number of messages stored for a session
+func (s *SessionStore) GetMessageCount(sessionID string) int {
+	shard := s.shardFor(sessionID)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	if session, exists := shard.sessions[sessionID]; exists {
+		return len(session.Messages)
+	}
+	return 0
+}
+
+// GetSession returns a copy of a session including its messages and metadata
+func (s *SessionStore) GetSession(sessionID string) (Session, bool) {
+	return s.GetSessionWithKey(sessionID, nil)
+}
+
+// GetSessionWithKey returns a copy of a session, decrypting client-encrypted sessions with the client's key
+func (s *SessionStore) GetSessionWithKey(sessionID string, clientCipher *messageCipher) (Session, bool) {
+	shard := s.shardFor(sessionID)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	session, exists := shard.sessions[sessionID]
+	if !exists {
+		return Session{}, false
+	}
+
+	result := *session
+	result.Messages = openMessages(sessionID, session.Messages, s.cipherFor(shard, sessionID, clientCipher))
+	result.Title = s.openTitle(sessionID, session)
+	result.SealedTitle = nil
+	return result, true
+}
+
+// SetSessionModel records the provider used for the latest reply in a session
+func (s *SessionStore) SetSessionModel(sessionID string, model string) {
+	shard := s.shardFor(sessionID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if session, exists := shard.sessions[sessionID]; exists {
+		s.resizeSession(session, len(model)-len(session.Model))
+		session.Model = model
+		s.persistRecord(shard, walRecord{Op: walModel, SessionID: sessionID, Model: model})
+	}
+}
+
+// AddSessionTokens adds LLM tokens consumed by a reply to a session's total and returns the new total
+func (s *SessionStore) AddSessionTokens(sessionID string, tokens int64) int64 {
+	shard := s.shardFor(sessionID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	session, exists := shard.sessions[sessionID]
+	if !exists {
+		return 0
+	}
+	session.TokensUsed += tokens
+	s.persistRecord(shard, walRecord{Op: walTokens, SessionID: sessionID, Tokens: session.TokensUsed})
+	return session.TokensUsed
+}
+
+// GetSessionTokens returns the LLM tokens a session has consumed
+func (s *SessionStore) GetSessionTokens(sessionID string) int64 {
+	shard := s.shardFor(sessionID)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	if session, exists := shard.sessions[sessionID]; exists {
+		return session.TokensUsed
+	}
+	return 0
+}
+
+// SetSessionTitle stores a generated title for a session
+// Returns an error if the title can't be encrypted, leaving the session untitled
+func (s *SessionStore) SetSessionTitle(sessionID string, title string) error {
+	shard := s.shardFor(sessionID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	session, exists := shard.sessions[sessionID]
+	if !exists {
+		return nil
+	}
+	if s.cipher != nil {
+		sealed, err := s.cipher.seal(sessionID, title)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt session title: %w", err)
+		}
+		s.resizeSession(session, cap(sealed)-cap(session.SealedTitle))
+		session.SealedTitle = sealed
+		s.persistRecord(shard, walRecord{Op: walTitle, SessionID: sessionID, SealedTitle: sealed})
+		return nil
+	}
+	s.resizeSession(session, len(title)-len(session.Title))
+	session.Title = title
+	s.persistRecord(shard, walRecord{Op: walTitle, SessionID: sessionID, Title: title})
+	return nil
+}
+
+// DeleteMessages removes count messages starting at index, shifting later messages down
+func (s *SessionStore) DeleteMessages(sessionID string, index, count int) error {
+	shard := s.shardFor(sessionID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	return s.deleteMessages(shard, sessionID, index, count)
+}
+
+// TruncateAtUserMessage removes a user message and every message after it, so it can be replaced
+func (s *SessionStore) TruncateAtUserMessage(sessionID string, index int) error {
+	shard := s.shardFor(sessionID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if err := shard.checkUserMessage(sessionID, index); err != nil {
+		return err
+	}
+	return s.deleteMessages(shard, sessionID, index, len(shard.sessions[sessionID].Messages)-index)
+}
+
+// CheckUserMessage reports whether TruncateAtUserMessage could replace the message at index
+func (s *SessionStore) CheckUserMessage(sessionID string, index int) error {
+	shard := s.shardFor(sessionID)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+	return shard.checkUserMessage(sessionID, index)
+}
+
+// checkUserMessage checks that index holds a user message
+// Caller must hold the shard's lock
+func (shard *sessionShard) checkUserMessage(sessionID string, index int) error {
+	session, exists := shard.sessions[sessionID]
+	if !exists || index < 0 || index >= len(session.Messages) {
+		return fmt.Errorf("message index out of range")
+	}
+	if session.Messages[index].Role != User {
+		return fmt.Errorf("only user messages can be edited")
+	}
+	return nil
+}
+
+// RollbackTurn removes the user message appended at or after since, along with the tool calls
+// stored for it, undoing a turn whose reply was abandoned
+// Nothing is removed once another reply follows the message, since that turn completed
+// Returns the number of messages removed
+func (s *SessionStore) RollbackTurn(sessionID string, since time.Time) int {
+	shard := s.shardFor(sessionID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	session, exists := shard.sessions[sessionID]
+	if !exists {
+		return 0
+	}
+	for i := len(session.Messages) - 1; i >= 0; i-- {
+		message := session.Messages[i]
+		if message.Timestamp.Before(since) {
+			return 0
+		}
+		switch message.Role {
+		case Tool:
+			continue
+		case User:
+			count := len(session.Messages) - i
+			if err := s.deleteMessages(shard, sessionID, i, count); err != nil {
+				return 0
+			}
+			return count
+		}
+		return 0
+	}
+	return 0
+}
+
+// deleteMessages removes a range of messages, updating the session's size and LRU position
+// Caller must hold the shard's write lock
+func (s *SessionStore) deleteMessages(shard *sessionShard, sessionID string, index, count int) error {
+	session, exists := shard.sessions[sessionID]
+	if !exists {
+		return fmt.Errorf("session has no messages")
+	}
+	if index < 0 || count <= 0 || index+count > len(session.Messages) {
+		return fmt.Errorf("message range out of bounds: session has %d messages", len(session.Messages))
+	}
+
+	// The backing array is kept, so only the removed messages' data is freed
+	session.Messages = slices.Delete(session.Messages, index, index+count)
+	s.resizeSession(session, computeSessionSize(session)-session.sizeBytes)
+	session.LastActive = time.Now().UTC()
+	s.mu.Lock()
+	s.updateSessionOrder(sessionID)
+	s.mu.Unlock()
+
+	s.persistRecord(shard, walRecord{Op: walDelete, SessionID: sessionID, Index: index, Count: count})
+	return nil
+}
+
 // GetFormattedMessages returns all messages for a session as formatted strings
 // For backward compatibility with Layer 1 format
 func (s *SessionStore) GetFormattedMessages(sessionID string) []string {
@@ -217,47 +879,64 @@ func (s *SessionStore) GetFormattedMessages(sessionID string) []string {
 
 // GetSessionCount returns the number of active sessions
 func (s *SessionStore) GetSessionCount() int {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	return len(s.sessions)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sessionCount
 }
 
 // GetTotalSessionsCreated returns the total number of sessions created
 func (s *SessionStore) GetTotalSessionsCreated() int64 {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.totalSessionsCreated
 }
 
 // GetAllSessionsInfo returns info about all active sessions
-func (s *SessionStore) GetAllSessionsInfo() []struct {
-	ID           string
-	MessageCount int
-	SizeBytes    int
-	LastActive   string
-} {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	result := make([]struct {
-		ID           string
-		MessageCount int
-		SizeBytes    int
-		LastActive   string
-	}, 0, len(s.sessions))
-
-	for sessionID, session := range s.sessions {
-		result = append(result, struct {
-			ID           string
-			MessageCount int
-			SizeBytes    int
-			LastActive   string
-		}{
-			ID:           sessionID,
-			MessageCount: len(session.Messages),
-			SizeBytes:    s.getSessionSize(session),
-			LastActive:   session.LastActive.UTC().Format("2006-01-02T15:04:05Z"),
-		})
+func (s *SessionStore) GetAllSessionsInfo() []SessionInfo {
+	result := make([]SessionInfo, 0, s.GetSessionCount())
+	for _, shard := range s.shards {
+		shard.mu.RLock()
+		for sessionID, session := range shard.sessions {
+			result = append(result, SessionInfo{
+				ID:           sessionID,
+				Title:        s.openTitle(sessionID, session),
+				MessageCount: len(session.Messages),
+				SizeBytes:    s.getSessionSize(session),
+				LastActive:   session.LastActive,
+			})
+		}
+		shard.mu.RUnlock()
+	}
+
+	return result
+}
+
+// GetSessionsInfoForOwner returns info about all sessions created by an API key,
+// including sessions that have been started but have no messages yet
+func (s *SessionStore) GetSessionsInfoForOwner(apiKey string) []SessionInfo {
+	owner := s.ownerID(apiKey)
+	s.mu.Lock()
+	sessionIDs := make([]string, 0, len(s.ownerSessions[owner]))
+	for sessionID := range s.ownerSessions[owner] {
+		sessionIDs = append(sessionIDs, sessionID)
+	}
+	s.mu.Unlock()
+
+	result := make([]SessionInfo, 0, len(sessionIDs))
+	for _, sessionID := range sessionIDs {
+		shard := s.shardFor(sessionID)
+		shard.mu.RLock()
+		if _, registered := shard.validSessions[sessionID]; registered {
+			info := SessionInfo{ID: sessionID}
+			if session, exists := shard.sessions[sessionID]; exists {
+				info.Title = s.openTitle(sessionID, session)
+				info.MessageCount = len(session.Messages)
+				info.SizeBytes = s.getSessionSize(session)
+				info.LastActive = session.LastActive
+			}
+			result = append(result, info)
+		}
+		shard.mu.RUnlock()
 	}
 
 	return result
@@ -265,44 +944,62 @@ func (s *SessionStore) GetAllSessionsInfo() []struct {
 
 // GetMessagesAsLLMFormat returns messages in the format expected by LLM providers
 func (s *SessionStore) GetMessagesAsLLMFormat(sessionID string) []llm.Message {
-	messages := s.GetMessages(sessionID)
-	result := make([]llm.Message, len(messages))
+	return toLLMMessages(s.GetMessages(sessionID))
+}
 
-	for i, msg := range messages {
-		result[i] = llm.Message{
+// toLLMMessages converts stored messages to the format expected by LLM providers
+// Tool records that can't be decoded (e.g. still sealed) are skipped
+func toLLMMessages(messages []Message) []llm.Message {
+	result := make([]llm.Message, 0, len(messages))
+
+	for _, msg := range messages {
+		llmMsg := llm.Message{
 			Role: msg.Role.String(),
 			Text: msg.Text,
 		}
+		if msg.Role == Tool {
+			var exchange llm.ToolExchange
+			if err := json.Unmarshal([]byte(msg.Text), &exchange); err != nil {
+				continue
+			}
+			llmMsg.Tool = &exchange
+		}
+		result = append(result, llmMsg)
 	}
 
 	return result
 }
 
-// CleanupIdleSessions removes sessions that have been idle for more than the configured timeout
-func (s *SessionStore) CleanupIdleSessions() {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	cutoff := time.Now().UTC().Add(-s.idleTimeout)
-	toDelete := make([]string, 0)
-
-	for sessionID, session := range s.sessions {
-		if session.LastActive.Before(cutoff) {
-			toDelete = append(toDelete, sessionID)
-		}
+// CleanupIdleSessions removes sessions that have been idle for more than their idle timeout
+// and returns how many were removed
+// Shards are cleaned one at a time so chats in other shards aren't blocked
+func (s *SessionStore) CleanupIdleSessions() int {
+	start := time.Now()
+	now := start.UTC()
+	removed := 0
+
+	if s.archiver != nil {
+		removed = s.archiveIdleSessions(now)
+		recordSessionsRemoved("idle_archive", removed)
+		recordSessionCleanupDuration(time.Since(start).Seconds())
+		return removed
 	}
 
-	// Remove from all tracking structures
-	for _, sessionID := range toDelete {
-		delete(s.sessions, sessionID)
-		delete(s.validSessions, sessionID)
-
-		// Remove from session order
-		for i, id := range s.sessionOrder {
-			if id == sessionID {
-				s.sessionOrder = append(s.sessionOrder[:i], s.sessionOrder[i+1:]...)
-				break
+	for _, shard := range s.shards {
+		shard.mu.Lock()
+		for sessionID, session := range shard.sessions {
+			cutoff := now.Add(-s.sessionIdleTimeout(shard, sessionID))
+			if session.LastActive.Before(cutoff) {
+				owner := shard.validSessions[sessionID]
+				s.removeSession(shard, sessionID)
+				s.notifyRemoved(sessionID, owner, "idle_cleanup")
+				removed++
 			}
 		}
+		shard.mu.Unlock()
 	}
+
+	recordSessionsRemoved("idle_cleanup", removed)
+	recordSessionCleanupDuration(time.Since(start).Seconds())
+	return removed
 }
@@ -6,13 +6,24 @@ import (
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
+// storedSession returns the store's internal session for tests that inspect or age it directly
+func storedSession(store *SessionStore, sessionID string) *Session {
+	shard := store.shardFor(sessionID)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+	return shard.sessions[sessionID]
+}
+
 func TestSessionStore_AppendMessage(t *testing.T) {
 	store := NewSessionStore(2*time.Hour, 1000, 100, 100*1024)
 
 	// Register a valid session ID first
-	store.RegisterSession("test-session-1")
+	store.RegisterSession("test-session-1", "")
 
 	// Test appending to new session
 	err := store.AppendMessage("test-session-1", User, "Hello")
@@ -81,7 +92,7 @@ func TestSessionStore_GetMessages_NonExistent(t *testing.T) {
 
 func TestSessionStore_GetMessages_ReturnsCopy(t *testing.T) {
 	store := NewSessionStore(2*time.Hour, 1000, 100, 100*1024)
-	store.RegisterSession("test-session-1")
+	store.RegisterSession("test-session-1", "")
 	err := store.AppendMessage("test-session-1", User, "test message")
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
@@ -103,7 +114,7 @@ func TestSessionStore_ConcurrentAccess(t *testing.T) {
 	store := NewSessionStore(2*time.Hour, 1000, 100, 100*1024)
 	var wg sync.WaitGroup
 	sessionID := "concurrent-test-session"
-	store.RegisterSession(sessionID)
+	store.RegisterSession(sessionID, "")
 
 	// Start multiple goroutines appending messages
 	numGoroutines := 10
@@ -140,8 +151,8 @@ func TestSessionStore_GetSessionCount(t *testing.T) {
 	}
 
 	// Add messages to different sessions
-	store.RegisterSession("session-1")
-	store.RegisterSession("session-2")
+	store.RegisterSession("session-1", "")
+	store.RegisterSession("session-2", "")
 	err := store.AppendMessage("session-1", User, "message 1")
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
@@ -211,7 +222,7 @@ func TestRole_String(t *testing.T) {
 func TestSessionStore_MessageTimestamps(t *testing.T) {
 	store := NewSessionStore(2*time.Hour, 1000, 100, 100*1024)
 
-	store.RegisterSession("timestamp-test-session")
+	store.RegisterSession("timestamp-test-session", "")
 	before := time.Now()
 	err := store.AppendMessage("timestamp-test-session", User, "First message")
 	if err != nil {
@@ -248,7 +259,7 @@ func TestSessionStore_LastActiveTimestamp(t *testing.T) {
 	store := NewSessionStore(2*time.Hour, 1000, 100, 100*1024)
 	sessionID := "last-active-test-session"
 
-	store.RegisterSession(sessionID)
+	store.RegisterSession(sessionID, "")
 	err := store.AppendMessage(sessionID, User, "First message")
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
@@ -266,9 +277,7 @@ func TestSessionStore_LastActiveTimestamp(t *testing.T) {
 	after := time.Now().UTC()
 
 	// Access session directly to check LastActive
-	store.mu.RLock()
-	session := store.sessions[sessionID]
-	store.mu.RUnlock()
+	session := storedSession(store, sessionID)
 
 	if session == nil {
 		t.Fatal("Expected session to exist")
@@ -288,8 +297,8 @@ func TestSessionStore_CleanupIdleSessions(t *testing.T) {
 	store := NewSessionStore(2*time.Hour, 1000, 100, 100*1024)
 
 	// Create sessions with different ages
-	store.RegisterSession("recent-session")
-	store.RegisterSession("old-session")
+	store.RegisterSession("recent-session", "")
+	store.RegisterSession("old-session", "")
 	err := store.AppendMessage("recent-session", User, "Recent message")
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
@@ -300,9 +309,7 @@ func TestSessionStore_CleanupIdleSessions(t *testing.T) {
 	}
 
 	// Manually set LastActive to simulate old session
-	store.mu.Lock()
-	store.sessions["old-session"].LastActive = time.Now().UTC().Add(-3 * time.Hour) // 3 hours ago
-	store.mu.Unlock()
+	storedSession(store, "old-session").LastActive = time.Now().UTC().Add(-3 * time.Hour) // 3 hours ago
 
 	// Verify both sessions exist
 	if count := store.GetSessionCount(); count != 2 {
@@ -310,7 +317,9 @@ func TestSessionStore_CleanupIdleSessions(t *testing.T) {
 	}
 
 	// Run cleanup
-	store.CleanupIdleSessions()
+	if removed := store.CleanupIdleSessions(); removed != 1 {
+		t.Errorf("Expected cleanup to report 1 removed session, got %d", removed)
+	}
 
 	// Only recent session should remain
 	if count := store.GetSessionCount(); count != 1 {
@@ -330,6 +339,96 @@ func TestSessionStore_CleanupIdleSessions(t *testing.T) {
 	}
 }
 
+func TestSessionStore_CleanupPerSessionIdleTimeout(t *testing.T) {
+	store := NewSessionStore(2*time.Hour, 1000, 100, 100*1024)
+
+	store.RegisterSessionWithTimeout("short-session", "", 10*time.Minute)
+	store.RegisterSessionWithTimeout("long-session", "", 8*time.Hour)
+	store.RegisterSession("default-session", "")
+
+	for _, id := range []string{"short-session", "long-session", "default-session"} {
+		if err := store.AppendMessage(id, User, "Hello"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		// Every session has been idle for 3 hours
+		storedSession(store, id).LastActive = time.Now().UTC().Add(-3 * time.Hour)
+	}
+
+	store.CleanupIdleSessions()
+
+	if store.IsValidSession("short-session") {
+		t.Error("short-session should have been cleaned up")
+	}
+	if store.IsValidSession("default-session") {
+		t.Error("default-session should have been cleaned up by the store default timeout")
+	}
+	if !store.IsValidSession("long-session") {
+		t.Error("long-session should still exist with its longer idle timeout")
+	}
+}
+
+func TestSessionStore_EncryptionAtRest(t *testing.T) {
+	store := NewSessionStore(2*time.Hour, 1000, 100, 100*1024)
+	if err := store.EnableEncryption(make([]byte, encryptionKeySize)); err != nil {
+		t.Fatalf("Failed to enable encryption: %v", err)
+	}
+
+	store.RegisterSession("encrypted-session", "")
+	if err := store.AppendMessage("encrypted-session", User, "my secret plan"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	store.SetSessionTitle("encrypted-session", "Secret Plan")
+
+	// Stored state must not contain plaintext
+	stored := storedSession(store, "encrypted-session")
+	if stored.Messages[0].Text != "" || stored.Messages[0].Sealed == nil {
+		t.Error("Expected message text to be stored encrypted")
+	}
+	if stored.Title != "" || stored.SealedTitle == nil {
+		t.Error("Expected title to be stored encrypted")
+	}
+
+	// Reads return plaintext
+	messages := store.GetMessages("encrypted-session")
+	if messages[0].Text != "my secret plan" || messages[0].Sealed != nil {
+		t.Errorf("Expected decrypted message, got %+v", messages[0])
+	}
+	session, _ := store.GetSession("encrypted-session")
+	if session.Title != "Secret Plan" {
+		t.Errorf("Expected decrypted title, got %q", session.Title)
+	}
+}
+
+func TestSessionStore_ClientKeyEncryption(t *testing.T) {
+	store := NewSessionStore(2*time.Hour, 1000, 100, 100*1024)
+	clientCipher, err := newMessageCipher(make([]byte, encryptionKeySize))
+	if err != nil {
+		t.Fatalf("Failed to create cipher: %v", err)
+	}
+
+	store.RegisterSession("e2e-session", "")
+	store.SetClientKeyFingerprint("e2e-session", "fingerprint")
+
+	// Writes without the client key are rejected
+	if err := store.AppendMessage("e2e-session", User, "no key"); err == nil {
+		t.Error("Expected error appending without the client key")
+	}
+
+	if err := store.AppendMessageWithKey("e2e-session", User, "client secret", clientCipher); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Reads without the key stay sealed, reads with the key decrypt
+	sealed := store.GetMessages("e2e-session")
+	if sealed[0].Text != "" || sealed[0].Sealed == nil {
+		t.Errorf("Expected sealed message without key, got %+v", sealed[0])
+	}
+	opened := store.GetMessagesWithKey("e2e-session", clientCipher)
+	if opened[0].Text != "client secret" {
+		t.Errorf("Expected decrypted message with key, got %+v", opened[0])
+	}
+}
+
 // New tests for session limits functionality
 
 func TestSessionStore_SessionValidation(t *testing.T) {
@@ -342,17 +441,60 @@ func TestSessionStore_SessionValidation(t *testing.T) {
 	}
 
 	// Test valid session
-	store.RegisterSession("valid-session")
+	store.RegisterSession("valid-session", "")
 	err = store.AppendMessage("valid-session", User, "Should work")
 	if err != nil {
 		t.Errorf("Unexpected error for valid session: %v", err)
 	}
 }
 
+func TestSessionStore_SessionOwnership(t *testing.T) {
+	store := NewSessionStore(2*time.Hour, 1000, 100, 100*1024)
+
+	store.RegisterSession("owned-session", "key-a")
+
+	if !store.IsSessionOwner("owned-session", "key-a") {
+		t.Error("Expected key-a to own the session")
+	}
+	if store.IsSessionOwner("owned-session", "key-b") {
+		t.Error("Expected key-b not to own the session")
+	}
+	if store.IsSessionOwner("unknown-session", "key-a") {
+		t.Error("Expected unknown session to have no owner")
+	}
+}
+
+func TestSessionStore_GetSessionsInfoForOwner(t *testing.T) {
+	store := NewSessionStore(2*time.Hour, 1, 100, 100*1024) // Max 1 session to force eviction
+
+	store.RegisterSession("session-a1", "key-a")
+	store.RegisterSession("session-b1", "key-b")
+
+	if err := store.AppendMessage("session-a1", User, "Hello"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	infos := store.GetSessionsInfoForOwner("key-a")
+	if len(infos) != 1 || infos[0].ID != "session-a1" || infos[0].MessageCount != 1 {
+		t.Errorf("Unexpected sessions for key-a: %+v", infos)
+	}
+
+	// Activity in session-b1 evicts session-a1, which must drop out of key-a's list
+	if err := store.AppendMessage("session-b1", User, "Hello"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if infos := store.GetSessionsInfoForOwner("key-a"); len(infos) != 0 {
+		t.Errorf("Expected no sessions for key-a after eviction, got %+v", infos)
+	}
+	if infos := store.GetSessionsInfoForOwner("key-b"); len(infos) != 1 {
+		t.Errorf("Expected 1 session for key-b, got %+v", infos)
+	}
+}
+
 func TestSessionStore_MessageLimits(t *testing.T) {
 	store := NewSessionStore(2*time.Hour, 1000, 3, 100*1024) // Max 3 messages per session
 
-	store.RegisterSession("test-session")
+	store.RegisterSession("test-session", "")
 
 	// Should allow up to 3 messages
 	for i := 0; i < 3; i++ {
@@ -372,7 +514,7 @@ func TestSessionStore_MessageLimits(t *testing.T) {
 func TestSessionStore_SessionSizeLimits(t *testing.T) {
 	store := NewSessionStore(2*time.Hour, 1000, 100, 100) // Max 100 bytes per session
 
-	store.RegisterSession("test-session")
+	store.RegisterSession("test-session", "")
 
 	// Add a large message that exceeds size limit
 	largeMessage := make([]byte, 200)
@@ -390,8 +532,8 @@ func TestSessionStore_MaxSessionsWithEviction(t *testing.T) {
 	store := NewSessionStore(2*time.Hour, 2, 100, 100*1024) // Max 2 sessions
 
 	// Create first two sessions
-	store.RegisterSession("session-1")
-	store.RegisterSession("session-2")
+	store.RegisterSession("session-1", "")
+	store.RegisterSession("session-2", "")
 
 	err := store.AppendMessage("session-1", User, "Message 1")
 	if err != nil {
@@ -407,7 +549,7 @@ func TestSessionStore_MaxSessionsWithEviction(t *testing.T) {
 	}
 
 	// Create third session - should evict oldest
-	store.RegisterSession("session-3")
+	store.RegisterSession("session-3", "")
 	err = store.AppendMessage("session-3", User, "Message 3")
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
@@ -434,3 +576,286 @@ func TestSessionStore_MaxSessionsWithEviction(t *testing.T) {
 		t.Error("session-3 should still exist")
 	}
 }
+
+func TestSessionStore_ConcurrentEviction(t *testing.T) {
+	const maxSessions = 10
+	store := NewSessionStore(2*time.Hour, maxSessions, 100, 100*1024)
+
+	// Sessions spread across shards while every append may trigger an eviction in another shard
+	var wg sync.WaitGroup
+	for i := range 200 {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sessionID := fmt.Sprintf("session-%d", i)
+			store.RegisterSession(sessionID, "owner")
+			for range 5 {
+				_ = store.AppendMessage(sessionID, User, "Hello")
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if count := store.GetSessionCount(); count != maxSessions {
+		t.Errorf("Expected %d sessions after concurrent eviction, got %d", maxSessions, count)
+	}
+	if infos := store.GetAllSessionsInfo(); len(infos) != maxSessions {
+		t.Errorf("Expected %d stored sessions, got %d", maxSessions, len(infos))
+	}
+	if infos := store.GetSessionsInfoForOwner("owner"); len(infos) != maxSessions {
+		t.Errorf("Expected evicted sessions to be removed from the owner index, got %d", len(infos))
+	}
+}
+
+func TestSessionStore_EvictsLeastRecentlyUsed(t *testing.T) {
+	store := NewSessionStore(2*time.Hour, 2, 100, 100*1024)
+	for _, id := range []string{"session-1", "session-2", "session-3"} {
+		store.RegisterSession(id, "")
+	}
+
+	store.AppendMessage("session-1", User, "Message 1")
+	store.AppendMessage("session-2", User, "Message 2")
+	// Touching session-1 makes session-2 the least recently used
+	store.AppendMessage("session-1", Assistant, "Reply 1")
+	store.AppendMessage("session-3", User, "Message 3")
+
+	if store.IsValidSession("session-2") {
+		t.Error("session-2 should have been evicted as least recently used")
+	}
+	if !store.IsValidSession("session-1") || !store.IsValidSession("session-3") {
+		t.Error("session-1 and session-3 should still exist")
+	}
+	if store.sessionOrder.Len() != 2 || len(store.orderIndex) != 2 {
+		t.Errorf("Expected LRU order to track 2 sessions, got %d (index %d)", store.sessionOrder.Len(), len(store.orderIndex))
+	}
+}
+
+func TestSessionStore_SizeAccounting(t *testing.T) {
+	store := NewSessionStore(2*time.Hour, 1000, 100, 100*1024)
+	store.RegisterSession("sized-session", "")
+
+	for i := range 10 {
+		if err := store.AppendMessage("sized-session", User, fmt.Sprintf("message %d", i)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	store.SetSessionModel("sized-session", "Echo")
+	store.SetSessionTitle("sized-session", "A title")
+
+	session := storedSession(store, "sized-session")
+	if got, want := session.sizeBytes, computeSessionSize(session); got != want {
+		t.Errorf("Expected cached size %d to match recomputed size %d", got, want)
+	}
+	// Struct overhead and spare slice capacity are counted, not just text
+	if minimum := sessionStructSize + cap(session.Messages)*messageStructSize; session.sizeBytes < minimum {
+		t.Errorf("Expected size to include struct and capacity overhead (%d), got %d", minimum, session.sizeBytes)
+	}
+
+	infos := store.GetAllSessionsInfo()
+	if len(infos) != 1 || infos[0].SizeBytes != session.sizeBytes {
+		t.Errorf("Expected session info to report the cached size, got %+v", infos)
+	}
+}
+
+func TestSessionStore_SizeLimitCountsOverhead(t *testing.T) {
+	// Room for the session struct and a little text, but not the message slice it needs
+	store := NewSessionStore(2*time.Hour, 1000, 100, sessionStructSize+16)
+	store.RegisterSession("tight-session", "")
+
+	if err := store.AppendMessage("tight-session", User, "hi"); err == nil {
+		t.Error("Expected size limit error once per-message overhead is counted")
+	}
+	if session := storedSession(store, "tight-session"); session.sizeBytes != computeSessionSize(session) {
+		t.Error("Expected rejected append to leave the cached size unchanged")
+	}
+}
+
+func TestSessionStore_RemovalMetrics(t *testing.T) {
+	store := NewSessionStore(2*time.Hour, 1, 1, 100*1024)
+	evicted := testutil.ToFloat64(sessionsRemovedTotal.WithLabelValues("lru_eviction"))
+	rejected := testutil.ToFloat64(messagesRejectedTotal.WithLabelValues("message_count"))
+
+	store.RegisterSession("session-1", "")
+	store.RegisterSession("session-2", "")
+	store.AppendMessage("session-1", User, "Hello")
+	if err := store.AppendMessage("session-1", User, "Over the limit"); err == nil {
+		t.Fatal("Expected message limit error")
+	}
+	store.AppendMessage("session-2", User, "Evicts session-1")
+
+	if got := testutil.ToFloat64(sessionsRemovedTotal.WithLabelValues("lru_eviction")) - evicted; got != 1 {
+		t.Errorf("Expected 1 LRU eviction to be counted, got %v", got)
+	}
+	if got := testutil.ToFloat64(messagesRejectedTotal.WithLabelValues("message_count")) - rejected; got != 1 {
+		t.Errorf("Expected 1 rejected message to be counted, got %v", got)
+	}
+}
+
+func TestSessionStore_MemoryBudget(t *testing.T) {
+	store := NewSessionStore(2*time.Hour, 1000, 100, 100*1024)
+	message := string(make([]byte, 1000))
+
+	for i := range 3 {
+		sessionID := fmt.Sprintf("session-%d", i)
+		store.RegisterSession(sessionID, "")
+		if err := store.AppendMessage(sessionID, User, message); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	total, budget := store.GetMemoryUsage()
+	if budget != 0 || total != 3*storedSession(store, "session-0").sizeBytes {
+		t.Fatalf("Expected total of 3 equal sessions without a budget, got %d (budget %d)", total, budget)
+	}
+
+	// A budget for roughly two sessions evicts the least recently used one
+	budgetBytes := total * 2 / 3
+	store.SetMemoryBudget(budgetBytes)
+	if store.IsValidSession("session-0") || !store.IsValidSession("session-2") {
+		t.Error("Expected session-0 to be evicted under memory pressure")
+	}
+	if remaining, _ := store.GetMemoryUsage(); store.GetSessionCount() != 2 || remaining > budgetBytes {
+		t.Errorf("Expected 2 sessions within the budget, got %d using %d bytes", store.GetSessionCount(), remaining)
+	}
+
+	// Growing a session evicts others, but never the session being written to
+	for range 5 {
+		if err := store.AppendMessage("session-2", User, message); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if store.GetSessionCount() != 1 || !store.IsValidSession("session-2") {
+		t.Errorf("Expected only the active session to remain, got %d sessions", store.GetSessionCount())
+	}
+	if total, _ := store.GetMemoryUsage(); total != storedSession(store, "session-2").sizeBytes {
+		t.Errorf("Expected total to match the remaining session, got %d", total)
+	}
+}
+
+func TestSessionStore_DeleteMessages(t *testing.T) {
+	store := NewSessionStore(2*time.Hour, 1000, 100, 100*1024)
+	store.RegisterSession("session", "key")
+	for _, text := range []string{"q1", "a1", "q2", "a2", "q3", "a3"} {
+		store.AppendMessage("session", User, text)
+	}
+	before, _ := store.GetMemoryUsage()
+
+	if err := store.DeleteMessages("session", 2, 2); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	messages := store.GetMessages("session")
+	if len(messages) != 4 || messages[1].Text != "a1" || messages[2].Text != "q3" {
+		t.Errorf("Expected the middle turn to be deleted, got %v", store.GetFormattedMessages("session"))
+	}
+
+	session := storedSession(store, "session")
+	if session.sizeBytes != computeSessionSize(session) {
+		t.Errorf("Expected cached size %d to match computed size %d", session.sizeBytes, computeSessionSize(session))
+	}
+	if after, _ := store.GetMemoryUsage(); after != before-len("q2")-len("a2") {
+		t.Errorf("Expected memory usage to drop by the deleted text, got %d -> %d", before, after)
+	}
+
+	for _, r := range [][2]int{{-1, 1}, {0, 0}, {3, 2}} {
+		if err := store.DeleteMessages("session", r[0], r[1]); err == nil {
+			t.Errorf("Expected error deleting %d messages at %d", r[1], r[0])
+		}
+	}
+	if err := store.DeleteMessages("missing", 0, 1); err == nil {
+		t.Error("Expected error for a session without messages")
+	}
+}
+
+func TestSessionStore_TruncateAtUserMessage(t *testing.T) {
+	store := NewSessionStore(2*time.Hour, 1000, 100, 100*1024)
+	store.RegisterSession("session", "key")
+	store.AppendMessage("session", User, "q1")
+	store.AppendMessage("session", Assistant, "a1")
+	store.AppendMessage("session", User, "q2")
+	store.AppendMessage("session", Assistant, "a2")
+
+	if err := store.TruncateAtUserMessage("session", 1); err == nil {
+		t.Error("Expected error truncating at an assistant message")
+	}
+	if err := store.TruncateAtUserMessage("session", 4); err == nil {
+		t.Error("Expected error for an out of range index")
+	}
+	if err := store.TruncateAtUserMessage("session", 2); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if count := store.GetMessageCount("session"); count != 2 {
+		t.Errorf("Expected 2 messages after truncation, got %d", count)
+	}
+}
+
+func TestSessionStore_RollbackTurn(t *testing.T) {
+	store := NewSessionStore(2*time.Hour, 1000, 100, 100*1024)
+	store.RegisterSession("session", "key")
+	store.AppendMessage("session", User, "q1")
+	store.AppendMessage("session", Assistant, "a1")
+
+	turnStart := time.Now().UTC()
+	store.AppendMessage("session", User, "q2")
+	store.AppendMessage("session", Tool, `{"call":{"name":"time"}}`)
+
+	if removed := store.RollbackTurn("session", turnStart); removed != 2 {
+		t.Errorf("Expected the user message and its tool call to be removed, got %d", removed)
+	}
+	if messages := store.GetMessages("session"); len(messages) != 2 || messages[1].Text != "a1" {
+		t.Errorf("Expected the previous turn to be kept, got %v", store.GetFormattedMessages("session"))
+	}
+
+	// Nothing newer than the turn start is left to remove
+	if removed := store.RollbackTurn("session", turnStart); removed != 0 {
+		t.Errorf("Expected nothing removed a second time, got %d", removed)
+	}
+
+	// A turn that already has its reply is left alone
+	turnStart = time.Now().UTC()
+	store.AppendMessage("session", User, "q3")
+	store.AppendMessage("session", Assistant, "a3")
+	if removed := store.RollbackTurn("session", turnStart); removed != 0 || store.GetMessageCount("session") != 4 {
+		t.Errorf("Expected a completed turn to be kept, removed %d", removed)
+	}
+	if removed := store.RollbackTurn("missing", turnStart); removed != 0 {
+		t.Errorf("Expected nothing removed for a missing session, got %d", removed)
+	}
+}
+
+func TestSessionStore_SessionSizeDistribution(t *testing.T) {
+	store := NewSessionStore(2*time.Hour, 10, 10, 100*1024)
+	store.RegisterSession("empty", "")
+	store.RegisterSession("chatty", "")
+	store.AppendMessage("chatty", User, "Hello")
+	store.AppendMessage("chatty", Assistant, "Hi there")
+
+	messageCounts, sizes := store.SessionSizes()
+	if len(messageCounts) != 2 || len(sizes) != 2 {
+		t.Fatalf("Expected one sample per session, got %v and %v", messageCounts, sizes)
+	}
+	if messageCounts[0]+messageCounts[1] != 2 {
+		t.Errorf("Expected 2 messages across sessions, got %v", messageCounts)
+	}
+
+	// Each pass replaces the distribution rather than adding to it
+	histogram := newSnapshotHistogramForTest([]float64{0, 5})
+	histogram.Update(messageCounts)
+	histogram.Update(messageCounts)
+	if histogram.count != 2 || histogram.sum != 2 {
+		t.Errorf("Expected 2 sessions with 2 messages in total, got count %d sum %v", histogram.count, histogram.sum)
+	}
+	if histogram.counts[0] != 1 || histogram.counts[5] != 2 {
+		t.Errorf("Expected cumulative bucket counts {0:1 5:2}, got %v", histogram.counts)
+	}
+	if got := testutil.CollectAndCount(histogram); got != 1 {
+		t.Errorf("Expected one histogram to be collected, got %d", got)
+	}
+}
+
+// newSnapshotHistogramForTest creates a snapshot histogram without registering it globally
+func newSnapshotHistogramForTest(buckets []float64) *snapshotHistogram {
+	return &snapshotHistogram{
+		desc:    prometheus.NewDesc("microchat_test_snapshot", "Test histogram", nil, nil),
+		buckets: buckets,
+		counts:  make(map[float64]uint64),
+	}
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultUsageRetentionDays is how long a key's usage entry outlives its last call by default
+const defaultUsageRetentionDays = 7
+
+// SpendingTracker tracks daily usage per API key: calls, and optionally LLM tokens,
+// which follow real provider cost more closely than calls do
+// Days start at midnight in the tracker's timezone (UTC unless configured), so every key's
+// count resets at the same instant regardless of the server's local time
+type SpendingTracker struct {
+	mu         sync.RWMutex
+	usage      map[string]keyUsage        // API key -> usage data
+	limit      int                        // Daily call limit
+	tokenLimit int64                      // Daily LLM token limit (0 = unlimited)
+	location   *time.Location             // Timezone whose midnight starts a new day
+	retention  int                        // Days an entry is kept after the key's last call
+	onLimit    func(apiKey, limit string) // Called when a key uses up a daily limit ("calls" or "tokens")
+}
+
+type keyUsage struct {
+	date     string    // YYYY-MM-DD format in the tracker's timezone
+	calls    int       // Number of calls today
+	tokens   int64     // LLM tokens consumed today
+	lastSeen time.Time // Time of the key's most recent call
+}
+
+// NewSpendingTracker creates a new spending tracker
+func NewSpendingTracker(dailyLimit int) *SpendingTracker {
+	return &SpendingTracker{
+		usage:     make(map[string]keyUsage),
+		limit:     dailyLimit,
+		location:  time.UTC,
+		retention: defaultUsageRetentionDays,
+	}
+}
+
+// SetLocation sets the timezone whose midnight resets daily counts
+func (st *SpendingTracker) SetLocation(location *time.Location) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.location = location
+}
+
+// SetRetention sets how many days a key's entry is kept after its last call
+func (st *SpendingTracker) SetRetention(days int) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.retention = days
+}
+
+// SetTokenLimit sets how many LLM tokens each key may consume per day (0 = unlimited)
+func (st *SpendingTracker) SetTokenLimit(limit int64) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.tokenLimit = limit
+}
+
+// OnLimitReached sets a callback for the call that uses up a key's daily call or token limit,
+// with "calls" or "tokens"; it runs once per key per limit per day, without the tracker's lock held
+// It must be set before the tracker is used
+func (st *SpendingTracker) OnLimitReached(fn func(apiKey, limit string)) {
+	st.onLimit = fn
+}
+
+// notifyLimit reports a key that just used up a limit to the callback, if any
+func (st *SpendingTracker) notifyLimit(apiKey, limit string) {
+	if st.onLimit != nil {
+		st.onLimit(apiKey, limit)
+	}
+}
+
+// TokenLimit returns the daily LLM token limit (0 = unlimited)
+func (st *SpendingTracker) TokenLimit() int64 {
+	if st == nil {
+		return 0
+	}
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tokenLimit
+}
+
+// today returns the current date in the tracker's timezone
+// Caller must hold the lock
+func (st *SpendingTracker) today() string {
+	return time.Now().In(st.location).Format("2006-01-02")
+}
+
+// CanMakeCall checks if API key can make another call today
+func (st *SpendingTracker) CanMakeCall(apiKey string) bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	usage, exists := st.usage[apiKey]
+	if !exists || usage.date != st.today() {
+		// New day or new key - can make call
+		return true
+	}
+
+	return usage.calls < st.limit
+}
+
+// RecordCall records a call for an API key
+func (st *SpendingTracker) RecordCall(apiKey string) {
+	st.mu.Lock()
+	reached := st.addCalls(apiKey, 1)
+	st.mu.Unlock()
+
+	if reached {
+		st.notifyLimit(apiKey, "calls")
+	}
+}
+
+// TryRecordCalls records n calls for an API key if they fit within today's limit,
+// reporting whether they were recorded
+func (st *SpendingTracker) TryRecordCalls(apiKey string, n int) bool {
+	st.mu.Lock()
+	if st.callsToday(apiKey)+n > st.limit {
+		st.mu.Unlock()
+		return false
+	}
+	reached := st.addCalls(apiKey, n)
+	st.mu.Unlock()
+
+	if reached {
+		st.notifyLimit(apiKey, "calls")
+	}
+	return true
+}
+
+// addCalls adds n calls to a key's count for today, starting a new count on a new day
+// Reports whether these calls used up the daily limit
+// Caller must hold the lock
+func (st *SpendingTracker) addCalls(apiKey string, n int) bool {
+	usage := st.todayUsage(apiKey)
+	before := usage.calls
+	usage.calls += n
+	usage.lastSeen = time.Now()
+	st.usage[apiKey] = usage
+	return before < st.limit && usage.calls >= st.limit
+}
+
+// todayUsage returns a key's usage for today, empty on a new day or for a new key
+// Caller must hold the lock
+func (st *SpendingTracker) todayUsage(apiKey string) keyUsage {
+	today := st.today()
+	usage, exists := st.usage[apiKey]
+	if !exists || usage.date != today {
+		return keyUsage{date: today, lastSeen: usage.lastSeen}
+	}
+	return usage
+}
+
+// CanSpendTokens checks if an API key has LLM tokens left today
+// A nil tracker or one without a token limit always allows
+func (st *SpendingTracker) CanSpendTokens(apiKey string) bool {
+	if st == nil {
+		return true
+	}
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	return st.tokenLimit == 0 || st.tokensToday(apiKey) < st.tokenLimit
+}
+
+// RecordTokens adds LLM tokens consumed by a call to a key's count for today
+// A reply already under way is never cut short, so a key can end the day slightly over its limit
+func (st *SpendingTracker) RecordTokens(apiKey string, tokens int64) {
+	if st == nil || tokens <= 0 {
+		return
+	}
+	st.mu.Lock()
+	usage := st.todayUsage(apiKey)
+	before := usage.tokens
+	usage.tokens += tokens
+	st.usage[apiKey] = usage
+	reached := st.tokenLimit > 0 && before < st.tokenLimit && usage.tokens >= st.tokenLimit
+	st.mu.Unlock()
+
+	if reached {
+		st.notifyLimit(apiKey, "tokens")
+	}
+}
+
+// TokensToday returns the LLM tokens an API key has consumed today
+func (st *SpendingTracker) TokensToday(apiKey string) int64 {
+	if st == nil {
+		return 0
+	}
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	return st.tokensToday(apiKey)
+}
+
+// tokensToday returns a key's tokens today
+// Caller must hold the lock
+func (st *SpendingTracker) tokensToday(apiKey string) int64 {
+	usage, exists := st.usage[apiKey]
+	if !exists || usage.date != st.today() {
+		return 0
+	}
+	return usage.tokens
+}
+
+// ResetsAt returns when today's call counts reset
+func (st *SpendingTracker) ResetsAt() time.Time {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	year, month, day := time.Now().In(st.location).Date()
+	return time.Date(year, month, day+1, 0, 0, 0, 0, st.location)
+}
+
+// CallsToday returns the number of calls an API key has made today
+func (st *SpendingTracker) CallsToday(apiKey string) int {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	return st.callsToday(apiKey)
+}
+
+// callsToday returns a key's calls today
+// Caller must hold the lock
+func (st *SpendingTracker) callsToday(apiKey string) int {
+	usage, exists := st.usage[apiKey]
+	if !exists || usage.date != st.today() {
+		return 0
+	}
+	return usage.calls
+}
+
+// Snapshot returns today's calls for every tracked API key
+// Keys seen within the retention period but not today are included with 0 calls
+func (st *SpendingTracker) Snapshot() map[string]int {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	today := st.today()
+	result := make(map[string]int, len(st.usage))
+	for apiKey, usage := range st.usage {
+		if usage.date == today {
+			result[apiKey] = usage.calls
+		} else {
+			result[apiKey] = 0
+		}
+	}
+	return result
+}
+
+// resetDay starts a new day: counts from earlier days are cleared and keys not seen within
+// the retention period are dropped, so the map doesn't grow with every key ever used
+// Returns the number of entries dropped
+func (st *SpendingTracker) resetDay(now time.Time) int {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	today := now.In(st.location).Format("2006-01-02")
+	cutoff := now.AddDate(0, 0, -st.retention)
+	dropped := 0
+	for apiKey, usage := range st.usage {
+		switch {
+		case usage.lastSeen.Before(cutoff):
+			delete(st.usage, apiKey)
+			dropped++
+		case usage.date != today:
+			st.usage[apiKey] = keyUsage{date: today, lastSeen: usage.lastSeen}
+		}
+	}
+	return dropped
+}
+
+// Run resets daily counts at each midnight in the tracker's timezone until done is closed
+func (st *SpendingTracker) Run(done <-chan bool) {
+	for {
+		timer := time.NewTimer(time.Until(st.ResetsAt()))
+		select {
+		case now := <-timer.C:
+			st.resetDay(now)
+		case <-done:
+			timer.Stop()
+			return
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"microchat.ai/cmd/server/llm"
+	pb "microchat.ai/proto"
+)
+
+// maxResponseSchemaBytes bounds the size of a client-supplied response schema
+const maxResponseSchemaBytes = 16 * 1024
+
+// jsonModeInstruction asks providers without a native JSON mode for a bare JSON reply
+const jsonModeInstruction = "Respond only with a single valid JSON value. Do not wrap it in Markdown or add any other text."
+
+// parseResponseSchema validates the response format options on a chat request
+// Returns nil when no schema was supplied
+func parseResponseSchema(req *pb.ChatRequest) (map[string]any, error) {
+	if req.ResponseSchema == "" {
+		return nil, nil
+	}
+	if req.ResponseFormat != pb.ResponseFormat_RESPONSE_JSON {
+		return nil, status.Error(codes.InvalidArgument, "response_schema requires response_format RESPONSE_JSON")
+	}
+	if len(req.ResponseSchema) > maxResponseSchemaBytes {
+		return nil, status.Errorf(codes.InvalidArgument, "response_schema too large: %d bytes (max %d)", len(req.ResponseSchema), maxResponseSchemaBytes)
+	}
+
+	var schema map[string]any
+	if err := json.Unmarshal([]byte(req.ResponseSchema), &schema); err != nil || schema == nil {
+		return nil, status.Error(codes.InvalidArgument, "response_schema must be a JSON object")
+	}
+	return schema, nil
+}
+
+// generateJSONReply asks the provider for a JSON reply, using its native JSON mode when available
+func generateJSONReply(ctx context.Context, provider llm.Provider, messages []llm.Message, schema map[string]any) (string, error) {
+	if generator, ok := provider.(llm.JSONGenerator); ok {
+		return generator.GenerateJSON(ctx, messages, schema)
+	}
+
+	instruction := jsonModeInstruction
+	if schema != nil {
+		encoded, err := json.Marshal(schema)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode response schema: %w", err)
+		}
+		instruction += " The JSON must match this JSON Schema: " + string(encoded)
+	}
+	prompted := append([]llm.Message{{Role: System.String(), Text: instruction}}, messages...)
+	return provider.GenerateResponse(ctx, prompted)
+}
+
+// normalizeJSONReply checks that a reply is a single JSON value matching schema (if any)
+// and returns it with surrounding whitespace and Markdown code fences removed
+func normalizeJSONReply(reply string, schema map[string]any) (string, error) {
+	text := strings.TrimSpace(reply)
+	if strings.HasPrefix(text, "```") && strings.HasSuffix(text, "```") {
+		text = strings.TrimPrefix(text, "```json")
+		text = strings.TrimPrefix(text, "```")
+		text = strings.TrimSpace(strings.TrimSuffix(text, "```"))
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(text))
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return "", fmt.Errorf("reply is not valid JSON: %w", err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return "", fmt.Errorf("reply contains data after the JSON value")
+	}
+
+	if schema != nil {
+		if err := validateJSONSchema(value, schema, "$"); err != nil {
+			return "", err
+		}
+	}
+	return text, nil
+}
+
+// validateJSONSchema checks value against the commonly used subset of JSON Schema:
+// type, enum, properties, required, additionalProperties (false only) and items
+// Other keywords are ignored
+func validateJSONSchema(value any, schema map[string]any, path string) error {
+	if expected, ok := schema["type"]; ok && !matchesSchemaType(value, expected) {
+		return fmt.Errorf("%s: expected type %v", path, expected)
+	}
+
+	if enum, ok := schema["enum"].([]any); ok {
+		found := false
+		for _, option := range enum {
+			if fmt.Sprint(option) == fmt.Sprint(value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: value not in enum", path)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		properties, _ := schema["properties"].(map[string]any)
+		if required, ok := schema["required"].([]any); ok {
+			for _, name := range required {
+				if key, _ := name.(string); key != "" {
+					if _, present := v[key]; !present {
+						return fmt.Errorf("%s: missing required property %q", path, key)
+					}
+				}
+			}
+		}
+		for key, child := range v {
+			childSchema, known := properties[key].(map[string]any)
+			if !known {
+				if additional, ok := schema["additionalProperties"].(bool); ok && !additional {
+					return fmt.Errorf("%s: unexpected property %q", path, key)
+				}
+				continue
+			}
+			if err := validateJSONSchema(child, childSchema, path+"."+key); err != nil {
+				return err
+			}
+		}
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				if err := validateJSONSchema(item, items, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// matchesSchemaType reports whether value has the JSON Schema type (or one of the types) given
+func matchesSchemaType(value any, expected any) bool {
+	if types, ok := expected.([]any); ok {
+		for _, t := range types {
+			if matchesSchemaType(value, t) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch expected {
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	default:
+		return true // Unknown types aren't enforced
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	pb "microchat.ai/proto"
+)
+
+func TestNormalizeJSONReply(t *testing.T) {
+	schema := map[string]any{
+		"type":                 "object",
+		"required":             []any{"name", "tags"},
+		"additionalProperties": false,
+		"properties": map[string]any{
+			"name":  map[string]any{"type": "string"},
+			"count": map[string]any{"type": "integer"},
+			"kind":  map[string]any{"enum": []any{"a", "b"}},
+			"tags":  map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		reply   string
+		schema  map[string]any
+		want    string
+		wantErr bool
+	}{
+		{"plain object", ` {"a": 1} `, nil, `{"a": 1}`, false},
+		{"code fence", "```json\n[1, 2]\n```", nil, "[1, 2]", false},
+		{"scalar", `"text"`, nil, `"text"`, false},
+		{"not json", "Sure! Here you go", nil, "", true},
+		{"trailing data", `{"a": 1} and more`, nil, "", true},
+		{"matches schema", `{"name": "x", "count": 2, "kind": "a", "tags": ["t"]}`, schema, `{"name": "x", "count": 2, "kind": "a", "tags": ["t"]}`, false},
+		{"missing required", `{"name": "x"}`, schema, "", true},
+		{"wrong type", `{"name": 1, "tags": []}`, schema, "", true},
+		{"non-integer", `{"name": "x", "count": 1.5, "tags": []}`, schema, "", true},
+		{"not in enum", `{"name": "x", "kind": "c", "tags": []}`, schema, "", true},
+		{"wrong item type", `{"name": "x", "tags": [1]}`, schema, "", true},
+		{"unexpected property", `{"name": "x", "tags": [], "extra": true}`, schema, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeJSONReply(tt.reply, tt.schema)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseResponseSchema(t *testing.T) {
+	if schema, err := parseResponseSchema(&pb.ChatRequest{}); err != nil || schema != nil {
+		t.Errorf("Expected no schema, got %v (err %v)", schema, err)
+	}
+
+	invalid := []*pb.ChatRequest{
+		{ResponseSchema: `{"type":"object"}`}, // schema without JSON mode
+		{ResponseFormat: pb.ResponseFormat_RESPONSE_JSON, ResponseSchema: `[1]`},
+		{ResponseFormat: pb.ResponseFormat_RESPONSE_JSON, ResponseSchema: `{bad`},
+	}
+	for _, req := range invalid {
+		if _, err := parseResponseSchema(req); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Expected InvalidArgument for %q, got %v", req.ResponseSchema, err)
+		}
+	}
+}
+
+func TestChatJSONMode(t *testing.T) {
+	app, mockProvider := setupTestApplicationWithMock(t)
+	ctx := context.Background()
+
+	startResp, err := app.StartSession(ctx, &pb.StartSessionRequest{})
+	if err != nil {
+		t.Fatalf("Failed to start session: %v", err)
+	}
+	req := &pb.ChatRequest{
+		SessionId:      startResp.SessionId,
+		Message:        "Give me a user",
+		ResponseFormat: pb.ResponseFormat_RESPONSE_JSON,
+		ResponseSchema: `{"type":"object","required":["name"]}`,
+	}
+
+	mockProvider.SetResponses("```json\n{\"name\": \"Ada\"}\n```")
+	resp, err := app.Chat(ctx, req)
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if resp.Reply != `{"name": "Ada"}` {
+		t.Errorf("Expected normalized JSON reply, got %q", resp.Reply)
+	}
+
+	// Payloads that don't match the schema are never sent to the client
+	mockProvider.SetResponses(`{"age": 36}`)
+	if _, err := app.Chat(ctx, req); status.Code(err) != codes.Internal {
+		t.Errorf("Expected Internal for invalid payload, got %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"microchat.ai/cmd/server/llm"
+)
+
+const (
+	maxTitleLength      = 60               // Maximum stored title length in characters
+	maxTitleInputLength = 500              // Maximum characters of each turn sent to the title prompt
+	titleTimeout        = 15 * time.Second // Upper bound for the title generation call
+)
+
+// truncateRunes shortens text to at most n runes
+func truncateRunes(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[:n])
+}
+
+// fallbackTitle derives a title from the first user message when the LLM can't provide one
+func fallbackTitle(userMessage string) string {
+	title := strings.Join(strings.Fields(userMessage), " ")
+	if len([]rune(title)) > maxTitleLength {
+		title = strings.TrimSpace(truncateRunes(title, maxTitleLength-3)) + "..."
+	}
+	return title
+}
+
+// cleanTitle normalizes an LLM-generated title to a single short line
+func cleanTitle(raw string) string {
+	title := sanitizeForTerminal(raw)
+	if line, _, found := strings.Cut(strings.TrimSpace(title), "\n"); found {
+		title = line
+	}
+	title = strings.TrimSpace(title)
+	title = strings.TrimPrefix(title, "Title:")
+	title = strings.Trim(strings.TrimSpace(title), "\"'*#`")
+	title = strings.Join(strings.Fields(title), " ")
+	return truncateRunes(title, maxTitleLength)
+}
+
+// generateSessionTitle asks the provider for a short title summarizing the first exchange,
+// returning the title and the tokens the call consumed
+func generateSessionTitle(ctx context.Context, provider llm.Provider, userMessage, reply string) (string, int64) {
+	prompt := fmt.Sprintf("Write a short title (at most 6 words) for a conversation that starts like this:\n\n"+
+		"User: %s\nAssistant: %s\n\nReply with the title only.",
+		truncateRunes(userMessage, maxTitleInputLength), truncateRunes(reply, maxTitleInputLength))
+	messages := []llm.Message{{Role: User.String(), Text: prompt}}
+
+	ctx, usage := llm.WithUsage(ctx)
+	generated, err := provider.GenerateResponse(ctx, messages)
+	tokens := replyTokens(usage, messages, generated, err)
+	if err != nil {
+		return fallbackTitle(userMessage), tokens
+	}
+
+	if title := cleanTitle(generated); title != "" {
+		return title, tokens
+	}
+	return fallbackTitle(userMessage), tokens
+}
+
+// generateTitleAsync generates and stores a session title without delaying the chat reply
+// The title call's tokens count against the session's budget and apiKey's daily limit like the reply's
+func (app *application) generateTitleAsync(sessionID, apiKey string, provider llm.Provider, userMessage, reply string) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), titleTimeout)
+		defer cancel()
+
+		title, tokens := generateSessionTitle(ctx, provider, userMessage, reply)
+		app.sessionStore.AddSessionTokens(sessionID, tokens)
+		app.spendingTracker.RecordTokens(apiKey, tokens)
+		if err := app.sessionStore.SetSessionTitle(sessionID, title); err != nil {
+			app.logger.Error("failed to store session title", "session_id", sessionID, "error", err)
+			return
+		}
+		app.logger.Info("generated session title", "session_id", sessionID, "title_len", len(title))
+	}()
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"microchat.ai/cmd/server/llm"
+)
+
+func TestCleanTitle(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"plain", "Go Concurrency Basics", "Go Concurrency Basics"},
+		{"quoted", "\"Go Concurrency Basics\"", "Go Concurrency Basics"},
+		{"prefixed", "Title: Go Concurrency Basics", "Go Concurrency Basics"},
+		{"multiline", "Go Concurrency Basics\nThis conversation covers...", "Go Concurrency Basics"},
+		{"markdown", "**Go Concurrency Basics**", "Go Concurrency Basics"},
+		{"control characters", "Go\x1b[31m Concurrency", "Go Concurrency"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cleanTitle(tt.input); got != tt.expected {
+				t.Errorf("cleanTitle(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+
+	long := cleanTitle(strings.Repeat("word ", 50))
+	if len([]rune(long)) > maxTitleLength {
+		t.Errorf("Expected title truncated to %d runes, got %d", maxTitleLength, len([]rune(long)))
+	}
+}
+
+// staticProvider returns a fixed reply for every request
+type staticProvider struct {
+	reply string
+}
+
+func (p *staticProvider) GenerateResponse(ctx context.Context, messages []llm.Message) (string, error) {
+	return p.reply, nil
+}
+
+func (p *staticProvider) GenerateResponseStream(ctx context.Context, messages []llm.Message) (<-chan llm.Chunk, error) {
+	return llm.StreamText(ctx, p.reply), nil
+}
+
+func (p *staticProvider) HealthCheck(ctx context.Context) error {
+	return nil
+}
+
+func (p *staticProvider) Name() string {
+	return "Static"
+}
+
+func TestGenerateSessionTitle(t *testing.T) {
+	title, tokens := generateSessionTitle(context.Background(), &staticProvider{reply: "\"Weekend Trip Ideas\"\n"}, "Where should I go this weekend?", "Try the coast.")
+	if title != "Weekend Trip Ideas" {
+		t.Errorf("Expected generated title, got %q", title)
+	}
+	if tokens == 0 {
+		t.Error("Expected the title call's tokens to be counted")
+	}
+
+	// Provider failure falls back to the first user message
+	mockProvider := llm.NewMockProvider("Title-Provider")
+	mockProvider.SetError("provider down")
+	title, _ = generateSessionTitle(context.Background(), mockProvider, "  Where should I   go this weekend?  ", "Try the coast.")
+	if title != "Where should I go this weekend?" {
+		t.Errorf("Expected fallback title, got %q", title)
+	}
+
+	title = fallbackTitle(strings.Repeat("a", 100))
+	if len([]rune(title)) != maxTitleLength || !strings.HasSuffix(title, "...") {
+		t.Errorf("Expected truncated fallback title, got %q", title)
+	}
+}
+
+func TestGenerateTitleAsync_CountsTokens(t *testing.T) {
+	app := setupTestApplication(t)
+	app.spendingTracker = NewSpendingTracker(0)
+	app.sessionStore.RegisterSession("session", "key-a")
+	app.sessionStore.AppendMessage("session", User, "Where should I go this weekend?")
+
+	app.generateTitleAsync("session", "key-a", &staticProvider{reply: "Weekend Trip Ideas"}, "Where should I go this weekend?", "Try the coast.")
+	deadline := time.Now().Add(5 * time.Second)
+	for storedTitle(app.sessionStore, "session") == "" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if title := storedTitle(app.sessionStore, "session"); title != "Weekend Trip Ideas" {
+		t.Fatalf("Expected the title to be stored, got %q", title)
+	}
+	if tokens := app.sessionStore.GetSessionTokens("session"); tokens == 0 {
+		t.Error("Expected the title call's tokens to count toward the session")
+	}
+	if tokens := app.spendingTracker.TokensToday("key-a"); tokens == 0 {
+		t.Error("Expected the title call's tokens to count toward the key's daily usage")
+	}
+}
+
+// storedTitle returns a session's title, empty until one is set
+func storedTitle(store *SessionStore, sessionID string) string {
+	session, _ := store.GetSession(sessionID)
+	return session.Title
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"microchat.ai/cmd/server/llm"
+	pb "microchat.ai/proto"
+)
+
+// replyTokens returns the tokens an LLM call consumed: the provider's reported usage, or for
+// providers that don't report it, an estimate from the prompt and reply of a successful call
+func replyTokens(usage *llm.Usage, messages []llm.Message, reply string, err error) int64 {
+	if input, output, reported := usage.Tokens(); reported {
+		return input + output
+	}
+	if err != nil {
+		return 0
+	}
+	tokens := llm.EstimateTokens(reply)
+	for _, m := range messages {
+		tokens += llm.EstimateTokens(m.Text)
+	}
+	return tokens
+}
+
+// checkSessionTokenBudget rejects a chat in a session that has used up its token budget
+// The session's history is what makes each reply expensive, so the fix is a new session
+func (app *application) checkSessionTokenBudget(sessionID string) error {
+	budget := app.config.sessionTokenBudget
+	if budget == 0 {
+		return nil
+	}
+	if used := app.sessionStore.GetSessionTokens(sessionID); used >= budget {
+		return status.Errorf(codes.ResourceExhausted, "session token budget exhausted (%d of %d tokens used), start a new session", used, budget)
+	}
+	return nil
+}
+
+// setSessionTokens reports a session's token usage and remaining budget on a chat response
+func (app *application) setSessionTokens(resp *pb.ChatResponse, used int64) {
+	resp.SessionTokensUsed = uint64(used)
+	budget := app.config.sessionTokenBudget
+	if budget == 0 {
+		return
+	}
+	resp.SessionTokenBudget = uint64(budget)
+	resp.SessionTokensRemaining = uint64(max(budget-used, 0))
+}
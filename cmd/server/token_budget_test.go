@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"microchat.ai/cmd/server/llm"
+	pb "microchat.ai/proto"
+)
+
+func TestReplyTokens(t *testing.T) {
+	messages := []llm.Message{{Role: "user", Text: "12345678"}}
+
+	ctx, usage := llm.WithUsage(context.Background())
+	if tokens := replyTokens(usage, messages, "1234", nil); tokens != 3 {
+		t.Errorf("Expected estimate from prompt and reply, got %d", tokens)
+	}
+	if tokens := replyTokens(usage, messages, "", errors.New("failed")); tokens != 0 {
+		t.Errorf("Expected failed call without reported usage to be free, got %d", tokens)
+	}
+
+	llm.RecordUsage(ctx, 100, 20)
+	if tokens := replyTokens(usage, messages, "1234", nil); tokens != 120 {
+		t.Errorf("Expected reported usage to win over the estimate, got %d", tokens)
+	}
+}
+
+func TestChatSessionTokenBudget(t *testing.T) {
+	app, mockProvider := setupTestApplicationWithMock(t)
+	mockProvider.SetResponses("First reply", "Second reply")
+	ctx := context.Background()
+
+	startResp, err := app.StartSession(ctx, &pb.StartSessionRequest{})
+	if err != nil {
+		t.Fatalf("Failed to start session: %v", err)
+	}
+	chat := func(index uint32) (*pb.ChatResponse, error) {
+		return app.Chat(ctx, &pb.ChatRequest{SessionId: startResp.SessionId, Message: "Hello there", Model: pb.Model_GEMINI_2_5_FLASH_LITE, MessageIndex: index})
+	}
+
+	// Usage is reported even without a budget
+	resp, err := chat(0)
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if resp.SessionTokensUsed == 0 || resp.SessionTokenBudget != 0 || resp.SessionTokensRemaining != 0 {
+		t.Errorf("Expected usage without a budget, got %+v", resp)
+	}
+	used := resp.SessionTokensUsed
+
+	app.config.sessionTokenBudget = int64(used) + 1
+	resp, err = chat(2)
+	if err != nil {
+		t.Fatalf("Expected a session under budget to chat, got %v", err)
+	}
+	if resp.SessionTokenBudget != used+1 || resp.SessionTokensRemaining != 0 || resp.SessionTokensUsed <= used {
+		t.Errorf("Expected budget and remaining tokens, got %+v", resp)
+	}
+
+	_, err = chat(4)
+	if status.Code(err) != codes.ResourceExhausted || !strings.Contains(err.Error(), "start a new session") {
+		t.Errorf("Expected exhausted budget to be rejected, got %v", err)
+	}
+}
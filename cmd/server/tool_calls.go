@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"microchat.ai/cmd/server/llm"
+)
+
+// maxToolRounds bounds how many times the model may request tools before answering
+const maxToolRounds = 5
+
+// generateReply asks the provider for a reply, executing any tool calls it requests
+// Each tool call is recorded in the session history
+func (app *application) generateReply(ctx context.Context, provider llm.Provider, sessionID string, messages []llm.Message, clientCipher *messageCipher) (string, error) {
+	toolCaller, ok := provider.(llm.ToolCaller)
+	if app.tools == nil || !ok {
+		return provider.GenerateResponse(ctx, messages)
+	}
+
+	definitions := app.tools.Definitions()
+	for round := 0; round < maxToolRounds; round++ {
+		turn, err := toolCaller.GenerateWithTools(ctx, messages, definitions)
+		if err != nil {
+			return "", err
+		}
+		if len(turn.Calls) == 0 {
+			return turn.Text, nil
+		}
+
+		for _, call := range turn.Calls {
+			exchange := llm.ToolExchange{Call: call}
+			output, err := app.tools.Execute(ctx, call)
+			if err != nil {
+				exchange.Error = err.Error()
+				recordToolCall(call.Name, "error")
+				app.logger.Warn("tool call failed", "session_id", sessionID, "tool", call.Name, "error", err)
+			} else {
+				exchange.Output = output
+				recordToolCall(call.Name, "success")
+				app.logger.Info("tool call executed", "session_id", sessionID, "tool", call.Name, "output_len", len(output))
+			}
+
+			record, err := json.Marshal(exchange)
+			if err != nil {
+				return "", status.Errorf(codes.Internal, "failed to encode tool call: %v", err)
+			}
+			if err := app.sessionStore.AppendMessageWithKey(sessionID, Tool, string(record), clientCipher); err != nil {
+				return "", status.Errorf(codes.ResourceExhausted, "failed to store tool call: %v", err)
+			}
+			messages = append(messages, llm.Message{Role: Tool.String(), Text: string(record), Tool: &exchange})
+		}
+	}
+
+	return "", status.Errorf(codes.ResourceExhausted, "model requested tools more than %d times without answering", maxToolRounds)
+}
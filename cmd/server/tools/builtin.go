@@ -0,0 +1,234 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"microchat.ai/cmd/server/llm"
+)
+
+// timeTool reports the current time, optionally in a given IANA time zone
+type timeTool struct{}
+
+func (timeTool) Definition() llm.ToolDefinition {
+	return llm.ToolDefinition{
+		Name:        "time",
+		Description: "Returns the current date and time. Optionally takes an IANA time zone such as Europe/London.",
+		Parameters: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"timezone": map[string]any{"type": "string", "description": "IANA time zone name, defaults to UTC"},
+			},
+		},
+	}
+}
+
+func (timeTool) Execute(ctx context.Context, args map[string]any) (string, error) {
+	loc := time.UTC
+	if tz, _ := args["timezone"].(string); tz != "" {
+		loaded, err := time.LoadLocation(tz)
+		if err != nil {
+			return "", fmt.Errorf("unknown time zone %q", tz)
+		}
+		loc = loaded
+	}
+	return time.Now().In(loc).Format("Monday, 2 January 2006 15:04:05 MST"), nil
+}
+
+// calculatorTool evaluates arithmetic expressions without executing any code
+type calculatorTool struct{}
+
+func (calculatorTool) Definition() llm.ToolDefinition {
+	return llm.ToolDefinition{
+		Name:        "calculator",
+		Description: "Evaluates an arithmetic expression with + - * / ^ and parentheses, e.g. (2 + 3) * 4.5",
+		Parameters: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"expression": map[string]any{"type": "string", "description": "Arithmetic expression to evaluate"},
+			},
+			"required": []string{"expression"},
+		},
+	}
+}
+
+func (calculatorTool) Execute(ctx context.Context, args map[string]any) (string, error) {
+	expression, _ := args["expression"].(string)
+	if expression == "" {
+		return "", fmt.Errorf("expression is required")
+	}
+	value, err := evaluate(expression)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64), nil
+}
+
+// maxExpressionLength bounds calculator input so parsing stays cheap
+const maxExpressionLength = 256
+
+// evaluate parses and evaluates an arithmetic expression
+func evaluate(expression string) (float64, error) {
+	if len(expression) > maxExpressionLength {
+		return 0, fmt.Errorf("expression too long (max %d characters)", maxExpressionLength)
+	}
+
+	p := &exprParser{input: strings.TrimSpace(expression)}
+	value, err := p.parseExpr()
+	if err != nil {
+		return 0, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.input) {
+		return 0, fmt.Errorf("unexpected %q at position %d", p.input[p.pos], p.pos)
+	}
+	if math.IsInf(value, 0) || math.IsNaN(value) {
+		return 0, fmt.Errorf("result is not a finite number")
+	}
+	return value, nil
+}
+
+// exprParser is a recursive descent parser for arithmetic:
+//
+//	expr   = term { ("+" | "-") term }
+//	term   = factor { ("*" | "/") factor }
+//	factor = unary [ "^" factor ]
+//	unary  = [ "-" | "+" ] unary | primary
+//	primary = number | "(" expr ")"
+type exprParser struct {
+	input string
+	pos   int
+	depth int
+}
+
+// maxNestingDepth bounds parenthesis nesting to keep recursion shallow
+const maxNestingDepth = 32
+
+func (p *exprParser) skipSpace() {
+	for p.pos < len(p.input) && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *exprParser) peek() byte {
+	p.skipSpace()
+	if p.pos < len(p.input) {
+		return p.input[p.pos]
+	}
+	return 0
+}
+
+func (p *exprParser) parseExpr() (float64, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		op := p.peek()
+		if op != '+' && op != '-' {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseTerm()
+		if err != nil {
+			return 0, err
+		}
+		if op == '+' {
+			left += right
+		} else {
+			left -= right
+		}
+	}
+}
+
+func (p *exprParser) parseTerm() (float64, error) {
+	left, err := p.parseFactor()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		op := p.peek()
+		if op != '*' && op != '/' {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseFactor()
+		if err != nil {
+			return 0, err
+		}
+		if op == '*' {
+			left *= right
+		} else {
+			if right == 0 {
+				return 0, fmt.Errorf("division by zero")
+			}
+			left /= right
+		}
+	}
+}
+
+func (p *exprParser) parseFactor() (float64, error) {
+	base, err := p.parseUnary()
+	if err != nil {
+		return 0, err
+	}
+	if p.peek() != '^' {
+		return base, nil
+	}
+	p.pos++
+	exponent, err := p.parseFactor() // Right associative
+	if err != nil {
+		return 0, err
+	}
+	return math.Pow(base, exponent), nil
+}
+
+func (p *exprParser) parseUnary() (float64, error) {
+	switch p.peek() {
+	case '-':
+		p.pos++
+		value, err := p.parseUnary()
+		return -value, err
+	case '+':
+		p.pos++
+		return p.parseUnary()
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (float64, error) {
+	if p.peek() == '(' {
+		p.depth++
+		if p.depth > maxNestingDepth {
+			return 0, fmt.Errorf("expression nested too deeply")
+		}
+		p.pos++
+		value, err := p.parseExpr()
+		if err != nil {
+			return 0, err
+		}
+		if p.peek() != ')' {
+			return 0, fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos++
+		p.depth--
+		return value, nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.input) && (p.input[p.pos] >= '0' && p.input[p.pos] <= '9' || p.input[p.pos] == '.') {
+		p.pos++
+	}
+	if start == p.pos {
+		if p.pos >= len(p.input) {
+			return 0, fmt.Errorf("unexpected end of expression")
+		}
+		return 0, fmt.Errorf("unexpected %q at position %d", p.input[p.pos], p.pos)
+	}
+	return strconv.ParseFloat(p.input[start:p.pos], 64)
+}
@@ -0,0 +1,115 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"microchat.ai/cmd/server/llm"
+)
+
+const (
+	executionTimeout = 5 * time.Second // Upper bound for a single tool execution
+	maxOutputBytes   = 4 * 1024        // Tool output is truncated beyond this size
+)
+
+// Tool is a function the LLM can call
+// Implementations must be safe for concurrent use
+type Tool interface {
+	Definition() llm.ToolDefinition
+	Execute(ctx context.Context, args map[string]any) (string, error)
+}
+
+// Registry holds the tools enabled on this server
+type Registry struct {
+	tools map[string]Tool
+}
+
+// builtins are the tools available to enable by name
+var builtins = map[string]Tool{
+	"time":       timeTool{},
+	"calculator": calculatorTool{},
+}
+
+// BuiltinNames returns the names of all built-in tools
+func BuiltinNames() []string {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewRegistry creates a registry from the given tools
+func NewRegistry(tools ...Tool) *Registry {
+	r := &Registry{tools: make(map[string]Tool, len(tools))}
+	for _, tool := range tools {
+		r.tools[tool.Definition().Name] = tool
+	}
+	return r
+}
+
+// NewBuiltinRegistry creates a registry with the named built-in tools enabled
+func NewBuiltinRegistry(names []string) (*Registry, error) {
+	var enabled []Tool
+	for _, name := range names {
+		tool, ok := builtins[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown tool %q (available: %s)", name, strings.Join(BuiltinNames(), ", "))
+		}
+		enabled = append(enabled, tool)
+	}
+	return NewRegistry(enabled...), nil
+}
+
+// Definitions returns the tool definitions to send to the provider, sorted by name
+func (r *Registry) Definitions() []llm.ToolDefinition {
+	definitions := make([]llm.ToolDefinition, 0, len(r.tools))
+	for _, tool := range r.tools {
+		definitions = append(definitions, tool.Definition())
+	}
+	sort.Slice(definitions, func(i, j int) bool {
+		return definitions[i].Name < definitions[j].Name
+	})
+	return definitions
+}
+
+// Execute runs a tool call in a sandbox: bounded time, bounded output, and
+// panics recovered, so a misbehaving tool can't take down the request
+func (r *Registry) Execute(ctx context.Context, call llm.ToolCall) (output string, err error) {
+	tool, ok := r.tools[call.Name]
+	if !ok {
+		return "", fmt.Errorf("unknown tool %q", call.Name)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, executionTimeout)
+	defer cancel()
+
+	type result struct {
+		output string
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- result{err: fmt.Errorf("tool %s panicked: %v", call.Name, p)}
+			}
+		}()
+		output, err := tool.Execute(ctx, call.Args)
+		done <- result{output: output, err: err}
+	}()
+
+	select {
+	case res := <-done:
+		if len(res.output) > maxOutputBytes {
+			res.output = res.output[:maxOutputBytes] + "...[truncated]"
+		}
+		return res.output, res.err
+	case <-ctx.Done():
+		return "", fmt.Errorf("tool %s timed out", call.Name)
+	}
+}
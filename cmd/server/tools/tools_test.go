@@ -0,0 +1,137 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"microchat.ai/cmd/server/llm"
+)
+
+func TestCalculator(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"2+2", "4"},
+		{"(2 + 3) * 4.5", "22.5"},
+		{"2^10", "1024"},
+		{"-3 + 5", "2"},
+		{"10 / 4", "2.5"},
+		{"2 + 3 * 4", "14"},
+	}
+
+	registry, err := NewBuiltinRegistry([]string{"calculator"})
+	if err != nil {
+		t.Fatalf("NewBuiltinRegistry failed: %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			got, err := registry.Execute(context.Background(), llm.ToolCall{Name: "calculator", Args: map[string]any{"expression": tt.expression}})
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCalculatorErrors(t *testing.T) {
+	expressions := []string{"", "1 / 0", "2 +", "(1 + 2", "os.Exit(1)", strings.Repeat("1+", 200) + "1", strings.Repeat("(", 40) + "1" + strings.Repeat(")", 40)}
+
+	for _, expression := range expressions {
+		if _, err := (calculatorTool{}).Execute(context.Background(), map[string]any{"expression": expression}); err == nil {
+			t.Errorf("Expected error for %q", expression)
+		}
+	}
+}
+
+func TestTimeTool(t *testing.T) {
+	if _, err := (timeTool{}).Execute(context.Background(), nil); err != nil {
+		t.Errorf("Expected UTC time, got error: %v", err)
+	}
+	if _, err := (timeTool{}).Execute(context.Background(), map[string]any{"timezone": "Not/AZone"}); err == nil {
+		t.Error("Expected error for unknown time zone")
+	}
+}
+
+func TestNewBuiltinRegistry(t *testing.T) {
+	if _, err := NewBuiltinRegistry([]string{"time", "shell"}); err == nil {
+		t.Error("Expected error for unknown built-in tool")
+	}
+
+	registry, err := NewBuiltinRegistry([]string{"time", "calculator"})
+	if err != nil {
+		t.Fatalf("NewBuiltinRegistry failed: %v", err)
+	}
+	definitions := registry.Definitions()
+	if len(definitions) != 2 || definitions[0].Name != "calculator" || definitions[1].Name != "time" {
+		t.Errorf("Expected sorted definitions for calculator and time, got %+v", definitions)
+	}
+}
+
+// stubTool is a test tool with configurable behavior
+type stubTool struct {
+	execute func(ctx context.Context) (string, error)
+}
+
+func (s stubTool) Definition() llm.ToolDefinition {
+	return llm.ToolDefinition{Name: "stub"}
+}
+
+func (s stubTool) Execute(ctx context.Context, args map[string]any) (string, error) {
+	return s.execute(ctx)
+}
+
+func TestExecuteSandbox(t *testing.T) {
+	call := llm.ToolCall{Name: "stub"}
+
+	t.Run("unknown tool", func(t *testing.T) {
+		if _, err := NewRegistry().Execute(context.Background(), call); err == nil {
+			t.Error("Expected error for unknown tool")
+		}
+	})
+
+	t.Run("panic is recovered", func(t *testing.T) {
+		registry := NewRegistry(stubTool{execute: func(ctx context.Context) (string, error) {
+			panic("boom")
+		}})
+		if _, err := registry.Execute(context.Background(), call); err == nil || !strings.Contains(err.Error(), "panicked") {
+			t.Errorf("Expected panic to be reported as an error, got %v", err)
+		}
+	})
+
+	t.Run("output is truncated", func(t *testing.T) {
+		registry := NewRegistry(stubTool{execute: func(ctx context.Context) (string, error) {
+			return strings.Repeat("x", maxOutputBytes*2), nil
+		}})
+		output, err := registry.Execute(context.Background(), call)
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if len(output) > maxOutputBytes+len("...[truncated]") {
+			t.Errorf("Expected output to be truncated, got %d bytes", len(output))
+		}
+	})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		registry := NewRegistry(stubTool{execute: func(ctx context.Context) (string, error) {
+			select {
+			case <-ctx.Done():
+				return "", ctx.Err()
+			case <-time.After(time.Minute):
+				return "too slow", nil
+			}
+		}})
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := registry.Execute(ctx, call)
+		if err == nil {
+			t.Error("Expected error when the context is cancelled")
+		}
+	})
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"microchat.ai/cmd/server/archive"
+	"microchat.ai/cmd/server/llm"
+	"microchat.ai/cmd/server/tools"
+)
+
+const (
+	// minAPIKeyLength is the shortest API key accepted in production
+	minAPIKeyLength = 16
+
+	// certExpiryWarning is how close to expiry a TLS certificate starts producing a warning
+	certExpiryWarning = 14 * 24 * time.Hour
+
+	// providerCheckTimeout bounds the provider reachability checks as a whole
+	providerCheckTimeout = 30 * time.Second
+)
+
+// validationResult is the outcome of one -validate-config check
+type validationResult struct {
+	check   string
+	problem string // Empty when the check passed
+	warning bool   // The problem is worth fixing but won't stop the server
+}
+
+// validateConfig checks what loadConfig can't see from the environment alone: that TLS files
+// load, API keys are well formed, ports don't collide and LLM providers accept their credentials
+func validateConfig(ctx context.Context, cfg config, logger *slog.Logger) []validationResult {
+	var results []validationResult
+	results = append(results, checkTLSFiles(cfg.listeners, cfg.certificates, time.Now())...)
+	results = append(results, checkAPIKeys(cfg.apiKeys.Snapshot(), cfg.env)...)
+	results = append(results, checkPortCollisions(cfg)...)
+	results = append(results, checkStartupFiles(cfg)...)
+	results = append(results, checkArchiveStore(ctx, cfg.archiveStore)...)
+	results = append(results, checkProviders(ctx, logger)...)
+	return results
+}
+
+// checkTLSFiles loads every listener's certificate and key
+func checkTLSFiles(listeners []listenerConfig, certificates *certificateStore, now time.Time) []validationResult {
+	var results []validationResult
+	checked := make(map[[2]string]bool)
+	for _, l := range listeners {
+		if l.certFile == "" || checked[[2]string{l.certFile, l.keyFile}] {
+			continue
+		}
+		checked[[2]string{l.certFile, l.keyFile}] = true
+
+		result := validationResult{check: "TLS " + l.certFile}
+		pair, err := loadKeyPair(l.certFile, l.keyFile, certificates)
+		if err != nil {
+			result.problem = fmt.Sprintf("failed to load certificate and key: %v", err)
+			results = append(results, result)
+			continue
+		}
+		cert, err := x509.ParseCertificate(pair.Certificate[0])
+		if err != nil {
+			result.problem = fmt.Sprintf("failed to parse certificate: %v", err)
+			results = append(results, result)
+			continue
+		}
+		switch {
+		case now.After(cert.NotAfter):
+			result.problem = fmt.Sprintf("certificate expired %s", cert.NotAfter.Format(time.RFC3339))
+		case now.Before(cert.NotBefore):
+			result.problem = fmt.Sprintf("certificate not valid until %s", cert.NotBefore.Format(time.RFC3339))
+		case cert.NotAfter.Sub(now) < certExpiryWarning:
+			result.problem = fmt.Sprintf("certificate expires %s", cert.NotAfter.Format(time.RFC3339))
+			result.warning = true
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// checkAPIKeys rejects keys the auth interceptor would accept but clients can't reliably send,
+// and short keys in production
+func checkAPIKeys(apiKeys map[string]string, env string) []validationResult {
+	if len(apiKeys) == 0 {
+		return []validationResult{{check: "API keys", problem: "API_KEYS is empty - every authenticated RPC will be rejected"}}
+	}
+
+	keys := make([]string, 0, len(apiKeys))
+	admins := 0
+	for key, role := range apiKeys {
+		keys = append(keys, key)
+		if role == "admin" {
+			admins++
+		}
+	}
+	sort.Strings(keys) // Stable output order
+
+	var results []validationResult
+	for _, key := range keys {
+		// Identify keys by the same hash as metrics so the key itself is never printed
+		result := validationResult{check: "API key " + hashAPIKey(key)}
+		switch {
+		case strings.ContainsAny(key, " \t\r\n"):
+			result.problem = "contains whitespace"
+		case strings.Contains(key, ":"):
+			result.problem = "contains ':' - the only role suffix is :admin"
+		case len(key) < minAPIKeyLength:
+			result.problem = fmt.Sprintf("shorter than %d characters", minAPIKeyLength)
+			result.warning = env == "development"
+		}
+		if result.problem != "" {
+			results = append(results, result)
+		}
+	}
+	if admins == 0 {
+		results = append(results, validationResult{check: "API keys", problem: "no :admin key - admin RPCs, metrics and pprof are unreachable", warning: true})
+	}
+	if len(results) == 0 {
+		results = append(results, validationResult{check: "API keys"})
+	}
+	return results
+}
+
+// portBinding is a TCP address the server will bind
+type portBinding struct {
+	name string
+	host string
+	port int
+}
+
+// checkPortCollisions reports TCP addresses bound twice across gRPC listeners, pprof and metrics
+// Ports held by another process (such as the server being replaced) aren't checked, since
+// they're expected to be busy until the restart
+func checkPortCollisions(cfg config) []validationResult {
+	bindings := []portBinding{
+		{name: "PPROF_PORT", host: "127.0.0.1", port: cfg.pprofPort},
+		{name: "METRICS_PORT", host: "", port: cfg.metricsPort},
+	}
+	for _, l := range cfg.listeners {
+		if l.network == "unix" {
+			continue
+		}
+		host, portStr, err := net.SplitHostPort(l.address)
+		if err != nil {
+			continue
+		}
+		port, err := strconv.Atoi(portStr)
+		if err != nil || port == 0 {
+			continue
+		}
+		bindings = append(bindings, portBinding{name: "listener " + l.name, host: host, port: port})
+	}
+
+	var results []validationResult
+	for i := range bindings {
+		for _, other := range bindings[i+1:] {
+			if bindings[i].port == other.port && bindingsOverlap(bindings[i].host, other.host) {
+				results = append(results, validationResult{
+					check:   "ports",
+					problem: fmt.Sprintf("%s and %s both bind port %d", bindings[i].name, other.name, other.port),
+				})
+			}
+		}
+	}
+	if len(results) == 0 {
+		results = append(results, validationResult{check: "ports"})
+	}
+	return results
+}
+
+// bindingsOverlap reports whether two hosts on the same port would conflict
+// A wildcard host binds every address, so it conflicts with any other host
+func bindingsOverlap(a, b string) bool {
+	isWildcard := func(host string) bool {
+		ip := net.ParseIP(host)
+		return host == "" || (ip != nil && ip.IsUnspecified())
+	}
+	return a == b || isWildcard(a) || isWildcard(b)
+}
+
+// checkStartupFiles loads the files and settings main would otherwise fail on after startup
+func checkStartupFiles(cfg config) []validationResult {
+	var results []validationResult
+	check := func(name string, err error) {
+		result := validationResult{check: name}
+		if err != nil {
+			result.problem = err.Error()
+		}
+		results = append(results, result)
+	}
+
+	_, err := loadEncryptionKey()
+	check("session encryption key", err)
+	if cfg.promptTemplatesFile != "" {
+		_, err := loadPromptTemplates(cfg.promptTemplatesFile)
+		check("prompt templates", err)
+	}
+	_, err = newModerationPipeline(cfg)
+	check("content moderation", err)
+	if len(cfg.toolsEnabled) > 0 {
+		_, err := tools.NewBuiltinRegistry(cfg.toolsEnabled)
+		check("tools", err)
+	}
+	return results
+}
+
+// checkArchiveStore lists the session archive store, which verifies its credentials and bucket
+func checkArchiveStore(ctx context.Context, store archive.Store) []validationResult {
+	if store == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, providerCheckTimeout)
+	defer cancel()
+
+	result := validationResult{check: "archive store " + store.Name()}
+	if _, err := store.List(ctx); err != nil {
+		result.problem = err.Error()
+	}
+	return []validationResult{result}
+}
+
+// checkProviders health checks every configured LLM provider, which verifies its credentials
+// are accepted and its endpoint is reachable
+func checkProviders(ctx context.Context, logger *slog.Logger) []validationResult {
+	ctx, cancel := context.WithTimeout(ctx, providerCheckTimeout)
+	defer cancel()
+
+	monitor := llm.NewHealthMonitor(llm.DefaultHealthProbes(logger), logger)
+	monitor.CheckAll(ctx)
+
+	snapshot := monitor.Snapshot()
+	names := make([]string, 0, len(snapshot))
+	for name := range snapshot {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	results := make([]validationResult, 0, len(names))
+	for _, name := range names {
+		results = append(results, validationResult{check: "provider " + name, problem: snapshot[name].LastError})
+	}
+	return results
+}
+
+// printValidationResults writes one line per check and reports whether any check failed
+func printValidationResults(w io.Writer, results []validationResult) bool {
+	failed := false
+	for _, r := range results {
+		switch {
+		case r.problem == "":
+			fmt.Fprintf(w, "ok    %s\n", r.check)
+		case r.warning:
+			fmt.Fprintf(w, "warn  %s: %s\n", r.check, r.problem)
+		default:
+			fmt.Fprintf(w, "FAIL  %s: %s\n", r.check, r.problem)
+			failed = true
+		}
+	}
+	return failed
+}
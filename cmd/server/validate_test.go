@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTestCert writes a self-signed certificate valid until notAfter and returns its cert and key paths
+func writeTestCert(t *testing.T, notAfter time.Time) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestCheckTLSFiles(t *testing.T) {
+	now := time.Now()
+	validCert, validKey := writeTestCert(t, now.Add(90*24*time.Hour))
+	expiringCert, expiringKey := writeTestCert(t, now.Add(24*time.Hour))
+	expiredCert, expiredKey := writeTestCert(t, now.Add(-time.Hour))
+
+	results := checkTLSFiles([]listenerConfig{
+		{name: "valid", certFile: validCert, keyFile: validKey},
+		{name: "shared", certFile: validCert, keyFile: validKey}, // Checked once
+		{name: "expiring", certFile: expiringCert, keyFile: expiringKey},
+		{name: "expired", certFile: expiredCert, keyFile: expiredKey},
+		{name: "missing", certFile: "/nonexistent/server.crt", keyFile: "/nonexistent/server.key"},
+		{name: "plaintext"},
+	}, nil, now)
+
+	if len(results) != 4 {
+		t.Fatalf("Expected 4 results, got %+v", results)
+	}
+	if results[0].problem != "" {
+		t.Errorf("Expected valid certificate to pass, got %q", results[0].problem)
+	}
+	if !results[1].warning || !strings.Contains(results[1].problem, "expires") {
+		t.Errorf("Expected a warning for an expiring certificate, got %+v", results[1])
+	}
+	if results[2].warning || !strings.Contains(results[2].problem, "expired") {
+		t.Errorf("Expected a failure for an expired certificate, got %+v", results[2])
+	}
+	if results[3].warning || results[3].problem == "" {
+		t.Errorf("Expected a failure for missing files, got %+v", results[3])
+	}
+}
+
+func TestCheckAPIKeys(t *testing.T) {
+	if results := checkAPIKeys(nil, "production"); len(results) != 1 || results[0].problem == "" || results[0].warning {
+		t.Errorf("Expected no keys to fail, got %+v", results)
+	}
+
+	results := checkAPIKeys(map[string]string{
+		"a-long-enough-admin-key": "admin",
+		"a-long-enough-user-key":  "user",
+	}, "production")
+	if len(results) != 1 || results[0].problem != "" {
+		t.Errorf("Expected well-formed keys to pass, got %+v", results)
+	}
+
+	results = checkAPIKeys(map[string]string{
+		"short":                      "user",
+		"a-long-enough-key:readonly": "user",
+	}, "production")
+	failures, warnings := 0, 0
+	for _, r := range results {
+		if strings.Contains(r.check, "short") || strings.Contains(r.check, "readonly") {
+			t.Errorf("Expected keys to be hashed in output, got %q", r.check)
+		}
+		switch {
+		case r.warning:
+			warnings++
+		case r.problem != "":
+			failures++
+		}
+	}
+	if failures != 2 || warnings != 1 {
+		t.Errorf("Expected 2 failures and a missing admin warning, got %+v", results)
+	}
+
+	// Short keys are fine for local development
+	results = checkAPIKeys(map[string]string{"dev-key-1": "admin"}, "development")
+	if len(results) != 1 || !results[0].warning {
+		t.Errorf("Expected a short key warning in development, got %+v", results)
+	}
+}
+
+func TestCheckPortCollisions(t *testing.T) {
+	cfg := config{
+		pprofPort:   6060,
+		metricsPort: 9090,
+		listeners: []listenerConfig{
+			{name: "tls://:4000", network: "tcp", address: ":4000"},
+			{name: "tcp://127.0.0.1:4001", network: "tcp", address: "127.0.0.1:4001"},
+			{name: "unix:///tmp/microchat.sock", network: "unix", address: "/tmp/microchat.sock"},
+		},
+	}
+	if results := checkPortCollisions(cfg); len(results) != 1 || results[0].problem != "" {
+		t.Errorf("Expected distinct ports to pass, got %+v", results)
+	}
+
+	cfg.metricsPort = 4001 // Wildcard metrics server overlaps the loopback listener
+	cfg.pprofPort = 4000
+	results := checkPortCollisions(cfg)
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 collisions, got %+v", results)
+	}
+	for _, r := range results {
+		if r.problem == "" || r.warning {
+			t.Errorf("Expected collision to fail, got %+v", r)
+		}
+	}
+
+	// Different specific hosts can share a port
+	if bindingsOverlap("127.0.0.1", "10.0.0.1") {
+		t.Error("Expected distinct hosts not to overlap")
+	}
+	if !bindingsOverlap("::", "127.0.0.1") {
+		t.Error("Expected unspecified address to overlap any host")
+	}
+}
+
+func TestPrintValidationResults(t *testing.T) {
+	var buf bytes.Buffer
+	failed := printValidationResults(&buf, []validationResult{
+		{check: "ports"},
+		{check: "API keys", problem: "no :admin key", warning: true},
+	})
+	if failed {
+		t.Error("Expected warnings alone not to fail validation")
+	}
+	if !strings.Contains(buf.String(), "ok    ports") || !strings.Contains(buf.String(), "warn  API keys: no :admin key") {
+		t.Errorf("Unexpected output:\n%s", buf.String())
+	}
+
+	if !printValidationResults(&buf, []validationResult{{check: "provider Gemini", problem: "invalid API key"}}) {
+		t.Error("Expected a failed check to fail validation")
+	}
+}
@@ -15,8 +15,14 @@ echo "building & deploying microchat.ai..."
 # Update code (fast-forward only, prevents merge conflicts)
 git pull --ff-only origin main
 
-# Build server (no sudo needed - user owns the directory)
-go build -o server cmd/server/*.go
+# Build server (no sudo needed - user owns the directory), stamping the version for -version and GetServerInfo
+VERSION_PKG=microchat.ai/version
+go build -ldflags "-X $VERSION_PKG.Version=$(git describe --tags --always --dirty) \
+  -X $VERSION_PKG.Commit=$(git rev-parse --short HEAD) \
+  -X $VERSION_PKG.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)" -o server ./cmd/server
+
+# Check the configuration before restarting so a bad .env doesn't take the service down
+./server -validate-config
 
 # Restart systemd service if it exists
 # Requires sudoers entry: microchat ALL=(ALL) NOPASSWD: /bin/systemctl restart microchat
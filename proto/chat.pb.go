@@ -26,6 +26,7 @@ type Model int32
 const (
 	Model_GEMINI_2_5_FLASH_LITE Model = 0 // default = 0 bytes in payload
 	Model_ECHO                  Model = 1 // Development/testing only
+	Model_OPENAI_COMPATIBLE     Model = 2 // Self-hosted OpenAI-compatible endpoint (vLLM, llama.cpp, LM Studio)
 )
 
 // Enum value maps for Model.
@@ -33,10 +34,12 @@ var (
 	Model_name = map[int32]string{
 		0: "GEMINI_2_5_FLASH_LITE",
 		1: "ECHO",
+		2: "OPENAI_COMPATIBLE",
 	}
 	Model_value = map[string]int32{
 		"GEMINI_2_5_FLASH_LITE": 0,
 		"ECHO":                  1,
+		"OPENAI_COMPATIBLE":     2,
 	}
 )
 
@@ -67,10 +70,103 @@ func (Model) EnumDescriptor() ([]byte, []int) {
 	return file_proto_chat_proto_rawDescGZIP(), []int{0}
 }
 
+type ExportFormat int32
+
+const (
+	ExportFormat_EXPORT_JSON     ExportFormat = 0 // Structured JSON transcript
+	ExportFormat_EXPORT_MARKDOWN ExportFormat = 1 // Human-readable Markdown transcript
+)
+
+// Enum value maps for ExportFormat.
+var (
+	ExportFormat_name = map[int32]string{
+		0: "EXPORT_JSON",
+		1: "EXPORT_MARKDOWN",
+	}
+	ExportFormat_value = map[string]int32{
+		"EXPORT_JSON":     0,
+		"EXPORT_MARKDOWN": 1,
+	}
+)
+
+func (x ExportFormat) Enum() *ExportFormat {
+	p := new(ExportFormat)
+	*p = x
+	return p
+}
+
+func (x ExportFormat) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ExportFormat) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_chat_proto_enumTypes[1].Descriptor()
+}
+
+func (ExportFormat) Type() protoreflect.EnumType {
+	return &file_proto_chat_proto_enumTypes[1]
+}
+
+func (x ExportFormat) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ExportFormat.Descriptor instead.
+func (ExportFormat) EnumDescriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{1}
+}
+
+type ResponseFormat int32
+
+const (
+	ResponseFormat_RESPONSE_TEXT ResponseFormat = 0 // Free-form text reply
+	ResponseFormat_RESPONSE_JSON ResponseFormat = 1 // Reply must be a valid JSON value
+)
+
+// Enum value maps for ResponseFormat.
+var (
+	ResponseFormat_name = map[int32]string{
+		0: "RESPONSE_TEXT",
+		1: "RESPONSE_JSON",
+	}
+	ResponseFormat_value = map[string]int32{
+		"RESPONSE_TEXT": 0,
+		"RESPONSE_JSON": 1,
+	}
+)
+
+func (x ResponseFormat) Enum() *ResponseFormat {
+	p := new(ResponseFormat)
+	*p = x
+	return p
+}
+
+func (x ResponseFormat) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ResponseFormat) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_chat_proto_enumTypes[2].Descriptor()
+}
+
+func (ResponseFormat) Type() protoreflect.EnumType {
+	return &file_proto_chat_proto_enumTypes[2]
+}
+
+func (x ResponseFormat) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ResponseFormat.Descriptor instead.
+func (ResponseFormat) EnumDescriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{2}
+}
+
 type StartSessionRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	IdleTimeoutSeconds uint32                 `protobuf:"varint,1,opt,name=idle_timeout_seconds,json=idleTimeoutSeconds,proto3" json:"idle_timeout_seconds,omitempty"` // Requested idle timeout, 0 for server default
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
 }
 
 func (x *StartSessionRequest) Reset() {
@@ -103,11 +199,19 @@ func (*StartSessionRequest) Descriptor() ([]byte, []int) {
 	return file_proto_chat_proto_rawDescGZIP(), []int{0}
 }
 
+func (x *StartSessionRequest) GetIdleTimeoutSeconds() uint32 {
+	if x != nil {
+		return x.IdleTimeoutSeconds
+	}
+	return 0
+}
+
 type StartSessionResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"` // Server-generated UUID session ID
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	SessionId          string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`                               // Server-generated UUID session ID
+	IdleTimeoutSeconds uint32                 `protobuf:"varint,2,opt,name=idle_timeout_seconds,json=idleTimeoutSeconds,proto3" json:"idle_timeout_seconds,omitempty"` // Idle timeout applied after clamping to server bounds
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
 }
 
 func (x *StartSessionResponse) Reset() {
@@ -147,14 +251,28 @@ func (x *StartSessionResponse) GetSessionId() string {
 	return ""
 }
 
+func (x *StartSessionResponse) GetIdleTimeoutSeconds() uint32 {
+	if x != nil {
+		return x.IdleTimeoutSeconds
+	}
+	return 0
+}
+
 type ChatRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`           // Server-generated UUID session ID
-	Model         Model                  `protobuf:"varint,2,opt,name=model,proto3,enum=chat.Model" json:"model,omitempty"`                   // enum, defaults to 0
-	Message       string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`                                // your actual chat message
-	MessageIndex  uint32                 `protobuf:"varint,4,opt,name=message_index,json=messageIndex,proto3" json:"message_index,omitempty"` // Index of last message client has, 0 for full context
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	SessionId      string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`                                                                                     // Server-generated UUID session ID
+	Model          Model                  `protobuf:"varint,2,opt,name=model,proto3,enum=chat.Model" json:"model,omitempty"`                                                                                             // enum, defaults to 0
+	Message        string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`                                                                                                          // your actual chat message
+	MessageIndex   uint32                 `protobuf:"varint,4,opt,name=message_index,json=messageIndex,proto3" json:"message_index,omitempty"`                                                                           // Index of last message client has, 0 for full context
+	ResponseFormat ResponseFormat         `protobuf:"varint,5,opt,name=response_format,json=responseFormat,proto3,enum=chat.ResponseFormat" json:"response_format,omitempty"`                                            // enum, defaults to free text
+	ResponseSchema string                 `protobuf:"bytes,6,opt,name=response_schema,json=responseSchema,proto3" json:"response_schema,omitempty"`                                                                      // Optional JSON Schema the reply must match (RESPONSE_JSON only)
+	Attachments    []*Attachment          `protobuf:"bytes,7,rep,name=attachments,proto3" json:"attachments,omitempty"`                                                                                                  // Optional images sent with this message only
+	UseKnowledge   bool                   `protobuf:"varint,8,opt,name=use_knowledge,json=useKnowledge,proto3" json:"use_knowledge,omitempty"`                                                                           // Retrieve relevant excerpts from uploaded documents into the prompt
+	Template       string                 `protobuf:"bytes,9,opt,name=template,proto3" json:"template,omitempty"`                                                                                                        // Optional server-defined prompt template (persona/system behavior)
+	TemplateVars   map[string]string      `protobuf:"bytes,10,rep,name=template_vars,json=templateVars,proto3" json:"template_vars,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // Values for the template's {{variables}}
+	SystemPrompt   string                 `protobuf:"bytes,11,opt,name=system_prompt,json=systemPrompt,proto3" json:"system_prompt,omitempty"`                                                                           // Optional caller-written system prompt, sent with every message of a session
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
 func (x *ChatRequest) Reset() {
@@ -215,18 +333,124 @@ func (x *ChatRequest) GetMessageIndex() uint32 {
 	return 0
 }
 
-type ChatResponse struct {
+func (x *ChatRequest) GetResponseFormat() ResponseFormat {
+	if x != nil {
+		return x.ResponseFormat
+	}
+	return ResponseFormat_RESPONSE_TEXT
+}
+
+func (x *ChatRequest) GetResponseSchema() string {
+	if x != nil {
+		return x.ResponseSchema
+	}
+	return ""
+}
+
+func (x *ChatRequest) GetAttachments() []*Attachment {
+	if x != nil {
+		return x.Attachments
+	}
+	return nil
+}
+
+func (x *ChatRequest) GetUseKnowledge() bool {
+	if x != nil {
+		return x.UseKnowledge
+	}
+	return false
+}
+
+func (x *ChatRequest) GetTemplate() string {
+	if x != nil {
+		return x.Template
+	}
+	return ""
+}
+
+func (x *ChatRequest) GetTemplateVars() map[string]string {
+	if x != nil {
+		return x.TemplateVars
+	}
+	return nil
+}
+
+func (x *ChatRequest) GetSystemPrompt() string {
+	if x != nil {
+		return x.SystemPrompt
+	}
+	return ""
+}
+
+type Attachment struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"` // Server-generated UUID session ID
-	Reply         string                 `protobuf:"bytes,2,opt,name=reply,proto3" json:"reply,omitempty"`
-	MessageCount  uint32                 `protobuf:"varint,3,opt,name=message_count,json=messageCount,proto3" json:"message_count,omitempty"` // Total messages in session after this response
+	MimeType      string                 `protobuf:"bytes,1,opt,name=mime_type,json=mimeType,proto3" json:"mime_type,omitempty"` // e.g. image/png, image/jpeg, image/webp
+	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`                         // Raw file bytes
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
+func (x *Attachment) Reset() {
+	*x = Attachment{}
+	mi := &file_proto_chat_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Attachment) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Attachment) ProtoMessage() {}
+
+func (x *Attachment) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Attachment.ProtoReflect.Descriptor instead.
+func (*Attachment) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Attachment) GetMimeType() string {
+	if x != nil {
+		return x.MimeType
+	}
+	return ""
+}
+
+func (x *Attachment) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type ChatResponse struct {
+	state                  protoimpl.MessageState `protogen:"open.v1"`
+	SessionId              string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"` // Server-generated UUID session ID
+	Reply                  string                 `protobuf:"bytes,2,opt,name=reply,proto3" json:"reply,omitempty"`
+	MessageCount           uint32                 `protobuf:"varint,3,opt,name=message_count,json=messageCount,proto3" json:"message_count,omitempty"`                                 // Total messages in session after this response
+	KnowledgeSources       []string               `protobuf:"bytes,4,rep,name=knowledge_sources,json=knowledgeSources,proto3" json:"knowledge_sources,omitempty"`                      // Names of documents whose excerpts were used (use_knowledge only)
+	SessionTokensUsed      uint64                 `protobuf:"varint,5,opt,name=session_tokens_used,json=sessionTokensUsed,proto3" json:"session_tokens_used,omitempty"`                // LLM tokens the session has consumed, including this reply
+	SessionTokenBudget     uint64                 `protobuf:"varint,6,opt,name=session_token_budget,json=sessionTokenBudget,proto3" json:"session_token_budget,omitempty"`             // Session's token ceiling, 0 when unlimited
+	SessionTokensRemaining uint64                 `protobuf:"varint,7,opt,name=session_tokens_remaining,json=sessionTokensRemaining,proto3" json:"session_tokens_remaining,omitempty"` // Tokens left before the session must be replaced (0 when unlimited)
+	HistoryDiverged        bool                   `protobuf:"varint,8,opt,name=history_diverged,json=historyDiverged,proto3" json:"history_diverged,omitempty"`                        // message_index didn't match the session's count, so the client's copy of the history is out of date
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
+}
+
 func (x *ChatResponse) Reset() {
 	*x = ChatResponse{}
-	mi := &file_proto_chat_proto_msgTypes[3]
+	mi := &file_proto_chat_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -238,7 +462,7 @@ func (x *ChatResponse) String() string {
 func (*ChatResponse) ProtoMessage() {}
 
 func (x *ChatResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_chat_proto_msgTypes[3]
+	mi := &file_proto_chat_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -251,7 +475,7 @@ func (x *ChatResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ChatResponse.ProtoReflect.Descriptor instead.
 func (*ChatResponse) Descriptor() ([]byte, []int) {
-	return file_proto_chat_proto_rawDescGZIP(), []int{3}
+	return file_proto_chat_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *ChatResponse) GetSessionId() string {
@@ -275,6 +499,41 @@ func (x *ChatResponse) GetMessageCount() uint32 {
 	return 0
 }
 
+func (x *ChatResponse) GetKnowledgeSources() []string {
+	if x != nil {
+		return x.KnowledgeSources
+	}
+	return nil
+}
+
+func (x *ChatResponse) GetSessionTokensUsed() uint64 {
+	if x != nil {
+		return x.SessionTokensUsed
+	}
+	return 0
+}
+
+func (x *ChatResponse) GetSessionTokenBudget() uint64 {
+	if x != nil {
+		return x.SessionTokenBudget
+	}
+	return 0
+}
+
+func (x *ChatResponse) GetSessionTokensRemaining() uint64 {
+	if x != nil {
+		return x.SessionTokensRemaining
+	}
+	return 0
+}
+
+func (x *ChatResponse) GetHistoryDiverged() bool {
+	if x != nil {
+		return x.HistoryDiverged
+	}
+	return false
+}
+
 type HealthRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	unknownFields protoimpl.UnknownFields
@@ -283,7 +542,7 @@ type HealthRequest struct {
 
 func (x *HealthRequest) Reset() {
 	*x = HealthRequest{}
-	mi := &file_proto_chat_proto_msgTypes[4]
+	mi := &file_proto_chat_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -295,7 +554,7 @@ func (x *HealthRequest) String() string {
 func (*HealthRequest) ProtoMessage() {}
 
 func (x *HealthRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_chat_proto_msgTypes[4]
+	mi := &file_proto_chat_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -308,7 +567,7 @@ func (x *HealthRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use HealthRequest.ProtoReflect.Descriptor instead.
 func (*HealthRequest) Descriptor() ([]byte, []int) {
-	return file_proto_chat_proto_rawDescGZIP(), []int{4}
+	return file_proto_chat_proto_rawDescGZIP(), []int{5}
 }
 
 type HealthResponse struct {
@@ -320,7 +579,7 @@ type HealthResponse struct {
 
 func (x *HealthResponse) Reset() {
 	*x = HealthResponse{}
-	mi := &file_proto_chat_proto_msgTypes[5]
+	mi := &file_proto_chat_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -332,7 +591,7 @@ func (x *HealthResponse) String() string {
 func (*HealthResponse) ProtoMessage() {}
 
 func (x *HealthResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_chat_proto_msgTypes[5]
+	mi := &file_proto_chat_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -345,7 +604,7 @@ func (x *HealthResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use HealthResponse.ProtoReflect.Descriptor instead.
 func (*HealthResponse) Descriptor() ([]byte, []int) {
-	return file_proto_chat_proto_rawDescGZIP(), []int{5}
+	return file_proto_chat_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *HealthResponse) GetOk() bool {
@@ -364,7 +623,7 @@ type GetHistoryRequest struct {
 
 func (x *GetHistoryRequest) Reset() {
 	*x = GetHistoryRequest{}
-	mi := &file_proto_chat_proto_msgTypes[6]
+	mi := &file_proto_chat_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -376,7 +635,7 @@ func (x *GetHistoryRequest) String() string {
 func (*GetHistoryRequest) ProtoMessage() {}
 
 func (x *GetHistoryRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_chat_proto_msgTypes[6]
+	mi := &file_proto_chat_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -389,7 +648,7 @@ func (x *GetHistoryRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetHistoryRequest.ProtoReflect.Descriptor instead.
 func (*GetHistoryRequest) Descriptor() ([]byte, []int) {
-	return file_proto_chat_proto_rawDescGZIP(), []int{6}
+	return file_proto_chat_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *GetHistoryRequest) GetSessionId() string {
@@ -400,16 +659,18 @@ func (x *GetHistoryRequest) GetSessionId() string {
 }
 
 type GetHistoryResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"` // Session ID
-	Messages      []string               `protobuf:"bytes,2,rep,name=messages,proto3" json:"messages,omitempty"`                    // All messages in session
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	SessionId      string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`                        // Session ID
+	Messages       []string               `protobuf:"bytes,2,rep,name=messages,proto3" json:"messages,omitempty"`                                           // All messages in session
+	Encrypted      bool                   `protobuf:"varint,3,opt,name=encrypted,proto3" json:"encrypted,omitempty"`                                        // Message text is base64 ciphertext sealed with the client-supplied key
+	TimestampsUnix []int64                `protobuf:"varint,4,rep,packed,name=timestamps_unix,json=timestampsUnix,proto3" json:"timestamps_unix,omitempty"` // Each message's time as Unix timestamp (seconds), in the order of messages
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
 func (x *GetHistoryResponse) Reset() {
 	*x = GetHistoryResponse{}
-	mi := &file_proto_chat_proto_msgTypes[7]
+	mi := &file_proto_chat_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -421,7 +682,7 @@ func (x *GetHistoryResponse) String() string {
 func (*GetHistoryResponse) ProtoMessage() {}
 
 func (x *GetHistoryResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_chat_proto_msgTypes[7]
+	mi := &file_proto_chat_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -434,7 +695,7 @@ func (x *GetHistoryResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetHistoryResponse.ProtoReflect.Descriptor instead.
 func (*GetHistoryResponse) Descriptor() ([]byte, []int) {
-	return file_proto_chat_proto_rawDescGZIP(), []int{7}
+	return file_proto_chat_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *GetHistoryResponse) GetSessionId() string {
@@ -451,45 +712,2975 @@ func (x *GetHistoryResponse) GetMessages() []string {
 	return nil
 }
 
-var File_proto_chat_proto protoreflect.FileDescriptor
+func (x *GetHistoryResponse) GetEncrypted() bool {
+	if x != nil {
+		return x.Encrypted
+	}
+	return false
+}
 
-const file_proto_chat_proto_rawDesc = "" +
-	"\n" +
-	"\x10proto/chat.proto\x12\x04chat\"\x15\n" +
-	"\x13StartSessionRequest\"5\n" +
-	"\x14StartSessionResponse\x12\x1d\n" +
-	"\n" +
-	"session_id\x18\x01 \x01(\tR\tsessionId\"\x8e\x01\n" +
-	"\vChatRequest\x12\x1d\n" +
-	"\n" +
-	"session_id\x18\x01 \x01(\tR\tsessionId\x12!\n" +
-	"\x05model\x18\x02 \x01(\x0e2\v.chat.ModelR\x05model\x12\x18\n" +
-	"\amessage\x18\x03 \x01(\tR\amessage\x12#\n" +
-	"\rmessage_index\x18\x04 \x01(\rR\fmessageIndex\"h\n" +
-	"\fChatResponse\x12\x1d\n" +
-	"\n" +
-	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x14\n" +
-	"\x05reply\x18\x02 \x01(\tR\x05reply\x12#\n" +
-	"\rmessage_count\x18\x03 \x01(\rR\fmessageCount\"\x0f\n" +
-	"\rHealthRequest\" \n" +
-	"\x0eHealthResponse\x12\x0e\n" +
-	"\x02ok\x18\x01 \x01(\bR\x02ok\"2\n" +
-	"\x11GetHistoryRequest\x12\x1d\n" +
-	"\n" +
-	"session_id\x18\x01 \x01(\tR\tsessionId\"O\n" +
-	"\x12GetHistoryResponse\x12\x1d\n" +
-	"\n" +
-	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x1a\n" +
-	"\bmessages\x18\x02 \x03(\tR\bmessages*,\n" +
-	"\x05Model\x12\x19\n" +
-	"\x15GEMINI_2_5_FLASH_LITE\x10\x00\x12\b\n" +
-	"\x04ECHO\x10\x012\xf9\x01\n" +
-	"\vChatService\x12E\n" +
-	"\fStartSession\x12\x19.chat.StartSessionRequest\x1a\x1a.chat.StartSessionResponse\x12-\n" +
-	"\x04Chat\x12\x11.chat.ChatRequest\x1a\x12.chat.ChatResponse\x123\n" +
-	"\x06Health\x12\x13.chat.HealthRequest\x1a\x14.chat.HealthResponse\x12?\n" +
+func (x *GetHistoryResponse) GetTimestampsUnix() []int64 {
+	if x != nil {
+		return x.TimestampsUnix
+	}
+	return nil
+}
+
+type ExportSessionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`  // Session to export
+	Format        ExportFormat           `protobuf:"varint,2,opt,name=format,proto3,enum=chat.ExportFormat" json:"format,omitempty"` // enum, defaults to JSON
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExportSessionRequest) Reset() {
+	*x = ExportSessionRequest{}
+	mi := &file_proto_chat_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExportSessionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExportSessionRequest) ProtoMessage() {}
+
+func (x *ExportSessionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExportSessionRequest.ProtoReflect.Descriptor instead.
+func (*ExportSessionRequest) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ExportSessionRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *ExportSessionRequest) GetFormat() ExportFormat {
+	if x != nil {
+		return x.Format
+	}
+	return ExportFormat_EXPORT_JSON
+}
+
+type ExportSessionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`  // Session ID
+	Format        ExportFormat           `protobuf:"varint,2,opt,name=format,proto3,enum=chat.ExportFormat" json:"format,omitempty"` // Format of the rendered content
+	Content       string                 `protobuf:"bytes,3,opt,name=content,proto3" json:"content,omitempty"`                       // Rendered transcript with metadata
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExportSessionResponse) Reset() {
+	*x = ExportSessionResponse{}
+	mi := &file_proto_chat_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExportSessionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExportSessionResponse) ProtoMessage() {}
+
+func (x *ExportSessionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExportSessionResponse.ProtoReflect.Descriptor instead.
+func (*ExportSessionResponse) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ExportSessionResponse) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *ExportSessionResponse) GetFormat() ExportFormat {
+	if x != nil {
+		return x.Format
+	}
+	return ExportFormat_EXPORT_JSON
+}
+
+func (x *ExportSessionResponse) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+type ListSessionsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSessionsRequest) Reset() {
+	*x = ListSessionsRequest{}
+	mi := &file_proto_chat_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSessionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSessionsRequest) ProtoMessage() {}
+
+func (x *ListSessionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSessionsRequest.ProtoReflect.Descriptor instead.
+func (*ListSessionsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{11}
+}
+
+type SessionInfo struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	SessionId      string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`                   // Session ID
+	Title          string                 `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`                                            // Auto-generated title, empty until first exchange
+	MessageCount   uint32                 `protobuf:"varint,3,opt,name=message_count,json=messageCount,proto3" json:"message_count,omitempty"`         // Messages stored in session
+	SizeBytes      uint32                 `protobuf:"varint,4,opt,name=size_bytes,json=sizeBytes,proto3" json:"size_bytes,omitempty"`                  // Approximate memory usage of session
+	LastActiveUnix int64                  `protobuf:"varint,5,opt,name=last_active_unix,json=lastActiveUnix,proto3" json:"last_active_unix,omitempty"` // Last activity as Unix timestamp (seconds)
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *SessionInfo) Reset() {
+	*x = SessionInfo{}
+	mi := &file_proto_chat_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SessionInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SessionInfo) ProtoMessage() {}
+
+func (x *SessionInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SessionInfo.ProtoReflect.Descriptor instead.
+func (*SessionInfo) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *SessionInfo) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *SessionInfo) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *SessionInfo) GetMessageCount() uint32 {
+	if x != nil {
+		return x.MessageCount
+	}
+	return 0
+}
+
+func (x *SessionInfo) GetSizeBytes() uint32 {
+	if x != nil {
+		return x.SizeBytes
+	}
+	return 0
+}
+
+func (x *SessionInfo) GetLastActiveUnix() int64 {
+	if x != nil {
+		return x.LastActiveUnix
+	}
+	return 0
+}
+
+type ListSessionsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Sessions      []*SessionInfo         `protobuf:"bytes,1,rep,name=sessions,proto3" json:"sessions,omitempty"` // All active sessions
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSessionsResponse) Reset() {
+	*x = ListSessionsResponse{}
+	mi := &file_proto_chat_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSessionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSessionsResponse) ProtoMessage() {}
+
+func (x *ListSessionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSessionsResponse.ProtoReflect.Descriptor instead.
+func (*ListSessionsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ListSessionsResponse) GetSessions() []*SessionInfo {
+	if x != nil {
+		return x.Sessions
+	}
+	return nil
+}
+
+type ListMySessionsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListMySessionsRequest) Reset() {
+	*x = ListMySessionsRequest{}
+	mi := &file_proto_chat_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListMySessionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListMySessionsRequest) ProtoMessage() {}
+
+func (x *ListMySessionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListMySessionsRequest.ProtoReflect.Descriptor instead.
+func (*ListMySessionsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{14}
+}
+
+type ListMySessionsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Sessions      []*SessionInfo         `protobuf:"bytes,1,rep,name=sessions,proto3" json:"sessions,omitempty"` // Sessions created by the caller's API key
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListMySessionsResponse) Reset() {
+	*x = ListMySessionsResponse{}
+	mi := &file_proto_chat_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListMySessionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListMySessionsResponse) ProtoMessage() {}
+
+func (x *ListMySessionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListMySessionsResponse.ProtoReflect.Descriptor instead.
+func (*ListMySessionsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ListMySessionsResponse) GetSessions() []*SessionInfo {
+	if x != nil {
+		return x.Sessions
+	}
+	return nil
+}
+
+type EmbedRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Model         Model                  `protobuf:"varint,1,opt,name=model,proto3,enum=chat.Model" json:"model,omitempty"` // Selects the embedding provider, defaults to Gemini
+	Texts         []string               `protobuf:"bytes,2,rep,name=texts,proto3" json:"texts,omitempty"`                  // Texts to embed, one vector each
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EmbedRequest) Reset() {
+	*x = EmbedRequest{}
+	mi := &file_proto_chat_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EmbedRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EmbedRequest) ProtoMessage() {}
+
+func (x *EmbedRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EmbedRequest.ProtoReflect.Descriptor instead.
+func (*EmbedRequest) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *EmbedRequest) GetModel() Model {
+	if x != nil {
+		return x.Model
+	}
+	return Model_GEMINI_2_5_FLASH_LITE
+}
+
+func (x *EmbedRequest) GetTexts() []string {
+	if x != nil {
+		return x.Texts
+	}
+	return nil
+}
+
+type Embedding struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Values        []float32              `protobuf:"fixed32,1,rep,packed,name=values,proto3" json:"values,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Embedding) Reset() {
+	*x = Embedding{}
+	mi := &file_proto_chat_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Embedding) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Embedding) ProtoMessage() {}
+
+func (x *Embedding) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Embedding.ProtoReflect.Descriptor instead.
+func (*Embedding) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *Embedding) GetValues() []float32 {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+type EmbedResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Provider      string                 `protobuf:"bytes,1,opt,name=provider,proto3" json:"provider,omitempty"`      // Embedding provider that produced the vectors
+	Dimensions    uint32                 `protobuf:"varint,2,opt,name=dimensions,proto3" json:"dimensions,omitempty"` // Length of each vector
+	Embeddings    []*Embedding           `protobuf:"bytes,3,rep,name=embeddings,proto3" json:"embeddings,omitempty"`  // One per input text, in order
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EmbedResponse) Reset() {
+	*x = EmbedResponse{}
+	mi := &file_proto_chat_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EmbedResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EmbedResponse) ProtoMessage() {}
+
+func (x *EmbedResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EmbedResponse.ProtoReflect.Descriptor instead.
+func (*EmbedResponse) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *EmbedResponse) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+func (x *EmbedResponse) GetDimensions() uint32 {
+	if x != nil {
+		return x.Dimensions
+	}
+	return 0
+}
+
+func (x *EmbedResponse) GetEmbeddings() []*Embedding {
+	if x != nil {
+		return x.Embeddings
+	}
+	return nil
+}
+
+type UploadDocumentRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`       // Display name, shown as the source of retrieved excerpts
+	Content       string                 `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"` // Plain text content
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UploadDocumentRequest) Reset() {
+	*x = UploadDocumentRequest{}
+	mi := &file_proto_chat_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UploadDocumentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadDocumentRequest) ProtoMessage() {}
+
+func (x *UploadDocumentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadDocumentRequest.ProtoReflect.Descriptor instead.
+func (*UploadDocumentRequest) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *UploadDocumentRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *UploadDocumentRequest) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+type UploadDocumentResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DocumentId    string                 `protobuf:"bytes,1,opt,name=document_id,json=documentId,proto3" json:"document_id,omitempty"`  // ID to delete the document with
+	ChunkCount    uint32                 `protobuf:"varint,2,opt,name=chunk_count,json=chunkCount,proto3" json:"chunk_count,omitempty"` // Number of chunks embedded
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UploadDocumentResponse) Reset() {
+	*x = UploadDocumentResponse{}
+	mi := &file_proto_chat_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UploadDocumentResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadDocumentResponse) ProtoMessage() {}
+
+func (x *UploadDocumentResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadDocumentResponse.ProtoReflect.Descriptor instead.
+func (*UploadDocumentResponse) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *UploadDocumentResponse) GetDocumentId() string {
+	if x != nil {
+		return x.DocumentId
+	}
+	return ""
+}
+
+func (x *UploadDocumentResponse) GetChunkCount() uint32 {
+	if x != nil {
+		return x.ChunkCount
+	}
+	return 0
+}
+
+type DeleteDocumentRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DocumentId    string                 `protobuf:"bytes,1,opt,name=document_id,json=documentId,proto3" json:"document_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteDocumentRequest) Reset() {
+	*x = DeleteDocumentRequest{}
+	mi := &file_proto_chat_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteDocumentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteDocumentRequest) ProtoMessage() {}
+
+func (x *DeleteDocumentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteDocumentRequest.ProtoReflect.Descriptor instead.
+func (*DeleteDocumentRequest) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *DeleteDocumentRequest) GetDocumentId() string {
+	if x != nil {
+		return x.DocumentId
+	}
+	return ""
+}
+
+type DeleteDocumentResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteDocumentResponse) Reset() {
+	*x = DeleteDocumentResponse{}
+	mi := &file_proto_chat_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteDocumentResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteDocumentResponse) ProtoMessage() {}
+
+func (x *DeleteDocumentResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteDocumentResponse.ProtoReflect.Descriptor instead.
+func (*DeleteDocumentResponse) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{22}
+}
+
+type SearchHistoryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"` // Session to search
+	Query         string                 `protobuf:"bytes,2,opt,name=query,proto3" json:"query,omitempty"`                          // Case-insensitive substring, or a regular expression when regex is set
+	Regex         bool                   `protobuf:"varint,3,opt,name=regex,proto3" json:"regex,omitempty"`                         // Treat query as an RE2 regular expression
+	PageSize      uint32                 `protobuf:"varint,4,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`   // Matches per page, 0 for server default
+	PageToken     string                 `protobuf:"bytes,5,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"` // next_page_token from the previous page, empty for the first
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchHistoryRequest) Reset() {
+	*x = SearchHistoryRequest{}
+	mi := &file_proto_chat_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchHistoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchHistoryRequest) ProtoMessage() {}
+
+func (x *SearchHistoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchHistoryRequest.ProtoReflect.Descriptor instead.
+func (*SearchHistoryRequest) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *SearchHistoryRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *SearchHistoryRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *SearchHistoryRequest) GetRegex() bool {
+	if x != nil {
+		return x.Regex
+	}
+	return false
+}
+
+func (x *SearchHistoryRequest) GetPageSize() uint32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *SearchHistoryRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+type SearchAllSessionsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Query         string                 `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`                          // Case-insensitive substring, or a regular expression when regex is set
+	Regex         bool                   `protobuf:"varint,2,opt,name=regex,proto3" json:"regex,omitempty"`                         // Treat query as an RE2 regular expression
+	PageSize      uint32                 `protobuf:"varint,3,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`   // Matches per page, 0 for server default
+	PageToken     string                 `protobuf:"bytes,4,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"` // next_page_token from the previous page, empty for the first
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchAllSessionsRequest) Reset() {
+	*x = SearchAllSessionsRequest{}
+	mi := &file_proto_chat_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchAllSessionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchAllSessionsRequest) ProtoMessage() {}
+
+func (x *SearchAllSessionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchAllSessionsRequest.ProtoReflect.Descriptor instead.
+func (*SearchAllSessionsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *SearchAllSessionsRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *SearchAllSessionsRequest) GetRegex() bool {
+	if x != nil {
+		return x.Regex
+	}
+	return false
+}
+
+func (x *SearchAllSessionsRequest) GetPageSize() uint32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *SearchAllSessionsRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+type SearchMatch struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`              // Session containing the message
+	MessageIndex  uint32                 `protobuf:"varint,2,opt,name=message_index,json=messageIndex,proto3" json:"message_index,omitempty"`    // Position of the message in the session history
+	Role          string                 `protobuf:"bytes,3,opt,name=role,proto3" json:"role,omitempty"`                                         // user, assistant, system or tool
+	Text          string                 `protobuf:"bytes,4,opt,name=text,proto3" json:"text,omitempty"`                                         // Full message text
+	TimestampUnix int64                  `protobuf:"varint,5,opt,name=timestamp_unix,json=timestampUnix,proto3" json:"timestamp_unix,omitempty"` // Message time as Unix timestamp (seconds)
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchMatch) Reset() {
+	*x = SearchMatch{}
+	mi := &file_proto_chat_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchMatch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchMatch) ProtoMessage() {}
+
+func (x *SearchMatch) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchMatch.ProtoReflect.Descriptor instead.
+func (*SearchMatch) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *SearchMatch) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *SearchMatch) GetMessageIndex() uint32 {
+	if x != nil {
+		return x.MessageIndex
+	}
+	return 0
+}
+
+func (x *SearchMatch) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+func (x *SearchMatch) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *SearchMatch) GetTimestampUnix() int64 {
+	if x != nil {
+		return x.TimestampUnix
+	}
+	return 0
+}
+
+type SearchHistoryResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Matches       []*SearchMatch         `protobuf:"bytes,1,rep,name=matches,proto3" json:"matches,omitempty"`                                    // Matches in history order
+	NextPageToken string                 `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"` // Empty when there are no more matches
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchHistoryResponse) Reset() {
+	*x = SearchHistoryResponse{}
+	mi := &file_proto_chat_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchHistoryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchHistoryResponse) ProtoMessage() {}
+
+func (x *SearchHistoryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchHistoryResponse.ProtoReflect.Descriptor instead.
+func (*SearchHistoryResponse) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *SearchHistoryResponse) GetMatches() []*SearchMatch {
+	if x != nil {
+		return x.Matches
+	}
+	return nil
+}
+
+func (x *SearchHistoryResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+type DeleteMessagesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`     // Session to delete messages from
+	StartIndex    uint32                 `protobuf:"varint,2,opt,name=start_index,json=startIndex,proto3" json:"start_index,omitempty"` // Index of the first message to delete
+	Count         uint32                 `protobuf:"varint,3,opt,name=count,proto3" json:"count,omitempty"`                             // Number of messages to delete
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteMessagesRequest) Reset() {
+	*x = DeleteMessagesRequest{}
+	mi := &file_proto_chat_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteMessagesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteMessagesRequest) ProtoMessage() {}
+
+func (x *DeleteMessagesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteMessagesRequest.ProtoReflect.Descriptor instead.
+func (*DeleteMessagesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *DeleteMessagesRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *DeleteMessagesRequest) GetStartIndex() uint32 {
+	if x != nil {
+		return x.StartIndex
+	}
+	return 0
+}
+
+func (x *DeleteMessagesRequest) GetCount() uint32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+type DeleteMessagesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MessageCount  uint32                 `protobuf:"varint,1,opt,name=message_count,json=messageCount,proto3" json:"message_count,omitempty"` // Total messages in session after the deletion
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteMessagesResponse) Reset() {
+	*x = DeleteMessagesResponse{}
+	mi := &file_proto_chat_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteMessagesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteMessagesResponse) ProtoMessage() {}
+
+func (x *DeleteMessagesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteMessagesResponse.ProtoReflect.Descriptor instead.
+func (*DeleteMessagesResponse) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *DeleteMessagesResponse) GetMessageCount() uint32 {
+	if x != nil {
+		return x.MessageCount
+	}
+	return 0
+}
+
+type EditMessageRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`           // Server-generated UUID session ID
+	MessageIndex  uint32                 `protobuf:"varint,2,opt,name=message_index,json=messageIndex,proto3" json:"message_index,omitempty"` // Index of the user message to replace; later turns are discarded
+	Message       string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`                                // Replacement message text
+	Model         Model                  `protobuf:"varint,4,opt,name=model,proto3,enum=chat.Model" json:"model,omitempty"`                   // Model that generates the new reply
+	Options       *ChatRequest           `protobuf:"bytes,5,opt,name=options,proto3" json:"options,omitempty"`                                // Chat options for the new turn; its session, message, index and model are ignored
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EditMessageRequest) Reset() {
+	*x = EditMessageRequest{}
+	mi := &file_proto_chat_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EditMessageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EditMessageRequest) ProtoMessage() {}
+
+func (x *EditMessageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EditMessageRequest.ProtoReflect.Descriptor instead.
+func (*EditMessageRequest) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *EditMessageRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *EditMessageRequest) GetMessageIndex() uint32 {
+	if x != nil {
+		return x.MessageIndex
+	}
+	return 0
+}
+
+func (x *EditMessageRequest) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *EditMessageRequest) GetModel() Model {
+	if x != nil {
+		return x.Model
+	}
+	return Model_GEMINI_2_5_FLASH_LITE
+}
+
+func (x *EditMessageRequest) GetOptions() *ChatRequest {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+type ForkSessionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`           // Session to copy
+	MessageCount  uint32                 `protobuf:"varint,2,opt,name=message_count,json=messageCount,proto3" json:"message_count,omitempty"` // Number of leading messages to copy, 0 for the whole history
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ForkSessionRequest) Reset() {
+	*x = ForkSessionRequest{}
+	mi := &file_proto_chat_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ForkSessionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ForkSessionRequest) ProtoMessage() {}
+
+func (x *ForkSessionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ForkSessionRequest.ProtoReflect.Descriptor instead.
+func (*ForkSessionRequest) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *ForkSessionRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *ForkSessionRequest) GetMessageCount() uint32 {
+	if x != nil {
+		return x.MessageCount
+	}
+	return 0
+}
+
+type ForkSessionResponse struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	SessionId          string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`                               // New session ID for the fork
+	MessageCount       uint32                 `protobuf:"varint,2,opt,name=message_count,json=messageCount,proto3" json:"message_count,omitempty"`                     // Messages copied into the fork
+	IdleTimeoutSeconds uint32                 `protobuf:"varint,3,opt,name=idle_timeout_seconds,json=idleTimeoutSeconds,proto3" json:"idle_timeout_seconds,omitempty"` // Idle timeout inherited from the original session
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *ForkSessionResponse) Reset() {
+	*x = ForkSessionResponse{}
+	mi := &file_proto_chat_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ForkSessionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ForkSessionResponse) ProtoMessage() {}
+
+func (x *ForkSessionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ForkSessionResponse.ProtoReflect.Descriptor instead.
+func (*ForkSessionResponse) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *ForkSessionResponse) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *ForkSessionResponse) GetMessageCount() uint32 {
+	if x != nil {
+		return x.MessageCount
+	}
+	return 0
+}
+
+func (x *ForkSessionResponse) GetIdleTimeoutSeconds() uint32 {
+	if x != nil {
+		return x.IdleTimeoutSeconds
+	}
+	return 0
+}
+
+type KeepAliveRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"` // Session to keep from idle cleanup
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *KeepAliveRequest) Reset() {
+	*x = KeepAliveRequest{}
+	mi := &file_proto_chat_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KeepAliveRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KeepAliveRequest) ProtoMessage() {}
+
+func (x *KeepAliveRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KeepAliveRequest.ProtoReflect.Descriptor instead.
+func (*KeepAliveRequest) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *KeepAliveRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+type KeepAliveResponse struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	ExpiresInSeconds uint32                 `protobuf:"varint,1,opt,name=expires_in_seconds,json=expiresInSeconds,proto3" json:"expires_in_seconds,omitempty"` // Seconds until idle cleanup, 0 if the session has no messages and never expires
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *KeepAliveResponse) Reset() {
+	*x = KeepAliveResponse{}
+	mi := &file_proto_chat_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KeepAliveResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KeepAliveResponse) ProtoMessage() {}
+
+func (x *KeepAliveResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KeepAliveResponse.ProtoReflect.Descriptor instead.
+func (*KeepAliveResponse) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *KeepAliveResponse) GetExpiresInSeconds() uint32 {
+	if x != nil {
+		return x.ExpiresInSeconds
+	}
+	return 0
+}
+
+type ListKeyUsageRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListKeyUsageRequest) Reset() {
+	*x = ListKeyUsageRequest{}
+	mi := &file_proto_chat_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListKeyUsageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListKeyUsageRequest) ProtoMessage() {}
+
+func (x *ListKeyUsageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListKeyUsageRequest.ProtoReflect.Descriptor instead.
+func (*ListKeyUsageRequest) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{34}
+}
+
+type KeyUsage struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	KeyHash         string                 `protobuf:"bytes,1,opt,name=key_hash,json=keyHash,proto3" json:"key_hash,omitempty"`                            // Short SHA-256 hash of the API key, "unauthenticated" for unknown keys
+	CallsToday      uint32                 `protobuf:"varint,2,opt,name=calls_today,json=callsToday,proto3" json:"calls_today,omitempty"`                  // Calls counted against the daily limit
+	PayloadBytesIn  int64                  `protobuf:"varint,3,opt,name=payload_bytes_in,json=payloadBytesIn,proto3" json:"payload_bytes_in,omitempty"`    // Serialized request bytes since server start
+	PayloadBytesOut int64                  `protobuf:"varint,4,opt,name=payload_bytes_out,json=payloadBytesOut,proto3" json:"payload_bytes_out,omitempty"` // Serialized response bytes since server start
+	WireBytesIn     int64                  `protobuf:"varint,5,opt,name=wire_bytes_in,json=wireBytesIn,proto3" json:"wire_bytes_in,omitempty"`             // Request bytes on the wire, including gRPC framing and headers
+	WireBytesOut    int64                  `protobuf:"varint,6,opt,name=wire_bytes_out,json=wireBytesOut,proto3" json:"wire_bytes_out,omitempty"`          // Response bytes on the wire, including gRPC framing
+	TokensToday     uint64                 `protobuf:"varint,7,opt,name=tokens_today,json=tokensToday,proto3" json:"tokens_today,omitempty"`               // LLM tokens consumed today
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *KeyUsage) Reset() {
+	*x = KeyUsage{}
+	mi := &file_proto_chat_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KeyUsage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KeyUsage) ProtoMessage() {}
+
+func (x *KeyUsage) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KeyUsage.ProtoReflect.Descriptor instead.
+func (*KeyUsage) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *KeyUsage) GetKeyHash() string {
+	if x != nil {
+		return x.KeyHash
+	}
+	return ""
+}
+
+func (x *KeyUsage) GetCallsToday() uint32 {
+	if x != nil {
+		return x.CallsToday
+	}
+	return 0
+}
+
+func (x *KeyUsage) GetPayloadBytesIn() int64 {
+	if x != nil {
+		return x.PayloadBytesIn
+	}
+	return 0
+}
+
+func (x *KeyUsage) GetPayloadBytesOut() int64 {
+	if x != nil {
+		return x.PayloadBytesOut
+	}
+	return 0
+}
+
+func (x *KeyUsage) GetWireBytesIn() int64 {
+	if x != nil {
+		return x.WireBytesIn
+	}
+	return 0
+}
+
+func (x *KeyUsage) GetWireBytesOut() int64 {
+	if x != nil {
+		return x.WireBytesOut
+	}
+	return 0
+}
+
+func (x *KeyUsage) GetTokensToday() uint64 {
+	if x != nil {
+		return x.TokensToday
+	}
+	return 0
+}
+
+type ListKeyUsageResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Keys          []*KeyUsage            `protobuf:"bytes,1,rep,name=keys,proto3" json:"keys,omitempty"` // One entry per key with traffic, ordered by key hash
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListKeyUsageResponse) Reset() {
+	*x = ListKeyUsageResponse{}
+	mi := &file_proto_chat_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListKeyUsageResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListKeyUsageResponse) ProtoMessage() {}
+
+func (x *ListKeyUsageResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListKeyUsageResponse.ProtoReflect.Descriptor instead.
+func (*ListKeyUsageResponse) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *ListKeyUsageResponse) GetKeys() []*KeyUsage {
+	if x != nil {
+		return x.Keys
+	}
+	return nil
+}
+
+type GetQuotaRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetQuotaRequest) Reset() {
+	*x = GetQuotaRequest{}
+	mi := &file_proto_chat_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetQuotaRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetQuotaRequest) ProtoMessage() {}
+
+func (x *GetQuotaRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[37]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetQuotaRequest.ProtoReflect.Descriptor instead.
+func (*GetQuotaRequest) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{37}
+}
+
+type GetQuotaResponse struct {
+	state                 protoimpl.MessageState `protogen:"open.v1"`
+	DailyCallLimit        uint32                 `protobuf:"varint,1,opt,name=daily_call_limit,json=dailyCallLimit,proto3" json:"daily_call_limit,omitempty"`                                                                // Calls allowed per API key per day
+	CallsToday            uint32                 `protobuf:"varint,2,opt,name=calls_today,json=callsToday,proto3" json:"calls_today,omitempty"`                                                                              // Calls counted against today's limit
+	CallsRemaining        uint32                 `protobuf:"varint,3,opt,name=calls_remaining,json=callsRemaining,proto3" json:"calls_remaining,omitempty"`                                                                  // Calls left before the daily limit is hit
+	ResetsAtUnix          int64                  `protobuf:"varint,4,opt,name=resets_at_unix,json=resetsAtUnix,proto3" json:"resets_at_unix,omitempty"`                                                                      // When the daily count resets, as Unix timestamp (seconds)
+	RateLimitRps          float64                `protobuf:"fixed64,5,opt,name=rate_limit_rps,json=rateLimitRps,proto3" json:"rate_limit_rps,omitempty"`                                                                     // Tokens refilled per second, after any adaptive tightening
+	RateLimitBurst        uint32                 `protobuf:"varint,6,opt,name=rate_limit_burst,json=rateLimitBurst,proto3" json:"rate_limit_burst,omitempty"`                                                                // Maximum tokens available at once
+	MethodCosts           map[string]uint32      `protobuf:"bytes,7,rep,name=method_costs,json=methodCosts,proto3" json:"method_costs,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"` // Tokens per RPC where not 1, keyed by method name
+	MaxMessagesPerSession uint32                 `protobuf:"varint,8,opt,name=max_messages_per_session,json=maxMessagesPerSession,proto3" json:"max_messages_per_session,omitempty"`                                         // Messages kept per session
+	MaxSessionSizeBytes   uint32                 `protobuf:"varint,9,opt,name=max_session_size_bytes,json=maxSessionSizeBytes,proto3" json:"max_session_size_bytes,omitempty"`                                               // Memory allowed per session, including per-message overhead
+	DailyTokenLimit       uint64                 `protobuf:"varint,10,opt,name=daily_token_limit,json=dailyTokenLimit,proto3" json:"daily_token_limit,omitempty"`                                                            // LLM tokens allowed per API key per day, 0 when unlimited
+	TokensToday           uint64                 `protobuf:"varint,11,opt,name=tokens_today,json=tokensToday,proto3" json:"tokens_today,omitempty"`                                                                          // LLM tokens consumed today
+	TokensRemaining       uint64                 `protobuf:"varint,12,opt,name=tokens_remaining,json=tokensRemaining,proto3" json:"tokens_remaining,omitempty"`                                                              // Tokens left before the daily token limit is hit (0 when unlimited)
+	SessionTokenBudget    uint64                 `protobuf:"varint,13,opt,name=session_token_budget,json=sessionTokenBudget,proto3" json:"session_token_budget,omitempty"`                                                   // LLM tokens allowed per session, 0 when unlimited
+	unknownFields         protoimpl.UnknownFields
+	sizeCache             protoimpl.SizeCache
+}
+
+func (x *GetQuotaResponse) Reset() {
+	*x = GetQuotaResponse{}
+	mi := &file_proto_chat_proto_msgTypes[38]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetQuotaResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetQuotaResponse) ProtoMessage() {}
+
+func (x *GetQuotaResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[38]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetQuotaResponse.ProtoReflect.Descriptor instead.
+func (*GetQuotaResponse) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{38}
+}
+
+func (x *GetQuotaResponse) GetDailyCallLimit() uint32 {
+	if x != nil {
+		return x.DailyCallLimit
+	}
+	return 0
+}
+
+func (x *GetQuotaResponse) GetCallsToday() uint32 {
+	if x != nil {
+		return x.CallsToday
+	}
+	return 0
+}
+
+func (x *GetQuotaResponse) GetCallsRemaining() uint32 {
+	if x != nil {
+		return x.CallsRemaining
+	}
+	return 0
+}
+
+func (x *GetQuotaResponse) GetResetsAtUnix() int64 {
+	if x != nil {
+		return x.ResetsAtUnix
+	}
+	return 0
+}
+
+func (x *GetQuotaResponse) GetRateLimitRps() float64 {
+	if x != nil {
+		return x.RateLimitRps
+	}
+	return 0
+}
+
+func (x *GetQuotaResponse) GetRateLimitBurst() uint32 {
+	if x != nil {
+		return x.RateLimitBurst
+	}
+	return 0
+}
+
+func (x *GetQuotaResponse) GetMethodCosts() map[string]uint32 {
+	if x != nil {
+		return x.MethodCosts
+	}
+	return nil
+}
+
+func (x *GetQuotaResponse) GetMaxMessagesPerSession() uint32 {
+	if x != nil {
+		return x.MaxMessagesPerSession
+	}
+	return 0
+}
+
+func (x *GetQuotaResponse) GetMaxSessionSizeBytes() uint32 {
+	if x != nil {
+		return x.MaxSessionSizeBytes
+	}
+	return 0
+}
+
+func (x *GetQuotaResponse) GetDailyTokenLimit() uint64 {
+	if x != nil {
+		return x.DailyTokenLimit
+	}
+	return 0
+}
+
+func (x *GetQuotaResponse) GetTokensToday() uint64 {
+	if x != nil {
+		return x.TokensToday
+	}
+	return 0
+}
+
+func (x *GetQuotaResponse) GetTokensRemaining() uint64 {
+	if x != nil {
+		return x.TokensRemaining
+	}
+	return 0
+}
+
+func (x *GetQuotaResponse) GetSessionTokenBudget() uint64 {
+	if x != nil {
+		return x.SessionTokenBudget
+	}
+	return 0
+}
+
+type GetServerInfoRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetServerInfoRequest) Reset() {
+	*x = GetServerInfoRequest{}
+	mi := &file_proto_chat_proto_msgTypes[39]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetServerInfoRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetServerInfoRequest) ProtoMessage() {}
+
+func (x *GetServerInfoRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[39]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetServerInfoRequest.ProtoReflect.Descriptor instead.
+func (*GetServerInfoRequest) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{39}
+}
+
+type GetServerInfoResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Version       string                 `protobuf:"bytes,1,opt,name=version,proto3" json:"version,omitempty"`                                     // Release version, "dev" for untagged builds
+	Commit        string                 `protobuf:"bytes,2,opt,name=commit,proto3" json:"commit,omitempty"`                                       // Git commit the server was built from
+	BuildDate     string                 `protobuf:"bytes,3,opt,name=build_date,json=buildDate,proto3" json:"build_date,omitempty"`                // Build time in RFC 3339, UTC
+	GoVersion     string                 `protobuf:"bytes,4,opt,name=go_version,json=goVersion,proto3" json:"go_version,omitempty"`                // Go toolchain the server was built with
+	StartedAtUnix int64                  `protobuf:"varint,5,opt,name=started_at_unix,json=startedAtUnix,proto3" json:"started_at_unix,omitempty"` // When the server process started, as Unix timestamp (seconds)
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetServerInfoResponse) Reset() {
+	*x = GetServerInfoResponse{}
+	mi := &file_proto_chat_proto_msgTypes[40]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetServerInfoResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetServerInfoResponse) ProtoMessage() {}
+
+func (x *GetServerInfoResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[40]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetServerInfoResponse.ProtoReflect.Descriptor instead.
+func (*GetServerInfoResponse) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{40}
+}
+
+func (x *GetServerInfoResponse) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *GetServerInfoResponse) GetCommit() string {
+	if x != nil {
+		return x.Commit
+	}
+	return ""
+}
+
+func (x *GetServerInfoResponse) GetBuildDate() string {
+	if x != nil {
+		return x.BuildDate
+	}
+	return ""
+}
+
+func (x *GetServerInfoResponse) GetGoVersion() string {
+	if x != nil {
+		return x.GoVersion
+	}
+	return ""
+}
+
+func (x *GetServerInfoResponse) GetStartedAtUnix() int64 {
+	if x != nil {
+		return x.StartedAtUnix
+	}
+	return 0
+}
+
+type RotateProviderKeyRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Provider      string                 `protobuf:"bytes,1,opt,name=provider,proto3" json:"provider,omitempty"`           // Provider name, e.g. "gemini" or "openai_compat"
+	ApiKey        string                 `protobuf:"bytes,2,opt,name=api_key,json=apiKey,proto3" json:"api_key,omitempty"` // New upstream API key; used from the next call, in-flight calls finish on the old one
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RotateProviderKeyRequest) Reset() {
+	*x = RotateProviderKeyRequest{}
+	mi := &file_proto_chat_proto_msgTypes[41]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RotateProviderKeyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RotateProviderKeyRequest) ProtoMessage() {}
+
+func (x *RotateProviderKeyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[41]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RotateProviderKeyRequest.ProtoReflect.Descriptor instead.
+func (*RotateProviderKeyRequest) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{41}
+}
+
+func (x *RotateProviderKeyRequest) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+func (x *RotateProviderKeyRequest) GetApiKey() string {
+	if x != nil {
+		return x.ApiKey
+	}
+	return ""
+}
+
+type RotateProviderKeyResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Provider      string                 `protobuf:"bytes,1,opt,name=provider,proto3" json:"provider,omitempty"`              // Provider whose key was rotated
+	KeyHash       string                 `protobuf:"bytes,2,opt,name=key_hash,json=keyHash,proto3" json:"key_hash,omitempty"` // Short hash of the new key, to confirm which key is active without exposing it
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RotateProviderKeyResponse) Reset() {
+	*x = RotateProviderKeyResponse{}
+	mi := &file_proto_chat_proto_msgTypes[42]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RotateProviderKeyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RotateProviderKeyResponse) ProtoMessage() {}
+
+func (x *RotateProviderKeyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[42]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RotateProviderKeyResponse.ProtoReflect.Descriptor instead.
+func (*RotateProviderKeyResponse) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{42}
+}
+
+func (x *RotateProviderKeyResponse) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+func (x *RotateProviderKeyResponse) GetKeyHash() string {
+	if x != nil {
+		return x.KeyHash
+	}
+	return ""
+}
+
+type ListLLMCapturesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Limit         uint32                 `protobuf:"varint,1,opt,name=limit,proto3" json:"limit,omitempty"` // Most recent exchanges to return, 0 for all captured
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListLLMCapturesRequest) Reset() {
+	*x = ListLLMCapturesRequest{}
+	mi := &file_proto_chat_proto_msgTypes[43]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListLLMCapturesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListLLMCapturesRequest) ProtoMessage() {}
+
+func (x *ListLLMCapturesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[43]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListLLMCapturesRequest.ProtoReflect.Descriptor instead.
+func (*ListLLMCapturesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{43}
+}
+
+func (x *ListLLMCapturesRequest) GetLimit() uint32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type CapturedMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Role          string                 `protobuf:"bytes,1,opt,name=role,proto3" json:"role,omitempty"` // user, assistant, system or tool
+	Text          string                 `protobuf:"bytes,2,opt,name=text,proto3" json:"text,omitempty"` // Message text after PII redaction and the size cap
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CapturedMessage) Reset() {
+	*x = CapturedMessage{}
+	mi := &file_proto_chat_proto_msgTypes[44]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CapturedMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CapturedMessage) ProtoMessage() {}
+
+func (x *CapturedMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[44]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CapturedMessage.ProtoReflect.Descriptor instead.
+func (*CapturedMessage) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{44}
+}
+
+func (x *CapturedMessage) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+func (x *CapturedMessage) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+type LLMExchange struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	CapturedAtUnix int64                  `protobuf:"varint,1,opt,name=captured_at_unix,json=capturedAtUnix,proto3" json:"captured_at_unix,omitempty"` // When the provider call finished, as Unix timestamp (seconds)
+	Provider       string                 `protobuf:"bytes,2,opt,name=provider,proto3" json:"provider,omitempty"`                                      // Provider that served the call
+	Model          string                 `protobuf:"bytes,3,opt,name=model,proto3" json:"model,omitempty"`                                            // Model requested by the client
+	SessionHash    string                 `protobuf:"bytes,4,opt,name=session_hash,json=sessionHash,proto3" json:"session_hash,omitempty"`             // Hash of the session ID, to correlate with redacted logs
+	KeyHash        string                 `protobuf:"bytes,5,opt,name=key_hash,json=keyHash,proto3" json:"key_hash,omitempty"`                         // Short SHA-256 hash of the caller's API key
+	Messages       []*CapturedMessage     `protobuf:"bytes,6,rep,name=messages,proto3" json:"messages,omitempty"`                                      // Conversation sent to the provider
+	Reply          string                 `protobuf:"bytes,7,opt,name=reply,proto3" json:"reply,omitempty"`                                            // Raw provider reply before sanitizing and moderation
+	Error          string                 `protobuf:"bytes,8,opt,name=error,proto3" json:"error,omitempty"`                                            // Provider error, empty on success
+	DurationMs     int64                  `protobuf:"varint,9,opt,name=duration_ms,json=durationMs,proto3" json:"duration_ms,omitempty"`               // Provider call duration in milliseconds
+	Truncated      bool                   `protobuf:"varint,10,opt,name=truncated,proto3" json:"truncated,omitempty"`                                  // Some text was cut to the capture size cap
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *LLMExchange) Reset() {
+	*x = LLMExchange{}
+	mi := &file_proto_chat_proto_msgTypes[45]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LLMExchange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LLMExchange) ProtoMessage() {}
+
+func (x *LLMExchange) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[45]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LLMExchange.ProtoReflect.Descriptor instead.
+func (*LLMExchange) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{45}
+}
+
+func (x *LLMExchange) GetCapturedAtUnix() int64 {
+	if x != nil {
+		return x.CapturedAtUnix
+	}
+	return 0
+}
+
+func (x *LLMExchange) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+func (x *LLMExchange) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *LLMExchange) GetSessionHash() string {
+	if x != nil {
+		return x.SessionHash
+	}
+	return ""
+}
+
+func (x *LLMExchange) GetKeyHash() string {
+	if x != nil {
+		return x.KeyHash
+	}
+	return ""
+}
+
+func (x *LLMExchange) GetMessages() []*CapturedMessage {
+	if x != nil {
+		return x.Messages
+	}
+	return nil
+}
+
+func (x *LLMExchange) GetReply() string {
+	if x != nil {
+		return x.Reply
+	}
+	return ""
+}
+
+func (x *LLMExchange) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *LLMExchange) GetDurationMs() int64 {
+	if x != nil {
+		return x.DurationMs
+	}
+	return 0
+}
+
+func (x *LLMExchange) GetTruncated() bool {
+	if x != nil {
+		return x.Truncated
+	}
+	return false
+}
+
+type ListLLMCapturesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Enabled       bool                   `protobuf:"varint,1,opt,name=enabled,proto3" json:"enabled,omitempty"`    // Whether capture is on (LLM_CAPTURE_SIZE > 0)
+	Exchanges     []*LLMExchange         `protobuf:"bytes,2,rep,name=exchanges,proto3" json:"exchanges,omitempty"` // Newest first
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListLLMCapturesResponse) Reset() {
+	*x = ListLLMCapturesResponse{}
+	mi := &file_proto_chat_proto_msgTypes[46]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListLLMCapturesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListLLMCapturesResponse) ProtoMessage() {}
+
+func (x *ListLLMCapturesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[46]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListLLMCapturesResponse.ProtoReflect.Descriptor instead.
+func (*ListLLMCapturesResponse) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{46}
+}
+
+func (x *ListLLMCapturesResponse) GetEnabled() bool {
+	if x != nil {
+		return x.Enabled
+	}
+	return false
+}
+
+func (x *ListLLMCapturesResponse) GetExchanges() []*LLMExchange {
+	if x != nil {
+		return x.Exchanges
+	}
+	return nil
+}
+
+type RestoreSessionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"` // Session archived by idle cleanup with ARCHIVE_STORE
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RestoreSessionRequest) Reset() {
+	*x = RestoreSessionRequest{}
+	mi := &file_proto_chat_proto_msgTypes[47]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RestoreSessionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestoreSessionRequest) ProtoMessage() {}
+
+func (x *RestoreSessionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[47]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestoreSessionRequest.ProtoReflect.Descriptor instead.
+func (*RestoreSessionRequest) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{47}
+}
+
+func (x *RestoreSessionRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+type RestoreSessionResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	SessionId      string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	OwnerKeyHash   string                 `protobuf:"bytes,2,opt,name=owner_key_hash,json=ownerKeyHash,proto3" json:"owner_key_hash,omitempty"` // Short SHA-256 hash of the API key that owns the session
+	MessageCount   uint32                 `protobuf:"varint,3,opt,name=message_count,json=messageCount,proto3" json:"message_count,omitempty"`
+	ArchivedAtUnix int64                  `protobuf:"varint,4,opt,name=archived_at_unix,json=archivedAtUnix,proto3" json:"archived_at_unix,omitempty"` // When the session was archived, as Unix timestamp (seconds)
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *RestoreSessionResponse) Reset() {
+	*x = RestoreSessionResponse{}
+	mi := &file_proto_chat_proto_msgTypes[48]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RestoreSessionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestoreSessionResponse) ProtoMessage() {}
+
+func (x *RestoreSessionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[48]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestoreSessionResponse.ProtoReflect.Descriptor instead.
+func (*RestoreSessionResponse) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{48}
+}
+
+func (x *RestoreSessionResponse) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *RestoreSessionResponse) GetOwnerKeyHash() string {
+	if x != nil {
+		return x.OwnerKeyHash
+	}
+	return ""
+}
+
+func (x *RestoreSessionResponse) GetMessageCount() uint32 {
+	if x != nil {
+		return x.MessageCount
+	}
+	return 0
+}
+
+func (x *RestoreSessionResponse) GetArchivedAtUnix() int64 {
+	if x != nil {
+		return x.ArchivedAtUnix
+	}
+	return 0
+}
+
+type ImportSessionRequest struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Content            string                 `protobuf:"bytes,1,opt,name=content,proto3" json:"content,omitempty"`                                                    // Transcript from ExportSession in EXPORT_JSON format
+	IdleTimeoutSeconds uint32                 `protobuf:"varint,2,opt,name=idle_timeout_seconds,json=idleTimeoutSeconds,proto3" json:"idle_timeout_seconds,omitempty"` // Optional per-session idle timeout, as in StartSession (0 = server default)
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *ImportSessionRequest) Reset() {
+	*x = ImportSessionRequest{}
+	mi := &file_proto_chat_proto_msgTypes[49]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportSessionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportSessionRequest) ProtoMessage() {}
+
+func (x *ImportSessionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[49]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportSessionRequest.ProtoReflect.Descriptor instead.
+func (*ImportSessionRequest) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{49}
+}
+
+func (x *ImportSessionRequest) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+func (x *ImportSessionRequest) GetIdleTimeoutSeconds() uint32 {
+	if x != nil {
+		return x.IdleTimeoutSeconds
+	}
+	return 0
+}
+
+type ImportSessionResponse struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	SessionId          string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`                               // New session holding the imported messages
+	MessageCount       uint32                 `protobuf:"varint,2,opt,name=message_count,json=messageCount,proto3" json:"message_count,omitempty"`                     // Messages imported
+	IdleTimeoutSeconds uint32                 `protobuf:"varint,3,opt,name=idle_timeout_seconds,json=idleTimeoutSeconds,proto3" json:"idle_timeout_seconds,omitempty"` // Effective idle timeout for the session
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *ImportSessionResponse) Reset() {
+	*x = ImportSessionResponse{}
+	mi := &file_proto_chat_proto_msgTypes[50]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportSessionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportSessionResponse) ProtoMessage() {}
+
+func (x *ImportSessionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[50]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportSessionResponse.ProtoReflect.Descriptor instead.
+func (*ImportSessionResponse) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{50}
+}
+
+func (x *ImportSessionResponse) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *ImportSessionResponse) GetMessageCount() uint32 {
+	if x != nil {
+		return x.MessageCount
+	}
+	return 0
+}
+
+func (x *ImportSessionResponse) GetIdleTimeoutSeconds() uint32 {
+	if x != nil {
+		return x.IdleTimeoutSeconds
+	}
+	return 0
+}
+
+type SetMaintenanceModeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Enabled       bool                   `protobuf:"varint,1,opt,name=enabled,proto3" json:"enabled,omitempty"` // While enabled, StartSession, ForkSession and ImportSession return UNAVAILABLE
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`  // Message returned to refused clients, empty for MAINTENANCE_MESSAGE or the default
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetMaintenanceModeRequest) Reset() {
+	*x = SetMaintenanceModeRequest{}
+	mi := &file_proto_chat_proto_msgTypes[51]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetMaintenanceModeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetMaintenanceModeRequest) ProtoMessage() {}
+
+func (x *SetMaintenanceModeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[51]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetMaintenanceModeRequest.ProtoReflect.Descriptor instead.
+func (*SetMaintenanceModeRequest) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{51}
+}
+
+func (x *SetMaintenanceModeRequest) GetEnabled() bool {
+	if x != nil {
+		return x.Enabled
+	}
+	return false
+}
+
+func (x *SetMaintenanceModeRequest) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type SetMaintenanceModeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Enabled       bool                   `protobuf:"varint,1,opt,name=enabled,proto3" json:"enabled,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`                       // Message refused clients receive, empty when disabled
+	SinceUnix     int64                  `protobuf:"varint,3,opt,name=since_unix,json=sinceUnix,proto3" json:"since_unix,omitempty"` // When maintenance mode was enabled, as Unix timestamp (seconds), 0 when disabled
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetMaintenanceModeResponse) Reset() {
+	*x = SetMaintenanceModeResponse{}
+	mi := &file_proto_chat_proto_msgTypes[52]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetMaintenanceModeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetMaintenanceModeResponse) ProtoMessage() {}
+
+func (x *SetMaintenanceModeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[52]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetMaintenanceModeResponse.ProtoReflect.Descriptor instead.
+func (*SetMaintenanceModeResponse) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{52}
+}
+
+func (x *SetMaintenanceModeResponse) GetEnabled() bool {
+	if x != nil {
+		return x.Enabled
+	}
+	return false
+}
+
+func (x *SetMaintenanceModeResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *SetMaintenanceModeResponse) GetSinceUnix() int64 {
+	if x != nil {
+		return x.SinceUnix
+	}
+	return 0
+}
+
+type ListModelsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListModelsRequest) Reset() {
+	*x = ListModelsRequest{}
+	mi := &file_proto_chat_proto_msgTypes[53]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListModelsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListModelsRequest) ProtoMessage() {}
+
+func (x *ListModelsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[53]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListModelsRequest.ProtoReflect.Descriptor instead.
+func (*ListModelsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{53}
+}
+
+type ModelInfo struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Model             Model                  `protobuf:"varint,1,opt,name=model,proto3,enum=chat.Model" json:"model,omitempty"`
+	Provider          string                 `protobuf:"bytes,2,opt,name=provider,proto3" json:"provider,omitempty"`                                            // Provider registered for the model, e.g. "gemini"
+	DisplayName       string                 `protobuf:"bytes,3,opt,name=display_name,json=displayName,proto3" json:"display_name,omitempty"`                   // Human-readable name
+	Available         bool                   `protobuf:"varint,4,opt,name=available,proto3" json:"available,omitempty"`                                         // Served by its own provider; otherwise requests fall back to another
+	UnavailableReason string                 `protobuf:"bytes,5,opt,name=unavailable_reason,json=unavailableReason,proto3" json:"unavailable_reason,omitempty"` // dev_only, not_configured or unhealthy, empty when available
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *ModelInfo) Reset() {
+	*x = ModelInfo{}
+	mi := &file_proto_chat_proto_msgTypes[54]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ModelInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ModelInfo) ProtoMessage() {}
+
+func (x *ModelInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[54]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ModelInfo.ProtoReflect.Descriptor instead.
+func (*ModelInfo) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{54}
+}
+
+func (x *ModelInfo) GetModel() Model {
+	if x != nil {
+		return x.Model
+	}
+	return Model_GEMINI_2_5_FLASH_LITE
+}
+
+func (x *ModelInfo) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+func (x *ModelInfo) GetDisplayName() string {
+	if x != nil {
+		return x.DisplayName
+	}
+	return ""
+}
+
+func (x *ModelInfo) GetAvailable() bool {
+	if x != nil {
+		return x.Available
+	}
+	return false
+}
+
+func (x *ModelInfo) GetUnavailableReason() string {
+	if x != nil {
+		return x.UnavailableReason
+	}
+	return ""
+}
+
+type ListModelsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Models        []*ModelInfo           `protobuf:"bytes,1,rep,name=models,proto3" json:"models,omitempty"` // Models compiled into the server, in enum order
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListModelsResponse) Reset() {
+	*x = ListModelsResponse{}
+	mi := &file_proto_chat_proto_msgTypes[55]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListModelsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListModelsResponse) ProtoMessage() {}
+
+func (x *ListModelsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chat_proto_msgTypes[55]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListModelsResponse.ProtoReflect.Descriptor instead.
+func (*ListModelsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_chat_proto_rawDescGZIP(), []int{55}
+}
+
+func (x *ListModelsResponse) GetModels() []*ModelInfo {
+	if x != nil {
+		return x.Models
+	}
+	return nil
+}
+
+var File_proto_chat_proto protoreflect.FileDescriptor
+
+const file_proto_chat_proto_rawDesc = "" +
+	"\n" +
+	"\x10proto/chat.proto\x12\x04chat\"G\n" +
+	"\x13StartSessionRequest\x120\n" +
+	"\x14idle_timeout_seconds\x18\x01 \x01(\rR\x12idleTimeoutSeconds\"g\n" +
+	"\x14StartSessionResponse\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x120\n" +
+	"\x14idle_timeout_seconds\x18\x02 \x01(\rR\x12idleTimeoutSeconds\"\x9b\x04\n" +
+	"\vChatRequest\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12!\n" +
+	"\x05model\x18\x02 \x01(\x0e2\v.chat.ModelR\x05model\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\x12#\n" +
+	"\rmessage_index\x18\x04 \x01(\rR\fmessageIndex\x12=\n" +
+	"\x0fresponse_format\x18\x05 \x01(\x0e2\x14.chat.ResponseFormatR\x0eresponseFormat\x12'\n" +
+	"\x0fresponse_schema\x18\x06 \x01(\tR\x0eresponseSchema\x122\n" +
+	"\vattachments\x18\a \x03(\v2\x10.chat.AttachmentR\vattachments\x12#\n" +
+	"\ruse_knowledge\x18\b \x01(\bR\fuseKnowledge\x12\x1a\n" +
+	"\btemplate\x18\t \x01(\tR\btemplate\x12H\n" +
+	"\rtemplate_vars\x18\n" +
+	" \x03(\v2#.chat.ChatRequest.TemplateVarsEntryR\ftemplateVars\x12#\n" +
+	"\rsystem_prompt\x18\v \x01(\tR\fsystemPrompt\x1a?\n" +
+	"\x11TemplateVarsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"=\n" +
+	"\n" +
+	"Attachment\x12\x1b\n" +
+	"\tmime_type\x18\x01 \x01(\tR\bmimeType\x12\x12\n" +
+	"\x04data\x18\x02 \x01(\fR\x04data\"\xdc\x02\n" +
+	"\fChatResponse\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x14\n" +
+	"\x05reply\x18\x02 \x01(\tR\x05reply\x12#\n" +
+	"\rmessage_count\x18\x03 \x01(\rR\fmessageCount\x12+\n" +
+	"\x11knowledge_sources\x18\x04 \x03(\tR\x10knowledgeSources\x12.\n" +
+	"\x13session_tokens_used\x18\x05 \x01(\x04R\x11sessionTokensUsed\x120\n" +
+	"\x14session_token_budget\x18\x06 \x01(\x04R\x12sessionTokenBudget\x128\n" +
+	"\x18session_tokens_remaining\x18\a \x01(\x04R\x16sessionTokensRemaining\x12)\n" +
+	"\x10history_diverged\x18\b \x01(\bR\x0fhistoryDiverged\"\x0f\n" +
+	"\rHealthRequest\" \n" +
+	"\x0eHealthResponse\x12\x0e\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok\"2\n" +
+	"\x11GetHistoryRequest\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\"\x96\x01\n" +
+	"\x12GetHistoryResponse\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x1a\n" +
+	"\bmessages\x18\x02 \x03(\tR\bmessages\x12\x1c\n" +
+	"\tencrypted\x18\x03 \x01(\bR\tencrypted\x12'\n" +
+	"\x0ftimestamps_unix\x18\x04 \x03(\x03R\x0etimestampsUnix\"a\n" +
+	"\x14ExportSessionRequest\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12*\n" +
+	"\x06format\x18\x02 \x01(\x0e2\x12.chat.ExportFormatR\x06format\"|\n" +
+	"\x15ExportSessionResponse\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12*\n" +
+	"\x06format\x18\x02 \x01(\x0e2\x12.chat.ExportFormatR\x06format\x12\x18\n" +
+	"\acontent\x18\x03 \x01(\tR\acontent\"\x15\n" +
+	"\x13ListSessionsRequest\"\xb0\x01\n" +
+	"\vSessionInfo\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x14\n" +
+	"\x05title\x18\x02 \x01(\tR\x05title\x12#\n" +
+	"\rmessage_count\x18\x03 \x01(\rR\fmessageCount\x12\x1d\n" +
+	"\n" +
+	"size_bytes\x18\x04 \x01(\rR\tsizeBytes\x12(\n" +
+	"\x10last_active_unix\x18\x05 \x01(\x03R\x0elastActiveUnix\"E\n" +
+	"\x14ListSessionsResponse\x12-\n" +
+	"\bsessions\x18\x01 \x03(\v2\x11.chat.SessionInfoR\bsessions\"\x17\n" +
+	"\x15ListMySessionsRequest\"G\n" +
+	"\x16ListMySessionsResponse\x12-\n" +
+	"\bsessions\x18\x01 \x03(\v2\x11.chat.SessionInfoR\bsessions\"G\n" +
+	"\fEmbedRequest\x12!\n" +
+	"\x05model\x18\x01 \x01(\x0e2\v.chat.ModelR\x05model\x12\x14\n" +
+	"\x05texts\x18\x02 \x03(\tR\x05texts\"#\n" +
+	"\tEmbedding\x12\x16\n" +
+	"\x06values\x18\x01 \x03(\x02R\x06values\"|\n" +
+	"\rEmbedResponse\x12\x1a\n" +
+	"\bprovider\x18\x01 \x01(\tR\bprovider\x12\x1e\n" +
+	"\n" +
+	"dimensions\x18\x02 \x01(\rR\n" +
+	"dimensions\x12/\n" +
+	"\n" +
+	"embeddings\x18\x03 \x03(\v2\x0f.chat.EmbeddingR\n" +
+	"embeddings\"E\n" +
+	"\x15UploadDocumentRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x18\n" +
+	"\acontent\x18\x02 \x01(\tR\acontent\"Z\n" +
+	"\x16UploadDocumentResponse\x12\x1f\n" +
+	"\vdocument_id\x18\x01 \x01(\tR\n" +
+	"documentId\x12\x1f\n" +
+	"\vchunk_count\x18\x02 \x01(\rR\n" +
+	"chunkCount\"8\n" +
+	"\x15DeleteDocumentRequest\x12\x1f\n" +
+	"\vdocument_id\x18\x01 \x01(\tR\n" +
+	"documentId\"\x18\n" +
+	"\x16DeleteDocumentResponse\"\x9d\x01\n" +
+	"\x14SearchHistoryRequest\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x14\n" +
+	"\x05query\x18\x02 \x01(\tR\x05query\x12\x14\n" +
+	"\x05regex\x18\x03 \x01(\bR\x05regex\x12\x1b\n" +
+	"\tpage_size\x18\x04 \x01(\rR\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x05 \x01(\tR\tpageToken\"\x82\x01\n" +
+	"\x18SearchAllSessionsRequest\x12\x14\n" +
+	"\x05query\x18\x01 \x01(\tR\x05query\x12\x14\n" +
+	"\x05regex\x18\x02 \x01(\bR\x05regex\x12\x1b\n" +
+	"\tpage_size\x18\x03 \x01(\rR\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x04 \x01(\tR\tpageToken\"\xa0\x01\n" +
+	"\vSearchMatch\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12#\n" +
+	"\rmessage_index\x18\x02 \x01(\rR\fmessageIndex\x12\x12\n" +
+	"\x04role\x18\x03 \x01(\tR\x04role\x12\x12\n" +
+	"\x04text\x18\x04 \x01(\tR\x04text\x12%\n" +
+	"\x0etimestamp_unix\x18\x05 \x01(\x03R\rtimestampUnix\"l\n" +
+	"\x15SearchHistoryResponse\x12+\n" +
+	"\amatches\x18\x01 \x03(\v2\x11.chat.SearchMatchR\amatches\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\"m\n" +
+	"\x15DeleteMessagesRequest\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x1f\n" +
+	"\vstart_index\x18\x02 \x01(\rR\n" +
+	"startIndex\x12\x14\n" +
+	"\x05count\x18\x03 \x01(\rR\x05count\"=\n" +
+	"\x16DeleteMessagesResponse\x12#\n" +
+	"\rmessage_count\x18\x01 \x01(\rR\fmessageCount\"\xc2\x01\n" +
+	"\x12EditMessageRequest\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12#\n" +
+	"\rmessage_index\x18\x02 \x01(\rR\fmessageIndex\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\x12!\n" +
+	"\x05model\x18\x04 \x01(\x0e2\v.chat.ModelR\x05model\x12+\n" +
+	"\aoptions\x18\x05 \x01(\v2\x11.chat.ChatRequestR\aoptions\"X\n" +
+	"\x12ForkSessionRequest\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12#\n" +
+	"\rmessage_count\x18\x02 \x01(\rR\fmessageCount\"\x8b\x01\n" +
+	"\x13ForkSessionResponse\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12#\n" +
+	"\rmessage_count\x18\x02 \x01(\rR\fmessageCount\x120\n" +
+	"\x14idle_timeout_seconds\x18\x03 \x01(\rR\x12idleTimeoutSeconds\"1\n" +
+	"\x10KeepAliveRequest\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\"A\n" +
+	"\x11KeepAliveResponse\x12,\n" +
+	"\x12expires_in_seconds\x18\x01 \x01(\rR\x10expiresInSeconds\"\x15\n" +
+	"\x13ListKeyUsageRequest\"\x89\x02\n" +
+	"\bKeyUsage\x12\x19\n" +
+	"\bkey_hash\x18\x01 \x01(\tR\akeyHash\x12\x1f\n" +
+	"\vcalls_today\x18\x02 \x01(\rR\n" +
+	"callsToday\x12(\n" +
+	"\x10payload_bytes_in\x18\x03 \x01(\x03R\x0epayloadBytesIn\x12*\n" +
+	"\x11payload_bytes_out\x18\x04 \x01(\x03R\x0fpayloadBytesOut\x12\"\n" +
+	"\rwire_bytes_in\x18\x05 \x01(\x03R\vwireBytesIn\x12$\n" +
+	"\x0ewire_bytes_out\x18\x06 \x01(\x03R\fwireBytesOut\x12!\n" +
+	"\ftokens_today\x18\a \x01(\x04R\vtokensToday\":\n" +
+	"\x14ListKeyUsageResponse\x12\"\n" +
+	"\x04keys\x18\x01 \x03(\v2\x0e.chat.KeyUsageR\x04keys\"\x11\n" +
+	"\x0fGetQuotaRequest\"\xa2\x05\n" +
+	"\x10GetQuotaResponse\x12(\n" +
+	"\x10daily_call_limit\x18\x01 \x01(\rR\x0edailyCallLimit\x12\x1f\n" +
+	"\vcalls_today\x18\x02 \x01(\rR\n" +
+	"callsToday\x12'\n" +
+	"\x0fcalls_remaining\x18\x03 \x01(\rR\x0ecallsRemaining\x12$\n" +
+	"\x0eresets_at_unix\x18\x04 \x01(\x03R\fresetsAtUnix\x12$\n" +
+	"\x0erate_limit_rps\x18\x05 \x01(\x01R\frateLimitRps\x12(\n" +
+	"\x10rate_limit_burst\x18\x06 \x01(\rR\x0erateLimitBurst\x12J\n" +
+	"\fmethod_costs\x18\a \x03(\v2'.chat.GetQuotaResponse.MethodCostsEntryR\vmethodCosts\x127\n" +
+	"\x18max_messages_per_session\x18\b \x01(\rR\x15maxMessagesPerSession\x123\n" +
+	"\x16max_session_size_bytes\x18\t \x01(\rR\x13maxSessionSizeBytes\x12*\n" +
+	"\x11daily_token_limit\x18\n" +
+	" \x01(\x04R\x0fdailyTokenLimit\x12!\n" +
+	"\ftokens_today\x18\v \x01(\x04R\vtokensToday\x12)\n" +
+	"\x10tokens_remaining\x18\f \x01(\x04R\x0ftokensRemaining\x120\n" +
+	"\x14session_token_budget\x18\r \x01(\x04R\x12sessionTokenBudget\x1a>\n" +
+	"\x10MethodCostsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\rR\x05value:\x028\x01\"\x16\n" +
+	"\x14GetServerInfoRequest\"\xaf\x01\n" +
+	"\x15GetServerInfoResponse\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\tR\aversion\x12\x16\n" +
+	"\x06commit\x18\x02 \x01(\tR\x06commit\x12\x1d\n" +
+	"\n" +
+	"build_date\x18\x03 \x01(\tR\tbuildDate\x12\x1d\n" +
+	"\n" +
+	"go_version\x18\x04 \x01(\tR\tgoVersion\x12&\n" +
+	"\x0fstarted_at_unix\x18\x05 \x01(\x03R\rstartedAtUnix\"O\n" +
+	"\x18RotateProviderKeyRequest\x12\x1a\n" +
+	"\bprovider\x18\x01 \x01(\tR\bprovider\x12\x17\n" +
+	"\aapi_key\x18\x02 \x01(\tR\x06apiKey\"R\n" +
+	"\x19RotateProviderKeyResponse\x12\x1a\n" +
+	"\bprovider\x18\x01 \x01(\tR\bprovider\x12\x19\n" +
+	"\bkey_hash\x18\x02 \x01(\tR\akeyHash\".\n" +
+	"\x16ListLLMCapturesRequest\x12\x14\n" +
+	"\x05limit\x18\x01 \x01(\rR\x05limit\"9\n" +
+	"\x0fCapturedMessage\x12\x12\n" +
+	"\x04role\x18\x01 \x01(\tR\x04role\x12\x12\n" +
+	"\x04text\x18\x02 \x01(\tR\x04text\"\xc5\x02\n" +
+	"\vLLMExchange\x12(\n" +
+	"\x10captured_at_unix\x18\x01 \x01(\x03R\x0ecapturedAtUnix\x12\x1a\n" +
+	"\bprovider\x18\x02 \x01(\tR\bprovider\x12\x14\n" +
+	"\x05model\x18\x03 \x01(\tR\x05model\x12!\n" +
+	"\fsession_hash\x18\x04 \x01(\tR\vsessionHash\x12\x19\n" +
+	"\bkey_hash\x18\x05 \x01(\tR\akeyHash\x121\n" +
+	"\bmessages\x18\x06 \x03(\v2\x15.chat.CapturedMessageR\bmessages\x12\x14\n" +
+	"\x05reply\x18\a \x01(\tR\x05reply\x12\x14\n" +
+	"\x05error\x18\b \x01(\tR\x05error\x12\x1f\n" +
+	"\vduration_ms\x18\t \x01(\x03R\n" +
+	"durationMs\x12\x1c\n" +
+	"\ttruncated\x18\n" +
+	" \x01(\bR\ttruncated\"d\n" +
+	"\x17ListLLMCapturesResponse\x12\x18\n" +
+	"\aenabled\x18\x01 \x01(\bR\aenabled\x12/\n" +
+	"\texchanges\x18\x02 \x03(\v2\x11.chat.LLMExchangeR\texchanges\"6\n" +
+	"\x15RestoreSessionRequest\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\"\xac\x01\n" +
+	"\x16RestoreSessionResponse\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12$\n" +
+	"\x0eowner_key_hash\x18\x02 \x01(\tR\fownerKeyHash\x12#\n" +
+	"\rmessage_count\x18\x03 \x01(\rR\fmessageCount\x12(\n" +
+	"\x10archived_at_unix\x18\x04 \x01(\x03R\x0earchivedAtUnix\"b\n" +
+	"\x14ImportSessionRequest\x12\x18\n" +
+	"\acontent\x18\x01 \x01(\tR\acontent\x120\n" +
+	"\x14idle_timeout_seconds\x18\x02 \x01(\rR\x12idleTimeoutSeconds\"\x8d\x01\n" +
+	"\x15ImportSessionResponse\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12#\n" +
+	"\rmessage_count\x18\x02 \x01(\rR\fmessageCount\x120\n" +
+	"\x14idle_timeout_seconds\x18\x03 \x01(\rR\x12idleTimeoutSeconds\"O\n" +
+	"\x19SetMaintenanceModeRequest\x12\x18\n" +
+	"\aenabled\x18\x01 \x01(\bR\aenabled\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"o\n" +
+	"\x1aSetMaintenanceModeResponse\x12\x18\n" +
+	"\aenabled\x18\x01 \x01(\bR\aenabled\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12\x1d\n" +
+	"\n" +
+	"since_unix\x18\x03 \x01(\x03R\tsinceUnix\"\x13\n" +
+	"\x11ListModelsRequest\"\xba\x01\n" +
+	"\tModelInfo\x12!\n" +
+	"\x05model\x18\x01 \x01(\x0e2\v.chat.ModelR\x05model\x12\x1a\n" +
+	"\bprovider\x18\x02 \x01(\tR\bprovider\x12!\n" +
+	"\fdisplay_name\x18\x03 \x01(\tR\vdisplayName\x12\x1c\n" +
+	"\tavailable\x18\x04 \x01(\bR\tavailable\x12-\n" +
+	"\x12unavailable_reason\x18\x05 \x01(\tR\x11unavailableReason\"=\n" +
+	"\x12ListModelsResponse\x12'\n" +
+	"\x06models\x18\x01 \x03(\v2\x0f.chat.ModelInfoR\x06models*C\n" +
+	"\x05Model\x12\x19\n" +
+	"\x15GEMINI_2_5_FLASH_LITE\x10\x00\x12\b\n" +
+	"\x04ECHO\x10\x01\x12\x15\n" +
+	"\x11OPENAI_COMPATIBLE\x10\x02*4\n" +
+	"\fExportFormat\x12\x0f\n" +
+	"\vEXPORT_JSON\x10\x00\x12\x13\n" +
+	"\x0fEXPORT_MARKDOWN\x10\x01*6\n" +
+	"\x0eResponseFormat\x12\x11\n" +
+	"\rRESPONSE_TEXT\x10\x00\x12\x11\n" +
+	"\rRESPONSE_JSON\x10\x012\xee\r\n" +
+	"\vChatService\x12E\n" +
+	"\fStartSession\x12\x19.chat.StartSessionRequest\x1a\x1a.chat.StartSessionResponse\x12-\n" +
+	"\x04Chat\x12\x11.chat.ChatRequest\x1a\x12.chat.ChatResponse\x123\n" +
+	"\x06Health\x12\x13.chat.HealthRequest\x1a\x14.chat.HealthResponse\x12?\n" +
+	"\n" +
+	"GetHistory\x12\x17.chat.GetHistoryRequest\x1a\x18.chat.GetHistoryResponse\x12H\n" +
+	"\rExportSession\x12\x1a.chat.ExportSessionRequest\x1a\x1b.chat.ExportSessionResponse\x12E\n" +
+	"\fListSessions\x12\x19.chat.ListSessionsRequest\x1a\x1a.chat.ListSessionsResponse\x12K\n" +
+	"\x0eListMySessions\x12\x1b.chat.ListMySessionsRequest\x1a\x1c.chat.ListMySessionsResponse\x120\n" +
+	"\x05Embed\x12\x12.chat.EmbedRequest\x1a\x13.chat.EmbedResponse\x12K\n" +
+	"\x0eUploadDocument\x12\x1b.chat.UploadDocumentRequest\x1a\x1c.chat.UploadDocumentResponse\x12K\n" +
+	"\x0eDeleteDocument\x12\x1b.chat.DeleteDocumentRequest\x1a\x1c.chat.DeleteDocumentResponse\x12H\n" +
+	"\rSearchHistory\x12\x1a.chat.SearchHistoryRequest\x1a\x1b.chat.SearchHistoryResponse\x12P\n" +
+	"\x11SearchAllSessions\x12\x1e.chat.SearchAllSessionsRequest\x1a\x1b.chat.SearchHistoryResponse\x12K\n" +
+	"\x0eDeleteMessages\x12\x1b.chat.DeleteMessagesRequest\x1a\x1c.chat.DeleteMessagesResponse\x12;\n" +
+	"\vEditMessage\x12\x18.chat.EditMessageRequest\x1a\x12.chat.ChatResponse\x12B\n" +
+	"\vForkSession\x12\x18.chat.ForkSessionRequest\x1a\x19.chat.ForkSessionResponse\x12<\n" +
+	"\tKeepAlive\x12\x16.chat.KeepAliveRequest\x1a\x17.chat.KeepAliveResponse\x12E\n" +
+	"\fListKeyUsage\x12\x19.chat.ListKeyUsageRequest\x1a\x1a.chat.ListKeyUsageResponse\x129\n" +
+	"\bGetQuota\x12\x15.chat.GetQuotaRequest\x1a\x16.chat.GetQuotaResponse\x12H\n" +
+	"\rGetServerInfo\x12\x1a.chat.GetServerInfoRequest\x1a\x1b.chat.GetServerInfoResponse\x12T\n" +
+	"\x11RotateProviderKey\x12\x1e.chat.RotateProviderKeyRequest\x1a\x1f.chat.RotateProviderKeyResponse\x12N\n" +
+	"\x0fListLLMCaptures\x12\x1c.chat.ListLLMCapturesRequest\x1a\x1d.chat.ListLLMCapturesResponse\x12K\n" +
+	"\x0eRestoreSession\x12\x1b.chat.RestoreSessionRequest\x1a\x1c.chat.RestoreSessionResponse\x12H\n" +
+	"\rImportSession\x12\x1a.chat.ImportSessionRequest\x1a\x1b.chat.ImportSessionResponse\x12W\n" +
+	"\x12SetMaintenanceMode\x12\x1f.chat.SetMaintenanceModeRequest\x1a .chat.SetMaintenanceModeResponse\x12?\n" +
 	"\n" +
-	"GetHistory\x12\x17.chat.GetHistoryRequest\x1a\x18.chat.GetHistoryResponseB\tZ\a./protob\x06proto3"
+	"ListModels\x12\x17.chat.ListModelsRequest\x1a\x18.chat.ListModelsResponseB\tZ\a./protob\x06proto3"
 
 var (
 	file_proto_chat_proto_rawDescOnce sync.Once
@@ -503,34 +3694,146 @@ func file_proto_chat_proto_rawDescGZIP() []byte {
 	return file_proto_chat_proto_rawDescData
 }
 
-var file_proto_chat_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_proto_chat_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_proto_chat_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_proto_chat_proto_msgTypes = make([]protoimpl.MessageInfo, 58)
 var file_proto_chat_proto_goTypes = []any{
-	(Model)(0),                   // 0: chat.Model
-	(*StartSessionRequest)(nil),  // 1: chat.StartSessionRequest
-	(*StartSessionResponse)(nil), // 2: chat.StartSessionResponse
-	(*ChatRequest)(nil),          // 3: chat.ChatRequest
-	(*ChatResponse)(nil),         // 4: chat.ChatResponse
-	(*HealthRequest)(nil),        // 5: chat.HealthRequest
-	(*HealthResponse)(nil),       // 6: chat.HealthResponse
-	(*GetHistoryRequest)(nil),    // 7: chat.GetHistoryRequest
-	(*GetHistoryResponse)(nil),   // 8: chat.GetHistoryResponse
+	(Model)(0),                         // 0: chat.Model
+	(ExportFormat)(0),                  // 1: chat.ExportFormat
+	(ResponseFormat)(0),                // 2: chat.ResponseFormat
+	(*StartSessionRequest)(nil),        // 3: chat.StartSessionRequest
+	(*StartSessionResponse)(nil),       // 4: chat.StartSessionResponse
+	(*ChatRequest)(nil),                // 5: chat.ChatRequest
+	(*Attachment)(nil),                 // 6: chat.Attachment
+	(*ChatResponse)(nil),               // 7: chat.ChatResponse
+	(*HealthRequest)(nil),              // 8: chat.HealthRequest
+	(*HealthResponse)(nil),             // 9: chat.HealthResponse
+	(*GetHistoryRequest)(nil),          // 10: chat.GetHistoryRequest
+	(*GetHistoryResponse)(nil),         // 11: chat.GetHistoryResponse
+	(*ExportSessionRequest)(nil),       // 12: chat.ExportSessionRequest
+	(*ExportSessionResponse)(nil),      // 13: chat.ExportSessionResponse
+	(*ListSessionsRequest)(nil),        // 14: chat.ListSessionsRequest
+	(*SessionInfo)(nil),                // 15: chat.SessionInfo
+	(*ListSessionsResponse)(nil),       // 16: chat.ListSessionsResponse
+	(*ListMySessionsRequest)(nil),      // 17: chat.ListMySessionsRequest
+	(*ListMySessionsResponse)(nil),     // 18: chat.ListMySessionsResponse
+	(*EmbedRequest)(nil),               // 19: chat.EmbedRequest
+	(*Embedding)(nil),                  // 20: chat.Embedding
+	(*EmbedResponse)(nil),              // 21: chat.EmbedResponse
+	(*UploadDocumentRequest)(nil),      // 22: chat.UploadDocumentRequest
+	(*UploadDocumentResponse)(nil),     // 23: chat.UploadDocumentResponse
+	(*DeleteDocumentRequest)(nil),      // 24: chat.DeleteDocumentRequest
+	(*DeleteDocumentResponse)(nil),     // 25: chat.DeleteDocumentResponse
+	(*SearchHistoryRequest)(nil),       // 26: chat.SearchHistoryRequest
+	(*SearchAllSessionsRequest)(nil),   // 27: chat.SearchAllSessionsRequest
+	(*SearchMatch)(nil),                // 28: chat.SearchMatch
+	(*SearchHistoryResponse)(nil),      // 29: chat.SearchHistoryResponse
+	(*DeleteMessagesRequest)(nil),      // 30: chat.DeleteMessagesRequest
+	(*DeleteMessagesResponse)(nil),     // 31: chat.DeleteMessagesResponse
+	(*EditMessageRequest)(nil),         // 32: chat.EditMessageRequest
+	(*ForkSessionRequest)(nil),         // 33: chat.ForkSessionRequest
+	(*ForkSessionResponse)(nil),        // 34: chat.ForkSessionResponse
+	(*KeepAliveRequest)(nil),           // 35: chat.KeepAliveRequest
+	(*KeepAliveResponse)(nil),          // 36: chat.KeepAliveResponse
+	(*ListKeyUsageRequest)(nil),        // 37: chat.ListKeyUsageRequest
+	(*KeyUsage)(nil),                   // 38: chat.KeyUsage
+	(*ListKeyUsageResponse)(nil),       // 39: chat.ListKeyUsageResponse
+	(*GetQuotaRequest)(nil),            // 40: chat.GetQuotaRequest
+	(*GetQuotaResponse)(nil),           // 41: chat.GetQuotaResponse
+	(*GetServerInfoRequest)(nil),       // 42: chat.GetServerInfoRequest
+	(*GetServerInfoResponse)(nil),      // 43: chat.GetServerInfoResponse
+	(*RotateProviderKeyRequest)(nil),   // 44: chat.RotateProviderKeyRequest
+	(*RotateProviderKeyResponse)(nil),  // 45: chat.RotateProviderKeyResponse
+	(*ListLLMCapturesRequest)(nil),     // 46: chat.ListLLMCapturesRequest
+	(*CapturedMessage)(nil),            // 47: chat.CapturedMessage
+	(*LLMExchange)(nil),                // 48: chat.LLMExchange
+	(*ListLLMCapturesResponse)(nil),    // 49: chat.ListLLMCapturesResponse
+	(*RestoreSessionRequest)(nil),      // 50: chat.RestoreSessionRequest
+	(*RestoreSessionResponse)(nil),     // 51: chat.RestoreSessionResponse
+	(*ImportSessionRequest)(nil),       // 52: chat.ImportSessionRequest
+	(*ImportSessionResponse)(nil),      // 53: chat.ImportSessionResponse
+	(*SetMaintenanceModeRequest)(nil),  // 54: chat.SetMaintenanceModeRequest
+	(*SetMaintenanceModeResponse)(nil), // 55: chat.SetMaintenanceModeResponse
+	(*ListModelsRequest)(nil),          // 56: chat.ListModelsRequest
+	(*ModelInfo)(nil),                  // 57: chat.ModelInfo
+	(*ListModelsResponse)(nil),         // 58: chat.ListModelsResponse
+	nil,                                // 59: chat.ChatRequest.TemplateVarsEntry
+	nil,                                // 60: chat.GetQuotaResponse.MethodCostsEntry
 }
 var file_proto_chat_proto_depIdxs = []int32{
-	0, // 0: chat.ChatRequest.model:type_name -> chat.Model
-	1, // 1: chat.ChatService.StartSession:input_type -> chat.StartSessionRequest
-	3, // 2: chat.ChatService.Chat:input_type -> chat.ChatRequest
-	5, // 3: chat.ChatService.Health:input_type -> chat.HealthRequest
-	7, // 4: chat.ChatService.GetHistory:input_type -> chat.GetHistoryRequest
-	2, // 5: chat.ChatService.StartSession:output_type -> chat.StartSessionResponse
-	4, // 6: chat.ChatService.Chat:output_type -> chat.ChatResponse
-	6, // 7: chat.ChatService.Health:output_type -> chat.HealthResponse
-	8, // 8: chat.ChatService.GetHistory:output_type -> chat.GetHistoryResponse
-	5, // [5:9] is the sub-list for method output_type
-	1, // [1:5] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	0,  // 0: chat.ChatRequest.model:type_name -> chat.Model
+	2,  // 1: chat.ChatRequest.response_format:type_name -> chat.ResponseFormat
+	6,  // 2: chat.ChatRequest.attachments:type_name -> chat.Attachment
+	59, // 3: chat.ChatRequest.template_vars:type_name -> chat.ChatRequest.TemplateVarsEntry
+	1,  // 4: chat.ExportSessionRequest.format:type_name -> chat.ExportFormat
+	1,  // 5: chat.ExportSessionResponse.format:type_name -> chat.ExportFormat
+	15, // 6: chat.ListSessionsResponse.sessions:type_name -> chat.SessionInfo
+	15, // 7: chat.ListMySessionsResponse.sessions:type_name -> chat.SessionInfo
+	0,  // 8: chat.EmbedRequest.model:type_name -> chat.Model
+	20, // 9: chat.EmbedResponse.embeddings:type_name -> chat.Embedding
+	28, // 10: chat.SearchHistoryResponse.matches:type_name -> chat.SearchMatch
+	0,  // 11: chat.EditMessageRequest.model:type_name -> chat.Model
+	5,  // 12: chat.EditMessageRequest.options:type_name -> chat.ChatRequest
+	38, // 13: chat.ListKeyUsageResponse.keys:type_name -> chat.KeyUsage
+	60, // 14: chat.GetQuotaResponse.method_costs:type_name -> chat.GetQuotaResponse.MethodCostsEntry
+	47, // 15: chat.LLMExchange.messages:type_name -> chat.CapturedMessage
+	48, // 16: chat.ListLLMCapturesResponse.exchanges:type_name -> chat.LLMExchange
+	0,  // 17: chat.ModelInfo.model:type_name -> chat.Model
+	57, // 18: chat.ListModelsResponse.models:type_name -> chat.ModelInfo
+	3,  // 19: chat.ChatService.StartSession:input_type -> chat.StartSessionRequest
+	5,  // 20: chat.ChatService.Chat:input_type -> chat.ChatRequest
+	8,  // 21: chat.ChatService.Health:input_type -> chat.HealthRequest
+	10, // 22: chat.ChatService.GetHistory:input_type -> chat.GetHistoryRequest
+	12, // 23: chat.ChatService.ExportSession:input_type -> chat.ExportSessionRequest
+	14, // 24: chat.ChatService.ListSessions:input_type -> chat.ListSessionsRequest
+	17, // 25: chat.ChatService.ListMySessions:input_type -> chat.ListMySessionsRequest
+	19, // 26: chat.ChatService.Embed:input_type -> chat.EmbedRequest
+	22, // 27: chat.ChatService.UploadDocument:input_type -> chat.UploadDocumentRequest
+	24, // 28: chat.ChatService.DeleteDocument:input_type -> chat.DeleteDocumentRequest
+	26, // 29: chat.ChatService.SearchHistory:input_type -> chat.SearchHistoryRequest
+	27, // 30: chat.ChatService.SearchAllSessions:input_type -> chat.SearchAllSessionsRequest
+	30, // 31: chat.ChatService.DeleteMessages:input_type -> chat.DeleteMessagesRequest
+	32, // 32: chat.ChatService.EditMessage:input_type -> chat.EditMessageRequest
+	33, // 33: chat.ChatService.ForkSession:input_type -> chat.ForkSessionRequest
+	35, // 34: chat.ChatService.KeepAlive:input_type -> chat.KeepAliveRequest
+	37, // 35: chat.ChatService.ListKeyUsage:input_type -> chat.ListKeyUsageRequest
+	40, // 36: chat.ChatService.GetQuota:input_type -> chat.GetQuotaRequest
+	42, // 37: chat.ChatService.GetServerInfo:input_type -> chat.GetServerInfoRequest
+	44, // 38: chat.ChatService.RotateProviderKey:input_type -> chat.RotateProviderKeyRequest
+	46, // 39: chat.ChatService.ListLLMCaptures:input_type -> chat.ListLLMCapturesRequest
+	50, // 40: chat.ChatService.RestoreSession:input_type -> chat.RestoreSessionRequest
+	52, // 41: chat.ChatService.ImportSession:input_type -> chat.ImportSessionRequest
+	54, // 42: chat.ChatService.SetMaintenanceMode:input_type -> chat.SetMaintenanceModeRequest
+	56, // 43: chat.ChatService.ListModels:input_type -> chat.ListModelsRequest
+	4,  // 44: chat.ChatService.StartSession:output_type -> chat.StartSessionResponse
+	7,  // 45: chat.ChatService.Chat:output_type -> chat.ChatResponse
+	9,  // 46: chat.ChatService.Health:output_type -> chat.HealthResponse
+	11, // 47: chat.ChatService.GetHistory:output_type -> chat.GetHistoryResponse
+	13, // 48: chat.ChatService.ExportSession:output_type -> chat.ExportSessionResponse
+	16, // 49: chat.ChatService.ListSessions:output_type -> chat.ListSessionsResponse
+	18, // 50: chat.ChatService.ListMySessions:output_type -> chat.ListMySessionsResponse
+	21, // 51: chat.ChatService.Embed:output_type -> chat.EmbedResponse
+	23, // 52: chat.ChatService.UploadDocument:output_type -> chat.UploadDocumentResponse
+	25, // 53: chat.ChatService.DeleteDocument:output_type -> chat.DeleteDocumentResponse
+	29, // 54: chat.ChatService.SearchHistory:output_type -> chat.SearchHistoryResponse
+	29, // 55: chat.ChatService.SearchAllSessions:output_type -> chat.SearchHistoryResponse
+	31, // 56: chat.ChatService.DeleteMessages:output_type -> chat.DeleteMessagesResponse
+	7,  // 57: chat.ChatService.EditMessage:output_type -> chat.ChatResponse
+	34, // 58: chat.ChatService.ForkSession:output_type -> chat.ForkSessionResponse
+	36, // 59: chat.ChatService.KeepAlive:output_type -> chat.KeepAliveResponse
+	39, // 60: chat.ChatService.ListKeyUsage:output_type -> chat.ListKeyUsageResponse
+	41, // 61: chat.ChatService.GetQuota:output_type -> chat.GetQuotaResponse
+	43, // 62: chat.ChatService.GetServerInfo:output_type -> chat.GetServerInfoResponse
+	45, // 63: chat.ChatService.RotateProviderKey:output_type -> chat.RotateProviderKeyResponse
+	49, // 64: chat.ChatService.ListLLMCaptures:output_type -> chat.ListLLMCapturesResponse
+	51, // 65: chat.ChatService.RestoreSession:output_type -> chat.RestoreSessionResponse
+	53, // 66: chat.ChatService.ImportSession:output_type -> chat.ImportSessionResponse
+	55, // 67: chat.ChatService.SetMaintenanceMode:output_type -> chat.SetMaintenanceModeResponse
+	58, // 68: chat.ChatService.ListModels:output_type -> chat.ListModelsResponse
+	44, // [44:69] is the sub-list for method output_type
+	19, // [19:44] is the sub-list for method input_type
+	19, // [19:19] is the sub-list for extension type_name
+	19, // [19:19] is the sub-list for extension extendee
+	0,  // [0:19] is the sub-list for field type_name
 }
 
 func init() { file_proto_chat_proto_init() }
@@ -543,8 +3846,8 @@ func file_proto_chat_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_chat_proto_rawDesc), len(file_proto_chat_proto_rawDesc)),
-			NumEnums:      1,
-			NumMessages:   8,
+			NumEnums:      3,
+			NumMessages:   58,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
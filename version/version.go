@@ -0,0 +1,74 @@
+// Package version reports which build of microchat.ai is running
+//
+// Release builds set the values at link time:
+//
+//	go build -ldflags "-X microchat.ai/version.Version=v1.2.0 \
+//	  -X microchat.ai/version.Commit=$(git rev-parse --short HEAD) \
+//	  -X microchat.ai/version.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd/server
+//
+// Builds without ldflags fall back to the VCS details the Go toolchain embeds
+package version
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// Set with -ldflags "-X microchat.ai/version.<Name>=<value>"
+var (
+	Version   = "dev"     // Release version, e.g. v1.2.0
+	Commit    = "unknown" // Git commit the binary was built from
+	BuildDate = "unknown" // Build time in RFC 3339, UTC
+)
+
+// Info describes the running build
+type Info struct {
+	Version   string
+	Commit    string
+	BuildDate string
+	GoVersion string
+}
+
+// Get returns the build info, filling in the commit and build date from the embedded
+// VCS settings when they weren't set at link time
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+	}
+
+	buildInfo, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	modified := false
+	for _, setting := range buildInfo.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if info.Commit == "unknown" {
+				info.Commit = setting.Value
+				if len(info.Commit) > 12 {
+					info.Commit = info.Commit[:12]
+				}
+			}
+		case "vcs.time":
+			if info.BuildDate == "unknown" {
+				info.BuildDate = setting.Value
+			}
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+	if modified && Commit == "unknown" && info.Commit != "unknown" {
+		info.Commit += "-dirty"
+	}
+	return info
+}
+
+// String formats the build info for -version output
+func (i Info) String() string {
+	return fmt.Sprintf("%s (commit %s, built %s, %s)", i.Version, i.Commit, i.BuildDate, i.GoVersion)
+}
@@ -0,0 +1,33 @@
+package version
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestGetUsesLinkTimeValues(t *testing.T) {
+	defer func(v, c, d string) { Version, Commit, BuildDate = v, c, d }(Version, Commit, BuildDate)
+	Version, Commit, BuildDate = "v1.2.3", "abc1234", "2025-01-02T03:04:05Z"
+
+	info := Get()
+	if info.Version != "v1.2.3" || info.Commit != "abc1234" || info.BuildDate != "2025-01-02T03:04:05Z" {
+		t.Errorf("expected link-time values, got %+v", info)
+	}
+	if info.GoVersion != runtime.Version() {
+		t.Errorf("expected Go version %s, got %s", runtime.Version(), info.GoVersion)
+	}
+	if s := info.String(); !strings.Contains(s, "v1.2.3") || !strings.Contains(s, "abc1234") {
+		t.Errorf("unexpected version string %q", s)
+	}
+}
+
+func TestGetDefaults(t *testing.T) {
+	info := Get()
+	if info.Version != "dev" {
+		t.Errorf("expected dev version without ldflags, got %q", info.Version)
+	}
+	if info.Commit == "" || info.BuildDate == "" {
+		t.Errorf("expected commit and build date to be set, got %+v", info)
+	}
+}
@@ -0,0 +1,87 @@
+// Package zstd registers a zstd compressor for gRPC, so requests and replies can be sent with
+// grpc.UseCompressor(zstd.Name)
+//
+// zstd usually compresses chat-sized messages smaller and faster than gzip. Both ends need the codec;
+// the server and client register it by importing this package
+package zstd
+
+import (
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/grpc/encoding"
+)
+
+// Name is the name the compressor is registered under, sent in the grpc-encoding header
+const Name = "zstd"
+
+// maxDecoderMemory caps what decoding a message may allocate, so a crafted frame can't exhaust memory
+// gRPC separately limits the decompressed message size
+const maxDecoderMemory = 64 << 20
+
+func init() {
+	encoding.RegisterCompressor(&compressor{})
+}
+
+type compressor struct {
+	encoders sync.Pool
+	decoders sync.Pool
+}
+
+func (c *compressor) Name() string {
+	return Name
+}
+
+// writer returns its encoder to the pool once the message is written
+type writer struct {
+	*zstd.Encoder
+	pool *sync.Pool
+}
+
+func (c *compressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	if z, ok := c.encoders.Get().(*writer); ok {
+		z.Reset(w)
+		return z, nil
+	}
+	// One goroutine per encoder: messages are small and each call compresses its own
+	enc, err := zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	return &writer{Encoder: enc, pool: &c.encoders}, nil
+}
+
+func (z *writer) Close() error {
+	defer z.pool.Put(z)
+	return z.Encoder.Close()
+}
+
+// reader returns its decoder to the pool once the message has been read to the end
+type reader struct {
+	*zstd.Decoder
+	pool *sync.Pool
+}
+
+func (c *compressor) Decompress(r io.Reader) (io.Reader, error) {
+	if z, ok := c.decoders.Get().(*reader); ok {
+		if err := z.Reset(r); err != nil {
+			c.decoders.Put(z)
+			return nil, err
+		}
+		return z, nil
+	}
+	dec, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(maxDecoderMemory))
+	if err != nil {
+		return nil, err
+	}
+	return &reader{Decoder: dec, pool: &c.decoders}, nil
+}
+
+func (z *reader) Read(p []byte) (int, error) {
+	n, err := z.Decoder.Read(p)
+	if err == io.EOF {
+		z.pool.Put(z)
+	}
+	return n, err
+}
@@ -0,0 +1,66 @@
+package zstd
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/encoding"
+)
+
+func roundTrip(t *testing.T, c encoding.Compressor, payload []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := c.Compress(&buf)
+	if err != nil {
+		t.Fatalf("Compress failed: %v", err)
+	}
+	if _, err := w.Write(payload); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	compressed := buf.Len()
+
+	r, err := c.Decompress(&buf)
+	if err != nil {
+		t.Fatalf("Decompress failed: %v", err)
+	}
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll failed: %v", err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Fatalf("Expected %d bytes back, got %d", len(payload), len(got))
+	}
+	if len(payload) > 1000 && compressed >= len(payload) {
+		t.Errorf("Expected %d bytes to compress, got %d", len(payload), compressed)
+	}
+	return got
+}
+
+func TestCompressorRoundTrip(t *testing.T) {
+	c := encoding.GetCompressor(Name)
+	if c == nil {
+		t.Fatalf("Expected a compressor registered as %q", Name)
+	}
+
+	// Repeated messages reuse pooled encoders and decoders
+	message := []byte(strings.Repeat("Explain CRDTs in one paragraph. ", 100))
+	for range 3 {
+		roundTrip(t, c, message)
+		roundTrip(t, c, nil)
+	}
+}
+
+func TestDecompressRejectsInvalidData(t *testing.T) {
+	r, err := encoding.GetCompressor(Name).Decompress(strings.NewReader("not a zstd frame"))
+	if err == nil {
+		_, err = io.ReadAll(r)
+	}
+	if err == nil {
+		t.Error("Expected invalid data to fail to decompress")
+	}
+}